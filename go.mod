@@ -1,15 +1,15 @@
-module Pushi_Pressure_Meter
+module github.com/foylaou/pressure-meter
 
 go 1.24.2
 
 require (
 	github.com/goburrow/modbus v0.1.0
+	github.com/goburrow/serial v0.1.0
 	go.bug.st/serial v1.6.4
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/creack/goselect v0.1.2 // indirect
-	github.com/goburrow/serial v0.1.0 // indirect
 	golang.org/x/sys v0.19.0 // indirect
 )
@@ -2,20 +2,32 @@
 package main
 
 import (
-	"Pushi_Pressure_Meter/pressure"
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/foylaou/pressure-meter/pressure"
+	"io"
 	"log"
+	"math"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 )
 
+// exitTooManyFailures 連續讀取失敗次數過多時的退出碼
+const exitTooManyFailures = 3
+
+// exitSelfTestFailed 守護程序模式下啟動自檢未通過時的退出碼
+const exitSelfTestFailed = 4
+
 // AppInfo 應用程式信息
 type AppInfo struct {
 	Name        string `json:"name"`
@@ -25,6 +37,12 @@ type AppInfo struct {
 	BuildTime   string `json:"build_time"`
 }
 
+// branding 配置中的品牌設置，為空則使用 appInfo
+var branding *pressure.Branding
+
+// frameTrace --trace 指定的 Modbus 幀跟蹤，為空則不記錄
+var frameTrace *pressure.FrameTrace
+
 // 應用程式信息
 var appInfo = AppInfo{
 	Name:        "壓差儀監測工具",
@@ -41,26 +59,224 @@ var (
 	autoScan       = flag.Bool("auto-scan", false, "自動掃描並配置第一個找到的設備")
 	quickScan      = flag.Bool("quick-scan", false, "快速掃描設備")
 	fullScan       = flag.Bool("full-scan", false, "完整掃描設備")
+	rescan         = flag.Bool("rescan", false, "增量掃描：先驗證掃描緩存中的已知設備，全部響應時不再完整掃描")
 	testConfig     = flag.Bool("test-config", false, "測試配置並退出")
+	diagnose       = flag.Bool("diagnose", false, "執行 Modbus 串行線路診斷 (功能碼 0x08) 和連接測試，輸出延遲、原始數據和按各種格式解析的結果並退出")
 	generateConfig = flag.Bool("generate-config", false, "生成配置檔案示例")
+	configBackups  = flag.Bool("config-backups", false, "列出配置檔案的自動備份並退出")
+	configRollback = flag.Bool("config-rollback", false, "用最近的備份恢復配置檔案並退出")
 	daemon         = flag.Bool("daemon", false, "以守護程序模式運行")
+	selfTest       = flag.Bool("self-test", false, "開始監測前執行啟動自檢，守護程序模式下未通過則拒絕啟動（除非 --force）")
+	selfTestReads  = flag.Int("self-test-reads", pressure.DefaultSelfTestReads, "啟動自檢的測試讀取次數")
+	noBanner       = flag.Bool("no-banner", false, "不顯示啟動橫幅")
 	logFile        = flag.String("log", "", "日誌檔案路徑")
 	configFile     = flag.String("config", "", "指定配置檔案路徑")
-	outputFormat   = flag.String("output", "text", "輸出格式 (text/json/csv)")
+	deviceFlag     = flag.String("device", "", "RS485 設備路徑")
+	transportFlag  = flag.String("transport", "", "傳輸方式 (rtu/tcp/rtuovertcp)，默認 rtu，設備路徑為 tcp://host:port 時為 rtuovertcp")
+	addressFlag    = flag.String("address", "", "Modbus TCP 網關地址 (host:port，默認端口 502)，指定時默認使用 tcp 傳輸")
+	slaveIDFlag    = flag.String("slave-id", "", "Modbus 站點號 (1-247，支援 0x16 格式)")
+	intervalFlag   = flag.Duration("interval", 0, "讀取間隔時間")
+	formatFlag     = flag.String("format", "", "數據格式 (decimal/float/int16/uint16)")
+	deviceProfile  = flag.String("device-profile", "", "設備配置檔，決定壓力寄存器地址和數值編碼")
+	registerType   = flag.String("register-type", "", "壓力寄存器類型 (holding/input)，掃描時也用於探測")
+	byteOrder      = flag.String("byte-order", "", "壓力值的字節序 (ABCD/CDAB/BADC/DCBA)，覆蓋設備配置檔，掃描時也用於檢測浮點格式")
+	scaleFlag      = flag.String("scale", "", "壓力原始數值到 Pa 的換算係數 (如 0.01 或 /100)，覆蓋設備配置檔，掃描時也用於解析十進制格式")
+	minPressure    = flag.String("min-pressure", "", "有效讀數下限 (Pa)，超出範圍的讀數標記為無效")
+	maxPressure    = flag.String("max-pressure", "", "有效讀數上限 (Pa)，超出範圍的讀數標記為無效")
+	enableControl  = flag.Bool("enable-control", false, "允許寫入儀表的命令 (阻尼、站點號、波特率、零點校準、設置恢復、廣播寫入) 和 HTTP 接口的控制操作")
+	dampingReg     = flag.String("damping-register", "", "儀表阻尼/濾波時間的保持寄存器地址 (如 0x0010)")
+	setDamping     = flag.String("set-damping", "", "寫入阻尼寄存器並讀回確認後退出")
+	slaveIDReg     = flag.String("slave-id-register", "", "儀表站點號的保持寄存器地址 (如 0x0100)")
+	setSlaveID     = flag.String("set-slave-id", "", "將 --slave-id 指定的儀表改為新站點號並讀回確認後退出")
+	baudRateFlag   = flag.Int("baud-rate", 0, "串口波特率 (默認 9600)")
+	baudRateReg    = flag.String("baud-rate-register", "", "儀表波特率的保持寄存器地址 (如 0x0101)")
+	baudRateCodes  = flag.String("baud-rate-codes", "", "波特率寄存器保存序號時，序號 0、1、2… 對應的波特率 (如 2400,4800,9600,19200)")
+	setBaudRate    = flag.String("set-baud-rate", "", "將儀表改為新波特率，以新波特率重新打開串口並讀回確認後退出")
+	zeroReg        = flag.String("zero-register", "", "儀表零點校準命令的保持寄存器地址 (如 0x0020)")
+	calibrateZero  = flag.Bool("calibrate-zero", false, "寫入零點校準命令並讀取校準後的壓力後退出，需配合 --force")
+	tempRegister   = flag.String("temperature-register", "", "儀表溫度的保持寄存器地址 (如 0x0036)，讀數附帶溫度")
+	timestampFlag  = flag.String("timestamp", "", "讀數時間戳取值時刻 (before/after/midpoint)")
+	outputFormat   = flag.String("output", "text", "輸出格式 (text/json/csv/cbor)")
+	outputFile     = flag.String("output-file", "", "讀數記錄追加寫入的檔案，不打印到標準輸出 (--output=cbor 時必須指定)")
 	maxReadings    = flag.Int("max-readings", 0, "最大讀數數量，0為無限制")
 	duration       = flag.Duration("duration", 0, "運行時間，0為無限制")
+	burstInterval  = flag.Duration("burst", 0, "突發採樣：以此間隔 (如 50ms) 讀取 --duration 時長 (默認 30s)，讀數緩存後寫入檔案並退出")
+	maxFailures    = flag.Int("max-failures", 0, "連續讀取失敗多少次後執行 --on-failure 處理，0為不處理")
+	onFailure      = flag.String("on-failure", "retry", "連續失敗處理方式 (retry/exit/hook/failover/recover)")
+	failureHook    = flag.String("failure-hook", "", "--on-failure=hook 時執行的命令，recover 時用於故障通知")
+	backupDevice   = flag.String("backup-device", "", "--on-failure=failover 時切換到的備用設備")
+	recoverySteps  = flag.String("recovery-steps", "", "--on-failure=recover 的恢復步驟，逗號分隔 (reopen,reset,failover,fail)")
+	resetCommand   = flag.String("reset-command", "", "reset 恢復步驟執行的命令 (如 usbreset 1a86:7523)")
+	recoveryWait   = flag.Duration("recovery-interval", pressure.DefaultRecoveryInterval, "兩個恢復步驟之間的最短間隔")
 	verbose        = flag.Bool("verbose", false, "詳細輸出")
 	quiet          = flag.Bool("quiet", false, "靜默模式")
+
+	onlyChanges     = flag.Bool("only-changes", false, "文本模式下只在數值變化超過容差或狀態變化時打印")
+	changeTolerance = flag.Float64("change-tolerance", 0.1, "--only-changes 的變化容差 (Pa)")
+	eventsOnly      = flag.Bool("events-only", false, "只輸出告警和區間變化事件，不輸出每個讀數")
+	signBands       = flag.Bool("sign-bands", false, "以負壓/正壓兩個區間產生 band_changed 事件")
+	setpointFlag    = flag.String("setpoint", "", "壓力設定值 (Pa)，統計偏差和達標時間，並以低於/位於/高於設定值 ± 容差三個區間產生 band_changed 事件")
+	setpointTol     = flag.Float64("setpoint-tolerance", pressure.DefaultSetpointTolerance, "--setpoint 的容差 (Pa)")
+	baselineFile    = flag.String("baseline", "", "按時段學習壓力基線並保存到檔案，偏離基線時觸發 baseline_anomaly 告警")
+	baselineSigma   = flag.Float64("baseline-sigma", 0, "偏離基線多少個標準差視為異常，0為使用配置值 (默認 4)")
+	statsFile       = flag.String("stats-file", "", "定期保存壓力統計和設定值達標統計，重啟後載入繼續累計")
+	ambientSource   = flag.String("ambient", "", "大氣壓來源 (檔案路徑、exec:命令或 http(s) 天氣接口)，附加到每個讀數")
+	ambientField    = flag.String("ambient-field", "", "--ambient 輸出 JSON 時氣壓字段的路徑 (如 current.surface_pressure)")
+	ambientUnit     = flag.String("ambient-unit", "", "--ambient 的氣壓單位 (默認 hPa，Linux IIO 氣壓傳感器為 kPa)")
+	connectTimeout  = flag.Duration("connect-timeout", 0, "連接超時時間，0為使用配置值")
+	responseTimeout = flag.Duration("response-timeout", 0, "響應超時時間，0為使用配置值")
+	latencyBudget   = flag.Duration("latency-budget", 0, "單次 Modbus 請求的延遲預算，超出的讀數標記為降級，0為使用配置值")
+	latencyLimit    = flag.Int("latency-violations", 0, "連續多少次超出延遲預算後觸發告警，0為使用配置值 (默認 5)")
+	maxRetries      = flag.Int("max-retries", -1, "單次讀取失敗後的重試次數，-1為使用配置值")
+	retryDelay      = flag.Duration("retry-delay", 0, "讀取重試前的等待時間，0為使用配置值 (默認 100ms)")
+	openRetryWait   = flag.Duration("open-retry-wait", 0, "啟動時打開連接失敗後重試的最長等待時間，0為使用配置值")
+	startDegraded   = flag.Bool("degraded", false, "重試後仍無法連接時以降級模式啟動，讀取時再嘗試連接")
+	rawData         = flag.Bool("raw-data", false, "在讀數中保留原始寄存器數據 (JSON 輸出 raw_data)，--verbose 時自動開啟")
+	bufferSize      = flag.Int("buffer-size", 0, "讀數緩衝區可容納的讀數數量，0為使用配置值 (默認 100)")
+	identify        = flag.Bool("identify", false, "讀取設備標識（型號和韌體版本），掃描時對每台響應的設備讀取")
+	traceFile       = flag.String("trace", "", "將每個 Modbus 請求和響應幀（十六進制和時間戳）追加到檔案，- 為標準錯誤")
+	overflowPolicy  = flag.String("overflow-policy", "", "讀數緩衝區已滿時的處理方式 (drop-oldest/drop-newest/block)")
+	scanTimeout     = flag.Duration("scan-timeout", 0, "掃描探測超時時間，0為使用掃描預設值")
+	formatSamples   = flag.Int("format-samples", 0, "掃描時對響應設備讀取多少次來檢測數據格式，0為使用掃描預設值 (3)")
+	scanRetry       = flag.Duration("scan-retry-timeout", 0, "串口掃描第二輪重試超時的站點號時使用的超時，0為使用掃描預設值")
+	noScanRetry     = flag.Bool("no-scan-retry", false, "串口掃描不重試超時的站點號")
+	scanDelay       = flag.Duration("scan-delay", 0, "掃描時同一串口上相鄰兩次探測之間的等待時間，用於連續請求時會丟幀的慢速轉換器")
+	commProfile     = flag.String("comm-profile", "", "通信時序配置檔 (bench/long-line/radio)")
+	unitPreset      = flag.String("unit-preset", "", "行業單位預設 (cleanroom/hvac/filtration)，決定文本輸出、告警和報告的單位")
+	resumeScan      = flag.Bool("resume", false, "從檢查點恢復中斷的完整掃描")
+	checkpointPath  = flag.String("checkpoint", pressure.DefaultCheckpointFile, "完整掃描的進度檢查點檔案")
+	scanCache       = flag.String("scan-cache", pressure.DefaultScanCacheFile, "掃描緩存檔案，完整掃描和網絡掃描後寫入，--rescan 從中讀取已知設備，為空則不寫入")
+	scanPlan        = flag.Bool("plan", false, "只打印計劃不訪問總線：掃描模式下為掃描計劃，否則為總線吞吐量估算")
+	busDevices      = flag.Int("bus-devices", 1, "總線吞吐量估算：同一總線上輪詢的設備數")
+	busBaudRate     = flag.Int("bus-baud", pressure.DefaultBaudRate, "總線吞吐量估算：波特率")
+	busOverhead     = flag.Duration("bus-overhead", pressure.DefaultTransactionOverhead, "總線吞吐量估算：每次事務的額外耗時（儀表處理、收發切換）")
+	parityMatrix    = flag.Bool("parity-matrix", false, "掃描時每個波特率同時嘗試 N 和 E 校驗")
+	serialMatrix    = flag.Bool("serial-matrix", false, "掃描時每個波特率嘗試全部校驗位和停止位組合 (8N1/8N2/8E1/8E2/8O1/8O2)")
+	scanWorkers     = flag.Int("scan-workers", 0, "完整掃描時同時掃描的串口數 (默認 4)，網絡掃描時同時探測的地址數 (默認 32)，1 為依次掃描")
+	networkScan     = flag.String("network-scan", "", "掃描網段 (CIDR 如 192.168.1.0/24 或單個 IP) 內的 Modbus TCP 設備")
+	tcpPorts        = flag.String("tcp-ports", pressure.DefaultModbusTCPPort, "網絡掃描的 TCP 端口，以逗號分隔")
+	scanOutput      = flag.String("scan-output", pressure.ScanOutputJSON, "完整掃描、網絡掃描和增量掃描保存結果的格式 (json/csv/yaml)，以逗號分隔可同時保存多種")
+	scanConfigFile  = flag.String("scan-config", "", "掃描配置檔案 (YAML/JSON)，取代快速、完整和自動掃描的內置配置")
+	scanSlaves      = flag.String("scan-slaves", "", "覆蓋掃描的站點號，以逗號分隔，支援範圍 (如 1-10,22)")
+	scanBauds       = flag.String("scan-bauds", "", "覆蓋掃描的波特率，以逗號分隔 (如 9600,19200)")
+	scanExclude     = flag.String("scan-exclude", "", "不掃描的串口，以逗號分隔，支援通配符 (如 /dev/ttyS0,/dev/ttyAMA*)，用於 PLC、GPS 等其他設備使用的串口")
+	scanPorts       = flag.String("scan-ports", "", "覆蓋掃描的串口，以逗號分隔 (如 /dev/ttyUSB0,/dev/ttyUSB1)")
+	selectDevice    = flag.Int("select-device", 0, "快速掃描後監測第 N 台響應設備 (從 1 開始)，0 為在終端中交互選擇")
+	probeRegister   = flag.String("probe-register", "", "掃描探測的寄存器地址 (默認 0x0034)")
+	probeCount      = flag.Uint("probe-count", 0, "掃描探測讀取的寄存器數量 (默認 2)")
+	probeFunction   = flag.Uint("probe-function", 0, "掃描探測的功能碼 (3=讀保持寄存器, 4=讀輸入寄存器)")
+	passiveFirst    = flag.Bool("passive-first", false, "掃描前先被動監聽總線，已有其他主站 (PLC 等) 輪詢的串口不探測，加上 --force 只警告")
+	passiveListen   = flag.Duration("passive-listen", pressure.DefaultPassiveListen, "--passive-first 在每個波特率下的監聽時長，應長於現有主站的輪詢間隔")
+	reportFile      = flag.String("report", "", "將掃描結果或監測趨勢輸出為 HTML 報告檔案")
+	xlsxFile        = flag.String("xlsx", "", "將掃描結果或監測讀數匯出為 Excel 檔案")
+	complianceLog   = flag.String("compliance-log", "", "防篡改合規日誌檔案（雜湊鏈，只追加）")
+	rebuildManifest = flag.Bool("compliance-rebuild-manifest", false, "合規日誌的清單缺失或損壞時按日誌重建清單並繼續追加（無法再發現之前的截斷，先用 --verify-log 人工核對）")
+	locationFile    = flag.String("locations", "", "站點號/串口到安裝位置（房間、樓層、資產編號）的 CSV 對照表")
+	sinkFailures    = flag.Int("sink-failures", 0, "輸出目標連續失敗多少次後暫停輸出，0為默認 (5)")
+	sinkRetry       = flag.Duration("sink-retry", 0, "輸出目標暫停後第一次重試的等待時間，0為默認 (30s)")
+	sinkTimeout     = flag.Duration("sink-timeout", 0, "每次向輸出目標寫入的期限，超時計為一次失敗，0為默認 (10s)")
+	verifyLog       = flag.String("verify-log", "", "驗證合規日誌的完整性並退出")
+	compareRef      = flag.String("compare", "", "與參考記錄儀 (標準器) 的 CSV 比對 --recorded 記錄的讀數並退出")
+	recordedFile    = flag.String("recorded", "", "--compare 使用的本工具記錄 (--output=csv/json 的輸出或合規日誌)")
+	refColumns      = flag.String("ref-columns", "", "參考 CSV 的時間列和壓力列 (列名或從 1 開始的序號，如 2,3)，默認自動識別")
+	refUnit         = flag.String("ref-unit", "Pa", "參考記錄的壓力單位 (Pa/kPa/mbar/Torr/psi/inH2O/mmH2O/at)")
+	refShift        = flag.Duration("ref-shift", 0, "加到參考時間上的偏移，修正兩台設備的時鐘差")
+	compareTol      = flag.Duration("compare-tolerance", pressure.DefaultCompareTolerance, "參考記錄與讀數對齊時允許的最大時間差")
+	compareLimit    = flag.Float64("compare-limit", 0, "允許的最大偏差 (Pa)，超出時以退出碼 1 退出，0為不判斷")
+	printSchema     = flag.Bool("schema", false, "打印 JSON 輸出格式的結構描述並退出")
+	migrateFile     = flag.String("migrate", "", "將 --output=json 記錄的讀數檔案原地升級到當前格式版本並退出")
+	decodeFile      = flag.String("decode", "", "將 --output=cbor 記錄的檔案 (- 為標準輸入) 逐條轉換為 JSON 打印並退出")
+	httpAddr        = flag.String("http", "", "HTTP 接口的監聽地址 (如 :8080 或 unix:/run/pressure-meter.sock)")
+	controlAddr     = flag.String("control-addr", "", "HTTP 控制操作的獨立監聽地址 (如 127.0.0.1:8081 或 unix:/run/pressure-meter-control.sock)，設置後 --http 只提供只讀接口")
+	controlToken    = flag.String("control-token", "", "HTTP 控制操作要求的 Bearer 令牌，也可用環境變量 "+pressure.ControlTokenEnv+" 設置")
+	gatewayAddr     = flag.String("gateway", "", "Modbus TCP 網關的監聽地址 (如 :502)，SCADA 可通過以太網輪詢讀數")
+	discoverEvery   = flag.Duration("discover", 0, "監測期間每隔指定時間快速掃描同一總線，記錄新接入和移除的儀表 (0 為不掃描)")
+	showStatus      = flag.Bool("status", false, "從 --http 指定的運行中監測程序獲取狀態快照並退出")
+	watchMode       = flag.Bool("watch", false, "連接 --http 指定的運行中監測程序，在終端持續顯示讀數和狀態")
+	watchRefresh    = flag.Duration("refresh", time.Second, "--watch 的畫面刷新間隔")
+	provisionMode   = flag.Bool("provision", false, "將 --from 指定的掃描結果轉換為多設備配置檔，逐台輸入名稱和位置")
+	provisionFrom   = flag.String("from", "", "--provision 讀取的掃描結果 (--full-scan 保存的 scan_results_*.json)")
+	devicesFile     = flag.String("devices-file", "devices.yaml", "--provision 生成的多設備配置檔 (.yaml 或 .json)")
+	paramsDump      = flag.String("params-dump", "", "讀取設備配置檔中的全部設置寄存器保存到 YAML 檔案並退出")
+	paramsRestore   = flag.String("params-restore", "", "將 --params-dump 保存的設置寫回儀表並讀回確認，需配合 --force")
+	simulate        = flag.String("simulate", "", "不連接儀表，以模擬波形 (sine/noise/step) 生成壓力，經正常的讀取流程輸出")
+	simulateRange   = flag.String("simulate-range", "-20,20", "--simulate 的壓力範圍 MIN,MAX (Pa)")
+	simulatePeriod  = flag.Duration("simulate-period", pressure.DefaultSimulatorPeriod, "--simulate 的正弦週期或階躍間隔")
+	simulateNoise   = flag.Float64("simulate-noise", 0, "--simulate 疊加的隨機噪聲標準差 (Pa)")
+	simulateDrift   = flag.Float64("simulate-drift", 0, "--simulate 的緩慢漂移 (Pa/小時)")
+	simulateSeed    = flag.Int64("simulate-seed", 0, "--simulate 的隨機數種子，0為按時間；固定種子時噪聲和機率故障可重現")
+	simulateFaults  = flag.String("simulate-faults", "", "--simulate 注入的通信故障，如 dropout:20x3,crc:5%,exception=4:50")
+	replayRaw       = flag.String("replay-raw", "", "將記錄的原始寄存器數據 (--output=json 輸出或掃描結果) 按當前配置重新解析並退出")
+	replaySpeed     = flag.Float64("replay-speed", 1, "--replay-raw 的回放速度倍數，1 為按記錄的時間間隔，0 為不等待")
+	alarmsTest      = flag.Bool("alarms-test", false, "用 --value 或 --replay 評估配置的告警規則，打印會觸發的規則並退出")
+	alarmValues     = flag.String("value", "", "--alarms-test 評估的壓力值 (Pa)，多個值以逗號分隔")
+	alarmReplay     = flag.String("replay", "", "--alarms-test 按時間回放的讀數記錄 (--output=csv/json 的輸出或合規日誌)")
+	broadcastWrite  = flag.String("broadcast-write", "", "以廣播地址 (站點號 0) 寫入保持寄存器，格式 REG=VALUE，需配合 --force")
+	force           = flag.Bool("force", false, "確認執行廣播寫入等影響總線上所有設備的操作，或在自檢未通過、--passive-first 發現其他主站時仍然繼續")
 )
 
 func main() {
 	// 解析命令列參數
 	flag.Parse()
+	// pressure-meter watch --http ADDR 等同 --watch，pressure-meter provision --from FILE 等同 --provision，
+	// pressure-meter alarms test --value PA 等同 --alarms-test，pressure-meter decode FILE 等同 --decode FILE，
+	// pressure-meter params dump|restore FILE 等同 --params-dump/--params-restore FILE，pressure-meter replay FILE 等同 --replay-raw FILE
+	switch flag.Arg(0) {
+	case "watch":
+		flag.CommandLine.Parse(flag.Args()[1:])
+		*watchMode = true
+	case "provision":
+		flag.CommandLine.Parse(flag.Args()[1:])
+		*provisionMode = true
+	case "alarms":
+		if flag.Arg(1) != "test" {
+			fmt.Println("❌ 用法: pressure-meter alarms test --value PA[,PA...] | --replay FILE")
+			os.Exit(2)
+		}
+		flag.CommandLine.Parse(flag.Args()[2:])
+		*alarmsTest = true
+	case "decode":
+		if flag.NArg() < 2 {
+			fmt.Println("❌ 用法: pressure-meter decode FILE (- 為標準輸入)")
+			os.Exit(2)
+		}
+		*decodeFile = flag.Arg(1)
+		flag.CommandLine.Parse(flag.Args()[2:])
+	case "params":
+		action, file := flag.Arg(1), flag.Arg(2)
+		if (action != "dump" && action != "restore") || file == "" {
+			fmt.Println("❌ 用法: pressure-meter params dump|restore FILE")
+			os.Exit(2)
+		}
+		flag.CommandLine.Parse(flag.Args()[3:])
+		if action == "dump" {
+			*paramsDump = file
+		} else {
+			*paramsRestore = file
+		}
+	case "replay":
+		if flag.NArg() < 2 {
+			fmt.Println("❌ 用法: pressure-meter replay FILE [--replay-speed N]")
+			os.Exit(2)
+		}
+		*replayRaw = flag.Arg(1)
+		flag.CommandLine.Parse(flag.Args()[2:])
+	}
 
 	// 設置日誌
 	logger := setupLogger()
 
+	if *traceFile != "" {
+		trace, err := pressure.OpenFrameTrace(*traceFile)
+		if err != nil {
+			logger.Fatalf("❌ %v", err)
+		}
+		defer trace.Close()
+		frameTrace = trace
+	}
+
 	// 處理特殊命令
+	loadBranding()
 	if *showVersion {
 		printVersion()
 		return
@@ -76,6 +292,52 @@ func main() {
 		return
 	}
 
+	if *configBackups || *configRollback {
+		os.Exit(runConfigBackupMode(*configRollback))
+	}
+
+	if *migrateFile != "" {
+		os.Exit(runMigrateMode(*migrateFile))
+	}
+
+	if *decodeFile != "" {
+		os.Exit(runDecodeMode(*decodeFile))
+	}
+
+	if *printSchema {
+		data, _ := json.MarshalIndent(pressure.JSONSchemas(), "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+
+	if *verifyLog != "" {
+		os.Exit(runVerifyLogMode(*verifyLog))
+	}
+
+	if *compareRef != "" {
+		os.Exit(runCompareMode())
+	}
+
+	if *showStatus {
+		os.Exit(runStatusMode())
+	}
+
+	if *watchMode {
+		os.Exit(runWatchMode())
+	}
+
+	if *provisionMode {
+		os.Exit(runProvisionMode(logger))
+	}
+
+	if *alarmsTest {
+		os.Exit(runAlarmsTestMode(logger))
+	}
+
+	if *replayRaw != "" {
+		os.Exit(runReplayMode(logger))
+	}
+
 	// 打印啟動信息
 	if !*quiet {
 		printStartupBanner(logger)
@@ -89,8 +351,32 @@ func main() {
 		runQuickScanMode(logger)
 	case *fullScan:
 		runFullScanMode(logger)
+	case *rescan:
+		os.Exit(runRescanMode(logger))
+	case *networkScan != "":
+		os.Exit(runNetworkScanMode(logger))
 	case *testConfig:
 		runTestConfigMode(logger)
+	case *diagnose:
+		os.Exit(runDiagnoseMode(logger))
+	case *setDamping != "":
+		os.Exit(runSetDampingMode(logger))
+	case *setSlaveID != "":
+		os.Exit(runSetSlaveIDMode(logger))
+	case *setBaudRate != "":
+		os.Exit(runSetBaudRateMode(logger))
+	case *calibrateZero:
+		os.Exit(runCalibrateZeroMode(logger))
+	case *broadcastWrite != "":
+		os.Exit(runBroadcastWriteMode(logger))
+	case *paramsDump != "":
+		os.Exit(runParamsDumpMode(logger))
+	case *paramsRestore != "":
+		os.Exit(runParamsRestoreMode(logger))
+	case *burstInterval > 0:
+		os.Exit(runBurstMode(logger))
+	case *scanPlan:
+		os.Exit(runBusPlanMode(logger))
 	default:
 		runNormalMode(logger)
 	}
@@ -129,31 +415,53 @@ func setupLogger() *log.Logger {
 	return logger
 }
 
-// printVersion 打印版本信息
+// printVersion 打印版本信息，配置了產品名稱時不顯示本工具的作者
 func printVersion() {
 	fmt.Printf("%s v%s\n", appInfo.Name, appInfo.Version)
 	fmt.Printf("構建時間: %s\n", appInfo.BuildTime)
-	fmt.Printf("作者: %s\n", appInfo.Author)
+	if branding.Name("") == "" {
+		fmt.Printf("作者: %s\n", appInfo.Author)
+	}
 }
 
-// printStartupBanner 打印啟動橫幅
+// loadBranding 讀取配置中的品牌設置，替換應用程式名稱和版本
+//
+// 在處理 --version 等命令之前調用，配置無效時使用默認名稱，錯誤留給後續的配置加載報告。
+func loadBranding() {
+	config, err := newConfigLoader(log.New(io.Discard, "", 0)).LoadConfig()
+	if err != nil || config.Branding == nil {
+		return
+	}
+	branding = config.Branding
+	appInfo.Name = branding.Name(appInfo.Name)
+	appInfo.Version = branding.Version(appInfo.Version)
+}
+
+// printStartupBanner 打印啟動橫幅，按品牌設置替換或不顯示
 func printStartupBanner(logger *log.Logger) {
+	defer logger.Printf("程式啟動: %s v%s", appInfo.Name, appInfo.Version)
+	if *noBanner || branding.HideBanner() {
+		return
+	}
+	if custom := branding.CustomBanner(appInfo.Name, appInfo.Version); custom != "" {
+		fmt.Println(strings.TrimRight(custom, "\n"))
+		return
+	}
+
 	// 計算內容長度以確保對齊
-	titleLine := fmt.Sprintf("🌡️  %s v%s", appInfo.Name, appInfo.Version)
-	buildLine := fmt.Sprintf("📅 構建時間: %s", appInfo.BuildTime)
-	authorLine := fmt.Sprintf("👤 作者: %s", appInfo.Author)
+	header := []string{
+		fmt.Sprintf("%s%s v%s", branding.Emoji("🌡️  "), appInfo.Name, appInfo.Version),
+		branding.Emoji("📡 ") + "普時達壓差儀 RS485 監測工具",
+		branding.Emoji("🔧 ") + "支援自動掃描和多種數據格式",
+	}
+	footer := []string{fmt.Sprintf("%s構建時間: %s", branding.Emoji("📅 "), appInfo.BuildTime)}
+	if branding.Name("") == "" {
+		footer = append(footer, fmt.Sprintf("%s作者: %s", branding.Emoji("👤 "), appInfo.Author))
+	}
 
 	// 找出最長的行來確定邊框寬度
 	maxWidth := 0
-	lines := []string{
-		titleLine,
-		"📡 普時達壓差儀 RS485 監測工具",
-		"🔧 支援自動掃描和多種數據格式",
-		buildLine,
-		authorLine,
-	}
-
-	for _, line := range lines {
+	for _, line := range append(append([]string{}, header...), footer...) {
 		// 計算實際顯示寬度（考慮 emoji 和中文字符）
 		width := calculateDisplayWidth(line)
 		if width > maxWidth {
@@ -167,32 +475,21 @@ func printStartupBanner(logger *log.Logger) {
 	}
 
 	// 構建橫幅
-	border := "═"
 	padding := 2
 	totalWidth := maxWidth + padding*2
 
-	banner := fmt.Sprintf(`
-╔%s╗
-║ %-*s ║
-║ %-*s ║
-║ %-*s ║
-║%s║
-║ %-*s ║
-║ %-*s ║
-╚%s╝
-`,
-		strings.Repeat(border, totalWidth),
-		maxWidth, titleLine,
-		maxWidth, "📡 普時達壓差儀 RS485 監測工具",
-		maxWidth, "🔧 支援自動掃描和多種數據格式",
-		strings.Repeat("─", totalWidth),
-		maxWidth, buildLine,
-		maxWidth, authorLine,
-		strings.Repeat(border, totalWidth),
-	)
-
-	fmt.Print(banner)
-	logger.Printf("程式啟動: %s v%s", appInfo.Name, appInfo.Version)
+	var banner strings.Builder
+	fmt.Fprintf(&banner, "\n╔%s╗\n", strings.Repeat("═", totalWidth))
+	for _, line := range header {
+		fmt.Fprintf(&banner, "║ %-*s ║\n", maxWidth, line)
+	}
+	fmt.Fprintf(&banner, "║%s║\n", strings.Repeat("─", totalWidth))
+	for _, line := range footer {
+		fmt.Fprintf(&banner, "║ %-*s ║\n", maxWidth, line)
+	}
+	fmt.Fprintf(&banner, "╚%s╝\n", strings.Repeat("═", totalWidth))
+
+	fmt.Print(banner.String())
 }
 
 // calculateDisplayWidth 計算字符串的實際顯示寬度
@@ -223,18 +520,183 @@ func printHelp() {
 	fmt.Println("📊 掃描模式:")
 	fmt.Println("  --auto-scan      自動掃描並配置第一個找到的設備")
 	fmt.Println("  --quick-scan     快速掃描常用設備配置")
+	fmt.Println("  --select-device N 快速掃描後監測第 N 台響應設備 (默認在終端中列出設備並提示選擇)")
 	fmt.Println("  --full-scan      完整掃描所有可能的設備")
+	fmt.Println("  --rescan         增量掃描：先驗證上次掃描發現的設備，全部響應時只需幾秒，否則完整掃描")
+	fmt.Println("  --scan-cache FILE 掃描緩存檔案 (默認 scan_cache.json)，完整掃描和網絡掃描後寫入，為空則不寫入")
+	fmt.Println("  --network-scan CIDR 掃描網段內的 Modbus TCP 網關和帶網口的儀表 (如 192.168.1.0/24)，探測默認和常用站點號")
+	fmt.Println("  --tcp-ports LIST 網絡掃描的 TCP 端口，以逗號分隔 (默認 502)")
+	fmt.Println("  --scan-output LIST 保存掃描結果的格式 json/csv/yaml，以逗號分隔 (默認 json；csv 可直接用 Excel 打開)")
+	fmt.Println("  --scan-config FILE 掃描配置檔案 (YAML/JSON)，取代快速、完整和自動掃描的內置配置")
+	fmt.Println("  --scan-slaves LIST 覆蓋掃描的站點號，支援範圍 (如 1-10,22)")
+	fmt.Println("  --scan-bauds LIST 覆蓋掃描的波特率 (如 9600,19200)")
+	fmt.Println("  --scan-ports LIST 覆蓋掃描的串口 (如 /dev/ttyUSB0)")
+	fmt.Println("  --scan-exclude LIST 不掃描的串口，支援通配符 (如 /dev/ttyS0,/dev/ttyAMA*)，避免干擾 PLC、GPS 等設備")
+	fmt.Println("  --resume         從檢查點恢復中斷的完整掃描")
+	fmt.Println("  --checkpoint FILE 完整掃描的進度檢查點檔案")
+	fmt.Println("  --provision --from FILE  將保存的掃描結果轉換為多設備配置檔，逐台輸入名稱、房間、樓層和資產編號")
+	fmt.Println("                   (也可寫作 pressure-meter provision --from FILE；可配合 --locations 預填位置)")
+	fmt.Println("  --devices-file FILE --provision 生成的多設備配置檔 (默認 devices.yaml)")
+	fmt.Println("  --report FILE    將掃描結果或監測趨勢（含趨勢圖）輸出為 HTML 報告")
+	fmt.Println("  --plan           只預覽掃描計劃和預計耗時，不訪問總線")
+	fmt.Println("                   不帶掃描模式時估算總線吞吐量和最小可行讀取間隔 (不可行時退出碼 1)")
+	fmt.Println("  --bus-devices N  吞吐量估算：同一總線上輪詢的設備數 (默認 1)")
+	fmt.Println("  --bus-baud BPS   吞吐量估算：波特率 (默認 9600)")
+	fmt.Println("  --bus-overhead DUR 吞吐量估算：每次事務的額外耗時 (默認 20ms)")
+	fmt.Println("  --parity-matrix  每個波特率同時嘗試 8N1 和 8E1 (出廠偶校驗的儀表)")
+	fmt.Println("  --serial-matrix  每個波特率嘗試全部校驗位和停止位組合 (8N1/8N2/8E1/8E2/8O1/8O2)，用於參數不明的現場儀表")
+	fmt.Println("  --scan-workers N 完整掃描時同時掃描的串口數 (默認 4)，每個串口上仍依次探測，1 為依次掃描")
+	fmt.Println("                   網絡掃描時同時探測的地址數 (默認 32)")
+	fmt.Println("  --probe-register ADDR 探測的寄存器地址，用於發現非普時達設備 (默認 0x0034)")
+	fmt.Println("  --probe-count N       探測讀取的寄存器數量 (默認 2)")
+	fmt.Println("  --probe-function FC   探測的功能碼 3 或 4 (默認 3)")
+	fmt.Println("  --passive-first  探測前先只接收不發送，串口上已有其他主站 (PLC 等) 輪詢時不探測，避免干擾生產總線")
+	fmt.Println("                   加上 --force 時只警告並照常探測")
+	fmt.Println("  --passive-listen TIME 每個波特率的監聽時長 (默認 2s，應長於現有主站的輪詢間隔)")
 	fmt.Println()
 
 	fmt.Println("⚙️  配置選項:")
 	fmt.Println("  --config FILE    指定配置檔案路徑")
-	fmt.Println("  --generate-config 生成配置檔案示例")
+	fmt.Println("  --device PATH    RS485 設備路徑，串口服務器 (Moxa NPort 等) 為 tcp://host:port")
+	fmt.Println("  --transport TYPE 傳輸方式 (rtu/tcp/rtuovertcp，默認 rtu)")
+	fmt.Println("  --address HOST:PORT Modbus TCP 網關地址 (默認端口 502，指定時使用 tcp 傳輸)")
+	fmt.Println("  --simulate WAVE  不連接儀表，以模擬波形生成壓力 (sine/noise/step)，讀數經正常的統計、告警和輸出流程")
+	fmt.Println("  --simulate-range MIN,MAX  模擬壓力範圍 (默認 -20,20 Pa)")
+	fmt.Println("  --simulate-period TIME    正弦週期或階躍間隔 (默認 1m)")
+	fmt.Println("  --simulate-noise PA       疊加的隨機噪聲標準差 (默認 0)")
+	fmt.Println("  --simulate-drift PA       每小時的緩慢漂移，模擬感測器零點漂移 (默認 0)")
+	fmt.Println("  --simulate-seed N         隨機數種子，固定後噪聲和機率故障可重現")
+	fmt.Println("  --simulate-faults LIST    注入通信故障，逗號分隔的 KIND[=CODE]:SCHEDULE:")
+	fmt.Println("                            KIND 為 dropout (不響應)、crc (CRC 錯誤)、exception (異常響應，默認 0x06)，")
+	fmt.Println("                            SCHEDULE 為 N (每 N 個請求)、NxB (每 N 個請求連續 B 次) 或 P% (機率)")
+	fmt.Println("  replay FILE      將記錄的原始寄存器數據按當前的設備配置檔和量程重新解析，列出與記錄不一致的讀數 (也可寫作 --replay-raw FILE)")
+	fmt.Println("                   FILE 為 --raw-data/--verbose 時 --output=json 的輸出或 --full-scan 保存的 scan_results_*.json")
+	fmt.Println("  --replay-speed N 回放速度倍數 (默認 1 按記錄的時間間隔，10 為 10 倍速，0 為不等待)")
+	fmt.Println("  --slave-id ID    Modbus 站點號 (1-247)")
+	fmt.Println("  --interval TIME  讀取間隔")
+	fmt.Println("  --format FORMAT  數據格式 (decimal/float/int16/uint16)，int16/uint16 為單寄存器的緊湊型號")
+	fmt.Println("  --device-profile NAME  設備配置檔，其他廠商的儀表可在配置檔案中用 customprofile 描述:")
+	for _, name := range pressure.DeviceProfileNames() {
+		profile, _ := pressure.GetDeviceProfile(name)
+		fmt.Printf("      %-16s %s\n", name, profile.Description)
+	}
+	fmt.Println("  --register-type TYPE  壓力寄存器類型: holding (功能碼 0x03，默認) 或 input (0x04，部分固件只提供輸入寄存器)")
+	fmt.Println("  --byte-order ORDER    壓力值的字節序: ABCD (大端) / CDAB (字交換，普時達浮點數) / BADC / DCBA (小端)")
+	fmt.Println("  --scale SCALE    壓力原始數值到 Pa 的換算係數: 0.1 (普時達十進制，默認) / 0.01 或 /100 (×100) / 1 (直接為 Pa)")
+	fmt.Println("  --min-pressure PA 有效讀數下限，超出範圍標記為無效 (默認 -50000)")
+	fmt.Println("  --max-pressure PA 有效讀數上限 (默認 50000)")
+	fmt.Println("  --timestamp WHEN 讀數時間戳取值: before=請求前, after=響應後, midpoint=中點")
+	fmt.Println("  --enable-control 允許寫入儀表：以下寫入命令、配置的 damping 和 HTTP 接口的 /api/v1/control/ 默認禁止")
+	fmt.Println("  --damping-register ADDR 儀表阻尼/濾波時間寄存器地址")
+	fmt.Println("  --set-damping N  寫入阻尼寄存器並讀回確認 (值越大響應越慢、讀數越平穩)")
+	fmt.Println("  --slave-id-register ADDR 儀表站點號寄存器地址")
+	fmt.Println("  --set-slave-id N 將 --slave-id 指定的儀表改為新站點號，以新站點號讀回確認")
+	fmt.Println("  --baud-rate BPS  串口波特率 (默認 9600)")
+	fmt.Println("  --baud-rate-register ADDR 儀表波特率寄存器地址")
+	fmt.Println("  --baud-rate-codes LIST 寄存器保存序號時各序號對應的波特率 (如 2400,4800,9600,19200)，默認直接保存波特率")
+	fmt.Println("  --set-baud-rate BPS 將儀表改為新波特率，以新波特率重新打開串口讀回確認 (總線逐台遷移到 19200 等)")
+	fmt.Println("  --zero-register ADDR 儀表零點校準命令寄存器地址")
+	fmt.Println("  --calibrate-zero 零點校準：連通兩個取壓口後執行，需配合 --force")
+	fmt.Println("  params dump FILE 讀取設備配置檔 parameters 和阻尼寄存器的設置保存到 YAML (也可寫作 --params-dump FILE)")
+	fmt.Println("  params restore FILE 將保存的設置寫回儀表 (如更換後的新儀表)，未指定 --force 時只列出差異")
+	fmt.Println("  --temperature-register ADDR 儀表溫度寄存器地址，配合配置檔案的 compensation 做溫度補償")
+	fmt.Println("  --generate-config 生成配置檔案示例 (覆蓋已有檔案前自動備份)")
+	fmt.Println("  --config-backups 列出配置檔案的自動備份 (<檔名>.<時間>.bak，保留最近 10 個)")
+	fmt.Println("  --config-rollback 用最近的備份恢復配置檔案，恢復前的內容保存為 <檔名>.before-rollback")
 	fmt.Println("  --test-config    測試配置並退出")
+	fmt.Println("  --diagnose       串行線路診斷：回顯測試和總線計數器，區分接線問題和設備故障")
+	fmt.Println("                   連接報告：打開耗時、請求延遲、原始數據和按各種格式解析的壓力值")
+	fmt.Println()
+
+	fmt.Println("📢 廣播寫入 (站點號 0，總線上所有設備都會執行且不響應):")
+	fmt.Println("  --broadcast-write REG=VALUE 寫入保持寄存器，如 0x0010=1")
+	fmt.Println("  --force          確認執行，未指定時只打印警告")
+	fmt.Println()
+
+	fmt.Println("⏱️  超時選項:")
+	fmt.Println("  --connect-timeout TIME   打開設備連接的超時時間")
+	fmt.Println("  --response-timeout TIME  每次 Modbus 請求的響應超時")
+	fmt.Println("  --latency-budget TIME    延遲預算，超出的讀數標記為降級 (degraded)")
+	fmt.Println("  --latency-violations N   連續 N 次超出延遲預算時觸發 latency_budget 告警並給出調整建議 (默認 5)")
+	fmt.Println("  --max-retries N          讀取失敗 (超時、校驗錯誤等) 時最多重試 N 次，避免總線干擾直接產生無效讀數")
+	fmt.Println("  --retry-delay TIME       讀取重試前的等待時間 (默認 100ms)")
+	fmt.Println("  --open-retry-wait TIME   打開連接失敗時按退避重試的最長時間 (轉換器開機後才出現等情況)")
+	fmt.Println("  --degraded               重試後仍無法連接時照常啟動，讀取時再連接")
+	fmt.Println("  --raw-data               在讀數中保留原始寄存器數據 (JSON 輸出 raw_data)，--verbose 時自動開啟")
+	fmt.Println("  --identify               讀取設備標識 (廠商型號寄存器或功能碼 0x2B/0x0E)，顯示在啟動信息、狀態和掃描結果中")
+	fmt.Println("  --trace FILE             將每個 Modbus 請求和響應的完整幀 (十六進制、時間戳、耗時) 追加到檔案，- 為標準錯誤")
+	fmt.Println("  --buffer-size N          讀數緩衝區可容納的讀數數量 (默認 100)")
+	fmt.Println("  --overflow-policy NAME   緩衝區已滿時: drop-oldest 丟棄最舊 (默認)、drop-newest 丟棄最新、block 暫停讀取")
+	fmt.Println("  --scan-timeout TIME      掃描時每次探測的超時時間")
+	fmt.Println("  --format-samples N       掃描時對響應設備讀取 N 次，比較十進制和浮點解析的穩定性來判斷數據格式 (默認 3，1 為只讀一次)")
+	fmt.Println("  --scan-retry-timeout TIME  第一輪超時的站點號以此超時再探測一次，重試後才響應的設備標記為通信不穩定 (marginal)")
+	fmt.Println("  --no-scan-retry          不重試超時的站點號 (掃描更快，但偶爾丟幀的儀表可能被漏掉)")
+	fmt.Println("  --scan-delay TIME        掃描時同一串口上相鄰兩次探測之間的等待時間 (轉換器連續請求時丟幀、設備被誤判為無響應時使用)")
+	fmt.Println("  --comm-profile NAME      通信時序配置檔，一次調整全部超時、讀取重試和節流:")
+	for _, name := range pressure.CommProfileNames() {
+		profile, _ := pressure.GetCommProfile(name)
+		fmt.Printf("      %-10s %s\n", name, profile.Description)
+	}
 	fmt.Println()
 
 	fmt.Println("📝 輸出選項:")
-	fmt.Println("  --output FORMAT  輸出格式 (text/json/csv)")
+	fmt.Println("  --output FORMAT  輸出格式 (text/json/csv/cbor)，cbor 為與 JSON 字段相同的緊湊二進制格式，需配合 --output-file")
+	fmt.Println("  --output-file FILE  讀數記錄追加寫入檔案而不打印到標準輸出")
+	fmt.Println("  --only-changes   文本模式下只在數值變化或狀態/告警變化時打印")
+	fmt.Println("  --change-tolerance PA  --only-changes 的變化容差 (默認 0.1 Pa)")
+	fmt.Println("  --unit-preset NAME  行業單位預設，設置文本輸出、告警描述和趨勢報告的單位、小數位數和用語")
+	fmt.Println("                      (JSON/CSV 輸出和告警上下限仍為 Pa):")
+	for _, name := range pressure.UnitPresetNames() {
+		preset, _ := pressure.GetUnitPreset(name)
+		fmt.Printf("      %-10s %s\n", name, preset.Description)
+	}
+	fmt.Println("  --sign-bands     壓力在負壓/正壓之間變化時產生 band_changed 事件")
+	fmt.Println("  --setpoint PA    統計與設定值的偏差、達標時間比例和每日超標分鐘數 (JSON 讀數帶 deviation)，")
+	fmt.Println("                   壓力在低於/位於/高於設定值 ± 容差之間變化時產生 band_changed 事件")
+	fmt.Println("  --setpoint-tolerance PA  --setpoint 的容差 (默認 2.5 Pa)")
+	fmt.Println("  --baseline FILE  按一天內的時段學習壓力基線 (保存到 FILE)，學習完成後偏離基線時觸發 baseline_anomaly 告警")
+	fmt.Println("  --baseline-sigma N  偏離基線多少個標準差視為異常 (默認 4)")
+	fmt.Println("  --stats-file FILE 每分鐘和停止時保存壓力統計、今天的最小/最大值和設定值達標統計，重啟後繼續累計")
+	fmt.Println("  --ambient SRC    大氣壓來源，附加到每個讀數以區分建築壓差變化和天氣影響：")
+	fmt.Println("                   檔案路徑 (如 BMP280 的 IIO 檔案)、exec:命令或 http(s) 天氣接口")
+	fmt.Println("  --ambient-field PATH  來源輸出 JSON 時氣壓字段的路徑 (如 current.surface_pressure)")
+	fmt.Println("  --ambient-unit UNIT   來源的氣壓單位 (默認 hPa，Linux IIO 氣壓傳感器為 kPa)")
+	fmt.Println("  --events-only    只輸出告警和區間變化事件 (適合 BMS 對接)，不輸出每個讀數")
 	fmt.Println("  --log FILE       指定日誌檔案路徑")
+	fmt.Println("  --compliance-log FILE 寫入防篡改合規日誌 (SHA-256 雜湊鏈)")
+	fmt.Println("  --compliance-rebuild-manifest  清單缺失或損壞時按日誌重建清單 (默認拒絕打開)")
+	fmt.Println("  --verify-log FILE    驗證合規日誌完整性")
+	fmt.Println("  alarms test --value PA[,PA...]  評估配置的告警規則會被哪些壓力值觸發 (也可寫作 --alarms-test)")
+	fmt.Println("  alarms test --replay FILE       按時間回放記錄的讀數，列出會產生的告警事件和各規則觸發次數")
+	fmt.Println("                   有規則觸發時退出碼為 1，可配合 --output=json")
+	fmt.Println("  --compare REF.csv --recorded FILE  與標準器記錄比對，輸出平均誤差、RMSE 和最大偏差")
+	fmt.Println("  --ref-columns T,V    參考 CSV 的時間列和壓力列 (列名或序號)，默認自動識別")
+	fmt.Println("  --ref-unit UNIT      參考記錄的壓力單位 (默認 Pa)")
+	fmt.Println("  --ref-shift TIME     參考時間的偏移 (修正時鐘差，如 -3s)")
+	fmt.Println("  --compare-tolerance TIME  對齊的最大時間差 (默認 2s)")
+	fmt.Println("  --compare-limit PA   最大偏差超出時以退出碼 1 退出")
+	fmt.Println("  --locations FILE 位置對照表 (CSV: port,slave_id,room,floor,asset_tag)")
+	fmt.Println("                   讀數、告警、腳本和掃描報告會附帶設備的安裝位置")
+	fmt.Println("  --http ADDR      啟動 HTTP 接口 (:8080 或 unix:/path 控制套接字)")
+	fmt.Println("                   GET /api/v1/value 返回最新壓力值，/api/v1/status 返回完整狀態快照")
+	fmt.Println("                   /api/v1/history 返回歷史讀數，/grafana 為 Grafana simple-json 數據源")
+	fmt.Println("                   POST /api/v1/control/{stop,calibrate-zero,write-register} 需要 --enable-control")
+	fmt.Println("  --control-addr ADDR   控制操作改在獨立地址監聽，--http 只提供只讀接口")
+	fmt.Println("  --control-token TOKEN 控制操作要求 Authorization: Bearer TOKEN (或環境變量 " + pressure.ControlTokenEnv + ")")
+	fmt.Println("                   控制操作監聽非本機地址時必須設置令牌，否則拒絕啟動")
+	fmt.Println("  --status         從 --http 指定的運行中程序獲取狀態快照 (可配合 --output=json)")
+	fmt.Println("  --watch          連接 --http 指定的運行中程序，在終端持續顯示讀數和狀態，Ctrl+C 離開")
+	fmt.Println("                   (也可寫作 pressure-meter watch --http ADDR；--output=json 時逐行輸出快照)")
+	fmt.Println("  --refresh TIME   --watch 的畫面刷新間隔 (默認 1s)")
+	fmt.Println("  --gateway ADDR   以 Modbus TCP 服務器 (如 :502) 提供最新讀數，SCADA 無需直接訪問串口")
+	fmt.Println("                   單元號對應站點號，壓力在寄存器 0 (浮點數)、2 (×10 整數) 和 0x34 (與儀表相同)")
+	fmt.Println("  --discover TIME  監測期間每隔 TIME (如 5m) 在同一總線上探測默認和常用站點號，記錄新接入和移除的儀表")
+	fmt.Println("                   每次探測與讀取串行執行，無響應的站點號會讓下一次讀取延後一個響應超時")
+	fmt.Println("  --sink-failures N  輸出目標 (HTTP、合規日誌、腳本等) 連續失敗 N 次後暫停輸出，不影響其他目標 (默認 5)")
+	fmt.Println("  --sink-retry TIME  暫停輸出後第一次重試的等待時間，重試失敗時加倍，最長 10 分鐘 (默認 30s)")
+	fmt.Println("  --sink-timeout TIME 每次寫入輸出目標的期限，卡住的目標超時後計為失敗，不阻塞讀取 (默認 10s)")
+	fmt.Println("  --xlsx FILE      匯出 Excel (掃描模式匯出掃描結果，監測模式匯出讀數和統計)")
+	fmt.Println("                   監測模式最多保留 1048575 個讀數，期間寫入 FILE.readings.jsonl，異常退出後下次運行時恢復")
 	fmt.Println("  --verbose        詳細輸出")
 	fmt.Println("  --quiet          靜默模式")
 	fmt.Println()
@@ -242,11 +704,31 @@ func printHelp() {
 	fmt.Println("🎮 控制選項:")
 	fmt.Println("  --max-readings N 最大讀數數量")
 	fmt.Println("  --duration TIME  運行時間 (如: 30s, 5m, 1h)")
+	fmt.Println("  --burst TIME     突發採樣：以此間隔 (如 50ms) 讀取 --duration 時長 (默認 30s) 後退出，")
+	fmt.Println("                   讀數緩存在記憶體中，結束後寫入 --output-file (默認 burst_時間.csv，--output=json 為 .json)")
+	fmt.Println("  --max-failures N 連續讀取失敗 N 次後執行失敗處理")
+	fmt.Println("  --on-failure ACTION 失敗處理: retry=繼續重試, exit=以退出碼 3 退出,")
+	fmt.Println("                      hook=執行 --failure-hook, failover=切換到 --backup-device,")
+	fmt.Println("                      recover=每次達到 N 次時執行下一個恢復步驟")
+	fmt.Println("  --failure-hook CMD  失敗處理腳本 (通過 PRESSURE_FAILURES 等環境變數獲取詳情)")
+	fmt.Println("  --backup-device PATH 備用設備路徑 (tcp 傳輸時為備用網關地址)")
+	fmt.Println("  --recovery-steps LIST 恢復步驟 (默認 reopen,reset,failover,fail，未配置的步驟跳過):")
+	fmt.Println("                      reopen=重新打開連接, reset=執行 --reset-command 後重新打開,")
+	fmt.Println("                      failover=切換到 --backup-device, fail=標記故障並執行 --failure-hook 通知")
+	fmt.Println("  --reset-command CMD 重置轉換器的命令 (如 usbreset 1a86:7523)，未設置時使用 --failure-hook")
+	fmt.Println("  --recovery-interval TIME 兩個恢復步驟之間的最短間隔 (默認 1m)")
 	fmt.Println("  --daemon         守護程序模式")
+	fmt.Println("  --self-test      啟動自檢: 測試讀取、數據格式、數值範圍和輸出目標")
+	fmt.Println("                   守護程序模式下未通過則以退出碼 4 退出，--force 可強制啟動")
+	fmt.Println("  --self-test-reads N 自檢的測試讀取次數 (默認 3)")
 	fmt.Println()
 
 	fmt.Println("ℹ️  信息選項:")
 	fmt.Println("  --version        顯示版本信息")
+	fmt.Println("  --no-banner      不顯示啟動橫幅 (也可在配置的 branding 中設置 banner: none)")
+	fmt.Println("  --schema         打印 JSON 輸出格式的結構描述")
+	fmt.Println("  --migrate FILE   將 --output=json 記錄的讀數檔案原地升級到當前格式版本 (修改前自動備份)")
+	fmt.Println("  decode FILE      將 --output=cbor 記錄的檔案逐條轉換為 JSON 打印 (- 為標準輸入，也可寫作 --decode FILE)")
 	fmt.Println("  --help           顯示此幫助信息")
 	fmt.Println()
 
@@ -256,6 +738,8 @@ func printHelp() {
 	fmt.Println("     export PRESSURE_SLAVE_ID=22")
 	fmt.Println("     export PRESSURE_READ_INTERVAL=1s")
 	fmt.Println("     export PRESSURE_DATA_FORMAT=decimal")
+	fmt.Println("     export PRESSURE_CONNECT_TIMEOUT=5s")
+	fmt.Println("     export PRESSURE_RESPONSE_TIMEOUT=5s")
 	fmt.Println()
 
 	fmt.Println("  2. 配置檔案 (pressure_config.yaml):")
@@ -276,6 +760,21 @@ func printHelp() {
 	fmt.Println("  # 快速掃描設備")
 	fmt.Printf("  %s --quick-scan\n", os.Args[0])
 	fmt.Println()
+	fmt.Println("  # 完整掃描並生成 HTML 報告")
+	fmt.Printf("  %s --full-scan --report=scan.html\n", os.Args[0])
+	fmt.Println()
+	fmt.Println("  # 在共用的生產總線上先預覽完整掃描的範圍")
+	fmt.Printf("  %s --full-scan --plan\n", os.Args[0])
+	fmt.Println()
+	fmt.Println("  # 繼續上次中斷的完整掃描")
+	fmt.Printf("  %s --full-scan --resume\n", os.Args[0])
+	fmt.Println()
+	fmt.Println("  # 開機時快速確認上次掃描發現的設備仍在線")
+	fmt.Printf("  %s --rescan\n", os.Args[0])
+	fmt.Println()
+	fmt.Println("  # 查找網段內的 Modbus TCP 網關")
+	fmt.Printf("  %s --network-scan=192.168.1.0/24\n", os.Args[0])
+	fmt.Println()
 	fmt.Println("  # 使用指定配置監測 5 分鐘")
 	fmt.Printf("  %s --config=my_config.yaml --duration=5m\n", os.Args[0])
 	fmt.Println()
@@ -290,11 +789,17 @@ func printHelp() {
 func runAutoScanMode(logger *log.Logger) {
 	fmt.Println("🔍 開始自動掃描壓差儀設備...")
 
-	scanner := pressure.NewScanner(logger).SetVerbose(!*quiet)
+	scanner := newScanner(logger)
+	if *scanPlan {
+		printScanPlan(scanner, scanner.PresetConfig(pressure.GetAutoScanConfig()), logger)
+		return
+	}
+
 	config, err := scanner.AutoConfigure()
 	if err != nil {
 		logger.Fatalf("❌ 自動配置失敗: %v", err)
 	}
+	applyFlagOverrides(config, nil)
 
 	fmt.Printf("✅ 自動配置成功！\n")
 	fmt.Printf("   📍 設備: %s\n", config.Device)
@@ -303,20 +808,30 @@ func runAutoScanMode(logger *log.Logger) {
 	fmt.Printf("   ⏱️  讀取間隔: %v\n", config.ReadInterval)
 
 	// 開始監測
-	startMonitoring(config, logger)
+	startMonitoring(config, nil, logger)
 }
 
 // runQuickScanMode 快速掃描模式
 func runQuickScanMode(logger *log.Logger) {
+	if *selectDevice < 0 {
+		logger.Fatalf("❌ 無效的設備序號: %d", *selectDevice)
+	}
 	fmt.Println("⚡ 開始快速掃描...")
 
-	scanner := pressure.NewScanner(logger).SetVerbose(!*quiet)
+	scanner := newScanner(logger)
+	if *scanPlan {
+		printScanPlan(scanner, scanner.PresetConfig(pressure.GetQuickScanConfig()), logger)
+		return
+	}
+
 	result, err := scanner.QuickScan()
 	if err != nil {
 		logger.Fatalf("❌ 掃描失敗: %v", err)
 	}
 
-	scanner.PrintScanResults(result)
+	loadLocations(logger).Annotate(result)
+	scanner.PrintScanResults(os.Stdout, result)
+	writeScanReport(result, logger)
 
 	// 如果找到設備，讓用戶選擇
 	responsiveDevices := getResponsiveDevices(result.Devices)
@@ -325,26 +840,89 @@ func runQuickScanMode(logger *log.Logger) {
 		return
 	}
 
-	// 使用第一個設備開始監測
-	device := responsiveDevices[0]
+	device, ok := pickScannedDevice(responsiveDevices, logger)
+	if !ok {
+		fmt.Println("👋 未選擇設備，退出")
+		return
+	}
 	config := createConfigFromDevice(device, logger)
+	applyFlagOverrides(config, nil)
 
 	fmt.Printf("\n🚀 使用設備: %s (站點 %d) 開始監測\n",
 		device.Device, device.SlaveID)
-	startMonitoring(config, logger)
+	startMonitoring(config, nil, logger)
+}
+
+// pickScannedDevice 列出響應設備並選擇要監測的一台
+//
+// 指定了 --select-device 時直接使用該序號；標準輸入為終端且有多台設備時提示選擇，
+// 直接回車使用第一台；否則使用第一台。輸入結束（如 Ctrl+D）時返回 false。
+func pickScannedDevice(devices []pressure.DeviceInfo, logger *log.Logger) (pressure.DeviceInfo, bool) {
+	fmt.Printf("\n📋 響應設備 (%d 台):\n", len(devices))
+	for i, device := range devices {
+		pressureText := "-"
+		if device.LastReading != nil {
+			pressureText = fmt.Sprintf("%.1f Pa", device.LastReading.Pressure)
+		}
+		fmt.Printf("  [%d] %s 站點 %d  壓力 %s", i+1, device.Device, device.SlaveID, pressureText)
+		if device.Location != nil {
+			fmt.Printf("  (%s)", device.Location)
+		}
+		fmt.Println()
+	}
+
+	if *selectDevice > 0 {
+		if *selectDevice > len(devices) {
+			logger.Fatalf("❌ 設備序號 %d 超出範圍，只找到 %d 台響應設備", *selectDevice, len(devices))
+		}
+		return devices[*selectDevice-1], true
+	}
+	if len(devices) == 1 || !stdinIsTerminal() {
+		return devices[0], true
+	}
+
+	input := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Printf("選擇要監測的設備 [1-%d，直接回車使用 1]: ", len(devices))
+		if !input.Scan() {
+			fmt.Println()
+			return pressure.DeviceInfo{}, false
+		}
+		text := strings.TrimSpace(input.Text())
+		if text == "" {
+			return devices[0], true
+		}
+		if n, err := strconv.Atoi(text); err == nil && n >= 1 && n <= len(devices) {
+			return devices[n-1], true
+		}
+		fmt.Printf("⚠️  無效的序號: %s\n", text)
+	}
+}
+
+// stdinIsTerminal 標準輸入是否為終端（而不是管道或檔案）
+func stdinIsTerminal() bool {
+	stat, err := os.Stdin.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
 }
 
 // runFullScanMode 完整掃描模式
 func runFullScanMode(logger *log.Logger) {
 	fmt.Println("🔍 開始完整掃描...")
 
-	scanner := pressure.NewScanner(logger).SetVerbose(!*quiet)
+	scanner := newScanner(logger).SetCheckpoint(*checkpointPath, *resumeScan).SetCache(*scanCache)
+	if *scanPlan {
+		printScanPlan(scanner, scanner.PresetConfig(pressure.GetDefaultScanConfig()), logger)
+		return
+	}
+
 	result, err := scanner.FullScan()
 	if err != nil {
 		logger.Fatalf("❌ 掃描失敗: %v", err)
 	}
 
-	scanner.PrintScanResults(result)
+	loadLocations(logger).Annotate(result)
+	scanner.PrintScanResults(os.Stdout, result)
+	writeScanReport(result, logger)
 
 	// 保存掃描結果
 	if err := saveScanResults(result); err != nil {
@@ -352,14 +930,97 @@ func runFullScanMode(logger *log.Logger) {
 	}
 }
 
+// runNetworkScanMode 網絡掃描模式，參數錯誤時返回 2，掃描失敗時返回 1
+func runNetworkScanMode(logger *log.Logger) int {
+	ports, err := pressure.ParseTCPPorts(*tcpPorts)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 2
+	}
+	if *scanPlan {
+		fmt.Println("❌ 網絡掃描不支援 --plan")
+		return 2
+	}
+
+	fmt.Printf("🌐 開始網絡掃描: %s 端口 %v\n", *networkScan, ports)
+	scanner := newScanner(logger).SetCache(*scanCache)
+	result, err := scanner.NetworkScan(*networkScan, ports)
+	if err != nil {
+		fmt.Printf("❌ 網絡掃描失敗: %v\n", err)
+		return 1
+	}
+
+	loadLocations(logger).Annotate(result)
+	scanner.PrintScanResults(os.Stdout, result)
+	writeScanReport(result, logger)
+
+	if err := saveScanResults(result); err != nil {
+		logger.Printf("⚠️  保存掃描結果失敗: %v", err)
+	}
+	return 0
+}
+
+// runRescanMode 增量掃描模式，先驗證掃描緩存中的已知設備，掃描失敗時返回 1
+func runRescanMode(logger *log.Logger) int {
+	if *scanCache == "" {
+		fmt.Println("❌ 增量掃描需要 --scan-cache")
+		return 2
+	}
+	fmt.Printf("⚡ 開始增量掃描 (緩存: %s)...\n", *scanCache)
+
+	scanner := newScanner(logger).SetCheckpoint(*checkpointPath, *resumeScan).SetCache(*scanCache)
+	result, err := scanner.Rescan()
+	if err != nil {
+		fmt.Printf("❌ 掃描失敗: %v\n", err)
+		return 1
+	}
+
+	loadLocations(logger).Annotate(result)
+	scanner.PrintScanResults(os.Stdout, result)
+	writeScanReport(result, logger)
+
+	if err := saveScanResults(result); err != nil {
+		logger.Printf("⚠️  保存掃描結果失敗: %v", err)
+	}
+	return 0
+}
+
+// printScanPlan 打印掃描計劃而不執行掃描
+func printScanPlan(scanner *pressure.Scanner, config pressure.ScanConfig, logger *log.Logger) {
+	plan, err := scanner.PlanScan(config)
+	if err != nil {
+		logger.Fatalf("❌ 生成掃描計劃失敗: %v", err)
+	}
+	plan.Print(os.Stdout)
+}
+
+// runBusPlanMode 打印總線吞吐量估算，讀取間隔不可行時返回 1
+func runBusPlanMode(logger *log.Logger) int {
+	info, err := newConfigLoader(logger).LoadConfigWithSource()
+	if err != nil {
+		fmt.Printf("❌ 載入配置失敗: %v\n", err)
+		return 2
+	}
+	config := info.Config
+	applyFlagOverrides(config, info.Source)
+
+	if config.IsTCP() {
+		fmt.Printf("💡 使用 Modbus TCP 網關 %s，網關後的串行總線需按網關設置估算\n", config.Endpoint())
+	}
+
+	plan := pressure.NewBusPlan(*config, *busDevices, *busBaudRate, *busOverhead)
+	plan.Print(os.Stdout)
+	if !plan.Feasible() {
+		return 1
+	}
+	return 0
+}
+
 // runTestConfigMode 測試配置模式
 func runTestConfigMode(logger *log.Logger) {
 	fmt.Println("🧪 測試配置...")
 
-	loader := pressure.NewConfigLoader()
-	if *configFile != "" {
-		loader.SetConfigFile(*configFile)
-	}
+	loader := newConfigLoader(logger)
 
 	info, err := loader.LoadConfigWithSource()
 	if err != nil {
@@ -367,7 +1028,7 @@ func runTestConfigMode(logger *log.Logger) {
 	}
 
 	fmt.Println("✅ 配置載入成功!")
-	loader.PrintConfigWithSource(info)
+	loader.PrintConfigWithSource(os.Stdout, info)
 
 	// 測試設備連接
 	fmt.Println("\n🔌 測試設備連接...")
@@ -377,11 +1038,18 @@ func runTestConfigMode(logger *log.Logger) {
 	}
 	defer pm.Close()
 
-	if err := pm.TestConnection(); err != nil {
+	if _, err := pm.TestConnection(); err != nil {
 		logger.Fatalf("❌ 設備連接測試失敗: %v", err)
 	}
 
 	fmt.Println("✅ 設備連接測試成功!")
+	if *identify {
+		if model, err := pm.ReadDeviceInfo(); err == nil {
+			fmt.Printf("🏷️  設備型號: %s\n", model)
+		} else {
+			fmt.Printf("⚠️  %v\n", err)
+		}
+	}
 
 	// 讀取一次數據
 	reading := pm.ReadPressure()
@@ -392,191 +1060,1467 @@ func runTestConfigMode(logger *log.Logger) {
 	}
 }
 
-// runNormalMode 正常模式
-func runNormalMode(logger *log.Logger) {
-	fmt.Println("📋 載入配置...")
-
-	loader := pressure.NewConfigLoader()
-	if *configFile != "" {
-		loader.SetConfigFile(*configFile)
-	}
-
+// runDiagnoseMode 執行串行線路診斷和連接測試，返回進程退出碼
+//
+// 串行線路診斷直接打開串口，只適用於本地 RTU；Modbus TCP、串口服務器和模擬儀表只輸出連接報告。
+func runDiagnoseMode(logger *log.Logger) int {
+	loader := newConfigLoader(logger)
 	config, err := loader.LoadConfig()
 	if err != nil {
 		fmt.Printf("❌ 載入配置失敗: %v\n", err)
-		fmt.Println("\n💡 建議:")
-		fmt.Println("   - 使用 --auto-scan 自動掃描設備")
-		fmt.Println("   - 使用 --quick-scan 快速掃描")
-		fmt.Println("   - 使用 --generate-config 生成配置檔案")
-		fmt.Println("   - 設置環境變數或創建配置文件")
-		fmt.Println("   - 使用 --help 查看詳細幫助")
-		return
+		return 2
 	}
 
-	if !*quiet {
-		loader.PrintConfig(config)
+	healthy := true
+	var lineResult *pressure.SerialDiagnostics
+	if config.ModbusTransport == nil && !config.IsTCP() && !config.IsRTUOverTCP() {
+		fmt.Println("🩺 串行線路診斷...")
+		if lineResult, err = pressure.RunSerialDiagnostics(*config); err != nil {
+			fmt.Printf("❌ 診斷失敗: %v\n", err)
+			return 2
+		}
+		healthy = lineResult.Healthy
 	}
 
-	startMonitoring(config, logger)
-}
-
-// startMonitoring 開始監測壓力
-func startMonitoring(config *pressure.Config, logger *log.Logger) {
-	fmt.Println("🚀 啟動壓差儀監測...")
-
-	// 創建壓差儀實例
+	// 串口已由線路診斷關閉，再以正常的讀取流程打開
+	fmt.Println("🔌 連接測試...")
 	pm, err := pressure.NewPressureMeter(*config)
 	if err != nil {
-		logger.Fatalf("❌ 創建壓差儀失敗: %v", err)
+		fmt.Printf("❌ 創建設備失敗: %v\n", err)
+		return 2
 	}
 	defer pm.Close()
-
-	// 測試連接
-	if err := pm.TestConnection(); err != nil {
-		logger.Fatalf("❌ 設備連接失敗: %v", err)
+	report, err := pm.TestConnection()
+	if err != nil {
+		healthy = false
 	}
 
-	// 創建上下文和取消函數
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	// 如果設置了運行時間限制
-	if *duration > 0 {
-		ctx, cancel = context.WithTimeout(ctx, *duration)
-		defer cancel()
+	if *outputFormat == "json" {
+		result := map[string]interface{}{
+			"schema_version": pressure.SchemaVersion,
+			"connection":     report,
+		}
+		if lineResult != nil {
+			result["diagnostics"] = lineResult
+		}
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		if lineResult != nil {
+			lineResult.Print(os.Stdout)
+			fmt.Println()
+		}
+		report.Print(os.Stdout)
 	}
 
-	// 開始讀取
-	pm.Start(config.ReadInterval)
+	if !healthy {
+		return 1
+	}
+	return 0
+}
 
-	// 創建信號通道，用於優雅關閉
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+// runBurstMode 突發採樣並將讀數寫入檔案，返回進程退出碼
+func runBurstMode(logger *log.Logger) int {
+	loader := newConfigLoader(logger)
+	config, err := loader.LoadConfig()
+	if err != nil {
+		fmt.Printf("❌ 載入配置失敗: %v\n", err)
+		return 2
+	}
 
-	if !*quiet {
-		fmt.Println("📊 開始實時監測壓力數據...")
-		if *duration > 0 {
-			fmt.Printf("⏰ 運行時間: %v\n", *duration)
+	// 突發採樣不經過監測循環，逐個讀取的日誌只在詳細模式下輸出
+	if !*verbose {
+		config.Logger = log.New(io.Discard, "", 0)
+	}
+	if config.ModbusTransport == nil && !config.IsTCP() {
+		planConfig := *config
+		planConfig.ReadInterval = *burstInterval
+		if plan := pressure.NewBusPlan(planConfig, 1, config.SerialBaudRate(), *busOverhead); !plan.Feasible() {
+			fmt.Printf("⚠️  %s\n", plan.Warning())
 		}
-		if *maxReadings > 0 {
-			fmt.Printf("📈 最大讀數: %d\n", *maxReadings)
+	}
+
+	filename := *outputFile
+	if filename == "" {
+		ext := "csv"
+		if *outputFormat == "json" {
+			ext = "json"
 		}
-		fmt.Println("   按 Ctrl+C 停止監測")
-		fmt.Println()
+		filename = fmt.Sprintf("burst_%s.%s", time.Now().Format("20060102_150405"), ext)
 	}
 
-	// 統計信息
-	stats := &pressure.Statistics{}
-	readingCount := 0
+	pm, err := pressure.NewPressureMeter(*config)
+	if err != nil {
+		fmt.Printf("❌ 創建設備失敗: %v\n", err)
+		return 2
+	}
+	defer pm.Close()
 
-	// 處理讀數
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case reading := <-pm.GetReadings():
-				readingCount++
-
-				if reading.Valid {
-					stats.Update(reading.Pressure)
-					outputReading(reading, readingCount, stats)
-				} else {
-					outputError(reading, readingCount)
-				}
-
-				// 檢查是否達到最大讀數
-				if *maxReadings > 0 && readingCount >= *maxReadings {
-					logger.Printf("已達到最大讀數限制: %d", *maxReadings)
-					cancel()
-					return
-				}
-			}
-		}
+		<-sigChan
+		cancel()
 	}()
 
-	// 等待退出信號或超時
-	select {
-	case <-ctx.Done():
-		if ctx.Err() == context.DeadlineExceeded {
-			fmt.Printf("\n⏰ 已達到運行時間限制: %v\n", *duration)
-		}
-	case sig := <-sigChan:
-		fmt.Printf("\n🛑 接收到信號: %v\n", sig)
+	duration := *duration
+	if duration == 0 {
+		duration = pressure.DefaultBurstDuration
+	}
+	fmt.Printf("⚡ 突發採樣: 每 %v 讀取一次，持續 %v (Ctrl+C 提前結束並保存)...\n", *burstInterval, duration)
+	capture, err := pm.CaptureBurst(ctx, *burstInterval, duration)
+	if err != nil {
+		fmt.Printf("❌ 突發採樣失敗: %v\n", err)
+		return 2
 	}
 
-	fmt.Println("🛑 正在停止監測...")
-	pm.Stop()
-
-	// 打印統計信息
-	if !*quiet && readingCount > 0 {
-		fmt.Println("\n📊 監測統計:")
-		fmt.Printf("   📈 總讀數: %d\n", readingCount)
-		fmt.Printf("   ⏱️  運行時間: %v\n", time.Since(stats.LastTime.Add(-time.Duration(readingCount)*config.ReadInterval)))
-		fmt.Printf("   📊 %s\n", stats)
+	file, err := os.Create(filename)
+	if err != nil {
+		fmt.Printf("❌ 創建檔案失敗: %v\n", err)
+		return 1
+	}
+	if *outputFormat == "json" {
+		err = capture.WriteJSON(file)
+	} else {
+		err = capture.WriteCSV(file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Printf("❌ 寫入 %s 失敗: %v\n", filename, err)
+		return 1
 	}
 
-	fmt.Println("✅ 監測已停止")
+	capture.Print(os.Stdout)
+	fmt.Printf("💾 %d 個讀數已保存到: %s\n", len(capture.Readings), filename)
+	if capture.Stats.Count == 0 {
+		return 1
+	}
+	return 0
 }
 
-// outputReading 輸出壓力讀數
-func outputReading(reading pressure.PressureReading, count int, stats *pressure.Statistics) {
-	timestamp := reading.Timestamp.Format("15:04:05")
+// runSetDampingMode 設置儀表阻尼時間，返回進程退出碼
+func runSetDampingMode(logger *log.Logger) int {
+	value, err := strconv.ParseUint(*setDamping, 0, 16)
+	if err != nil {
+		fmt.Printf("❌ 無效的阻尼值: %s\n", *setDamping)
+		return 2
+	}
 
-	switch *outputFormat {
-	case "json":
-		data := map[string]interface{}{
-			"timestamp": reading.Timestamp,
-			"count":     count,
-			"slave_id":  reading.SlaveID,
-			"pressure":  reading.Pressure,
-			"unit":      "Pa",
-			"valid":     reading.Valid,
-		}
-		jsonData, _ := json.Marshal(data)
-		fmt.Println(string(jsonData))
+	loader := newConfigLoader(logger)
+	config, err := loader.LoadConfig()
+	if err != nil {
+		fmt.Printf("❌ 載入配置失敗: %v\n", err)
+		return 2
+	}
+	if config.DampingRegister == 0 {
+		fmt.Println("❌ 未指定阻尼寄存器，請使用 --damping-register 或配置 dampingregister")
+		return 2
+	}
+	if !requireControl(config) {
+		return 2
+	}
 
-	case "csv":
-		if count == 1 {
-			fmt.Println("timestamp,count,slave_id,pressure,unit,valid")
-		}
-		fmt.Printf("%s,%d,%d,%.3f,Pa,%t\n",
-			reading.Timestamp.Format("2006-01-02 15:04:05"),
-			count, reading.SlaveID, reading.Pressure, reading.Valid)
+	// 由本命令負責寫入，避免創建設備時重複套用配置中的阻尼值
+	config.Damping = nil
+	pm, err := pressure.NewPressureMeter(*config)
+	if err != nil {
+		fmt.Printf("❌ 創建設備失敗: %v\n", err)
+		return 2
+	}
+	defer pm.Close()
 
-	default: // text
-		if !*quiet {
-			fmt.Printf("[%s] #%d 站點%d: %.2f Pa (平均: %.2f Pa)\n",
-				timestamp, count, reading.SlaveID, reading.Pressure, stats.Mean)
-		}
+	if current, err := pm.GetDamping(); err == nil {
+		fmt.Printf("🎛️  當前阻尼值: %d\n", current)
 	}
+	if err := pm.SetDamping(uint16(value)); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	fmt.Printf("✅ 阻尼值已設置為 %d (寄存器 0x%04X，已讀回確認)\n", value, config.DampingRegister)
+	return 0
 }
 
-// outputError 輸出錯誤信息
-func outputError(reading pressure.PressureReading, count int) {
-	timestamp := reading.Timestamp.Format("15:04:05")
-
+// runSetSlaveIDMode 修改儀表站點號，返回進程退出碼
+func runSetSlaveIDMode(logger *log.Logger) int {
+	newID, err := strconv.ParseUint(*setSlaveID, 0, 8)
+	if err != nil {
+		fmt.Printf("❌ 無效的新站點號: %s\n", *setSlaveID)
+		return 2
+	}
+	if err := pressure.CheckPollingSlaveID(byte(newID)); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 2
+	}
+
+	loader := newConfigLoader(logger)
+	config, err := loader.LoadConfig()
+	if err != nil {
+		fmt.Printf("❌ 載入配置失敗: %v\n", err)
+		return 2
+	}
+	if config.SlaveIDRegister == 0 {
+		fmt.Println("❌ 未指定站點號寄存器，請使用 --slave-id-register 或配置 slaveidregister")
+		return 2
+	}
+	if !requireControl(config) {
+		return 2
+	}
+
+	config.Damping = nil
+	pm, err := pressure.NewPressureMeter(*config)
+	if err != nil {
+		fmt.Printf("❌ 創建設備失敗: %v\n", err)
+		return 2
+	}
+	defer pm.Close()
+
+	fmt.Printf("🏷️  修改站點號: %s 站點 %d → %d (寄存器 0x%04X)\n", config.Endpoint(), config.SlaveID, newID, config.SlaveIDRegister)
+	if err := pm.SetSlaveAddress(byte(newID)); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	reading := pm.ReadPressure()
+	if !reading.Valid {
+		fmt.Printf("⚠️  站點號已修改，但以新站點號讀取壓力失敗: %s\n", reading.Error)
+		return 1
+	}
+	fmt.Printf("✅ 站點號已改為 %d，已讀回確認 (壓力 %.2f Pa)\n", newID, reading.Pressure)
+	fmt.Printf("💡 請更新配置中的 slaveid 或使用 --slave-id=%d\n", newID)
+	return 0
+}
+
+// runSetBaudRateMode 修改儀表波特率，返回進程退出碼
+func runSetBaudRateMode(logger *log.Logger) int {
+	newRate, err := strconv.Atoi(*setBaudRate)
+	if err != nil || !pressure.IsValidBaudRate(newRate) {
+		fmt.Printf("❌ 無效的新波特率: %s，支援: %v\n", *setBaudRate, pressure.GetSupportedBaudRates())
+		return 2
+	}
+
+	loader := newConfigLoader(logger)
+	config, err := loader.LoadConfig()
+	if err != nil {
+		fmt.Printf("❌ 載入配置失敗: %v\n", err)
+		return 2
+	}
+	if config.BaudRateRegister == 0 {
+		fmt.Println("❌ 未指定波特率寄存器，請使用 --baud-rate-register 或配置 baudrateregister")
+		return 2
+	}
+	if !requireControl(config) {
+		return 2
+	}
+
+	config.Damping = nil
+	pm, err := pressure.NewPressureMeter(*config)
+	if err != nil {
+		fmt.Printf("❌ 創建設備失敗: %v\n", err)
+		return 2
+	}
+	defer pm.Close()
+
+	fmt.Printf("📶 修改波特率: %s 站點 %d, %d → %d (寄存器 0x%04X)\n",
+		config.Endpoint(), config.SlaveID, pm.BaudRate(), newRate, config.BaudRateRegister)
+	if err := pm.SetBaudRate(newRate); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	reading := pm.ReadPressure()
+	if !reading.Valid {
+		fmt.Printf("⚠️  波特率已修改，但以新波特率讀取壓力失敗: %s\n", reading.Error)
+		return 1
+	}
+	fmt.Printf("✅ 波特率已改為 %d，已讀回確認 (壓力 %.2f Pa)\n", newRate, reading.Pressure)
+	fmt.Printf("💡 請更新配置中的 baudrate 或使用 --baud-rate=%d；同一總線上的其他儀表也需逐台修改\n", newRate)
+	return 0
+}
+
+// runCalibrateZeroMode 執行零點校準並讀取校準前後的壓力，返回進程退出碼
+func runCalibrateZeroMode(logger *log.Logger) int {
+	loader := newConfigLoader(logger)
+	config, err := loader.LoadConfig()
+	if err != nil {
+		fmt.Printf("❌ 載入配置失敗: %v\n", err)
+		return 2
+	}
+	if config.ZeroRegister == 0 {
+		fmt.Println("❌ 未指定零點校準寄存器，請使用 --zero-register 或配置 zeroregister")
+		return 2
+	}
+	if !requireControl(config) {
+		return 2
+	}
+
+	fmt.Printf("🎯 零點校準: %s 站點 %d, 寄存器 0x%04X\n", config.Endpoint(), config.SlaveID, config.ZeroRegister)
+	fmt.Println("⚠️  儀表會以當前壓差作為新的零點，請先用軟管連通兩個取壓口（或都通大氣）並等待讀數穩定")
+	if !*force {
+		fmt.Println("❌ 未指定 --force，已取消。確認取壓口已連通後加上 --force 重新執行")
+		return 2
+	}
+
+	config.Damping = nil
+	pm, err := pressure.NewPressureMeter(*config)
+	if err != nil {
+		fmt.Printf("❌ 創建設備失敗: %v\n", err)
+		return 2
+	}
+	defer pm.Close()
+
+	if before := pm.ReadPressure(); before.Valid {
+		fmt.Printf("📏 校準前: %.2f Pa\n", before.Pressure)
+	} else {
+		fmt.Printf("⚠️  校準前讀取失敗: %s\n", before.Error)
+	}
+
+	if err := pm.ZeroCalibrate(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	fmt.Printf("⏳ 已發送校準命令，等待 %v...\n", pressure.ZeroSettleTime)
+	time.Sleep(pressure.ZeroSettleTime)
+
+	after := pm.ReadPressure()
+	if !after.Valid {
+		fmt.Printf("❌ 校準後讀取失敗: %s\n", after.Error)
+		return 1
+	}
+	fmt.Printf("📏 校準後: %.2f Pa\n", after.Pressure)
+	if math.Abs(after.Pressure) > pressure.ZeroTolerance {
+		fmt.Printf("⚠️  校準後讀數偏離 0 超過 %.2f Pa，請確認取壓口已連通、儀表支援此命令後重試\n", pressure.ZeroTolerance)
+		return 1
+	}
+	fmt.Println("✅ 零點校準完成")
+	return 0
+}
+
+// runBroadcastWriteMode 以廣播地址寫入寄存器，返回進程退出碼
+func runBroadcastWriteMode(logger *log.Logger) int {
+	register, value, err := parseRegisterWrite(*broadcastWrite)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 2
+	}
+
+	loader := newConfigLoader(logger)
+	config, err := loader.LoadConfig()
+	if err != nil {
+		fmt.Printf("❌ 載入配置失敗: %v\n", err)
+		return 2
+	}
+	if !requireControl(config) {
+		return 2
+	}
+
+	fmt.Printf("📢 廣播寫入: 串口 %s, 寄存器 0x%04X = %d (0x%04X)\n", config.Device, register, value, value)
+	fmt.Println("⚠️  站點號 0 為廣播地址，總線上的所有設備都會執行此寫入")
+	fmt.Println("⚠️  廣播沒有響應，無法確認各設備是否執行成功，寫入後請逐台讀回確認")
+	if !*force {
+		fmt.Println("❌ 未指定 --force，已取消。確認無誤後加上 --force 重新執行")
+		return 2
+	}
+
+	if err := pressure.BroadcastWriteRegister(*config, register, value); err != nil {
+		fmt.Printf("❌ 廣播寫入失敗: %v\n", err)
+		return 1
+	}
+	fmt.Println("✅ 廣播已發送")
+	return 0
+}
+
+// openParamsMeter 按配置創建讀寫設置寄存器的壓差儀
+func openParamsMeter(logger *log.Logger) (*pressure.PressureMeter, error) {
+	loader := newConfigLoader(logger)
+	config, err := loader.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("載入配置失敗: %v", err)
+	}
+	// 由本命令負責寫入，避免創建設備時套用配置中的阻尼值
+	config.Damping = nil
+	pm, err := pressure.NewPressureMeter(*config)
+	if err != nil {
+		return nil, fmt.Errorf("創建設備失敗: %v", err)
+	}
+	return pm, nil
+}
+
+// runParamsDumpMode 讀取儀表的全部設置寄存器保存到檔案，返回進程退出碼
+func runParamsDumpMode(logger *log.Logger) int {
+	pm, err := openParamsMeter(logger)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 2
+	}
+	defer pm.Close()
+
+	dump, err := pm.DumpParameters()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	dump.Print(os.Stdout)
+	if err := dump.Save(*paramsDump); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	fmt.Printf("✅ %d 項設置已保存到 %s\n", len(dump.Parameters), *paramsDump)
+	return 0
+}
+
+// runParamsRestoreMode 將參數檔案中的設置寫回儀表，返回進程退出碼
+//
+// 未指定 --force 時只列出與當前值不同的設置，不寫入。
+func runParamsRestoreMode(logger *log.Logger) int {
+	dump, err := pressure.LoadParameterDump(*paramsRestore)
+	if err != nil {
+		fmt.Printf("❌ 讀取參數檔案失敗: %v\n", err)
+		return 2
+	}
+	pm, err := openParamsMeter(logger)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 2
+	}
+	defer pm.Close()
+	if *force && !pm.ControlEnabled() {
+		fmt.Println(controlDisabledHint)
+		return 2
+	}
+
+	fmt.Printf("📥 參數檔案: %s (讀取自 %s 站點 %d，%s)\n", *paramsRestore, dump.Device, dump.SlaveID,
+		dump.DumpedAt.Format("2006-01-02 15:04:05"))
+	if dump.Model != "" {
+		fmt.Printf("   原儀表: %s\n", dump.Model)
+	}
+	changes, err := pm.RestoreParameters(dump, *force)
+	pending := 0
+	for _, change := range changes {
+		switch {
+		case change.Skipped != "":
+			fmt.Printf("   ⏭️  %-16s 0x%04X 跳過: %s\n", change.Name, change.Register, change.Skipped)
+		case change.Error != "":
+			fmt.Printf("   ❌ %-16s 0x%04X %s\n", change.Name, change.Register, change.Error)
+		case !change.Changed():
+			fmt.Printf("   ✅ %-16s 0x%04X = %v (相同)\n", change.Name, change.Register, change.Target)
+		case change.Written:
+			fmt.Printf("   ✍️  %-16s 0x%04X %v → %v (已讀回確認)\n", change.Name, change.Register, change.Current, change.Target)
+		default:
+			pending++
+			fmt.Printf("   🔸 %-16s 0x%04X %v → %v\n", change.Name, change.Register, change.Current, change.Target)
+		}
+	}
+	if err != nil {
+		fmt.Printf("❌ 恢復中止: %v\n", err)
+		return 1
+	}
+	if pending > 0 {
+		fmt.Printf("❌ 未指定 --force，%d 項設置未寫入。確認無誤後加上 --force 重新執行\n", pending)
+		return 2
+	}
+	fmt.Println("✅ 儀表設置與參數檔案一致")
+	return 0
+}
+
+// controlDisabledHint 未啟用控制操作時寫入命令的提示
+const controlDisabledHint = "❌ 此命令會寫入儀表，默認禁止 (只監測的部署不向總線寫入)。確認後加上 --enable-control 或配置 enablecontrol: true"
+
+// requireControl 寫入儀表的命令在配置未啟用控制操作時打印提示並返回 false
+func requireControl(config *pressure.Config) bool {
+	if config.EnableControl {
+		return true
+	}
+	fmt.Println(controlDisabledHint)
+	return false
+}
+
+// parseRegisterWrite 解析 REG=VALUE 格式的寄存器寫入，支援十進制和十六進制
+func parseRegisterWrite(s string) (uint16, uint16, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("寄存器寫入格式應為 REG=VALUE: %s", s)
+	}
+	register, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 0, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("無效的寄存器地址: %s", parts[0])
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 0, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("無效的寄存器值: %s", parts[1])
+	}
+	return uint16(register), uint16(value), nil
+}
+
+// runStatusMode 獲取運行中監測程序的狀態快照，返回進程退出碼
+func runStatusMode() int {
+	if *httpAddr == "" {
+		fmt.Println("❌ 請用 --http 指定監測程序的 HTTP 地址或 unix: 控制套接字")
+		return 2
+	}
+
+	snapshot, err := pressure.FetchStatus(*httpAddr)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	if *outputFormat == "json" {
+		data, _ := json.MarshalIndent(snapshot, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		snapshot.Print(os.Stdout)
+	}
+	return 0
+}
+
+// runWatchMode 持續顯示運行中監測程序的讀數和狀態，直到 Ctrl+C，返回進程退出碼
+func runWatchMode() int {
+	if *httpAddr == "" {
+		fmt.Println("❌ 請用 --http 指定監測程序的 HTTP 地址或 unix: 控制套接字")
+		return 2
+	}
+
+	watcher := pressure.NewWatcher(*httpAddr)
+	if _, err := watcher.Poll(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	stop := make(chan struct{})
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		close(stop)
+	}()
+
+	if *outputFormat == "json" {
+		// 逐行輸出快照，便於管道處理
+		ticker := time.NewTicker(*watchRefresh)
+		defer ticker.Stop()
+		for {
+			if snapshot, err := watcher.Poll(); err == nil {
+				data, _ := json.Marshal(snapshot)
+				fmt.Println(string(data))
+			} else {
+				fmt.Fprintf(os.Stderr, "⚠️  %v\n", err)
+			}
+			select {
+			case <-stop:
+				return 0
+			case <-ticker.C:
+			}
+		}
+	}
+
+	watcher.Run(os.Stdout, *watchRefresh, stop)
+	fmt.Println("\n👋 已離開，監測程序繼續運行")
+	return 0
+}
+
+// runProvisionMode 將掃描結果轉換為多設備配置檔，返回進程退出碼
+//
+// 標準輸入為終端時逐台提示輸入名稱和位置（直接回車保留默認值），否則全部使用默認值。
+func runProvisionMode(logger *log.Logger) int {
+	if *provisionFrom == "" {
+		fmt.Println("❌ 請用 --from 指定掃描結果檔案 (--full-scan 保存的 scan_results_*.json)")
+		return 2
+	}
+	result, err := pressure.LoadScanResult(*provisionFrom)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 2
+	}
+	loadLocations(logger).Annotate(result)
+
+	devices, warnings := pressure.ProvisionFromScan(result)
+	if len(devices.Devices) == 0 {
+		fmt.Println("❌ 掃描結果中沒有響應的設備")
+		return 1
+	}
+	fmt.Printf("📋 掃描結果中有 %d 台響應的設備\n", len(devices.Devices))
+
+	if stdinIsTerminal() {
+		fmt.Println("💡 逐台輸入名稱和位置，直接回車保留 [] 中的默認值")
+		input := bufio.NewScanner(os.Stdin)
+		eof := false
+		prompt := func(label, value string) string {
+			if eof {
+				return value
+			}
+			fmt.Printf("   %s [%s]: ", label, value)
+			if !input.Scan() {
+				// 輸入結束（如 Ctrl+D），其餘設備使用默認值
+				eof = true
+				fmt.Println()
+				return value
+			}
+			if text := strings.TrimSpace(input.Text()); text != "" {
+				return text
+			}
+			return value
+		}
+		for i := range devices.Devices {
+			pd := &devices.Devices[i]
+			fmt.Printf("\n🔌 [%d/%d] %s 站點 %d (%s)\n", i+1, len(devices.Devices), pd.Config.Device, pd.Config.SlaveID, pd.Config.DataFormat)
+			pd.Name = prompt("名稱", pd.Name)
+			pd.Room = prompt("房間", pd.Room)
+			pd.Floor = prompt("樓層", pd.Floor)
+			pd.AssetTag = prompt("資產編號", pd.AssetTag)
+		}
+		fmt.Println()
+	}
+
+	for _, warning := range warnings {
+		fmt.Printf("⚠️  %s\n", warning)
+	}
+	if err := devices.Validate(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	if err := devices.Save(*devicesFile); err != nil {
+		fmt.Printf("❌ 保存多設備配置失敗: %v\n", err)
+		return 1
+	}
+	fmt.Printf("💾 已生成多設備配置: %s (%d 台設備，%d 條總線)\n", *devicesFile, len(devices.Devices), len(devices.ManagedDevices()))
+	return 0
+}
+
+// runAlarmsTestMode 用指定的壓力值或記錄的讀數評估配置的告警規則，有規則觸發時返回 1
+func runAlarmsTestMode(logger *log.Logger) int {
+	if *alarmValues == "" && *alarmReplay == "" {
+		fmt.Println("❌ 請用 --value 指定壓力值 (多個值以逗號分隔) 或用 --replay 指定讀數記錄")
+		return 2
+	}
+	info, err := newConfigLoader(logger).LoadConfigWithSource()
+	if err != nil {
+		fmt.Printf("❌ 載入配置失敗: %v\n", err)
+		return 2
+	}
+	config := info.Config
+	applyFlagOverrides(config, info.Source)
+	if len(config.Alarms) == 0 {
+		fmt.Println("❌ 配置中沒有告警規則 (alarms)")
+		return 2
+	}
+	preset, err := pressure.GetUnitPreset(config.UnitPreset)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 2
+	}
+
+	var tests []*pressure.AlarmTest
+	if *alarmValues != "" {
+		for _, field := range strings.Split(*alarmValues, ",") {
+			value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+			if err != nil {
+				fmt.Printf("❌ 無效的壓力值: %s\n", field)
+				return 2
+			}
+			tests = append(tests, pressure.TestAlarmRules(config.Alarms, preset, value))
+		}
+	}
+	var replay *pressure.AlarmReplay
+	if *alarmReplay != "" {
+		points, err := pressure.LoadRecordedFile(*alarmReplay)
+		if err != nil {
+			fmt.Printf("❌ 讀取讀數記錄失敗: %v\n", err)
+			return 2
+		}
+		replay = pressure.ReplayAlarms(config.Alarms, preset, points)
+	}
+
+	fired := replay != nil && replay.Triggered()
+	for _, test := range tests {
+		fired = fired || len(test.Fired()) > 0
+	}
+
+	if *outputFormat == "json" {
+		data, _ := json.MarshalIndent(map[string]interface{}{
+			"values": tests,
+			"replay": replay,
+		}, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("🧪 評估 %d 條告警規則\n", len(config.Alarms))
+		for _, test := range tests {
+			test.Print(os.Stdout)
+		}
+		if replay != nil {
+			fmt.Printf("⏪ 回放 %s\n", *alarmReplay)
+			replay.Print(os.Stdout)
+		}
+	}
+	if fired {
+		return 1
+	}
+	return 0
+}
+
+// runReplayMode 按當前配置重新解析記錄的原始寄存器數據，有讀數與記錄時不一致時返回 1
+func runReplayMode(logger *log.Logger) int {
+	config, err := newConfigLoader(logger).LoadConfig()
+	if err != nil {
+		fmt.Printf("❌ 載入配置失敗: %v\n", err)
+		return 2
+	}
+	profile, err := pressure.ResolveDeviceProfile(*config)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 2
+	}
+	replay := config.ModbusTransport.(*pressure.ReplayTransport)
+	if *outputFormat != "json" {
+		fmt.Printf("⏪ 回放 %s: %d 組原始數據，按設備配置檔 %s 解析", *replayRaw, len(replay.Frames()), profile.Name)
+		if *replaySpeed > 0 {
+			fmt.Printf("，%gx 速度\n", *replaySpeed)
+		} else {
+			fmt.Println("，不等待")
+		}
+	}
+
+	summary, err := pressure.ReplayRecording(*config, func(result pressure.ReplayResult) {
+		frame, reading := result.Frame, result.Reading
+		if *outputFormat == "json" {
+			data := map[string]interface{}{
+				"source":         frame.Source,
+				"timestamp":      frame.Time,
+				"slave_id":       frame.SlaveID,
+				"raw_data":       fmt.Sprintf("% X", frame.Data),
+				"pressure":       reading.Pressure,
+				"valid":          reading.Valid,
+				"recorded_valid": frame.Valid,
+				"changed":        result.Changed(),
+			}
+			if frame.Pressure != nil {
+				data["recorded_pressure"] = *frame.Pressure
+			}
+			if !reading.Valid {
+				data["error"] = reading.Error
+				data["error_code"] = reading.ErrorCode.String()
+			}
+			writeRecord(os.Stdout, data)
+			return
+		}
+		if *quiet && !result.Changed() {
+			return
+		}
+		parsed := fmt.Sprintf("%.2f Pa", reading.Pressure)
+		if !reading.Valid {
+			parsed = "❌ " + reading.Error
+		}
+		recorded := ""
+		if frame.Pressure != nil {
+			recorded = fmt.Sprintf(" (記錄: %.2f Pa)", *frame.Pressure)
+			if !frame.Valid {
+				recorded = fmt.Sprintf(" (記錄: 無效 %.2f Pa)", *frame.Pressure)
+			}
+		}
+		mark := ""
+		if result.Changed() {
+			mark = " ⚠️  不一致"
+		}
+		fmt.Printf("[%s] %s 站點%d: % X → %s%s%s\n", frame.Time.Format("2006-01-02 15:04:05"),
+			frame.Source, frame.SlaveID, frame.Data, parsed, recorded, mark)
+	})
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	if *outputFormat != "json" {
+		summary.Print(os.Stdout)
+	}
+	if summary.Changed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runVerifyLogMode 驗證合規日誌，返回進程退出碼
+func runVerifyLogMode(path string) int {
+	fmt.Printf("🔏 驗證合規日誌: %s\n", path)
+
+	result, err := pressure.VerifyComplianceLog(path)
+	if err != nil {
+		fmt.Printf("❌ 驗證失敗: %v\n", err)
+		return 2
+	}
+
+	if *outputFormat == "json" {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+	}
+
+	if !result.Valid {
+		fmt.Printf("❌ 日誌完整性驗證未通過: %s\n", result.Problem)
+		fmt.Printf("   已驗證記錄: %d\n", result.Records)
+		switch {
+		case result.ManifestBehind:
+			fmt.Println("   最後一條記錄完整且接續雜湊鏈，用 --compliance-log 打開日誌時會自動更新清單")
+		case result.ManifestMissing:
+			fmt.Println("   雜湊鏈本身完整，人工核對後可用 --compliance-log 加 --compliance-rebuild-manifest 重建清單")
+		case result.TornTail:
+			fmt.Println("   最後一行是寫入時中斷留下的殘片，用 --compliance-log 打開日誌時會移到 .torn 檔案並截斷")
+		}
+		return 1
+	}
+
+	fmt.Printf("✅ 日誌完整: %d 條記錄\n", result.Records)
+	fmt.Printf("   鏈頭雜湊: %s\n", result.HeadHash)
+	return 0
+}
+
+// runCompareMode 與參考記錄儀的 CSV 比對，返回進程退出碼
+func runCompareMode() int {
+	if *recordedFile == "" {
+		fmt.Println("❌ --compare 需要用 --recorded 指定本工具記錄的讀數")
+		return 2
+	}
+	unit, err := pressure.ParsePressureUnit(*refUnit)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 2
+	}
+	opts := pressure.CompareOptions{Unit: unit, Shift: *refShift, Tolerance: *compareTol}
+	if *refColumns != "" {
+		columns := strings.Split(*refColumns, ",")
+		if len(columns) != 2 {
+			fmt.Printf("❌ 無效的參考列: %s (格式: 時間列,壓力列)\n", *refColumns)
+			return 2
+		}
+		opts.TimeColumn, opts.ValueColumn = columns[0], columns[1]
+	}
+
+	fmt.Printf("📐 比對參考記錄 %s 與 %s\n", *compareRef, *recordedFile)
+	reference, err := pressure.LoadReferenceFile(*compareRef, opts)
+	if err != nil {
+		fmt.Printf("❌ 讀取參考記錄失敗: %v\n", err)
+		return 2
+	}
+	recorded, err := pressure.LoadRecordedFile(*recordedFile)
+	if err != nil {
+		fmt.Printf("❌ 讀取記錄失敗: %v\n", err)
+		return 2
+	}
+
+	result := pressure.CompareReadings(reference, recorded, opts)
+	if *outputFormat == "json" {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		result.Print(os.Stdout)
+	}
+
+	if result.Matched == 0 {
+		fmt.Println("❌ 沒有可對齊的記錄，請檢查兩份記錄的時間範圍、時區或用 --ref-shift 修正時鐘差")
+		return 1
+	}
+	if *compareLimit > 0 {
+		if math.Abs(result.MaxDeviation) > *compareLimit {
+			fmt.Printf("❌ 最大偏差 %.3f Pa 超出允許值 %.3f Pa\n", math.Abs(result.MaxDeviation), *compareLimit)
+			return 1
+		}
+		fmt.Printf("✅ 最大偏差在允許值 %.3f Pa 內\n", *compareLimit)
+	}
+	return 0
+}
+
+// runNormalMode 正常模式
+func runNormalMode(logger *log.Logger) {
+	fmt.Println("📋 載入配置...")
+
+	loader := newConfigLoader(logger)
+
+	info, err := loader.LoadConfigWithSource()
+	if err != nil {
+		fmt.Printf("❌ 載入配置失敗: %v\n", err)
+		fmt.Println("\n💡 建議:")
+		fmt.Println("   - 使用 --auto-scan 自動掃描設備")
+		fmt.Println("   - 使用 --quick-scan 快速掃描")
+		fmt.Println("   - 使用 --generate-config 生成配置檔案")
+		fmt.Println("   - 設置環境變數或創建配置文件")
+		fmt.Println("   - 使用 --help 查看詳細幫助")
+		return
+	}
+	config := info.Config
+
+	if !*quiet {
+		loader.PrintConfig(os.Stdout, config)
+	}
+
+	startMonitoring(config, info, logger)
+}
+
+// startMonitoring 開始監測壓力，info 不為空時狀態快照中包含配置來源
+func startMonitoring(config *pressure.Config, info *pressure.ConfigInfo, logger *log.Logger) {
+	fmt.Println("🚀 啟動壓差儀監測...")
+	if simulator, ok := config.ModbusTransport.(*pressure.Simulator); ok {
+		fmt.Printf("🧪 模擬儀表: %s (不連接實際儀表)\n", simulator.Config())
+	}
+
+	// 創建監測流程
+	monitor, err := pressure.NewMonitor(*config)
+	if err != nil {
+		logger.Fatalf("❌ 創建壓差儀失敗: %v", err)
+	}
+	if *identify {
+		if model, err := monitor.Meter().ReadDeviceInfo(); err == nil {
+			fmt.Printf("🏷️  設備型號: %s\n", model)
+		} else {
+			logger.Printf("⚠️  %v", err)
+		}
+	}
+	monitor.SetMaxReadings(*maxReadings)
+	if err := monitor.SetFailurePolicy(failurePolicyFromFlags()); err != nil {
+		logger.Fatalf("❌ 無效的失敗處理策略: %v", err)
+	}
+	if err := monitor.SetSinkBreaker(pressure.SinkBreakerPolicy{
+		FailureBudget: *sinkFailures,
+		RetryInterval: *sinkRetry,
+		WriteTimeout:  *sinkTimeout,
+	}); err != nil {
+		logger.Fatalf("❌ 無效的輸出目標熔斷參數: %v", err)
+	}
+	monitor.SetLocations(loadLocations(logger))
+	if *discoverEvery < 0 {
+		logger.Fatalf("❌ 無效的後台發現間隔: %v", *discoverEvery)
+	}
+	if *discoverEvery > 0 {
+		monitor.SetDiscovery(pressure.DiscoveryConfig{Interval: *discoverEvery})
+	}
+	preset, _ := pressure.GetUnitPreset(config.UnitPreset)
+	console := &consoleSink{onlyChanges: *onlyChanges, tolerance: *changeTolerance, eventsOnly: *eventsOnly, preset: preset, out: os.Stdout}
+	if *outputFile != "" {
+		file, err := os.OpenFile(*outputFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			logger.Fatalf("❌ 打開輸出檔案失敗: %v", err)
+		}
+		console.out, console.file = file, file
+		fmt.Printf("📝 讀數記錄將寫入: %s (%s)\n", *outputFile, *outputFormat)
+	} else if *outputFormat == "cbor" {
+		logger.Fatalf("❌ --output=cbor 為二進制格式，需配合 --output-file 指定寫入的檔案")
+	}
+	monitor.AddSink(console)
+
+	// 告警規則和事件腳本
+	for _, rule := range config.Alarms {
+		monitor.AddAlarmRule(rule)
+	}
+	for _, band := range config.Bands {
+		monitor.AddBand(band)
+	}
+	if config.Baseline != nil {
+		if err := monitor.SetBaseline(*config.Baseline); err != nil {
+			logger.Fatalf("❌ 基線學習配置錯誤: %v", err)
+		}
+	}
+	if config.StatsFile != "" {
+		monitor.SetStatsFile(config.StatsFile)
+	}
+	if config.Ambient != nil {
+		if err := monitor.SetAmbient(*config.Ambient); err != nil {
+			logger.Fatalf("❌ 大氣壓來源配置錯誤: %v", err)
+		}
+	}
+	if len(config.Hooks) > 0 {
+		hooks, err := pressure.NewHookRunner(config.Hooks, logger)
+		if err != nil {
+			logger.Fatalf("❌ 外部腳本配置錯誤: %v", err)
+		}
+		monitor.AddSink(hooks)
+	}
+
+	// 合規日誌
+	if *complianceLog != "" {
+		compliance, err := pressure.OpenComplianceLogWithOptions(*complianceLog, pressure.ComplianceOpenOptions{
+			AcceptMissingManifest: *rebuildManifest,
+		})
+		if err != nil {
+			logger.Fatalf("❌ 打開合規日誌失敗: %v", err)
+		}
+		for _, repair := range compliance.Repairs() {
+			logger.Printf("⚠️  合規日誌%s", repair)
+		}
+		monitor.AddSink(compliance)
+	}
+
+	// HTTP 接口
+	if *controlAddr != "" && *httpAddr == "" {
+		logger.Fatalf("❌ --control-addr 需要同時用 --http 啟動 HTTP 接口")
+	}
+	if *httpAddr != "" {
+		token := *controlToken
+		if token == "" {
+			token = os.Getenv(pressure.ControlTokenEnv)
+		}
+		api := pressure.NewAPIServer(*httpAddr, logger).
+			SetCacheMaxAge(config.ReadInterval).
+			SetMonitor(monitor).
+			SetConfigInfo(info).
+			SetControlAddr(*controlAddr).
+			SetControlToken(token)
+		if err := api.Start(); err != nil {
+			logger.Fatalf("❌ %v", err)
+		}
+		monitor.AddSink(api)
+	}
+
+	// Modbus TCP 網關
+	if *gatewayAddr != "" {
+		gateway := pressure.NewModbusGateway(*gatewayAddr, logger)
+		if err := gateway.Start(); err != nil {
+			logger.Fatalf("❌ %v", err)
+		}
+		monitor.AddSink(gateway)
+	}
+
+	// 需要匯出 Excel 或趨勢報告時保留歷史讀數，同時寫入暫存檔案，異常退出後下次運行時恢復
+	var history *pressure.ReadingHistory
+	if *xlsxFile != "" || *reportFile != "" {
+		history = pressure.NewReadingHistory()
+		spool := *xlsxFile
+		if spool == "" {
+			spool = *reportFile
+		}
+		spool += ".readings.jsonl"
+		if recovered, err := history.Spool(spool); err != nil {
+			logger.Printf("⚠️  %v，異常退出時未匯出的讀數會遺失", err)
+		} else if recovered > 0 {
+			logger.Printf("♻️  從 %s 恢復了上次未匯出的 %d 個讀數", spool, recovered)
+		}
+		monitor.AddSink(history)
+	}
+
+	// 創建上下文和取消函數
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 如果設置了運行時間限制
+	if *duration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	// 啟動自檢
+	if *selfTest {
+		result := monitor.SelfTest(*selfTestReads)
+		result.Print(os.Stdout)
+		if !result.Passed {
+			if *daemon && !*force {
+				monitor.Stop()
+				fmt.Println("❌ 自檢未通過，拒絕進入守護程序模式 (加上 --force 強制啟動)")
+				os.Exit(exitSelfTestFailed)
+			}
+			fmt.Println("⚠️  自檢未通過，繼續監測")
+		}
+	}
+
+	// 測試連接並開始讀取
+	if err := monitor.Start(ctx); err != nil {
+		monitor.Stop()
+		logger.Fatalf("❌ %v", err)
+	}
+
+	// 創建信號通道，用於優雅關閉
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	if !*quiet {
+		fmt.Println("📊 開始實時監測壓力數據...")
+		if *duration > 0 {
+			fmt.Printf("⏰ 運行時間: %v\n", *duration)
+		}
+		if *maxReadings > 0 {
+			fmt.Printf("📈 最大讀數: %d\n", *maxReadings)
+		}
+		fmt.Println("   按 Ctrl+C 停止監測")
+		fmt.Println()
+	}
+
+	// 等待退出信號、超時或達到最大讀數
+	select {
+	case <-monitor.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			fmt.Printf("\n⏰ 已達到運行時間限制: %v\n", *duration)
+		}
+		if err := monitor.Err(); err != nil {
+			fmt.Printf("\n❌ %v\n", err)
+		}
+	case sig := <-sigChan:
+		fmt.Printf("\n🛑 接收到信號: %v\n", sig)
+	}
+
+	fmt.Println("🛑 正在停止監測...")
+	if err := monitor.Stop(); err != nil {
+		logger.Printf("⚠️  停止監測時出錯: %v", err)
+	}
+
+	// 打印統計信息
+	stats := monitor.Stats()
+	if !*quiet && stats.Readings > 0 {
+		fmt.Println("\n📊 監測統計:")
+		fmt.Printf("   📈 總讀數: %d\n", stats.Readings)
+		fmt.Printf("   ⏱️  運行時間: %v\n", stats.Uptime.Round(time.Second))
+		fmt.Printf("   📊 %s\n", stats.Pressure)
+		if stats.Band != "" {
+			fmt.Printf("   🔁 當前區間: %s (變化 %d 次)\n", stats.Band, stats.BandChanges)
+		}
+		if sp := stats.Setpoint; sp != nil && sp.Readings > 0 {
+			fmt.Printf("   🎯 設定值 %s: 達標 %.1f%%，累計超標 %v，平均偏差 %+.2f Pa，最大絕對偏差 %.2f Pa\n",
+				sp.Config(), sp.InSpecPercent, sp.OutOfSpecTime.Round(time.Second), sp.MeanDeviation, sp.MaxAbsDeviation)
+		}
+		if stats.LatencyViolations > 0 {
+			fmt.Printf("   🐢 超出延遲預算: %d 次\n", stats.LatencyViolations)
+		}
+		if comm := monitor.Meter().CommHealth(); comm.Requests > 0 {
+			fmt.Printf("   📡 通信: 成功率 %.1f%% (超時 %d，校驗錯誤 %d，幀錯誤 %d)，響應延遲 平均 %v，P95 %v\n",
+				comm.SuccessRate, comm.Timeouts, comm.CRCErrors, comm.FramingErrors,
+				comm.AvgLatency.Round(time.Microsecond), comm.P95Latency.Round(time.Microsecond))
+		}
+		if baseline := monitor.Baseline(); baseline != nil {
+			fmt.Printf("   🌬️  基線: 已學習 %d/%d 個時段，偏離基線 %d 次\n",
+				stats.BaselineLearned, len(baseline.Slots), stats.BaselineAnomalies)
+		}
+		if stats.Failovers > 0 {
+			fmt.Printf("   🔀 切換備用設備: %d 次，當前設備: %s\n", stats.Failovers, stats.Device)
+		}
+		if stats.RecoverySteps > 0 {
+			fmt.Printf("   🛠️  恢復步驟: %d 次，最近: %s\n", stats.RecoverySteps, stats.LastRecoveryStep)
+		}
+		if stats.DeviceFailed {
+			fmt.Printf("   🚨 設備已標記為故障: %s\n", stats.Device)
+		}
+	}
+
+	exported := true
+	if history != nil && history.Dropped() > 0 {
+		logger.Printf("⚠️  讀數超出保留上限 %d 個，最舊的 %d 個讀數未匯出", pressure.DefaultHistoryLimit, history.Dropped())
+	}
+	if history != nil && *xlsxFile != "" {
+		if err := history.SaveXLSX(*xlsxFile); err != nil {
+			exported = false
+			logger.Printf("⚠️  匯出 Excel 失敗: %v", err)
+		} else {
+			fmt.Printf("📊 讀數已匯出到: %s\n", *xlsxFile)
+		}
+	}
+	if history != nil && *reportFile != "" {
+		opts := pressure.TrendReportOptions{Preset: preset, Setpoint: config.Setpoint, Interval: config.ReadInterval}
+		if err := pressure.SaveTrendReportHTMLWithOptions(history.Readings(), config.Alarms, opts, *reportFile); err != nil {
+			exported = false
+			logger.Printf("⚠️  生成趨勢報告失敗: %v", err)
+		} else {
+			fmt.Printf("📄 趨勢報告已保存到: %s\n", *reportFile)
+		}
+	}
+	// 匯出失敗時保留暫存檔案，下次運行時恢復
+	if history != nil && exported {
+		if err := history.RemoveSpool(); err != nil {
+			logger.Printf("⚠️  刪除讀數暫存檔案失敗: %v", err)
+		}
+	}
+
+	fmt.Println("✅ 監測已停止")
+
+	if errors.Is(monitor.Err(), pressure.ErrTooManyFailures) {
+		os.Exit(exitTooManyFailures)
+	}
+}
+
+// loadLocations 載入 --locations 指定的位置對照表，未指定時返回 nil
+func loadLocations(logger *log.Logger) *pressure.LocationMap {
+	if *locationFile == "" {
+		return nil
+	}
+	locations, err := pressure.LoadLocationMap(*locationFile)
+	if err != nil {
+		logger.Fatalf("❌ %v", err)
+	}
+	logger.Printf("📍 已載入位置對照表: %s (%d 個位置)", *locationFile, locations.Len())
+	return locations
+}
+
+// failurePolicyFromFlags 根據命令列參數構造連續失敗處理策略
+func failurePolicyFromFlags() pressure.FailurePolicy {
+	policy := pressure.FailurePolicy{
+		MaxConsecutive: *maxFailures,
+		Hook:           *failureHook,
+		BackupDevice:   *backupDevice,
+
+		ResetCommand:     *resetCommand,
+		RecoveryInterval: *recoveryWait,
+	}
+	if err := policy.Action.UnmarshalText([]byte(*onFailure)); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	ladder, err := pressure.ParseRecoveryLadder(*recoverySteps)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	policy.Ladder = ladder
+	return policy
+}
+
+// consoleSink 將讀數和告警打印到標準輸出，或寫入 --output-file 指定的檔案
+type consoleSink struct {
+	onlyChanges bool                // 文本模式下只打印變化
+	eventsOnly  bool                // 只打印告警和區間變化事件
+	tolerance   float64             // 變化容差 (Pa)
+	preset      pressure.UnitPreset // 文本輸出的單位預設
+	out         io.Writer           // 標準輸出或輸出檔案
+	file        *os.File            // 輸出檔案，為空則打印到標準輸出
+
+	printed   bool    // 是否已打印過讀數
+	lastValid bool    // 上次打印的讀數是否有效
+	lastValue float64 // 上次打印的壓力值
+	lastError string  // 上次打印的錯誤信息
+}
+
+// WriteReading 按輸出格式打印讀數
+func (cs *consoleSink) WriteReading(reading pressure.MonitorReading) error {
+	if cs.eventsOnly || cs.suppress(reading.PressureReading) {
+		return nil
+	}
+
+	if reading.Valid {
+		outputValue(cs.out, reading, cs.preset)
+	} else {
+		outputError(cs.out, reading)
+	}
+	return nil
+}
+
+// WriteAlarm 打印告警觸發和解除事件（僅文本模式）
+func (cs *consoleSink) WriteAlarm(event pressure.AlarmEvent) error {
+	if *outputFormat != "text" && *outputFormat != "" {
+		return nil
+	}
+
+	timestamp := event.Timestamp.Format("15:04:05")
+	if event.Active {
+		fmt.Fprintf(cs.out, "[%s] 🚨 告警觸發 [%s]%s: %s\n", timestamp, event.Rule, locationSuffix(event.Location), event.Message)
+	} else {
+		fmt.Fprintf(cs.out, "[%s] ✅ 告警解除 [%s]%s: %s\n", timestamp, event.Rule, locationSuffix(event.Location), event.Message)
+	}
+	return nil
+}
+
+// WriteBand 打印區間變化事件（文本、JSON 和 CBOR 模式）
+func (cs *consoleSink) WriteBand(event pressure.BandEvent) error {
+	switch *outputFormat {
+	case "json", "cbor":
+		data := map[string]interface{}{
+			"schema_version": pressure.SchemaVersion,
+			"event":          pressure.HookBandChanged,
+			"timestamp":      event.Timestamp,
+			"slave_id":       event.Reading.SlaveID,
+			"pressure":       event.Reading.Pressure,
+			"unit":           "Pa",
+			"from":           event.From,
+			"to":             event.To,
+		}
+		if event.Location != nil {
+			data["location"] = event.Location
+		}
+		writeRecord(cs.out, data)
+	case "text", "":
+		timestamp := event.Timestamp.Format("15:04:05")
+		fmt.Fprintf(cs.out, "[%s] 🔁 區間變化%s: %s\n", timestamp, locationSuffix(event.Location), event.Message)
+	}
+	return nil
+}
+
+// Close 實現 pressure.Sink 接口，關閉輸出檔案
+func (cs *consoleSink) Close() error {
+	if cs.file != nil {
+		return cs.file.Close()
+	}
+	return nil
+}
+
+// suppress 在 --only-changes 文本模式下判斷讀數是否與上次打印的相同
+func (cs *consoleSink) suppress(reading pressure.PressureReading) bool {
+	if !cs.onlyChanges || *outputFormat != "text" {
+		return false
+	}
+
+	unchanged := cs.printed && reading.Valid == cs.lastValid
+	if unchanged {
+		if reading.Valid {
+			unchanged = math.Abs(reading.Pressure-cs.lastValue) <= cs.tolerance
+		} else {
+			unchanged = reading.Error == cs.lastError
+		}
+	}
+	if unchanged {
+		return true
+	}
+
+	cs.printed = true
+	cs.lastValid = reading.Valid
+	cs.lastValue = reading.Pressure
+	cs.lastError = reading.Error
+	return false
+}
+
+// writeRecord 按輸出格式寫入一條 JSON 行或 CBOR 數據項
+func writeRecord(w io.Writer, data map[string]interface{}) {
+	if *outputFormat == "cbor" {
+		if record, err := pressure.MarshalCBOR(data); err == nil {
+			w.Write(record)
+		}
+		return
+	}
+	jsonData, _ := json.Marshal(data)
+	fmt.Fprintln(w, string(jsonData))
+}
+
+// outputValue 輸出壓力讀數
+func outputValue(w io.Writer, reading pressure.MonitorReading, preset pressure.UnitPreset) {
+	timestamp := reading.Timestamp.Format("15:04:05")
+	count, stats := reading.Count, reading.Stats
+
+	switch *outputFormat {
+	case "json", "cbor":
+		data := map[string]interface{}{
+			"schema_version": pressure.SchemaVersion,
+			"timestamp":      reading.Timestamp,
+			"duration_ms":    float64(reading.Duration) / float64(time.Millisecond),
+			"count":          count,
+			"slave_id":       reading.SlaveID,
+			"pressure":       reading.Pressure,
+			"unit":           "Pa",
+			"valid":          reading.Valid,
+		}
+		if reading.RawPressure != nil {
+			data["raw_pressure"] = *reading.RawPressure
+		}
+		if reading.Degraded {
+			data["degraded"] = true
+		}
+		if reading.Retries > 0 {
+			data["retries"] = reading.Retries
+		}
+		if reading.Scale != 0 {
+			data["scale"] = reading.Scale
+		}
+		if len(reading.RawData) > 0 {
+			data["raw_data"] = fmt.Sprintf("% X", reading.RawData)
+		}
+		if reading.Temperature != nil {
+			data["temperature"] = *reading.Temperature
+		}
+		if reading.Deviation != nil {
+			data["deviation"] = *reading.Deviation
+		}
+		if reading.Ambient != nil {
+			data["ambient_pressure"] = reading.Ambient.Pressure
+		}
+		if reading.Location != nil {
+			data["location"] = reading.Location
+		}
+		writeRecord(w, data)
+
+	case "csv":
+		if count == 1 {
+			fmt.Fprintln(w, "timestamp,count,slave_id,pressure,unit,valid")
+		}
+		fmt.Fprintf(w, "%s,%d,%d,%.3f,Pa,%t\n",
+			reading.Timestamp.Format("2006-01-02 15:04:05"),
+			count, reading.SlaveID, reading.Pressure, reading.Valid)
+
+	default: // text
+		if !*quiet {
+			fmt.Fprintf(w, "[%s] #%d 站點%d%s: %s (平均: %s)%s%s%s%s\n",
+				timestamp, count, reading.SlaveID, locationSuffix(reading.Location),
+				preset.Format(reading.Pressure), preset.Format(stats.Mean),
+				temperatureSuffix(reading.PressureReading), ambientSuffix(reading.Ambient),
+				latencySuffix(reading.PressureReading), retrySuffix(reading.PressureReading))
+		}
+	}
+}
+
+// outputError 輸出錯誤信息
+func outputError(w io.Writer, reading pressure.MonitorReading) {
+	timestamp := reading.Timestamp.Format("15:04:05")
+	count := reading.Count
+
 	switch *outputFormat {
-	case "json":
+	case "json", "cbor":
 		data := map[string]interface{}{
-			"timestamp": reading.Timestamp,
-			"count":     count,
-			"slave_id":  reading.SlaveID,
-			"error":     reading.Error,
-			"valid":     false,
+			"schema_version": pressure.SchemaVersion,
+			"timestamp":      reading.Timestamp,
+			"duration_ms":    float64(reading.Duration) / float64(time.Millisecond),
+			"count":          count,
+			"slave_id":       reading.SlaveID,
+			"error":          reading.Error,
+			"error_code":     reading.ErrorCode.String(),
+			"valid":          false,
+		}
+		if reading.Retries > 0 {
+			data["retries"] = reading.Retries
+		}
+		if reading.Location != nil {
+			data["location"] = reading.Location
 		}
-		jsonData, _ := json.Marshal(data)
-		fmt.Println(string(jsonData))
+		writeRecord(w, data)
 
 	case "csv":
-		fmt.Printf("%s,%d,%d,NaN,Pa,false\n",
+		fmt.Fprintf(w, "%s,%d,%d,NaN,Pa,false\n",
 			reading.Timestamp.Format("2006-01-02 15:04:05"),
 			count, reading.SlaveID)
 
 	default: // text
-		fmt.Printf("[%s] #%d ❌ 讀取失敗: %s\n",
-			timestamp, count, reading.Error)
+		fmt.Fprintf(w, "[%s] #%d ❌%s 讀取失敗: %s%s\n",
+			timestamp, count, locationSuffix(reading.Location), reading.Error, retrySuffix(reading.PressureReading))
+	}
+}
+
+// temperatureSuffix 返回附加在讀數後的溫度和未補償值，沒有溫度時為空
+func temperatureSuffix(reading pressure.PressureReading) string {
+	if reading.Temperature == nil {
+		return ""
+	}
+	if reading.RawPressure != nil {
+		return fmt.Sprintf(" [%.1f °C, 未補償 %.2f Pa]", *reading.Temperature, *reading.RawPressure)
+	}
+	return fmt.Sprintf(" [%.1f °C]", *reading.Temperature)
+}
+
+// ambientSuffix 附加了大氣壓時返回大氣壓
+func ambientSuffix(ambient *pressure.AmbientReading) string {
+	if ambient == nil {
+		return ""
+	}
+	return fmt.Sprintf(" [大氣壓 %.1f hPa]", ambient.Pressure)
+}
+
+// latencySuffix 讀數超出延遲預算時返回耗時提示
+func latencySuffix(reading pressure.PressureReading) string {
+	if !reading.Degraded {
+		return ""
+	}
+	return fmt.Sprintf(" ⚠️ 慢 (%v)", reading.Duration.Round(time.Microsecond))
+}
+
+// retrySuffix 讀取經過重試時返回重試次數提示
+func retrySuffix(reading pressure.PressureReading) string {
+	if reading.Retries == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (重試 %d 次)", reading.Retries)
+}
+
+// locationSuffix 返回附加在站點號後的安裝位置，未知時為空
+func locationSuffix(location *pressure.Location) string {
+	if location == nil {
+		return ""
 	}
+	return fmt.Sprintf(" (%s)", location)
 }
 
 // generateConfigFiles 生成配置檔案示例
@@ -588,7 +2532,7 @@ func generateConfigFiles() {
 device: /dev/ttyUSB0          # RS485 設備路徑
 slaveid: 22                   # 從站ID (1-247)
 readinterval: 1s              # 讀取間隔
-dataformat: 0                 # 數據格式: 0=十進制, 1=浮點數
+dataformat: 0                 # 數據格式: 0=十進制, 1=浮點數, 2=16 位有符號整數, 3=16 位無符號整數
 `
 
 	// 生成 JSON 配置
@@ -606,6 +2550,12 @@ dataformat: 0                 # 數據格式: 0=十進制, 1=浮點數
 	}
 
 	for filename, content := range files {
+		if backup, err := pressure.BackupConfigFile(filename); err != nil {
+			fmt.Printf("❌ 備份 %s 失敗，未覆蓋: %v\n", filename, err)
+			continue
+		} else if backup != "" {
+			fmt.Printf("💾 已備份: %s -> %s\n", filename, backup)
+		}
 		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
 			fmt.Printf("❌ 創建 %s 失敗: %v\n", filename, err)
 		} else {
@@ -619,11 +2569,631 @@ dataformat: 0                 # 數據格式: 0=十進制, 1=浮點數
 	fmt.Println("    Windows: COM1, COM2")
 	fmt.Println("  slaveid: Modbus 從站ID (1-247)")
 	fmt.Println("  readinterval: 讀取間隔 (如: 1s, 500ms, 2m)")
-	fmt.Println("  dataformat: 0=十進制(預設), 1=浮點數")
+	fmt.Println("  dataformat: 0=十進制(預設), 1=浮點數, 2=16 位有符號整數, 3=16 位無符號整數")
+}
+
+// runConfigBackupMode 列出配置檔案的備份，rollback 時用最近的備份恢復
+func runConfigBackupMode(rollback bool) int {
+	filename, err := pressure.NewConfigLoader().SetConfigFile(*configFile).FindConfigFile()
+	if err != nil {
+		fmt.Printf("❌ %v，請用 --config 指定\n", err)
+		return 2
+	}
+
+	backups, err := pressure.ListConfigBackups(filename)
+	if err != nil {
+		fmt.Printf("❌ 讀取備份失敗: %v\n", err)
+		return 2
+	}
+
+	if !rollback {
+		if *outputFormat == "json" {
+			data, _ := json.MarshalIndent(backups, "", "  ")
+			fmt.Println(string(data))
+			return 0
+		}
+		if len(backups) == 0 {
+			fmt.Printf("📂 %s 沒有備份\n", filename)
+			return 0
+		}
+		fmt.Printf("📂 %s 的備份 (最近的在前):\n", filename)
+		for _, backup := range backups {
+			fmt.Printf("   %s  %s (%d 字節)\n", backup.Time.Format("2006-01-02 15:04:05"), backup.Path, backup.Size)
+		}
+		return 0
+	}
+
+	backup, err := pressure.RollbackConfigFile(filename)
+	if err != nil {
+		fmt.Printf("❌ 回滾失敗: %v\n", err)
+		return 1
+	}
+	fmt.Printf("✅ 已用 %s 的備份恢復 %s\n", backup.Time.Format("2006-01-02 15:04:05"), filename)
+	fmt.Printf("   恢復前的內容保存在 %s.before-rollback\n", filename)
+	if len(backups) > 1 {
+		fmt.Printf("   還有 %d 個更早的備份，再次執行 --config-rollback 可繼續回滾\n", len(backups)-1)
+	}
+	return 0
+}
+
+// runMigrateMode 將記錄的讀數檔案升級到當前格式版本
+func runMigrateMode(filename string) int {
+	result, err := pressure.MigrateRecordingFile(filename)
+	if err != nil {
+		fmt.Printf("❌ 遷移失敗: %v\n", err)
+		return 1
+	}
+
+	if *outputFormat == "json" {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+		return 0
+	}
+
+	if result.Records == 0 {
+		fmt.Printf("⚠️  %s 中沒有 JSON 讀數記錄\n", filename)
+		return 0
+	}
+	versions := make([]string, 0, len(result.Versions))
+	for version, count := range result.Versions {
+		label := version
+		if version == "0" {
+			label = "無版本號"
+		}
+		versions = append(versions, fmt.Sprintf("%s: %d", label, count))
+	}
+	sort.Strings(versions)
+	fmt.Printf("📄 %s: %d 條讀數記錄 (%s)\n", filename, result.Records, strings.Join(versions, ", "))
+	if result.Migrated == 0 {
+		fmt.Printf("✅ 已是當前格式版本 %s，無需遷移\n", pressure.SchemaVersion)
+		return 0
+	}
+	fmt.Printf("✅ 已將 %d 條記錄升級到格式版本 %s\n", result.Migrated, pressure.SchemaVersion)
+	if result.Backup != "" {
+		fmt.Printf("   修改前的檔案保存在 %s\n", result.Backup)
+	}
+	if result.Skipped > 0 {
+		fmt.Printf("   %d 行非讀數內容保持原樣\n", result.Skipped)
+	}
+	return 0
+}
+
+// runDecodeMode 將 CBOR 記錄逐條轉換為 JSON 行打印，記錄損壞時返回 1
+func runDecodeMode(filename string) int {
+	var r io.Reader = os.Stdin
+	if filename != "-" {
+		file, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ 打開 %s 失敗: %v\n", filename, err)
+			return 1
+		}
+		defer file.Close()
+		r = file
+	}
+
+	decoder := pressure.NewCBORDecoder(r)
+	for count := 1; ; count++ {
+		record, err := decoder.Decode()
+		if err == io.EOF {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ 第 %d 條記錄解碼失敗: %v\n", count, err)
+			return 1
+		}
+		data, err := json.Marshal(record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ 第 %d 條記錄無法轉換為 JSON: %v\n", count, err)
+			return 1
+		}
+		fmt.Println(string(data))
+	}
 }
 
 // 輔助函數
 
+// newConfigLoader 創建配置加載器，命令列參數作為最高優先級覆蓋
+func newConfigLoader(logger *log.Logger) *pressure.ConfigLoader {
+	loader := pressure.NewConfigLoader().SetLogger(logger)
+	if *configFile != "" {
+		loader.SetConfigFile(*configFile)
+	}
+	return loader.SetOverrides(func(info *pressure.ConfigInfo) {
+		applyFlagOverrides(info.Config, info.Source)
+	})
+}
+
+// newScanner 根據命令列參數創建掃描器
+func newScanner(logger *log.Logger) *pressure.Scanner {
+	probeTimeout := *scanTimeout
+	if probeTimeout == 0 && *commProfile != "" {
+		if profile, err := pressure.GetCommProfile(*commProfile); err == nil {
+			probeTimeout = profile.ProbeTimeout
+		}
+	}
+	if probeTimeout == 0 {
+		if envTimeout := os.Getenv("PRESSURE_SCAN_TIMEOUT"); envTimeout != "" {
+			if timeout, err := time.ParseDuration(envTimeout); err == nil {
+				probeTimeout = timeout
+			} else {
+				logger.Printf("⚠️  環境變數 PRESSURE_SCAN_TIMEOUT 格式錯誤: %v", err)
+			}
+		}
+	}
+
+	var register uint64
+	if *probeRegister != "" {
+		var err error
+		register, err = strconv.ParseUint(*probeRegister, 0, 16)
+		if err != nil {
+			logger.Fatalf("❌ 無效的探測寄存器地址: %s", *probeRegister)
+		}
+	}
+	if *probeFunction > 0xFF || *probeCount > 0xFFFF {
+		logger.Fatalf("❌ 無效的探測參數: 功能碼=%d, 數量=%d", *probeFunction, *probeCount)
+	}
+	function := byte(*probeFunction)
+	if function == 0 && *registerType != "" {
+		var err error
+		if function, err = pressure.RegisterTypeFunction(*registerType); err != nil {
+			logger.Fatalf("❌ %v", err)
+		}
+	}
+
+	if *byteOrder != "" {
+		if err := pressure.CheckByteOrder(*byteOrder); err != nil {
+			logger.Fatalf("❌ %v", err)
+		}
+	}
+	var scale float64
+	if *scaleFlag != "" {
+		var err error
+		if scale, err = pressure.ParseScale(*scaleFlag); err != nil {
+			logger.Fatalf("❌ %v", err)
+		}
+	}
+
+	scanner := pressure.NewScanner(logger)
+	if *parityMatrix {
+		scanner.SetParities(pressure.CommonParities())
+	}
+	if *serialMatrix {
+		scanner.SetParities(pressure.AllParities()).SetStopBits(pressure.AllStopBits())
+	}
+	if *scanWorkers < 0 {
+		logger.Fatalf("❌ 無效的並行串口數: %d", *scanWorkers)
+	}
+	if *scanDelay < 0 {
+		logger.Fatalf("❌ 無效的探測間隔: %v", *scanDelay)
+	}
+	scanner.SetProbeDelay(*scanDelay)
+	if *scanRetry < 0 {
+		logger.Fatalf("❌ 無效的重試超時: %v", *scanRetry)
+	}
+	if *noScanRetry {
+		scanner.SetRetryTimeout(-1)
+	} else {
+		scanner.SetRetryTimeout(*scanRetry)
+	}
+	if *formatSamples < 0 {
+		logger.Fatalf("❌ 無效的格式檢測採樣次數: %d", *formatSamples)
+	}
+	scanner.SetFormatSamples(*formatSamples)
+	if _, err := pressure.ParseScanOutputFormats(*scanOutput); err != nil {
+		logger.Fatalf("❌ %v", err)
+	}
+	if *scanConfigFile != "" {
+		config, err := pressure.LoadScanConfig(*scanConfigFile)
+		if err != nil {
+			logger.Fatalf("❌ %v", err)
+		}
+		scanner.SetScanConfig(config)
+	}
+	if *scanSlaves != "" {
+		slaveIDs, err := pressure.ParseSlaveIDList(*scanSlaves)
+		if err != nil {
+			logger.Fatalf("❌ %v", err)
+		}
+		scanner.SetSlaveIDs(slaveIDs)
+	}
+	if *scanBauds != "" {
+		baudRates, err := pressure.ParseBaudRateList(*scanBauds)
+		if err != nil {
+			logger.Fatalf("❌ %v", err)
+		}
+		scanner.SetBaudRates(baudRates)
+	}
+	if *scanPorts != "" {
+		ports, err := pressure.ParseSerialPortList(*scanPorts)
+		if err != nil {
+			logger.Fatalf("❌ %v", err)
+		}
+		scanner.SetSerialPorts(ports)
+	}
+	excludePorts := *scanExclude
+	if excludePorts == "" {
+		excludePorts = os.Getenv("PRESSURE_SCAN_EXCLUDE_PORTS")
+	}
+	if excludePorts != "" {
+		ports, err := pressure.ParseSerialPortList(excludePorts)
+		if err != nil {
+			logger.Fatalf("❌ 無效的排除串口: %v", err)
+		}
+		scanner.SetExcludePorts(ports)
+	}
+	if *passiveFirst {
+		if *passiveListen <= 0 {
+			logger.Fatalf("❌ 無效的監聽時長: %v", *passiveListen)
+		}
+		scanner.SetPassiveFirst(*passiveListen, *force)
+	}
+
+	return scanner.
+		SetVerbose(!*quiet).
+		SetProbeTimeout(probeTimeout).
+		SetMaxParallel(*scanWorkers).
+		SetProbe(function, uint16(register), uint16(*probeCount)).
+		SetIdentify(*identify).
+		SetByteOrder(*byteOrder).
+		SetScale(scale).
+		SetFrameTrace(frameTrace)
+}
+
+// applyFlagOverrides 將命令列指定的通信配置檔和超時參數覆蓋到配置中
+func applyFlagOverrides(config *pressure.Config, source map[string]pressure.ConfigSource) {
+	setSource := func(key string) {
+		if source != nil {
+			source[key] = pressure.SourceFlags
+		}
+	}
+
+	if *deviceFlag != "" {
+		config.Device = *deviceFlag
+		setSource("device")
+	}
+	if *transportFlag != "" {
+		if !pressure.IsValidTransport(*transportFlag) {
+			log.Fatalf("❌ 無效的傳輸方式: %s (可用: rtu, tcp, rtuovertcp)", *transportFlag)
+		}
+		config.Transport = strings.ToLower(*transportFlag)
+		setSource("transport")
+	}
+	if *addressFlag != "" {
+		config.Address = *addressFlag
+		setSource("address")
+		if *transportFlag == "" {
+			config.Transport = pressure.TransportTCP
+			setSource("transport")
+		}
+	}
+	if *slaveIDFlag != "" {
+		slaveID, err := strconv.ParseUint(*slaveIDFlag, 0, 8)
+		if err != nil {
+			log.Fatalf("❌ 無效的站點號: %s", *slaveIDFlag)
+		}
+		config.SlaveID = byte(slaveID)
+		setSource("slaveid")
+	}
+	if *intervalFlag > 0 {
+		config.ReadInterval = *intervalFlag
+		setSource("readinterval")
+	}
+	if *formatFlag != "" {
+		var format pressure.DataFormatType
+		if err := format.UnmarshalText([]byte(*formatFlag)); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		config.DataFormat = format
+		setSource("dataformat")
+	}
+	if *deviceProfile != "" {
+		profile, err := pressure.GetDeviceProfile(*deviceProfile)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		config.DeviceProfile = profile.Name
+		config.CustomProfile = nil
+		setSource("deviceprofile")
+	}
+	if *registerType != "" {
+		if _, err := pressure.RegisterTypeFunction(*registerType); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		config.RegisterType = *registerType
+		setSource("registertype")
+	}
+	if *byteOrder != "" {
+		if err := pressure.CheckByteOrder(*byteOrder); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		config.ByteOrder = strings.ToUpper(*byteOrder)
+		setSource("byteorder")
+	}
+	if *scaleFlag != "" {
+		scale, err := pressure.ParseScale(*scaleFlag)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		config.Scale = scale
+		setSource("scale")
+	}
+	if *minPressure != "" {
+		value, err := strconv.ParseFloat(*minPressure, 64)
+		if err != nil {
+			log.Fatalf("❌ 無效的有效讀數下限: %s", *minPressure)
+		}
+		config.MinPressure = &value
+		setSource("minpressure")
+	}
+	if *maxPressure != "" {
+		value, err := strconv.ParseFloat(*maxPressure, 64)
+		if err != nil {
+			log.Fatalf("❌ 無效的有效讀數上限: %s", *maxPressure)
+		}
+		config.MaxPressure = &value
+		setSource("maxpressure")
+	}
+	if *signBands && *setpointFlag != "" {
+		log.Fatalf("❌ --sign-bands 和 --setpoint 不能同時使用")
+	}
+	if *signBands {
+		config.Bands = pressure.NewSignBands()
+		setSource("bands")
+	}
+	if *setpointFlag != "" {
+		value, err := strconv.ParseFloat(*setpointFlag, 64)
+		if err != nil {
+			log.Fatalf("❌ 無效的壓力設定值: %s", *setpointFlag)
+		}
+		if *setpointTol <= 0 {
+			log.Fatalf("❌ 設定值容差必須大於 0: %v", *setpointTol)
+		}
+		config.Bands = pressure.NewSetpointBands(value, *setpointTol)
+		config.Setpoint = &pressure.SetpointConfig{Target: value, Tolerance: *setpointTol}
+		setSource("bands")
+		setSource("setpoint")
+	}
+	if *baselineFile != "" || *baselineSigma > 0 {
+		if config.Baseline == nil {
+			config.Baseline = &pressure.BaselineConfig{}
+		}
+		if *baselineFile != "" {
+			config.Baseline.File = *baselineFile
+		}
+		if *baselineSigma > 0 {
+			config.Baseline.Sigma = *baselineSigma
+		}
+		setSource("baseline")
+	}
+	if *statsFile != "" {
+		config.StatsFile = *statsFile
+		setSource("statsfile")
+	}
+	if *ambientSource != "" || *ambientField != "" || *ambientUnit != "" {
+		if *ambientSource != "" {
+			ambient := pressure.ParseAmbientSource(*ambientSource)
+			if config.Ambient != nil {
+				ambient.Field, ambient.Unit = config.Ambient.Field, config.Ambient.Unit
+				ambient.Interval, ambient.MaxAge = config.Ambient.Interval, config.Ambient.MaxAge
+			}
+			config.Ambient = &ambient
+		} else if config.Ambient == nil {
+			log.Fatalf("❌ --ambient-field 和 --ambient-unit 需配合 --ambient 或配置檔案中的 ambient 使用")
+		}
+		if *ambientField != "" {
+			config.Ambient.Field = *ambientField
+		}
+		if *ambientUnit != "" {
+			config.Ambient.Unit = *ambientUnit
+		}
+		if err := config.Ambient.Validate(); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		setSource("ambient")
+	}
+	if *dampingReg != "" {
+		register, err := strconv.ParseUint(*dampingReg, 0, 16)
+		if err != nil {
+			log.Fatalf("❌ 無效的阻尼寄存器地址: %s", *dampingReg)
+		}
+		config.DampingRegister = uint16(register)
+		setSource("dampingregister")
+	}
+	if *slaveIDReg != "" {
+		register, err := strconv.ParseUint(*slaveIDReg, 0, 16)
+		if err != nil {
+			log.Fatalf("❌ 無效的站點號寄存器地址: %s", *slaveIDReg)
+		}
+		config.SlaveIDRegister = uint16(register)
+		setSource("slaveidregister")
+	}
+	if *baudRateFlag != 0 {
+		if !pressure.IsValidBaudRate(*baudRateFlag) {
+			log.Fatalf("❌ 不支援的波特率: %d，支援: %v", *baudRateFlag, pressure.GetSupportedBaudRates())
+		}
+		config.BaudRate = *baudRateFlag
+		setSource("baudrate")
+	}
+	if *baudRateReg != "" {
+		register, err := strconv.ParseUint(*baudRateReg, 0, 16)
+		if err != nil {
+			log.Fatalf("❌ 無效的波特率寄存器地址: %s", *baudRateReg)
+		}
+		config.BaudRateRegister = uint16(register)
+		setSource("baudrateregister")
+	}
+	if *baudRateCodes != "" {
+		codes, err := pressure.ParseBaudRateCodes(*baudRateCodes)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		config.BaudRateCodes = codes
+		setSource("baudratecodes")
+	}
+	if *zeroReg != "" {
+		register, err := strconv.ParseUint(*zeroReg, 0, 16)
+		if err != nil {
+			log.Fatalf("❌ 無效的零點校準寄存器地址: %s", *zeroReg)
+		}
+		config.ZeroRegister = uint16(register)
+		setSource("zeroregister")
+	}
+	if *tempRegister != "" {
+		register, err := strconv.ParseUint(*tempRegister, 0, 16)
+		if err != nil {
+			log.Fatalf("❌ 無效的溫度寄存器地址: %s", *tempRegister)
+		}
+		config.TemperatureRegister = uint16(register)
+		setSource("temperatureregister")
+	}
+	if *timestampFlag != "" {
+		var ts pressure.TimestampSource
+		if err := ts.UnmarshalText([]byte(*timestampFlag)); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		config.TimestampSource = ts
+		setSource("timestampsource")
+	}
+
+	if *commProfile != "" {
+		profile, err := pressure.GetCommProfile(*commProfile)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		config.CommProfile = profile.Name
+		if source == nil || source["connecttimeout"] == pressure.SourceDefault {
+			config.ConnectTimeout = profile.ConnectTimeout
+		}
+		if source == nil || source["responsetimeout"] == pressure.SourceDefault {
+			config.ResponseTimeout = profile.ResponseTimeout
+		}
+		if source == nil || source["maxretries"] == pressure.SourceDefault {
+			config.MaxRetries = profile.MaxRetries
+		}
+		if source == nil || source["retrydelay"] == pressure.SourceDefault {
+			config.RetryDelay = profile.RetryDelay
+		}
+		if config.ReadInterval < profile.MinReadInterval {
+			config.ReadInterval = profile.MinReadInterval
+		}
+		setSource("commprofile")
+	}
+
+	if *unitPreset != "" {
+		preset, err := pressure.GetUnitPreset(*unitPreset)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		config.UnitPreset = preset.Name
+		setSource("unitpreset")
+	}
+
+	if *connectTimeout > 0 {
+		config.ConnectTimeout = *connectTimeout
+		setSource("connecttimeout")
+	}
+	if *responseTimeout > 0 {
+		config.ResponseTimeout = *responseTimeout
+		setSource("responsetimeout")
+	}
+	if *latencyBudget > 0 {
+		config.LatencyBudget = *latencyBudget
+		setSource("latencybudget")
+	}
+	if *latencyLimit > 0 {
+		config.LatencyViolations = *latencyLimit
+		setSource("latencyviolations")
+	}
+	if *maxRetries >= 0 {
+		config.MaxRetries = *maxRetries
+		setSource("maxretries")
+	}
+	if *retryDelay > 0 {
+		config.RetryDelay = *retryDelay
+		setSource("retrydelay")
+	}
+	if *openRetryWait > 0 {
+		config.OpenRetryWait = *openRetryWait
+		setSource("openretrywait")
+	}
+	if *startDegraded {
+		config.StartDegraded = true
+		setSource("startdegraded")
+	}
+	if *enableControl {
+		config.EnableControl = true
+		setSource("enablecontrol")
+	}
+	if *bufferSize > 0 {
+		config.BufferSize = *bufferSize
+		setSource("buffersize")
+	}
+	if *overflowPolicy != "" {
+		var policy pressure.OverflowPolicy
+		if err := policy.UnmarshalText([]byte(*overflowPolicy)); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		config.OverflowPolicy = policy
+		setSource("overflowpolicy")
+	}
+	// 詳細輸出用於排查問題，總是保留原始數據
+	if *rawData || *verbose {
+		config.RawData = true
+		setSource("rawdata")
+	}
+	config.FrameTrace = frameTrace
+
+	// 模擬儀表和原始數據回放放在最後，按最終的站點號、設備配置檔和溫度寄存器生成寄存器
+	if *replayRaw != "" {
+		frames, err := pressure.LoadRecordedFramesFile(*replayRaw)
+		if err != nil {
+			log.Fatalf("❌ 讀取原始數據記錄失敗: %v", err)
+		}
+		config.Transport = pressure.TransportRTU
+		config.Device = "replay"
+		setSource("transport")
+		setSource("device")
+		replay, err := pressure.NewReplayTransport(*config, frames, *replaySpeed)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		config.ModbusTransport = replay
+	} else if *simulate != "" {
+		sim := pressure.SimulatorConfig{
+			Waveform: strings.ToLower(*simulate),
+			Period:   *simulatePeriod,
+			Noise:    *simulateNoise,
+			Drift:    *simulateDrift,
+			Seed:     *simulateSeed,
+		}
+		bounds := strings.Split(*simulateRange, ",")
+		if len(bounds) != 2 {
+			log.Fatalf("❌ 無效的模擬壓力範圍: %s (格式 MIN,MAX)", *simulateRange)
+		}
+		var err error
+		if sim.Min, err = strconv.ParseFloat(strings.TrimSpace(bounds[0]), 64); err != nil {
+			log.Fatalf("❌ 無效的模擬壓力範圍: %s", *simulateRange)
+		}
+		if sim.Max, err = strconv.ParseFloat(strings.TrimSpace(bounds[1]), 64); err != nil {
+			log.Fatalf("❌ 無效的模擬壓力範圍: %s", *simulateRange)
+		}
+		if *simulateFaults != "" {
+			if sim.Faults, err = pressure.ParseSimulatorFaults(*simulateFaults); err != nil {
+				log.Fatalf("❌ %v", err)
+			}
+		}
+		config.Transport = pressure.TransportRTU
+		config.Device = "simulator"
+		setSource("transport")
+		setSource("device")
+		simulator, err := pressure.NewSimulator(*config, sim)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		config.ModbusTransport = simulator
+	}
+}
+
 // getResponsiveDevices 獲取響應的設備
 func getResponsiveDevices(devices []pressure.DeviceInfo) []pressure.DeviceInfo {
 	var responsive []pressure.DeviceInfo
@@ -637,29 +3207,56 @@ func getResponsiveDevices(devices []pressure.DeviceInfo) []pressure.DeviceInfo {
 
 // createConfigFromDevice 從設備信息創建配置
 func createConfigFromDevice(device pressure.DeviceInfo, logger *log.Logger) *pressure.Config {
-	return &pressure.Config{
+	config := &pressure.Config{
 		Device:       device.Device,
 		SlaveID:      device.SlaveID,
 		ReadInterval: time.Second,
 		DataFormat:   device.DataFormat,
+		Parity:       pressure.DeviceParity(device),
+		StopBits:     pressure.DeviceStopBits(device),
+		BaudRate:     pressure.DeviceBaudRate(device),
+		RegisterType: pressure.DeviceRegisterType(device),
+		ByteOrder:    pressure.DeviceByteOrder(device),
+		Scale:        pressure.DeviceScale(device),
 		Logger:       logger,
 	}
+	config.UseScannedEndpoint(device)
+	return config
+}
+
+// writeScanReport 根據 --report 和 --xlsx 參數生成掃描報告
+func writeScanReport(result *pressure.ScanResult, logger *log.Logger) {
+	if *reportFile != "" {
+		if err := pressure.SaveScanReportHTML(result, *reportFile); err != nil {
+			logger.Printf("⚠️  生成掃描報告失敗: %v", err)
+		} else {
+			fmt.Printf("📄 掃描報告已保存到: %s\n", *reportFile)
+		}
+	}
+
+	if *xlsxFile != "" {
+		if err := pressure.SaveScanResultsXLSX(result, *xlsxFile); err != nil {
+			logger.Printf("⚠️  匯出 Excel 失敗: %v", err)
+		} else {
+			fmt.Printf("📊 掃描結果已匯出到: %s\n", *xlsxFile)
+		}
+	}
 }
 
 // saveScanResults 保存掃描結果
 func saveScanResults(result *pressure.ScanResult) error {
-	filename := fmt.Sprintf("scan_results_%s.json",
-		time.Now().Format("20060102_150405"))
-
-	data, err := json.MarshalIndent(result, "", "  ")
+	formats, err := pressure.ParseScanOutputFormats(*scanOutput)
 	if err != nil {
 		return err
 	}
+	base := fmt.Sprintf("scan_results_%s", time.Now().Format("20060102_150405"))
 
-	if err := os.WriteFile(filename, data, 0644); err != nil {
-		return err
+	for _, format := range formats {
+		filename := base + "." + format
+		if err := pressure.SaveScanResults(result, filename); err != nil {
+			return err
+		}
+		fmt.Printf("💾 掃描結果已保存到: %s\n", filename)
 	}
-
-	fmt.Printf("💾 掃描結果已保存到: %s\n", filename)
 	return nil
 }
@@ -0,0 +1,173 @@
+// pressure/alarmtest.go - 告警規則試算：部署前用指定的壓力值或歷史記錄評估告警規則會如何觸發
+package pressure
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// maxPrintedAlarmEvents 打印回放結果時最多列出的告警事件數量
+const maxPrintedAlarmEvents = 100
+
+// AlarmRuleResult 一條告警規則對一個壓力值的評估結果
+type AlarmRuleResult struct {
+	Rule    string `json:"rule"`              // 規則名稱
+	Fired   bool   `json:"fired"`             // 是否觸發
+	Message string `json:"message,omitempty"` // 觸發原因
+}
+
+// AlarmTest 一個壓力值對全部告警規則的評估結果
+type AlarmTest struct {
+	Pressure float64           `json:"pressure"` // 壓力值 (Pa)
+	Results  []AlarmRuleResult `json:"results"`  // 按規則順序排列
+	preset   UnitPreset
+}
+
+// TestAlarmRules 評估壓力值 (Pa) 會觸發哪些告警規則，判斷和描述與監測時相同（按 preset 的符號約定和用語）
+func TestAlarmRules(rules []AlarmRule, preset UnitPreset, pressure float64) *AlarmTest {
+	test := &AlarmTest{Pressure: pressure, preset: preset}
+	for _, rule := range rules {
+		message, violated := preset.CheckAlarm(rule, pressure)
+		test.Results = append(test.Results, AlarmRuleResult{Rule: rule.Name, Fired: violated, Message: message})
+	}
+	return test
+}
+
+// Fired 返回觸發的規則名稱
+func (at *AlarmTest) Fired() []string {
+	var fired []string
+	for _, result := range at.Results {
+		if result.Fired {
+			fired = append(fired, result.Rule)
+		}
+	}
+	return fired
+}
+
+// Print 將評估結果寫入 w
+func (at *AlarmTest) Print(w io.Writer) {
+	fired := at.Fired()
+	fmt.Fprintf(w, "壓力 %s: ", at.preset.Format(at.Pressure))
+	if len(fired) == 0 {
+		fmt.Fprintln(w, "不觸發任何告警")
+	} else {
+		fmt.Fprintf(w, "觸發 %d 條告警\n", len(fired))
+	}
+	for _, result := range at.Results {
+		if result.Fired {
+			fmt.Fprintf(w, "   🚨 %s: %s\n", result.Rule, result.Message)
+		} else {
+			fmt.Fprintf(w, "   ✅ %s\n", result.Rule)
+		}
+	}
+}
+
+// AlarmRuleReplay 一條告警規則在回放中的統計
+type AlarmRuleReplay struct {
+	Rule       string        `json:"rule"`        // 規則名稱
+	Triggered  int           `json:"triggered"`   // 觸發次數
+	Violations int           `json:"violations"`  // 違反規則的讀數
+	ActiveTime time.Duration `json:"active_time"` // 觸發中的累計時長
+	Active     bool          `json:"active"`      // 回放結束時是否仍觸發中
+	since      time.Time
+}
+
+// AlarmReplay 按歷史讀數回放告警規則的結果
+type AlarmReplay struct {
+	Readings int               `json:"readings"` // 回放的有效讀數
+	Start    time.Time         `json:"start"`    // 第一個讀數的時間
+	End      time.Time         `json:"end"`      // 最後一個讀數的時間
+	Events   []AlarmEvent      `json:"events"`   // 觸發和解除事件，時間為讀數時間
+	Rules    []AlarmRuleReplay `json:"rules"`    // 按規則順序排列
+	preset   UnitPreset
+}
+
+// ReplayAlarms 按時間順序用歷史讀數評估告警規則
+//
+// 觸發和解除的判斷與監測時相同：讀數違反規則時觸發，回到範圍內時解除，觸發中的重複違反不產生新事件。
+func ReplayAlarms(rules []AlarmRule, preset UnitPreset, points []TrendPoint) *AlarmReplay {
+	points = append([]TrendPoint(nil), points...)
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+
+	replay := &AlarmReplay{Readings: len(points), preset: preset}
+	stats := make(map[string]*AlarmRuleReplay, len(rules))
+	for _, rule := range rules {
+		replay.Rules = append(replay.Rules, AlarmRuleReplay{Rule: rule.Name})
+	}
+	for i := range replay.Rules {
+		stats[replay.Rules[i].Rule] = &replay.Rules[i]
+	}
+	if len(points) == 0 {
+		return replay
+	}
+	replay.Start, replay.End = points[0].Time, points[len(points)-1].Time
+
+	active := make(map[string]bool)
+	for _, point := range points {
+		reading := PressureReading{Timestamp: point.Time, Pressure: point.Pressure, Valid: true}
+		for _, rule := range rules {
+			if _, violated := preset.CheckAlarm(rule, point.Pressure); violated {
+				stats[rule.Name].Violations++
+			}
+		}
+		for _, event := range evaluateAlarmRules(rules, active, preset, reading, nil, point.Time) {
+			rs := stats[event.Rule]
+			if event.Active {
+				rs.Triggered++
+				rs.since = point.Time
+			} else {
+				rs.ActiveTime += point.Time.Sub(rs.since)
+			}
+			replay.Events = append(replay.Events, event)
+		}
+	}
+	for i := range replay.Rules {
+		rs := &replay.Rules[i]
+		if active[rs.Rule] {
+			rs.Active = true
+			rs.ActiveTime += replay.End.Sub(rs.since)
+		}
+	}
+	return replay
+}
+
+// Triggered 回放中是否有規則觸發
+func (ar *AlarmReplay) Triggered() bool {
+	for _, rs := range ar.Rules {
+		if rs.Triggered > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Print 將回放結果寫入 w，事件過多時只列出前 100 個
+func (ar *AlarmReplay) Print(w io.Writer) {
+	if ar.Readings == 0 {
+		fmt.Fprintln(w, "沒有可回放的有效讀數")
+		return
+	}
+	fmt.Fprintf(w, "回放 %d 個讀數 (%s ~ %s)，告警事件 %d 個\n", ar.Readings,
+		ar.Start.Format("2006-01-02 15:04:05"), ar.End.Format("2006-01-02 15:04:05"), len(ar.Events))
+	for i, event := range ar.Events {
+		if i == maxPrintedAlarmEvents {
+			fmt.Fprintf(w, "   ... 另有 %d 個事件\n", len(ar.Events)-maxPrintedAlarmEvents)
+			break
+		}
+		icon := "✅"
+		if event.Active {
+			icon = "🚨"
+		}
+		fmt.Fprintf(w, "   %s %s %s: %s\n", event.Timestamp.Format("2006-01-02 15:04:05"), icon, event.Rule, event.Message)
+	}
+	fmt.Fprintln(w, "規則統計:")
+	for _, rs := range ar.Rules {
+		fmt.Fprintf(w, "   %s: 觸發 %d 次，違反讀數 %d，觸發中 %v", rs.Rule, rs.Triggered, rs.Violations, rs.ActiveTime.Round(time.Second))
+		if rs.Active {
+			fmt.Fprint(w, "，回放結束時仍觸發中")
+		}
+		fmt.Fprintln(w)
+	}
+}
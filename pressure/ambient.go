@@ -0,0 +1,344 @@
+// pressure/ambient.go - 大氣壓參考：定期讀取本地氣壓傳感器或天氣接口的大氣壓，附加到讀數，便於區分建築壓差變化和天氣鋒面
+package pressure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 大氣壓來源的默認參數
+const (
+	DefaultAmbientInterval     = time.Minute      // 檔案和命令的刷新間隔
+	DefaultAmbientHTTPInterval = 10 * time.Minute // 天氣接口的刷新間隔，避免超出免費額度
+	DefaultAmbientTimeout      = 10 * time.Second // 單次讀取命令或請求的超時
+)
+
+// 合理的大氣壓範圍 (hPa)，超出時多半是單位設置錯誤
+const (
+	minAmbientPressure = 300.0
+	maxAmbientPressure = 1100.0
+)
+
+// AmbientConfig 大氣壓來源，File、Command 和 URL 三選一
+//
+// 來源輸出單個數值時直接使用；輸出 JSON 時按 Field 取出氣壓字段，
+// 如 Open-Meteo 的 current.surface_pressure 或 OpenWeatherMap 的 main.pressure。
+type AmbientConfig struct {
+	File     string        `json:"file,omitempty" yaml:"file,omitempty"`         // 讀取的檔案，如 BMP280 的 /sys/bus/iio/devices/iio:device0/in_pressure_input
+	Command  string        `json:"command,omitempty" yaml:"command,omitempty"`   // 執行的命令，按空白分割後直接執行，不經過 shell
+	URL      string        `json:"url,omitempty" yaml:"url,omitempty"`           // 返回 JSON 的天氣接口
+	Field    string        `json:"field,omitempty" yaml:"field,omitempty"`       // JSON 中氣壓字段的路徑，以 . 分隔，數組下標為數字
+	Unit     string        `json:"unit,omitempty" yaml:"unit,omitempty"`         // 來源的氣壓單位 (hPa/Pa/kPa/mbar 等)，默認 hPa；Linux IIO 驅動為 kPa
+	Interval time.Duration `json:"interval,omitempty" yaml:"interval,omitempty"` // 刷新間隔，默認檔案和命令 1 分鐘、天氣接口 10 分鐘
+	MaxAge   time.Duration `json:"maxage,omitempty" yaml:"maxage,omitempty"`     // 超過此時長未成功刷新時不再附加，默認 3 個刷新間隔
+}
+
+// ParseAmbientSource 按來源字串設置大氣壓來源：http(s):// 開頭為天氣接口，exec: 開頭為命令，其他為檔案路徑
+func ParseAmbientSource(source string) AmbientConfig {
+	source = strings.TrimSpace(source)
+	switch {
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		return AmbientConfig{URL: source}
+	case strings.HasPrefix(source, "exec:"):
+		return AmbientConfig{Command: strings.TrimSpace(strings.TrimPrefix(source, "exec:"))}
+	default:
+		return AmbientConfig{File: source}
+	}
+}
+
+// Validate 檢查只設置了一個來源，以及單位和時長
+func (ac AmbientConfig) Validate() error {
+	sources := 0
+	for _, source := range []string{ac.File, ac.Command, ac.URL} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("大氣壓來源必須設置 file、command 和 url 其中一個")
+	}
+	if ac.Command != "" && len(strings.Fields(ac.Command)) == 0 {
+		return fmt.Errorf("大氣壓命令為空")
+	}
+	if ac.URL != "" {
+		if u, err := url.Parse(ac.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("無效的大氣壓接口地址: %s", ac.URL)
+		}
+	}
+	if _, err := ac.unit(); err != nil {
+		return err
+	}
+	if ac.Interval < 0 || ac.MaxAge < 0 {
+		return fmt.Errorf("大氣壓刷新間隔和最長有效時間不能為負數")
+	}
+	return nil
+}
+
+// String 返回大氣壓來源的描述，接口地址不含查詢參數（可能帶有 API 密鑰）
+func (ac AmbientConfig) String() string {
+	s := ac.source()
+	if ac.Field != "" {
+		s += " (" + ac.Field + ")"
+	}
+	return fmt.Sprintf("%s，每 %v 刷新", s, ac.interval())
+}
+
+// source 返回來源的簡短名稱
+func (ac AmbientConfig) source() string {
+	switch {
+	case ac.URL != "":
+		if u, err := url.Parse(ac.URL); err == nil {
+			return u.Scheme + "://" + u.Host + u.Path
+		}
+		return ac.URL
+	case ac.Command != "":
+		return "exec:" + ac.Command
+	default:
+		return ac.File
+	}
+}
+
+// unit 返回來源的氣壓單位，hPa 與 mbar 相同
+func (ac AmbientConfig) unit() (PressureUnit, error) {
+	if ac.Unit == "" || strings.EqualFold(strings.TrimSpace(ac.Unit), "hPa") {
+		return Millibar, nil
+	}
+	return ParsePressureUnit(ac.Unit)
+}
+
+// interval 返回刷新間隔
+func (ac AmbientConfig) interval() time.Duration {
+	switch {
+	case ac.Interval > 0:
+		return ac.Interval
+	case ac.URL != "":
+		return DefaultAmbientHTTPInterval
+	default:
+		return DefaultAmbientInterval
+	}
+}
+
+// maxAge 返回大氣壓的最長有效時間
+func (ac AmbientConfig) maxAge() time.Duration {
+	if ac.MaxAge > 0 {
+		return ac.MaxAge
+	}
+	return 3 * ac.interval()
+}
+
+// AmbientReading 附加到讀數的大氣壓
+type AmbientReading struct {
+	Pressure  float64   `json:"pressure_hpa"` // 大氣壓 (hPa)
+	Timestamp time.Time `json:"timestamp"`    // 取得時間
+	Source    string    `json:"source"`       // 來源（檔案、命令或不含查詢參數的接口地址）
+}
+
+// AmbientSource 定期刷新的大氣壓來源
+type AmbientSource struct {
+	config AmbientConfig
+	unit   PressureUnit
+	logger *log.Logger
+	client *http.Client
+
+	mu      sync.Mutex
+	latest  *AmbientReading
+	lastErr string // 最近一次刷新的錯誤，相同錯誤只記錄一次日誌
+}
+
+// NewAmbientSource 創建大氣壓來源，logger 為空時使用默認日誌
+func NewAmbientSource(config AmbientConfig, logger *log.Logger) (*AmbientSource, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	unit, _ := config.unit()
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &AmbientSource{
+		config: config,
+		unit:   unit,
+		logger: logger,
+		client: &http.Client{Timeout: DefaultAmbientTimeout},
+	}, nil
+}
+
+// Config 返回大氣壓來源配置
+func (as *AmbientSource) Config() AmbientConfig {
+	return as.config
+}
+
+// Refresh 立即讀取一次大氣壓，失敗時保留上次的值直到過期
+func (as *AmbientSource) Refresh() (AmbientReading, error) {
+	reading, err := as.read()
+
+	as.mu.Lock()
+	if err == nil {
+		as.latest, as.lastErr = &reading, ""
+		as.mu.Unlock()
+		return reading, nil
+	}
+	repeated := as.lastErr == err.Error()
+	as.lastErr = err.Error()
+	as.mu.Unlock()
+
+	if !repeated {
+		as.logger.Printf("⚠️  讀取大氣壓失敗 (%s): %v", as.config.source(), err)
+	}
+	return AmbientReading{}, err
+}
+
+// read 讀取來源並換算為 hPa
+func (as *AmbientSource) read() (AmbientReading, error) {
+	data, err := as.fetch()
+	if err != nil {
+		return AmbientReading{}, err
+	}
+	value, err := parseAmbientValue(data, as.config.Field)
+	if err != nil {
+		return AmbientReading{}, err
+	}
+	pressure := Millibar.ConvertFromPascal(as.unit.ConvertToPascal(value))
+	if pressure < minAmbientPressure || pressure > maxAmbientPressure {
+		unit := as.config.Unit
+		if unit == "" {
+			unit = "hPa"
+		}
+		return AmbientReading{}, fmt.Errorf("大氣壓 %.1f hPa 超出合理範圍 %.0f-%.0f hPa，請檢查單位設置 (當前: %s)",
+			pressure, minAmbientPressure, maxAmbientPressure, unit)
+	}
+	return AmbientReading{Pressure: pressure, Timestamp: time.Now(), Source: as.config.source()}, nil
+}
+
+// Latest 返回 now 時仍然有效的最近一次大氣壓，沒有或已過期時返回 nil
+func (as *AmbientSource) Latest(now time.Time) *AmbientReading {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	if as.latest == nil || now.Sub(as.latest.Timestamp) > as.config.maxAge() {
+		return nil
+	}
+	reading := *as.latest
+	return &reading
+}
+
+// Run 立即讀取一次，之後按刷新間隔讀取，直到 ctx 取消
+func (as *AmbientSource) Run(ctx context.Context) {
+	as.Refresh()
+	ticker := time.NewTicker(as.config.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			as.Refresh()
+		}
+	}
+}
+
+// fetch 從來源讀取原始內容
+func (as *AmbientSource) fetch() ([]byte, error) {
+	switch {
+	case as.config.URL != "":
+		resp, err := as.client.Get(as.config.URL)
+		if err != nil {
+			// 錯誤中的完整地址可能帶有 API 密鑰，不寫入日誌
+			if urlErr, ok := err.(*url.Error); ok {
+				err = urlErr.Err
+			}
+			return nil, fmt.Errorf("請求天氣接口失敗: %v", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return nil, fmt.Errorf("讀取天氣接口響應失敗: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("天氣接口返回 %s", resp.Status)
+		}
+		return body, nil
+	case as.config.Command != "":
+		args := strings.Fields(as.config.Command)
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultAmbientTimeout)
+		defer cancel()
+		output, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+		if err != nil {
+			return nil, fmt.Errorf("執行 %s 失敗: %v", args[0], err)
+		}
+		return output, nil
+	default:
+		data, err := os.ReadFile(as.config.File)
+		if err != nil {
+			return nil, fmt.Errorf("讀取檔案失敗: %v", err)
+		}
+		return data, nil
+	}
+}
+
+// parseAmbientValue 從來源內容中取出氣壓數值：內容為單個數值時直接解析，否則按 field 路徑從 JSON 中取出
+func parseAmbientValue(data []byte, field string) (float64, error) {
+	text := strings.TrimSpace(string(data))
+	if value, err := strconv.ParseFloat(text, 64); err == nil {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return 0, fmt.Errorf("無效的氣壓數值: %s", text)
+		}
+		return value, nil
+	}
+	if field == "" {
+		return 0, fmt.Errorf("內容不是數值，輸出 JSON 時需要設置氣壓字段路徑 (field)")
+	}
+
+	var node interface{}
+	if err := json.Unmarshal(data, &node); err != nil {
+		return 0, fmt.Errorf("內容既不是數值也不是 JSON: %v", err)
+	}
+	for _, key := range strings.Split(field, ".") {
+		switch v := node.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if node, ok = v[key]; !ok {
+				return 0, fmt.Errorf("JSON 中沒有字段 %s", field)
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return 0, fmt.Errorf("JSON 字段 %s 的數組下標無效: %s", field, key)
+			}
+			node = v[index]
+		default:
+			return 0, fmt.Errorf("JSON 中沒有字段 %s", field)
+		}
+	}
+	switch v := node.(type) {
+	case float64:
+		return v, nil
+	case string:
+		if value, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("JSON 字段 %s 不是數值: %v", field, node)
+}
+
+// SetAmbient 設置大氣壓來源，監測開始後按刷新間隔讀取並附加到每個讀數（需在 Start 之前調用）
+func (m *Monitor) SetAmbient(config AmbientConfig) error {
+	source, err := NewAmbientSource(config, m.logger)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ambient = source
+	return nil
+}
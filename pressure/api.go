@@ -0,0 +1,453 @@
+// pressure/api.go - HTTP 接口，供輪詢式集成讀取當前值
+package pressure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIServer 提供 HTTP 接口的輸出目標，保存最新讀數
+//
+// 作為 Monitor 的 Sink 使用，Monitor 停止時關閉 HTTP 服務。
+type APIServer struct {
+	addr   string
+	logger *log.Logger
+	mux    *http.ServeMux
+	server *http.Server
+	bound  string        // 實際監聽地址
+	maxAge time.Duration // Cache-Control 的 max-age，通常為讀取間隔
+	trend  *TrendBuffer  // 趨勢圖使用的最近讀數
+
+	controlAddr   string // 控制接口的獨立監聽地址，空為與只讀接口共用
+	controlToken  string // 控制接口要求的 Bearer 令牌，空為不驗證
+	controlMux    *http.ServeMux
+	controlServer *http.Server
+
+	mu      sync.RWMutex
+	latest  *MonitorReading
+	alarms  []AlarmEvent // Grafana 標註使用的最近告警事件
+	monitor *Monitor
+	config  *ConfigInfo
+}
+
+// NewAPIServer 創建監聽 addr 的 HTTP 接口
+func NewAPIServer(addr string, logger *log.Logger) *APIServer {
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	as := &APIServer{
+		addr:   addr,
+		logger: logger,
+		mux:    http.NewServeMux(),
+		maxAge: DefaultReadInterval,
+		trend:  NewTrendBuffer(DefaultTrendRetention),
+
+		controlMux: http.NewServeMux(),
+	}
+	as.mux.HandleFunc("/api/v1/value", as.handleValue)
+	as.mux.HandleFunc("/api/v1/status", as.handleStatus)
+	as.mux.HandleFunc("/api/v1/chart", as.handleChart)
+	as.mux.HandleFunc("/api/v1/history", as.handleHistory)
+	as.mux.HandleFunc("/grafana/", as.handleGrafana)
+	as.mux.HandleFunc("/grafana/search", as.handleGrafanaSearch)
+	as.mux.HandleFunc("/grafana/query", as.handleGrafanaQuery)
+	as.mux.HandleFunc("/grafana/annotations", as.handleGrafanaAnnotations)
+	as.mux.HandleFunc(ControlPathPrefix, as.handleSharedControl)
+	as.controlMux.HandleFunc(ControlPathPrefix, as.handleControl)
+	return as
+}
+
+// SetMonitor 設置提供狀態快照的監測流程
+func (as *APIServer) SetMonitor(monitor *Monitor) *APIServer {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.monitor = monitor
+	return as
+}
+
+// SetConfigInfo 設置狀態快照中的配置及其來源
+func (as *APIServer) SetConfigInfo(info *ConfigInfo) *APIServer {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.config = info
+	return as
+}
+
+// SetCacheMaxAge 設置響應的 Cache-Control max-age，一般等於讀取間隔
+func (as *APIServer) SetCacheMaxAge(maxAge time.Duration) *APIServer {
+	as.maxAge = maxAge
+	return as
+}
+
+// SetControlAddr 設置控制接口的獨立監聽地址，設置後只讀接口不再提供控制操作
+func (as *APIServer) SetControlAddr(addr string) *APIServer {
+	as.controlAddr = addr
+	return as
+}
+
+// SetControlToken 設置控制接口要求的 Bearer 令牌
+func (as *APIServer) SetControlToken(token string) *APIServer {
+	as.controlToken = token
+	return as
+}
+
+// Handler 返回 HTTP 處理器，便於嵌入其他服務或測試
+func (as *APIServer) Handler() http.Handler {
+	return as.mux
+}
+
+// ControlHandler 返回獨立監聽地址上的控制接口處理器
+func (as *APIServer) ControlHandler() http.Handler {
+	return as.controlMux
+}
+
+// Start 開始監聽，監聽失敗時立即返回錯誤
+//
+// 地址為 unix:/path 形式時監聽 Unix 域套接字（控制套接字），否則監聽 TCP。
+// 啟用了控制操作而控制接口監聽非本機地址時必須設置令牌，否則拒絕啟動。
+func (as *APIServer) Start() error {
+	if err := as.checkControlScope(); err != nil {
+		return err
+	}
+
+	listener, err := listenAPI(as.addr)
+	if err != nil {
+		return fmt.Errorf("HTTP 接口監聽 %s 失敗: %v", as.addr, err)
+	}
+	as.bound = listener.Addr().String()
+	as.server = as.serve(listener, as.mux, "HTTP 接口")
+	as.logger.Printf("🌐 HTTP 接口已啟動: %s", listener.Addr())
+
+	if as.controlAddr != "" {
+		listener, err := listenAPI(as.controlAddr)
+		if err != nil {
+			as.server.Close()
+			return fmt.Errorf("控制接口監聽 %s 失敗: %v", as.controlAddr, err)
+		}
+		as.controlServer = as.serve(listener, as.controlMux, "控制接口")
+		as.logger.Printf("🔐 控制接口已啟動: %s", listener.Addr())
+	}
+	return nil
+}
+
+// listenAPI 監聽 TCP 或 unix:/path 地址
+func listenAPI(addr string) (net.Listener, error) {
+	network, address := splitAPIAddr(addr)
+	if network == "unix" {
+		// 清理上次異常退出留下的套接字檔案
+		os.Remove(address)
+	}
+	return net.Listen(network, address)
+}
+
+// serve 在 listener 上提供 handler，返回對應的 HTTP 服務
+func (as *APIServer) serve(listener net.Listener, handler http.Handler, name string) *http.Server {
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			as.logger.Printf("❌ %s出錯: %v", name, err)
+		}
+	}()
+	return server
+}
+
+// WriteReading 實現 Sink 接口，保存最新讀數
+func (as *APIServer) WriteReading(reading MonitorReading) error {
+	as.mu.Lock()
+	as.latest = &reading
+	as.mu.Unlock()
+	return as.trend.WriteReading(reading)
+}
+
+// Check 實現 SinkChecker 接口，確認 HTTP 接口可以連接
+func (as *APIServer) Check() error {
+	if as.server == nil {
+		return fmt.Errorf("HTTP 接口尚未啟動")
+	}
+	network, _ := splitAPIAddr(as.addr)
+	conn, err := net.DialTimeout(network, as.bound, 2*time.Second)
+	if err != nil {
+		return fmt.Errorf("無法連接 HTTP 接口 %s: %v", as.bound, err)
+	}
+	return conn.Close()
+}
+
+// Close 實現 Sink 接口，關閉 HTTP 服務
+func (as *APIServer) Close() error {
+	if as.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if as.controlServer != nil {
+		as.controlServer.Shutdown(ctx)
+	}
+	return as.server.Shutdown(ctx)
+}
+
+// Latest 返回最新讀數，尚無讀數時返回 false
+func (as *APIServer) Latest() (MonitorReading, bool) {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	if as.latest == nil {
+		return MonitorReading{}, false
+	}
+	return *as.latest, true
+}
+
+// handleValue 返回最新壓力值
+//
+// 默認輸出純文本數值，?format=json 或 Accept: application/json 時輸出 JSON，?format=cbor 或 Accept: application/cbor 時輸出 CBOR。
+// ?max_age=30s 指定可接受的最大讀數年齡，超過時返回 503。
+// 尚無讀數、最新讀數無效或讀數過舊時返回 503，便於 Zabbix 等輪詢工具直接判斷。
+func (as *APIServer) handleValue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := responseFormat(r)
+	if format == "" {
+		format = "text"
+	}
+	if format != "text" && format != "json" && format != "cbor" {
+		http.Error(w, fmt.Sprintf("invalid format: %s (text/json/cbor)", format), http.StatusBadRequest)
+		return
+	}
+
+	var maxAge time.Duration
+	if value := r.URL.Query().Get("max_age"); value != "" {
+		var err error
+		if maxAge, err = time.ParseDuration(value); err != nil {
+			http.Error(w, fmt.Sprintf("invalid max_age: %s", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(as.maxAge.Seconds())))
+
+	reading, ok := as.Latest()
+	if !ok {
+		w.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(as.maxAge.Seconds()))))
+		as.writeValueError(w, format, http.StatusServiceUnavailable, "尚無讀數", nil)
+		return
+	}
+
+	age := time.Since(reading.Timestamp)
+	if age < 0 {
+		age = 0
+	}
+	w.Header().Set("Age", fmt.Sprintf("%d", int(age.Seconds())))
+	w.Header().Set("Last-Modified", reading.Timestamp.UTC().Format(http.TimeFormat))
+
+	switch {
+	case !reading.Valid:
+		as.writeValueError(w, format, http.StatusServiceUnavailable, reading.Error, &reading)
+		return
+	case maxAge > 0 && age > maxAge:
+		as.writeValueError(w, format, http.StatusServiceUnavailable,
+			fmt.Sprintf("讀數已過期: %v", age.Round(time.Millisecond)), &reading)
+		return
+	}
+
+	if format == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "%.3f\n", reading.Pressure)
+		return
+	}
+
+	body := map[string]interface{}{
+		"schema_version": SchemaVersion,
+		"value":          reading.Pressure,
+		"unit":           Pascal.Symbol(),
+		"timestamp":      reading.Timestamp,
+		"age_seconds":    age.Seconds(),
+		"slave_id":       reading.SlaveID,
+	}
+	if reading.RawPressure != nil {
+		body["raw_value"] = *reading.RawPressure
+	}
+	if reading.Temperature != nil {
+		body["temperature"] = *reading.Temperature
+	}
+	writeBody(w, format, http.StatusOK, body)
+}
+
+// writeValueError 輸出無法提供當前值的原因
+func (as *APIServer) writeValueError(w http.ResponseWriter, format string, status int, message string, reading *MonitorReading) {
+	w.Header().Set("Cache-Control", "no-store")
+	if format == "text" {
+		http.Error(w, message, status)
+		return
+	}
+
+	body := map[string]interface{}{
+		"schema_version": SchemaVersion,
+		"error":          message,
+	}
+	if reading != nil {
+		body["timestamp"] = reading.Timestamp
+		body["slave_id"] = reading.SlaveID
+	}
+	writeBody(w, format, status, body)
+}
+
+// handleStatus 返回完整狀態快照，默認為 JSON，?format=cbor 或 Accept: application/cbor 時輸出 CBOR
+func (as *APIServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	format := responseFormat(r)
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "cbor" {
+		http.Error(w, fmt.Sprintf("invalid format: %s (json/cbor)", format), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	snapshot, ok := as.Snapshot()
+	if !ok {
+		writeBody(w, format, http.StatusServiceUnavailable, map[string]interface{}{
+			"schema_version": SchemaVersion,
+			"error":          "監測尚未啟動",
+		})
+		return
+	}
+	writeBody(w, format, http.StatusOK, snapshot)
+}
+
+// handleChart 返回最近一段時間的壓力趨勢圖
+//
+// ?range=1h 指定時間範圍（默認 1h，最長為趨勢緩衝的保留時長 24h），
+// ?format=png 輸出 PNG（默認 SVG），?width=&height= 指定像素尺寸。
+// 配置了告警規則時以虛線標出上下限，便於通知郵件直接嵌入超限時段的圖片。
+func (as *APIServer) handleChart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	span := time.Hour
+	if value := query.Get("range"); value != "" {
+		var err error
+		if span, err = time.ParseDuration(value); err != nil || span <= 0 {
+			http.Error(w, fmt.Sprintf("invalid range: %s", value), http.StatusBadRequest)
+			return
+		}
+	}
+	if span > as.trend.Retention() {
+		span = as.trend.Retention()
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "svg"
+	}
+	if format != "svg" && format != "png" {
+		http.Error(w, fmt.Sprintf("invalid format: %s (svg/png)", format), http.StatusBadRequest)
+		return
+	}
+
+	width, height := DefaultChartWidth, DefaultChartHeight
+	for name, target := range map[string]*int{"width": &width, "height": &height} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 100 || n > 4000 {
+			http.Error(w, fmt.Sprintf("invalid %s: %s (100-4000)", name, value), http.StatusBadRequest)
+			return
+		}
+		*target = n
+	}
+
+	end := time.Now()
+	start := end.Add(-span)
+	chart := NewChart(fmt.Sprintf("壓差趨勢 (最近 %v)", span), as.trend.Since(start)).
+		SetRange(start, end).
+		SetSize(width, height)
+	as.mu.RLock()
+	if as.config != nil {
+		chart.AddLines(AlarmChartLines(as.config.Config.Alarms)...)
+	}
+	as.mu.RUnlock()
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(as.maxAge.Seconds())))
+	if format == "png" {
+		w.Header().Set("Content-Type", "image/png")
+		chart.WritePNG(w)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+	chart.WriteSVG(w)
+}
+
+// splitAPIAddr 將監聽地址拆分為網絡類型和地址
+func splitAPIAddr(addr string) (network, address string) {
+	if strings.HasPrefix(addr, "unix:") {
+		return "unix", strings.TrimPrefix(addr, "unix:")
+	}
+	return "tcp", addr
+}
+
+// responseFormat 返回請求的響應格式：?format= 參數，未指定時按 Accept 頭判斷 json/cbor，都沒有時為空
+func responseFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, CBORContentType):
+		return "cbor"
+	case strings.Contains(accept, "application/json"):
+		return "json"
+	}
+	return ""
+}
+
+// writeBody 按格式 (json/cbor) 寫入響應
+func writeBody(w http.ResponseWriter, format string, status int, body interface{}) {
+	if format == "cbor" {
+		writeCBOR(w, status, body)
+		return
+	}
+	writeJSON(w, status, body)
+}
+
+// writeCBOR 以 CBOR 格式寫入響應
+func writeCBOR(w http.ResponseWriter, status int, body interface{}) {
+	data, err := MarshalCBOR(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", CBORContentType)
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// writeJSON 以 JSON 格式寫入響應
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,129 @@
+// pressure/backup.go - 配置檔案的自動備份和回滾
+package pressure
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxConfigBackups 每個配置檔案保留的備份數量，超出時刪除最舊的備份
+const MaxConfigBackups = 10
+
+// configBackupTimeLayout 備份檔名中的時間格式，按字典序排列即按時間排列
+const configBackupTimeLayout = "20060102-150405.000"
+
+// ConfigBackup 配置檔案的一個備份
+type ConfigBackup struct {
+	Path string    `json:"path"` // 備份檔案路徑
+	Time time.Time `json:"time"` // 備份時間
+	Size int64     `json:"size"` // 檔案大小（字節）
+}
+
+// BackupConfigFile 在修改配置檔案前保存帶時間戳的備份 <檔名>.<時間>.bak
+//
+// 檔案不存在時不備份並返回空路徑；內容與最近的備份相同時不重複備份。
+func BackupConfigFile(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("讀取配置檔案失敗: %v", err)
+	}
+
+	backups, err := ListConfigBackups(filename)
+	if err != nil {
+		return "", err
+	}
+	if len(backups) > 0 {
+		if latest, err := os.ReadFile(backups[0].Path); err == nil && bytes.Equal(latest, data) {
+			return backups[0].Path, nil
+		}
+	}
+
+	path := fmt.Sprintf("%s.%s.bak", filename, time.Now().Format(configBackupTimeLayout))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("保存配置備份失敗: %v", err)
+	}
+
+	// 只保留最近的 MaxConfigBackups 個備份
+	for i := MaxConfigBackups - 1; i < len(backups); i++ {
+		os.Remove(backups[i].Path)
+	}
+	return path, nil
+}
+
+// ListConfigBackups 返回配置檔案的備份，最近的在前
+func ListConfigBackups(filename string) ([]ConfigBackup, error) {
+	dir, base := filepath.Split(filename)
+	entries, err := os.ReadDir(filepath.Clean(dir + "."))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []ConfigBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, base+".") || !strings.HasSuffix(name, ".bak") {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, base+"."), ".bak")
+		t, err := time.ParseInLocation(configBackupTimeLayout, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backup := ConfigBackup{Path: dir + name, Time: t}
+		if info, err := entry.Info(); err == nil {
+			backup.Size = info.Size()
+		}
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.After(backups[j].Time) })
+	return backups, nil
+}
+
+// RollbackConfigFile 用最近的備份恢復配置檔案，返回使用的備份
+//
+// 恢復前的內容保存為 <檔名>.before-rollback；使用過的備份會被刪除，再次回滾時恢復更早的版本。
+func RollbackConfigFile(filename string) (*ConfigBackup, error) {
+	backups, err := ListConfigBackups(filename)
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("%s 沒有可用的備份", filename)
+	}
+	backup := backups[0]
+
+	data, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return nil, fmt.Errorf("讀取備份失敗: %v", err)
+	}
+	if current, err := os.ReadFile(filename); err == nil {
+		if err := os.WriteFile(filename+".before-rollback", current, 0644); err != nil {
+			return nil, fmt.Errorf("保存當前配置失敗: %v", err)
+		}
+	}
+	if err := writeFileAtomic(filename, data); err != nil {
+		return nil, fmt.Errorf("恢復配置檔案失敗: %v", err)
+	}
+	os.Remove(backup.Path)
+	return &backup, nil
+}
+
+// writeFileAtomic 先寫入臨時檔案再改名，避免寫到一半時留下殘缺的檔案
+func writeFileAtomic(filename string, data []byte) error {
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
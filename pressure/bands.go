@@ -0,0 +1,157 @@
+// pressure/bands.go - 壓力區間和區間變化事件（如負壓→正壓、低於/高於設定值）
+package pressure
+
+import (
+	"fmt"
+	"time"
+)
+
+// 預設區間的名稱
+const (
+	BandNegative = "negative" // 負壓
+	BandPositive = "positive" // 正壓（含 0）
+	BandBelow    = "below"    // 低於設定值 − 容差
+	BandWithin   = "within"   // 在設定值 ± 容差內
+	BandAbove    = "above"    // 高於設定值 + 容差
+)
+
+// Band 壓力區間，包含 Low、不包含 High，未設置的一側不限
+//
+// 多個區間按順序匹配，讀數歸入第一個包含它的區間。
+type Band struct {
+	Name string   `json:"name" yaml:"name"`                     // 區間名稱
+	Low  *float64 `json:"low,omitempty" yaml:"low,omitempty"`   // 下限 (Pa)，包含
+	High *float64 `json:"high,omitempty" yaml:"high,omitempty"` // 上限 (Pa)，不包含
+}
+
+// Contains 壓力值是否在區間內
+func (b Band) Contains(pressure float64) bool {
+	if b.Low != nil && pressure < *b.Low {
+		return false
+	}
+	if b.High != nil && pressure >= *b.High {
+		return false
+	}
+	return true
+}
+
+// String 返回區間的描述，如 "within [-12.50, -7.50) Pa"
+func (b Band) String() string {
+	low, high := "-∞", "+∞"
+	if b.Low != nil {
+		low = fmt.Sprintf("%.2f", *b.Low)
+	}
+	if b.High != nil {
+		high = fmt.Sprintf("%.2f", *b.High)
+	}
+	return fmt.Sprintf("%s [%s, %s) Pa", b.Name, low, high)
+}
+
+// NewSignBands 創建負壓/正壓兩個區間
+func NewSignBands() []Band {
+	zero := 0.0
+	return []Band{
+		{Name: BandNegative, High: &zero},
+		{Name: BandPositive, Low: &zero},
+	}
+}
+
+// NewSetpointBands 創建低於、位於、高於設定值 ± 容差的三個區間
+func NewSetpointBands(setpoint, tolerance float64) []Band {
+	low, high := setpoint-tolerance, setpoint+tolerance
+	return []Band{
+		{Name: BandBelow, High: &low},
+		{Name: BandWithin, Low: &low, High: &high},
+		{Name: BandAbove, Low: &high},
+	}
+}
+
+// ValidateBands 檢查區間名稱不為空且不重複，上限大於下限
+func ValidateBands(bands []Band) error {
+	seen := make(map[string]bool)
+	for i, band := range bands {
+		if band.Name == "" {
+			return fmt.Errorf("第 %d 個區間未設置名稱", i+1)
+		}
+		if seen[band.Name] {
+			return fmt.Errorf("區間名稱重複: %s", band.Name)
+		}
+		seen[band.Name] = true
+		if band.Low != nil && band.High != nil && *band.High <= *band.Low {
+			return fmt.Errorf("區間 %s 的上限 %.2f 必須大於下限 %.2f", band.Name, *band.High, *band.Low)
+		}
+	}
+	return nil
+}
+
+// ClassifyBand 返回壓力值所在的第一個區間名稱，不在任何區間內時返回空字符串
+func ClassifyBand(bands []Band, pressure float64) string {
+	for _, band := range bands {
+		if band.Contains(pressure) {
+			return band.Name
+		}
+	}
+	return ""
+}
+
+// BandEvent 讀數進入另一個區間的事件
+//
+// 第一個有效讀數也會產生事件（From 為空），下游可據此得知初始狀態。
+type BandEvent struct {
+	From      string          `json:"from"`               // 原區間，首次分類或原讀數不在任何區間時為空
+	To        string          `json:"to"`                 // 新區間，不在任何區間時為空
+	Message   string          `json:"message"`            // 描述
+	Reading   PressureReading `json:"reading"`            // 觸發變化的讀數
+	Location  *Location       `json:"location,omitempty"` // 安裝位置（設置了位置對照表時）
+	Timestamp time.Time       `json:"timestamp"`          // 事件時間
+}
+
+// BandSink 可選接口，輸出目標實現後會收到區間變化事件
+type BandSink interface {
+	WriteBand(event BandEvent) error
+}
+
+// AddBand 添加壓力區間，按添加順序匹配
+func (m *Monitor) AddBand(band Band) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bands = append(m.bands, band)
+	return m
+}
+
+// evaluateBands 對有效讀數分類，所在區間變化時返回事件（調用方需持有鎖）
+func (m *Monitor) evaluateBands(reading PressureReading, location *Location) []BandEvent {
+	if len(m.bands) == 0 || !reading.Valid {
+		return nil
+	}
+
+	band := ClassifyBand(m.bands, reading.Pressure)
+	if m.bandKnown && band == m.stats.Band {
+		return nil
+	}
+
+	from := m.stats.Band
+	message := fmt.Sprintf("%.2f Pa 位於區間 %s", reading.Pressure, bandLabel(band))
+	if m.bandKnown {
+		message = fmt.Sprintf("%.2f Pa 從區間 %s 進入 %s", reading.Pressure, bandLabel(from), bandLabel(band))
+		m.stats.BandChanges++
+	}
+	m.bandKnown = true
+	m.stats.Band = band
+	return []BandEvent{{
+		From:      from,
+		To:        band,
+		Message:   message,
+		Reading:   reading,
+		Location:  location,
+		Timestamp: time.Now(),
+	}}
+}
+
+// bandLabel 返回用於描述的區間名稱，不在任何區間時為 "(無)"
+func bandLabel(name string) string {
+	if name == "" {
+		return "(無)"
+	}
+	return name
+}
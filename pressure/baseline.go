@@ -0,0 +1,343 @@
+// pressure/baseline.go - 按時段學習壓力基線（風壓、煙囪效應等晝夜變化），偏離基線時告警
+package pressure
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"time"
+)
+
+// BaselineAlarmRule 基線偏離告警的規則名稱
+const BaselineAlarmRule = "baseline_anomaly"
+
+// 基線學習的默認參數
+const (
+	DefaultBaselineSlot         = time.Hour // 時段長度
+	DefaultBaselineLearnDays    = 3         // 每個時段學習多少天後開始判斷
+	DefaultBaselineMemoryDays   = 7         // 指數加權的記憶長度（天）
+	DefaultBaselineSigma        = 4         // 偏離多少個標準差視為異常
+	DefaultBaselineMinDeviation = 2.0       // 最小偏離 (Pa)，避免穩定時段的微小波動觸發告警
+	DefaultBaselinePersist      = 10        // 連續多少個異常讀數後觸發告警
+)
+
+// BaselineConfig 基線學習配置，未設置的字段使用默認值
+//
+// 受天氣影響的建築，室壓會隨風壓和室內外溫差（煙囪效應）按晝夜規律變化，
+// 固定閾值要麼誤報、要麼過寬。基線按一天內的時段分別學習讀數的均值和方差，
+// 讀數明顯偏離同一時段的正常範圍時才告警。
+type BaselineConfig struct {
+	File         string        `json:"file,omitempty" yaml:"file,omitempty"`                 // 基線保存檔案，為空時只在內存中學習（重啟後重新學習）
+	Slot         time.Duration `json:"slot,omitempty" yaml:"slot,omitempty"`                 // 時段長度，須整除 24h，默認 1h
+	LearnDays    float64       `json:"learndays,omitempty" yaml:"learndays,omitempty"`       // 每個時段學習多少天後開始判斷，默認 3
+	MemoryDays   float64       `json:"memorydays,omitempty" yaml:"memorydays,omitempty"`     // 指數加權的記憶長度（天），默認 7
+	Sigma        float64       `json:"sigma,omitempty" yaml:"sigma,omitempty"`               // 偏離多少個標準差視為異常，默認 4
+	MinDeviation float64       `json:"mindeviation,omitempty" yaml:"mindeviation,omitempty"` // 最小偏離 (Pa)，默認 2
+	Persist      int           `json:"persist,omitempty" yaml:"persist,omitempty"`           // 連續多少個異常讀數後觸發告警，默認 10
+}
+
+// withDefaults 返回填入默認值後的配置
+func (bc BaselineConfig) withDefaults() BaselineConfig {
+	if bc.Slot == 0 {
+		bc.Slot = DefaultBaselineSlot
+	}
+	if bc.LearnDays == 0 {
+		bc.LearnDays = DefaultBaselineLearnDays
+	}
+	if bc.MemoryDays == 0 {
+		bc.MemoryDays = DefaultBaselineMemoryDays
+	}
+	if bc.Sigma == 0 {
+		bc.Sigma = DefaultBaselineSigma
+	}
+	if bc.MinDeviation == 0 {
+		bc.MinDeviation = DefaultBaselineMinDeviation
+	}
+	if bc.Persist == 0 {
+		bc.Persist = DefaultBaselinePersist
+	}
+	return bc
+}
+
+// Validate 檢查基線配置
+func (bc BaselineConfig) Validate() error {
+	bc = bc.withDefaults()
+	if bc.Slot < 5*time.Minute || bc.Slot > 24*time.Hour || (24*time.Hour)%bc.Slot != 0 {
+		return fmt.Errorf("基線時段長度必須在 5m 到 24h 之間且整除 24h，當前: %v", bc.Slot)
+	}
+	if bc.LearnDays < 0 || bc.MemoryDays < 0 || bc.Sigma < 0 || bc.MinDeviation < 0 || bc.Persist < 0 {
+		return fmt.Errorf("基線參數不能為負數")
+	}
+	if bc.MemoryDays < bc.LearnDays {
+		return fmt.Errorf("基線記憶長度 %g 天不能短於學習天數 %g 天", bc.MemoryDays, bc.LearnDays)
+	}
+	return nil
+}
+
+// String 返回基線配置的簡短描述
+func (bc BaselineConfig) String() string {
+	bc = bc.withDefaults()
+	file := "僅內存"
+	if bc.File != "" {
+		file = bc.File
+	}
+	return fmt.Sprintf("時段 %v, 學習 %g 天, 記憶 %g 天, %gσ 且 ≥ %.2f Pa, 連續 %d 次 (%s)",
+		bc.Slot, bc.LearnDays, bc.MemoryDays, bc.Sigma, bc.MinDeviation, bc.Persist, file)
+}
+
+// BaselineSlot 一個時段學到的讀數分佈
+type BaselineSlot struct {
+	Samples  int     `json:"samples"`  // 已學習的讀數
+	Mean     float64 `json:"mean"`     // 均值 (Pa)
+	Variance float64 `json:"variance"` // 方差 (Pa²)
+}
+
+// StdDev 返回標準差 (Pa)
+func (bs BaselineSlot) StdDev() float64 {
+	return math.Sqrt(bs.Variance)
+}
+
+// Baseline 按一天內的時段（本地時間）學習的壓力基線
+type Baseline struct {
+	Device    string         `json:"device"`     // 設備
+	SlaveID   byte           `json:"slave_id"`   // 站點號
+	Slot      time.Duration  `json:"slot"`       // 時段長度
+	Slots     []BaselineSlot `json:"slots"`      // 各時段的分佈，從 00:00 開始
+	UpdatedAt time.Time      `json:"updated_at"` // 最後學習時間
+}
+
+// NewBaseline 創建空的基線
+func NewBaseline(device string, slaveID byte, slot time.Duration) *Baseline {
+	return &Baseline{
+		Device:  device,
+		SlaveID: slaveID,
+		Slot:    slot,
+		Slots:   make([]BaselineSlot, int(24*time.Hour/slot)),
+	}
+}
+
+// LoadBaseline 從檔案讀取基線
+func LoadBaseline(filename string) (*Baseline, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("解析基線檔案失敗: %v", err)
+	}
+	if baseline.Slot <= 0 || len(baseline.Slots) != int(24*time.Hour/baseline.Slot) {
+		return nil, fmt.Errorf("基線檔案的時段數量與時段長度不一致")
+	}
+	return &baseline, nil
+}
+
+// Save 將基線寫入檔案（先寫臨時檔案再替換，避免中斷時損壞）
+func (b *Baseline) Save(filename string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("寫入基線檔案失敗: %v", err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		return fmt.Errorf("寫入基線檔案失敗: %v", err)
+	}
+	return nil
+}
+
+// SlotIndex 返回時間所在的時段
+func (b *Baseline) SlotIndex(t time.Time) int {
+	t = t.Local()
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+	return int(sinceMidnight / b.Slot)
+}
+
+// SlotStart 返回時段的開始時刻描述，如 "14:00"
+func (b *Baseline) SlotStart(index int) string {
+	start := time.Duration(index) * b.Slot
+	return fmt.Sprintf("%02d:%02d", int(start.Hours()), int(start.Minutes())%60)
+}
+
+// Update 以讀數更新所在時段的分佈
+//
+// 樣本不足 1/alpha 時按算術平均累積，之後按 alpha 指數加權，使基線隨季節緩慢跟隨。
+func (b *Baseline) Update(t time.Time, pressure, alpha float64) {
+	slot := &b.Slots[b.SlotIndex(t)]
+	slot.Samples++
+	weight := 1 / float64(slot.Samples)
+	if weight < alpha {
+		weight = alpha
+	}
+	delta := pressure - slot.Mean
+	slot.Mean += weight * delta
+	slot.Variance = (1 - weight) * (slot.Variance + weight*delta*delta)
+	b.UpdatedAt = t
+}
+
+// LearnedSlots 返回樣本數達到 minSamples 的時段數量
+func (b *Baseline) LearnedSlots(minSamples int) int {
+	learned := 0
+	for _, slot := range b.Slots {
+		if slot.Samples >= minSamples {
+			learned++
+		}
+	}
+	return learned
+}
+
+// baselineState 監測流程中的基線學習狀態
+type baselineState struct {
+	config     BaselineConfig
+	baseline   *Baseline
+	alpha      float64 // 指數加權係數
+	minSamples int     // 時段開始判斷所需的樣本數
+	streak     int     // 連續異常讀數
+	active     bool    // 告警是否觸發中
+	savedSlot  int     // 上次保存時所在的時段
+}
+
+// newBaselineState 根據配置和讀取間隔創建基線學習狀態，配置了檔案時載入已學習的基線
+func newBaselineState(config BaselineConfig, device string, slaveID byte, interval time.Duration, logger *log.Logger) *baselineState {
+	config = config.withDefaults()
+	perDay := float64(config.Slot) / float64(interval) // 每個時段每天的讀數
+	state := &baselineState{
+		config:     config,
+		alpha:      1 / (config.MemoryDays * perDay),
+		minSamples: int(math.Ceil(config.LearnDays * perDay)),
+		savedSlot:  -1,
+	}
+
+	if config.File != "" {
+		baseline, err := LoadBaseline(config.File)
+		switch {
+		case err == nil && baseline.Slot == config.Slot:
+			if baseline.Device != device || baseline.SlaveID != slaveID {
+				logger.Printf("⚠️  基線檔案來自 %s 站點 %d，繼續用於當前設備", baseline.Device, baseline.SlaveID)
+			}
+			baseline.Device, baseline.SlaveID = device, slaveID
+			state.baseline = baseline
+			logger.Printf("已載入基線 %s (已學習 %d/%d 個時段)", config.File,
+				baseline.LearnedSlots(state.minSamples), len(baseline.Slots))
+		case err == nil:
+			logger.Printf("⚠️  基線檔案的時段長度 %v 與配置 %v 不同，重新學習", baseline.Slot, config.Slot)
+		case !os.IsNotExist(err):
+			logger.Printf("⚠️  讀取基線檔案失敗，重新學習: %v", err)
+		}
+	}
+	if state.baseline == nil {
+		state.baseline = NewBaseline(device, slaveID, config.Slot)
+	}
+	return state
+}
+
+// SetBaseline 啟用基線學習和基線偏離告警（需在 Start 之前調用）
+func (m *Monitor) SetBaseline(config BaselineConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.baseline = newBaselineState(config, m.stats.Device, m.config.SlaveID, m.interval, m.logger)
+	m.stats.BaselineLearned = m.baseline.baseline.LearnedSlots(m.baseline.minSamples)
+	return nil
+}
+
+// Baseline 返回當前學到的基線副本，未啟用基線學習時返回 nil
+func (m *Monitor) Baseline() *Baseline {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.baseline == nil {
+		return nil
+	}
+	baseline := *m.baseline.baseline
+	baseline.Slots = append([]BaselineSlot(nil), baseline.Slots...)
+	return &baseline
+}
+
+// evaluateBaseline 將有效讀數與所在時段的基線比較，再用讀數更新基線（調用方需持有鎖）
+//
+// 時段學習完成前只學習不判斷。偏離基線的讀數同樣參與學習，
+// 建築工況永久改變時基線會在記憶長度內跟上，無需手動重置。
+func (m *Monitor) evaluateBaseline(reading PressureReading, location *Location) []AlarmEvent {
+	bs := m.baseline
+	if bs == nil || !reading.Valid {
+		return nil
+	}
+
+	index := bs.baseline.SlotIndex(reading.Timestamp)
+	slot := bs.baseline.Slots[index]
+	bs.baseline.Update(reading.Timestamp, reading.Pressure, bs.alpha)
+	m.stats.BaselineLearned = bs.baseline.LearnedSlots(bs.minSamples)
+	if slot.Samples < bs.minSamples {
+		return nil
+	}
+
+	deviation := reading.Pressure - slot.Mean
+	limit := math.Max(bs.config.Sigma*slot.StdDev(), bs.config.MinDeviation)
+	if math.Abs(deviation) <= limit {
+		bs.streak = 0
+		if !bs.active {
+			return nil
+		}
+		bs.active = false
+		return []AlarmEvent{{
+			Rule:      BaselineAlarmRule,
+			Active:    false,
+			Message:   fmt.Sprintf("%.2f Pa 已回到 %s 時段基線 %.2f±%.2f Pa 內", reading.Pressure, bs.baseline.SlotStart(index), slot.Mean, limit),
+			Reading:   reading,
+			Location:  location,
+			Timestamp: time.Now(),
+		}}
+	}
+
+	m.stats.BaselineAnomalies++
+	bs.streak++
+	if bs.active || bs.streak < bs.config.Persist {
+		return nil
+	}
+
+	bs.active = true
+	sigmas := math.Inf(1)
+	if std := slot.StdDev(); std > 0 {
+		sigmas = math.Abs(deviation) / std
+	}
+	return []AlarmEvent{{
+		Rule:   BaselineAlarmRule,
+		Active: true,
+		Message: fmt.Sprintf("%.2f Pa 連續 %d 次偏離 %s 時段基線 %.2f Pa (%+.2f Pa, %.1fσ)",
+			reading.Pressure, bs.streak, bs.baseline.SlotStart(index), slot.Mean, deviation, sigmas),
+		Reading:   reading,
+		Location:  location,
+		Timestamp: time.Now(),
+	}}
+}
+
+// saveBaseline 時段變化或 force 時將基線保存到檔案
+func (m *Monitor) saveBaseline(force bool) {
+	m.mu.Lock()
+	bs := m.baseline
+	if bs == nil || bs.config.File == "" {
+		m.mu.Unlock()
+		return
+	}
+	index := bs.baseline.SlotIndex(time.Now())
+	if !force && index == bs.savedSlot {
+		m.mu.Unlock()
+		return
+	}
+	bs.savedSlot = index
+	baseline := *bs.baseline
+	baseline.Slots = append([]BaselineSlot(nil), baseline.Slots...)
+	m.mu.Unlock()
+
+	if err := baseline.Save(bs.config.File); err != nil {
+		m.logger.Printf("保存基線失敗: %v", err)
+	}
+}
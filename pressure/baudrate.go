@@ -0,0 +1,170 @@
+// pressure/baudrate.go - 遠程修改儀表波特率（總線遷移到更高速率）
+package pressure
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// BaudRateSwitchDelay 寫入波特率後等待儀表切換的時間
+const BaudRateSwitchDelay = 500 * time.Millisecond
+
+// SupportsBaudRateChange 設備是否配置了波特率寄存器
+func (pm *PressureMeter) SupportsBaudRateChange() bool {
+	return pm.baudRegister != 0
+}
+
+// BaudRate 返回當前使用的串口波特率
+func (pm *PressureMeter) BaudRate() int {
+	return pm.baudRate
+}
+
+// SetBaudRate 將儀表波特率改為 newRate，以新波特率重新打開串口並讀回確認
+//
+// 寫入前先確認波特率寄存器的值與當前波特率一致，避免寫到無關的寄存器。儀表通常在響應寫入後才切換，
+// 部分儀表立即切換，舊波特率的寫入響應可能出錯，因此寫入出錯時仍以新波特率的讀回結果為準。
+// 讀回失敗時改回原波特率打開串口（部分儀表需要重新上電才使用新波特率）。成功後本實例以新波特率通信。
+func (pm *PressureMeter) SetBaudRate(newRate int) error {
+	if !pm.SupportsBaudRateChange() {
+		return fmt.Errorf("未配置波特率寄存器 (baudrateregister)")
+	}
+	if !IsValidBaudRate(newRate) {
+		return fmt.Errorf("不支援的波特率: %d，支援: %v", newRate, GetSupportedBaudRates())
+	}
+	if err := pm.checkControl(); err != nil {
+		return err
+	}
+	oldRate := pm.baudRate
+	if newRate == oldRate {
+		return fmt.Errorf("新波特率與當前波特率相同: %d", newRate)
+	}
+	if !canSwitchBaudRate(pm.handler) {
+		return fmt.Errorf("只有本地 RTU 串口可以切換波特率，Modbus TCP 網關和串口服務器請在網關上設置")
+	}
+	value, err := encodeBaudRate(newRate, pm.baudCodes)
+	if err != nil {
+		return err
+	}
+	expected, err := encodeBaudRate(oldRate, pm.baudCodes)
+	if err != nil {
+		return fmt.Errorf("當前波特率 %v", err)
+	}
+
+	pm.busMu.Lock()
+	defer pm.busMu.Unlock()
+	current, err := pm.readRegister(pm.baudRegister)
+	if err != nil {
+		return fmt.Errorf("讀取波特率寄存器失敗: %v", err)
+	}
+	if current != expected {
+		return fmt.Errorf("波特率寄存器 0x%04X 的值 %d 與當前波特率 %d (%d) 不一致，請確認寄存器地址和 baudratecodes",
+			pm.baudRegister, current, oldRate, expected)
+	}
+
+	_, writeErr := pm.client.WriteSingleRegister(pm.baudRegister, value)
+	if writeErr != nil {
+		pm.logger.Printf("寫入波特率的響應異常，以讀回結果為準: %v", writeErr)
+	}
+	time.Sleep(BaudRateSwitchDelay)
+
+	// 以新波特率讀回確認
+	if err := pm.reopenAt(newRate); err != nil {
+		return err
+	}
+	readback, err := pm.readRegister(pm.baudRegister)
+	if err != nil || readback != value {
+		if reopenErr := pm.reopenAt(oldRate); reopenErr != nil {
+			pm.logger.Printf("⚠️  改回波特率 %d 失敗: %v", oldRate, reopenErr)
+		}
+		if err == nil {
+			err = fmt.Errorf("讀回值 %d", readback)
+		}
+		if writeErr != nil {
+			return fmt.Errorf("修改波特率失敗: 寫入: %v；以 %d 讀回: %v", writeErr, newRate, err)
+		}
+		return fmt.Errorf("修改波特率失敗: 以 %d 讀回: %v (部分儀表需要重新上電後才使用新波特率)", newRate, err)
+	}
+
+	pm.logger.Printf("波特率已從 %d 改為 %d (寄存器 0x%04X)", oldRate, newRate, pm.baudRegister)
+	return nil
+}
+
+// reopenAt 以指定波特率重新打開串口
+func (pm *PressureMeter) reopenAt(baudRate int) error {
+	pm.connMu.Lock()
+	defer pm.connMu.Unlock()
+
+	if h, ok := pm.handler.(*modbus.RTUClientHandler); ok {
+		h.Close()
+		h.BaudRate = baudRate
+		if err := pm.connect(); err != nil {
+			pm.connected = false
+			pm.backoff = OpenRetryInitialBackoff
+			pm.nextConnect = time.Now().Add(pm.backoff)
+			return fmt.Errorf("以波特率 %d 重新打開 %s 失敗: %v", baudRate, pm.endpoint, err)
+		}
+		pm.connected = true
+	}
+	pm.baudRate = baudRate
+	return nil
+}
+
+// canSwitchBaudRate 處理器是否可以切換波特率，模擬設備沒有物理線路，直接視為已切換
+func canSwitchBaudRate(handler ModbusTransport) bool {
+	switch handler.(type) {
+	case *modbus.RTUClientHandler, *MockTransport, *Simulator:
+		return true
+	}
+	return false
+}
+
+// encodeBaudRate 返回波特率寄存器中表示 baudRate 的值：配置了序號表時為序號，否則為波特率本身
+func encodeBaudRate(baudRate int, codes []int) (uint16, error) {
+	if len(codes) == 0 {
+		if baudRate > 0xFFFF {
+			return 0, fmt.Errorf("波特率 %d 超出 16 位寄存器的範圍，請配置 baudratecodes", baudRate)
+		}
+		return uint16(baudRate), nil
+	}
+	for i, code := range codes {
+		if code == baudRate {
+			return uint16(i), nil
+		}
+	}
+	return 0, fmt.Errorf("波特率 %d 不在 baudratecodes %v 中", baudRate, codes)
+}
+
+// ParseBaudRateCodes 解析以逗號分隔的波特率序號表，如 "2400,4800,9600,19200"
+func ParseBaudRateCodes(value string) ([]int, error) {
+	var codes []int
+	for _, part := range strings.Split(value, ",") {
+		rate, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("無效的波特率: %s", part)
+		}
+		codes = append(codes, rate)
+	}
+	if err := CheckBaudRateCodes(codes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CheckBaudRateCodes 檢查波特率序號表中的波特率都受支援且不重複
+func CheckBaudRateCodes(codes []int) error {
+	seen := make(map[int]bool)
+	for i, rate := range codes {
+		if !IsValidBaudRate(rate) {
+			return fmt.Errorf("baudratecodes 第 %d 項 %d 不是支援的波特率 %v", i, rate, GetSupportedBaudRates())
+		}
+		if seen[rate] {
+			return fmt.Errorf("baudratecodes 中的波特率 %d 重複", rate)
+		}
+		seen[rate] = true
+	}
+	return nil
+}
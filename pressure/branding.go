@@ -0,0 +1,70 @@
+// pressure/branding.go - OEM 品牌設置：產品名稱、版本和啟動橫幅
+package pressure
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BannerNone 不顯示啟動橫幅
+const BannerNone = "none"
+
+// Branding 嵌入其他產品時使用的名稱、版本和啟動橫幅，未設置的字段使用本工具的默認值
+type Branding struct {
+	ProductName    string `json:"productname,omitempty" yaml:"productname,omitempty"`       // 產品名稱，用於 --version、啟動橫幅和狀態快照
+	ProductVersion string `json:"productversion,omitempty" yaml:"productversion,omitempty"` // 產品版本
+	Banner         string `json:"banner,omitempty" yaml:"banner,omitempty"`                 // 啟動橫幅：為空使用默認橫幅，none 不顯示，其他文字取代默認橫幅
+	NoEmoji        bool   `json:"noemoji,omitempty" yaml:"noemoji,omitempty"`               // 啟動橫幅和版本信息不使用表情符號
+}
+
+// Name 返回產品名稱，未設置時返回 fallback
+func (b *Branding) Name(fallback string) string {
+	if b == nil || b.ProductName == "" {
+		return fallback
+	}
+	return b.ProductName
+}
+
+// Version 返回產品版本，未設置時返回 fallback
+func (b *Branding) Version(fallback string) string {
+	if b == nil || b.ProductVersion == "" {
+		return fallback
+	}
+	return b.ProductVersion
+}
+
+// HideBanner 是否不顯示啟動橫幅
+func (b *Branding) HideBanner() bool {
+	return b != nil && strings.EqualFold(strings.TrimSpace(b.Banner), BannerNone)
+}
+
+// CustomBanner 返回自定義啟動橫幅，{name} 和 {version} 替換為產品名稱和版本；使用默認橫幅時返回空字符串
+func (b *Branding) CustomBanner(name, version string) string {
+	if b == nil || b.Banner == "" || b.HideBanner() {
+		return ""
+	}
+	return strings.NewReplacer("{name}", name, "{version}", version).Replace(b.Banner)
+}
+
+// Emoji 返回帶表情符號的前綴，設置了 NoEmoji 時返回空字符串
+func (b *Branding) Emoji(emoji string) string {
+	if b != nil && b.NoEmoji {
+		return ""
+	}
+	return emoji
+}
+
+// String 返回品牌設置的簡短描述
+func (b *Branding) String() string {
+	banner := "默認橫幅"
+	switch {
+	case b.HideBanner():
+		banner = "不顯示橫幅"
+	case b.Banner != "":
+		banner = "自定義橫幅"
+	}
+	if b.NoEmoji {
+		banner += "，無表情符號"
+	}
+	return fmt.Sprintf("%s %s (%s)", b.Name("默認名稱"), b.Version(""), banner)
+}
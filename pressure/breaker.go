@@ -0,0 +1,303 @@
+// pressure/breaker.go - 輸出目標的錯誤隔離和熔斷：單個輸出目標故障不影響其他目標
+package pressure
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 輸出目標熔斷的默認參數
+const (
+	DefaultSinkFailureBudget = 5                // 連續失敗多少次後暫停輸出
+	DefaultSinkRetryInterval = 30 * time.Second // 暫停後第一次重試的等待時間
+	MaxSinkRetryInterval     = 10 * time.Minute // 重試仍失敗時等待時間加倍的上限
+	DefaultSinkEventBacklog  = 100              // 暫停或輸出失敗期間每個輸出目標最多保留的告警（區間事件另計）
+	DefaultSinkWriteTimeout  = 10 * time.Second // 每次向輸出目標寫入讀數和事件的期限
+)
+
+// 輸出目標的熔斷狀態
+const (
+	SinkStateClosed = "closed" // 正常輸出
+	SinkStateOpen   = "open"   // 暫停輸出，等待重試
+)
+
+// SinkBreakerPolicy 輸出目標的錯誤預算和重試間隔，未設置的字段使用默認值
+type SinkBreakerPolicy struct {
+	FailureBudget int           `json:"failure_budget"` // 連續失敗多少次後暫停輸出，0 為 DefaultSinkFailureBudget
+	RetryInterval time.Duration `json:"retry_interval"` // 暫停後第一次重試的等待時間，0 為 DefaultSinkRetryInterval
+	WriteTimeout  time.Duration `json:"write_timeout"`  // 每次寫入的期限，超時計為一次失敗，0 為 DefaultSinkWriteTimeout
+}
+
+// Validate 檢查策略
+func (sp SinkBreakerPolicy) Validate() error {
+	if sp.FailureBudget < 0 {
+		return fmt.Errorf("輸出目標的錯誤預算不能為負數: %d", sp.FailureBudget)
+	}
+	if sp.RetryInterval < 0 {
+		return fmt.Errorf("輸出目標的重試間隔不能為負數: %v", sp.RetryInterval)
+	}
+	if sp.WriteTimeout < 0 {
+		return fmt.Errorf("輸出目標的寫入期限不能為負數: %v", sp.WriteTimeout)
+	}
+	return nil
+}
+
+// withDefaults 返回填入默認值後的策略
+func (sp SinkBreakerPolicy) withDefaults() SinkBreakerPolicy {
+	if sp.FailureBudget == 0 {
+		sp.FailureBudget = DefaultSinkFailureBudget
+	}
+	if sp.RetryInterval == 0 {
+		sp.RetryInterval = DefaultSinkRetryInterval
+	}
+	if sp.WriteTimeout == 0 {
+		sp.WriteTimeout = DefaultSinkWriteTimeout
+	}
+	return sp
+}
+
+// SinkHealth 輸出目標的健康狀態
+type SinkHealth struct {
+	Name                string     `json:"name"`                    // 輸出目標類型和添加順序，如 "main.consoleSink#1"
+	State               string     `json:"state"`                   // closed 正常，open 暫停輸出
+	Writes              int        `json:"writes"`                  // 成功輸出的讀數
+	Failures            int        `json:"failures"`                // 輸出失敗次數（讀數、告警和區間事件）
+	ConsecutiveFailures int        `json:"consecutive_failures"`    // 當前連續失敗的讀數
+	Skipped             int        `json:"skipped"`                 // 暫停期間跳過的讀數
+	Trips               int        `json:"trips"`                   // 暫停輸出的次數
+	PendingEvents       int        `json:"pending_events"`          // 等待補發的告警和區間事件
+	DroppedEvents       int        `json:"dropped_events"`          // 超出保留上限而丟棄的告警和區間事件
+	LastError           string     `json:"last_error,omitempty"`    // 最近一次錯誤
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"` // 最近一次錯誤的時間
+	NextRetry           *time.Time `json:"next_retry,omitempty"`    // 暫停時下一次重試的時間
+}
+
+// managedSink 監測流程中的輸出目標及其熔斷狀態
+//
+// 暫停期間跳過的讀數不補發，告警和區間事件則保留下來，在下次輸出時先於新事件補發，
+// 保證告警觸發和解除不會因輸出目標短暫故障而遺失。
+type managedSink struct {
+	sink          Sink
+	policy        *SinkBreakerPolicy // 單獨設置的策略，為空時使用監測流程的策略
+	health        SinkHealth
+	backoff       time.Duration // 當前重試等待時間
+	pendingAlarms []AlarmEvent  // 等待補發的告警事件
+	pendingBands  []BandEvent   // 等待補發的區間事件
+	inflight      chan struct{} // 超時後仍在背景執行的寫入，完成時關閉；只由監測流程的分發循環訪問
+}
+
+// sinkResult 一次寫入的結果
+type sinkResult struct {
+	errs         []error
+	failedAlarms []AlarmEvent
+	failedBands  []BandEvent
+}
+
+// newManagedSink 包裝第 index 個（從 1 開始）輸出目標
+func newManagedSink(sink Sink, index int, policy *SinkBreakerPolicy) *managedSink {
+	name := fmt.Sprintf("%s#%d", strings.TrimPrefix(fmt.Sprintf("%T", sink), "*"), index)
+	return &managedSink{sink: sink, policy: policy, health: SinkHealth{Name: name, State: SinkStateClosed}}
+}
+
+// SetSinkBreaker 設置輸出目標默認的錯誤預算和重試間隔
+func (m *Monitor) SetSinkBreaker(policy SinkBreakerPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sinkPolicy = policy
+	return nil
+}
+
+// AddSinkWithBreaker 添加使用單獨錯誤預算和重試間隔的輸出目標
+func (m *Monitor) AddSinkWithBreaker(sink Sink, policy SinkBreakerPolicy) (*Monitor, error) {
+	if err := policy.Validate(); err != nil {
+		return m, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sinks = append(m.sinks, newManagedSink(sink, len(m.sinks)+1, &policy))
+	return m, nil
+}
+
+// SinkHealth 返回各輸出目標的健康狀態
+func (m *Monitor) SinkHealth() []SinkHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sinkHealthLocked()
+}
+
+// sinkHealthLocked 返回各輸出目標的健康狀態副本（調用方需持有鎖）
+func (m *Monitor) sinkHealthLocked() []SinkHealth {
+	health := make([]SinkHealth, 0, len(m.sinks))
+	for _, ms := range m.sinks {
+		health = append(health, ms.health)
+	}
+	return health
+}
+
+// allow 返回本次是否向輸出目標寫入：暫停期間到達重試時間前跳過（調用方需持有鎖）
+func (ms *managedSink) allow(now time.Time) bool {
+	if ms.health.State == SinkStateClosed || !now.Before(*ms.health.NextRetry) {
+		return true
+	}
+	ms.health.Skipped++
+	return false
+}
+
+// hold 保留未能輸出的告警和區間事件，各自超出 DefaultSinkEventBacklog 時丟棄最舊的事件（調用方需持有鎖）
+//
+// 只保留輸出目標能接收的事件類型。
+func (ms *managedSink) hold(events []AlarmEvent, bandEvents []BandEvent) {
+	if _, ok := ms.sink.(AlarmSink); ok {
+		ms.pendingAlarms = append(ms.pendingAlarms, events...)
+		if over := len(ms.pendingAlarms) - DefaultSinkEventBacklog; over > 0 {
+			ms.pendingAlarms = append([]AlarmEvent(nil), ms.pendingAlarms[over:]...)
+			ms.health.DroppedEvents += over
+		}
+	}
+	if _, ok := ms.sink.(BandSink); ok {
+		ms.pendingBands = append(ms.pendingBands, bandEvents...)
+		if over := len(ms.pendingBands) - DefaultSinkEventBacklog; over > 0 {
+			ms.pendingBands = append([]BandEvent(nil), ms.pendingBands[over:]...)
+			ms.health.DroppedEvents += over
+		}
+	}
+	ms.health.PendingEvents = len(ms.pendingAlarms) + len(ms.pendingBands)
+}
+
+// takePending 返回待補發事件和本次事件，補發的事件在前，並清空保留的事件（調用方需持有鎖）
+func (ms *managedSink) takePending(events []AlarmEvent, bandEvents []BandEvent) ([]AlarmEvent, []BandEvent) {
+	if len(ms.pendingAlarms) > 0 {
+		events = append(ms.pendingAlarms, events...)
+		ms.pendingAlarms = nil
+	}
+	if len(ms.pendingBands) > 0 {
+		bandEvents = append(ms.pendingBands, bandEvents...)
+		ms.pendingBands = nil
+	}
+	ms.health.PendingEvents = 0
+	return events, bandEvents
+}
+
+// policyFor 返回輸出目標生效的策略：單獨設置的策略或監測流程的策略，並填入默認值
+func (ms *managedSink) policyFor(defaults SinkBreakerPolicy) SinkBreakerPolicy {
+	policy := defaults
+	if ms.policy != nil {
+		policy = *ms.policy
+	}
+	return policy.withDefaults()
+}
+
+// record 記錄一次輸出結果，返回狀態變化時需要記錄的日誌（調用方需持有鎖）
+func (ms *managedSink) record(errs []error, defaults SinkBreakerPolicy, now time.Time) string {
+	policy := ms.policyFor(defaults)
+
+	if len(errs) == 0 {
+		ms.health.Writes++
+		ms.health.ConsecutiveFailures = 0
+		if ms.health.State == SinkStateClosed {
+			return ""
+		}
+		ms.health.State = SinkStateClosed
+		ms.health.NextRetry = nil
+		ms.backoff = 0
+		return fmt.Sprintf("✅ 輸出目標 %s 已恢復", ms.health.Name)
+	}
+
+	ms.health.Failures += len(errs)
+	ms.health.ConsecutiveFailures++
+	ms.health.LastError = errs[len(errs)-1].Error()
+	ms.health.LastErrorAt = &now
+
+	switch {
+	case ms.health.State == SinkStateOpen:
+		// 重試仍失敗，等待時間加倍
+		ms.backoff *= 2
+		if ms.backoff > MaxSinkRetryInterval {
+			ms.backoff = MaxSinkRetryInterval
+		}
+	case ms.health.ConsecutiveFailures >= policy.FailureBudget:
+		ms.health.State = SinkStateOpen
+		ms.health.Trips++
+		ms.backoff = policy.RetryInterval
+	default:
+		return ""
+	}
+
+	next := now.Add(ms.backoff)
+	ms.health.NextRetry = &next
+	return fmt.Sprintf("⚠️  輸出目標 %s 連續失敗 %d 次 (%s)，暫停輸出，%v 後重試",
+		ms.health.Name, ms.health.ConsecutiveFailures, ms.health.LastError, ms.backoff)
+}
+
+// deliver 在期限內向輸出目標寫入讀數和事件，超時時按失敗處理，全部事件保留待補發
+//
+// 寫入在單獨的協程中執行，卡住的輸出目標不會阻塞監測流程和其他輸出目標；超時的寫入在背景繼續執行，
+// 完成前不再向該輸出目標寫入，避免同一輸出目標被並發調用：下次寫入先在期限內等待它完成，仍未完成時按失敗處理。
+func (ms *managedSink) deliver(record MonitorReading, events []AlarmEvent, bandEvents []BandEvent, timeout time.Duration) ([]error, []AlarmEvent, []BandEvent) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	if ms.inflight != nil {
+		select {
+		case <-ms.inflight:
+			ms.inflight = nil
+			timer.Reset(timeout)
+		case <-timer.C:
+			return []error{fmt.Errorf("上一次超時的輸出仍未完成")}, events, bandEvents
+		}
+	}
+
+	done := make(chan sinkResult, 1)
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		errs, failedAlarms, failedBands := ms.write(record, events, bandEvents)
+		done <- sinkResult{errs: errs, failedAlarms: failedAlarms, failedBands: failedBands}
+	}()
+
+	select {
+	case result := <-done:
+		return result.errs, result.failedAlarms, result.failedBands
+	case <-timer.C:
+		ms.inflight = finished
+		return []error{fmt.Errorf("輸出超時 (%v)", timeout)}, events, bandEvents
+	}
+}
+
+// write 向輸出目標寫入讀數和事件，返回全部錯誤和未能寫入的事件；輸出目標 panic 時轉為錯誤，不影響監測流程
+//
+// panic 時無法確定哪些事件已寫入，全部事件按未寫入返回，補發時可能重複。
+func (ms *managedSink) write(record MonitorReading, events []AlarmEvent, bandEvents []BandEvent) (errs []error, failedAlarms []AlarmEvent, failedBands []BandEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			errs = append(errs, fmt.Errorf("輸出目標異常: %v", r))
+			failedAlarms, failedBands = events, bandEvents
+		}
+	}()
+
+	if err := ms.sink.WriteReading(record); err != nil {
+		errs = append(errs, fmt.Errorf("輸出讀數失敗: %v", err))
+	}
+	if alarmSink, ok := ms.sink.(AlarmSink); ok {
+		for _, event := range events {
+			if err := alarmSink.WriteAlarm(event); err != nil {
+				errs = append(errs, fmt.Errorf("輸出告警失敗: %v", err))
+				failedAlarms = append(failedAlarms, event)
+			}
+		}
+	}
+	if bandSink, ok := ms.sink.(BandSink); ok {
+		for _, event := range bandEvents {
+			if err := bandSink.WriteBand(event); err != nil {
+				errs = append(errs, fmt.Errorf("輸出區間事件失敗: %v", err))
+				failedBands = append(failedBands, event)
+			}
+		}
+	}
+	return errs, failedAlarms, failedBands
+}
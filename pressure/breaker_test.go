@@ -0,0 +1,89 @@
+package pressure
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestManagedSinkBreakerTransitions(t *testing.T) {
+	ms := newManagedSink(NewReadingHistory(), 1, nil)
+	policy := SinkBreakerPolicy{FailureBudget: 2, RetryInterval: time.Minute}
+	failed := []error{errors.New("broker unreachable")}
+	now := time.Now()
+
+	if message := ms.record(failed, policy, now); message != "" || ms.health.State != SinkStateClosed {
+		t.Fatalf("first failure: state=%s message=%q, want closed within budget", ms.health.State, message)
+	}
+	if message := ms.record(failed, policy, now); message == "" || ms.health.State != SinkStateOpen {
+		t.Fatalf("budget spent: state=%s message=%q, want open", ms.health.State, message)
+	}
+	if ms.health.Trips != 1 || !ms.health.NextRetry.Equal(now.Add(time.Minute)) {
+		t.Errorf("after trip: trips=%d next retry=%v, want 1 and %v", ms.health.Trips, ms.health.NextRetry, now.Add(time.Minute))
+	}
+
+	// 暫停期間跳過讀數，到達重試時間後放行
+	if ms.allow(now.Add(30 * time.Second)) {
+		t.Error("allow before retry time: want false")
+	}
+	if ms.health.Skipped != 1 {
+		t.Errorf("skipped = %d, want 1", ms.health.Skipped)
+	}
+	retryAt := now.Add(time.Minute)
+	if !ms.allow(retryAt) {
+		t.Fatal("allow at retry time: want true")
+	}
+
+	// 重試仍失敗，等待時間加倍，不重複計算熔斷次數
+	ms.record(failed, policy, retryAt)
+	if ms.health.State != SinkStateOpen || ms.health.Trips != 1 || !ms.health.NextRetry.Equal(retryAt.Add(2*time.Minute)) {
+		t.Errorf("failed retry: state=%s trips=%d next retry=%v, want open, 1 trip, backoff doubled",
+			ms.health.State, ms.health.Trips, ms.health.NextRetry)
+	}
+
+	// 重試成功後恢復
+	if message := ms.record(nil, policy, retryAt.Add(2*time.Minute)); message == "" || ms.health.State != SinkStateClosed {
+		t.Fatalf("successful retry: state=%s message=%q, want closed", ms.health.State, message)
+	}
+	if ms.health.NextRetry != nil || ms.health.ConsecutiveFailures != 0 || ms.backoff != 0 {
+		t.Errorf("after recovery: %+v backoff=%v, want cleared", ms.health, ms.backoff)
+	}
+	if ms.health.Failures != 3 || ms.health.Writes != 1 {
+		t.Errorf("failures=%d writes=%d, want 3 and 1", ms.health.Failures, ms.health.Writes)
+	}
+}
+
+func TestManagedSinkBackoffCapped(t *testing.T) {
+	ms := newManagedSink(NewReadingHistory(), 1, &SinkBreakerPolicy{FailureBudget: 1, RetryInterval: 4 * time.Minute})
+	failed := []error{errors.New("disk full")}
+	now := time.Now()
+
+	for i := 0; i < 4; i++ {
+		ms.record(failed, SinkBreakerPolicy{}, now)
+	}
+	if ms.backoff != MaxSinkRetryInterval {
+		t.Errorf("backoff = %v, want capped at %v", ms.backoff, MaxSinkRetryInterval)
+	}
+}
+
+func TestManagedSinkEventBacklogBounded(t *testing.T) {
+	ms := newManagedSink(&alarmRecorder{}, 1, nil)
+	events := make([]AlarmEvent, DefaultSinkEventBacklog+5)
+	for i := range events {
+		events[i] = AlarmEvent{Rule: "room", Reading: PressureReading{Pressure: float64(i)}}
+	}
+	ms.hold(events, []BandEvent{{}})
+
+	if ms.health.PendingEvents != DefaultSinkEventBacklog || ms.health.DroppedEvents != 5 {
+		t.Errorf("pending=%d dropped=%d, want %d and 5; band events are not held for a sink without WriteBand",
+			ms.health.PendingEvents, ms.health.DroppedEvents, DefaultSinkEventBacklog)
+	}
+	alarms, bands := ms.takePending([]AlarmEvent{{Rule: "new"}}, nil)
+	if len(alarms) != DefaultSinkEventBacklog+1 || alarms[0].Reading.Pressure != 5 || alarms[len(alarms)-1].Rule != "new" {
+		t.Errorf("replayed %d alarms starting at %v, want the newest %d held first then the new event",
+			len(alarms), alarms[0].Reading.Pressure, DefaultSinkEventBacklog)
+	}
+	if len(bands) != 0 || ms.health.PendingEvents != 0 {
+		t.Errorf("bands=%d pending=%d after take, want 0 and 0", len(bands), ms.health.PendingEvents)
+	}
+}
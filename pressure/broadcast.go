@@ -0,0 +1,57 @@
+// pressure/broadcast.go - 廣播寫入（站點號 0）
+package pressure
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BroadcastTurnaroundDelay 廣播後等待所有設備處理完成的時間
+//
+// Modbus 規範建議主站在廣播後等待 100-200ms 再發送下一個請求。
+const BroadcastTurnaroundDelay = 200 * time.Millisecond
+
+// BroadcastWriteRegister 以廣播地址向總線上的所有設備寫入單個保持寄存器（功能碼 0x06）
+//
+// 廣播請求沒有響應，無法確認每台設備是否執行成功，寫入後應逐台讀回確認。
+// 只使用配置中的串口和校驗位，忽略站點號。調用前需確保沒有其他程序佔用該串口。需要啟用控制操作。
+func BroadcastWriteRegister(config Config, address, value uint16) error {
+	if !config.EnableControl {
+		return ErrControlDisabled
+	}
+	if config.IsTCP() || config.IsRTUOverTCP() {
+		return fmt.Errorf("廣播寫入只支援本地 RTU 串口，Modbus TCP 網關和串口服務器請逐台寫入")
+	}
+
+	parity := strings.ToUpper(config.Parity)
+	if parity == "" {
+		parity = DefaultParity
+	}
+	if !IsValidParity(parity) {
+		return fmt.Errorf("無效的校驗位: %s", config.Parity)
+	}
+	if !IsValidStopBits(config.SerialStopBits()) {
+		return fmt.Errorf("無效的停止位: %d", config.StopBits)
+	}
+
+	line, err := openDiagLine(config.Device, config.SerialBaudRate(), parity, config.SerialStopBits(), ModbusBroadcastID, DefaultResponseTimeout)
+	if err != nil {
+		return err
+	}
+	defer line.port.Close()
+
+	data := make([]byte, 4)
+	binary.BigEndian.PutUint16(data[0:], address)
+	binary.BigEndian.PutUint16(data[2:], value)
+	if _, _, err := line.send(ModbusFunctionWriteSingleRegister, data); err != nil {
+		return fmt.Errorf("廣播寫入寄存器 0x%04X 失敗: %v", address, err)
+	}
+	if err := line.port.Drain(); err != nil {
+		return fmt.Errorf("等待廣播發送完成失敗: %v", err)
+	}
+
+	time.Sleep(BroadcastTurnaroundDelay)
+	return nil
+}
@@ -0,0 +1,190 @@
+// pressure/burst.go - 突發採樣：以遠短於監測間隔的週期讀取並緩存在記憶體中，結束後一次寫入檔案
+//
+// 用於捕捉潔淨室開門等短暫的壓力瞬變，正常監測間隔（秒級）無法記錄這類幾百毫秒內的變化。
+package pressure
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 突發採樣限制
+const (
+	MinBurstInterval     = 10 * time.Millisecond // 最短採樣間隔，再短也受限於總線事務時間
+	DefaultBurstDuration = 30 * time.Second      // 默認採樣時長
+	MaxBurstReadings     = 100000                // 單次採樣最多緩存的讀數數量
+)
+
+// BurstCapture 一次突發採樣的結果
+type BurstCapture struct {
+	SlaveID     byte              `json:"slave_id"`              // 設備 ID
+	Interval    time.Duration     `json:"interval"`              // 請求的採樣間隔
+	Duration    time.Duration     `json:"duration"`              // 請求的採樣時長
+	Start       time.Time         `json:"start"`                 // 開始時間
+	End         time.Time         `json:"end"`                   // 結束時間
+	Interrupted bool              `json:"interrupted,omitempty"` // 是否提前中止
+	Overruns    int               `json:"overruns"`              // 讀取耗時超出採樣間隔而跳過的採樣點
+	Stats       Statistics        `json:"stats"`                 // 有效讀數的統計
+	MaxStep     float64           `json:"max_step"`              // 相鄰有效讀數的最大變化 (Pa)
+	MaxStepAt   *time.Time        `json:"max_step_at,omitempty"` // 最大變化發生的時間
+	Readings    []PressureReading `json:"readings"`              // 全部讀數，包括讀取失敗的
+}
+
+// CaptureBurst 以 interval 為週期讀取 duration 時長，讀數緩存在記憶體中，ctx 取消時提前結束並返回已採集的讀數
+//
+// 採樣點按開始時間對齊，讀取耗時超出間隔時跳過錯過的採樣點並計入 Overruns，不會為追趕進度而連續讀取。
+// 採樣期間不能同時運行 Start 的監測循環，兩者會在總線上交錯請求。
+func (pm *PressureMeter) CaptureBurst(ctx context.Context, interval, duration time.Duration) (*BurstCapture, error) {
+	if interval < MinBurstInterval {
+		return nil, fmt.Errorf("採樣間隔 %v 過短，最短 %v", interval, MinBurstInterval)
+	}
+	if duration <= 0 {
+		duration = DefaultBurstDuration
+	}
+	samples := int(duration / interval)
+	if samples > MaxBurstReadings {
+		return nil, fmt.Errorf("%v 內以 %v 間隔採樣需要 %d 個讀數，超過上限 %d，請縮短時長或加大間隔",
+			duration, interval, samples, MaxBurstReadings)
+	}
+	if pm.IsRunning() {
+		return nil, fmt.Errorf("設備正在監測中，請先停止監測再進行突發採樣")
+	}
+
+	capture := &BurstCapture{
+		SlaveID:  pm.slaveID,
+		Interval: interval,
+		Duration: duration,
+		Start:    time.Now(),
+		Readings: make([]PressureReading, 0, samples+1),
+	}
+	pm.logger.Printf("⚡ 開始突發採樣: 每 %v 讀取一次，持續 %v", interval, duration)
+
+	deadline := capture.Start.Add(duration)
+	next := capture.Start
+	timer := time.NewTimer(0)
+	<-timer.C
+	defer timer.Stop()
+
+	var last float64
+	haveLast := false
+loop:
+	for {
+		reading := pm.ReadPressure()
+		capture.Readings = append(capture.Readings, reading)
+		if reading.Valid {
+			capture.Stats.Update(reading.Pressure)
+			if haveLast {
+				if step := math.Abs(reading.Pressure - last); step > capture.MaxStep {
+					capture.MaxStep = step
+					at := reading.Timestamp
+					capture.MaxStepAt = &at
+				}
+			}
+			last, haveLast = reading.Pressure, true
+		}
+
+		next = next.Add(interval)
+		if now := time.Now(); now.After(next) {
+			missed := int(now.Sub(next)/interval) + 1
+			capture.Overruns += missed
+			next = next.Add(time.Duration(missed) * interval)
+		}
+		if !next.Before(deadline) {
+			break
+		}
+
+		timer.Reset(time.Until(next))
+		select {
+		case <-ctx.Done():
+			capture.Interrupted = true
+			break loop
+		case <-timer.C:
+		}
+	}
+
+	capture.End = time.Now()
+	pm.logger.Printf("⚡ 突發採樣結束: %d 個讀數，%d 個有效，跳過 %d 個採樣點",
+		len(capture.Readings), capture.Stats.Count, capture.Overruns)
+	return capture, nil
+}
+
+// Rate 返回實際的平均採樣間隔
+func (bc *BurstCapture) Rate() time.Duration {
+	if len(bc.Readings) < 2 {
+		return 0
+	}
+	first, last := bc.Readings[0].Timestamp, bc.Readings[len(bc.Readings)-1].Timestamp
+	return last.Sub(first) / time.Duration(len(bc.Readings)-1)
+}
+
+// WriteCSV 將讀數寫為 CSV，elapsed_ms 為相對開始時間的毫秒數，便於在試算表中繪製瞬變曲線
+func (bc *BurstCapture) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"timestamp", "elapsed_ms", "slave_id", "pressure", "unit", "valid", "duration_ms", "error"})
+	for _, reading := range bc.Readings {
+		pressure := "NaN"
+		if reading.Valid {
+			pressure = strconv.FormatFloat(reading.Pressure, 'f', 3, 64)
+		}
+		cw.Write([]string{
+			reading.Timestamp.Format("2006-01-02 15:04:05.000"),
+			strconv.FormatFloat(float64(reading.Timestamp.Sub(bc.Start))/float64(time.Millisecond), 'f', 1, 64),
+			strconv.Itoa(int(reading.SlaveID)),
+			pressure,
+			"Pa",
+			strconv.FormatBool(reading.Valid),
+			strconv.FormatFloat(float64(reading.Duration)/float64(time.Millisecond), 'f', 1, 64),
+			reading.Error,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON 將採樣結果寫為 JSON
+func (bc *BurstCapture) WriteJSON(w io.Writer) error {
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"schema_version": SchemaVersion,
+		"burst":          bc,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// Print 輸出採樣摘要
+func (bc *BurstCapture) Print(w io.Writer) {
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+	fmt.Fprintf(w, "⚡ 突發採樣: 站點 %d，每 %v 持續 %v\n", bc.SlaveID, bc.Interval, bc.Duration)
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+
+	fmt.Fprintf(w, "讀數:       %d 個，有效 %d 個\n", len(bc.Readings), bc.Stats.Count)
+	fmt.Fprintf(w, "實際時長:   %v", bc.End.Sub(bc.Start).Round(time.Millisecond))
+	if bc.Interrupted {
+		fmt.Fprint(w, " (提前中止)")
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "平均間隔:   %v\n", bc.Rate().Round(time.Millisecond))
+	if bc.Overruns > 0 {
+		fmt.Fprintf(w, "⚠️  %d 個採樣點因讀取耗時超出間隔而跳過，請加大間隔或提高波特率\n", bc.Overruns)
+	}
+
+	if bc.Stats.Count > 0 {
+		fmt.Fprintf(w, "\n📊 壓力: 最小 %.2f Pa，最大 %.2f Pa，平均 %.2f Pa，峰峰值 %.2f Pa\n",
+			bc.Stats.Min, bc.Stats.Max, bc.Stats.Mean, bc.Stats.Max-bc.Stats.Min)
+		if bc.MaxStepAt != nil {
+			fmt.Fprintf(w, "📈 最大相鄰變化: %.2f Pa (開始後 %v)\n",
+				bc.MaxStep, bc.MaxStepAt.Sub(bc.Start).Round(time.Millisecond))
+		}
+	}
+	fmt.Fprintln(w, strings.Repeat("=", 52))
+}
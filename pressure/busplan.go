@@ -0,0 +1,154 @@
+// pressure/busplan.go - RS485 總線吞吐量估算，計算最小可行讀取間隔
+package pressure
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// DefaultTransactionOverhead 每次 Modbus 事務除線路傳輸外的額外耗時（儀表處理、收發切換、轉換器延遲）
+const DefaultTransactionOverhead = 20 * time.Millisecond
+
+// Modbus RTU 幀長度（字節）
+const (
+	rtuReadRequestBytes  = 8   // 站點號 + 功能碼 + 起始地址 + 數量 + CRC
+	rtuReadResponseBytes = 5   // 站點號 + 功能碼 + 字節數 + CRC，不含數據
+	rtuFrameGapChars     = 3.5 // 幀間靜默時間（字符）
+)
+
+// PlannedTransaction 每個讀取週期內對單台設備的一次讀取
+type PlannedTransaction struct {
+	Name      string        `json:"name"`      // 用途
+	Registers uint16        `json:"registers"` // 讀取的寄存器數量
+	Bytes     int           `json:"bytes"`     // 請求和響應的總字節數
+	Duration  time.Duration `json:"duration"`  // 含幀間隔和額外耗時的事務時間
+}
+
+// BusPlan 總線吞吐量估算
+//
+// 同一條 RS485 總線上的事務只能串行執行，所有設備讀取一輪的耗時就是最小可行讀取間隔。
+type BusPlan struct {
+	BaudRate     int                  `json:"baud_rate"`     // 波特率
+	Parity       string               `json:"parity"`        // 校驗位
+	StopBits     int                  `json:"stop_bits"`     // 停止位
+	BitsPerChar  int                  `json:"bits_per_char"` // 每字符位數（起始位 + 數據位 + 校驗位 + 停止位）
+	Devices      int                  `json:"devices"`       // 總線上輪詢的設備數
+	Overhead     time.Duration        `json:"overhead"`      // 每次事務的額外耗時
+	Transactions []PlannedTransaction `json:"transactions"`  // 每台設備每週期的事務
+	CycleTime    time.Duration        `json:"cycle_time"`    // 讀取一輪的耗時，即最小可行讀取間隔
+	Interval     time.Duration        `json:"interval"`      // 請求的讀取間隔
+	Utilization  float64              `json:"utilization"`   // 總線佔用率 (CycleTime / Interval)
+}
+
+// NewBusPlan 根據配置估算總線吞吐量
+//
+// devices 為同一總線上按相同方式輪詢的設備數（0 視為 1），baudRate 為 0 時使用 DefaultBaudRate，
+// overhead 為 0 時使用 DefaultTransactionOverhead。
+func NewBusPlan(config Config, devices, baudRate int, overhead time.Duration) *BusPlan {
+	if devices <= 0 {
+		devices = 1
+	}
+	if baudRate <= 0 {
+		baudRate = DefaultBaudRate
+	}
+	if overhead <= 0 {
+		overhead = DefaultTransactionOverhead
+	}
+	parity := strings.ToUpper(config.Parity)
+	if parity == "" {
+		parity = DefaultParity
+	}
+	interval := config.ReadInterval
+	if interval <= 0 {
+		interval = DefaultReadInterval
+	}
+
+	bp := &BusPlan{
+		BaudRate:    baudRate,
+		Parity:      parity,
+		StopBits:    config.SerialStopBits(),
+		BitsPerChar: 9 + config.SerialStopBits(),
+		Devices:     devices,
+		Overhead:    overhead,
+		Interval:    interval,
+	}
+	if parity != "N" {
+		bp.BitsPerChar++
+	}
+
+	registers := uint16(RegisterCount)
+	if profile, err := ResolveDeviceProfile(config); err == nil {
+		registers = profile.Count
+	}
+	bp.addTransaction("壓力", registers)
+	if config.TemperatureRegister != 0 {
+		bp.addTransaction("溫度", 1)
+	}
+
+	var perDevice time.Duration
+	for _, tx := range bp.Transactions {
+		perDevice += tx.Duration
+	}
+	bp.CycleTime = perDevice * time.Duration(devices)
+	bp.Utilization = float64(bp.CycleTime) / float64(bp.Interval)
+	return bp
+}
+
+// addTransaction 計算一次讀保持寄存器事務的耗時
+func (bp *BusPlan) addTransaction(name string, registers uint16) {
+	bytes := rtuReadRequestBytes + rtuReadResponseBytes + 2*int(registers)
+	chars := float64(bytes) + 2*rtuFrameGapChars
+	wire := time.Duration(chars * float64(bp.BitsPerChar) / float64(bp.BaudRate) * float64(time.Second))
+	bp.Transactions = append(bp.Transactions, PlannedTransaction{
+		Name:      name,
+		Registers: registers,
+		Bytes:     bytes,
+		Duration:  wire + bp.Overhead,
+	})
+}
+
+// Feasible 請求的讀取間隔是否能在總線上實現
+func (bp *BusPlan) Feasible() bool {
+	return bp.CycleTime <= bp.Interval
+}
+
+// MinInterval 返回最小可行讀取間隔
+func (bp *BusPlan) MinInterval() time.Duration {
+	return bp.CycleTime
+}
+
+// Warning 讀取間隔不可行時返回警告，可行時返回空字符串
+func (bp *BusPlan) Warning() string {
+	if bp.Feasible() {
+		return ""
+	}
+	return fmt.Sprintf("讀取間隔 %v 無法在總線上實現：%d 台設備在 %d bps 下讀取一輪至少需要 %v (佔用率 %.0f%%)，請加大間隔或提高波特率",
+		bp.Interval, bp.Devices, bp.BaudRate, bp.CycleTime.Round(time.Millisecond), bp.Utilization*100)
+}
+
+// Print 將吞吐量估算寫入 w
+func (bp *BusPlan) Print(w io.Writer) {
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+	fmt.Fprintln(w, "📋 總線吞吐量估算（不會訪問總線）")
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+	fmt.Fprintf(w, "📡 線路: %d bps, %s (%d 位/字符)\n", bp.BaudRate, charFormat(bp.Parity, bp.StopBits), bp.BitsPerChar)
+	fmt.Fprintf(w, "🔌 設備數: %d\n", bp.Devices)
+	fmt.Fprintf(w, "⏳ 每次事務額外耗時: %v\n", bp.Overhead)
+
+	fmt.Fprintln(w, "\n🔁 每台設備每週期的事務:")
+	for _, tx := range bp.Transactions {
+		fmt.Fprintf(w, "   %-6s %d 個寄存器, %d 字節, %v\n",
+			tx.Name, tx.Registers, tx.Bytes, tx.Duration.Round(100*time.Microsecond))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "⏱️  最小可行讀取間隔: %v\n", bp.CycleTime.Round(time.Millisecond))
+	fmt.Fprintf(w, "🎯 請求的讀取間隔: %v (總線佔用率 %.0f%%)\n", bp.Interval, bp.Utilization*100)
+	if bp.Feasible() {
+		fmt.Fprintln(w, "✅ 讀取間隔可行")
+	} else {
+		fmt.Fprintf(w, "⚠️  %s\n", bp.Warning())
+	}
+}
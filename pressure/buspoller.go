@@ -0,0 +1,360 @@
+// pressure/buspoller.go - 同一條 RS485 總線上多台儀表的輪流讀取，共用一個串口
+package pressure
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BusPoller 在同一條總線上按順序輪流讀取多台儀表
+//
+// 所有儀表共用一個串口（或 TCP 連接）和同一份配置，只有站點號不同；
+// 讀數的 SlaveID 標明來源，全部放入同一個通道。
+type BusPoller struct {
+	meter    *PressureMeter
+	slaveIDs []byte
+	logger   *log.Logger
+
+	mu           sync.Mutex // 總線上的事務只能串行執行
+	stopCh       chan struct{}
+	running      bool
+	synchronized bool       // 同步採樣：每輪讀數帶共同時間戳，讀取時刻對齊到間隔的整數倍
+	cycle        uint64     // 同步採樣的輪次
+	discovery    *Discovery // 後台發現，為空則未啟動
+}
+
+// SampleCycle 同步採樣的一輪讀數
+type SampleCycle struct {
+	Number   uint64            `json:"number"`   // 輪次，從 1 開始
+	Time     time.Time         `json:"time"`     // 共同時間戳：第一個請求發出到最後一個響應之間的中點
+	Spread   time.Duration     `json:"spread"`   // 第一個請求發出到最後一個響應的時間，越小各讀數越接近同一時刻
+	Readings []PressureReading `json:"readings"` // 各儀表的讀數，順序同 SlaveIDs
+}
+
+// NewBusPoller 打開 config 指定的串口，輪流讀取 slaveIDs 中的儀表
+//
+// config.SlaveID 會被忽略；站點號不能重複。讀取間隔不足以讀完一輪時記錄警告。
+func NewBusPoller(config Config, slaveIDs []byte) (*BusPoller, error) {
+	if err := checkSlaveIDList(slaveIDs); err != nil {
+		return nil, err
+	}
+
+	config.SlaveID = slaveIDs[0]
+	meter, err := NewPressureMeter(config)
+	if err != nil {
+		return nil, err
+	}
+	if len(slaveIDs) > 1 {
+		if err := meter.checkSlaveSwitching(); err != nil {
+			meter.Close()
+			return nil, err
+		}
+	}
+
+	bus := &BusPoller{
+		meter:    meter,
+		slaveIDs: append([]byte(nil), slaveIDs...),
+		logger:   meter.logger,
+		stopCh:   make(chan struct{}),
+	}
+	if warning := NewBusPlan(config, len(slaveIDs), 0, 0).Warning(); warning != "" {
+		bus.logger.Printf("⚠️  %s", warning)
+	}
+	return bus, nil
+}
+
+// checkSlaveIDList 檢查輪詢的站點號列表：不能為空、不能重複、不能是廣播地址
+func checkSlaveIDList(slaveIDs []byte) error {
+	if len(slaveIDs) == 0 {
+		return fmt.Errorf("至少需要一個站點號")
+	}
+	seen := make(map[byte]bool, len(slaveIDs))
+	for _, id := range slaveIDs {
+		if err := CheckPollingSlaveID(id); err != nil {
+			return err
+		}
+		if seen[id] {
+			return fmt.Errorf("站點號 %d 重複", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// ParseSlaveIDList 解析逗號分隔的站點號列表，支援範圍，如 "22,23,30-35"
+func ParseSlaveIDList(s string) ([]byte, error) {
+	var ids []byte
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if from, to, ok := strings.Cut(part, "-"); ok {
+			first, err := parseSlaveID(from)
+			if err != nil {
+				return nil, err
+			}
+			last, err := parseSlaveID(to)
+			if err != nil {
+				return nil, err
+			}
+			if first > last {
+				return nil, fmt.Errorf("無效的站點號範圍: %s", part)
+			}
+			for id := int(first); id <= int(last); id++ {
+				ids = append(ids, byte(id))
+			}
+			continue
+		}
+		id, err := parseSlaveID(part)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("站點號列表為空")
+	}
+	return ids, nil
+}
+
+// SlaveIDs 返回輪詢的站點號，啟動後台發現後包括新發現的儀表
+func (bus *BusPoller) SlaveIDs() []byte {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	return append([]byte(nil), bus.slaveIDs...)
+}
+
+// SetEventBus 設置事件總線，發布儀表的連接和讀數事件及後台發現的事件
+func (bus *BusPoller) SetEventBus(eventBus *EventBus) *BusPoller {
+	bus.meter.SetEventBus(eventBus)
+	return bus
+}
+
+// StartDiscovery 在輪詢的同時按間隔快速掃描總線，新發現的儀表加入輪詢，由發現加入的儀表移除後停止輪詢
+//
+// 可在 Start 之前或之後調用，Stop 時一併停止。傳輸層不能切換站點號時返回錯誤。
+func (bus *BusPoller) StartDiscovery(config DiscoveryConfig) (*Discovery, error) {
+	if err := bus.meter.checkSlaveSwitching(); err != nil {
+		return nil, fmt.Errorf("無法啟動後台發現: %v", err)
+	}
+	d := newDiscovery(bus.meter, config, bus.SlaveIDs, bus.addSlave, bus.removeSlave)
+
+	bus.mu.Lock()
+	if bus.discovery != nil {
+		d = bus.discovery
+		bus.mu.Unlock()
+		return d, nil
+	}
+	bus.discovery = d
+	bus.mu.Unlock()
+
+	go d.run()
+	return d, nil
+}
+
+// addSlave 將後台發現的儀表加入輪詢
+func (bus *BusPoller) addSlave(slaveID byte) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	if !containsSlaveID(bus.slaveIDs, slaveID) {
+		bus.slaveIDs = append(append([]byte(nil), bus.slaveIDs...), slaveID)
+	}
+}
+
+// removeSlave 停止輪詢已移除的儀表
+func (bus *BusPoller) removeSlave(slaveID byte) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	slaveIDs := make([]byte, 0, len(bus.slaveIDs))
+	for _, id := range bus.slaveIDs {
+		if id != slaveID {
+			slaveIDs = append(slaveIDs, id)
+		}
+	}
+	bus.slaveIDs = slaveIDs
+}
+
+// ReadSlave 讀取一台儀表
+func (bus *BusPoller) ReadSlave(slaveID byte) PressureReading {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	return bus.readLocked(slaveID)
+}
+
+// ReadAll 按順序讀取所有儀表一輪
+func (bus *BusPoller) ReadAll() []PressureReading {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	readings := make([]PressureReading, 0, len(bus.slaveIDs))
+	for _, id := range bus.slaveIDs {
+		readings = append(readings, bus.readLocked(id))
+	}
+	return readings
+}
+
+// SetSynchronized 設置同步採樣模式
+//
+// 同步採樣時每輪在總線允許的最短時間內連續讀完所有儀表，讀數帶相同的 Cycle 和 CycleTime，
+// 一輪讀完後才放入通道；讀取時刻對齊到間隔的整數倍（如 5s 間隔在 :00、:05…），
+// 多條總線各用一個 BusPoller 時各輪也能對齊。下游計算房間之間的壓差時應使用 CycleTime。
+func (bus *BusPoller) SetSynchronized(enabled bool) *BusPoller {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	bus.synchronized = enabled
+	return bus
+}
+
+// ReadCycle 連續讀取所有儀表一輪，並為讀數加上共同時間戳
+func (bus *BusPoller) ReadCycle() SampleCycle {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.cycle++
+	cycle := SampleCycle{Number: bus.cycle, Readings: make([]PressureReading, 0, len(bus.slaveIDs))}
+	start := time.Now()
+	for _, id := range bus.slaveIDs {
+		cycle.Readings = append(cycle.Readings, bus.readLocked(id))
+	}
+	end := time.Now()
+
+	cycle.Spread = end.Sub(start)
+	cycle.Time = start.Add(cycle.Spread / 2)
+	for i := range cycle.Readings {
+		cycle.Readings[i].Cycle = cycle.Number
+		cycleTime := cycle.Time
+		cycle.Readings[i].CycleTime = &cycleTime
+	}
+	return cycle
+}
+
+// readLocked 切換站點號後讀取（調用方需持有鎖）
+func (bus *BusPoller) readLocked(slaveID byte) PressureReading {
+	return bus.meter.readSlave(slaveID)
+}
+
+// Start 開始按 interval 輪流讀取，讀數放入 GetReadings 返回的通道
+func (bus *BusPoller) Start(interval time.Duration) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	if bus.running {
+		bus.logger.Println("總線輪詢已在運行中")
+		return
+	}
+
+	bus.running = true
+	if bus.synchronized {
+		bus.logger.Printf("開始同步採樣 %d 台儀表，間隔: %v", len(bus.slaveIDs), interval)
+		go bus.runSynchronized(interval)
+		return
+	}
+	bus.logger.Printf("開始輪詢 %d 台儀表，間隔: %v", len(bus.slaveIDs), interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-bus.stopCh:
+				bus.logger.Println("停止總線輪詢")
+				return
+			case <-ticker.C:
+				for _, id := range bus.SlaveIDs() {
+					select {
+					case <-bus.stopCh:
+						return
+					default:
+					}
+					bus.meter.publish(bus.ReadSlave(id), bus.stopCh)
+				}
+			}
+		}
+	}()
+}
+
+// runSynchronized 在間隔的整數倍時刻讀取一輪，讀完後一起放入通道
+func (bus *BusPoller) runSynchronized(interval time.Duration) {
+	// 等到下一個整數倍時刻再開始
+	first := time.NewTimer(time.Until(time.Now().Truncate(interval).Add(interval)))
+	select {
+	case <-bus.stopCh:
+		first.Stop()
+		bus.logger.Println("停止總線輪詢")
+		return
+	case <-first.C:
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cycle := bus.ReadCycle()
+		if cycle.Spread > interval {
+			bus.logger.Printf("⚠️  第 %d 輪採樣耗時 %v，超過間隔 %v", cycle.Number, cycle.Spread.Round(time.Millisecond), interval)
+		}
+		for _, reading := range cycle.Readings {
+			bus.meter.publish(reading, bus.stopCh)
+		}
+
+		select {
+		case <-bus.stopCh:
+			bus.logger.Println("停止總線輪詢")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop 停止輪詢和後台發現，未調用 Start 時也停止已啟動的後台發現
+func (bus *BusPoller) Stop() {
+	bus.mu.Lock()
+	if bus.running {
+		bus.running = false
+		close(bus.stopCh)
+	}
+	discovery := bus.discovery
+	bus.discovery = nil
+	bus.mu.Unlock()
+
+	if discovery != nil {
+		discovery.Stop()
+	}
+}
+
+// GetReadings 獲取所有儀表共用的讀數通道，通道已滿時丟棄最舊的讀數
+func (bus *BusPoller) GetReadings() <-chan PressureReading {
+	return bus.meter.readings
+}
+
+// Subscribe 訂閱所有儀表的讀數，每個訂閱者各自收到全部讀數，見 PressureMeter.Subscribe
+func (bus *BusPoller) Subscribe() (<-chan PressureReading, func()) {
+	return bus.meter.Subscribe()
+}
+
+// ChannelStats 返回讀數通道的使用情況
+func (bus *BusPoller) ChannelStats() ChannelStats {
+	return bus.meter.ChannelStats()
+}
+
+// Close 停止輪詢並關閉串口
+func (bus *BusPoller) Close() error {
+	bus.Stop()
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	return bus.meter.Close()
+}
+
+// String 實現 Stringer 接口
+func (bus *BusPoller) String() string {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	ids := make([]string, len(bus.slaveIDs))
+	for i, id := range bus.slaveIDs {
+		ids[i] = fmt.Sprintf("%d", id)
+	}
+	return fmt.Sprintf("總線輪詢[%s, 站點:%s]", bus.meter.endpoint, strings.Join(ids, ","))
+}
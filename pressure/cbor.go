@@ -0,0 +1,472 @@
+// pressure/cbor.go - CBOR (RFC 8949) 編碼和解碼，蜂窩網絡網關上以緊湊的二進制格式傳輸和保存讀數，僅依賴標準庫
+package pressure
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// CBORContentType CBOR 的 MIME 類型
+const CBORContentType = "application/cbor"
+
+// CBOR 主類型
+const (
+	cborUnsigned = 0 << 5
+	cborNegative = 1 << 5
+	cborBytes    = 2 << 5
+	cborText     = 3 << 5
+	cborArray    = 4 << 5
+	cborMap      = 5 << 5
+	cborTag      = 6 << 5
+	cborSimple   = 7 << 5
+)
+
+// cborTagEpoch 標籤 1：Unix 時間（秒）
+const cborTagEpoch = 1
+
+// cborMaxDepth 解碼時允許的最大嵌套層數
+const cborMaxDepth = 32
+
+// MarshalCBOR 將值編碼為 CBOR
+//
+// 數據模型與 JSON 輸出相同：map 的鍵按字典序排列，整數使用最短編碼，浮點數在不損失精度時使用單精度，
+// time.Time 編碼為標籤 1 的 Unix 時間；結構體等其他類型按其 JSON 形式編碼。
+func MarshalCBOR(v interface{}) ([]byte, error) {
+	return appendCBOR(nil, v, 0)
+}
+
+// appendCBOR 將值的 CBOR 編碼追加到 buf
+func appendCBOR(buf []byte, v interface{}, depth int) ([]byte, error) {
+	if depth > cborMaxDepth {
+		return nil, fmt.Errorf("CBOR 編碼嵌套過深")
+	}
+	switch value := v.(type) {
+	case nil:
+		return append(buf, cborSimple|22), nil
+	case bool:
+		if value {
+			return append(buf, cborSimple|21), nil
+		}
+		return append(buf, cborSimple|20), nil
+	case string:
+		buf = appendCBORHead(buf, cborText, uint64(len(value)))
+		return append(buf, value...), nil
+	case []byte:
+		buf = appendCBORHead(buf, cborBytes, uint64(len(value)))
+		return append(buf, value...), nil
+	case time.Time:
+		buf = appendCBORHead(buf, cborTag, cborTagEpoch)
+		if value.Nanosecond() == 0 {
+			return appendCBORInt(buf, value.Unix()), nil
+		}
+		return appendCBORFloat(buf, float64(value.UnixNano())/1e9), nil
+	case *time.Time:
+		if value == nil {
+			return append(buf, cborSimple|22), nil
+		}
+		return appendCBOR(buf, *value, depth)
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return appendCBORInt(buf, i), nil
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("CBOR 編碼數值 %s 失敗: %v", value, err)
+		}
+		return appendCBORFloat(buf, f), nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf = appendCBORHead(buf, cborMap, uint64(len(keys)))
+		for _, key := range keys {
+			buf = appendCBORHead(buf, cborText, uint64(len(key)))
+			buf = append(buf, key...)
+			var err error
+			if buf, err = appendCBOR(buf, value[key], depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case []interface{}:
+		buf = appendCBORHead(buf, cborArray, uint64(len(value)))
+		for _, item := range value {
+			var err error
+			if buf, err = appendCBOR(buf, item, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendCBORInt(buf, rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return appendCBORHead(buf, cborUnsigned, rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return appendCBORFloat(buf, rv.Float()), nil
+	}
+
+	// 結構體、指針和其他容器：按 JSON 形式編碼，與 JSON 輸出的字段名和 omitempty 一致
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("CBOR 編碼失敗: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("CBOR 編碼失敗: %v", err)
+	}
+	return appendCBOR(buf, generic, depth+1)
+}
+
+// appendCBORHead 追加主類型和參數，參數使用最短編碼
+func appendCBORHead(buf []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, major|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major|27), n)
+	}
+}
+
+// appendCBORInt 追加有符號整數
+func appendCBORInt(buf []byte, i int64) []byte {
+	if i < 0 {
+		return appendCBORHead(buf, cborNegative, uint64(-1-i))
+	}
+	return appendCBORHead(buf, cborUnsigned, uint64(i))
+}
+
+// appendCBORFloat 追加浮點數，整數值編碼為整數，單精度可精確表示時使用單精度
+func appendCBORFloat(buf []byte, f float64) []byte {
+	if f == math.Trunc(f) && math.Abs(f) < 1<<53 && !(f == 0 && math.Signbit(f)) {
+		return appendCBORInt(buf, int64(f))
+	}
+	if f32 := float32(f); float64(f32) == f || math.IsNaN(f) {
+		return binary.BigEndian.AppendUint32(append(buf, cborSimple|26), math.Float32bits(f32))
+	}
+	return binary.BigEndian.AppendUint64(append(buf, cborSimple|27), math.Float64bits(f))
+}
+
+// CBORDecoder 從 CBOR 序列 (RFC 8742，多個 CBOR 數據項首尾相接) 中逐個解碼數據項
+type CBORDecoder struct {
+	r *bufio.Reader
+}
+
+// NewCBORDecoder 創建讀取 r 的解碼器
+func NewCBORDecoder(r io.Reader) *CBORDecoder {
+	return &CBORDecoder{r: bufio.NewReader(r)}
+}
+
+// Decode 解碼下一個數據項，沒有更多數據時返回 io.EOF
+//
+// map 解碼為 map[string]interface{}，整數為 int64 或 uint64，浮點數為 float64，標籤 1 和 0 為 time.Time，
+// 字節串為十六進制字符串，其他標籤返回其內容。
+func (cd *CBORDecoder) Decode() (interface{}, error) {
+	if _, err := cd.r.Peek(1); err != nil {
+		return nil, err
+	}
+	value, err := cd.decode(0)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return value, cborItemError(err)
+}
+
+// errCBORBreak 不定長數據項的結束標記
+var errCBORBreak = errors.New("CBOR break")
+
+// decode 解碼一個數據項
+func (cd *CBORDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, fmt.Errorf("CBOR 數據嵌套過深")
+	}
+	initial, err := cd.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	major, info := initial&0xE0, initial&0x1F
+	if initial == 0xFF {
+		return nil, errCBORBreak
+	}
+
+	if major == cborSimple {
+		return cd.decodeSimple(info)
+	}
+	if info == 31 {
+		return cd.decodeIndefinite(major, depth)
+	}
+	n, err := cd.readArgument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, nil
+	case cborNegative:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("CBOR 負整數超出範圍")
+		}
+		return -1 - int64(n), nil
+	case cborBytes, cborText:
+		data, err := cd.readBytes(n)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborBytes {
+			return hex.EncodeToString(data), nil
+		}
+		return string(data), nil
+	case cborArray:
+		items := make([]interface{}, 0, min(n, 1024))
+		for i := uint64(0); i < n; i++ {
+			item, err := cd.decode(depth + 1)
+			if err != nil {
+				return nil, cborItemError(err)
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case cborMap:
+		m := make(map[string]interface{}, min(n, 1024))
+		for i := uint64(0); i < n; i++ {
+			if err := cd.decodeEntry(m, depth); err != nil {
+				return nil, cborItemError(err)
+			}
+		}
+		return m, nil
+	default: // cborTag
+		content, err := cd.decode(depth + 1)
+		if err != nil {
+			return nil, cborItemError(err)
+		}
+		return cborTagged(n, content), nil
+	}
+}
+
+// decodeIndefinite 解碼不定長的字符串、數組或 map
+func (cd *CBORDecoder) decodeIndefinite(major byte, depth int) (interface{}, error) {
+	switch major {
+	case cborBytes, cborText:
+		var joined []byte
+		for {
+			chunk, err := cd.decode(depth + 1)
+			if err == errCBORBreak {
+				if major == cborText {
+					return string(joined), nil
+				}
+				return hex.EncodeToString(joined), nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			s, ok := chunk.(string)
+			if !ok {
+				return nil, fmt.Errorf("CBOR 不定長字符串的分段類型錯誤")
+			}
+			if major == cborBytes {
+				data, _ := hex.DecodeString(s)
+				joined = append(joined, data...)
+			} else {
+				joined = append(joined, s...)
+			}
+		}
+	case cborArray:
+		var items []interface{}
+		for {
+			item, err := cd.decode(depth + 1)
+			if err == errCBORBreak {
+				return items, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+	case cborMap:
+		m := make(map[string]interface{})
+		for {
+			err := cd.decodeEntry(m, depth)
+			if err == errCBORBreak {
+				return m, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("CBOR 主類型 %d 不支援不定長編碼", major>>5)
+	}
+}
+
+// decodeEntry 解碼 map 的一個鍵值對，非字符串的鍵轉為字符串
+func (cd *CBORDecoder) decodeEntry(m map[string]interface{}, depth int) error {
+	key, err := cd.decode(depth + 1)
+	if err != nil {
+		return err
+	}
+	value, err := cd.decode(depth + 1)
+	if err != nil {
+		return cborItemError(err)
+	}
+	if s, ok := key.(string); ok {
+		m[s] = value
+	} else {
+		m[fmt.Sprint(key)] = value
+	}
+	return nil
+}
+
+// decodeSimple 解碼簡單值和浮點數
+func (cd *CBORDecoder) decodeSimple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null、undefined
+		return nil, nil
+	case 25:
+		data, err := cd.readBytes(2)
+		if err != nil {
+			return nil, err
+		}
+		return float16ToFloat64(binary.BigEndian.Uint16(data)), nil
+	case 26:
+		data, err := cd.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(data))), nil
+	case 27:
+		data, err := cd.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data)), nil
+	}
+	if info < 24 {
+		return int64(info), nil
+	}
+	if info == 24 {
+		simple, err := cd.r.ReadByte()
+		return int64(simple), err
+	}
+	return nil, fmt.Errorf("無效的 CBOR 簡單值: %d", info)
+}
+
+// readArgument 讀取數據項頭部的參數
+func (cd *CBORDecoder) readArgument(info byte) (uint64, error) {
+	if info < 24 {
+		return uint64(info), nil
+	}
+	size := 0
+	switch info {
+	case 24:
+		size = 1
+	case 25:
+		size = 2
+	case 26:
+		size = 4
+	case 27:
+		size = 8
+	default:
+		return 0, fmt.Errorf("無效的 CBOR 參數編碼: %d", info)
+	}
+	data, err := cd.readBytes(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, b := range data {
+		n = n<<8 | uint64(b)
+	}
+	return n, nil
+}
+
+// readBytes 讀取 n 個字節
+func (cd *CBORDecoder) readBytes(n uint64) ([]byte, error) {
+	if n > 16<<20 {
+		return nil, fmt.Errorf("CBOR 數據項過長: %d 字節", n)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(cd.r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// cborItemError 數據項中間出現結束標記時視為格式錯誤
+func cborItemError(err error) error {
+	if err == errCBORBreak {
+		return fmt.Errorf("CBOR 數據項中出現意外的結束標記")
+	}
+	return err
+}
+
+// cborTagged 按標籤轉換內容：標籤 0 (RFC 3339 字符串) 和 1 (Unix 時間) 轉為 time.Time
+func cborTagged(tag uint64, content interface{}) interface{} {
+	switch tag {
+	case 0:
+		if s, ok := content.(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return t
+			}
+		}
+	case cborTagEpoch:
+		switch seconds := content.(type) {
+		case int64:
+			return time.Unix(seconds, 0)
+		case float64:
+			whole, frac := math.Modf(seconds)
+			return time.Unix(int64(whole), int64(math.Round(frac*1e9)))
+		}
+	}
+	return content
+}
+
+// float16ToFloat64 將半精度浮點數轉為 float64
+func float16ToFloat64(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exponent := int(h>>10) & 0x1F
+	mantissa := float64(h & 0x3FF)
+	switch exponent {
+	case 0:
+		return sign * math.Ldexp(mantissa, -24)
+	case 0x1F:
+		if mantissa == 0 {
+			return sign * math.Inf(1)
+		}
+		return math.NaN()
+	}
+	return sign * math.Ldexp(mantissa+1024, exponent-25)
+}
@@ -0,0 +1,507 @@
+// pressure/chart.go - 壓力趨勢圖（SVG/PNG），供 HTTP 接口和報告嵌入
+package pressure
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 趨勢圖默認尺寸（像素）
+const (
+	DefaultChartWidth  = 800
+	DefaultChartHeight = 300
+)
+
+// 繪圖區邊距（像素）
+const (
+	chartMarginLeft   = 64
+	chartMarginRight  = 16
+	chartMarginTop    = 32
+	chartMarginBottom = 28
+)
+
+// TrendPoint 趨勢圖上的一個有效讀數
+type TrendPoint struct {
+	Time     time.Time `json:"time"`     // 讀取時間
+	Pressure float64   `json:"pressure"` // 壓力值 (Pa)
+}
+
+// TrendPoints 從讀數中取出有效讀數作為趨勢點
+func TrendPoints(readings []PressureReading) []TrendPoint {
+	points := make([]TrendPoint, 0, len(readings))
+	for _, reading := range readings {
+		if reading.Valid {
+			points = append(points, TrendPoint{Time: reading.Timestamp, Pressure: reading.Pressure})
+		}
+	}
+	return points
+}
+
+// ChartLine 趨勢圖上的水平參考線，如告警上下限
+type ChartLine struct {
+	Label string  // 標籤
+	Value float64 // 壓力值 (Pa)
+}
+
+// AlarmChartLines 將告警規則的上下限轉換為參考線
+func AlarmChartLines(rules []AlarmRule) []ChartLine {
+	var lines []ChartLine
+	for _, rule := range rules {
+		if rule.Low != nil {
+			lines = append(lines, ChartLine{Label: rule.Name, Value: *rule.Low})
+		}
+		if rule.High != nil {
+			lines = append(lines, ChartLine{Label: rule.Name, Value: *rule.High})
+		}
+	}
+	return lines
+}
+
+// Chart 壓力趨勢圖
+//
+// 讀數間隔超過正常間隔 3 倍時斷開折線，顯示數據缺失；點數多於像素寬度時
+// 每個像素列保留最小值和最大值，短時尖峰不會因抽樣而消失。
+type Chart struct {
+	Title  string
+	Unit   string // 縱軸單位，為空時為 Pa
+	Points []TrendPoint
+	Lines  []ChartLine
+	Start  time.Time // 時間軸起點，為零值時取第一個點
+	End    time.Time // 時間軸終點，為零值時取最後一個點
+	Width  int
+	Height int
+}
+
+// NewChart 創建默認尺寸的趨勢圖
+func NewChart(title string, points []TrendPoint) *Chart {
+	return &Chart{Title: title, Points: points, Width: DefaultChartWidth, Height: DefaultChartHeight}
+}
+
+// SetRange 設置時間軸範圍
+func (c *Chart) SetRange(start, end time.Time) *Chart {
+	c.Start, c.End = start, end
+	return c
+}
+
+// SetSize 設置圖片尺寸（像素）
+func (c *Chart) SetSize(width, height int) *Chart {
+	c.Width, c.Height = width, height
+	return c
+}
+
+// AddLines 添加水平參考線
+func (c *Chart) AddLines(lines ...ChartLine) *Chart {
+	c.Lines = append(c.Lines, lines...)
+	return c
+}
+
+// chartLayout 繪圖用的坐標換算
+type chartLayout struct {
+	width, height         int
+	left, right, top, bot float64
+	start, end            time.Time
+	minY, maxY            float64
+	yTicks                []float64
+	xTicks                []time.Time
+	timeLayout            string
+	segments              [][]chartXY
+}
+
+// chartXY 像素坐標
+type chartXY struct {
+	x, y float64
+}
+
+// layout 計算坐標範圍、刻度和折線
+func (c *Chart) layout() *chartLayout {
+	width, height := c.Width, c.Height
+	if width <= chartMarginLeft+chartMarginRight {
+		width = DefaultChartWidth
+	}
+	if height <= chartMarginTop+chartMarginBottom {
+		height = DefaultChartHeight
+	}
+
+	l := &chartLayout{
+		width:  width,
+		height: height,
+		left:   chartMarginLeft,
+		right:  float64(width - chartMarginRight),
+		top:    chartMarginTop,
+		bot:    float64(height - chartMarginBottom),
+		start:  c.Start,
+		end:    c.End,
+	}
+	if l.start.IsZero() && len(c.Points) > 0 {
+		l.start = c.Points[0].Time
+	}
+	if l.end.IsZero() && len(c.Points) > 0 {
+		l.end = c.Points[len(c.Points)-1].Time
+	}
+	if l.start.IsZero() {
+		l.end = time.Now()
+		l.start = l.end.Add(-time.Hour)
+	}
+	if !l.end.After(l.start) {
+		l.end = l.start.Add(time.Minute)
+	}
+
+	// 縱軸範圍包含所有點和參考線
+	l.minY, l.maxY = math.Inf(1), math.Inf(-1)
+	for _, p := range c.Points {
+		l.minY = math.Min(l.minY, p.Pressure)
+		l.maxY = math.Max(l.maxY, p.Pressure)
+	}
+	for _, line := range c.Lines {
+		l.minY = math.Min(l.minY, line.Value)
+		l.maxY = math.Max(l.maxY, line.Value)
+	}
+	if math.IsInf(l.minY, 0) {
+		l.minY, l.maxY = -1, 1
+	}
+	if l.maxY-l.minY < 1e-9 {
+		l.minY, l.maxY = l.minY-1, l.maxY+1
+	}
+	step := niceStep((l.maxY - l.minY) / 5)
+	l.minY = math.Floor(l.minY/step) * step
+	l.maxY = math.Ceil(l.maxY/step) * step
+	for v := l.minY; v <= l.maxY+step/2; v += step {
+		l.yTicks = append(l.yTicks, v)
+	}
+
+	span := l.end.Sub(l.start)
+	l.timeLayout = "15:04"
+	if span > 24*time.Hour {
+		l.timeLayout = "01-02 15:04"
+	} else if span < 10*time.Minute {
+		l.timeLayout = "15:04:05"
+	}
+	for i := 0; i <= 4; i++ {
+		l.xTicks = append(l.xTicks, l.start.Add(span*time.Duration(i)/4))
+	}
+
+	l.segments = c.segments(l)
+	return l
+}
+
+// px 將時間換算為橫坐標
+func (l *chartLayout) px(t time.Time) float64 {
+	ratio := float64(t.Sub(l.start)) / float64(l.end.Sub(l.start))
+	return l.left + ratio*(l.right-l.left)
+}
+
+// py 將壓力值換算為縱坐標
+func (l *chartLayout) py(v float64) float64 {
+	ratio := (v - l.minY) / (l.maxY - l.minY)
+	return l.bot - ratio*(l.bot-l.top)
+}
+
+// segments 將範圍內的點按像素列抽樣，並在數據缺失處斷開
+func (c *Chart) segments(l *chartLayout) [][]chartXY {
+	var points []TrendPoint
+	for _, p := range c.Points {
+		if !p.Time.Before(l.start) && !p.Time.After(l.end) {
+			points = append(points, p)
+		}
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	gap := 3 * medianInterval(points)
+	var segments [][]chartXY
+	var current []chartXY
+	column, colMin, colMax := -1, 0.0, 0.0
+	var colMinFirst bool
+	flush := func() {
+		if column < 0 {
+			return
+		}
+		x := l.left + float64(column)
+		if colMinFirst {
+			current = append(current, chartXY{x, l.py(colMin)}, chartXY{x, l.py(colMax)})
+		} else {
+			current = append(current, chartXY{x, l.py(colMax)}, chartXY{x, l.py(colMin)})
+		}
+	}
+
+	for i, p := range points {
+		if i > 0 && gap > 0 && p.Time.Sub(points[i-1].Time) > gap {
+			flush()
+			segments = append(segments, current)
+			current, column = nil, -1
+		}
+		col := int(l.px(p.Time) - l.left)
+		if col != column {
+			flush()
+			column, colMin, colMax, colMinFirst = col, p.Pressure, p.Pressure, true
+			continue
+		}
+		if p.Pressure < colMin {
+			colMin, colMinFirst = p.Pressure, false
+		}
+		if p.Pressure > colMax {
+			colMax = p.Pressure
+		}
+	}
+	flush()
+	return append(segments, current)
+}
+
+// medianInterval 返回相鄰點時間間隔的中位數
+func medianInterval(points []TrendPoint) time.Duration {
+	if len(points) < 2 {
+		return 0
+	}
+	intervals := make([]time.Duration, 0, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		intervals = append(intervals, points[i].Time.Sub(points[i-1].Time))
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+	return intervals[len(intervals)/2]
+}
+
+// niceStep 返回不小於 raw 的 1、2、5 × 10^n 刻度間隔
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// formatTick 格式化縱軸刻度
+func formatTick(v float64) string {
+	if math.Abs(v) < 1e-9 {
+		return "0"
+	}
+	s := fmt.Sprintf("%.4f", v)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return s
+}
+
+// WriteSVG 將趨勢圖渲染為 SVG
+func (c *Chart) WriteSVG(w io.Writer) error {
+	l := c.layout()
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`+"\n",
+		l.width, l.height, l.width, l.height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/>`+"\n", l.width, l.height)
+	fmt.Fprintf(&b, `<text x="%.0f" y="20" font-size="14" font-weight="bold">%s</text>`+"\n", l.left, html.EscapeString(c.Title))
+
+	for _, v := range l.yTicks {
+		y := l.py(v)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#e5e5e5"/>`+"\n", l.left, y, l.right, y)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="end">%s</text>`+"\n", l.left-6, y+4, formatTick(v))
+	}
+	for i, t := range l.xTicks {
+		x := l.px(t)
+		anchor := "middle"
+		if i == len(l.xTicks)-1 {
+			anchor = "end" // 最後一個刻度靠右對齊，避免超出圖片
+		}
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#e5e5e5"/>`+"\n", x, l.top, x, l.bot)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`+"\n", x, l.bot+16, anchor, t.Format(l.timeLayout))
+	}
+	fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="none" stroke="#999999"/>`+"\n",
+		l.left, l.top, l.right-l.left, l.bot-l.top)
+	unit := c.Unit
+	if unit == "" {
+		unit = Pascal.Symbol()
+	}
+	fmt.Fprintf(&b, `<text x="12" y="%.1f" transform="rotate(-90 12 %.1f)" text-anchor="middle">%s</text>`+"\n",
+		(l.top+l.bot)/2, (l.top+l.bot)/2, html.EscapeString(unit))
+
+	for _, line := range c.Lines {
+		y := l.py(line.Value)
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#d92d20" stroke-dasharray="6 4"/>`+"\n", l.left, y, l.right, y)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="end" fill="#d92d20">%s %s</text>`+"\n",
+			l.right-4, y-4, html.EscapeString(line.Label), formatTick(line.Value))
+	}
+
+	for _, segment := range l.segments {
+		if len(segment) == 0 {
+			continue
+		}
+		b.WriteString(`<polyline fill="none" stroke="#1d4ed8" stroke-width="1.5" points="`)
+		for i, p := range segment {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%.1f,%.1f", p.x, p.y)
+		}
+		b.WriteString(`"/>` + "\n")
+	}
+	if len(l.segments) == 0 {
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle" fill="#999999">無有效讀數</text>`+"\n",
+			(l.left+l.right)/2, (l.top+l.bot)/2)
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// SVG 返回 SVG 字符串，便於嵌入 HTML
+func (c *Chart) SVG() string {
+	var b strings.Builder
+	c.WriteSVG(&b)
+	return b.String()
+}
+
+// PNG 繪圖顏色
+var (
+	chartColorGrid  = color.RGBA{0xe5, 0xe5, 0xe5, 0xff}
+	chartColorFrame = color.RGBA{0x99, 0x99, 0x99, 0xff}
+	chartColorText  = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	chartColorLimit = color.RGBA{0xd9, 0x2d, 0x20, 0xff}
+	chartColorData  = color.RGBA{0x1d, 0x4e, 0xd8, 0xff}
+)
+
+// WritePNG 將趨勢圖渲染為 PNG
+//
+// 標準庫沒有字體，刻度用內置的點陣數字繪製，不繪製標題和參考線標籤。
+func (c *Chart) WritePNG(w io.Writer) error {
+	l := c.layout()
+	img := image.NewRGBA(image.Rect(0, 0, l.width, l.height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+
+	for _, v := range l.yTicks {
+		y := l.py(v)
+		drawLine(img, l.left, y, l.right, y, chartColorGrid, 0)
+		label := formatTick(v)
+		drawDigits(img, int(l.left)-6-digitsWidth(label), int(y)-digitHeight, label, chartColorText)
+	}
+	for _, t := range l.xTicks {
+		x := l.px(t)
+		drawLine(img, x, l.top, x, l.bot, chartColorGrid, 0)
+		label := t.Format(l.timeLayout)
+		left := int(x) - digitsWidth(label)/2
+		if left+digitsWidth(label) > l.width {
+			left = l.width - digitsWidth(label)
+		}
+		drawDigits(img, left, int(l.bot)+6, label, chartColorText)
+	}
+	drawLine(img, l.left, l.top, l.right, l.top, chartColorFrame, 0)
+	drawLine(img, l.left, l.bot, l.right, l.bot, chartColorFrame, 0)
+	drawLine(img, l.left, l.top, l.left, l.bot, chartColorFrame, 0)
+	drawLine(img, l.right, l.top, l.right, l.bot, chartColorFrame, 0)
+
+	for _, line := range c.Lines {
+		y := l.py(line.Value)
+		drawLine(img, l.left, y, l.right, y, chartColorLimit, 6)
+	}
+	for _, segment := range l.segments {
+		for i := 1; i < len(segment); i++ {
+			drawLine(img, segment[i-1].x, segment[i-1].y, segment[i].x, segment[i].y, chartColorData, 0)
+		}
+		if len(segment) == 1 {
+			img.Set(int(segment[0].x), int(segment[0].y), chartColorData)
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// drawLine 以 Bresenham 算法畫線，dash 大於 0 時畫虛線
+func drawLine(img *image.RGBA, x0f, y0f, x1f, y1f float64, c color.Color, dash int) {
+	x0, y0, x1, y1 := int(math.Round(x0f)), int(math.Round(y0f)), int(math.Round(x1f)), int(math.Round(y1f))
+	dx, dy := absInt(x1-x0), -absInt(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for n := 0; ; n++ {
+		if dash <= 0 || (n/dash)%2 == 0 {
+			img.Set(x0, y0, c)
+		}
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// 點陣數字：每個字符 3×5 點，放大 2 倍繪製
+const (
+	digitScale  = 2
+	digitWidth  = 4 * digitScale // 含 1 點間距
+	digitHeight = 5 * digitScale
+)
+
+// digitGlyphs 每行 3 位，高位在左
+var digitGlyphs = map[rune][5]uint8{
+	'0': {7, 5, 5, 5, 7},
+	'1': {2, 6, 2, 2, 7},
+	'2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 7, 1, 7},
+	'4': {5, 5, 7, 1, 1},
+	'5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7},
+	'7': {7, 1, 1, 1, 1},
+	'8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	'-': {0, 0, 7, 0, 0},
+	'.': {0, 0, 0, 0, 2},
+	':': {0, 2, 0, 2, 0},
+	' ': {0, 0, 0, 0, 0},
+}
+
+// digitsWidth 返回點陣字符串的像素寬度
+func digitsWidth(s string) int {
+	return len([]rune(s)) * digitWidth
+}
+
+// drawDigits 在 (x, y) 處繪製點陣字符串，不支援的字符留空
+func drawDigits(img *image.RGBA, x, y int, s string, c color.Color) {
+	for _, r := range s {
+		glyph := digitGlyphs[r]
+		for row := 0; row < 5; row++ {
+			for col := 0; col < 3; col++ {
+				if glyph[row]&(4>>col) == 0 {
+					continue
+				}
+				for dy := 0; dy < digitScale; dy++ {
+					for dx := 0; dx < digitScale; dx++ {
+						img.Set(x+col*digitScale+dx, y+row*digitScale+dy, c)
+					}
+				}
+			}
+		}
+		x += digitWidth
+	}
+}
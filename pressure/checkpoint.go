@@ -0,0 +1,207 @@
+// pressure/checkpoint.go - 掃描進度檢查點，支援中斷後恢復完整掃描
+package pressure
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultCheckpointFile 默認的掃描檢查點檔案
+const DefaultCheckpointFile = "scan_checkpoint.json"
+
+// checkpointSaveInterval 每完成多少次探測保存一次檢查點；發現設備和掃描序列結束時立即保存
+const checkpointSaveInterval = 16
+
+// ScanCheckpoint 掃描進度檢查點
+type ScanCheckpoint struct {
+	Config    ScanConfig                `json:"config"`     // 掃描配置
+	Progress  map[string]*SweepProgress `json:"progress"`   // 每個 串口@波特率/階段 的進度
+	StartedAt time.Time                 `json:"started_at"` // 掃描開始時間
+	UpdatedAt time.Time                 `json:"updated_at"` // 最後更新時間
+}
+
+// SweepProgress 單次掃描序列（串口 + 波特率 + 站點號列表）的進度
+type SweepProgress struct {
+	Port     string       `json:"port"`      // 串口
+	BaudRate int          `json:"baud_rate"` // 波特率
+	Next     int          `json:"next"`      // 下一個待探測站點號的索引
+	Devices  []DeviceInfo `json:"devices"`   // 已發現的響應設備
+}
+
+// checkpointTracker 線程安全地記錄和保存掃描進度
+type checkpointTracker struct {
+	mu      sync.Mutex
+	path    string
+	data    ScanCheckpoint
+	logger  *log.Logger
+	unsaved int   // 上次保存後完成的探測數
+	saveErr error // 最近一次保存失敗的錯誤，保存成功後清除
+}
+
+// LoadScanCheckpoint 讀取掃描檢查點檔案
+func LoadScanCheckpoint(path string) (*ScanCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint ScanCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("解析檢查點檔案失敗: %v", err)
+	}
+	if checkpoint.Progress == nil {
+		checkpoint.Progress = make(map[string]*SweepProgress)
+	}
+	return &checkpoint, nil
+}
+
+// newCheckpointTracker 創建進度記錄器，resume 為 true 時嘗試從既有檢查點恢復；保存失敗時記錄到 logger
+func newCheckpointTracker(path string, config ScanConfig, resume bool, logger *log.Logger) (*checkpointTracker, bool) {
+	if logger == nil {
+		logger = log.Default()
+	}
+	tracker := &checkpointTracker{
+		path:   path,
+		logger: logger,
+		data: ScanCheckpoint{
+			Config:    config,
+			Progress:  make(map[string]*SweepProgress),
+			StartedAt: time.Now(),
+		},
+	}
+
+	if !resume {
+		return tracker, false
+	}
+
+	checkpoint, err := LoadScanCheckpoint(path)
+	if err != nil {
+		return tracker, false
+	}
+
+	// 站點號、波特率或探測目標不同時進度無法對應，重新開始
+	if !reflect.DeepEqual(checkpoint.Config.SlaveIDs, config.SlaveIDs) ||
+		!reflect.DeepEqual(checkpoint.Config.BaudRates, config.BaudRates) ||
+		checkpoint.Config.ProbeFunction != config.ProbeFunction ||
+		checkpoint.Config.ProbeRegister != config.ProbeRegister ||
+		checkpoint.Config.ProbeCount != config.ProbeCount {
+		return tracker, false
+	}
+
+	checkpoint.Config = config
+	tracker.data = *checkpoint
+	return tracker, true
+}
+
+// sweep 獲取指定掃描序列的進度，不存在時創建
+func (t *checkpointTracker) sweep(port string, baudRate int, phase string) *SweepProgress {
+	key := fmt.Sprintf("%s@%d/%s", port, baudRate, phase)
+	progress, ok := t.data.Progress[key]
+	if !ok {
+		progress = &SweepProgress{Port: port, BaudRate: baudRate}
+		t.data.Progress[key] = progress
+	}
+	return progress
+}
+
+// resumeSweep 返回掃描序列的起始索引和已發現的設備
+func (t *checkpointTracker) resumeSweep(port string, baudRate int, phase string) (int, []DeviceInfo) {
+	if t == nil {
+		return 0, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	progress := t.sweep(port, baudRate, phase)
+	devices := make([]DeviceInfo, len(progress.Devices))
+	copy(devices, progress.Devices)
+	return progress.Next, devices
+}
+
+// advance 記錄一次探測完成，發現設備或累積 checkpointSaveInterval 次探測後保存檢查點
+func (t *checkpointTracker) advance(port string, baudRate int, phase string, device DeviceInfo) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	progress := t.sweep(port, baudRate, phase)
+	progress.Next++
+	t.unsaved++
+	if device.Responsive {
+		progress.Devices = append(progress.Devices, device)
+	}
+	if device.Responsive || t.unsaved >= checkpointSaveInterval {
+		t.saveLocked()
+	}
+}
+
+// flush 保存尚未寫入檔案的進度，在每個掃描序列結束時調用
+func (t *checkpointTracker) flush() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.unsaved > 0 {
+		t.saveLocked()
+	}
+}
+
+// saveLocked 將進度寫入檔案，調用者必須持有鎖
+//
+// 保存失敗時記錄一次警告，恢復後再記錄一次，不會每次探測重複記錄；失敗不中斷掃描，只是中斷後無法恢復到最新進度。
+func (t *checkpointTracker) saveLocked() error {
+	t.unsaved = 0
+	err := t.writeLocked()
+	switch {
+	case err != nil && t.saveErr == nil:
+		t.logger.Printf("⚠️  保存掃描檢查點失敗，中斷後將無法從當前進度恢復: %v", err)
+	case err == nil && t.saveErr != nil:
+		t.logger.Printf("掃描檢查點已恢復保存: %s", t.path)
+	}
+	t.saveErr = err
+	return err
+}
+
+// writeLocked 將進度寫入檔案，調用者必須持有鎖
+func (t *checkpointTracker) writeLocked() error {
+	t.data.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(t.data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化檢查點失敗: %v", err)
+	}
+
+	// 先寫臨時檔案再改名，避免中斷時留下不完整的檢查點
+	tmpPath := t.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("寫入檢查點失敗: %v", err)
+	}
+	if err := os.Rename(tmpPath, t.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("替換檢查點檔案失敗: %v", err)
+	}
+	return nil
+}
+
+// remove 掃描完成後刪除檢查點檔案
+func (t *checkpointTracker) remove() {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := os.Remove(t.path); err != nil && !os.IsNotExist(err) {
+		t.logger.Printf("⚠️  刪除掃描檢查點失敗，下次 --resume 會從已完成的掃描恢復: %v", err)
+	}
+}
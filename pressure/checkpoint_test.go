@@ -0,0 +1,70 @@
+package pressure
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckpointSavesThrottled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	tracker, _ := newCheckpointTracker(path, ScanConfig{}, false, log.New(&bytes.Buffer{}, "", 0))
+
+	for i := 0; i < 3; i++ {
+		tracker.advance("COM3", 9600, "pushida", DeviceInfo{SlaveID: byte(i + 1)})
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint written after 3 unresponsive probes: %v", err)
+	}
+
+	// 發現設備時立即保存
+	tracker.advance("COM3", 9600, "pushida", DeviceInfo{SlaveID: 4, Responsive: true})
+	checkpoint, err := LoadScanCheckpoint(path)
+	if err != nil {
+		t.Fatalf("checkpoint after a responsive probe: %v", err)
+	}
+	if progress := checkpoint.Progress["COM3@9600/pushida"]; progress == nil || progress.Next != 4 || len(progress.Devices) != 1 {
+		t.Fatalf("progress = %+v, want next 4 with 1 device", progress)
+	}
+
+	// 掃描序列結束時保存剩餘進度
+	tracker.advance("COM3", 9600, "pushida", DeviceInfo{SlaveID: 5})
+	tracker.flush()
+	if checkpoint, err = LoadScanCheckpoint(path); err != nil || checkpoint.Progress["COM3@9600/pushida"].Next != 5 {
+		t.Fatalf("checkpoint after flush: %+v, %v; want next 5", checkpoint, err)
+	}
+
+	for i := 0; i < checkpointSaveInterval; i++ {
+		tracker.advance("COM4", 9600, "pushida", DeviceInfo{SlaveID: byte(i + 1)})
+	}
+	if checkpoint, err = LoadScanCheckpoint(path); err != nil || checkpoint.Progress["COM4@9600/pushida"] == nil {
+		t.Fatalf("checkpoint after %d probes: %v; want COM4 progress saved", checkpointSaveInterval, err)
+	}
+}
+
+func TestCheckpointReportsSaveErrorsOnce(t *testing.T) {
+	var logs bytes.Buffer
+	path := filepath.Join(t.TempDir(), "missing", "checkpoint.json")
+	tracker, _ := newCheckpointTracker(path, ScanConfig{}, false, log.New(&logs, "", 0))
+
+	for i := 0; i < 3; i++ {
+		tracker.advance("COM3", 9600, "pushida", DeviceInfo{SlaveID: byte(i + 1), Responsive: true})
+	}
+	if tracker.saveErr == nil {
+		t.Fatal("saveErr = nil for an unwritable checkpoint path")
+	}
+	if count := strings.Count(logs.String(), "保存掃描檢查點失敗"); count != 1 {
+		t.Errorf("save failure logged %d times, want once:\n%s", count, logs.String())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tracker.advance("COM3", 9600, "pushida", DeviceInfo{SlaveID: 4, Responsive: true})
+	if tracker.saveErr != nil || !strings.Contains(logs.String(), "已恢復保存") {
+		t.Errorf("after the directory appeared: saveErr=%v logs:\n%s", tracker.saveErr, logs.String())
+	}
+}
@@ -0,0 +1,173 @@
+// pressure/coalesce.go - 合併讀取：地址相鄰的通道（壓力、溫度）用一次請求讀取，減少每個週期的總線事務
+package pressure
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/goburrow/modbus"
+)
+
+// MaxReadRegisters Modbus 單次讀取寄存器數量的上限
+const MaxReadRegisters = 125
+
+// DefaultCoalesceGap 合併讀取時默認最多跨越的未使用寄存器數量
+const DefaultCoalesceGap = 8
+
+// 讀取通道序號
+const (
+	channelPressure    = iota // 壓力寄存器（設備配置檔）
+	channelTemperature        // 溫度寄存器，未配置時不存在
+)
+
+// registerSpan 一段連續的寄存器
+type registerSpan struct {
+	function byte
+	address  uint16
+	count    uint16
+}
+
+// end 返回最後一個寄存器之後的地址
+func (rs registerSpan) end() int {
+	return int(rs.address) + int(rs.count)
+}
+
+// String 返回寄存器範圍的描述
+func (rs registerSpan) String() string {
+	return fmt.Sprintf("0x%04X-0x%04X (功能碼 0x%02X，%d 個寄存器)", rs.address, rs.end()-1, rs.function, rs.count)
+}
+
+// readBlock 一次讀取請求，覆蓋一個或多個通道
+type readBlock struct {
+	registerSpan
+	channels []int // 覆蓋的通道序號
+}
+
+// covers 讀取請求是否覆蓋通道
+func (rb readBlock) covers(channel int) bool {
+	for _, c := range rb.channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// extract 從讀取請求的響應數據中取出通道的寄存器數據
+func (rb readBlock) extract(data []byte, span registerSpan) []byte {
+	offset := 2 * int(span.address-rb.address)
+	return data[offset : offset+2*int(span.count)]
+}
+
+// readPlan 每個讀取週期的通道和讀取請求
+type readPlan struct {
+	spans  []registerSpan // 各通道的寄存器，按通道序號排列
+	blocks []readBlock
+}
+
+// newReadPlan 將通道合併為讀取請求
+//
+// 功能碼相同、間隔的未使用寄存器不超過 maxGap 且合併後不超過 MaxReadRegisters 的通道合為一次讀取；
+// maxGap 為負時每個通道單獨讀取。
+func newReadPlan(spans []registerSpan, maxGap int) *readPlan {
+	order := make([]int, len(spans))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := spans[order[i]], spans[order[j]]
+		if a.function != b.function {
+			return a.function < b.function
+		}
+		return a.address < b.address
+	})
+
+	plan := &readPlan{spans: spans}
+	for _, channel := range order {
+		span := spans[channel]
+		if n := len(plan.blocks); n > 0 && maxGap >= 0 {
+			last := &plan.blocks[n-1]
+			end := last.end()
+			if span.end() > end {
+				end = span.end()
+			}
+			if span.function == last.function && int(span.address)-last.end() <= maxGap &&
+				end-int(last.address) <= MaxReadRegisters {
+				last.count = uint16(end - int(last.address))
+				last.channels = append(last.channels, channel)
+				continue
+			}
+		}
+		plan.blocks = append(plan.blocks, readBlock{registerSpan: span, channels: []int{channel}})
+	}
+	return plan
+}
+
+// block 返回覆蓋通道的讀取請求
+func (rp *readPlan) block(channel int) readBlock {
+	for _, block := range rp.blocks {
+		if block.covers(channel) {
+			return block
+		}
+	}
+	return readBlock{registerSpan: rp.spans[channel], channels: []int{channel}}
+}
+
+// coalesced 是否有通道合併讀取
+func (rp *readPlan) coalesced() bool {
+	return len(rp.blocks) < len(rp.spans)
+}
+
+// channelSpans 返回每個週期需要讀取的通道
+func (pm *PressureMeter) channelSpans() []registerSpan {
+	spans := []registerSpan{{function: pm.profile.Function, address: pm.profile.Register, count: pm.profile.Count}}
+	if pm.SupportsTemperature() {
+		spans = append(spans, registerSpan{function: ModbusFunctionReadHoldingRegisters, address: pm.tempRegister, count: 1})
+	}
+	return spans
+}
+
+// planReads 按設備配置檔的合併間隔生成讀取計劃
+func (pm *PressureMeter) planReads() {
+	plan := newReadPlan(pm.channelSpans(), pm.profile.coalesceGap())
+	pm.plan.Store(plan)
+	if plan.coalesced() {
+		pm.logger.Printf("合併讀取壓力和溫度寄存器: %s", plan.block(channelPressure).registerSpan)
+	}
+}
+
+// disableCoalescing 合併讀取的範圍包含設備不提供的地址時改為每個通道單獨讀取
+//
+// 只在儀表以非法地址 (0x02) 拒絕合併後的請求時生效，返回新的讀取計劃；其他錯誤返回 nil。
+func (pm *PressureMeter) disableCoalescing(plan *readPlan, err error) *readPlan {
+	var modbusErr *modbus.ModbusError
+	if !plan.coalesced() || !errors.As(err, &modbusErr) || modbusErr.ExceptionCode != modbus.ExceptionCodeIllegalDataAddress {
+		return nil
+	}
+	separate := newReadPlan(plan.spans, -1)
+	pm.plan.Store(separate)
+	pm.logger.Printf("⚠️  設備拒絕合併讀取 %s (%v)，改為分別讀取各寄存器", plan.block(channelPressure).registerSpan, err)
+	return separate
+}
+
+// readSpan 讀取一段寄存器
+func (pm *PressureMeter) readSpan(span registerSpan) ([]byte, error) {
+	if span.function == ModbusFunctionReadInputRegisters {
+		return pm.client.ReadInputRegisters(span.address, span.count)
+	}
+	return pm.client.ReadHoldingRegisters(span.address, span.count)
+}
+
+// ReadRequests 返回每個讀取週期的 Modbus 請求數（不含重試），合併讀取時少於通道數
+func (pm *PressureMeter) ReadRequests() int {
+	return len(pm.plan.Load().blocks)
+}
+
+// coalesceGap 返回合併讀取時最多跨越的未使用寄存器數量，負數為不合併
+func (dp DeviceProfile) coalesceGap() int {
+	if dp.CoalesceGap == 0 {
+		return DefaultCoalesceGap
+	}
+	return dp.CoalesceGap
+}
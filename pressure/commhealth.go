@@ -0,0 +1,113 @@
+// pressure/commhealth.go - 設備通信健康統計：成功、超時、校驗和幀錯誤次數及響應延遲
+package pressure
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// DefaultCommLatencySamples 計算 P95 響應延遲使用的最近請求數量
+const DefaultCommLatencySamples = 200
+
+// CommHealth 設備通信健康統計，用於排查接線、終端電阻和干擾等問題
+//
+// 每次 Modbus 請求（含重試）計數一次；延遲只統計成功的請求，平均值為累計平均，
+// P95 按最近 DefaultCommLatencySamples 次成功請求計算。
+type CommHealth struct {
+	Requests         int64         `json:"requests"`             // Modbus 請求總數
+	Successes        int64         `json:"successes"`            // 成功的請求
+	Timeouts         int64         `json:"timeouts"`             // 設備無響應
+	CRCErrors        int64         `json:"crc_errors"`           // 響應校驗錯誤
+	FramingErrors    int64         `json:"framing_errors"`       // 響應長度、事務號等幀格式錯誤
+	Exceptions       int64         `json:"exceptions"`           // 設備返回的 Modbus 異常響應
+	ConnectionErrors int64         `json:"connection_errors"`    // 串口或網絡連接錯誤
+	SuccessRate      float64       `json:"success_rate"`         // 成功率 (%)
+	AvgLatency       time.Duration `json:"avg_latency"`          // 平均響應延遲
+	P95Latency       time.Duration `json:"p95_latency"`          // P95 響應延遲
+	LastError        string        `json:"last_error,omitempty"` // 最近一次失敗的錯誤
+}
+
+// commCounters 通信健康統計的計數器
+type commCounters struct {
+	mu           sync.Mutex
+	health       CommHealth
+	totalLatency time.Duration
+	samples      []time.Duration // 按請求順序循環記錄最近的成功請求延遲
+}
+
+// record 按請求結果更新計數
+func (cc *commCounters) record(err error, latency time.Duration) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	h := &cc.health
+	h.Requests++
+	if err == nil {
+		if len(cc.samples) < DefaultCommLatencySamples {
+			cc.samples = append(cc.samples, latency)
+		} else {
+			cc.samples[h.Successes%DefaultCommLatencySamples] = latency
+		}
+		h.Successes++
+		cc.totalLatency += latency
+		return
+	}
+
+	h.LastError = err.Error()
+	var modbusErr *modbus.ModbusError
+	switch {
+	case errors.As(err, &modbusErr):
+		h.Exceptions++
+	case ClassifyError(err) == ErrTimeout:
+		h.Timeouts++
+	case strings.Contains(strings.ToLower(err.Error()), "crc"):
+		h.CRCErrors++
+	case ClassifyError(err) == ErrProtocol:
+		h.FramingErrors++
+	default:
+		h.ConnectionErrors++
+	}
+}
+
+// snapshot 返回計數的副本並計算成功率和延遲
+func (cc *commCounters) snapshot() CommHealth {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	h := cc.health
+	if h.Requests > 0 {
+		h.SuccessRate = float64(h.Successes) / float64(h.Requests) * 100
+	}
+	if h.Successes > 0 {
+		h.AvgLatency = cc.totalLatency / time.Duration(h.Successes)
+		sorted := append([]time.Duration(nil), cc.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		h.P95Latency = sorted[(len(sorted)*95+99)/100-1]
+	}
+	return h
+}
+
+// CommHealth 返回設備的通信健康統計
+func (pm *PressureMeter) CommHealth() CommHealth {
+	return pm.comm.snapshot()
+}
+
+// Print 將通信健康統計寫入 w
+func (ch CommHealth) Print(w io.Writer) {
+	fmt.Fprintf(w, "通信: 請求 %d，成功 %d (%.1f%%)\n", ch.Requests, ch.Successes, ch.SuccessRate)
+	fmt.Fprintf(w, "   超時 %d，校驗錯誤 %d，幀錯誤 %d，異常響應 %d，連接錯誤 %d\n",
+		ch.Timeouts, ch.CRCErrors, ch.FramingErrors, ch.Exceptions, ch.ConnectionErrors)
+	if ch.Successes > 0 {
+		fmt.Fprintf(w, "   響應延遲: 平均 %v，P95 %v\n", ch.AvgLatency.Round(time.Microsecond), ch.P95Latency.Round(time.Microsecond))
+	}
+	if ch.LastError != "" {
+		fmt.Fprintf(w, "   最近錯誤: %s\n", ch.LastError)
+	}
+}
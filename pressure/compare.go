@@ -0,0 +1,383 @@
+// pressure/compare.go - 與標準器記錄比對：讀入參考記錄儀的 CSV，按時間對齊本工具的記錄並計算誤差
+package pressure
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCompareTolerance 參考記錄與讀數對齊時允許的最大時間差
+const DefaultCompareTolerance = 2 * time.Second
+
+// CompareOptions 比對選項
+type CompareOptions struct {
+	TimeColumn  string        // 參考 CSV 的時間列（列名或從 1 開始的序號），為空時自動識別
+	ValueColumn string        // 參考 CSV 的壓力列（列名或從 1 開始的序號），為空時自動識別
+	Unit        PressureUnit  // 參考記錄的壓力單位
+	Shift       time.Duration // 加到參考時間上的偏移，用於修正兩台設備的時鐘差
+	Tolerance   time.Duration // 對齊時允許的最大時間差，0 為 DefaultCompareTolerance
+}
+
+// ComparePoint 一個對齊後的比對點
+type ComparePoint struct {
+	Time      time.Time `json:"time"`      // 參考時間（已加偏移）
+	Reference float64   `json:"reference"` // 參考值 (Pa)
+	Measured  float64   `json:"measured"`  // 本工具讀數 (Pa)，在相鄰兩個讀數間線性插值
+	Error     float64   `json:"error"`     // 誤差 = 讀數 − 參考值 (Pa)
+}
+
+// CompareResult 比對的誤差統計
+type CompareResult struct {
+	ReferencePoints int            `json:"reference_points"` // 參考記錄數
+	RecordedPoints  int            `json:"recorded_points"`  // 本工具的有效讀數
+	Matched         int            `json:"matched"`          // 對齊成功的比對點
+	Start           time.Time      `json:"start"`            // 第一個比對點
+	End             time.Time      `json:"end"`              // 最後一個比對點
+	Bias            float64        `json:"bias"`             // 平均誤差 (Pa)
+	StdDev          float64        `json:"std_dev"`          // 誤差標準差 (Pa)
+	RMSE            float64        `json:"rmse"`             // 均方根誤差 (Pa)
+	MaxDeviation    float64        `json:"max_deviation"`    // 絕對值最大的誤差 (Pa，帶符號)
+	MaxDeviationAt  time.Time      `json:"max_deviation_at"` // 最大誤差出現的時間
+	Points          []ComparePoint `json:"-"`                // 全部比對點
+}
+
+// referenceTimeLayouts 參考記錄支援的時間格式（無時區的按本地時間解析）
+var referenceTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006/01/02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"01/02/2006 15:04:05",
+	"2006-01-02 15:04",
+	"2006/01/02 15:04",
+}
+
+// LoadReferenceCSV 讀取參考記錄儀匯出的 CSV
+//
+// 分隔符（逗號、分號或 Tab）和表頭自動識別；沒有表頭時第 1 列為時間、第 2 列為壓力。
+// 時間支援常見的日期時間格式和 Unix 秒，壓力按 opts.Unit 換算為 Pa。
+func LoadReferenceCSV(r io.Reader, opts CompareOptions) ([]TrendPoint, error) {
+	points, err := readPressureCSV(r, opts.TimeColumn, opts.ValueColumn)
+	if err != nil {
+		return nil, err
+	}
+	for i := range points {
+		points[i].Time = points[i].Time.Add(opts.Shift)
+		points[i].Pressure = opts.Unit.ConvertToPascal(points[i].Pressure)
+	}
+	return points, nil
+}
+
+// recordedReading JSON 輸出和合規日誌中比對需要的字段
+type recordedReading struct {
+	Timestamp time.Time `json:"timestamp"`
+	Pressure  float64   `json:"pressure"`
+	Valid     bool      `json:"valid"`
+}
+
+// LoadRecordedReadings 讀取本工具記錄的讀數：--output=csv 或 --output=json 的輸出，或合規日誌
+//
+// 無效讀數會被跳過。
+func LoadRecordedReadings(r io.Reader) ([]TrendPoint, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := bytes.Split(data, []byte("\n"))
+	isJSON := false
+	for _, line := range lines {
+		if bytes.HasPrefix(bytes.TrimSpace(line), []byte("{")) {
+			isJSON = true
+			break
+		}
+	}
+	if !isJSON {
+		return readPressureCSV(bytes.NewReader(data), "timestamp", "pressure")
+	}
+
+	// JSON 行：跳過不是 JSON 對象的行（如重定向輸出時混入的提示信息）
+	var points []TrendPoint
+	for n, line := range lines {
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("{")) {
+			continue
+		}
+		var entry struct {
+			recordedReading
+			Record *recordedReading `json:"record"` // 合規日誌
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("第 %d 行不是有效的 JSON: %v", n+1, err)
+		}
+		reading := entry.recordedReading
+		if entry.Record != nil {
+			reading = *entry.Record
+		}
+		if reading.Valid && !reading.Timestamp.IsZero() {
+			points = append(points, TrendPoint{Time: reading.Timestamp, Pressure: reading.Pressure})
+		}
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("沒有有效讀數")
+	}
+	return points, nil
+}
+
+// LoadReferenceFile 打開並讀取參考 CSV 檔案
+func LoadReferenceFile(filename string, opts CompareOptions) ([]TrendPoint, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	points, err := LoadReferenceCSV(file, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return points, nil
+}
+
+// LoadRecordedFile 打開並讀取本工具記錄的讀數檔案
+func LoadRecordedFile(filename string) ([]TrendPoint, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	points, err := LoadRecordedReadings(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return points, nil
+}
+
+// CompareReadings 將參考記錄與本工具的讀數按時間對齊並計算誤差
+//
+// 參考時間兩側各有一個讀數且間隔不超過 2 倍容差時線性插值，否則取容差內最近的讀數；
+// 容差內沒有讀數的參考記錄不參與統計。
+func CompareReadings(reference, recorded []TrendPoint, opts CompareOptions) CompareResult {
+	tolerance := opts.Tolerance
+	if tolerance <= 0 {
+		tolerance = DefaultCompareTolerance
+	}
+	sorted := append([]TrendPoint(nil), recorded...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	result := CompareResult{ReferencePoints: len(reference), RecordedPoints: len(recorded)}
+	var sum, sumSquares float64
+	for _, ref := range reference {
+		measured, ok := alignReading(sorted, ref.Time, tolerance)
+		if !ok {
+			continue
+		}
+		point := ComparePoint{Time: ref.Time, Reference: ref.Pressure, Measured: measured, Error: measured - ref.Pressure}
+		result.Points = append(result.Points, point)
+
+		sum += point.Error
+		sumSquares += point.Error * point.Error
+		if len(result.Points) == 1 || math.Abs(point.Error) > math.Abs(result.MaxDeviation) {
+			result.MaxDeviation = point.Error
+			result.MaxDeviationAt = point.Time
+		}
+		if result.Start.IsZero() || point.Time.Before(result.Start) {
+			result.Start = point.Time
+		}
+		if point.Time.After(result.End) {
+			result.End = point.Time
+		}
+	}
+
+	result.Matched = len(result.Points)
+	if result.Matched > 0 {
+		n := float64(result.Matched)
+		result.Bias = sum / n
+		result.RMSE = math.Sqrt(sumSquares / n)
+		result.StdDev = math.Sqrt(math.Max(sumSquares/n-result.Bias*result.Bias, 0))
+	}
+	return result
+}
+
+// Print 將比對結果寫入 w
+func (cr CompareResult) Print(w io.Writer) {
+	fmt.Fprintf(w, "參考記錄: %d 筆，本工具有效讀數: %d 筆，對齊: %d 筆\n", cr.ReferencePoints, cr.RecordedPoints, cr.Matched)
+	if cr.Matched == 0 {
+		return
+	}
+	fmt.Fprintf(w, "時間範圍: %s ~ %s\n", cr.Start.Format("2006-01-02 15:04:05"), cr.End.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "平均誤差 (bias): %+.3f Pa\n", cr.Bias)
+	fmt.Fprintf(w, "誤差標準差: %.3f Pa\n", cr.StdDev)
+	fmt.Fprintf(w, "均方根誤差 (RMSE): %.3f Pa\n", cr.RMSE)
+	fmt.Fprintf(w, "最大偏差: %+.3f Pa (%s)\n", cr.MaxDeviation, cr.MaxDeviationAt.Format("2006-01-02 15:04:05"))
+}
+
+// alignReading 返回 t 時刻的讀數（按時間排序的 points），容差內沒有讀數時返回 false
+func alignReading(points []TrendPoint, t time.Time, tolerance time.Duration) (float64, bool) {
+	i := sort.Search(len(points), func(i int) bool { return !points[i].Time.Before(t) })
+	if i < len(points) && points[i].Time.Equal(t) {
+		return points[i].Pressure, true
+	}
+
+	var before, after *TrendPoint
+	if i > 0 {
+		before = &points[i-1]
+	}
+	if i < len(points) {
+		after = &points[i]
+	}
+	if before != nil && after != nil && after.Time.Sub(before.Time) <= 2*tolerance {
+		ratio := float64(t.Sub(before.Time)) / float64(after.Time.Sub(before.Time))
+		return before.Pressure + ratio*(after.Pressure-before.Pressure), true
+	}
+
+	best, bestGap := 0.0, tolerance+1
+	if before != nil && t.Sub(before.Time) <= tolerance {
+		best, bestGap = before.Pressure, t.Sub(before.Time)
+	}
+	if after != nil && after.Time.Sub(t) < bestGap {
+		best, bestGap = after.Pressure, after.Time.Sub(t)
+	}
+	return best, bestGap <= tolerance
+}
+
+// readPressureCSV 讀取含時間和壓力兩列的 CSV，跳過壓力不是數字或 valid 列為 false 的行
+func readPressureCSV(r io.Reader, timeColumn, valueColumn string) ([]TrendPoint, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.Comma = sniffDelimiter(data)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析 CSV 失敗: %v", err)
+	}
+	// 跳過開頭只有一列的行（如重定向輸出時混入的提示信息）
+	skipped := 0
+	for len(rows) > 0 && len(rows[0]) < 2 {
+		rows = rows[1:]
+		skipped++
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("CSV 為空")
+	}
+
+	// 第一行的第二列不是數字時視為表頭
+	var header []string
+	if len(rows[0]) > 1 {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(rows[0][1]), 64); err != nil {
+			header, rows = rows[0], rows[1:]
+		}
+	}
+	timeIdx, err := csvColumn(header, timeColumn, []string{"timestamp", "time", "date", "時間", "日期"}, 0)
+	if err != nil {
+		return nil, err
+	}
+	valueIdx, err := csvColumn(header, valueColumn, []string{"pressure", "壓力", "壓差", "value", "reading"}, 1)
+	if err != nil {
+		return nil, err
+	}
+	validIdx := -1
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), "valid") {
+			validIdx = i
+		}
+	}
+
+	firstLine := skipped + 1
+	if header != nil {
+		firstLine++
+	}
+	var points []TrendPoint
+	for n, row := range rows {
+		if timeIdx >= len(row) || valueIdx >= len(row) {
+			continue
+		}
+		if validIdx >= 0 && validIdx < len(row) && strings.TrimSpace(row[validIdx]) == "false" {
+			continue
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(row[valueIdx]), 64)
+		if err != nil || math.IsNaN(value) {
+			continue
+		}
+		t, err := parseReferenceTime(row[timeIdx])
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", n+firstLine, err)
+		}
+		points = append(points, TrendPoint{Time: t, Pressure: value})
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("沒有可用的記錄")
+	}
+	return points, nil
+}
+
+// csvColumn 按列名、從 1 開始的序號或候選列名找出列的位置，沒有表頭時使用 fallback
+func csvColumn(header []string, column string, candidates []string, fallback int) (int, error) {
+	column = strings.TrimSpace(column)
+	if column != "" {
+		if index, err := strconv.Atoi(column); err == nil && index >= 1 {
+			return index - 1, nil
+		}
+		for i, name := range header {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("找不到列: %s", column)
+	}
+	if header == nil {
+		return fallback, nil
+	}
+	for _, candidate := range candidates {
+		for i, name := range header {
+			if strings.Contains(strings.ToLower(name), candidate) {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("無法識別%s列，請指定列名或序號 (表頭: %s)",
+		map[int]string{0: "時間", 1: "壓力"}[fallback], strings.Join(header, ", "))
+}
+
+// sniffDelimiter 按第一行中出現最多的字符選擇分隔符
+func sniffDelimiter(data []byte) rune {
+	line := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line = data[:i]
+	}
+	best, bestCount := ',', bytes.Count(line, []byte(","))
+	for _, delim := range []rune{';', '\t'} {
+		if count := bytes.Count(line, []byte(string(delim))); count > bestCount {
+			best, bestCount = delim, count
+		}
+	}
+	return best
+}
+
+// parseReferenceTime 按支援的格式解析時間，純數字按 Unix 秒解析
+func parseReferenceTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		whole, frac := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(frac*1e9)), nil
+	}
+	for _, layout := range referenceTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("無法解析時間: %q", value)
+}
@@ -0,0 +1,108 @@
+// pressure/compensation.go - 溫度寄存器讀取和壓力通道的溫度補償
+package pressure
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// DefaultTemperatureScale 溫度寄存器原始值到 °C 的默認換算係數（有符號整數 ×0.1）
+const DefaultTemperatureScale = 0.1
+
+// TemperatureCompensation 壓力通道的溫度補償係數
+//
+// 補償公式：P = Praw × (1 + Gain × ΔT) + Offset × ΔT，其中 ΔT = T − Reference。
+// 係數一般來自儀表校準證書或現場兩點溫度試驗。
+type TemperatureCompensation struct {
+	Reference float64 `json:"reference" yaml:"reference"`               // 參考溫度 (°C)，通常為校準時的溫度
+	Offset    float64 `json:"offset,omitempty" yaml:"offset,omitempty"` // 零點溫漂 (Pa/°C)
+	Gain      float64 `json:"gain,omitempty" yaml:"gain,omitempty"`     // 量程溫漂 (1/°C)
+}
+
+// Apply 返回補償後的壓力值
+func (tc TemperatureCompensation) Apply(pressure, temperature float64) float64 {
+	delta := temperature - tc.Reference
+	return pressure*(1+tc.Gain*delta) + tc.Offset*delta
+}
+
+// String 返回補償公式的描述
+func (tc TemperatureCompensation) String() string {
+	return fmt.Sprintf("P×(1%+g×ΔT)%+g×ΔT, ΔT=T-%g°C", tc.Gain, tc.Offset, tc.Reference)
+}
+
+// SupportsTemperature 設備是否配置了溫度寄存器
+func (pm *PressureMeter) SupportsTemperature() bool {
+	return pm.tempRegister != 0
+}
+
+// ReadTemperature 讀取溫度寄存器並換算為 °C
+func (pm *PressureMeter) ReadTemperature() (float64, error) {
+	pm.busMu.Lock()
+	defer pm.busMu.Unlock()
+	return pm.readTemperature()
+}
+
+// readTemperature 讀取溫度寄存器，調用方需持有 busMu
+func (pm *PressureMeter) readTemperature() (float64, error) {
+	if !pm.SupportsTemperature() {
+		return 0, fmt.Errorf("未配置溫度寄存器")
+	}
+	raw, err := pm.readRegister(pm.tempRegister)
+	if err != nil {
+		return 0, err
+	}
+	return pm.temperature(raw), nil
+}
+
+// temperature 將溫度寄存器原始值換算為 °C
+func (pm *PressureMeter) temperature(raw uint16) float64 {
+	return float64(int16(raw)) * pm.tempScale
+}
+
+// readTemperatureSpan 在讀取週期中單獨讀取溫度寄存器，調用方需持有 busMu
+//
+// 與壓力讀取一樣計入通信統計，失敗時按配置重試，重試次數累計到讀數。
+func (pm *PressureMeter) readTemperatureSpan(reading *PressureReading) ([]byte, error) {
+	span := pm.plan.Load().spans[channelTemperature]
+	for retries := 0; ; retries++ {
+		start := time.Now()
+		data, err := pm.readSpan(span)
+		if err == nil && len(data) != 2*int(span.count) {
+			err = errShortResponse{expected: 2 * int(span.count), actual: len(data)}
+		}
+		pm.comm.record(err, time.Since(start))
+		if err == nil || retries >= pm.maxRetries || !isRetryable(err) {
+			return data, err
+		}
+		reading.Retries++
+		pm.logger.Printf("讀取溫度失敗，%v 後第 %d 次重試: %v", pm.retryDelay, retries+1, err)
+		time.Sleep(pm.retryDelay)
+	}
+}
+
+// applyTemperature 為讀數附加溫度，配置了補償時保留原始值並寫入補償後的壓力
+//
+// data 為與壓力寄存器合併讀取到的溫度寄存器數據，為空時單獨讀取溫度寄存器。
+// 只讀取溫度時讀取失敗不影響讀數；配置了補償時返回未包裝的通信錯誤，由調用方分類，避免輸出未補償的數值。
+func (pm *PressureMeter) applyTemperature(reading *PressureReading, data []byte) error {
+	if len(data) != 2 {
+		var err error
+		if data, err = pm.readTemperatureSpan(reading); err != nil {
+			if pm.compensation != nil {
+				return err
+			}
+			pm.logger.Printf("讀取溫度失敗: %v", err)
+			return nil
+		}
+	}
+	temperature := pm.temperature(binary.BigEndian.Uint16(data))
+	reading.Temperature = &temperature
+
+	if pm.compensation != nil {
+		raw := reading.Pressure
+		reading.RawPressure = &raw
+		reading.Pressure = pm.compensation.Apply(raw, temperature)
+	}
+	return nil
+}
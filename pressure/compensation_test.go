@@ -0,0 +1,90 @@
+package pressure
+
+import (
+	"encoding/binary"
+	"os"
+	"sync"
+	"testing"
+)
+
+// withCompensation 配置與壓力寄存器不相鄰的溫度寄存器 0x0200 和溫度補償，溫度單獨讀取
+func withCompensation(config *Config) {
+	config.TemperatureRegister = 0x0200
+	config.Compensation = &TemperatureCompensation{Reference: 20, Offset: 1}
+}
+
+// dropTransport 讀取 address 的前 drops 個請求不響應的傳輸層
+type dropTransport struct {
+	*MockTransport
+	address uint16
+
+	mu    sync.Mutex
+	drops int
+}
+
+func (dt *dropTransport) Send(request []byte) ([]byte, error) {
+	dt.mu.Lock()
+	drop := dt.drops > 0 && len(request) >= 4 && binary.BigEndian.Uint16(request[2:]) == dt.address
+	if drop {
+		dt.drops--
+	}
+	dt.mu.Unlock()
+	if drop {
+		return nil, os.ErrDeadlineExceeded
+	}
+	return dt.MockTransport.Send(request)
+}
+
+func TestTemperatureCompensation(t *testing.T) {
+	// 12.3 Pa，25.0 °C：補償後 12.3 + 1 × 5 = 17.3 Pa
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0, 123).SetRegisters(0x0200, 250)
+	pm := newTestMeter(t, mock, withCompensation)
+
+	reading := pm.ReadPressure()
+	if !reading.Valid || reading.RawPressure == nil || reading.Temperature == nil {
+		t.Fatalf("reading = %+v, want valid with raw pressure and temperature", reading)
+	}
+	if *reading.RawPressure != 12.3 || *reading.Temperature != 25 || reading.Pressure < 17.299 || reading.Pressure > 17.301 {
+		t.Errorf("pressure=%v raw=%v temperature=%v, want 17.3, 12.3 and 25",
+			reading.Pressure, *reading.RawPressure, *reading.Temperature)
+	}
+}
+
+func TestTemperatureReadErrorClassified(t *testing.T) {
+	// 溫度寄存器未設置，儀表返回非法地址異常
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0, 123)
+	pm := newTestMeter(t, mock, withCompensation)
+
+	reading := pm.ReadPressure()
+	if reading.Valid || reading.ErrorCode != ErrProtocol {
+		t.Fatalf("reading: valid=%v code=%v (%s), want invalid ErrProtocol", reading.Valid, reading.ErrorCode, reading.Error)
+	}
+	if health := pm.CommHealth(); health.Requests != 2 || health.Exceptions != 1 {
+		t.Errorf("comm health requests=%d exceptions=%d, want the temperature exception recorded", health.Requests, health.Exceptions)
+	}
+}
+
+func TestTemperatureReadRetried(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0, 123).SetRegisters(0x0200, 250)
+	transport := &dropTransport{MockTransport: mock, address: 0x0200, drops: 1}
+	pm := newTestMeter(t, transport, withCompensation, func(c *Config) {
+		c.MaxRetries = 1
+		c.RetryDelay = 1
+	})
+
+	reading := pm.ReadPressure()
+	if !reading.Valid || reading.Retries != 1 {
+		t.Fatalf("reading: valid=%v retries=%d (%s), want valid after 1 retry", reading.Valid, reading.Retries, reading.Error)
+	}
+	if health := pm.CommHealth(); health.Timeouts != 1 || health.Successes != 2 {
+		t.Errorf("comm health timeouts=%d successes=%d, want 1 and 2", health.Timeouts, health.Successes)
+	}
+
+	// 重試用完後按超時分類
+	transport.mu.Lock()
+	transport.drops = 2
+	transport.mu.Unlock()
+	if reading := pm.ReadPressure(); reading.Valid || reading.ErrorCode != ErrTimeout {
+		t.Errorf("reading: valid=%v code=%v, want invalid ErrTimeout", reading.Valid, reading.ErrorCode)
+	}
+}
@@ -0,0 +1,414 @@
+// pressure/compliance.go - 防篡改合規日誌（雜湊鏈）
+package pressure
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GenesisHash 雜湊鏈第一條記錄的前一雜湊值
+var GenesisHash = strings.Repeat("0", 64)
+
+// ComplianceEntry 合規日誌中的一行記錄
+//
+// Hash = SHA-256(PrevHash + Record 的原始 JSON 位元組)，
+// 任何記錄被修改、刪除或重排都會使後續雜湊不一致。
+type ComplianceEntry struct {
+	Seq      uint64          `json:"seq"`       // 記錄序號，從 1 開始
+	PrevHash string          `json:"prev_hash"` // 前一條記錄的雜湊
+	Hash     string          `json:"hash"`      // 本條記錄的雜湊
+	Record   json.RawMessage `json:"record"`    // 記錄內容
+}
+
+// ComplianceManifest 合規日誌的雜湊鏈清單，與日誌檔案並存
+type ComplianceManifest struct {
+	File      string    `json:"file"`       // 日誌檔案名
+	Records   uint64    `json:"records"`    // 記錄總數
+	HeadHash  string    `json:"head_hash"`  // 最後一條記錄的雜湊
+	CreatedAt time.Time `json:"created_at"` // 創建時間
+	UpdatedAt time.Time `json:"updated_at"` // 最後更新時間
+}
+
+// ComplianceLog 只追加的防篡改日誌寫入器
+type ComplianceLog struct {
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	seq      uint64
+	prevHash string
+	manifest ComplianceManifest
+	repairs  []string // 打開時做過的修復
+}
+
+// ComplianceOpenOptions 打開既有合規日誌的選項
+type ComplianceOpenOptions struct {
+	// AcceptMissingManifest 清單缺失或損壞時按日誌自身的雜湊鏈重建清單並繼續追加。
+	// 清單丟失前發生的尾部截斷無法再發現，只應在人工核對日誌後使用。
+	AcceptMissingManifest bool
+}
+
+// ManifestPath 返回日誌檔案對應的清單檔案路徑
+func ManifestPath(logPath string) string {
+	return logPath + ".manifest.json"
+}
+
+// OpenComplianceLog 打開（或創建）合規日誌，若檔案已存在則驗證後接續雜湊鏈
+//
+// 每條記錄先同步寫入日誌再更新清單，兩步之間中斷時日誌比清單多一條記錄。此時最後一條記錄仍由雜湊鏈
+// 接在清單的鏈頭之後，打開時接受該記錄並重寫清單；落後超過一條或鏈頭不符仍視為篡改，拒絕追加。
+// 寫入記錄時中斷留下的不完整最後一行（無換行且無法解析）在之前的記錄與清單一致時移到旁邊的
+// .torn 檔案後截斷。已有記錄的日誌缺少清單或清單損壞時拒絕打開，見 OpenComplianceLogWithOptions。
+func OpenComplianceLog(path string) (*ComplianceLog, error) {
+	return OpenComplianceLogWithOptions(path, ComplianceOpenOptions{})
+}
+
+// OpenComplianceLogWithOptions 按選項打開（或創建）合規日誌
+func OpenComplianceLogWithOptions(path string, options ComplianceOpenOptions) (*ComplianceLog, error) {
+	cl := &ComplianceLog{
+		path:     path,
+		prevHash: GenesisHash,
+		manifest: ComplianceManifest{
+			File:      path,
+			HeadHash:  GenesisHash,
+			CreatedAt: time.Now(),
+		},
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		result, err := VerifyComplianceLog(path)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case result.ManifestMissing && !options.AcceptMissingManifest:
+			return nil, fmt.Errorf("既有合規日誌%s，拒絕追加；人工核對日誌後可按日誌重建清單", result.Problem)
+		case !result.Valid && !result.ManifestMissing && !result.ManifestBehind && !result.TornTail:
+			return nil, fmt.Errorf("既有合規日誌驗證失敗，拒絕追加: %s", result.Problem)
+		}
+
+		if result.TornTail {
+			tornPath, err := moveTornTail(path, result.TornOffset)
+			if err != nil {
+				return nil, fmt.Errorf("移出合規日誌不完整的最後一行失敗: %v", err)
+			}
+			cl.repairs = append(cl.repairs, fmt.Sprintf("第 %d 行不完整（寫入時中斷），已移到 %s", result.BrokenLine, tornPath))
+		}
+		if result.ManifestMissing {
+			cl.repairs = append(cl.repairs, fmt.Sprintf("清單缺失或損壞，已按日誌的雜湊鏈重建 (%d 條記錄)", result.Records))
+		}
+		if result.ManifestBehind {
+			cl.repairs = append(cl.repairs, "清單落後最後一條記錄（上次寫入時中斷），已按日誌更新清單")
+		}
+		cl.seq = result.Records
+		cl.prevHash = result.HeadHash
+		if manifest, err := loadComplianceManifest(path); err == nil {
+			cl.manifest.CreatedAt = manifest.CreatedAt
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打開合規日誌失敗: %v", err)
+	}
+	cl.file = file
+
+	// 最後一條記錄完整但換行未寫入時補上換行，避免下一條記錄接在同一行
+	if err := terminateLastLine(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("補全合規日誌最後一行失敗: %v", err)
+	}
+
+	if len(cl.repairs) > 0 {
+		if err := cl.writeManifest(); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("修復合規日誌清單失敗: %v", err)
+		}
+	}
+
+	return cl, nil
+}
+
+// Repairs 返回打開時做過的修復（移出不完整的最後一行、重建或更新清單），沒有修復時為空
+func (cl *ComplianceLog) Repairs() []string {
+	return cl.repairs
+}
+
+// moveTornTail 將 offset 之後的不完整內容寫到旁邊的 .torn 檔案保存，再將日誌截斷到 offset
+func moveTornTail(path string, offset int64) (string, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	fragment, err := io.ReadAll(io.NewSectionReader(file, offset, 1<<62))
+	if err != nil {
+		return "", err
+	}
+	tornPath := fmt.Sprintf("%s.torn-%s", path, time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(tornPath, fragment, 0644); err != nil {
+		return "", err
+	}
+	if err := file.Truncate(offset); err != nil {
+		return "", err
+	}
+	return tornPath, file.Sync()
+}
+
+// terminateLastLine 檔案非空且不以換行結尾時追加換行
+func terminateLastLine(file *os.File) error {
+	info, err := file.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+		return err
+	}
+	if last[0] == '\n' {
+		return nil
+	}
+	if _, err := file.Write([]byte{'\n'}); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// Append 追加一條記錄
+func (cl *ComplianceLog) Append(record interface{}) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("序列化記錄失敗: %v", err)
+	}
+
+	entry := ComplianceEntry{
+		Seq:      cl.seq + 1,
+		PrevHash: cl.prevHash,
+		Hash:     chainHash(cl.prevHash, payload),
+		Record:   payload,
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("序列化記錄失敗: %v", err)
+	}
+	line = append(line, '\n')
+
+	if _, err := cl.file.Write(line); err != nil {
+		return fmt.Errorf("寫入合規日誌失敗: %v", err)
+	}
+	if err := cl.file.Sync(); err != nil {
+		return fmt.Errorf("同步合規日誌失敗: %v", err)
+	}
+
+	cl.seq = entry.Seq
+	cl.prevHash = entry.Hash
+	return cl.writeManifest()
+}
+
+// AppendReading 追加一條壓力讀數
+func (cl *ComplianceLog) AppendReading(reading PressureReading) error {
+	return cl.Append(reading)
+}
+
+// WriteReading 實現 Sink 接口，只記錄讀數本身
+func (cl *ComplianceLog) WriteReading(reading MonitorReading) error {
+	return cl.AppendReading(reading.PressureReading)
+}
+
+// Check 實現 SinkChecker 接口，確認日誌檔案仍可寫入
+func (cl *ComplianceLog) Check() error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.file == nil {
+		return fmt.Errorf("合規日誌已關閉: %s", cl.path)
+	}
+	if _, err := cl.file.Stat(); err != nil {
+		return fmt.Errorf("合規日誌不可用: %v", err)
+	}
+	return nil
+}
+
+// Close 關閉日誌
+func (cl *ComplianceLog) Close() error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	if cl.file == nil {
+		return nil
+	}
+	err := cl.file.Close()
+	cl.file = nil
+	return err
+}
+
+// writeManifest 更新清單檔案，調用者必須持有鎖
+func (cl *ComplianceLog) writeManifest() error {
+	cl.manifest.Records = cl.seq
+	cl.manifest.HeadHash = cl.prevHash
+	cl.manifest.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(cl.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	manifestPath := ManifestPath(cl.path)
+	tmpPath := manifestPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("寫入清單失敗: %v", err)
+	}
+	return os.Rename(tmpPath, manifestPath)
+}
+
+// ComplianceVerifyResult 合規日誌驗證結果
+type ComplianceVerifyResult struct {
+	File         string `json:"file"`          // 日誌檔案
+	Valid        bool   `json:"valid"`         // 是否通過驗證
+	Records      uint64 `json:"records"`       // 已驗證的記錄數
+	HeadHash     string `json:"head_hash"`     // 最後一條有效記錄的雜湊
+	ManifestUsed bool   `json:"manifest_used"` // 是否與清單比對
+	BrokenLine   int    `json:"broken_line"`   // 首個出錯的行號（0 表示無）
+	Problem      string `json:"problem"`       // 問題描述
+
+	// 清單恰好落後最後一條記錄（追加記錄後、更新清單前中斷），OpenComplianceLog 打開時會修復清單
+	ManifestBehind bool `json:"manifest_behind,omitempty"`
+	// 日誌已有記錄但清單缺失或無法解析，無法確認日誌未被截斷
+	ManifestMissing bool `json:"manifest_missing,omitempty"`
+	// 最後一行無換行且無法解析（寫入記錄時中斷），TornOffset 為該行起始的位元組偏移；
+	// 之前的記錄與清單一致時 OpenComplianceLog 打開時會移出該行
+	TornTail   bool  `json:"torn_tail,omitempty"`
+	TornOffset int64 `json:"torn_offset,omitempty"`
+}
+
+// VerifyComplianceLog 驗證合規日誌的雜湊鏈，並與清單比對以發現尾部截斷
+func VerifyComplianceLog(path string) (*ComplianceVerifyResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打開合規日誌失敗: %v", err)
+	}
+	defer file.Close()
+
+	result := &ComplianceVerifyResult{File: path, Valid: true, HeadHash: GenesisHash}
+	fail := func(line int, format string, args ...interface{}) (*ComplianceVerifyResult, error) {
+		result.Valid = false
+		result.BrokenLine = line
+		result.Problem = fmt.Sprintf(format, args...)
+		return result, nil
+	}
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	line := 0
+	var offset int64        // 當前行起始的位元組偏移
+	prevHead := GenesisHash // 最後一條記錄之前的鏈頭
+	for {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("讀取合規日誌失敗: %v", err)
+		}
+		if len(data) == 0 {
+			break
+		}
+		line++
+		start := offset
+		offset += int64(len(data))
+		raw := bytes.TrimSpace(data)
+		if len(raw) == 0 {
+			continue
+		}
+
+		var entry ComplianceEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			if data[len(data)-1] != '\n' {
+				// 沒有換行的最後一行是寫入時中斷留下的殘片，按清單判斷之前的記錄是否完整
+				result.TornTail = true
+				result.TornOffset = start
+				result.BrokenLine = line
+				break
+			}
+			return fail(line, "第 %d 行格式錯誤: %v", line, err)
+		}
+		if entry.Seq != result.Records+1 {
+			return fail(line, "第 %d 行序號不連續: 期望 %d，實際 %d", line, result.Records+1, entry.Seq)
+		}
+		if entry.PrevHash != result.HeadHash {
+			return fail(line, "第 %d 行前一雜湊不匹配", line)
+		}
+		if expected := chainHash(entry.PrevHash, entry.Record); entry.Hash != expected {
+			return fail(line, "第 %d 行雜湊不匹配，記錄可能被修改", line)
+		}
+
+		prevHead = result.HeadHash
+		result.Records = entry.Seq
+		result.HeadHash = entry.Hash
+	}
+	tornLine := result.BrokenLine
+
+	manifest, err := loadComplianceManifest(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist) && result.Records == 0 && !result.TornTail:
+		// 新建的日誌在第一條記錄寫入後才有清單
+		return result, nil
+	case errors.Is(err, fs.ErrNotExist):
+		result.ManifestMissing = true
+		return fail(tornLine, "缺少清單檔案，無法確認日誌未被截斷")
+	case err != nil:
+		result.ManifestMissing = true
+		return fail(tornLine, "清單檔案無法讀取或已損壞 (%v)，無法確認日誌未被截斷", err)
+	}
+
+	result.ManifestUsed = true
+	if !result.TornTail && manifest.Records+1 == result.Records && manifest.HeadHash == prevHead {
+		result.ManifestBehind = true
+		return fail(0, "清單落後日誌一條記錄: 清單記錄 %d 條，日誌記錄 %d 條，最後一條記錄寫入後清單未更新",
+			manifest.Records, result.Records)
+	}
+	if manifest.Records != result.Records || manifest.HeadHash != result.HeadHash {
+		result.TornTail = false
+		result.TornOffset = 0
+		return fail(0, "與清單不一致: 清單記錄 %d 條，日誌記錄 %d 條，日誌可能被截斷或替換",
+			manifest.Records, result.Records)
+	}
+	if result.TornTail {
+		return fail(tornLine, "第 %d 行不完整 (%d 字節，無換行)，寫入記錄時中斷；之前的 %d 條記錄與清單一致",
+			tornLine, offset-result.TornOffset, result.Records)
+	}
+
+	return result, nil
+}
+
+// loadComplianceManifest 讀取清單檔案
+func loadComplianceManifest(logPath string) (*ComplianceManifest, error) {
+	data, err := os.ReadFile(ManifestPath(logPath))
+	if err != nil {
+		return nil, err
+	}
+	var manifest ComplianceManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// chainHash 計算雜湊鏈中的一環
+func chainHash(prevHash string, record []byte) string {
+	h := sha256.New()
+	h.Write([]byte(prevHash))
+	h.Write(record)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,203 @@
+package pressure
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeComplianceLog 寫入 n 條記錄，返回每條記錄寫入後的清單內容
+func writeComplianceLog(t *testing.T, path string, n int) [][]byte {
+	t.Helper()
+	cl, err := OpenComplianceLog(path)
+	if err != nil {
+		t.Fatalf("OpenComplianceLog: %v", err)
+	}
+	defer cl.Close()
+
+	var manifests [][]byte
+	for i := 1; i <= n; i++ {
+		if err := cl.AppendReading(PressureReading{Pressure: float64(i), Valid: true}); err != nil {
+			t.Fatalf("Append %d: %v", i, err)
+		}
+		data, err := os.ReadFile(ManifestPath(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifests = append(manifests, data)
+	}
+	return manifests
+}
+
+func TestComplianceLogRecoversManifestOneRecordBehind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	manifests := writeComplianceLog(t, path, 3)
+
+	// 模擬第 3 條記錄同步寫入後、清單更新前中斷
+	if err := os.WriteFile(ManifestPath(path), manifests[1], 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := VerifyComplianceLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || !result.ManifestBehind || result.Records != 3 {
+		t.Fatalf("verify = %+v, want invalid with manifest one record behind", result)
+	}
+
+	cl, err := OpenComplianceLog(path)
+	if err != nil {
+		t.Fatalf("OpenComplianceLog with manifest one record behind: %v", err)
+	}
+	if len(cl.Repairs()) != 1 {
+		t.Errorf("Repairs() = %q, want the manifest update", cl.Repairs())
+	}
+	if err := cl.AppendReading(PressureReading{Pressure: 4, Valid: true}); err != nil {
+		t.Fatalf("Append after repair: %v", err)
+	}
+	cl.Close()
+
+	result, err = VerifyComplianceLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Records != 4 {
+		t.Errorf("verify after repair = %+v, want valid with 4 records", result)
+	}
+}
+
+func TestComplianceLogRejectsManifestFurtherBehind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	manifests := writeComplianceLog(t, path, 3)
+
+	// 清單落後兩條記錄不可能由一次中斷造成，視為篡改
+	if err := os.WriteFile(ManifestPath(path), manifests[0], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cl, err := OpenComplianceLog(path); err == nil {
+		cl.Close()
+		t.Fatal("OpenComplianceLog with manifest two records behind: want error")
+	}
+	result, err := VerifyComplianceLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || result.ManifestBehind {
+		t.Errorf("verify = %+v, want invalid and not recoverable", result)
+	}
+}
+
+func TestComplianceLogRequiresManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	writeComplianceLog(t, path, 3)
+
+	for name, damage := range map[string]func() error{
+		"missing": func() error { return os.Remove(ManifestPath(path)) },
+		"corrupt": func() error { return os.WriteFile(ManifestPath(path), []byte("{\"records\":"), 0644) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := damage(); err != nil {
+				t.Fatal(err)
+			}
+			result, err := VerifyComplianceLog(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Valid || !result.ManifestMissing || result.Records != 3 {
+				t.Fatalf("verify = %+v, want invalid with manifest missing", result)
+			}
+			if cl, err := OpenComplianceLog(path); err == nil {
+				cl.Close()
+				t.Fatal("OpenComplianceLog without a usable manifest: want error")
+			}
+
+			// 人工核對後按日誌重建清單
+			cl, err := OpenComplianceLogWithOptions(path, ComplianceOpenOptions{AcceptMissingManifest: true})
+			if err != nil {
+				t.Fatalf("OpenComplianceLogWithOptions: %v", err)
+			}
+			if len(cl.Repairs()) != 1 {
+				t.Errorf("Repairs() = %q, want the manifest rebuild", cl.Repairs())
+			}
+			cl.Close()
+			if result, err := VerifyComplianceLog(path); err != nil || !result.Valid || !result.ManifestUsed {
+				t.Errorf("verify after rebuild = %+v, %v; want valid against the manifest", result, err)
+			}
+		})
+	}
+}
+
+func TestComplianceLogRecoversTornTail(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+	writeComplianceLog(t, path, 2)
+
+	// 模擬第 3 條記錄只寫入一半時中斷
+	fragment := []byte(`{"seq":3,"prev_hash":"ab`)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(fragment)
+	file.Close()
+
+	result, err := VerifyComplianceLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || !result.TornTail || result.BrokenLine != 3 || result.Records != 2 {
+		t.Fatalf("verify = %+v, want invalid with a torn third line after 2 records", result)
+	}
+
+	cl, err := OpenComplianceLog(path)
+	if err != nil {
+		t.Fatalf("OpenComplianceLog with torn tail: %v", err)
+	}
+	if len(cl.Repairs()) != 1 {
+		t.Errorf("Repairs() = %q, want the torn line moved aside", cl.Repairs())
+	}
+	if err := cl.AppendReading(PressureReading{Pressure: 3, Valid: true}); err != nil {
+		t.Fatalf("Append after recovery: %v", err)
+	}
+	cl.Close()
+
+	if result, err := VerifyComplianceLog(path); err != nil || !result.Valid || result.Records != 3 {
+		t.Errorf("verify after recovery = %+v, %v; want valid with 3 records", result, err)
+	}
+	torn, _ := filepath.Glob(path + ".torn-*")
+	if len(torn) != 1 {
+		t.Fatalf("torn fragment files = %v, want 1", torn)
+	}
+	if data, _ := os.ReadFile(torn[0]); string(data) != string(fragment) {
+		t.Errorf("torn fragment = %q, want %q", data, fragment)
+	}
+}
+
+func TestComplianceLogTerminatesCompleteLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	manifests := writeComplianceLog(t, path, 2)
+
+	// 最後一條記錄寫完但換行和清單都未寫入
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data[:len(data)-1], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(ManifestPath(path), manifests[0], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cl, err := OpenComplianceLog(path)
+	if err != nil {
+		t.Fatalf("OpenComplianceLog: %v", err)
+	}
+	if err := cl.AppendReading(PressureReading{Pressure: 3, Valid: true}); err != nil {
+		t.Fatal(err)
+	}
+	cl.Close()
+	if result, err := VerifyComplianceLog(path); err != nil || !result.Valid || result.Records != 3 {
+		t.Errorf("verify = %+v, %v; want valid with 3 records", result, err)
+	}
+}
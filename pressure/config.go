@@ -3,9 +3,10 @@ package pressure
 
 import (
 	"encoding/json"
-	"flag"
 	"fmt"
+	"io"
 	"log"
+	"net"
 	"os"
 	"strconv"
 	"strings"
@@ -18,7 +19,8 @@ import (
 type ConfigLoader struct {
 	configFile string
 	useEnv     bool
-	useFlags   bool
+	overrides  func(info *ConfigInfo)
+	logger     *log.Logger
 }
 
 // ConfigSource 配置來源類型
@@ -31,6 +33,44 @@ const (
 	SourceFlags                       // 命令列參數
 )
 
+// String 實現 Stringer 接口
+func (cs ConfigSource) String() string {
+	switch cs {
+	case SourceDefault:
+		return "default"
+	case SourceFile:
+		return "file"
+	case SourceEnv:
+		return "env"
+	case SourceFlags:
+		return "flags"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText 實現 encoding.TextMarshaler 接口
+func (cs ConfigSource) MarshalText() ([]byte, error) {
+	return []byte(cs.String()), nil
+}
+
+// UnmarshalText 實現 encoding.TextUnmarshaler 接口
+func (cs *ConfigSource) UnmarshalText(text []byte) error {
+	switch strings.ToLower(strings.TrimSpace(string(text))) {
+	case "default":
+		*cs = SourceDefault
+	case "file":
+		*cs = SourceFile
+	case "env":
+		*cs = SourceEnv
+	case "flags":
+		*cs = SourceFlags
+	default:
+		return fmt.Errorf("unknown config source: %s", string(text))
+	}
+	return nil
+}
+
 // ConfigInfo 配置信息，包含來源追蹤
 type ConfigInfo struct {
 	Config *Config                 `json:"config"`
@@ -40,8 +80,8 @@ type ConfigInfo struct {
 // NewConfigLoader 創建配置加載器
 func NewConfigLoader() *ConfigLoader {
 	return &ConfigLoader{
-		useEnv:   true,
-		useFlags: true,
+		useEnv: true,
+		logger: log.Default(),
 	}
 }
 
@@ -57,13 +97,22 @@ func (cl *ConfigLoader) SetUseEnv(use bool) *ConfigLoader {
 	return cl
 }
 
-// SetUseFlags 設置是否使用命令列參數
-func (cl *ConfigLoader) SetUseFlags(use bool) *ConfigLoader {
-	cl.useFlags = use
+// SetOverrides 設置最高優先級的配置覆蓋函數，例如由命令列參數提供的值。
+// 覆蓋函數應同時更新 info.Source 以便追蹤來源。
+func (cl *ConfigLoader) SetOverrides(overrides func(info *ConfigInfo)) *ConfigLoader {
+	cl.overrides = overrides
 	return cl
 }
 
-// LoadConfig 加載配置，優先級：命令列 > 環境變數 > 配置檔案 > 默認值
+// SetLogger 設置日誌記錄器
+func (cl *ConfigLoader) SetLogger(logger *log.Logger) *ConfigLoader {
+	if logger != nil {
+		cl.logger = logger
+	}
+	return cl
+}
+
+// LoadConfig 加載配置，優先級：覆蓋（命令列） > 環境變數 > 配置檔案 > 默認值
 func (cl *ConfigLoader) LoadConfig() (*Config, error) {
 	info, err := cl.LoadConfigWithSource()
 	if err != nil {
@@ -84,7 +133,7 @@ func (cl *ConfigLoader) LoadConfigWithSource() (*ConfigInfo, error) {
 
 	// 2. 從配置檔案讀取（如果存在）
 	if err := cl.loadFromFile(info); err != nil {
-		log.Printf("警告：讀取配置檔案失敗: %v", err)
+		cl.logger.Printf("警告：讀取配置檔案失敗: %v", err)
 	}
 
 	// 3. 從環境變數讀取
@@ -92,12 +141,17 @@ func (cl *ConfigLoader) LoadConfigWithSource() (*ConfigInfo, error) {
 		cl.loadFromEnv(info)
 	}
 
-	// 4. 從命令列參數讀取（最高優先級）
-	if cl.useFlags {
-		cl.loadFromFlags(info)
+	// 4. 套用調用方提供的覆蓋（最高優先級）
+	if cl.overrides != nil {
+		cl.overrides(info)
+	}
+
+	// 5. 套用通信時序配置檔（只影響仍為默認值的字段）
+	if err := cl.applyCommProfile(info); err != nil {
+		return nil, fmt.Errorf("配置驗證失敗: %v", err)
 	}
 
-	// 5. 驗證配置
+	// 6. 驗證配置
 	if err := cl.validateConfig(info.Config); err != nil {
 		return nil, fmt.Errorf("配置驗證失敗: %v", err)
 	}
@@ -117,17 +171,24 @@ func (cl *ConfigLoader) setDefaults(info *ConfigInfo) {
 	info.Config.SlaveID = 0x16                 // 默認站點號 22
 	info.Config.ReadInterval = 1 * time.Second // 默認讀取間隔
 	info.Config.DataFormat = DecimalFormat     // 默認十進制格式
-	info.Config.Logger = log.Default()
+	info.Config.ConnectTimeout = DefaultConnectTimeout
+	info.Config.ResponseTimeout = DefaultResponseTimeout
+	info.Config.Logger = cl.logger
 
 	// 記錄來源
 	info.Source["device"] = SourceDefault
 	info.Source["slaveid"] = SourceDefault
 	info.Source["readinterval"] = SourceDefault
 	info.Source["dataformat"] = SourceDefault
+	info.Source["connecttimeout"] = SourceDefault
+	info.Source["responsetimeout"] = SourceDefault
+	info.Source["timestampsource"] = SourceDefault
+	info.Source["minpressure"] = SourceDefault
+	info.Source["maxpressure"] = SourceDefault
 }
 
-// loadFromFile 從配置檔案讀取
-func (cl *ConfigLoader) loadFromFile(info *ConfigInfo) error {
+// configCandidates 按優先級返回候選的配置檔案路徑
+func (cl *ConfigLoader) configCandidates() []string {
 	// 按優先級檢查配置檔案
 	configFiles := []string{
 		"pressure_config.yaml",
@@ -152,16 +213,37 @@ func (cl *ConfigLoader) loadFromFile(info *ConfigInfo) error {
 		configDirs = []string{"./"}
 	}
 
-	var lastErr error
+	var candidates []string
 	for _, dir := range configDirs {
 		for _, filename := range configFiles {
-			fullPath := dir + filename
-			if err := cl.loadConfigFile(fullPath, info); err == nil {
-				log.Printf("已載入配置檔案: %s", fullPath)
-				return nil
-			} else {
-				lastErr = err
-			}
+			candidates = append(candidates, dir+filename)
+		}
+	}
+	return candidates
+}
+
+// FindConfigFile 返回會被載入的配置檔案路徑：指定的配置檔案，或按優先級第一個存在的檔案
+func (cl *ConfigLoader) FindConfigFile() (string, error) {
+	if cl.configFile != "" {
+		return cl.configFile, nil
+	}
+	for _, path := range cl.configCandidates() {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("未找到配置檔案")
+}
+
+// loadFromFile 從配置檔案讀取
+func (cl *ConfigLoader) loadFromFile(info *ConfigInfo) error {
+	var lastErr error
+	for _, fullPath := range cl.configCandidates() {
+		if err := cl.loadConfigFile(fullPath, info); err == nil {
+			cl.logger.Printf("已載入配置檔案: %s", fullPath)
+			return nil
+		} else {
+			lastErr = err
 		}
 	}
 
@@ -169,7 +251,14 @@ func (cl *ConfigLoader) loadFromFile(info *ConfigInfo) error {
 }
 
 // loadConfigFile 載入指定的配置檔案
-func (cl *ConfigLoader) loadConfigFile(filename string, info *ConfigInfo) error {
+func (cl *ConfigLoader) loadConfigFile(filename string, info *ConfigInfo) (err error) {
+	// 畸形的配置內容不應導致程式崩潰
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("解析配置檔案失敗: %v", r)
+		}
+	}()
+
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		return fmt.Errorf("檔案不存在: %s", filename)
 	}
@@ -208,6 +297,14 @@ func (cl *ConfigLoader) mergeConfig(info *ConfigInfo, source *Config, sourceType
 		info.Config.Device = source.Device
 		info.Source["device"] = sourceType
 	}
+	if source.Transport != "" {
+		info.Config.Transport = strings.ToLower(source.Transport)
+		info.Source["transport"] = sourceType
+	}
+	if source.Address != "" {
+		info.Config.Address = source.Address
+		info.Source["address"] = sourceType
+	}
 	if source.SlaveID != 0 {
 		info.Config.SlaveID = source.SlaveID
 		info.Source["slaveid"] = sourceType
@@ -216,6 +313,180 @@ func (cl *ConfigLoader) mergeConfig(info *ConfigInfo, source *Config, sourceType
 		info.Config.ReadInterval = source.ReadInterval
 		info.Source["readinterval"] = sourceType
 	}
+	if source.TimestampSource != TimestampBefore {
+		info.Config.TimestampSource = source.TimestampSource
+		info.Source["timestampsource"] = sourceType
+	}
+	if source.Parity != "" {
+		info.Config.Parity = source.Parity
+		info.Source["parity"] = sourceType
+	}
+	if source.StopBits != 0 {
+		info.Config.StopBits = source.StopBits
+		info.Source["stopbits"] = sourceType
+	}
+	if source.BaudRate != 0 {
+		info.Config.BaudRate = source.BaudRate
+		info.Source["baudrate"] = sourceType
+	}
+	if source.ConnectTimeout != 0 {
+		info.Config.ConnectTimeout = source.ConnectTimeout
+		info.Source["connecttimeout"] = sourceType
+	}
+	if source.ResponseTimeout != 0 {
+		info.Config.ResponseTimeout = source.ResponseTimeout
+		info.Source["responsetimeout"] = sourceType
+	}
+	if source.LatencyBudget != 0 {
+		info.Config.LatencyBudget = source.LatencyBudget
+		info.Source["latencybudget"] = sourceType
+	}
+	if source.LatencyViolations != 0 {
+		info.Config.LatencyViolations = source.LatencyViolations
+		info.Source["latencyviolations"] = sourceType
+	}
+	if source.MaxRetries != 0 {
+		info.Config.MaxRetries = source.MaxRetries
+		info.Source["maxretries"] = sourceType
+	}
+	if source.RetryDelay != 0 {
+		info.Config.RetryDelay = source.RetryDelay
+		info.Source["retrydelay"] = sourceType
+	}
+	if source.OpenRetryWait != 0 {
+		info.Config.OpenRetryWait = source.OpenRetryWait
+		info.Source["openretrywait"] = sourceType
+	}
+	if source.StartDegraded {
+		info.Config.StartDegraded = true
+		info.Source["startdegraded"] = sourceType
+	}
+	if source.RawData {
+		info.Config.RawData = true
+		info.Source["rawdata"] = sourceType
+	}
+	if source.BufferSize != 0 {
+		info.Config.BufferSize = source.BufferSize
+		info.Source["buffersize"] = sourceType
+	}
+	if source.OverflowPolicy != OverflowDropOldest {
+		info.Config.OverflowPolicy = source.OverflowPolicy
+		info.Source["overflowpolicy"] = sourceType
+	}
+	if source.DeviceProfile != "" {
+		info.Config.DeviceProfile = source.DeviceProfile
+		info.Source["deviceprofile"] = sourceType
+	}
+	if source.CustomProfile != nil {
+		info.Config.CustomProfile = source.CustomProfile
+		info.Source["customprofile"] = sourceType
+	}
+	if source.RegisterType != "" {
+		info.Config.RegisterType = source.RegisterType
+		info.Source["registertype"] = sourceType
+	}
+	if source.ByteOrder != "" {
+		info.Config.ByteOrder = source.ByteOrder
+		info.Source["byteorder"] = sourceType
+	}
+	if source.Scale != 0 {
+		info.Config.Scale = source.Scale
+		info.Source["scale"] = sourceType
+	}
+	if source.CommProfile != "" {
+		info.Config.CommProfile = source.CommProfile
+		info.Source["commprofile"] = sourceType
+	}
+	if source.Setpoint != nil {
+		setpoint := *source.Setpoint
+		info.Config.Setpoint = &setpoint
+		info.Source["setpoint"] = sourceType
+	}
+	if source.UnitPreset != "" {
+		info.Config.UnitPreset = source.UnitPreset
+		info.Source["unitpreset"] = sourceType
+	}
+	if source.MinPressure != nil {
+		info.Config.MinPressure = source.MinPressure
+		info.Source["minpressure"] = sourceType
+	}
+	if source.MaxPressure != nil {
+		info.Config.MaxPressure = source.MaxPressure
+		info.Source["maxpressure"] = sourceType
+	}
+	if source.EnableControl {
+		info.Config.EnableControl = true
+		info.Source["enablecontrol"] = sourceType
+	}
+	if source.DampingRegister != 0 {
+		info.Config.DampingRegister = source.DampingRegister
+		info.Source["dampingregister"] = sourceType
+	}
+	if source.Damping != nil {
+		info.Config.Damping = source.Damping
+		info.Source["damping"] = sourceType
+	}
+	if source.TemperatureRegister != 0 {
+		info.Config.TemperatureRegister = source.TemperatureRegister
+		info.Source["temperatureregister"] = sourceType
+	}
+	if source.SlaveIDRegister != 0 {
+		info.Config.SlaveIDRegister = source.SlaveIDRegister
+		info.Source["slaveidregister"] = sourceType
+	}
+	if source.BaudRateRegister != 0 {
+		info.Config.BaudRateRegister = source.BaudRateRegister
+		info.Source["baudrateregister"] = sourceType
+	}
+	if len(source.BaudRateCodes) > 0 {
+		info.Config.BaudRateCodes = source.BaudRateCodes
+		info.Source["baudratecodes"] = sourceType
+	}
+	if source.ZeroRegister != 0 {
+		info.Config.ZeroRegister = source.ZeroRegister
+		info.Source["zeroregister"] = sourceType
+	}
+	if source.ZeroCommand != 0 {
+		info.Config.ZeroCommand = source.ZeroCommand
+		info.Source["zerocommand"] = sourceType
+	}
+	if source.TemperatureScale != 0 {
+		info.Config.TemperatureScale = source.TemperatureScale
+		info.Source["temperaturescale"] = sourceType
+	}
+	if source.Compensation != nil {
+		info.Config.Compensation = source.Compensation
+		info.Source["compensation"] = sourceType
+	}
+	if len(source.Alarms) > 0 {
+		info.Config.Alarms = source.Alarms
+		info.Source["alarms"] = sourceType
+	}
+	if len(source.Bands) > 0 {
+		info.Config.Bands = source.Bands
+		info.Source["bands"] = sourceType
+	}
+	if source.Baseline != nil {
+		info.Config.Baseline = source.Baseline
+		info.Source["baseline"] = sourceType
+	}
+	if source.Ambient != nil {
+		ambient := *source.Ambient
+		info.Config.Ambient = &ambient
+		info.Source["ambient"] = sourceType
+	}
+	if source.StatsFile != "" {
+		info.Config.StatsFile = source.StatsFile
+		info.Source["statsfile"] = sourceType
+	}
+	if source.Branding != nil {
+		info.Config.Branding = source.Branding
+		info.Source["branding"] = sourceType
+	}
+	if len(source.Hooks) > 0 {
+		info.Config.Hooks = source.Hooks
+		info.Source["hooks"] = sourceType
+	}
 	// DataFormat 可以是 0，所以需要特殊處理
 	info.Config.DataFormat = source.DataFormat
 	info.Source["dataformat"] = sourceType
@@ -229,13 +500,23 @@ func (cl *ConfigLoader) loadFromEnv(info *ConfigInfo) {
 		info.Source["device"] = SourceEnv
 	}
 
+	// 傳輸方式和 Modbus TCP 網關地址
+	if transport := os.Getenv("PRESSURE_TRANSPORT"); transport != "" {
+		info.Config.Transport = strings.ToLower(strings.TrimSpace(transport))
+		info.Source["transport"] = SourceEnv
+	}
+	if address := os.Getenv("PRESSURE_ADDRESS"); address != "" {
+		info.Config.Address = strings.TrimSpace(address)
+		info.Source["address"] = SourceEnv
+	}
+
 	// 站點號
 	if slaveIDStr := os.Getenv("PRESSURE_SLAVE_ID"); slaveIDStr != "" {
 		if slaveID, err := parseSlaveID(slaveIDStr); err == nil {
 			info.Config.SlaveID = slaveID
 			info.Source["slaveid"] = SourceEnv
 		} else {
-			log.Printf("警告：環境變數 PRESSURE_SLAVE_ID 格式錯誤: %v", err)
+			cl.logger.Printf("警告：環境變數 PRESSURE_SLAVE_ID 格式錯誤: %v", err)
 		}
 	}
 
@@ -245,7 +526,7 @@ func (cl *ConfigLoader) loadFromEnv(info *ConfigInfo) {
 			info.Config.ReadInterval = interval
 			info.Source["readinterval"] = SourceEnv
 		} else {
-			log.Printf("警告：環境變數 PRESSURE_READ_INTERVAL 格式錯誤: %v", err)
+			cl.logger.Printf("警告：環境變數 PRESSURE_READ_INTERVAL 格式錯誤: %v", err)
 		}
 	}
 
@@ -255,81 +536,535 @@ func (cl *ConfigLoader) loadFromEnv(info *ConfigInfo) {
 			info.Config.DataFormat = format
 			info.Source["dataformat"] = SourceEnv
 		} else {
-			log.Printf("警告：環境變數 PRESSURE_DATA_FORMAT 格式錯誤: %v", err)
+			cl.logger.Printf("警告：環境變數 PRESSURE_DATA_FORMAT 格式錯誤: %v", err)
 		}
 	}
 
-	log.Println("已載入環境變數配置")
-}
+	// 時間戳取值時刻
+	if tsStr := os.Getenv("PRESSURE_TIMESTAMP_SOURCE"); tsStr != "" {
+		var ts TimestampSource
+		if err := ts.UnmarshalText([]byte(tsStr)); err == nil {
+			info.Config.TimestampSource = ts
+			info.Source["timestampsource"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_TIMESTAMP_SOURCE 格式錯誤: %v", err)
+		}
+	}
 
-// loadFromFlags 從命令列參數讀取
-func (cl *ConfigLoader) loadFromFlags(info *ConfigInfo) {
-	// 只有在 flag 還沒有被解析時才定義參數
-	if !flag.Parsed() {
-		device := flag.String("device", info.Config.Device, "RS485 設備路徑")
-		slaveID := flag.Uint("slave-id", uint(info.Config.SlaveID), "Modbus 站點號 (1-247)")
-		interval := flag.Duration("interval", info.Config.ReadInterval, "讀取間隔時間")
-		format := flag.String("format", "decimal", "數據格式 (decimal/float)")
-		configFile := flag.String("config", "", "配置檔案路徑")
+	// 校驗位
+	if parity := os.Getenv("PRESSURE_PARITY"); parity != "" {
+		info.Config.Parity = strings.ToUpper(strings.TrimSpace(parity))
+		info.Source["parity"] = SourceEnv
+	}
 
-		flag.Parse()
+	// 波特率
+	if baudStr := os.Getenv("PRESSURE_BAUD_RATE"); baudStr != "" {
+		if baudRate, err := strconv.Atoi(strings.TrimSpace(baudStr)); err == nil {
+			info.Config.BaudRate = baudRate
+			info.Source["baudrate"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_BAUD_RATE 格式錯誤: %v", err)
+		}
+	}
 
-		// 更新配置
-		if *device != info.Config.Device {
-			info.Config.Device = *device
-			info.Source["device"] = SourceFlags
+	// 停止位
+	if stopStr := os.Getenv("PRESSURE_STOP_BITS"); stopStr != "" {
+		if stopBits, err := strconv.Atoi(strings.TrimSpace(stopStr)); err == nil {
+			info.Config.StopBits = stopBits
+			info.Source["stopbits"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_STOP_BITS 格式錯誤: %v", err)
 		}
-		if byte(*slaveID) != info.Config.SlaveID {
-			info.Config.SlaveID = byte(*slaveID)
-			info.Source["slaveid"] = SourceFlags
+	}
+
+	// 有效讀數範圍
+	if minStr := os.Getenv("PRESSURE_MIN_PRESSURE"); minStr != "" {
+		if value, err := strconv.ParseFloat(strings.TrimSpace(minStr), 64); err == nil {
+			info.Config.MinPressure = &value
+			info.Source["minpressure"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_MIN_PRESSURE 格式錯誤: %v", err)
 		}
-		if *interval != info.Config.ReadInterval {
-			info.Config.ReadInterval = *interval
-			info.Source["readinterval"] = SourceFlags
+	}
+	if maxStr := os.Getenv("PRESSURE_MAX_PRESSURE"); maxStr != "" {
+		if value, err := strconv.ParseFloat(strings.TrimSpace(maxStr), 64); err == nil {
+			info.Config.MaxPressure = &value
+			info.Source["maxpressure"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_MAX_PRESSURE 格式錯誤: %v", err)
 		}
+	}
 
-		// 處理數據格式
-		if parsedFormat, err := parseDataFormat(*format); err == nil {
-			if parsedFormat != info.Config.DataFormat {
-				info.Config.DataFormat = parsedFormat
-				info.Source["dataformat"] = SourceFlags
-			}
+	// 阻尼寄存器和啟動時寫入的阻尼值
+	if registerStr := os.Getenv("PRESSURE_DAMPING_REGISTER"); registerStr != "" {
+		if register, err := strconv.ParseUint(strings.TrimSpace(registerStr), 0, 16); err == nil {
+			info.Config.DampingRegister = uint16(register)
+			info.Source["dampingregister"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_DAMPING_REGISTER 格式錯誤: %v", err)
+		}
+	}
+	if dampingStr := os.Getenv("PRESSURE_DAMPING"); dampingStr != "" {
+		if damping, err := strconv.ParseUint(strings.TrimSpace(dampingStr), 0, 16); err == nil {
+			value := uint16(damping)
+			info.Config.Damping = &value
+			info.Source["damping"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_DAMPING 格式錯誤: %v", err)
+		}
+	}
+
+	// 站點號寄存器
+	if registerStr := os.Getenv("PRESSURE_SLAVE_ID_REGISTER"); registerStr != "" {
+		if register, err := strconv.ParseUint(strings.TrimSpace(registerStr), 0, 16); err == nil {
+			info.Config.SlaveIDRegister = uint16(register)
+			info.Source["slaveidregister"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_SLAVE_ID_REGISTER 格式錯誤: %v", err)
+		}
+	}
+
+	// 波特率寄存器和序號表
+	if registerStr := os.Getenv("PRESSURE_BAUD_RATE_REGISTER"); registerStr != "" {
+		if register, err := strconv.ParseUint(strings.TrimSpace(registerStr), 0, 16); err == nil {
+			info.Config.BaudRateRegister = uint16(register)
+			info.Source["baudrateregister"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_BAUD_RATE_REGISTER 格式錯誤: %v", err)
+		}
+	}
+	if codesStr := os.Getenv("PRESSURE_BAUD_RATE_CODES"); codesStr != "" {
+		if codes, err := ParseBaudRateCodes(codesStr); err == nil {
+			info.Config.BaudRateCodes = codes
+			info.Source["baudratecodes"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_BAUD_RATE_CODES 格式錯誤: %v", err)
+		}
+	}
+
+	// 零點校準寄存器和命令值
+	if registerStr := os.Getenv("PRESSURE_ZERO_REGISTER"); registerStr != "" {
+		if register, err := strconv.ParseUint(strings.TrimSpace(registerStr), 0, 16); err == nil {
+			info.Config.ZeroRegister = uint16(register)
+			info.Source["zeroregister"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_ZERO_REGISTER 格式錯誤: %v", err)
+		}
+	}
+	if commandStr := os.Getenv("PRESSURE_ZERO_COMMAND"); commandStr != "" {
+		if command, err := strconv.ParseUint(strings.TrimSpace(commandStr), 0, 16); err == nil {
+			info.Config.ZeroCommand = uint16(command)
+			info.Source["zerocommand"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_ZERO_COMMAND 格式錯誤: %v", err)
+		}
+	}
+
+	// 溫度寄存器和換算係數（補償係數只能在配置檔案中設置）
+	if registerStr := os.Getenv("PRESSURE_TEMPERATURE_REGISTER"); registerStr != "" {
+		if register, err := strconv.ParseUint(strings.TrimSpace(registerStr), 0, 16); err == nil {
+			info.Config.TemperatureRegister = uint16(register)
+			info.Source["temperatureregister"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_TEMPERATURE_REGISTER 格式錯誤: %v", err)
+		}
+	}
+	if scaleStr := os.Getenv("PRESSURE_TEMPERATURE_SCALE"); scaleStr != "" {
+		if scale, err := strconv.ParseFloat(strings.TrimSpace(scaleStr), 64); err == nil {
+			info.Config.TemperatureScale = scale
+			info.Source["temperaturescale"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_TEMPERATURE_SCALE 格式錯誤: %v", err)
+		}
+	}
+
+	// 連接超時
+	if timeoutStr := os.Getenv("PRESSURE_CONNECT_TIMEOUT"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			info.Config.ConnectTimeout = timeout
+			info.Source["connecttimeout"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_CONNECT_TIMEOUT 格式錯誤: %v", err)
+		}
+	}
+
+	// 響應超時
+	if timeoutStr := os.Getenv("PRESSURE_RESPONSE_TIMEOUT"); timeoutStr != "" {
+		if timeout, err := time.ParseDuration(timeoutStr); err == nil {
+			info.Config.ResponseTimeout = timeout
+			info.Source["responsetimeout"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_RESPONSE_TIMEOUT 格式錯誤: %v", err)
+		}
+	}
+
+	// 延遲預算
+	if budgetStr := os.Getenv("PRESSURE_LATENCY_BUDGET"); budgetStr != "" {
+		if budget, err := time.ParseDuration(budgetStr); err == nil {
+			info.Config.LatencyBudget = budget
+			info.Source["latencybudget"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_LATENCY_BUDGET 格式錯誤: %v", err)
+		}
+	}
+	if violationsStr := os.Getenv("PRESSURE_LATENCY_VIOLATIONS"); violationsStr != "" {
+		if violations, err := strconv.Atoi(strings.TrimSpace(violationsStr)); err == nil {
+			info.Config.LatencyViolations = violations
+			info.Source["latencyviolations"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_LATENCY_VIOLATIONS 格式錯誤: %v", err)
+		}
+	}
+
+	// 讀取重試
+	if retriesStr := os.Getenv("PRESSURE_MAX_RETRIES"); retriesStr != "" {
+		if retries, err := strconv.Atoi(strings.TrimSpace(retriesStr)); err == nil {
+			info.Config.MaxRetries = retries
+			info.Source["maxretries"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_MAX_RETRIES 格式錯誤: %v", err)
+		}
+	}
+	if delayStr := os.Getenv("PRESSURE_RETRY_DELAY"); delayStr != "" {
+		if delay, err := time.ParseDuration(delayStr); err == nil {
+			info.Config.RetryDelay = delay
+			info.Source["retrydelay"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_RETRY_DELAY 格式錯誤: %v", err)
+		}
+	}
+
+	// 基線學習：只設置保存檔案，其他參數在配置檔案中調整
+	if file := os.Getenv("PRESSURE_BASELINE_FILE"); file != "" {
+		if info.Config.Baseline == nil {
+			info.Config.Baseline = &BaselineConfig{}
+		}
+		info.Config.Baseline.File = file
+		info.Source["baseline"] = SourceEnv
+	}
+
+	// 大氣壓來源：檔案路徑、exec:命令或 http(s) 接口，刷新間隔在配置檔案中調整
+	if source := os.Getenv("PRESSURE_AMBIENT"); source != "" {
+		ambient := ParseAmbientSource(source)
+		if info.Config.Ambient != nil {
+			ambient.Field, ambient.Unit = info.Config.Ambient.Field, info.Config.Ambient.Unit
+			ambient.Interval, ambient.MaxAge = info.Config.Ambient.Interval, info.Config.Ambient.MaxAge
+		}
+		if field := os.Getenv("PRESSURE_AMBIENT_FIELD"); field != "" {
+			ambient.Field = field
+		}
+		if unit := os.Getenv("PRESSURE_AMBIENT_UNIT"); unit != "" {
+			ambient.Unit = unit
+		}
+		info.Config.Ambient = &ambient
+		info.Source["ambient"] = SourceEnv
+	}
+
+	// 統計持久化
+	if file := os.Getenv("PRESSURE_STATS_FILE"); file != "" {
+		info.Config.StatsFile = file
+		info.Source["statsfile"] = SourceEnv
+	}
+
+	// 品牌設置：產品名稱、版本和啟動橫幅
+	productName := os.Getenv("PRESSURE_PRODUCT_NAME")
+	productVersion := os.Getenv("PRESSURE_PRODUCT_VERSION")
+	banner := os.Getenv("PRESSURE_BANNER")
+	if productName != "" || productVersion != "" || banner != "" {
+		if info.Config.Branding == nil {
+			info.Config.Branding = &Branding{}
+		}
+		if productName != "" {
+			info.Config.Branding.ProductName = productName
+		}
+		if productVersion != "" {
+			info.Config.Branding.ProductVersion = productVersion
+		}
+		if banner != "" {
+			info.Config.Branding.Banner = banner
 		}
+		info.Source["branding"] = SourceEnv
+	}
+
+	// 啟動時打開連接的重試和降級模式
+	if waitStr := os.Getenv("PRESSURE_OPEN_RETRY_WAIT"); waitStr != "" {
+		if wait, err := time.ParseDuration(waitStr); err == nil {
+			info.Config.OpenRetryWait = wait
+			info.Source["openretrywait"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_OPEN_RETRY_WAIT 格式錯誤: %v", err)
+		}
+	}
+	if controlStr := os.Getenv("PRESSURE_ENABLE_CONTROL"); controlStr != "" {
+		if control, err := strconv.ParseBool(strings.TrimSpace(controlStr)); err == nil {
+			info.Config.EnableControl = control
+			info.Source["enablecontrol"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_ENABLE_CONTROL 格式錯誤: %v", err)
+		}
+	}
+	if degradedStr := os.Getenv("PRESSURE_START_DEGRADED"); degradedStr != "" {
+		if degraded, err := strconv.ParseBool(strings.TrimSpace(degradedStr)); err == nil {
+			info.Config.StartDegraded = degraded
+			info.Source["startdegraded"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_START_DEGRADED 格式錯誤: %v", err)
+		}
+	}
+	if rawStr := os.Getenv("PRESSURE_RAW_DATA"); rawStr != "" {
+		if raw, err := strconv.ParseBool(strings.TrimSpace(rawStr)); err == nil {
+			info.Config.RawData = raw
+			info.Source["rawdata"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_RAW_DATA 格式錯誤: %v", err)
+		}
+	}
 
-		// 設置配置檔案路徑
-		if *configFile != "" {
-			cl.configFile = *configFile
+	// 讀數緩衝區
+	if sizeStr := os.Getenv("PRESSURE_BUFFER_SIZE"); sizeStr != "" {
+		if size, err := strconv.Atoi(strings.TrimSpace(sizeStr)); err == nil {
+			info.Config.BufferSize = size
+			info.Source["buffersize"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_BUFFER_SIZE 格式錯誤: %v", err)
+		}
+	}
+	if policyStr := os.Getenv("PRESSURE_OVERFLOW_POLICY"); policyStr != "" {
+		var policy OverflowPolicy
+		if err := policy.UnmarshalText([]byte(policyStr)); err == nil {
+			info.Config.OverflowPolicy = policy
+			info.Source["overflowpolicy"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_OVERFLOW_POLICY 格式錯誤: %v", err)
+		}
+	}
+
+	// 通信時序配置檔
+	if profile := os.Getenv("PRESSURE_DEVICE_PROFILE"); profile != "" {
+		info.Config.DeviceProfile = profile
+		info.Source["deviceprofile"] = SourceEnv
+	}
+	if registerType := os.Getenv("PRESSURE_REGISTER_TYPE"); registerType != "" {
+		info.Config.RegisterType = registerType
+		info.Source["registertype"] = SourceEnv
+	}
+	if byteOrder := os.Getenv("PRESSURE_BYTE_ORDER"); byteOrder != "" {
+		info.Config.ByteOrder = byteOrder
+		info.Source["byteorder"] = SourceEnv
+	}
+	if scaleStr := os.Getenv("PRESSURE_SCALE"); scaleStr != "" {
+		if scale, err := ParseScale(scaleStr); err == nil {
+			info.Config.Scale = scale
+			info.Source["scale"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_SCALE 格式錯誤: %v", err)
+		}
+	}
+	if profile := os.Getenv("PRESSURE_COMM_PROFILE"); profile != "" {
+		info.Config.CommProfile = profile
+		info.Source["commprofile"] = SourceEnv
+	}
+	if setpointStr := os.Getenv("PRESSURE_SETPOINT"); setpointStr != "" {
+		if target, err := strconv.ParseFloat(strings.TrimSpace(setpointStr), 64); err == nil {
+			setpoint := SetpointConfig{Target: target, Tolerance: DefaultSetpointTolerance}
+			if info.Config.Setpoint != nil {
+				setpoint.Tolerance = info.Config.Setpoint.Tolerance
+			}
+			info.Config.Setpoint = &setpoint
+			info.Source["setpoint"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_SETPOINT 格式錯誤: %v", err)
+		}
+	}
+	if tolStr := os.Getenv("PRESSURE_SETPOINT_TOLERANCE"); tolStr != "" && info.Config.Setpoint != nil {
+		if tolerance, err := strconv.ParseFloat(strings.TrimSpace(tolStr), 64); err == nil {
+			info.Config.Setpoint.Tolerance = tolerance
+			info.Source["setpoint"] = SourceEnv
+		} else {
+			cl.logger.Printf("警告：環境變數 PRESSURE_SETPOINT_TOLERANCE 格式錯誤: %v", err)
 		}
 	}
+	if preset := os.Getenv("PRESSURE_UNIT_PRESET"); preset != "" {
+		info.Config.UnitPreset = preset
+		info.Source["unitpreset"] = SourceEnv
+	}
 
-	log.Println("已載入命令列參數配置")
+	cl.logger.Println("已載入環境變數配置")
+}
+
+// applyCommProfile 套用通信時序配置檔，已明確設置的超時和重試不受影響
+func (cl *ConfigLoader) applyCommProfile(info *ConfigInfo) error {
+	if info.Config.CommProfile == "" {
+		return nil
+	}
+
+	profile, err := GetCommProfile(info.Config.CommProfile)
+	if err != nil {
+		return err
+	}
+	source := info.Source["commprofile"]
+
+	if info.Source["connecttimeout"] == SourceDefault {
+		info.Config.ConnectTimeout = profile.ConnectTimeout
+		info.Source["connecttimeout"] = source
+	}
+	if info.Source["responsetimeout"] == SourceDefault {
+		info.Config.ResponseTimeout = profile.ResponseTimeout
+		info.Source["responsetimeout"] = source
+	}
+	if info.Source["maxretries"] == SourceDefault {
+		info.Config.MaxRetries = profile.MaxRetries
+		info.Source["maxretries"] = source
+	}
+	if info.Source["retrydelay"] == SourceDefault {
+		info.Config.RetryDelay = profile.RetryDelay
+		info.Source["retrydelay"] = source
+	}
+	if info.Config.ReadInterval < profile.MinReadInterval {
+		cl.logger.Printf("警告：讀取間隔 %v 低於配置檔 %s 的最小間隔，已調整為 %v",
+			info.Config.ReadInterval, profile.Name, profile.MinReadInterval)
+		info.Config.ReadInterval = profile.MinReadInterval
+		info.Source["readinterval"] = source
+	}
+	return nil
 }
 
 // validateConfig 驗證配置
 func (cl *ConfigLoader) validateConfig(config *Config) error {
-	if config.Device == "" {
+	if config.Transport != "" && !IsValidTransport(config.Transport) {
+		return fmt.Errorf("傳輸方式必須為 rtu、tcp 或 rtuovertcp，當前: %s", config.Transport)
+	}
+
+	if config.IsTCP() {
+		if config.Address == "" {
+			return fmt.Errorf("Modbus TCP 傳輸需要指定網關地址 (host:port)")
+		}
+		if _, _, err := net.SplitHostPort(NormalizeTCPAddress(config.Address)); err != nil {
+			return fmt.Errorf("無效的網關地址 %s: %v", config.Address, err)
+		}
+	} else if config.Device == "" {
 		return fmt.Errorf("設備路徑不能為空")
+	} else if config.IsRTUOverTCP() {
+		if err := checkSerialServerAddress(config.SerialServerAddress()); err != nil {
+			return err
+		}
 	}
 
-	if config.SlaveID < 1 || config.SlaveID > 247 {
-		return fmt.Errorf("站點號必須在 1-247 之間，當前: %d", config.SlaveID)
+	if err := CheckPollingSlaveID(config.SlaveID); err != nil {
+		return err
 	}
 
 	if config.ReadInterval < 100*time.Millisecond {
 		return fmt.Errorf("讀取間隔不能小於 100ms，當前: %v", config.ReadInterval)
 	}
 
+	if config.Parity != "" && !IsValidParity(config.Parity) {
+		return fmt.Errorf("校驗位必須為 N、E 或 O，當前: %s", config.Parity)
+	}
+
+	if config.StopBits != 0 && !IsValidStopBits(config.StopBits) {
+		return fmt.Errorf("停止位必須為 1 或 2，當前: %d", config.StopBits)
+	}
+
+	if config.BaudRate != 0 && !IsValidBaudRate(config.BaudRate) {
+		return fmt.Errorf("波特率必須為 %v 之一，當前: %d", GetSupportedBaudRates(), config.BaudRate)
+	}
+
+	if err := CheckBaudRateCodes(config.BaudRateCodes); err != nil {
+		return err
+	}
+
+	if low, high := config.PressureLimits(); low >= high {
+		return fmt.Errorf("有效讀數下限必須小於上限，當前: [%.2f, %.2f]", low, high)
+	}
+
+	if config.Damping != nil && config.DampingRegister == 0 {
+		return fmt.Errorf("設置了阻尼值 (damping) 但未指定阻尼寄存器 (dampingregister)")
+	}
+
+	if config.Damping != nil && !config.EnableControl {
+		return fmt.Errorf("設置了阻尼值 (damping) 需要在啟動時寫入儀表，請同時啟用控制操作 (enablecontrol)")
+	}
+
+	if config.Compensation != nil && config.TemperatureRegister == 0 {
+		return fmt.Errorf("設置了溫度補償 (compensation) 但未指定溫度寄存器 (temperatureregister)")
+	}
+
+	if err := ValidateBands(config.Bands); err != nil {
+		return err
+	}
+
+	if _, err := ResolveDeviceProfile(*config); err != nil {
+		return err
+	}
+
+	if _, err := GetUnitPreset(config.UnitPreset); err != nil {
+		return err
+	}
+
+	if config.Setpoint != nil {
+		if err := config.Setpoint.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if config.BufferSize < 0 {
+		return fmt.Errorf("讀數緩衝區大小不能為負數: %d", config.BufferSize)
+	}
+	if config.OverflowPolicy.String() == "unknown" {
+		return fmt.Errorf("未知的溢出策略: %d", config.OverflowPolicy)
+	}
+
+	if config.Baseline != nil {
+		if err := config.Baseline.Validate(); err != nil {
+			return err
+		}
+	}
+	if config.Ambient != nil {
+		if err := config.Ambient.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if config.ConnectTimeout < 0 {
+		return fmt.Errorf("連接超時不能為負數，當前: %v", config.ConnectTimeout)
+	}
+
+	if config.ResponseTimeout < 0 {
+		return fmt.Errorf("響應超時不能為負數，當前: %v", config.ResponseTimeout)
+	}
+
+	if config.LatencyBudget < 0 {
+		return fmt.Errorf("延遲預算不能為負數，當前: %v", config.LatencyBudget)
+	}
+
+	if config.LatencyViolations < 0 {
+		return fmt.Errorf("延遲告警的連續次數不能為負數，當前: %d", config.LatencyViolations)
+	}
+
+	if config.OpenRetryWait < 0 {
+		return fmt.Errorf("打開連接的重試等待時間不能為負數，當前: %v", config.OpenRetryWait)
+	}
+
+	if config.MaxRetries < 0 {
+		return fmt.Errorf("讀取重試次數不能為負數，當前: %d", config.MaxRetries)
+	}
+
+	if config.RetryDelay < 0 {
+		return fmt.Errorf("讀取重試等待時間不能為負數，當前: %v", config.RetryDelay)
+	}
+
 	// 檢查設備路徑是否存在（僅在類 Unix 系統上）
-	if !isWindows() {
+	if !isWindows() && !config.IsTCP() && !config.IsRTUOverTCP() && config.ModbusTransport == nil {
 		if _, err := os.Stat(config.Device); os.IsNotExist(err) {
-			log.Printf("警告：設備路徑可能不存在: %s", config.Device)
+			cl.logger.Printf("警告：設備路徑可能不存在: %s", config.Device)
 		}
 	}
 
 	return nil
 }
 
-// SaveConfig 保存配置到檔案
+// SaveConfig 保存配置到檔案，覆蓋已有檔案前先保存備份（見 BackupConfigFile）
 func (cl *ConfigLoader) SaveConfig(config *Config, filename string) error {
 	var data []byte
 	var err error
@@ -348,31 +1083,248 @@ func (cl *ConfigLoader) SaveConfig(config *Config, filename string) error {
 		return fmt.Errorf("序列化配置失敗: %v", err)
 	}
 
-	return os.WriteFile(filename, data, 0644)
+	if backup, err := BackupConfigFile(filename); err != nil {
+		return err
+	} else if backup != "" {
+		cl.logger.Printf("已備份配置檔案: %s", backup)
+	}
+	return writeFileAtomic(filename, data)
 }
 
-// PrintConfig 打印當前配置
-func (cl *ConfigLoader) PrintConfig(config *Config) {
-	fmt.Println("=== 壓差儀配置 ===")
-	fmt.Printf("設備路徑: %s\n", config.Device)
-	fmt.Printf("站點號: %d (0x%02X)\n", config.SlaveID, config.SlaveID)
-	fmt.Printf("讀取間隔: %v\n", config.ReadInterval)
-	fmt.Printf("數據格式: %s\n", formatToString(config.DataFormat))
-	fmt.Println("==================")
+// PrintConfig 將當前配置寫入 w
+func (cl *ConfigLoader) PrintConfig(w io.Writer, config *Config) {
+	fmt.Fprintln(w, "=== 壓差儀配置 ===")
+	if config.IsTCP() {
+		fmt.Fprintln(w, "傳輸方式: Modbus TCP")
+		fmt.Fprintf(w, "網關地址: %s\n", config.Endpoint())
+	} else if config.IsRTUOverTCP() {
+		fmt.Fprintln(w, "傳輸方式: 串口服務器 (RTU over TCP)")
+		fmt.Fprintf(w, "串口服務器: %s\n", config.SerialServerAddress())
+	} else {
+		fmt.Fprintf(w, "設備路徑: %s\n", config.Device)
+	}
+	fmt.Fprintf(w, "站點號: %d (0x%02X)\n", config.SlaveID, config.SlaveID)
+	fmt.Fprintf(w, "讀取間隔: %v\n", config.ReadInterval)
+	fmt.Fprintf(w, "數據格式: %s\n", formatToString(config.DataFormat))
+	if config.Parity != "" {
+		fmt.Fprintf(w, "校驗位: %s\n", config.Parity)
+	}
+	if config.StopBits != 0 {
+		fmt.Fprintf(w, "停止位: %d\n", config.StopBits)
+	}
+	if config.BaudRate != 0 {
+		fmt.Fprintf(w, "波特率: %d\n", config.BaudRate)
+	}
+	fmt.Fprintf(w, "時間戳: %s\n", timestampSourceToString(config.TimestampSource))
+	low, high := config.PressureLimits()
+	fmt.Fprintf(w, "有效範圍: [%.2f, %.2f] Pa\n", low, high)
+	fmt.Fprintf(w, "連接超時: %v\n", config.ConnectTimeout)
+	fmt.Fprintf(w, "響應超時: %v\n", config.ResponseTimeout)
+	if config.LatencyBudget > 0 {
+		fmt.Fprintf(w, "延遲預算: %v\n", config.LatencyBudget)
+	}
+	if config.MaxRetries > 0 {
+		fmt.Fprintf(w, "讀取重試: %d 次\n", config.MaxRetries)
+	}
+	if config.OpenRetryWait > 0 {
+		fmt.Fprintf(w, "打開重試: 最長 %v\n", config.OpenRetryWait)
+	}
+	if config.StartDegraded {
+		fmt.Fprintln(w, "降級啟動: 是")
+	}
+	if config.EnableControl {
+		fmt.Fprintln(w, "控制操作: 已啟用")
+	}
+	if config.RawData {
+		fmt.Fprintln(w, "原始數據: 保留")
+	}
+	if config.BufferSize != 0 || config.OverflowPolicy != OverflowDropOldest {
+		fmt.Fprintf(w, "讀數緩衝: %d 個，已滿時 %s\n", bufferSizeOrDefault(config.BufferSize), config.OverflowPolicy)
+	}
+	if config.DeviceProfile != "" || config.CustomProfile != nil || config.RegisterType != "" || config.ByteOrder != "" || config.Scale != 0 {
+		if profile, err := ResolveDeviceProfile(*config); err == nil {
+			fmt.Fprintf(w, "設備配置檔: %s\n", profile)
+		}
+	}
+	if config.CommProfile != "" {
+		fmt.Fprintf(w, "通信配置檔: %s\n", config.CommProfile)
+	}
+	if config.Setpoint != nil {
+		fmt.Fprintf(w, "設定值: %s\n", config.Setpoint)
+	}
+	if preset, err := GetUnitPreset(config.UnitPreset); err == nil && config.UnitPreset != "" {
+		fmt.Fprintf(w, "單位預設: %s\n", preset)
+	}
+	if config.DampingRegister != 0 {
+		fmt.Fprintf(w, "阻尼寄存器: 0x%04X\n", config.DampingRegister)
+	}
+	if config.SlaveIDRegister != 0 {
+		fmt.Fprintf(w, "站點號寄存器: 0x%04X\n", config.SlaveIDRegister)
+	}
+	if config.BaudRateRegister != 0 {
+		fmt.Fprintf(w, "波特率寄存器: 0x%04X%s\n", config.BaudRateRegister, baudRateCodesSuffix(config.BaudRateCodes))
+	}
+	if config.ZeroRegister != 0 {
+		fmt.Fprintf(w, "零點校準寄存器: 0x%04X\n", config.ZeroRegister)
+	}
+	if config.Damping != nil {
+		fmt.Fprintf(w, "阻尼值: %d\n", *config.Damping)
+	}
+	if config.TemperatureRegister != 0 {
+		fmt.Fprintf(w, "溫度寄存器: 0x%04X\n", config.TemperatureRegister)
+	}
+	if config.Compensation != nil {
+		fmt.Fprintf(w, "溫度補償: %s\n", config.Compensation)
+	}
+	printAlarmsAndHooks(w, config)
+	fmt.Fprintln(w, "==================")
+}
+
+// PrintConfigWithSource 將配置及其來源寫入 w
+func (cl *ConfigLoader) PrintConfigWithSource(w io.Writer, info *ConfigInfo) {
+	fmt.Fprintln(w, "=== 壓差儀配置（含來源）===")
+	if info.Config.IsTCP() {
+		fmt.Fprintf(w, "傳輸方式: Modbus TCP [%s]\n", sourceToString(info.Source["transport"]))
+		fmt.Fprintf(w, "網關地址: %s [%s]\n", info.Config.Endpoint(), sourceToString(info.Source["address"]))
+	} else if info.Config.IsRTUOverTCP() {
+		fmt.Fprintf(w, "傳輸方式: 串口服務器 (RTU over TCP) [%s]\n", sourceToString(info.Source["transport"]))
+		fmt.Fprintf(w, "串口服務器: %s [%s]\n", info.Config.SerialServerAddress(), sourceToString(info.Source["device"]))
+	} else {
+		fmt.Fprintf(w, "設備路徑: %s [%s]\n", info.Config.Device, sourceToString(info.Source["device"]))
+	}
+	fmt.Fprintf(w, "站點號: %d (0x%02X) [%s]\n", info.Config.SlaveID, info.Config.SlaveID, sourceToString(info.Source["slaveid"]))
+	fmt.Fprintf(w, "讀取間隔: %v [%s]\n", info.Config.ReadInterval, sourceToString(info.Source["readinterval"]))
+	fmt.Fprintf(w, "數據格式: %s [%s]\n", formatToString(info.Config.DataFormat), sourceToString(info.Source["dataformat"]))
+	if info.Config.Parity != "" {
+		fmt.Fprintf(w, "校驗位: %s [%s]\n", info.Config.Parity, sourceToString(info.Source["parity"]))
+	}
+	if info.Config.StopBits != 0 {
+		fmt.Fprintf(w, "停止位: %d [%s]\n", info.Config.StopBits, sourceToString(info.Source["stopbits"]))
+	}
+	if info.Config.BaudRate != 0 {
+		fmt.Fprintf(w, "波特率: %d [%s]\n", info.Config.BaudRate, sourceToString(info.Source["baudrate"]))
+	}
+	fmt.Fprintf(w, "時間戳: %s [%s]\n", timestampSourceToString(info.Config.TimestampSource), sourceToString(info.Source["timestampsource"]))
+	low, high := info.Config.PressureLimits()
+	fmt.Fprintf(w, "有效範圍: [%.2f, %.2f] Pa [%s/%s]\n", low, high,
+		sourceToString(info.Source["minpressure"]), sourceToString(info.Source["maxpressure"]))
+	fmt.Fprintf(w, "連接超時: %v [%s]\n", info.Config.ConnectTimeout, sourceToString(info.Source["connecttimeout"]))
+	fmt.Fprintf(w, "響應超時: %v [%s]\n", info.Config.ResponseTimeout, sourceToString(info.Source["responsetimeout"]))
+	if info.Config.LatencyBudget > 0 {
+		fmt.Fprintf(w, "延遲預算: %v [%s]\n", info.Config.LatencyBudget, sourceToString(info.Source["latencybudget"]))
+	}
+	if info.Config.MaxRetries > 0 {
+		fmt.Fprintf(w, "讀取重試: %d 次 [%s]\n", info.Config.MaxRetries, sourceToString(info.Source["maxretries"]))
+	}
+	if info.Config.OpenRetryWait > 0 {
+		fmt.Fprintf(w, "打開重試: 最長 %v [%s]\n", info.Config.OpenRetryWait, sourceToString(info.Source["openretrywait"]))
+	}
+	if info.Config.StartDegraded {
+		fmt.Fprintf(w, "降級啟動: 是 [%s]\n", sourceToString(info.Source["startdegraded"]))
+	}
+	if info.Config.EnableControl {
+		fmt.Fprintf(w, "控制操作: 已啟用 [%s]\n", sourceToString(info.Source["enablecontrol"]))
+	}
+	if info.Config.RawData {
+		fmt.Fprintf(w, "原始數據: 保留 [%s]\n", sourceToString(info.Source["rawdata"]))
+	}
+	if info.Config.BufferSize != 0 || info.Config.OverflowPolicy != OverflowDropOldest {
+		fmt.Fprintf(w, "讀數緩衝: %d 個 [%s]，已滿時 %s [%s]\n",
+			bufferSizeOrDefault(info.Config.BufferSize), sourceToString(info.Source["buffersize"]),
+			info.Config.OverflowPolicy, sourceToString(info.Source["overflowpolicy"]))
+	}
+	if info.Config.DeviceProfile != "" || info.Config.CustomProfile != nil || info.Config.RegisterType != "" ||
+		info.Config.ByteOrder != "" || info.Config.Scale != 0 {
+		key := "deviceprofile"
+		if info.Config.CustomProfile != nil {
+			key = "customprofile"
+		}
+		if info.Config.RegisterType != "" {
+			key = "registertype"
+		}
+		if info.Config.ByteOrder != "" {
+			key = "byteorder"
+		}
+		if info.Config.Scale != 0 {
+			key = "scale"
+		}
+		if profile, err := ResolveDeviceProfile(*info.Config); err == nil {
+			fmt.Fprintf(w, "設備配置檔: %s [%s]\n", profile, sourceToString(info.Source[key]))
+		}
+	}
+	if info.Config.CommProfile != "" {
+		fmt.Fprintf(w, "通信配置檔: %s [%s]\n", info.Config.CommProfile, sourceToString(info.Source["commprofile"]))
+	}
+	if info.Config.Setpoint != nil {
+		fmt.Fprintf(w, "設定值: %s [%s]\n", info.Config.Setpoint, sourceToString(info.Source["setpoint"]))
+	}
+	if preset, err := GetUnitPreset(info.Config.UnitPreset); err == nil && info.Config.UnitPreset != "" {
+		fmt.Fprintf(w, "單位預設: %s [%s]\n", preset, sourceToString(info.Source["unitpreset"]))
+	}
+	if info.Config.DampingRegister != 0 {
+		fmt.Fprintf(w, "阻尼寄存器: 0x%04X [%s]\n", info.Config.DampingRegister, sourceToString(info.Source["dampingregister"]))
+	}
+	if info.Config.SlaveIDRegister != 0 {
+		fmt.Fprintf(w, "站點號寄存器: 0x%04X [%s]\n", info.Config.SlaveIDRegister, sourceToString(info.Source["slaveidregister"]))
+	}
+	if info.Config.BaudRateRegister != 0 {
+		fmt.Fprintf(w, "波特率寄存器: 0x%04X%s [%s]\n", info.Config.BaudRateRegister,
+			baudRateCodesSuffix(info.Config.BaudRateCodes), sourceToString(info.Source["baudrateregister"]))
+	}
+	if info.Config.ZeroRegister != 0 {
+		fmt.Fprintf(w, "零點校準寄存器: 0x%04X [%s]\n", info.Config.ZeroRegister, sourceToString(info.Source["zeroregister"]))
+	}
+	if info.Config.Damping != nil {
+		fmt.Fprintf(w, "阻尼值: %d [%s]\n", *info.Config.Damping, sourceToString(info.Source["damping"]))
+	}
+	if info.Config.TemperatureRegister != 0 {
+		fmt.Fprintf(w, "溫度寄存器: 0x%04X [%s]\n", info.Config.TemperatureRegister, sourceToString(info.Source["temperatureregister"]))
+	}
+	if info.Config.Compensation != nil {
+		fmt.Fprintf(w, "溫度補償: %s [%s]\n", info.Config.Compensation, sourceToString(info.Source["compensation"]))
+	}
+	if info.Config.StatsFile != "" {
+		fmt.Fprintf(w, "統計保存檔案: %s [%s]\n", info.Config.StatsFile, sourceToString(info.Source["statsfile"]))
+	}
+	printAlarmsAndHooks(w, info.Config)
+	fmt.Fprintln(w, "========================")
 }
 
-// PrintConfigWithSource 打印配置及其來源
-func (cl *ConfigLoader) PrintConfigWithSource(info *ConfigInfo) {
-	fmt.Println("=== 壓差儀配置（含來源）===")
-	fmt.Printf("設備路徑: %s [%s]\n", info.Config.Device, sourceToString(info.Source["device"]))
-	fmt.Printf("站點號: %d (0x%02X) [%s]\n", info.Config.SlaveID, info.Config.SlaveID, sourceToString(info.Source["slaveid"]))
-	fmt.Printf("讀取間隔: %v [%s]\n", info.Config.ReadInterval, sourceToString(info.Source["readinterval"]))
-	fmt.Printf("數據格式: %s [%s]\n", formatToString(info.Config.DataFormat), sourceToString(info.Source["dataformat"]))
-	fmt.Println("========================")
+// printAlarmsAndHooks 寫入告警規則、壓力區間和外部腳本摘要
+func printAlarmsAndHooks(w io.Writer, config *Config) {
+	for _, rule := range config.Alarms {
+		fmt.Fprintf(w, "告警規則: %s", rule.Name)
+		if rule.Low != nil {
+			fmt.Fprintf(w, " 下限=%.2f Pa", *rule.Low)
+		}
+		if rule.High != nil {
+			fmt.Fprintf(w, " 上限=%.2f Pa", *rule.High)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, band := range config.Bands {
+		fmt.Fprintf(w, "壓力區間: %s\n", band)
+	}
+	if config.Baseline != nil {
+		fmt.Fprintf(w, "基線學習: %s\n", config.Baseline)
+	}
+	if config.Ambient != nil {
+		fmt.Fprintf(w, "大氣壓來源: %s\n", config.Ambient)
+	}
+	if config.Branding != nil {
+		fmt.Fprintf(w, "品牌設置: %s\n", config.Branding)
+	}
+	for _, hook := range config.Hooks {
+		events := "全部事件"
+		if len(hook.Events) > 0 {
+			events = strings.Join(hook.Events, ",")
+		}
+		fmt.Fprintf(w, "外部腳本: %s %s [%s]\n", hook.Name, hook.Command, events)
+	}
 }
 
-// GenerateConfigExample 生成配置檔案示例
-func GenerateConfigExample() {
+// GenerateConfigExample 將配置檔案示例寫入 w
+func GenerateConfigExample(w io.Writer) {
 	config := &Config{
 		Device:       "/dev/ttyUSB0",
 		SlaveID:      22,
@@ -380,36 +1332,68 @@ func GenerateConfigExample() {
 		DataFormat:   DecimalFormat,
 	}
 
-	fmt.Println("=== YAML 配置檔案示例 (pressure_config.yaml) ===")
+	fmt.Fprintln(w, "=== YAML 配置檔案示例 (pressure_config.yaml) ===")
 	yamlData, _ := yaml.Marshal(config)
-	fmt.Println(string(yamlData))
+	fmt.Fprintln(w, string(yamlData))
 
-	fmt.Println("=== JSON 配置檔案示例 (pressure_config.json) ===")
+	fmt.Fprintln(w, "=== JSON 配置檔案示例 (pressure_config.json) ===")
 	jsonData, _ := json.MarshalIndent(config, "", "  ")
-	fmt.Println(string(jsonData))
+	fmt.Fprintln(w, string(jsonData))
 }
 
-// PrintEnvExample 打印環境變數示例
-func PrintEnvExample() {
-	fmt.Println("=== 環境變數設置示例 ===")
-	fmt.Println("export PRESSURE_DEVICE=/dev/ttyUSB0")
-	fmt.Println("export PRESSURE_SLAVE_ID=22")
-	fmt.Println("export PRESSURE_READ_INTERVAL=1s")
-	fmt.Println("export PRESSURE_DATA_FORMAT=decimal")
-	fmt.Println("========================")
+// PrintEnvExample 將環境變數示例寫入 w
+func PrintEnvExample(w io.Writer) {
+	fmt.Fprintln(w, "=== 環境變數設置示例 ===")
+	fmt.Fprintln(w, "export PRESSURE_DEVICE=/dev/ttyUSB0")
+	fmt.Fprintln(w, "export PRESSURE_TRANSPORT=rtu")
+	fmt.Fprintln(w, "# export PRESSURE_ADDRESS=192.168.1.50:502")
+	fmt.Fprintln(w, "export PRESSURE_SLAVE_ID=22")
+	fmt.Fprintln(w, "export PRESSURE_READ_INTERVAL=1s")
+	fmt.Fprintln(w, "export PRESSURE_DATA_FORMAT=decimal")
+	fmt.Fprintln(w, "# export PRESSURE_DEVICE_PROFILE=pushida-decimal")
+	fmt.Fprintln(w, "# export PRESSURE_REGISTER_TYPE=input")
+	fmt.Fprintln(w, "# export PRESSURE_BYTE_ORDER=ABCD")
+	fmt.Fprintln(w, "# export PRESSURE_SCALE=0.01")
+	fmt.Fprintln(w, "export PRESSURE_PARITY=N")
+	fmt.Fprintln(w, "export PRESSURE_BAUD_RATE=9600")
+	fmt.Fprintln(w, "export PRESSURE_TIMESTAMP_SOURCE=before")
+	fmt.Fprintln(w, "export PRESSURE_MIN_PRESSURE=-50000")
+	fmt.Fprintln(w, "export PRESSURE_MAX_PRESSURE=50000")
+	fmt.Fprintln(w, "export PRESSURE_CONNECT_TIMEOUT=5s")
+	fmt.Fprintln(w, "export PRESSURE_RESPONSE_TIMEOUT=5s")
+	fmt.Fprintln(w, "export PRESSURE_LATENCY_BUDGET=200ms")
+	fmt.Fprintln(w, "# export PRESSURE_BASELINE_FILE=pressure_baseline.json")
+	fmt.Fprintln(w, "# export PRESSURE_AMBIENT=/sys/bus/iio/devices/iio:device0/in_pressure_input")
+	fmt.Fprintln(w, "# export PRESSURE_AMBIENT_UNIT=kPa")
+	fmt.Fprintln(w, "# export PRESSURE_STATS_FILE=pressure_stats.json")
+	fmt.Fprintln(w, "export PRESSURE_MAX_RETRIES=2")
+	fmt.Fprintln(w, "export PRESSURE_RETRY_DELAY=100ms")
+	fmt.Fprintln(w, "export PRESSURE_OPEN_RETRY_WAIT=30s")
+	fmt.Fprintln(w, "# export PRESSURE_PRODUCT_NAME=\"ACME 潔淨室監控\"")
+	fmt.Fprintln(w, "# export PRESSURE_PRODUCT_VERSION=2.3.0")
+	fmt.Fprintln(w, "# export PRESSURE_BANNER=none")
+	fmt.Fprintln(w, "# export PRESSURE_UNIT_PRESET=cleanroom")
+	fmt.Fprintln(w, "# export PRESSURE_SETPOINT=-10")
+	fmt.Fprintln(w, "# export PRESSURE_SETPOINT_TOLERANCE=2.5")
+	fmt.Fprintln(w, "export PRESSURE_START_DEGRADED=false")
+	fmt.Fprintln(w, "export PRESSURE_ENABLE_CONTROL=false")
+	fmt.Fprintln(w, "export PRESSURE_RAW_DATA=false")
+	fmt.Fprintln(w, "# export PRESSURE_BUFFER_SIZE=100")
+	fmt.Fprintln(w, "# export PRESSURE_OVERFLOW_POLICY=drop-oldest")
+	fmt.Fprintln(w, "========================")
 }
 
-// PrintDockerExample 打印 Docker 環境變數示例
-func PrintDockerExample() {
-	fmt.Println("=== Docker 環境變數示例 ===")
-	fmt.Println("docker run -d \\")
-	fmt.Println("  --device=/dev/ttyUSB0 \\")
-	fmt.Println("  -e PRESSURE_DEVICE=/dev/ttyUSB0 \\")
-	fmt.Println("  -e PRESSURE_SLAVE_ID=22 \\")
-	fmt.Println("  -e PRESSURE_READ_INTERVAL=2s \\")
-	fmt.Println("  -e PRESSURE_DATA_FORMAT=decimal \\")
-	fmt.Println("  pressure-meter-macArm64:latest")
-	fmt.Println("==========================")
+// PrintDockerExample 將 Docker 環境變數示例寫入 w
+func PrintDockerExample(w io.Writer) {
+	fmt.Fprintln(w, "=== Docker 環境變數示例 ===")
+	fmt.Fprintln(w, "docker run -d \\")
+	fmt.Fprintln(w, "  --device=/dev/ttyUSB0 \\")
+	fmt.Fprintln(w, "  -e PRESSURE_DEVICE=/dev/ttyUSB0 \\")
+	fmt.Fprintln(w, "  -e PRESSURE_SLAVE_ID=22 \\")
+	fmt.Fprintln(w, "  -e PRESSURE_READ_INTERVAL=2s \\")
+	fmt.Fprintln(w, "  -e PRESSURE_DATA_FORMAT=decimal \\")
+	fmt.Fprintln(w, "  pressure-meter-macArm64:latest")
+	fmt.Fprintln(w, "==========================")
 }
 
 // 輔助函數
@@ -442,6 +1426,10 @@ func parseDataFormat(s string) (DataFormatType, error) {
 		return DecimalFormat, nil
 	case "float", "floating", "1":
 		return FloatFormat, nil
+	case "int16", "2":
+		return Int16Format, nil
+	case "uint16", "3":
+		return Uint16Format, nil
 	default:
 		return DecimalFormat, fmt.Errorf("無效的數據格式: %s", s)
 	}
@@ -454,6 +1442,32 @@ func formatToString(format DataFormatType) string {
 		return "十進制"
 	case FloatFormat:
 		return "浮點數"
+	case Int16Format:
+		return "16 位有符號整數"
+	case Uint16Format:
+		return "16 位無符號整數"
+	default:
+		return "未知"
+	}
+}
+
+// baudRateCodesSuffix 返回波特率寄存器序號表的說明，寄存器直接保存波特率時為空
+func baudRateCodesSuffix(codes []int) string {
+	if len(codes) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (序號: %v)", codes)
+}
+
+// timestampSourceToString 將時間戳取值時刻轉為字符串
+func timestampSourceToString(ts TimestampSource) string {
+	switch ts {
+	case TimestampBefore:
+		return "請求前"
+	case TimestampAfter:
+		return "響應後"
+	case TimestampMidpoint:
+		return "請求與響應中點"
 	default:
 		return "未知"
 	}
@@ -479,3 +1493,11 @@ func sourceToString(source ConfigSource) string {
 func isWindows() bool {
 	return strings.Contains(strings.ToLower(os.Getenv("OS")), "windows")
 }
+
+// bufferSizeOrDefault 返回讀數緩衝區大小，0 為默認值
+func bufferSizeOrDefault(size int) int {
+	if size == 0 {
+		return DefaultReadingBufferSize
+	}
+	return size
+}
@@ -0,0 +1,201 @@
+// pressure/connreport.go - 連接測試報告：連接耗時、請求延遲、原始數據和按各種格式解析的結果
+package pressure
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// ConnectionReport TestConnection 的結構化結果，現場排查時不必再開啟調試日誌逐行分析
+type ConnectionReport struct {
+	Endpoint  string `json:"endpoint"`            // 串口、網關或串口服務器地址
+	Transport string `json:"transport"`           // 傳輸方式
+	SlaveID   byte   `json:"slave_id"`            // 站點號
+	BaudRate  int    `json:"baud_rate,omitempty"` // 串口波特率，僅 RTU
+	Parity    string `json:"parity,omitempty"`    // 串口校驗位，僅 RTU
+	StopBits  int    `json:"stop_bits,omitempty"` // 串口停止位，僅 RTU
+	Profile   string `json:"profile"`             // 設備配置檔
+	Register  uint16 `json:"register"`            // 壓力寄存器起始地址
+	Function  byte   `json:"function"`            // 讀取功能碼
+	Count     uint16 `json:"count"`               // 讀取的寄存器數量
+
+	OpenTime time.Duration `json:"open_time"`         // 最近一次打開連接的耗時
+	Latency  time.Duration `json:"latency"`           // 壓力寄存器請求到響應的耗時
+	Retries  int           `json:"retries,omitempty"` // 讀取的重試次數
+
+	RawData          string                 `json:"raw_data,omitempty"`          // 原始寄存器數據（十六進制）
+	DetectedFormat   string                 `json:"detected_format,omitempty"`   // 按原始數據檢測的普時達數據格式
+	FormatConfidence float64                `json:"format_confidence,omitempty"` // 格式檢測置信度
+	Interpretations  []FormatInterpretation `json:"interpretations,omitempty"`   // 原始數據按各種格式解析的結果
+
+	Pressure  float64   `json:"pressure"`             // 按配置解析的壓力值 (Pa)
+	Valid     bool      `json:"valid"`                // 讀數是否有效
+	Error     string    `json:"error,omitempty"`      // 讀取失敗原因
+	ErrorCode ErrorCode `json:"error_code,omitempty"` // 讀取失敗的錯誤代碼
+}
+
+// FormatInterpretation 原始數據按一種編碼和字節序解析的壓力值
+type FormatInterpretation struct {
+	Format    string  `json:"format"`    // 編碼、字節序和換算係數，如 "float32 CDAB"
+	Value     float64 `json:"value"`     // 解析出的壓力值 (Pa)
+	Plausible bool    `json:"plausible"` // 是否為合理壓力範圍內的有限數值
+	Current   bool    `json:"current"`   // 是否為當前配置使用的格式
+}
+
+// TestConnection 讀取一次壓力並返回連接報告，讀數無效時同時返回錯誤
+//
+// 讀取時臨時保留原始數據，報告中附帶原始數據按各種整數和浮點格式解析的結果，
+// 用於判斷數據格式、字節序或換算係數是否配置錯誤。
+func (pm *PressureMeter) TestConnection() (*ConnectionReport, error) {
+	if !pm.RawDataEnabled() {
+		pm.SetRawData(true)
+		defer pm.SetRawData(false)
+	}
+	reading := pm.ReadPressure()
+
+	pm.connMu.Lock()
+	openTime := pm.openTime
+	pm.connMu.Unlock()
+
+	report := &ConnectionReport{
+		Endpoint:  pm.endpoint,
+		Transport: pm.transport,
+		SlaveID:   pm.slaveID,
+		Profile:   pm.profile.Name,
+		Register:  pm.profile.Register,
+		Function:  pm.profile.Function,
+		Count:     pm.profile.Count,
+		OpenTime:  openTime,
+		Latency:   reading.Duration,
+		Retries:   reading.Retries,
+		Pressure:  reading.Pressure,
+		Valid:     reading.Valid,
+		Error:     reading.Error,
+		ErrorCode: reading.ErrorCode,
+	}
+	if pm.transport == TransportRTU {
+		report.BaudRate = pm.baudRate
+		report.Parity = pm.parity
+		report.StopBits = pm.stopBits
+		if report.Parity == "" {
+			report.Parity = DefaultParity
+		}
+	}
+
+	if len(reading.RawData) > 0 {
+		report.RawData = fmt.Sprintf("% X", reading.RawData)
+		report.Interpretations = pm.interpret(reading.RawData)
+
+		scanner := NewScanner(pm.logger).SetVerbose(false)
+		if pm.profile.Encoding == EncodingFloat32 {
+			scanner.SetByteOrder(pm.profile.ByteOrder)
+		} else {
+			scanner.SetScale(pm.profile.Scale)
+		}
+		detected, confidence := scanner.detectDataFormat(reading.RawData)
+		report.DetectedFormat = detected.String()
+		report.FormatConfidence = confidence
+	}
+
+	if !reading.Valid {
+		return report, fmt.Errorf("連接測試失敗: %s", reading.Error)
+	}
+	pm.logger.Printf("連接測試成功，當前壓力: %.2f Pa", reading.Pressure)
+	return report, nil
+}
+
+// interpret 將原始數據按 16 位整數、32 位整數和四種字節序的浮點數解析
+//
+// 整數使用當前配置的換算係數（浮點配置時為普時達的 0.1），浮點數使用當前配置的係數（整數配置時為 1）。
+func (pm *PressureMeter) interpret(raw []byte) []FormatInterpretation {
+	intScale, floatScale := DefaultDecimalScale, 1.0
+	if pm.profile.Encoding == EncodingFloat32 {
+		floatScale = pm.profile.Scale
+	} else {
+		intScale = pm.profile.Scale
+	}
+
+	candidates := []DeviceProfile{
+		{Encoding: EncodingInt16, ByteOrder: ByteOrderABCD, Scale: intScale},
+		{Encoding: EncodingUint16, ByteOrder: ByteOrderABCD, Scale: intScale},
+	}
+	if len(raw) >= 4 {
+		candidates = append(candidates,
+			DeviceProfile{Encoding: EncodingInt32, ByteOrder: ByteOrderABCD, Scale: intScale},
+			DeviceProfile{Encoding: EncodingInt32, ByteOrder: ByteOrderCDAB, Scale: intScale},
+		)
+		for _, order := range []string{ByteOrderABCD, ByteOrderCDAB, ByteOrderBADC, ByteOrderDCBA} {
+			candidates = append(candidates, DeviceProfile{Encoding: EncodingFloat32, ByteOrder: order, Scale: floatScale})
+		}
+	}
+
+	var results []FormatInterpretation
+	for _, candidate := range candidates {
+		value, err := candidate.Decode(raw)
+		if err != nil {
+			continue
+		}
+		format := candidate.Encoding
+		if candidate.valueSize() == 4 {
+			format += " " + candidate.ByteOrder
+		}
+		if candidate.Scale != 1 {
+			format += fmt.Sprintf(" ×%g", candidate.Scale)
+		}
+		results = append(results, FormatInterpretation{
+			Format: format,
+			Value:  value,
+			Plausible: !math.IsNaN(value) && !math.IsInf(value, 0) &&
+				value >= MinReasonablePressure && value <= MaxReasonablePressure,
+			Current: candidate.Encoding == pm.profile.Encoding &&
+				(candidate.valueSize() == 2 || candidate.ByteOrder == pm.profile.ByteOrder),
+		})
+	}
+	return results
+}
+
+// Print 輸出連接報告
+func (r *ConnectionReport) Print(w io.Writer) {
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+	fmt.Fprintf(w, "🔌 連接測試: %s 站點 %d (%s)\n", r.Endpoint, r.SlaveID, r.Transport)
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+
+	if r.BaudRate != 0 {
+		fmt.Fprintf(w, "串口參數:   %d %s\n", r.BaudRate, charFormat(r.Parity, r.StopBits))
+	}
+	fmt.Fprintf(w, "設備配置檔: %s (寄存器 0x%04X × %d，功能碼 %d)\n", r.Profile, r.Register, r.Count, r.Function)
+	fmt.Fprintf(w, "打開耗時:   %v\n", r.OpenTime.Round(time.Millisecond))
+	if r.Retries > 0 {
+		fmt.Fprintf(w, "請求延遲:   %v (重試 %d 次)\n", r.Latency.Round(time.Millisecond), r.Retries)
+	} else {
+		fmt.Fprintf(w, "請求延遲:   %v\n", r.Latency.Round(time.Millisecond))
+	}
+
+	if r.RawData != "" {
+		fmt.Fprintf(w, "原始數據:   %s\n", r.RawData)
+		fmt.Fprintf(w, "格式檢測:   %s (置信度 %.2f)\n", r.DetectedFormat, r.FormatConfidence)
+		fmt.Fprintln(w, "\n📐 按各種格式解析:")
+		for _, interp := range r.Interpretations {
+			mark := "  "
+			if interp.Current {
+				mark = "➤ "
+			}
+			note := ""
+			if !interp.Plausible {
+				note = " (不合理)"
+			}
+			fmt.Fprintf(w, "   %s%-18s %14.2f Pa%s\n", mark, interp.Format, interp.Value, note)
+		}
+	}
+
+	fmt.Fprintln(w)
+	if r.Valid {
+		fmt.Fprintf(w, "✅ 讀取成功: %.2f Pa\n", r.Pressure)
+	} else {
+		fmt.Fprintf(w, "❌ 讀取失敗: %s\n", r.Error)
+	}
+	fmt.Fprintln(w, strings.Repeat("=", 52))
+}
@@ -0,0 +1,192 @@
+// pressure/control.go - 控制操作開關：寫入儀表和停止監測需要明確啟用，只監測的部署不會向總線寫入
+package pressure
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrControlDisabled 未啟用控制操作 (enablecontrol) 時寫入儀表返回的錯誤
+var ErrControlDisabled = errors.New("未啟用控制操作，拒絕寫入儀表 (設置 enablecontrol 或使用 --enable-control)")
+
+// ControlPathPrefix HTTP 接口控制操作的路徑前綴，與只讀接口分開，便於反向代理單獨限制訪問
+const ControlPathPrefix = "/api/v1/control/"
+
+// ControlTokenEnv 控制接口令牌的環境變量，避免令牌出現在進程參數中
+const ControlTokenEnv = "PRESSURE_CONTROL_TOKEN"
+
+// 控制操作
+const (
+	ControlStop          = "stop"           // 停止監測，守護程序正常退出
+	ControlCalibrateZero = "calibrate-zero" // 零點校準
+	ControlWriteRegister = "write-register" // 寫入單個保持寄存器並讀回確認
+)
+
+// ControlActions 返回 HTTP 接口支援的控制操作
+func ControlActions() []string {
+	return []string{ControlStop, ControlCalibrateZero, ControlWriteRegister}
+}
+
+// ControlEnabled 是否允許寫入儀表
+func (pm *PressureMeter) ControlEnabled() bool {
+	return pm.control
+}
+
+// checkControl 未啟用控制操作時返回 ErrControlDisabled
+func (pm *PressureMeter) checkControl() error {
+	if !pm.control {
+		return ErrControlDisabled
+	}
+	return nil
+}
+
+// Cancel 結束監測循環，不關閉輸出目標和設備連接
+//
+// 監測結束後 Done 關閉，由調用 Start 的一方繼續調用 Stop 完成清理，與 ctx 取消相同。
+func (m *Monitor) Cancel() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+}
+
+// controlRequest 控制操作的請求體，只有 write-register 需要
+type controlRequest struct {
+	Register *uint16 `json:"register"`
+	Value    *uint16 `json:"value"`
+}
+
+// checkControlScope 啟用控制操作、未設置令牌且控制接口監聽非本機地址時返回錯誤
+func (as *APIServer) checkControlScope() error {
+	as.mu.RLock()
+	monitor := as.monitor
+	as.mu.RUnlock()
+
+	if monitor == nil || !monitor.Meter().ControlEnabled() || as.controlToken != "" {
+		return nil
+	}
+	addr := as.addr
+	if as.controlAddr != "" {
+		addr = as.controlAddr
+	}
+	if !isLocalAPIAddr(addr) {
+		return fmt.Errorf("拒絕在非本機地址 %s 上無令牌開放控制操作: 設置控制令牌 (--control-token 或 %s)，或用 --control-addr 監聽 127.0.0.1 或 unix 套接字",
+			addr, ControlTokenEnv)
+	}
+	return nil
+}
+
+// isLocalAPIAddr 地址是否只能從本機訪問 (unix 套接字或回環地址)
+func isLocalAPIAddr(addr string) bool {
+	network, address := splitAPIAddr(addr)
+	if network == "unix" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// authorizeControl 設置了令牌時檢查請求的 Authorization: Bearer 頭
+func (as *APIServer) authorizeControl(r *http.Request) bool {
+	if as.controlToken == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(as.controlToken)) == 1
+}
+
+// handleSharedControl 只讀接口上的控制路徑，控制接口有獨立監聽地址時返回 404
+func (as *APIServer) handleSharedControl(w http.ResponseWriter, r *http.Request) {
+	if as.controlAddr != "" {
+		http.NotFound(w, r)
+		return
+	}
+	as.handleControl(w, r)
+}
+
+// handleControl 處理 POST /api/v1/control/{stop,calibrate-zero,write-register}
+//
+// 設置了令牌時先驗證令牌，未通過返回 401；未啟用控制操作時一律返回 403，
+// 只讀部署即使暴露了 HTTP 接口也無法寫入儀表或停止監測。
+func (as *APIServer) handleControl(w http.ResponseWriter, r *http.Request) {
+	if !as.authorizeControl(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="pressure-meter control"`)
+		http.Error(w, "unauthorized: control token required", http.StatusUnauthorized)
+		return
+	}
+
+	as.mu.RLock()
+	monitor := as.monitor
+	as.mu.RUnlock()
+
+	if monitor == nil || !monitor.Meter().ControlEnabled() {
+		http.Error(w, "control disabled: start with --enable-control or set enablecontrol", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	action := strings.TrimPrefix(r.URL.Path, ControlPathPrefix)
+	body := map[string]interface{}{
+		"schema_version": SchemaVersion,
+		"action":         action,
+		"timestamp":      time.Now(),
+	}
+	meter := monitor.Meter()
+	switch action {
+	case ControlStop:
+		as.logger.Printf("🛑 HTTP 接口 (%s) 請求停止監測", r.RemoteAddr)
+		monitor.Cancel()
+	case ControlCalibrateZero:
+		if !meter.SupportsZeroCalibration() {
+			http.Error(w, "zero calibration not configured (zeroregister)", http.StatusConflict)
+			return
+		}
+		as.logger.Printf("🎯 HTTP 接口 (%s) 請求零點校準", r.RemoteAddr)
+		if err := meter.ZeroCalibrate(); err != nil {
+			as.writeControlError(w, body, http.StatusBadGateway, err)
+			return
+		}
+		body["settle_seconds"] = ZeroSettleTime.Seconds()
+	case ControlWriteRegister:
+		var req controlRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Register == nil || req.Value == nil {
+			http.Error(w, `invalid request: expected {"register": N, "value": N}`, http.StatusBadRequest)
+			return
+		}
+		as.logger.Printf("✏️  HTTP 接口 (%s) 請求寫入寄存器 0x%04X = %d", r.RemoteAddr, *req.Register, *req.Value)
+		body["register"], body["value"] = *req.Register, *req.Value
+		if err := meter.WriteRegister(*req.Register, *req.Value); err != nil {
+			as.writeControlError(w, body, http.StatusBadGateway, err)
+			return
+		}
+	default:
+		http.Error(w, fmt.Sprintf("unknown control action: %s (%s)", action, strings.Join(ControlActions(), "/")), http.StatusNotFound)
+		return
+	}
+	body["ok"] = true
+	writeJSON(w, http.StatusOK, body)
+}
+
+// writeControlError 輸出控制操作失敗的原因
+func (as *APIServer) writeControlError(w http.ResponseWriter, body map[string]interface{}, status int, err error) {
+	as.logger.Printf("❌ 控制操作 %v 失敗: %v", body["action"], err)
+	body["ok"] = false
+	body["error"] = err.Error()
+	writeJSON(w, status, body)
+}
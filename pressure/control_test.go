@@ -0,0 +1,101 @@
+package pressure
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// controlStatus 發送 POST 控制請求，返回狀態碼
+func controlStatus(t *testing.T, handler http.Handler, action, token string) int {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, ControlPathPrefix+action, strings.NewReader(`{"register": 256, "value": 9}`))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+func TestControlRequiresEnableControl(t *testing.T) {
+	m := newTestMonitor(t, NewMockTransport(1).SetRegisters(0x0100, 0))
+	as := NewAPIServer("127.0.0.1:0", log.New(io.Discard, "", 0)).SetMonitor(m)
+
+	if code := controlStatus(t, as.Handler(), ControlWriteRegister, ""); code != http.StatusForbidden {
+		t.Errorf("write-register without enable-control = %d, want 403", code)
+	}
+}
+
+func TestControlToken(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0100, 0)
+	m := newTestMonitor(t, mock, withControl)
+	as := NewAPIServer("127.0.0.1:0", log.New(io.Discard, "", 0)).SetMonitor(m).SetControlToken("s3cret")
+
+	for _, token := range []string{"", "wrong"} {
+		if code := controlStatus(t, as.Handler(), ControlWriteRegister, token); code != http.StatusUnauthorized {
+			t.Errorf("token %q = %d, want 401", token, code)
+		}
+	}
+	if value, _ := mock.Register(0x0100); value != 0 {
+		t.Fatalf("register written without a valid token: %d", value)
+	}
+	if code := controlStatus(t, as.Handler(), ControlWriteRegister, "s3cret"); code != http.StatusOK {
+		t.Errorf("valid token = %d, want 200", code)
+	}
+	if value, _ := mock.Register(0x0100); value != 9 {
+		t.Errorf("register 0x0100 = %d, want 9", value)
+	}
+}
+
+func TestControlSeparateAddr(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0100, 0)
+	m := newTestMonitor(t, mock, withControl)
+	as := NewAPIServer("127.0.0.1:0", log.New(io.Discard, "", 0)).SetMonitor(m).SetControlAddr("127.0.0.1:0")
+
+	if code := controlStatus(t, as.Handler(), ControlWriteRegister, ""); code != http.StatusNotFound {
+		t.Errorf("control on the read-only listener = %d, want 404", code)
+	}
+	if code := controlStatus(t, as.ControlHandler(), ControlWriteRegister, ""); code != http.StatusOK {
+		t.Errorf("control on the control listener = %d, want 200", code)
+	}
+}
+
+func TestControlRefusesRemoteAddrWithoutToken(t *testing.T) {
+	m := newTestMonitor(t, NewMockTransport(1), withControl)
+	logger := log.New(io.Discard, "", 0)
+
+	tests := []struct {
+		name    string
+		server  *APIServer
+		wantErr bool
+	}{
+		{"all interfaces", NewAPIServer(":0", logger), true},
+		{"remote control addr", NewAPIServer("127.0.0.1:0", logger).SetControlAddr("0.0.0.0:0"), true},
+		{"loopback", NewAPIServer("127.0.0.1:0", logger), false},
+		{"remote with loopback control addr", NewAPIServer(":0", logger).SetControlAddr("localhost:0"), false},
+		{"remote with token", NewAPIServer(":0", logger).SetControlToken("s3cret"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			as := tt.server.SetMonitor(m)
+			err := as.Start()
+			if err == nil {
+				as.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Start() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// 未啟用控制操作時只讀接口可以監聽任何地址
+	readOnly := NewAPIServer(":0", logger).SetMonitor(newTestMonitor(t, NewMockTransport(1)))
+	if err := readOnly.Start(); err != nil {
+		t.Errorf("read-only Start() on all interfaces: %v", err)
+	}
+	readOnly.Close()
+}
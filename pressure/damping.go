@@ -0,0 +1,35 @@
+// pressure/damping.go - 儀表阻尼/濾波時間設置
+package pressure
+
+import "fmt"
+
+// SupportsDamping 設備是否配置了阻尼寄存器
+func (pm *PressureMeter) SupportsDamping() bool {
+	return pm.damping != 0
+}
+
+// GetDamping 讀取儀表當前的阻尼寄存器原始值
+func (pm *PressureMeter) GetDamping() (uint16, error) {
+	if !pm.SupportsDamping() {
+		return 0, fmt.Errorf("未配置阻尼寄存器 (dampingregister)")
+	}
+	return pm.ReadRegister(pm.damping)
+}
+
+// SetDamping 寫入阻尼寄存器並讀回確認，值越大響應越慢、讀數越平穩
+func (pm *PressureMeter) SetDamping(value uint16) error {
+	pm.busMu.Lock()
+	defer pm.busMu.Unlock()
+	return pm.setDamping(value)
+}
+
+// setDamping 寫入阻尼寄存器，調用方需持有 busMu
+func (pm *PressureMeter) setDamping(value uint16) error {
+	if !pm.SupportsDamping() {
+		return fmt.Errorf("未配置阻尼寄存器 (dampingregister)")
+	}
+	if err := pm.writeRegister(pm.damping, value); err != nil {
+		return fmt.Errorf("設置阻尼失敗: %v", err)
+	}
+	return nil
+}
@@ -3,9 +3,12 @@ package pressure
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"log"
-	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/goburrow/modbus"
@@ -13,38 +16,253 @@ import (
 
 // Config 普時達壓差儀配置
 type Config struct {
-	// Device RS485 設備路徑 (如 /dev/ttyUSB0 或 COM1)
+	// Device RS485 設備路徑 (如 /dev/ttyUSB0 或 COM1)，串口服務器為 tcp://host:port
 	Device string `json:"device" yaml:"device"`
+	// Transport 傳輸方式 (rtu/tcp/rtuovertcp)，為空則為 rtu，設備路徑為 tcp://host:port 時為 rtuovertcp
+	Transport string `json:"transport,omitempty" yaml:"transport,omitempty"`
+	// Address Modbus TCP 網關地址 (host:port，未指定端口時為 502)，僅 tcp 傳輸使用
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
 	// SlaveID 儀表站點號 (1-247)
 	SlaveID byte `json:"slaveid" yaml:"slaveid"`
 	// ReadInterval 讀取間隔時間
 	ReadInterval time.Duration `json:"readinterval" yaml:"readinterval"`
-	// DataFormat 數據格式：0=十進制(默認), 1=浮點數
+	// DataFormat 數據格式：0=十進制(默認), 1=浮點數, 2=16 位有符號整數, 3=16 位無符號整數，僅在未指定設備配置檔時使用
 	DataFormat DataFormatType `json:"dataformat" yaml:"dataformat"`
+	// DeviceProfile 內建設備配置檔名稱（壓力寄存器地址和數值編碼），為空則按 DataFormat 使用普時達配置檔
+	DeviceProfile string `json:"deviceprofile,omitempty" yaml:"deviceprofile,omitempty"`
+	// CustomProfile 自定義設備配置檔，用於其他廠商的儀表，優先於 DeviceProfile
+	CustomProfile *DeviceProfile `json:"customprofile,omitempty" yaml:"customprofile,omitempty"`
+	// RegisterType 壓力寄存器類型 (holding/input)，為空則使用設備配置檔的功能碼
+	RegisterType string `json:"registertype,omitempty" yaml:"registertype,omitempty"`
+	// ByteOrder 壓力值的字節序 (ABCD/CDAB/BADC/DCBA)，為空則使用設備配置檔的字節序（普時達浮點數為 CDAB）
+	ByteOrder string `json:"byteorder,omitempty" yaml:"byteorder,omitempty"`
+	// Scale 壓力原始數值到 Pa 的換算係數，0 則使用設備配置檔的係數（普時達十進制格式為 0.1）
+	Scale float64 `json:"scale,omitempty" yaml:"scale,omitempty"`
+	// Parity 串口校驗位 (N/E/O)，為空則為 N
+	Parity string `json:"parity,omitempty" yaml:"parity,omitempty"`
+	// StopBits 串口停止位 (1/2)，0 為 1
+	StopBits int `json:"stopbits,omitempty" yaml:"stopbits,omitempty"`
+	// BaudRate 串口波特率，0 為 DefaultBaudRate (9600)；Modbus TCP 網關和串口服務器在網關上設置
+	BaudRate int `json:"baudrate,omitempty" yaml:"baudrate,omitempty"`
+	// TimestampSource 讀數時間戳取值時刻 (before/after/midpoint)，默認為請求前
+	TimestampSource TimestampSource `json:"timestampsource,omitempty" yaml:"timestampsource,omitempty"`
+	// ConnectTimeout 打開設備連接的超時時間
+	ConnectTimeout time.Duration `json:"connecttimeout" yaml:"connecttimeout"`
+	// ResponseTimeout 單次 Modbus 請求等待響應的超時時間
+	ResponseTimeout time.Duration `json:"responsetimeout" yaml:"responsetimeout"`
+	// LatencyBudget 單次 Modbus 請求的延遲預算，超出的讀數標記為降級，0 為不檢查
+	LatencyBudget time.Duration `json:"latencybudget,omitempty" yaml:"latencybudget,omitempty"`
+	// LatencyViolations 連續多少次超出延遲預算後觸發告警，0 為 DefaultLatencyViolations
+	LatencyViolations int `json:"latencyviolations,omitempty" yaml:"latencyviolations,omitempty"`
+	// MaxRetries 單次讀取失敗（超時、校驗錯誤等）後的重試次數，0 為不重試
+	MaxRetries int `json:"maxretries,omitempty" yaml:"maxretries,omitempty"`
+	// RetryDelay 讀取重試前的等待時間，0 為 DefaultRetryDelay
+	RetryDelay time.Duration `json:"retrydelay,omitempty" yaml:"retrydelay,omitempty"`
+	// OpenRetryWait 啟動時打開連接失敗後按退避時間重試的最長等待時間，0 為不重試
+	OpenRetryWait time.Duration `json:"openretrywait,omitempty" yaml:"openretrywait,omitempty"`
+	// StartDegraded 重試後仍無法連接時以降級模式啟動，讀取時再嘗試連接
+	StartDegraded bool `json:"startdegraded,omitempty" yaml:"startdegraded,omitempty"`
+	// RawData 在每個讀數中保留原始寄存器數據 (PressureReading.RawData)，默認關閉以節省內存
+	RawData bool `json:"rawdata,omitempty" yaml:"rawdata,omitempty"`
+	// BufferSize 讀數通道可緩衝的讀數數量，0 為默認值 100
+	BufferSize int `json:"buffersize,omitempty" yaml:"buffersize,omitempty"`
+	// OverflowPolicy 讀數通道已滿時的處理方式 (drop-oldest/drop-newest/block)
+	OverflowPolicy OverflowPolicy `json:"overflowpolicy,omitempty" yaml:"overflowpolicy,omitempty"`
+	// CommProfile 通信時序配置檔 (bench/long-line/radio)，為空則不使用
+	CommProfile string `json:"commprofile,omitempty" yaml:"commprofile,omitempty"`
+	// MinPressure 有效讀數下限 (Pa)，低於此值的讀數標記為無效，為空則為 MinReasonablePressure
+	MinPressure *float64 `json:"minpressure,omitempty" yaml:"minpressure,omitempty"`
+	// MaxPressure 有效讀數上限 (Pa)，高於此值的讀數標記為無效，為空則為 MaxReasonablePressure
+	MaxPressure *float64 `json:"maxpressure,omitempty" yaml:"maxpressure,omitempty"`
+	// EnableControl 允許寫入儀表（阻尼、站點號、波特率、零點校準、設置恢復、廣播寫入）和 HTTP 接口的控制操作，
+	// 默認關閉，只監測的部署可以保證不會向總線寫入
+	EnableControl bool `json:"enablecontrol,omitempty" yaml:"enablecontrol,omitempty"`
+	// DampingRegister 儀表阻尼/濾波時間的保持寄存器地址，0 表示設備沒有此寄存器
+	DampingRegister uint16 `json:"dampingregister,omitempty" yaml:"dampingregister,omitempty"`
+	// Damping 啟動時寫入阻尼寄存器的原始值（單位由儀表定義），為空則不修改
+	Damping *uint16 `json:"damping,omitempty" yaml:"damping,omitempty"`
+	// SlaveIDRegister 儀表站點號的保持寄存器地址，0 表示不支援遠程修改站點號
+	SlaveIDRegister uint16 `json:"slaveidregister,omitempty" yaml:"slaveidregister,omitempty"`
+	// BaudRateRegister 儀表波特率的保持寄存器地址，0 表示不支援遠程修改波特率
+	BaudRateRegister uint16 `json:"baudrateregister,omitempty" yaml:"baudrateregister,omitempty"`
+	// BaudRateCodes 波特率寄存器保存序號時，序號 0、1、2… 對應的波特率；為空則寄存器直接保存波特率
+	BaudRateCodes []int `json:"baudratecodes,omitempty" yaml:"baudratecodes,omitempty"`
+	// ZeroRegister 零點校準命令的保持寄存器地址，0 表示設備沒有此寄存器
+	ZeroRegister uint16 `json:"zeroregister,omitempty" yaml:"zeroregister,omitempty"`
+	// ZeroCommand 寫入零點校準寄存器觸發校準的命令值，0 為 DefaultZeroCommand
+	ZeroCommand uint16 `json:"zerocommand,omitempty" yaml:"zerocommand,omitempty"`
+	// TemperatureRegister 儀表溫度的保持寄存器地址，0 表示設備沒有此寄存器
+	TemperatureRegister uint16 `json:"temperatureregister,omitempty" yaml:"temperatureregister,omitempty"`
+	// TemperatureScale 溫度寄存器原始值（有符號）到 °C 的換算係數，0 為 DefaultTemperatureScale
+	TemperatureScale float64 `json:"temperaturescale,omitempty" yaml:"temperaturescale,omitempty"`
+	// Compensation 壓力通道的溫度補償係數，需要溫度寄存器，為空則不補償
+	Compensation *TemperatureCompensation `json:"compensation,omitempty" yaml:"compensation,omitempty"`
+	// Alarms 監測時使用的告警規則
+	Alarms []AlarmRule `json:"alarms,omitempty" yaml:"alarms,omitempty"`
+	// Bands 壓力區間，讀數進入另一個區間時產生 band_changed 事件
+	Bands []Band `json:"bands,omitempty" yaml:"bands,omitempty"`
+	// Baseline 按時段學習壓力基線，讀數偏離基線時觸發 baseline_anomaly 告警，為空則不啟用
+	Baseline *BaselineConfig `json:"baseline,omitempty" yaml:"baseline,omitempty"`
+	// Ambient 大氣壓來源（本地氣壓傳感器或天氣接口），附加到讀數便於區分建築壓差變化和天氣影響，為空則不啟用
+	Ambient *AmbientConfig `json:"ambient,omitempty" yaml:"ambient,omitempty"`
+	// StatsFile 定期保存壓力統計和設定值達標統計的檔案，重啟後載入繼續累計，為空則不保存
+	StatsFile string `json:"statsfile,omitempty" yaml:"statsfile,omitempty"`
+	// Hooks 事件觸發的外部腳本
+	Hooks []Hook `json:"hooks,omitempty" yaml:"hooks,omitempty"`
+	// Setpoint 壓力設定值和容差，設置後統計偏差、達標時間比例和每日超標時長
+	Setpoint *SetpointConfig `json:"setpoint,omitempty" yaml:"setpoint,omitempty"`
+	// UnitPreset 行業單位預設 (cleanroom/hvac/filtration)，決定文本輸出、告警描述和報告的單位與用語，為空則為 Pa
+	UnitPreset string `json:"unitpreset,omitempty" yaml:"unitpreset,omitempty"`
+	// Branding 嵌入其他產品時的名稱、版本和啟動橫幅，為空則使用本工具的默認值
+	Branding *Branding `json:"branding,omitempty" yaml:"branding,omitempty"`
+	// FrameTrace 記錄每個 Modbus 請求和響應幀，為空則不記錄
+	FrameTrace *FrameTrace `json:"-" yaml:"-"`
+	// ModbusTransport 自定義 Modbus 傳輸層，設置後不按 Transport 打開串口或網絡連接（測試時使用 MockTransport）
+	ModbusTransport ModbusTransport `json:"-" yaml:"-"`
 	// Logger 日誌記錄器
 	Logger *log.Logger `json:"-" yaml:"-"`
 }
 
 // PressureReading 壓力讀數
 type PressureReading struct {
-	Timestamp time.Time `json:"timestamp"` // 讀取時間
-	Pressure  float64   `json:"pressure"`  // 壓力值 (Pa)
-	SlaveID   byte      `json:"slave_id"`  // 設備 ID
-	RawData   []byte    `json:"raw_data"`  // 原始數據
-	Valid     bool      `json:"valid"`     // 數據是否有效
-	Error     string    `json:"error"`     // 錯誤信息（如果有）
+	Timestamp   time.Time     `json:"timestamp"`              // 讀取時間
+	Duration    time.Duration `json:"duration"`               // Modbus 請求到響應的耗時
+	Pressure    float64       `json:"pressure"`               // 壓力值 (Pa)，配置了溫度補償時為補償後的值
+	RawPressure *float64      `json:"raw_pressure,omitempty"` // 未補償的壓力值 (Pa)，僅溫度補償時存在
+	Scale       float64       `json:"scale,omitempty"`        // 原始數值到 Pa 的換算係數，解析出壓力值時存在
+	Degraded    bool          `json:"degraded,omitempty"`     // 有效但耗時超出延遲預算
+	Retries     int           `json:"retries,omitempty"`      // 本次讀取的重試次數
+	Cycle       uint64        `json:"cycle,omitempty"`        // 同步採樣的輪次（BusPoller 同步模式）
+	CycleTime   *time.Time    `json:"cycle_time,omitempty"`   // 同步採樣的共同時間戳，同一輪的讀數相同
+	Temperature *float64      `json:"temperature,omitempty"`  // 儀表溫度 (°C)，僅配置了溫度寄存器時存在
+	SlaveID     byte          `json:"slave_id"`               // 設備 ID
+	RawData     []byte        `json:"raw_data,omitempty"`     // 原始寄存器數據，僅啟用 Config.RawData 時存在
+	Valid       bool          `json:"valid"`                  // 數據是否有效
+	Error       string        `json:"error"`                  // 錯誤信息（如果有）
+	ErrorCode   ErrorCode     `json:"error_code,omitempty"`   // 錯誤代碼（如果有）
 }
 
-// PressureMeter 普時達壓差儀驅動
+// PressureLimits 返回有效讀數的上下限，未設置時使用合理壓力範圍常量
+func (c Config) PressureLimits() (low, high float64) {
+	low, high = MinReasonablePressure, MaxReasonablePressure
+	if c.MinPressure != nil {
+		low = *c.MinPressure
+	}
+	if c.MaxPressure != nil {
+		high = *c.MaxPressure
+	}
+	return low, high
+}
+
+// SerialBaudRate 返回串口波特率，未設置時為 DefaultBaudRate
+func (c Config) SerialBaudRate() int {
+	if c.BaudRate == 0 {
+		return DefaultBaudRate
+	}
+	return c.BaudRate
+}
+
+// SerialStopBits 返回串口停止位，未設置時為 DefaultStopBits
+func (c Config) SerialStopBits() int {
+	if c.StopBits == 0 {
+		return DefaultStopBits
+	}
+	return c.StopBits
+}
+
+// IsTCP 是否使用 Modbus TCP 傳輸
+func (c Config) IsTCP() bool {
+	return strings.EqualFold(c.Transport, TransportTCP)
+}
+
+// Endpoint 返回設備的連接端點：RTU 為串口路徑（串口服務器為 tcp://host:port），TCP 為網關地址
+func (c Config) Endpoint() string {
+	if c.IsTCP() {
+		return NormalizeTCPAddress(c.Address)
+	}
+	return c.Device
+}
+
+// setEndpoint 按傳輸方式設置連接端點（切換備用設備時使用）
+func (c *Config) setEndpoint(endpoint string) {
+	if c.IsTCP() {
+		c.Address = endpoint
+	} else {
+		c.Device = endpoint
+	}
+}
+
+// ModbusTransport Modbus 傳輸層：RTU、TCP 和串口服務器客戶端處理器，以及測試用的 MockTransport
+type ModbusTransport interface {
+	modbus.ClientHandler
+	Connect() error
+	Close() error
+}
+
+// PressureMeter 壓差儀驅動，寄存器佈局由設備配置檔決定（默認為普時達）
 type PressureMeter struct {
 	client     modbus.Client
-	handler    *modbus.RTUClientHandler // 保存 handler 引用以便關閉連接
+	handler    ModbusTransport     // 保存 handler 引用以便關閉連接
+	tracer     *tracingTransporter // 幀跟蹤包裝
+	transport  string
+	endpoint   string
 	slaveID    byte
 	dataFormat DataFormatType
-	logger     *log.Logger
-	readings   chan PressureReading
-	stopCh     chan struct{}
-	running    bool
+	profile    DeviceProfile // 壓力寄存器地址和數值編碼
+	timestamp  TimestampSource
+	minValid   float64 // 有效讀數下限 (Pa)
+	maxValid   float64 // 有效讀數上限 (Pa)
+	damping    uint16  // 阻尼寄存器地址，0 為不支援
+	control    bool    // 是否允許寫入儀表
+
+	zeroRegister    uint16 // 零點校準寄存器地址，0 為不支援
+	zeroCommand     uint16 // 零點校準命令值
+	slaveIDRegister uint16 // 站點號寄存器地址，0 為不支援
+	baudRegister    uint16 // 波特率寄存器地址，0 為不支援
+	baudCodes       []int  // 波特率寄存器的序號對應的波特率，為空則直接保存波特率
+
+	tempRegister uint16                   // 溫度寄存器地址，0 為不支援
+	tempScale    float64                  // 溫度換算係數
+	compensation *TemperatureCompensation // 溫度補償係數，為空則不補償
+
+	plan atomic.Pointer[readPlan] // 每個週期的讀取請求，相鄰的壓力和溫度寄存器合併讀取
+
+	busMu sync.Mutex // 經 client 的所有請求（讀取、寄存器讀寫、控制操作和後台發現的探測）串行執行，探測時臨時切換處理器的站點號
+
+	connMu         sync.Mutex
+	connected      bool            // 連接是否已打開
+	connectTimeout time.Duration   // 打開連接的超時時間
+	attempts       connectAttempts // 打開連接的嘗試計數，超時的嘗試遲到成功時據此判斷是否關閉
+	openTime       time.Duration   // 最近一次打開連接的耗時
+	parity         string          // RTU 校驗位，讀取設備標識時直接打開串口使用
+	stopBits       int             // RTU 停止位，讀取設備標識時直接打開串口使用
+	baudRate       int             // RTU 波特率，讀取設備標識時直接打開串口使用
+	respTimeout    time.Duration   // 每次請求的響應超時
+	latencyBudget  time.Duration   // 延遲預算，0 為不檢查
+	maxRetries     int             // 讀取失敗後的重試次數
+	retryDelay     time.Duration   // 讀取重試前的等待時間
+	backoff        time.Duration   // 降級模式下的重連退避時間
+	nextConnect    time.Time       // 降級模式下下次嘗試連接的時間
+	pendingDamping *uint16         // 連上後需要補寫的阻尼值
+	keepRaw        atomic.Bool     // 是否在讀數中保留原始寄存器數據
+
+	statusMu    sync.Mutex
+	status      DeviceStatus     // 連接和讀取狀態
+	statusSince time.Time        // 進入當前狀態的時間
+	createdAt   time.Time        // 創建時間，用於計算運行時長
+	lastReading *PressureReading // 最近一次讀數
+	model       *DeviceModel     // ReadDeviceInfo 讀取到的設備標識
+	events      chan Event       // 連接和狀態變化事件
+	bus         *EventBus        // 事件總線，為空則不發布
+
+	logger      *log.Logger
+	readings    chan PressureReading
+	queue       readingQueue       // 讀數通道的統計
+	subscribers readingSubscribers // 讀數訂閱者
+	overflow    OverflowPolicy     // 讀數通道已滿時的處理方式
+	comm        commCounters       // 通信健康統計
+	stopCh      chan struct{}
+	running     bool
 }
 
 // Modbus 寄存器地址常量
@@ -57,50 +275,262 @@ const (
 // NewPressureMeter 創建新的壓差儀實例
 func NewPressureMeter(config Config) (*PressureMeter, error) {
 	// 驗證配置
-	if config.SlaveID < 1 || config.SlaveID > 247 {
-		return nil, fmt.Errorf("invalid slave ID: %d, must be 1-247", config.SlaveID)
+	if err := CheckPollingSlaveID(config.SlaveID); err != nil {
+		return nil, fmt.Errorf("invalid slave ID: %v", err)
 	}
 
 	if config.ReadInterval == 0 {
 		config.ReadInterval = time.Second // 默認 1 秒讀取一次
 	}
 
+	deviceProfile, err := ResolveDeviceProfile(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.BufferSize < 0 {
+		return nil, fmt.Errorf("讀數緩衝區大小不能為負數: %d", config.BufferSize)
+	}
+
+	if config.CommProfile != "" {
+		profile, err := GetCommProfile(config.CommProfile)
+		if err != nil {
+			return nil, err
+		}
+		profile.ApplyTo(&config)
+	}
+
+	if config.ConnectTimeout == 0 {
+		config.ConnectTimeout = DefaultConnectTimeout
+	}
+
+	if config.ResponseTimeout == 0 {
+		config.ResponseTimeout = DefaultResponseTimeout
+	}
+
+	if config.Transport == "" {
+		config.Transport = DefaultTransport
+	}
+	if !IsValidTransport(config.Transport) {
+		return nil, fmt.Errorf("invalid transport: %s, must be rtu, tcp or rtuovertcp", config.Transport)
+	}
+	if config.IsTCP() && config.Address == "" {
+		return nil, fmt.Errorf("invalid address: Modbus TCP requires host:port")
+	}
+	if config.IsRTUOverTCP() {
+		config.Transport = TransportRTUOverTCP
+		if err := checkSerialServerAddress(config.SerialServerAddress()); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.Parity == "" {
+		config.Parity = DefaultParity
+	}
+	if !IsValidParity(config.Parity) {
+		return nil, fmt.Errorf("invalid parity: %s, must be N, E or O", config.Parity)
+	}
+	if !IsValidStopBits(config.SerialStopBits()) {
+		return nil, fmt.Errorf("invalid stop bits: %d, must be 1 or 2", config.StopBits)
+	}
+	if !IsValidBaudRate(config.SerialBaudRate()) {
+		return nil, fmt.Errorf("invalid baud rate: %d, supported: %v", config.BaudRate, GetSupportedBaudRates())
+	}
+
+	minValid, maxValid := config.PressureLimits()
+	if minValid >= maxValid {
+		return nil, fmt.Errorf("invalid pressure limits: min %.2f must be less than max %.2f", minValid, maxValid)
+	}
+
+	if config.Compensation != nil && config.TemperatureRegister == 0 {
+		return nil, fmt.Errorf("invalid compensation: temperature register is required")
+	}
+	if config.ZeroCommand == 0 {
+		config.ZeroCommand = DefaultZeroCommand
+	}
+	if config.TemperatureScale == 0 {
+		config.TemperatureScale = DefaultTemperatureScale
+	}
+
 	if config.Logger == nil {
 		config.Logger = log.Default()
 	}
 
-	// 創建 Modbus RTU 客戶端處理器
-	handler := modbus.NewRTUClientHandler(config.Device)
-	handler.BaudRate = 9600
-	handler.DataBits = 8
-	handler.Parity = "N"
-	handler.StopBits = 1
-	handler.SlaveId = config.SlaveID
-	handler.Timeout = 5 * time.Second
-
-	// 連接設備
-	err := handler.Connect()
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to device %s: %v", config.Device, err)
+	if config.OpenRetryWait < 0 {
+		return nil, fmt.Errorf("invalid open retry wait: %v", config.OpenRetryWait)
+	}
+	if config.MaxRetries < 0 {
+		return nil, fmt.Errorf("invalid max retries: %d", config.MaxRetries)
+	}
+	if config.RetryDelay == 0 {
+		config.RetryDelay = DefaultRetryDelay
 	}
 
-	// 創建 Modbus 客戶端
-	client := modbus.NewClient(handler)
+	// 創建 Modbus 客戶端處理器和客戶端，調用方提供傳輸層時直接使用
+	handler := config.ModbusTransport
+	if handler == nil {
+		handler = newModbusHandler(config)
+	}
+	client, tracer := newTracedClient(handler, config.Endpoint(), !config.IsTCP(), config.FrameTrace)
 
 	pm := &PressureMeter{
 		client:     client,
 		handler:    handler, // 保存 handler 引用
+		tracer:     tracer,
+		transport:  strings.ToLower(config.Transport),
+		endpoint:   config.Endpoint(),
 		slaveID:    config.SlaveID,
 		dataFormat: config.DataFormat,
-		logger:     config.Logger,
-		readings:   make(chan PressureReading, 100), // 緩衝 100 個讀數
-		stopCh:     make(chan struct{}),
-		running:    false,
+		profile:    deviceProfile,
+		timestamp:  config.TimestampSource,
+		minValid:   minValid,
+		maxValid:   maxValid,
+		damping:    config.DampingRegister,
+		control:    config.EnableControl,
+
+		zeroRegister:    config.ZeroRegister,
+		zeroCommand:     config.ZeroCommand,
+		slaveIDRegister: config.SlaveIDRegister,
+		baudRegister:    config.BaudRateRegister,
+		baudCodes:       config.BaudRateCodes,
+
+		tempRegister: config.TemperatureRegister,
+		tempScale:    config.TemperatureScale,
+		compensation: config.Compensation,
+
+		connectTimeout: config.ConnectTimeout,
+		parity:         strings.ToUpper(config.Parity),
+		stopBits:       config.SerialStopBits(),
+		baudRate:       config.SerialBaudRate(),
+		respTimeout:    config.ResponseTimeout,
+		latencyBudget:  config.LatencyBudget,
+		maxRetries:     config.MaxRetries,
+		retryDelay:     config.RetryDelay,
+
+		status:      StatusConnecting,
+		statusSince: time.Now(),
+		createdAt:   time.Now(),
+		events:      make(chan Event, DefaultEventBufferSize),
+
+		logger:   config.Logger,
+		readings: make(chan PressureReading, bufferSizeOrDefault(config.BufferSize)),
+		overflow: config.OverflowPolicy,
+		stopCh:   make(chan struct{}),
+		running:  false,
+	}
+	pm.keepRaw.Store(config.RawData)
+	pm.planReads()
+
+	// 連接設備，失敗時按配置重試
+	if err := pm.openWithRetry(config.OpenRetryWait); err != nil {
+		if !config.StartDegraded {
+			return nil, fmt.Errorf("failed to connect to device %s: %v", config.Endpoint(), err)
+		}
+		pm.logger.Printf("⚠️  無法打開 %s: %v，以降級模式啟動，讀取時再嘗試連接", config.Endpoint(), err)
+		pm.setStatus(StatusDisconnected, err.Error())
+		pm.backoff = OpenRetryInitialBackoff
+		pm.nextConnect = time.Now().Add(pm.backoff)
+		pm.pendingDamping = config.Damping
+		return pm, nil
+	}
+	pm.connected = true
+	pm.setStatus(StatusRunning, "連接已打開")
+
+	// 啟動時套用配置的阻尼時間
+	if config.Damping != nil {
+		if err := pm.SetDamping(*config.Damping); err != nil {
+			handler.Close()
+			return nil, fmt.Errorf("failed to apply damping: %v", err)
+		}
 	}
 
 	return pm, nil
 }
 
+// newModbusHandler 按傳輸方式創建 Modbus 客戶端處理器
+func newModbusHandler(config Config) ModbusTransport {
+	if config.IsTCP() {
+		// Modbus TCP 網關：單元標識符即儀表站點號
+		handler := modbus.NewTCPClientHandler(config.Endpoint())
+		handler.SlaveId = config.SlaveID
+		handler.Timeout = config.ResponseTimeout
+		return handler
+	}
+	if config.IsRTUOverTCP() {
+		// 串口服務器：RTU 幀原樣經 TCP 轉發到串口
+		return newRTUOverTCPHandler(config.SerialServerAddress(), config.SlaveID, config.ResponseTimeout)
+	}
+
+	handler := modbus.NewRTUClientHandler(config.Device)
+	handler.BaudRate = config.SerialBaudRate()
+	handler.DataBits = 8
+	handler.Parity = strings.ToUpper(config.Parity)
+	handler.StopBits = config.SerialStopBits()
+	handler.SlaveId = config.SlaveID
+	handler.Timeout = config.ResponseTimeout
+	return handler
+}
+
+// connectHandler 在指定時間內打開 Modbus 連接，超時後在背景關閉遲到的連接
+func connectHandler(handler ModbusTransport, timeout time.Duration) error {
+	return connectHandlerLate(handler, timeout, func() { handler.Close() })
+}
+
+// connectHandlerLate 在指定時間內打開 Modbus 連接，超時後連接最終成功時調用 closeLate
+func connectHandlerLate(handler ModbusTransport, timeout time.Duration, closeLate func()) error {
+	if timeout <= 0 {
+		return handler.Connect()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- handler.Connect()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		// 連接最終成功時釋放串口，避免佔用
+		go func() {
+			if err := <-done; err == nil {
+				closeLate()
+			}
+		}()
+		return fmt.Errorf("連接超時 (%v)", timeout)
+	}
+}
+
+// connectAttempts 重複使用同一處理器打開連接的嘗試計數
+//
+// 重試和重新打開共用一個處理器，超時的嘗試遲到成功時只在之後沒有新的嘗試時關閉連接，
+// 避免關閉之後的嘗試已經打開的連接。
+type connectAttempts struct {
+	mu sync.Mutex
+	n  uint64
+}
+
+// connect 在指定時間內打開 handler 的連接，計為一次新的嘗試
+func (ca *connectAttempts) connect(handler ModbusTransport, timeout time.Duration) error {
+	ca.mu.Lock()
+	ca.n++
+	attempt := ca.n
+	ca.mu.Unlock()
+
+	return connectHandlerLate(handler, timeout, func() {
+		ca.mu.Lock()
+		defer ca.mu.Unlock()
+		if ca.n == attempt {
+			handler.Close()
+		}
+	})
+}
+
+// connect 在連接超時內打開儀表的連接
+func (pm *PressureMeter) connect() error {
+	return pm.attempts.connect(pm.handler, pm.connectTimeout)
+}
+
 // Start 開始連續讀取壓力數據
 func (pm *PressureMeter) Start(interval time.Duration) {
 	if pm.running {
@@ -121,18 +551,7 @@ func (pm *PressureMeter) Start(interval time.Duration) {
 				pm.logger.Println("停止讀取壓差儀數據")
 				return
 			case <-ticker.C:
-				reading := pm.ReadPressure()
-				select {
-				case pm.readings <- reading:
-				default:
-					// 通道已滿，丟棄最舊的讀數
-					pm.logger.Println("讀數通道已滿，丟棄舊數據")
-					select {
-					case <-pm.readings:
-					default:
-					}
-					pm.readings <- reading
-				}
+				pm.publish(pm.ReadPressure(), pm.stopCh)
 			}
 		}
 	}()
@@ -149,101 +568,222 @@ func (pm *PressureMeter) Stop() {
 	pm.logger.Println("已停止壓差儀讀取")
 }
 
-// ReadPressure 讀取一次壓力數據
+// ReadPressure 讀取一次壓力數據，並按結果更新設備狀態
 func (pm *PressureMeter) ReadPressure() PressureReading {
+	pm.busMu.Lock()
+	reading := pm.readPressure()
+	pm.busMu.Unlock()
+	pm.updateStatus(reading)
+	return reading
+}
+
+// readSlave 切換到 slaveID 後讀取一次壓力，用於同一總線上輪流讀取多台儀表
+func (pm *PressureMeter) readSlave(slaveID byte) PressureReading {
+	pm.busMu.Lock()
+	if err := setHandlerSlaveID(pm.handler, slaveID); err != nil {
+		pm.busMu.Unlock()
+		return PressureReading{Timestamp: time.Now(), SlaveID: slaveID, Error: err.Error(), ErrorCode: ErrConnection}
+	}
+	pm.slaveID = slaveID
+	reading := pm.readPressure()
+	pm.busMu.Unlock()
+	pm.updateStatus(reading)
+	return reading
+}
+
+// readPressure 讀取一次壓力數據
+func (pm *PressureMeter) readPressure() PressureReading {
 	reading := PressureReading{
 		Timestamp: time.Now(),
 		SlaveID:   pm.slaveID,
 		Valid:     false,
 	}
 
-	// 發送 Modbus 讀取命令
-	// 功能碼 0x03, 地址 0x0034, 數量 0x0002
-	results, err := pm.client.ReadHoldingRegisters(PressureRegisterAddr, RegisterCount)
-	if err != nil {
-		reading.Error = fmt.Sprintf("讀取壓力數據失敗: %v", err)
-		pm.logger.Printf(reading.Error)
+	// 降級模式下先嘗試建立連接
+	if err := pm.ensureConnected(); err != nil {
+		reading.Error = err.Error()
+		reading.ErrorCode = ErrConnection
 		return reading
 	}
 
-	if len(results) != 4 {
-		reading.Error = fmt.Sprintf("接收數據長度錯誤: 期望4字節，實際%d字節", len(results))
-		pm.logger.Printf(reading.Error)
+	// 按設備配置檔發送 Modbus 讀取命令（普時達：功能碼 0x03, 地址 0x0034, 數量 0x0002），失敗時按配置重試；
+	// 溫度寄存器與壓力寄存器相鄰時在同一請求中讀取
+	plan := pm.plan.Load()
+	block := plan.block(channelPressure)
+	var data []byte
+	var err error
+	for {
+		start := time.Now()
+		data, err = pm.readSpan(block.registerSpan)
+		end := time.Now()
+		reading.Timestamp = pm.timestamp.Resolve(start, end)
+		reading.Duration = end.Sub(start)
+		if err == nil {
+			if expected := 2 * int(block.count); len(data) != expected {
+				err = errShortResponse{expected: expected, actual: len(data)}
+			}
+		}
+		pm.comm.record(err, reading.Duration)
+		if separate := pm.disableCoalescing(plan, err); separate != nil {
+			plan, block = separate, separate.block(channelPressure)
+			continue
+		}
+		if err == nil || reading.Retries >= pm.maxRetries || !isRetryable(err) {
+			break
+		}
+		reading.Retries++
+		pm.logger.Printf("讀取壓力數據失敗，%v 後第 %d 次重試: %v", pm.retryDelay, reading.Retries, err)
+		time.Sleep(pm.retryDelay)
+	}
+	if err != nil {
+		// 區分異常響應、超時和報文錯誤，下游可按錯誤代碼分別處理
+		readErr := NewModbusPressureError(err, pm.slaveID)
+		reading.Error = fmt.Sprintf("讀取壓力數據失敗: %s", readErr.Message)
+		reading.ErrorCode = readErr.Code
+		pm.logger.Print(reading.Error)
 		return reading
 	}
+	results := block.extract(data, plan.spans[channelPressure])
+	var temperatureData []byte
+	if block.covers(channelTemperature) {
+		temperatureData = block.extract(data, plan.spans[channelTemperature])
+	}
 
-	reading.RawData = make([]byte, len(results))
-	copy(reading.RawData, results)
+	if pm.keepRaw.Load() {
+		reading.RawData = make([]byte, len(results))
+		copy(reading.RawData, results)
+	}
 
-	// 根據數據格式解析壓力值
-	switch pm.dataFormat {
-	case DecimalFormat:
-		reading.Pressure = pm.parseDecimalFormat(results)
-	case FloatFormat:
-		reading.Pressure = pm.parseFloatFormat(results)
-	default:
-		reading.Error = fmt.Sprintf("未知數據格式: %d", pm.dataFormat)
-		pm.logger.Printf(reading.Error)
+	// 按設備配置檔的編碼和字節序解析壓力值
+	// 非有限值（NaN/Inf）不能進入統計和告警，也不能寫入讀數（JSON 無法編碼），無效讀數的壓力保持為 0
+	pressure, err := pm.profile.Decode(results)
+	reading.Scale = pm.profile.Scale
+	if err != nil {
+		var nonFinite errNonFinite
+		if errors.As(err, &nonFinite) {
+			reading.Error = fmt.Sprintf("%v (原始數據: % X)", err, results)
+			reading.ErrorCode = ErrInvalidData
+		} else {
+			reading.Error = fmt.Sprintf("解析壓力數據失敗: %v", err)
+			reading.ErrorCode = ErrConfig
+		}
+		pm.logger.Print(reading.Error)
+		return reading
+	}
+	reading.Pressure = pressure
+
+	// 溫度補償在範圍檢查之前，範圍限制針對補償後的值
+	if pm.SupportsTemperature() {
+		if err := pm.applyTemperature(&reading, temperatureData); err != nil {
+			// 與壓力讀取一樣區分異常響應、超時和報文錯誤
+			readErr := NewModbusPressureError(err, pm.slaveID)
+			reading.Error = fmt.Sprintf("讀取溫度失敗，無法補償: %s", readErr.Message)
+			reading.ErrorCode = readErr.Code
+			pm.logger.Print(reading.Error)
+			return reading
+		}
+	}
+
+	// 超出量程限制的讀數標記為無效，不讓荒謬的數值進入統計和告警
+	if reading.Pressure < pm.minValid || reading.Pressure > pm.maxValid {
+		reading.Error = fmt.Sprintf("壓力值 %.2f Pa 超出有效範圍 [%.2f, %.2f] (原始數據: % X)",
+			reading.Pressure, pm.minValid, pm.maxValid, results)
+		reading.ErrorCode = ErrOutOfRange
+		pm.logger.Print(reading.Error)
 		return reading
 	}
 
 	reading.Valid = true
-	pm.logger.Printf("讀取壓力: %.2f Pa (原始數據: %02X %02X %02X %02X)",
-		reading.Pressure, results[0], results[1], results[2], results[3])
+	if pm.latencyBudget > 0 && reading.Duration > pm.latencyBudget {
+		reading.Degraded = true
+		pm.logger.Printf("讀取耗時 %v 超出延遲預算 %v", reading.Duration.Round(time.Microsecond), pm.latencyBudget)
+	}
+	pm.logger.Printf("讀取壓力: %.2f Pa (原始數據: % X)", reading.Pressure, results)
 
 	return reading
 }
 
-// parseDecimalFormat 解析十進制格式數據
-func (pm *PressureMeter) parseDecimalFormat(data []byte) float64 {
-	// 組合 4 字節數據為 32 位整數
-	// data[0] data[1] data[2] data[3] = D1 D2 D3 D4
-	value := int32(binary.BigEndian.Uint32(data))
+// DefaultRetryDelay 讀取重試前的默認等待時間
+const DefaultRetryDelay = 100 * time.Millisecond
 
-	// 檢查是否為負數
-	// 方法1: 檢查最高字節是否為 0xFF
-	if data[0] == 0xFF {
-		pm.logger.Printf("檢測到負數 (最高字節 0xFF): %08X", uint32(value))
-		// 對於負數，直接使用 int32 的值然後除以 10
-		return float64(value) / 10.0
-	}
+// errShortResponse 響應數據長度與請求的寄存器數量不符
+type errShortResponse struct {
+	expected, actual int
+}
+
+func (e errShortResponse) Error() string {
+	return fmt.Sprintf("接收數據長度錯誤: 期望%d字節，實際%d字節", e.expected, e.actual)
+}
 
-	// 方法2: 檢查符號位
-	if (uint32(value) & 0x80000000) == 0x80000000 {
-		pm.logger.Printf("檢測到負數 (符號位): %08X", uint32(value))
-		return float64(value) / 10.0
+// isRetryable 讀取錯誤是否可能由總線干擾等暫時原因造成
+//
+// 儀表返回的 Modbus 異常響應（如非法地址）重試也不會成功，只有設備忙和網關目標無響應值得重試。
+func isRetryable(err error) bool {
+	if modbusErr, ok := err.(*modbus.ModbusError); ok {
+		code := ClassifyError(modbusErr)
+		return code == ErrDeviceBusy || code == ErrTimeout
 	}
+	return true
+}
 
-	// 正數處理：除以 10 得到實際壓力值
-	pressure := float64(value) / 10.0
-	return pressure
+// ReadRegister 讀取單個保持寄存器
+func (pm *PressureMeter) ReadRegister(address uint16) (uint16, error) {
+	pm.busMu.Lock()
+	defer pm.busMu.Unlock()
+	return pm.readRegister(address)
 }
 
-// parseFloatFormat 解析浮點數格式數據 (IEEE 754, Modbus 3412 字節序)
-func (pm *PressureMeter) parseFloatFormat(data []byte) float64 {
-	// Modbus 3412 字節序轉換為標準 IEEE 754
-	// 收到: data[0] data[1] data[2] data[3] (對應 Word1_High Word1_Low Word2_High Word2_Low)
-	// 需要重排為: data[2] data[3] data[0] data[1] (標準 IEEE 754)
+// readRegister 讀取單個保持寄存器，調用方需持有 busMu
+func (pm *PressureMeter) readRegister(address uint16) (uint16, error) {
+	results, err := pm.client.ReadHoldingRegisters(address, 1)
+	if err != nil {
+		return 0, fmt.Errorf("讀取寄存器 0x%04X 失敗: %v", address, err)
+	}
+	if len(results) != 2 {
+		return 0, fmt.Errorf("讀取寄存器 0x%04X 返回長度錯誤: %d 字節", address, len(results))
+	}
+	return binary.BigEndian.Uint16(results), nil
+}
 
-	ieeeBytes := make([]byte, 4)
-	ieeeBytes[0] = data[2] // Byte 2
-	ieeeBytes[1] = data[3] // Byte 3
-	ieeeBytes[2] = data[0] // Byte 0
-	ieeeBytes[3] = data[1] // Byte 1
+// WriteRegister 寫入單個保持寄存器（功能碼 0x06）並讀回確認，需要啟用控制操作
+func (pm *PressureMeter) WriteRegister(address, value uint16) error {
+	pm.busMu.Lock()
+	defer pm.busMu.Unlock()
+	return pm.writeRegister(address, value)
+}
 
-	// 轉換為 float32
-	bits := binary.BigEndian.Uint32(ieeeBytes)
-	pressure := math.Float32frombits(bits)
+// writeRegister 寫入單個保持寄存器並讀回確認，調用方需持有 busMu
+func (pm *PressureMeter) writeRegister(address, value uint16) error {
+	if err := pm.checkControl(); err != nil {
+		return err
+	}
+	if _, err := pm.client.WriteSingleRegister(address, value); err != nil {
+		return fmt.Errorf("寫入寄存器 0x%04X 失敗: %v", address, err)
+	}
 
-	pm.logger.Printf("浮點數解析: 原始=%02X%02X%02X%02X, 重排=%02X%02X%02X%02X, 值=%.2f",
-		data[0], data[1], data[2], data[3],
-		ieeeBytes[0], ieeeBytes[1], ieeeBytes[2], ieeeBytes[3],
-		pressure)
+	readback, err := pm.readRegister(address)
+	if err != nil {
+		return fmt.Errorf("寫入後讀回失敗: %v", err)
+	}
+	if readback != value {
+		return fmt.Errorf("寄存器 0x%04X 讀回值 %d 與寫入值 %d 不一致", address, readback, value)
+	}
+	pm.logger.Printf("寄存器 0x%04X 已寫入: %d", address, value)
+	return nil
+}
+
+// SetRawData 設置是否在讀數中保留原始寄存器數據 (RawData)，診斷時臨時開啟
+func (pm *PressureMeter) SetRawData(enabled bool) *PressureMeter {
+	pm.keepRaw.Store(enabled)
+	return pm
+}
 
-	return float64(pressure)
+// RawDataEnabled 返回是否在讀數中保留原始寄存器數據
+func (pm *PressureMeter) RawDataEnabled() bool {
+	return pm.keepRaw.Load()
 }
 
-// GetReadings 獲取讀數通道
+// GetReadings 獲取讀數通道，只能由一個消費者讀取，多個消費者請使用 Subscribe
 func (pm *PressureMeter) GetReadings() <-chan PressureReading {
 	return pm.readings
 }
@@ -251,8 +791,10 @@ func (pm *PressureMeter) GetReadings() <-chan PressureReading {
 // Close 關閉連接
 func (pm *PressureMeter) Close() error {
 	pm.Stop()
+	pm.closeSubscribers()
 
 	// 關閉 Modbus 連接
+	pm.setStatus(StatusDisconnected, "連接已關閉")
 	if pm.handler != nil {
 		return pm.handler.Close()
 	}
@@ -260,21 +802,20 @@ func (pm *PressureMeter) Close() error {
 	return nil
 }
 
-// SetDataFormat 設置數據格式
+// SetDataFormat 設置數據格式，使用普時達配置檔時同時切換解析方式
 func (pm *PressureMeter) SetDataFormat(format DataFormatType) {
 	pm.dataFormat = format
+	if isPushidaProfile(pm.profile.Name) {
+		function := pm.profile.Function
+		pm.profile = ProfileForFormat(format)
+		pm.profile.Function = function
+	}
 	pm.logger.Printf("數據格式已設置為: %d", format)
 }
 
-// GetStatus 獲取設備狀態
-func (pm *PressureMeter) GetStatus() map[string]interface{} {
-	return map[string]interface{}{
-		"running":        pm.running,
-		"slave_id":       pm.slaveID,
-		"data_format":    pm.dataFormat,
-		"queue_size":     len(pm.readings),
-		"queue_capacity": cap(pm.readings),
-	}
+// Profile 返回使用中的設備配置檔
+func (pm *PressureMeter) Profile() DeviceProfile {
+	return pm.profile
 }
 
 // IsRunning 檢查設備是否正在運行
@@ -292,16 +833,6 @@ func (pm *PressureMeter) GetDataFormat() DataFormatType {
 	return pm.dataFormat
 }
 
-// TestConnection 測試連接是否正常
-func (pm *PressureMeter) TestConnection() error {
-	reading := pm.ReadPressure()
-	if !reading.Valid {
-		return fmt.Errorf("連接測試失敗: %s", reading.Error)
-	}
-	pm.logger.Printf("連接測試成功，當前壓力: %.2f Pa", reading.Pressure)
-	return nil
-}
-
 // GetLastReading 獲取最後一次讀數（非阻塞）
 func (pm *PressureMeter) GetLastReading() *PressureReading {
 	select {
@@ -333,11 +864,6 @@ func (pm *PressureMeter) String() string {
 		status = "運行中"
 	}
 
-	formatStr := "十進制"
-	if pm.dataFormat == FloatFormat {
-		formatStr = "浮點數"
-	}
-
 	return fmt.Sprintf("壓差儀[站點:%d, 格式:%s, 狀態:%s]",
-		pm.slaveID, formatStr, status)
+		pm.slaveID, formatToString(pm.dataFormat), status)
 }
@@ -0,0 +1,116 @@
+package pressure
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReadPressureRejectsNonFiniteFloat(t *testing.T) {
+	// 普時達浮點格式為 CDAB：第一個寄存器為低位字，0x7FC00000 為 NaN
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0x0000, 0x7FC0)
+	pm := newTestMeter(t, mock, func(c *Config) { c.DataFormat = FloatFormat })
+
+	reading := pm.ReadPressure()
+	if reading.Valid || reading.ErrorCode != ErrInvalidData {
+		t.Fatalf("NaN reading: valid=%v code=%v, want invalid ErrInvalidData", reading.Valid, reading.ErrorCode)
+	}
+	if reading.Pressure != 0 {
+		t.Errorf("NaN reading carries pressure %v, want 0", reading.Pressure)
+	}
+	if _, err := json.Marshal(reading); err != nil {
+		t.Errorf("json.Marshal of rejected reading: %v", err)
+	}
+}
+
+func TestReadPressureDecoding(t *testing.T) {
+	tests := []struct {
+		name      string
+		format    DataFormatType
+		custom    *DeviceProfile
+		register  uint16
+		registers []uint16
+		want      float64
+	}{
+		{name: "decimal", format: DecimalFormat, register: 0x0034, registers: []uint16{0x0000, 0x007B}, want: 12.3},
+		{name: "negative decimal", format: DecimalFormat, register: 0x0034, registers: []uint16{0xFFFF, 0xFF85}, want: -12.3},
+		// 普時達浮點格式為 CDAB：0x437A0000 = 250.0
+		{name: "float", format: FloatFormat, register: 0x0034, registers: []uint16{0x0000, 0x437A}, want: 250},
+		{name: "int16", format: Int16Format, register: 0x0034, registers: []uint16{0xFF85}, want: -12.3},
+		{
+			name:      "custom uint16",
+			custom:    &DeviceProfile{Name: "custom", Register: 0x0010, Encoding: EncodingUint16, Scale: 0.01},
+			register:  0x0010,
+			registers: []uint16{0xFF85},
+			want:      654.13,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := NewMockTransport(1).SetRegisters(tt.register, tt.registers...)
+			pm := newTestMeter(t, mock, func(c *Config) {
+				c.DataFormat = tt.format
+				c.CustomProfile = tt.custom
+			})
+
+			reading := pm.ReadPressure()
+			if !reading.Valid {
+				t.Fatalf("reading invalid: %s", reading.Error)
+			}
+			if math.Abs(reading.Pressure-tt.want) > 1e-9 {
+				t.Errorf("pressure = %v, want %v", reading.Pressure, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadPressureReportsTimeout(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0x0000, 0x007B).SetFailures(1, nil)
+	pm := newTestMeter(t, mock)
+
+	if reading := pm.ReadPressure(); reading.Valid || reading.ErrorCode != ErrTimeout {
+		t.Fatalf("first reading: valid=%v code=%v, want invalid ErrTimeout", reading.Valid, reading.ErrorCode)
+	}
+	if reading := pm.ReadPressure(); !reading.Valid || reading.Pressure != 12.3 {
+		t.Fatalf("second reading: valid=%v pressure=%v error=%q, want 12.3", reading.Valid, reading.Pressure, reading.Error)
+	}
+}
+
+// stallingTransport 打開連接時可以被卡住的傳輸層，模擬打開超時後才完成的串口
+type stallingTransport struct {
+	*MockTransport
+	stall chan chan struct{} // 收到時下一次打開連接等待該通道關閉
+}
+
+func (st stallingTransport) Connect() error {
+	select {
+	case release := <-st.stall:
+		<-release
+	default:
+	}
+	return st.MockTransport.Connect()
+}
+
+func TestLateConnectDoesNotCloseNewerConnection(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0, 123)
+	transport := stallingTransport{MockTransport: mock, stall: make(chan chan struct{}, 1)}
+	pm := newTestMeter(t, transport, func(c *Config) { c.ConnectTimeout = 20 * time.Millisecond })
+
+	// 第一次重新打開超時，第二次成功，之後第一次的連接才遲到完成
+	release := make(chan struct{})
+	transport.stall <- release
+	if err := pm.Reopen(); err == nil {
+		t.Fatal("Reopen with stalled connect: want timeout")
+	}
+	if err := pm.Reopen(); err != nil {
+		t.Fatalf("second Reopen: %v", err)
+	}
+	close(release)
+	time.Sleep(20 * time.Millisecond)
+
+	if reading := pm.ReadPressure(); !reading.Valid {
+		t.Errorf("reading after the stale connect finished: %s", reading.Error)
+	}
+}
@@ -0,0 +1,317 @@
+// pressure/devicestatus.go - 設備狀態跟蹤和狀態變化事件
+package pressure
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Status 設備狀態 (PressureMeter.GetStatus)：連接狀態、配置摘要、讀數緩衝區和通信計數器及最近一次讀數
+//
+// JSON 字段與設備狀態結構描述 (--schema) 一致，時長以毫秒輸出到帶 _ms 後綴的字段。
+type Status struct {
+	SchemaVersion string `json:"schema_version,omitempty"` // 輸出格式版本，嵌入狀態快照時省略
+
+	// 連接狀態
+	Running      bool          `json:"running"`         // 是否正在連續讀取
+	Transport    string        `json:"transport"`       // 傳輸方式 (rtu/tcp/rtuovertcp)
+	Endpoint     string        `json:"endpoint"`        // 串口或網關地址
+	SlaveID      byte          `json:"slave_id"`        // Modbus 站點號
+	Connected    bool          `json:"connected"`       // 連接是否已打開
+	DeviceStatus DeviceStatus  `json:"device_status"`   // 設備狀態
+	StatusSince  time.Time     `json:"status_since"`    // 進入當前狀態的時間
+	Uptime       time.Duration `json:"-"`               // 自創建起的運行時長
+	Model        *DeviceModel  `json:"model,omitempty"` // 設備標識，僅調用過 ReadDeviceInfo 時存在
+
+	// 配置摘要
+	DataFormat          DataFormatType  `json:"data_format"`          // 數據格式
+	DeviceProfile       string          `json:"device_profile"`       // 設備配置檔名稱
+	TimestampSource     TimestampSource `json:"timestamp_source"`     // 讀數時間戳取值時刻
+	MinPressure         float64         `json:"min_pressure"`         // 有效讀數下限 (Pa)
+	MaxPressure         float64         `json:"max_pressure"`         // 有效讀數上限 (Pa)
+	DampingRegister     uint16          `json:"damping_register"`     // 阻尼寄存器地址，0 為未配置
+	LatencyBudget       time.Duration   `json:"-"`                    // 延遲預算，0 為不檢查
+	MaxRetries          int             `json:"max_retries"`          // 讀取失敗後的重試次數
+	TemperatureRegister uint16          `json:"temperature_register"` // 溫度寄存器地址，0 為未配置
+	Compensated         bool            `json:"compensated"`          // 是否做溫度補償
+	ReadRequests        int             `json:"read_requests"`        // 每個讀取週期的 Modbus 請求數，合併讀取時少於通道數
+	RawData             bool            `json:"raw_data"`             // 讀數是否保留原始寄存器數據
+
+	// 讀數緩衝區
+	QueueSize      int            `json:"queue_size"`      // 緩衝區中的讀數數量
+	QueueCapacity  int            `json:"queue_capacity"`  // 緩衝區容量
+	QueuePublished int64          `json:"queue_published"` // 放入緩衝區的讀數總數
+	QueueDropped   int64          `json:"queue_dropped"`   // 按溢出策略丟棄的讀數總數
+	QueueBlocked   int64          `json:"queue_blocked"`   // block 策略下等待消費端的次數
+	OverflowPolicy OverflowPolicy `json:"overflow_policy"` // 緩衝區已滿時的處理方式
+	QueueLag       time.Duration  `json:"-"`               // 緩衝區中最舊讀數已等待的時間
+
+	// 通信計數器
+	CommRequests      int64         `json:"comm_requests"`       // Modbus 請求總數（含重試）
+	CommSuccesses     int64         `json:"comm_successes"`      // 成功的請求
+	CommTimeouts      int64         `json:"comm_timeouts"`       // 設備無響應的請求
+	CommCRCErrors     int64         `json:"comm_crc_errors"`     // 響應校驗錯誤的請求
+	CommFramingErrors int64         `json:"comm_framing_errors"` // 幀格式錯誤的請求
+	CommExceptions    int64         `json:"comm_exceptions"`     // 設備返回異常響應的請求
+	CommConnErrors    int64         `json:"comm_conn_errors"`    // 連接錯誤的請求
+	CommSuccessRate   float64       `json:"comm_success_rate"`   // 請求成功率 (%)
+	CommAvgLatency    time.Duration `json:"-"`                   // 成功請求的平均響應延遲
+	CommP95Latency    time.Duration `json:"-"`                   // 成功請求的 P95 響應延遲
+
+	LastReading *PressureReading `json:"last_reading,omitempty"` // 最近一次讀數，尚未讀取時為空
+}
+
+// statusJSON Status 的 JSON 形式，時長以毫秒表示
+type statusJSON struct {
+	plainStatus
+	UptimeMs         float64 `json:"uptime_ms"`
+	LatencyBudgetMs  float64 `json:"latency_budget_ms"`
+	QueueLagMs       float64 `json:"queue_lag_ms"`
+	CommAvgLatencyMs float64 `json:"comm_avg_latency_ms"`
+	CommP95LatencyMs float64 `json:"comm_p95_latency_ms"`
+}
+
+// plainStatus 去掉 MarshalJSON 方法的 Status，避免遞歸
+type plainStatus Status
+
+// MarshalJSON 實現 json.Marshaler 接口
+func (s Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(statusJSON{
+		plainStatus:      plainStatus(s),
+		UptimeMs:         durationMs(s.Uptime),
+		LatencyBudgetMs:  durationMs(s.LatencyBudget),
+		QueueLagMs:       durationMs(s.QueueLag),
+		CommAvgLatencyMs: durationMs(s.CommAvgLatency),
+		CommP95LatencyMs: durationMs(s.CommP95Latency),
+	})
+}
+
+// UnmarshalJSON 實現 json.Unmarshaler 接口
+func (s *Status) UnmarshalJSON(data []byte) error {
+	var sj statusJSON
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return err
+	}
+	*s = Status(sj.plainStatus)
+	s.Uptime = msDuration(sj.UptimeMs)
+	s.LatencyBudget = msDuration(sj.LatencyBudgetMs)
+	s.QueueLag = msDuration(sj.QueueLagMs)
+	s.CommAvgLatency = msDuration(sj.CommAvgLatencyMs)
+	s.CommP95Latency = msDuration(sj.CommP95LatencyMs)
+	return nil
+}
+
+// durationMs 將時長轉換為毫秒
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// msDuration 將毫秒轉換為時長
+func msDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// GetStatus 獲取設備狀態
+func (pm *PressureMeter) GetStatus() Status {
+	queue := pm.ChannelStats()
+	comm := pm.CommHealth()
+
+	pm.statusMu.Lock()
+	status, since, model := pm.status, pm.statusSince, pm.model
+	var last *PressureReading
+	if pm.lastReading != nil {
+		reading := *pm.lastReading
+		last = &reading
+	}
+	pm.statusMu.Unlock()
+
+	return Status{
+		SchemaVersion: SchemaVersion,
+		Running:       pm.running,
+		Transport:     pm.transport,
+		Endpoint:      pm.endpoint,
+		SlaveID:       pm.slaveID,
+		Connected:     pm.IsConnected(),
+		DeviceStatus:  status,
+		StatusSince:   since,
+		Uptime:        time.Since(pm.createdAt),
+		Model:         model,
+
+		DataFormat:          pm.dataFormat,
+		DeviceProfile:       pm.profile.Name,
+		TimestampSource:     pm.timestamp,
+		MinPressure:         pm.minValid,
+		MaxPressure:         pm.maxValid,
+		DampingRegister:     pm.damping,
+		LatencyBudget:       pm.latencyBudget,
+		MaxRetries:          pm.maxRetries,
+		TemperatureRegister: pm.tempRegister,
+		Compensated:         pm.compensation != nil,
+		ReadRequests:        pm.ReadRequests(),
+		RawData:             pm.RawDataEnabled(),
+
+		QueueSize:      queue.Size,
+		QueueCapacity:  queue.Capacity,
+		QueuePublished: queue.Published,
+		QueueDropped:   queue.Dropped,
+		QueueBlocked:   queue.Blocked,
+		OverflowPolicy: queue.Policy,
+		QueueLag:       queue.Lag,
+
+		CommRequests:      comm.Requests,
+		CommSuccesses:     comm.Successes,
+		CommTimeouts:      comm.Timeouts,
+		CommCRCErrors:     comm.CRCErrors,
+		CommFramingErrors: comm.FramingErrors,
+		CommExceptions:    comm.Exceptions,
+		CommConnErrors:    comm.ConnectionErrors,
+		CommSuccessRate:   comm.SuccessRate,
+		CommAvgLatency:    comm.AvgLatency,
+		CommP95Latency:    comm.P95Latency,
+
+		LastReading: last,
+	}
+}
+
+// Print 將設備狀態寫入 w
+func (s Status) Print(w io.Writer) {
+	fmt.Fprintf(w, "   連接: %s %s 站點 %d，%s (自 %s)，已連接 %v，連續讀取 %v，運行 %v\n",
+		s.Transport, s.Endpoint, s.SlaveID, s.DeviceStatus, s.StatusSince.Format("2006-01-02 15:04:05"),
+		s.Connected, s.Running, s.Uptime.Round(time.Second))
+	if s.Model != nil {
+		fmt.Fprintf(w, "   型號: %s\n", s.Model)
+	}
+	fmt.Fprintf(w, "   配置: 格式 %s，配置檔 %s，時間戳 %s，有效範圍 %.2f ~ %.2f Pa，重試 %d 次\n",
+		s.DataFormat, s.DeviceProfile, s.TimestampSource, s.MinPressure, s.MaxPressure, s.MaxRetries)
+	if s.DampingRegister != 0 || s.TemperatureRegister != 0 || s.LatencyBudget > 0 {
+		fmt.Fprintf(w, "   寄存器: 阻尼 0x%04X，溫度 0x%04X (補償 %v)，每週期 %d 個請求，延遲預算 %v\n",
+			s.DampingRegister, s.TemperatureRegister, s.Compensated, s.ReadRequests, s.LatencyBudget)
+	}
+	fmt.Fprintf(w, "   緩衝區: %d/%d，已放入 %d，丟棄 %d，等待 %d (%s)，落後 %v\n",
+		s.QueueSize, s.QueueCapacity, s.QueuePublished, s.QueueDropped, s.QueueBlocked, s.OverflowPolicy,
+		s.QueueLag.Round(time.Millisecond))
+	fmt.Fprintf(w, "   通信: 請求 %d，成功 %d (%.1f%%)，超時 %d，校驗錯誤 %d，幀錯誤 %d，異常響應 %d，連接錯誤 %d\n",
+		s.CommRequests, s.CommSuccesses, s.CommSuccessRate, s.CommTimeouts, s.CommCRCErrors,
+		s.CommFramingErrors, s.CommExceptions, s.CommConnErrors)
+	if s.CommSuccesses > 0 {
+		fmt.Fprintf(w, "   響應延遲: 平均 %v，P95 %v\n", s.CommAvgLatency.Round(time.Microsecond), s.CommP95Latency.Round(time.Microsecond))
+	}
+}
+
+// Status 返回設備狀態
+//
+// 打開連接時為 connecting；連接打開或讀取成功後為 running；設備返回錯誤、超時或數據無效時為 error；
+// 連接錯誤、降級模式下未連上設備或 Close 後為 disconnected。
+func (pm *PressureMeter) Status() DeviceStatus {
+	pm.statusMu.Lock()
+	defer pm.statusMu.Unlock()
+	return pm.status
+}
+
+// StatusSince 返回進入當前狀態的時間
+func (pm *PressureMeter) StatusSince() time.Time {
+	pm.statusMu.Lock()
+	defer pm.statusMu.Unlock()
+	return pm.statusSince
+}
+
+// GetEvents 獲取事件通道，收到設備連接、斷開和狀態變化事件（EventStatusChanged 的數據為 StatusChange）
+//
+// 通道已滿時丟棄最舊的事件。
+func (pm *PressureMeter) GetEvents() <-chan Event {
+	return pm.events
+}
+
+// SetEventBus 設置事件總線，除 GetEvents 的事件外還發布每個讀數的 EventReadingReceived 或 EventReadingError（數據為 PressureReading）
+func (pm *PressureMeter) SetEventBus(bus *EventBus) *PressureMeter {
+	pm.statusMu.Lock()
+	defer pm.statusMu.Unlock()
+	pm.bus = bus
+	return pm
+}
+
+// eventBus 返回設置的事件總線
+func (pm *PressureMeter) eventBus() *EventBus {
+	pm.statusMu.Lock()
+	defer pm.statusMu.Unlock()
+	return pm.bus
+}
+
+// updateStatus 按讀數結果更新設備狀態，再將讀數發布到事件總線
+func (pm *PressureMeter) updateStatus(reading PressureReading) {
+	event := Event{Type: EventReadingReceived, Timestamp: reading.Timestamp, Source: pm.endpoint, SlaveID: reading.SlaveID, Data: reading}
+	if !reading.Valid {
+		event.Type = EventReadingError
+		event.Message = reading.Error
+	}
+
+	pm.statusMu.Lock()
+	pm.lastReading = &reading
+	pm.statusMu.Unlock()
+
+	switch {
+	case reading.Valid:
+		pm.setStatus(StatusRunning, "讀取成功")
+	case reading.ErrorCode == ErrConnection:
+		pm.setStatus(StatusDisconnected, reading.Error)
+	default:
+		pm.setStatus(StatusError, reading.Error)
+	}
+	pm.eventBus().Publish(event)
+}
+
+// setStatus 切換設備狀態，狀態變化時記錄日誌並發出 EventStatusChanged 事件
+func (pm *PressureMeter) setStatus(status DeviceStatus, reason string) {
+	pm.statusMu.Lock()
+	from := pm.status
+	if from == status {
+		pm.statusMu.Unlock()
+		return
+	}
+	now := time.Now()
+	pm.status = status
+	pm.statusSince = now
+	pm.statusMu.Unlock()
+
+	pm.logger.Printf("設備 %s 狀態: %s → %s (%s)", pm.endpoint, from, status, reason)
+
+	// 連接打開或斷開時另外發出連接事件
+	wasConnected := from == StatusRunning || from == StatusError
+	isConnected := status == StatusRunning || status == StatusError
+	switch {
+	case !wasConnected && isConnected:
+		pm.emit(Event{Type: EventDeviceConnected, Timestamp: now, Source: pm.endpoint, SlaveID: pm.slaveID,
+			Message: EventDeviceConnected.Description()})
+	case wasConnected && status == StatusDisconnected:
+		pm.emit(Event{Type: EventDeviceDisconnected, Timestamp: now, Source: pm.endpoint, SlaveID: pm.slaveID,
+			Message: fmt.Sprintf("%s: %s", EventDeviceDisconnected.Description(), reason)})
+	}
+	pm.emit(Event{
+		Type:      EventStatusChanged,
+		Timestamp: now,
+		Source:    pm.endpoint,
+		SlaveID:   pm.slaveID,
+		Message:   fmt.Sprintf("%s: %s → %s", EventStatusChanged.Description(), from, status),
+		Data:      StatusChange{From: from, To: status, Reason: reason},
+	})
+}
+
+// emit 將事件放入事件通道並發布到事件總線，通道已滿時丟棄最舊的事件
+func (pm *PressureMeter) emit(event Event) {
+	pm.eventBus().Publish(event)
+	select {
+	case pm.events <- event:
+		return
+	default:
+	}
+	select {
+	case <-pm.events:
+	default:
+	}
+	select {
+	case pm.events <- event:
+	default:
+	}
+}
@@ -0,0 +1,405 @@
+// pressure/diagnostics.go - Modbus 串行線路診斷（功能碼 0x08）
+package pressure
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/goburrow/modbus"
+	"go.bug.st/serial"
+)
+
+// 診斷功能碼及其子功能
+const (
+	FunctionDiagnostics = 0x08
+
+	DiagReturnQueryData          uint16 = 0x0000 // 回顯查詢數據
+	DiagClearCounters            uint16 = 0x000A // 清除計數器
+	DiagBusMessageCount          uint16 = 0x000B // 總線報文計數
+	DiagBusCommErrorCount        uint16 = 0x000C // 總線通信錯誤（CRC）計數
+	DiagBusExceptionErrorCount   uint16 = 0x000D // 異常響應計數
+	DiagSlaveMessageCount        uint16 = 0x000E // 發給本站的報文計數
+	DiagSlaveNoResponseCount     uint16 = 0x000F // 本站未響應計數
+	DiagSlaveNAKCount            uint16 = 0x0010 // 本站 NAK 計數
+	DiagSlaveBusyCount           uint16 = 0x0011 // 本站忙計數
+	DiagBusCharacterOverrunCount uint16 = 0x0012 // 字符溢出計數
+)
+
+// diagEchoPattern 回顯測試使用的數據，交替位便於發現位錯誤
+const diagEchoPattern uint16 = 0xA55A
+
+// 診斷計數器名稱
+var diagCounters = []struct {
+	subFunction uint16
+	name        string
+}{
+	{DiagBusMessageCount, "總線報文"},
+	{DiagBusCommErrorCount, "總線 CRC 錯誤"},
+	{DiagBusExceptionErrorCount, "異常響應"},
+	{DiagSlaveMessageCount, "本站報文"},
+	{DiagSlaveNoResponseCount, "本站未響應"},
+	{DiagSlaveNAKCount, "本站 NAK"},
+	{DiagSlaveBusyCount, "本站忙"},
+	{DiagBusCharacterOverrunCount, "字符溢出"},
+}
+
+// errCRCMismatch 收到響應但校驗失敗
+var errCRCMismatch = errors.New("響應 CRC 校驗失敗")
+
+// errNoResponse 在超時時間內沒有收到任何字節
+var errNoResponse = errors.New("無響應")
+
+// DiagnosticCounter 單個診斷計數器的讀取結果
+type DiagnosticCounter struct {
+	Name        string `json:"name"`            // 名稱
+	SubFunction uint16 `json:"sub_function"`    // 子功能碼
+	Value       uint16 `json:"value"`           // 計數值
+	Error       string `json:"error,omitempty"` // 讀取失敗原因
+}
+
+// SerialDiagnostics 串行線路診斷結果
+type SerialDiagnostics struct {
+	Device   string `json:"device"`    // 串口
+	SlaveID  byte   `json:"slave_id"`  // 站點號
+	BaudRate int    `json:"baud_rate"` // 波特率
+	Parity   string `json:"parity"`    // 校驗位
+	StopBits int    `json:"stop_bits"` // 停止位
+
+	EchoOK      bool          `json:"echo_ok"`              // 回顯測試是否通過
+	EchoLatency time.Duration `json:"echo_latency"`         // 回顯往返時間
+	EchoError   string        `json:"echo_error,omitempty"` // 回顯失敗原因
+	Supported   bool          `json:"supported"`            // 設備是否支援功能碼 0x08
+
+	Counters []DiagnosticCounter `json:"counters,omitempty"` // 診斷計數器
+
+	ReadOK      bool          `json:"read_ok"`              // 壓力寄存器讀取是否成功
+	ReadLatency time.Duration `json:"read_latency"`         // 讀取往返時間
+	ReadError   string        `json:"read_error,omitempty"` // 讀取失敗原因
+
+	Healthy    bool   `json:"healthy"`    // 綜合判斷是否正常
+	Conclusion string `json:"conclusion"` // 診斷結論
+}
+
+// RunSerialDiagnostics 對配置中的設備執行串行線路診斷
+//
+// 診斷直接打開串口，調用前需確保沒有其他程序（包括 PressureMeter）佔用該串口。
+func RunSerialDiagnostics(config Config) (*SerialDiagnostics, error) {
+	if config.IsTCP() || config.IsRTUOverTCP() {
+		return nil, fmt.Errorf("串行線路診斷只支援本地串口的 RTU 傳輸")
+	}
+	profile, err := ResolveDeviceProfile(config)
+	if err != nil {
+		return nil, err
+	}
+
+	parity := strings.ToUpper(config.Parity)
+	if parity == "" {
+		parity = DefaultParity
+	}
+	timeout := config.ResponseTimeout
+	if timeout <= 0 {
+		timeout = DefaultResponseTimeout
+	}
+
+	result := &SerialDiagnostics{
+		Device:   config.Device,
+		SlaveID:  config.SlaveID,
+		BaudRate: config.SerialBaudRate(),
+		Parity:   parity,
+		StopBits: config.SerialStopBits(),
+	}
+
+	line, err := openDiagLine(config.Device, config.SerialBaudRate(), parity, config.SerialStopBits(), config.SlaveID, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer line.port.Close()
+
+	// 回顯測試
+	start := time.Now()
+	echo, echoErr := line.diagnostic(DiagReturnQueryData, diagEchoPattern)
+	result.EchoLatency = time.Since(start)
+	var modbusErr *modbus.ModbusError
+	switch {
+	case echoErr == nil && echo == diagEchoPattern:
+		result.EchoOK = true
+		result.Supported = true
+	case echoErr == nil:
+		result.Supported = true
+		result.EchoError = fmt.Sprintf("回顯數據不一致: 發送 %04X，收到 %04X", diagEchoPattern, echo)
+	case errors.As(echoErr, &modbusErr):
+		result.EchoError = fmt.Sprintf("設備不支援診斷功能: %v", echoErr)
+	default:
+		result.EchoError = echoErr.Error()
+	}
+
+	// 計數器
+	if result.Supported {
+		for _, counter := range diagCounters {
+			value, err := line.diagnostic(counter.subFunction, 0)
+			entry := DiagnosticCounter{Name: counter.name, SubFunction: counter.subFunction, Value: value}
+			if err != nil {
+				entry.Error = err.Error()
+			}
+			result.Counters = append(result.Counters, entry)
+		}
+	}
+
+	// 按設備配置檔讀取壓力寄存器
+	start = time.Now()
+	_, readErr := line.readRegisters(profile.Function, profile.Register, profile.Count)
+	result.ReadLatency = time.Since(start)
+	if readErr == nil {
+		result.ReadOK = true
+	} else {
+		result.ReadError = readErr.Error()
+	}
+
+	result.Healthy, result.Conclusion = concludeDiagnostics(result, echoErr, readErr)
+	return result, nil
+}
+
+// CounterValue 返回指定子功能的計數值
+func (d *SerialDiagnostics) CounterValue(subFunction uint16) (uint16, bool) {
+	for _, counter := range d.Counters {
+		if counter.SubFunction == subFunction && counter.Error == "" {
+			return counter.Value, true
+		}
+	}
+	return 0, false
+}
+
+// Print 將診斷結果寫入 w
+func (d *SerialDiagnostics) Print(w io.Writer) {
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+	fmt.Fprintf(w, "🩺 串行線路診斷: %s 站點 %d (%d %s)\n", d.Device, d.SlaveID, d.BaudRate, charFormat(d.Parity, d.StopBits))
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+
+	if d.EchoOK {
+		fmt.Fprintf(w, "✅ 回顯測試 (0x08/0000): 通過，往返 %v\n", d.EchoLatency.Round(time.Millisecond))
+	} else {
+		fmt.Fprintf(w, "❌ 回顯測試 (0x08/0000): %s\n", d.EchoError)
+	}
+
+	if len(d.Counters) > 0 {
+		fmt.Fprintln(w, "\n📊 診斷計數器:")
+		for _, counter := range d.Counters {
+			if counter.Error != "" {
+				fmt.Fprintf(w, "   %-14s (0x%04X): 讀取失敗 - %s\n", counter.Name, counter.SubFunction, counter.Error)
+			} else {
+				fmt.Fprintf(w, "   %-14s (0x%04X): %d\n", counter.Name, counter.SubFunction, counter.Value)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	if d.ReadOK {
+		fmt.Fprintf(w, "✅ 壓力寄存器讀取: 成功，往返 %v\n", d.ReadLatency.Round(time.Millisecond))
+	} else {
+		fmt.Fprintf(w, "❌ 壓力寄存器讀取: %s\n", d.ReadError)
+	}
+
+	fmt.Fprintln(w)
+	if d.Healthy {
+		fmt.Fprintf(w, "💡 結論: %s\n", d.Conclusion)
+	} else {
+		fmt.Fprintf(w, "⚠️  結論: %s\n", d.Conclusion)
+	}
+	fmt.Fprintln(w, strings.Repeat("=", 52))
+}
+
+// concludeDiagnostics 根據各項結果區分線路問題和設備故障
+func concludeDiagnostics(d *SerialDiagnostics, echoErr, readErr error) (bool, string) {
+	switch {
+	case errors.Is(echoErr, errNoResponse) && errors.Is(readErr, errNoResponse):
+		return false, "設備完全無響應：檢查 A/B 接線極性、終端電阻、供電，以及站點號和波特率/校驗位"
+	case errors.Is(echoErr, errCRCMismatch) || errors.Is(readErr, errCRCMismatch):
+		return false, "收到響應但 CRC 錯誤：線路干擾、接地不良，或波特率/校驗位不匹配"
+	case d.Supported && !d.EchoOK:
+		return false, "回顯數據不一致：線路存在位錯誤，檢查屏蔽和接地"
+	case !d.ReadOK && (d.Supported || isModbusException(readErr)):
+		return false, "線路通信正常，但讀取壓力寄存器失敗：設備故障或寄存器配置錯誤"
+	case !d.ReadOK:
+		return false, "讀取壓力寄存器失敗：" + d.ReadError
+	}
+
+	if crcErrors, ok := d.CounterValue(DiagBusCommErrorCount); ok && crcErrors > 0 {
+		return true, fmt.Sprintf("通信正常，但設備記錄了 %d 次 CRC 錯誤：線路質量不佳，建議檢查終端電阻和屏蔽", crcErrors)
+	}
+	if !d.Supported {
+		return true, "通信正常（設備不支援診斷計數器）"
+	}
+	return true, "通信正常"
+}
+
+// isModbusException 檢查錯誤是否為 Modbus 異常響應
+func isModbusException(err error) bool {
+	var modbusErr *modbus.ModbusError
+	return errors.As(err, &modbusErr)
+}
+
+// serialParity 將校驗位字母轉為串口庫的常量
+func serialParity(parity string) serial.Parity {
+	switch parity {
+	case "E":
+		return serial.EvenParity
+	case "O":
+		return serial.OddParity
+	default:
+		return serial.NoParity
+	}
+}
+
+// serialStopBits 將停止位數轉為串口庫的常量
+func serialStopBits(stopBits int) serial.StopBits {
+	if stopBits == 2 {
+		return serial.TwoStopBits
+	}
+	return serial.OneStopBit
+}
+
+// diagLine 直接在串口上收發 Modbus RTU 幀
+type diagLine struct {
+	port    serial.Port
+	slaveID byte
+	timeout time.Duration
+}
+
+// openDiagLine 以指定波特率直接打開串口
+func openDiagLine(device string, baudRate int, parity string, stopBits int, slaveID byte, timeout time.Duration) (*diagLine, error) {
+	mode := &serial.Mode{
+		BaudRate: baudRate,
+		DataBits: 8,
+		Parity:   serialParity(parity),
+		StopBits: serialStopBits(stopBits),
+	}
+	port, err := serial.Open(device, mode)
+	if err != nil {
+		return nil, fmt.Errorf("打開串口 %s 失敗: %v", device, err)
+	}
+
+	if err := port.SetReadTimeout(timeout); err != nil {
+		port.Close()
+		return nil, fmt.Errorf("設置串口超時失敗: %v", err)
+	}
+	return &diagLine{port: port, slaveID: slaveID, timeout: timeout}, nil
+}
+
+// diagnostic 發送功能碼 0x08 請求並返回響應數據字段
+func (dl *diagLine) diagnostic(subFunction, data uint16) (uint16, error) {
+	request := make([]byte, 4)
+	binary.BigEndian.PutUint16(request[0:], subFunction)
+	binary.BigEndian.PutUint16(request[2:], data)
+
+	response, err := dl.transact(FunctionDiagnostics, request, len(request))
+	if err != nil {
+		return 0, err
+	}
+	if binary.BigEndian.Uint16(response[0:]) != subFunction {
+		return 0, fmt.Errorf("響應子功能碼不一致: %04X", binary.BigEndian.Uint16(response[0:]))
+	}
+	return binary.BigEndian.Uint16(response[2:]), nil
+}
+
+// readRegisters 發送功能碼 0x03（保持寄存器）或 0x04（輸入寄存器）請求
+func (dl *diagLine) readRegisters(function byte, address, quantity uint16) ([]byte, error) {
+	request := make([]byte, 4)
+	binary.BigEndian.PutUint16(request[0:], address)
+	binary.BigEndian.PutUint16(request[2:], quantity)
+
+	response, err := dl.transact(function, request, 1+int(quantity)*2)
+	if err != nil {
+		return nil, err
+	}
+	return response[1:], nil
+}
+
+// send 編碼並發送請求，不等待響應
+func (dl *diagLine) send(function byte, data []byte) (*modbus.RTUClientHandler, []byte, error) {
+	packager := modbus.NewRTUClientHandler("")
+	packager.SlaveId = dl.slaveID
+
+	request, err := packager.Encode(&modbus.ProtocolDataUnit{FunctionCode: function, Data: data})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dl.port.ResetInputBuffer()
+	if _, err := dl.port.Write(request); err != nil {
+		return nil, nil, fmt.Errorf("發送失敗: %v", err)
+	}
+	return packager, request, nil
+}
+
+// transact 發送請求並讀取固定長度的響應，返回響應 PDU 的數據部分
+func (dl *diagLine) transact(function byte, data []byte, responseDataLen int) ([]byte, error) {
+	// 站點號 + 功能碼 + 數據 + CRC
+	response, err := dl.exchange(function, data, func([]byte) int { return 2 + responseDataLen + 2 })
+	if err != nil {
+		return nil, err
+	}
+	if len(response) < responseDataLen {
+		return nil, fmt.Errorf("響應數據過短: % X", response)
+	}
+	return response, nil
+}
+
+// exchange 發送請求並讀取響應，返回響應 PDU 的數據部分
+//
+// frameLen 按已收到的字節返回完整響應幀的長度，尚不能確定時返回 0；異常響應固定 5 字節。
+func (dl *diagLine) exchange(function byte, data []byte, frameLen func(frame []byte) int) ([]byte, error) {
+	packager, request, err := dl.send(function, data)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := 0
+	var buf bytes.Buffer
+	chunk := make([]byte, 256)
+	deadline := time.Now().Add(dl.timeout)
+	for (expected == 0 || buf.Len() < expected) && time.Now().Before(deadline) {
+		n, err := dl.port.Read(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("讀取失敗: %v", err)
+		}
+		if n == 0 {
+			break // 讀取超時
+		}
+		buf.Write(chunk[:n])
+		if buf.Len() >= 5 && buf.Bytes()[1] == function|0x80 {
+			expected = 5
+		} else {
+			expected = frameLen(buf.Bytes())
+		}
+	}
+
+	response := buf.Bytes()
+	if len(response) == 0 {
+		return nil, errNoResponse
+	}
+	if expected == 0 || len(response) < expected {
+		return nil, fmt.Errorf("響應不完整: 期望 %d 字節，收到 % X", expected, response)
+	}
+	response = response[:expected]
+
+	if err := packager.Verify(request, response); err != nil {
+		return nil, fmt.Errorf("響應無效: %v", err)
+	}
+	pdu, err := packager.Decode(response)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errCRCMismatch, err)
+	}
+	if pdu.FunctionCode != function {
+		exception := &modbus.ModbusError{FunctionCode: pdu.FunctionCode}
+		if len(pdu.Data) > 0 {
+			exception.ExceptionCode = pdu.Data[0]
+		}
+		return nil, exception
+	}
+	return pdu.Data, nil
+}
@@ -0,0 +1,182 @@
+// pressure/differential.go - 虛擬壓差通道：兩台儀表讀數之差，如房間對走廊的壓差驗證
+package pressure
+
+import (
+	"fmt"
+	"time"
+)
+
+// ChannelRef 設備管理器中的一台儀表：設備名稱和站點號
+type ChannelRef struct {
+	Device  string `json:"device" yaml:"device"`     // 設備管理器中的設備名稱
+	SlaveID byte   `json:"slave_id" yaml:"slave_id"` // 站點號
+}
+
+// String 返回 "設備/站點號"
+func (ref ChannelRef) String() string {
+	return fmt.Sprintf("%s/%d", ref.Device, ref.SlaveID)
+}
+
+// DifferentialChannel 虛擬壓差通道，讀數為 Plus 減 Minus
+//
+// 兩台儀表各有一個新讀數且時間差不超過 MaxSkew 時產生一個讀數，Device 為通道名稱，
+// SlaveID 為合成站點號。任一讀數無效時壓差讀數也無效，錯誤信息指明來源。
+type DifferentialChannel struct {
+	Name    string        `json:"name"`               // 通道名稱，不能與設備名稱重複
+	Plus    ChannelRef    `json:"plus"`               // 被減數（如房間）
+	Minus   ChannelRef    `json:"minus"`              // 減數（如走廊）
+	SlaveID byte          `json:"slave_id,omitempty"` // 合成站點號 (248-255)，0 為自動分配
+	MaxSkew time.Duration `json:"max_skew,omitempty"` // 兩個讀數允許的最大時間差，0 為兩台設備讀取間隔的較大者
+}
+
+// differential 設備管理器中的虛擬壓差通道及兩側最近的讀數
+type differential struct {
+	spec                DifferentialChannel
+	plus, minus         *PressureReading
+	usedPlus, usedMinus time.Time // 已用於產生壓差讀數的兩側讀數時間，每對讀數只使用一次
+}
+
+// AddDifferential 添加虛擬壓差通道，兩台儀表需已添加到設備管理器
+func (m *Manager) AddDifferential(channel DifferentialChannel) error {
+	if channel.Name == "" {
+		return fmt.Errorf("壓差通道缺少名稱")
+	}
+	if channel.Plus == channel.Minus {
+		return fmt.Errorf("壓差通道 %s 的兩側是同一台儀表 %s", channel.Name, channel.Plus)
+	}
+	if channel.SlaveID != 0 && !IsReservedSlaveID(channel.SlaveID) {
+		return fmt.Errorf("壓差通道 %s 的合成站點號 %d 必須在 %d-255 之間，避免與儀表重複",
+			channel.Name, channel.SlaveID, ModbusReservedMinID)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.devices[channel.Name]; ok {
+		return fmt.Errorf("壓差通道名稱 %s 與設備重複", channel.Name)
+	}
+	used := make(map[byte]bool)
+	for _, d := range m.differentials {
+		if d.spec.Name == channel.Name {
+			return fmt.Errorf("壓差通道 %s 已存在", channel.Name)
+		}
+		used[d.spec.SlaveID] = true
+	}
+
+	var skew time.Duration
+	for _, ref := range []ChannelRef{channel.Plus, channel.Minus} {
+		md, ok := m.devices[ref.Device]
+		if !ok {
+			return fmt.Errorf("壓差通道 %s: 未知的設備 %s", channel.Name, ref.Device)
+		}
+		if !containsSlaveID(md.spec.SlaveIDs, ref.SlaveID) {
+			return fmt.Errorf("壓差通道 %s: 設備 %s 沒有讀取站點 %d", channel.Name, ref.Device, ref.SlaveID)
+		}
+		if md.spec.Interval > skew {
+			skew = md.spec.Interval
+		}
+	}
+	if channel.MaxSkew <= 0 {
+		channel.MaxSkew = skew
+	}
+
+	if channel.SlaveID == 0 {
+		for id := ModbusReservedMinID; id <= 255; id++ {
+			if !used[byte(id)] {
+				channel.SlaveID = byte(id)
+				break
+			}
+		}
+		if channel.SlaveID == 0 {
+			return fmt.Errorf("壓差通道 %s: 合成站點號已用完", channel.Name)
+		}
+	} else if used[channel.SlaveID] {
+		return fmt.Errorf("壓差通道 %s 的合成站點號 %d 已被使用", channel.Name, channel.SlaveID)
+	}
+
+	m.differentials = append(m.differentials, &differential{spec: channel})
+	return nil
+}
+
+// Differentials 返回已添加的虛擬壓差通道（含自動分配的站點號和時間差）
+func (m *Manager) Differentials() []DifferentialChannel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	channels := make([]DifferentialChannel, 0, len(m.differentials))
+	for _, d := range m.differentials {
+		channels = append(channels, d.spec)
+	}
+	return channels
+}
+
+// derive 記錄儀表讀數，返回因此產生的壓差讀數
+func (m *Manager) derive(device string, reading PressureReading) []ManagedReading {
+	ref := ChannelRef{Device: device, SlaveID: reading.SlaveID}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var derived []ManagedReading
+	for _, d := range m.differentials {
+		switch ref {
+		case d.spec.Plus:
+			d.plus = &reading
+		case d.spec.Minus:
+			d.minus = &reading
+		default:
+			continue
+		}
+		if result, ok := d.pair(); ok {
+			derived = append(derived, ManagedReading{Device: d.spec.Name, PressureReading: result})
+		}
+	}
+	return derived
+}
+
+// pair 兩側都有未使用過且時間差在允許範圍內的讀數時返回壓差讀數
+func (d *differential) pair() (PressureReading, bool) {
+	plus, minus := d.plus, d.minus
+	if plus == nil || minus == nil || !plus.Timestamp.After(d.usedPlus) || !minus.Timestamp.After(d.usedMinus) {
+		return PressureReading{}, false
+	}
+	skew := plus.Timestamp.Sub(minus.Timestamp)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > d.spec.MaxSkew {
+		return PressureReading{}, false
+	}
+	d.usedPlus, d.usedMinus = plus.Timestamp, minus.Timestamp
+
+	result := PressureReading{
+		Timestamp: plus.Timestamp,
+		SlaveID:   d.spec.SlaveID,
+	}
+	if minus.Timestamp.After(plus.Timestamp) {
+		result.Timestamp = minus.Timestamp
+	}
+	if plus.CycleTime != nil && minus.CycleTime != nil && plus.Cycle == minus.Cycle {
+		result.Cycle, result.CycleTime = plus.Cycle, plus.CycleTime
+	}
+
+	switch {
+	case !plus.Valid:
+		result.Error = fmt.Sprintf("%s: %s", d.spec.Plus, plus.Error)
+		result.ErrorCode = plus.ErrorCode
+	case !minus.Valid:
+		result.Error = fmt.Sprintf("%s: %s", d.spec.Minus, minus.Error)
+		result.ErrorCode = minus.ErrorCode
+	default:
+		result.Pressure = plus.Pressure - minus.Pressure
+		result.Valid = true
+	}
+	return result, true
+}
+
+// containsSlaveID 站點號是否在列表中
+func containsSlaveID(ids []byte, id byte) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
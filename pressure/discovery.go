@@ -0,0 +1,284 @@
+// pressure/discovery.go - 後台發現：監測期間按間隔快速掃描同一總線，發現新接入和已移除的儀表，無需重啟程式
+package pressure
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// 後台發現默認值
+const (
+	DefaultDiscoveryInterval = time.Minute // 兩輪快速掃描的間隔
+	DefaultDiscoveryMisses   = 3           // 已知儀表連續多少輪無響應後視為移除
+)
+
+// DiscoveryConfig 後台發現配置
+type DiscoveryConfig struct {
+	Interval time.Duration // 兩輪快速掃描的間隔，0 為 DefaultDiscoveryInterval
+	SlaveIDs []byte        // 探測的站點號，為空則為默認和常用站點號
+	Misses   int           // 已知儀表連續多少輪無響應後視為移除，0 為 DefaultDiscoveryMisses
+}
+
+// Discovery 後台發現協程
+//
+// 每輪依次探測配置的站點號和正在讀取的站點號，每次探測與正常讀取串行執行，
+// 無響應的站點號會讓下一次讀取最多延後一個響應超時，因此間隔應遠大於讀取間隔。
+// 新響應的站點號發布 EventDeviceFound，已知的站點號連續 Misses 輪無響應後發布 EventDeviceDisconnected。
+// 用於 BusPoller 時新發現的儀表自動加入輪詢，由發現加入的儀表移除後停止輪詢。
+type Discovery struct {
+	meter  *PressureMeter
+	config DiscoveryConfig
+	polled func() []byte // 正在讀取的站點號，為空則沒有
+	add    func(byte)    // 新發現的儀表加入讀取，為空則只發布事件
+	remove func(byte)    // 停止讀取由發現加入的儀表
+
+	mu      sync.Mutex
+	present map[byte]bool // 當前在線的站點號
+	misses  map[byte]int  // 已知站點號連續無響應的輪數
+	added   map[byte]bool // 由發現加入讀取的站點號
+
+	stopCh   chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// newDiscovery 創建後台發現，正在讀取的站點號視為已知
+func newDiscovery(pm *PressureMeter, config DiscoveryConfig, polled func() []byte, add, remove func(byte)) *Discovery {
+	if config.Interval <= 0 {
+		config.Interval = DefaultDiscoveryInterval
+	}
+	if config.Misses <= 0 {
+		config.Misses = DefaultDiscoveryMisses
+	}
+	if len(config.SlaveIDs) == 0 {
+		config.SlaveIDs = quickSlaveIDs()
+	}
+
+	d := &Discovery{
+		meter:   pm,
+		config:  config,
+		polled:  polled,
+		add:     add,
+		remove:  remove,
+		present: make(map[byte]bool),
+		misses:  make(map[byte]int),
+		added:   make(map[byte]bool),
+		stopCh:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if polled != nil {
+		for _, id := range polled() {
+			d.present[id] = true
+		}
+	}
+	return d
+}
+
+// StartDiscovery 在監測的同時按間隔快速掃描本儀表所在的總線，只發布事件，不讀取新發現的儀表
+//
+// 本儀表的站點號不在探測範圍內，其連接狀態由 GetEvents 的狀態事件反映。調用 Discovery.Stop 停止。
+// 傳輸層不能切換站點號（自定義 ModbusTransport）時返回錯誤。
+func (pm *PressureMeter) StartDiscovery(config DiscoveryConfig) (*Discovery, error) {
+	if err := pm.checkSlaveSwitching(); err != nil {
+		return nil, fmt.Errorf("無法啟動後台發現: %v", err)
+	}
+	candidates := config.SlaveIDs
+	if len(candidates) == 0 {
+		candidates = quickSlaveIDs()
+	}
+	config.SlaveIDs = nil
+	for _, id := range candidates {
+		if id != pm.slaveID {
+			config.SlaveIDs = append(config.SlaveIDs, id)
+		}
+	}
+
+	d := newDiscovery(pm, config, nil, nil, nil)
+	go d.run()
+	return d, nil
+}
+
+// run 按間隔執行快速掃描，直到 Stop
+func (d *Discovery) run() {
+	defer close(d.done)
+	d.meter.logger.Printf("🔭 後台發現: 每 %v 探測站點 %v", d.config.Interval, d.config.SlaveIDs)
+
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stopCh:
+			return
+		case <-ticker.C:
+			d.Scan()
+		}
+	}
+}
+
+// Stop 停止後台發現並等待正在進行的探測結束
+func (d *Discovery) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stopCh)
+	})
+	<-d.done
+}
+
+// Present 返回當前在線的站點號
+func (d *Discovery) Present() []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	ids := make([]byte, 0, len(d.present))
+	for id := range d.present {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Scan 立即執行一輪快速掃描，返回本輪新發現和判定為移除的站點號
+//
+// 連接斷開（降級模式）時跳過本輪，重連由正常讀取負責，不計入無響應輪數。
+func (d *Discovery) Scan() (found, lost []byte) {
+	if !d.meter.IsConnected() {
+		return nil, nil
+	}
+	for _, id := range d.candidates() {
+		select {
+		case <-d.stopCh:
+			return found, lost
+		default:
+		}
+
+		responsive, err := d.meter.probeSlave(id)
+		if err != nil {
+			d.meter.logger.Printf("⚠️  後台發現: %v", err)
+			return found, lost
+		}
+		switch d.update(id, responsive) {
+		case EventDeviceFound:
+			found = append(found, id)
+			d.announce(EventDeviceFound, id, "後台發現")
+			if d.add != nil && !containsSlaveID(d.polledIDs(), id) {
+				d.mu.Lock()
+				d.added[id] = true
+				d.mu.Unlock()
+				d.add(id)
+			}
+		case EventDeviceDisconnected:
+			lost = append(lost, id)
+			d.announce(EventDeviceDisconnected, id, fmt.Sprintf("連續 %d 輪快速掃描無響應", d.config.Misses))
+			d.mu.Lock()
+			added := d.added[id]
+			delete(d.added, id)
+			d.mu.Unlock()
+			if added && d.remove != nil {
+				d.remove(id)
+			}
+		}
+	}
+	return found, lost
+}
+
+// candidates 返回本輪探測的站點號：配置的站點號和正在讀取的站點號
+func (d *Discovery) candidates() []byte {
+	ids := append([]byte(nil), d.config.SlaveIDs...)
+	for _, id := range d.polledIDs() {
+		if !containsSlaveID(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	candidates := ids[:0]
+	for _, id := range ids {
+		if CheckPollingSlaveID(id) == nil {
+			candidates = append(candidates, id)
+		}
+	}
+	return candidates
+}
+
+// polledIDs 返回正在讀取的站點號
+func (d *Discovery) polledIDs() []byte {
+	if d.polled == nil {
+		return nil
+	}
+	return d.polled()
+}
+
+// update 記錄一次探測結果，返回狀態變化：EventDeviceFound、EventDeviceDisconnected 或 0
+func (d *Discovery) update(slaveID byte, responsive bool) EventType {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if responsive {
+		d.misses[slaveID] = 0
+		if d.present[slaveID] {
+			return 0
+		}
+		d.present[slaveID] = true
+		return EventDeviceFound
+	}
+	if !d.present[slaveID] {
+		return 0
+	}
+	d.misses[slaveID]++
+	if d.misses[slaveID] < d.config.Misses {
+		return 0
+	}
+	delete(d.present, slaveID)
+	delete(d.misses, slaveID)
+	return EventDeviceDisconnected
+}
+
+// announce 記錄日誌並發布發現或移除事件
+func (d *Discovery) announce(eventType EventType, slaveID byte, detail string) {
+	pm := d.meter
+	now := time.Now()
+	event := Event{
+		Type:      eventType,
+		Timestamp: now,
+		Source:    pm.endpoint,
+		SlaveID:   slaveID,
+		Message:   fmt.Sprintf("%s: 站點 %d %s", eventType.Description(), slaveID, detail),
+	}
+	if eventType == EventDeviceFound {
+		pm.logger.Printf("🆕 發現新儀表: %s 站點 %d", pm.endpoint, slaveID)
+		event.Data = DeviceInfo{
+			Device:     pm.endpoint,
+			SlaveID:    slaveID,
+			Responsive: true,
+			Properties: map[string]interface{}{"transport": pm.transport},
+			ScanTime:   now,
+		}
+	} else {
+		pm.logger.Printf("📴 儀表已移除: %s 站點 %d (%s)", pm.endpoint, slaveID, detail)
+	}
+	pm.emit(event)
+}
+
+// probeSlave 讀取另一個站點號的壓力寄存器確認其是否在線，不更新本儀表的讀數、狀態和通信統計
+//
+// Modbus 異常響應也表示該站點號上有設備。傳輸層不能切換站點號時返回錯誤，避免把本儀表的響應當作其他站點號。
+func (pm *PressureMeter) probeSlave(slaveID byte) (bool, error) {
+	pm.busMu.Lock()
+	defer pm.busMu.Unlock()
+
+	if err := setHandlerSlaveID(pm.handler, slaveID); err != nil {
+		return false, err
+	}
+	defer setHandlerSlaveID(pm.handler, pm.slaveID)
+	_, err := pm.readSpan(pm.plan.Load().block(channelPressure).registerSpan)
+	var modbusErr *modbus.ModbusError
+	return err == nil || errors.As(err, &modbusErr), nil
+}
+
+// checkSlaveSwitching 確認傳輸層可以切換站點號，用於輪流讀取和探測其他儀表
+func (pm *PressureMeter) checkSlaveSwitching() error {
+	pm.busMu.Lock()
+	defer pm.busMu.Unlock()
+	return setHandlerSlaveID(pm.handler, pm.slaveID)
+}
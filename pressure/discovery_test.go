@@ -0,0 +1,95 @@
+package pressure
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// foreignTransport 不能切換站點號的自定義傳輸層
+type foreignTransport struct {
+	*MockTransport
+}
+
+func TestProbeSlaveDoesNotRedirectWrites(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0, 123).SetRegisters(0x0100, 0)
+	pm := newTestMeter(t, mock, withControl)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if responsive, err := pm.probeSlave(5); err != nil || responsive {
+				t.Errorf("probeSlave(5) = %v, %v; want false, nil", responsive, err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		// 模擬儀表只響應站點 1，寫入被發到探測的站點號時會失敗
+		if err := pm.WriteRegister(0x0100, uint16(i)); err != nil {
+			t.Fatalf("WriteRegister during discovery probe: %v", err)
+		}
+	}
+	wg.Wait()
+
+	if value, _ := mock.Register(0x0100); value != 199 {
+		t.Errorf("register 0x0100 = %d, want 199", value)
+	}
+}
+
+func TestDiscoveryRejectsCustomTransport(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0, 123)
+	pm := newTestMeter(t, foreignTransport{mock})
+
+	if d, err := pm.StartDiscovery(DiscoveryConfig{Interval: time.Hour}); err == nil {
+		d.Stop()
+		t.Fatal("StartDiscovery with custom transport: want error")
+	}
+	if _, err := pm.probeSlave(5); err == nil {
+		t.Error("probeSlave with custom transport: want error")
+	}
+
+	_, err := NewBusPoller(testConfig(foreignTransport{NewMockTransport(1)}), []byte{1, 2})
+	if err == nil {
+		t.Error("NewBusPoller with custom transport and several slave IDs: want error")
+	}
+}
+
+func TestBusPollerStopsDiscoveryWithoutStart(t *testing.T) {
+	bus, err := NewBusPoller(testConfig(NewMockTransport(1).SetRegisters(0x0034, 0, 123)), []byte{1})
+	if err != nil {
+		t.Fatalf("NewBusPoller: %v", err)
+	}
+	defer bus.Close()
+
+	d, err := bus.StartDiscovery(DiscoveryConfig{Interval: time.Hour})
+	if err != nil {
+		t.Fatalf("StartDiscovery: %v", err)
+	}
+	bus.Stop()
+
+	select {
+	case <-d.done:
+	case <-time.After(time.Second):
+		t.Fatal("discovery goroutine still running after Stop")
+	}
+}
+
+func TestDiscoveryFindsAndLosesMeter(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0, 123)
+	pm := newTestMeter(t, mock, withControl)
+	d := newDiscovery(pm, DiscoveryConfig{SlaveIDs: []byte{1}, Misses: 2}, nil, nil, nil)
+
+	if found, _ := d.Scan(); len(found) != 1 || found[0] != 1 {
+		t.Fatalf("first scan found %v, want [1]", found)
+	}
+	mock.SetFailures(2, nil)
+	if _, lost := d.Scan(); len(lost) != 0 {
+		t.Fatalf("lost after one miss: %v", lost)
+	}
+	if _, lost := d.Scan(); len(lost) != 1 {
+		t.Fatalf("lost after two misses = %v, want [1]", lost)
+	}
+}
@@ -0,0 +1,116 @@
+// pressure/eventbus.go - 事件總線：設備、掃描器和監測流程的事件按類型分發給訂閱者
+package pressure
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventBus 按事件類型將事件分發給訂閱者
+//
+// 發布不會阻塞：訂閱者的通道已滿時丟棄該訂閱者最舊的事件。
+// 壓差儀、掃描器和監測流程通過各自的 SetEventBus 接入。
+type EventBus struct {
+	mu      sync.RWMutex
+	subs    map[<-chan Event]*subscription
+	closed  bool
+	dropped atomic.Int64
+}
+
+// subscription 一個訂閱者
+type subscription struct {
+	ch    chan Event
+	types map[EventType]bool // 為空表示訂閱所有類型
+}
+
+// NewEventBus 創建事件總線
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[<-chan Event]*subscription)}
+}
+
+// Subscribe 訂閱指定類型的事件，不指定類型時訂閱所有事件
+//
+// 事件總線關閉後返回已關閉的通道。
+func (eb *EventBus) Subscribe(types ...EventType) <-chan Event {
+	sub := &subscription{ch: make(chan Event, DefaultEventBufferSize)}
+	if len(types) > 0 {
+		sub.types = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	if eb.closed {
+		close(sub.ch)
+		return sub.ch
+	}
+	eb.subs[sub.ch] = sub
+	return sub.ch
+}
+
+// Unsubscribe 取消訂閱並關閉通道
+func (eb *EventBus) Unsubscribe(ch <-chan Event) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	if sub, ok := eb.subs[ch]; ok {
+		delete(eb.subs, ch)
+		close(sub.ch)
+	}
+}
+
+// Publish 將事件發給訂閱了該類型的訂閱者，未設置時間時使用當前時間
+//
+// eb 為空時不做任何事，發布方不需要判斷是否設置了事件總線。
+func (eb *EventBus) Publish(event Event) {
+	if eb == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+	for _, sub := range eb.subs {
+		if sub.types != nil && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+			continue
+		default:
+		}
+		select {
+		case <-sub.ch:
+			eb.dropped.Add(1)
+		default:
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			eb.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped 返回因訂閱者處理過慢而丟棄的事件總數
+func (eb *EventBus) Dropped() int64 {
+	return eb.dropped.Load()
+}
+
+// Close 關閉事件總線和所有訂閱通道
+func (eb *EventBus) Close() {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	if eb.closed {
+		return
+	}
+	eb.closed = true
+	for ch, sub := range eb.subs {
+		delete(eb.subs, ch)
+		close(sub.ch)
+	}
+}
@@ -0,0 +1,96 @@
+// pressure/exception.go - Modbus 異常響應和通信錯誤的分類
+package pressure
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/goburrow/modbus"
+	"github.com/goburrow/serial"
+)
+
+// ModbusExceptionName 返回 Modbus 異常碼的中文名稱
+func ModbusExceptionName(code byte) string {
+	switch code {
+	case modbus.ExceptionCodeIllegalFunction:
+		return "非法功能碼"
+	case modbus.ExceptionCodeIllegalDataAddress:
+		return "非法數據地址"
+	case modbus.ExceptionCodeIllegalDataValue:
+		return "非法數據值"
+	case modbus.ExceptionCodeServerDeviceFailure:
+		return "設備故障"
+	case modbus.ExceptionCodeAcknowledge:
+		return "已接受，處理中"
+	case modbus.ExceptionCodeServerDeviceBusy:
+		return "設備忙"
+	case modbus.ExceptionCodeMemoryParityError:
+		return "存儲奇偶校驗錯誤"
+	case modbus.ExceptionCodeGatewayPathUnavailable:
+		return "網關路徑不可用"
+	case modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond:
+		return "網關目標設備無響應"
+	default:
+		return "未知異常"
+	}
+}
+
+// ClassifyError 將 Modbus 讀取錯誤歸類為錯誤代碼
+//
+// 設備返回的異常響應按異常碼區分配置問題（非法地址等，歸為協議錯誤）、設備故障和設備忙；
+// 沒有響應歸為超時；CRC、站號不符等報文錯誤歸為協議錯誤；其餘歸為連接錯誤。
+func ClassifyError(err error) ErrorCode {
+	if err == nil {
+		return ErrNone
+	}
+
+	var modbusErr *modbus.ModbusError
+	if errors.As(err, &modbusErr) {
+		switch modbusErr.ExceptionCode {
+		case modbus.ExceptionCodeServerDeviceFailure, modbus.ExceptionCodeMemoryParityError:
+			return ErrHardware
+		case modbus.ExceptionCodeAcknowledge, modbus.ExceptionCodeServerDeviceBusy:
+			return ErrDeviceBusy
+		case modbus.ExceptionCodeGatewayPathUnavailable:
+			return ErrConnection
+		case modbus.ExceptionCodeGatewayTargetDeviceFailedToRespond:
+			return ErrTimeout
+		default:
+			return ErrProtocol
+		}
+	}
+
+	var netErr net.Error
+	if errors.Is(err, serial.ErrTimeout) || errors.Is(err, os.ErrDeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrTimeout
+	}
+
+	var short errShortResponse
+	if errors.As(err, &short) || strings.HasPrefix(err.Error(), "modbus: response") {
+		return ErrProtocol
+	}
+	return ErrConnection
+}
+
+// NewModbusPressureError 將 Modbus 讀取錯誤轉為帶錯誤代碼的壓差儀錯誤
+//
+// Message 為中文說明（異常響應包含異常碼和名稱），Context 保留原始錯誤。
+func NewModbusPressureError(err error, slaveID byte) *PressureError {
+	code := ClassifyError(err)
+	pe := NewPressureError(code, err.Error(), slaveID).WithContext(err.Error())
+
+	var modbusErr *modbus.ModbusError
+	switch {
+	case errors.As(err, &modbusErr):
+		pe.ExceptionCode = modbusErr.ExceptionCode
+		pe.Message = fmt.Sprintf("設備返回 Modbus 異常 0x%02X (%s，功能碼 0x%02X)",
+			modbusErr.ExceptionCode, ModbusExceptionName(modbusErr.ExceptionCode), modbusErr.FunctionCode&0x7F)
+	case code == ErrTimeout:
+		pe.Message = fmt.Sprintf("設備無響應 (%v)", err)
+	}
+	return pe
+}
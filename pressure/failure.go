@@ -0,0 +1,139 @@
+// pressure/failure.go - 連續讀取失敗的升級處理策略
+package pressure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrTooManyFailures 連續讀取失敗次數達到上限且策略為退出
+var ErrTooManyFailures = errors.New("連續讀取失敗次數過多")
+
+// FailureAction 連續失敗達到上限時的處理方式
+type FailureAction int
+
+const (
+	FailureRetry    FailureAction = 0 // 記錄告警後繼續重試（默認）
+	FailureExit     FailureAction = 1 // 停止監測並返回 ErrTooManyFailures
+	FailureHook     FailureAction = 2 // 執行外部腳本後繼續重試
+	FailureFailover FailureAction = 3 // 切換到備用設備
+	FailureRecover  FailureAction = 4 // 按恢復階梯逐級處理：重新打開連接、重置轉換器、切換備用設備、標記故障
+)
+
+// String 實現 Stringer 接口
+func (fa FailureAction) String() string {
+	switch fa {
+	case FailureRetry:
+		return "retry"
+	case FailureExit:
+		return "exit"
+	case FailureHook:
+		return "hook"
+	case FailureFailover:
+		return "failover"
+	case FailureRecover:
+		return "recover"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText 實現 encoding.TextMarshaler 接口
+func (fa FailureAction) MarshalText() ([]byte, error) {
+	return []byte(fa.String()), nil
+}
+
+// UnmarshalText 實現 encoding.TextUnmarshaler 接口
+func (fa *FailureAction) UnmarshalText(text []byte) error {
+	switch strings.ToLower(strings.TrimSpace(string(text))) {
+	case "retry", "":
+		*fa = FailureRetry
+	case "exit":
+		*fa = FailureExit
+	case "hook":
+		*fa = FailureHook
+	case "failover", "backup":
+		*fa = FailureFailover
+	case "recover", "ladder":
+		*fa = FailureRecover
+	default:
+		return fmt.Errorf("unknown failure action: %s", string(text))
+	}
+	return nil
+}
+
+// DefaultHookTimeout 外部腳本的最長執行時間
+const DefaultHookTimeout = 30 * time.Second
+
+// FailurePolicy 連續讀取失敗的處理策略
+type FailurePolicy struct {
+	MaxConsecutive int           `json:"max_consecutive"`         // 觸發處理的連續失敗次數，0 為不處理
+	Action         FailureAction `json:"action"`                  // 處理方式
+	Hook           string        `json:"hook,omitempty"`          // hook 方式執行的命令
+	BackupDevice   string        `json:"backup_device,omitempty"` // failover 方式切換到的設備路徑
+
+	Ladder           []RecoveryStep `json:"ladder,omitempty"`            // recover 方式的恢復步驟，為空則使用 DefaultRecoveryLadder
+	ResetCommand     string         `json:"reset_command,omitempty"`     // reset 步驟執行的命令（如 usbreset 1a86:7523），為空則使用 Hook
+	RecoveryInterval time.Duration  `json:"recovery_interval,omitempty"` // 兩個恢復步驟之間的最短間隔，0 為 DefaultRecoveryInterval
+}
+
+// Validate 檢查策略是否完整
+func (fp FailurePolicy) Validate() error {
+	if fp.MaxConsecutive < 0 {
+		return fmt.Errorf("連續失敗次數不能為負數: %d", fp.MaxConsecutive)
+	}
+	if fp.Action == FailureHook && strings.TrimSpace(fp.Hook) == "" {
+		return fmt.Errorf("hook 處理方式需要指定命令")
+	}
+	if fp.Action == FailureFailover && fp.BackupDevice == "" {
+		return fmt.Errorf("failover 處理方式需要指定備用設備")
+	}
+	if fp.RecoveryInterval < 0 {
+		return fmt.Errorf("恢復步驟間隔不能為負數: %v", fp.RecoveryInterval)
+	}
+	for _, step := range fp.Ladder {
+		switch {
+		case step == RecoveryReset && fp.resetCommand() == "":
+			return fmt.Errorf("reset 恢復步驟需要指定重置命令或失敗處理腳本")
+		case step == RecoveryFailover && fp.BackupDevice == "":
+			return fmt.Errorf("failover 恢復步驟需要指定備用設備")
+		case step < RecoveryReopen || step > RecoveryFail:
+			return fmt.Errorf("未知的恢復步驟: %d", step)
+		}
+	}
+	return nil
+}
+
+// runHook 執行外部命令，env 以 PRESSURE_ 前綴的環境變數傳入
+//
+// 命令按空白分割後直接執行，不經過 shell。
+func runHook(command string, env map[string]string, timeout time.Duration) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return fmt.Errorf("命令為空")
+	}
+	return runCommand(args[0], args[1:], env, timeout)
+}
+
+// runCommand 執行外部程序，env 以 PRESSURE_ 前綴的環境變數傳入
+func runCommand(name string, args []string, env map[string]string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = os.Environ()
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, "PRESSURE_"+key+"="+value)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("執行 %s 失敗: %v (輸出: %s)", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
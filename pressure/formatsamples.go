@@ -0,0 +1,155 @@
+// pressure/formatsamples.go - 多次採樣的格式檢測：比較各種解析方式在連續讀數間的穩定性，提高格式判斷的可靠性
+package pressure
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// 多次採樣格式檢測的參數
+const (
+	DefaultFormatSamples = 3                      // 掃描預設的格式檢測採樣次數
+	formatSampleInterval = 200 * time.Millisecond // 兩次採樣的最短間隔，讓讀數有機會變化
+	formatJumpLimit      = 1000.0                 // 相鄰兩次採樣的壓力差超過此值 (Pa) 視為不連續
+	formatTinyFloat      = 1e-3                   // 絕對值小於此值的非零浮點讀數不像實際壓力
+)
+
+// sampleInterval 返回兩次格式採樣之間的等待時間，不短於探測間隔
+func (sc ScanConfig) sampleInterval() time.Duration {
+	if sc.ProbeDelay > formatSampleInterval {
+		return sc.ProbeDelay
+	}
+	return formatSampleInterval
+}
+
+// formatCandidate 一種解析方式在各次採樣中的解析結果
+type formatCandidate struct {
+	format     DataFormatType
+	values     []float64
+	confidence float64 // 單次採樣置信度的平均值
+}
+
+// detectDataFormatSamples 根據多次讀取的原始數據檢測數據格式，返回格式、置信度和所選格式的穩定性
+//
+// 單次採樣的置信度只看數值範圍和編碼特徵，十進制讀數按浮點解析時常得到同樣高的分數。多次採樣時再檢查
+// 每種解析方式的時間一致性：原始數據變化時讀數應隨之連續變化，按錯誤格式解析時讀數要麼劇烈跳變、
+// 要麼是極小的浮點數、要麼不隨原始數據變化。置信度為單次置信度和穩定性的平均值。
+func (s *Scanner) detectDataFormatSamples(samples [][]byte) (DataFormatType, float64, float64) {
+	if len(samples) <= 1 {
+		if len(samples) == 0 {
+			return DecimalFormat, 0, 0
+		}
+		format, confidence := s.detectDataFormat(samples[0])
+		return format, confidence, 0
+	}
+
+	candidates := s.formatCandidates(samples)
+	if len(candidates) == 0 {
+		return DecimalFormat, 0, 0
+	}
+
+	best, bestConfidence, bestStability := candidates[0].format, -1.0, 0.0
+	for _, candidate := range candidates {
+		stability := sampleStability(candidate.format, candidate.values, samples)
+		confidence := (candidate.confidence + stability) / 2
+		s.logf("      📊 %d 次採樣: %s 讀數 [%s], 單次置信度%.2f, 穩定性%.2f",
+			len(samples), formatToString(candidate.format), formatValues(candidate.values), candidate.confidence, stability)
+		if confidence > bestConfidence {
+			best, bestConfidence, bestStability = candidate.format, confidence, stability
+		}
+	}
+	return best, bestConfidence, bestStability
+}
+
+// formatCandidates 按十進制、浮點和 16 位整數解析每次採樣，順序與 detectDataFormat 同分時的優先順序一致
+func (s *Scanner) formatCandidates(samples [][]byte) []formatCandidate {
+	for _, data := range samples {
+		if len(data) != len(samples[0]) {
+			return nil
+		}
+	}
+
+	var candidates []formatCandidate
+	if len(samples[0]) >= 4 {
+		candidates = append(candidates,
+			formatCandidate{format: DecimalFormat},
+			formatCandidate{format: FloatFormat})
+	}
+	if len(samples[0]) >= 2 {
+		candidates = append(candidates, formatCandidate{format: Int16Format})
+	}
+
+	for i := range candidates {
+		candidate := &candidates[i]
+		for _, data := range samples {
+			var value, confidence float64
+			switch candidate.format {
+			case DecimalFormat:
+				value = parseDecimalFormatStatic(data, s.decimalScale())
+				confidence = s.calculateDecimalConfidence(value, data)
+			case FloatFormat:
+				value = parseFloatFormatStatic(data, s.floatByteOrder())
+				confidence = s.calculateFloatConfidence(value, data)
+			default:
+				value = parse16BitFormatStatic(data, true, s.decimalScale())
+				confidence = s.calculate16BitConfidence(value, data)
+			}
+			candidate.values = append(candidate.values, value)
+			candidate.confidence += confidence / float64(len(samples))
+		}
+	}
+	return candidates
+}
+
+// sampleStability 計算一種解析方式在多次採樣中的穩定性 (0-1)
+//
+// 為合理讀數的比例、相鄰讀數連續的比例和原始數據變化時讀數隨之變化的比例之積。
+func sampleStability(format DataFormatType, values []float64, samples [][]byte) float64 {
+	plausible := 0
+	for _, value := range values {
+		if isPlausibleSample(format, value) {
+			plausible++
+		}
+	}
+
+	smooth, changed, followed := 0, 0, 0
+	for i := 1; i < len(values); i++ {
+		if math.Abs(values[i]-values[i-1]) <= formatJumpLimit {
+			smooth++
+		}
+		if string(samples[i]) != string(samples[i-1]) {
+			changed++
+			if values[i] != values[i-1] {
+				followed++
+			}
+		}
+	}
+
+	stability := float64(plausible) / float64(len(values)) * float64(smooth) / float64(len(values)-1)
+	if changed > 0 {
+		stability *= float64(followed) / float64(changed)
+	}
+	return stability
+}
+
+// isPlausibleSample 讀數是否可能是實際壓力：有限、在 ±10000 Pa 內，浮點解析時不是極小的非零值
+func isPlausibleSample(format DataFormatType, value float64) bool {
+	if math.IsNaN(value) || math.IsInf(value, 0) || value < -10000 || value > 10000 {
+		return false
+	}
+	if format == FloatFormat && value != 0 && math.Abs(value) < formatTinyFloat {
+		return false
+	}
+	return true
+}
+
+// formatValues 將讀數格式化為四位有效數字，用於日誌
+func formatValues(values []float64) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = fmt.Sprintf("%.4g", value)
+	}
+	return strings.Join(parts, " ")
+}
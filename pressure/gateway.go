@@ -0,0 +1,308 @@
+// pressure/gateway.go - Modbus TCP 網關：將 RTU 儀表的讀數以 Modbus TCP 服務器提供給 SCADA 輪詢
+package pressure
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// 網關寄存器映射（保持寄存器和輸入寄存器相同，功能碼 0x03 和 0x04 都可讀取）
+const (
+	GatewayRegPressureFloat = 0x0000 // 壓力 (Pa)，IEEE 754 浮點數，2 個寄存器，ABCD 字節序
+	GatewayRegPressureInt   = 0x0002 // 壓力 ×10 (0.1 Pa)，32 位有符號整數，2 個寄存器
+	GatewayRegValid         = 0x0004 // 讀數是否有效 (1/0)
+	GatewayRegErrorCode     = 0x0005 // 錯誤代碼 (ErrorCode)，有效時為 0
+	GatewayRegCount         = 0x0006 // 讀數序號，32 位無符號整數，2 個寄存器
+	GatewayRegTimestamp     = 0x0008 // 讀取時間 (Unix 秒)，32 位無符號整數，2 個寄存器
+	GatewayRegAge           = 0x000A // 讀數已過去的秒數，最大 65535
+	GatewayRegTemperature   = 0x000B // 儀表溫度 ×10 (0.1 °C)，16 位有符號整數，未配置溫度寄存器時為 0x8000
+	GatewayRegPushida       = 0x0034 // 與普時達十進制格式相同的壓力寄存器，原本直接輪詢儀表的 SCADA 只需改 IP
+
+	// gatewayRegisterCount 寄存器映射的大小
+	gatewayRegisterCount = GatewayRegPushida + 2
+)
+
+// GatewayNoTemperature 未配置溫度寄存器時溫度寄存器的值
+const GatewayNoTemperature = 0x8000
+
+// DefaultGatewayIdleTimeout 網關關閉空閒客戶端連接前的等待時間
+const DefaultGatewayIdleTimeout = 2 * time.Minute
+
+// Modbus 異常碼
+const (
+	exceptionIllegalFunction    = 0x01
+	exceptionIllegalAddress     = 0x02
+	exceptionIllegalValue       = 0x03
+	exceptionGatewayTargetError = 0x0B
+)
+
+// ModbusGateway 以 Modbus TCP 服務器提供最新讀數的輸出目標
+//
+// 作為 Monitor 的 Sink 使用，每個站點號的最新讀數寫入一份寄存器映射，單元號 (Unit ID) 對應站點號；
+// 只有一台儀表時單元號 0 和 255 也返回該儀表。尚無讀數或單元號未知時返回異常 0x0B（網關目標無響應）。
+// 只支援讀取（功能碼 0x03/0x04），其他功能碼返回異常 0x01。
+type ModbusGateway struct {
+	addr        string
+	logger      *log.Logger
+	idleTimeout time.Duration
+	listener    net.Listener
+
+	mu        sync.RWMutex
+	registers map[byte][]uint16 // 站點號到寄存器映射
+	latest    map[byte]time.Time
+	conns     map[net.Conn]struct{}
+	requests  int64
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewModbusGateway 創建監聽 addr 的 Modbus TCP 網關
+func NewModbusGateway(addr string, logger *log.Logger) *ModbusGateway {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &ModbusGateway{
+		addr:        addr,
+		logger:      logger,
+		idleTimeout: DefaultGatewayIdleTimeout,
+		registers:   make(map[byte][]uint16),
+		latest:      make(map[byte]time.Time),
+		conns:       make(map[net.Conn]struct{}),
+	}
+}
+
+// SetIdleTimeout 設置關閉空閒客戶端連接前的等待時間
+func (gw *ModbusGateway) SetIdleTimeout(timeout time.Duration) *ModbusGateway {
+	if timeout > 0 {
+		gw.idleTimeout = timeout
+	}
+	return gw
+}
+
+// Start 開始監聽並接受客戶端連接
+func (gw *ModbusGateway) Start() error {
+	listener, err := net.Listen("tcp", gw.addr)
+	if err != nil {
+		return fmt.Errorf("Modbus TCP 網關監聽 %s 失敗: %v", gw.addr, err)
+	}
+	gw.listener = listener
+
+	gw.wg.Add(1)
+	go gw.acceptLoop()
+	gw.logger.Printf("🔀 Modbus TCP 網關已啟動: %s", listener.Addr())
+	return nil
+}
+
+// Addr 返回實際監聽地址，未啟動時返回配置的地址
+func (gw *ModbusGateway) Addr() string {
+	if gw.listener == nil {
+		return gw.addr
+	}
+	return gw.listener.Addr().String()
+}
+
+// Requests 返回已處理的請求數量
+func (gw *ModbusGateway) Requests() int64 {
+	gw.mu.RLock()
+	defer gw.mu.RUnlock()
+	return gw.requests
+}
+
+// WriteReading 實現 Sink 接口，更新讀數站點號的寄存器映射
+func (gw *ModbusGateway) WriteReading(reading MonitorReading) error {
+	registers := gatewayRegisters(reading)
+	gw.mu.Lock()
+	gw.registers[reading.SlaveID] = registers
+	gw.latest[reading.SlaveID] = reading.Timestamp
+	gw.mu.Unlock()
+	return nil
+}
+
+// Check 實現 SinkChecker 接口，確認網關可以連接
+func (gw *ModbusGateway) Check() error {
+	if gw.listener == nil {
+		return fmt.Errorf("Modbus TCP 網關尚未啟動")
+	}
+	conn, err := net.DialTimeout("tcp", gw.listener.Addr().String(), 2*time.Second)
+	if err != nil {
+		return fmt.Errorf("無法連接 Modbus TCP 網關 %s: %v", gw.listener.Addr(), err)
+	}
+	return conn.Close()
+}
+
+// Close 實現 Sink 接口，停止監聽並斷開所有客戶端
+func (gw *ModbusGateway) Close() error {
+	gw.mu.Lock()
+	if gw.closed || gw.listener == nil {
+		gw.mu.Unlock()
+		return nil
+	}
+	gw.closed = true
+	for conn := range gw.conns {
+		conn.Close()
+	}
+	gw.mu.Unlock()
+
+	err := gw.listener.Close()
+	gw.wg.Wait()
+	return err
+}
+
+// acceptLoop 接受客戶端連接，直到監聽關閉
+func (gw *ModbusGateway) acceptLoop() {
+	defer gw.wg.Done()
+	for {
+		conn, err := gw.listener.Accept()
+		if err != nil {
+			gw.mu.RLock()
+			closed := gw.closed
+			gw.mu.RUnlock()
+			if !closed {
+				gw.logger.Printf("❌ Modbus TCP 網關出錯: %v", err)
+			}
+			return
+		}
+
+		gw.mu.Lock()
+		if gw.closed {
+			gw.mu.Unlock()
+			conn.Close()
+			return
+		}
+		gw.conns[conn] = struct{}{}
+		gw.mu.Unlock()
+
+		gw.wg.Add(1)
+		go gw.serve(conn)
+	}
+}
+
+// serve 處理一個客戶端連接的請求
+func (gw *ModbusGateway) serve(conn net.Conn) {
+	defer gw.wg.Done()
+	defer func() {
+		gw.mu.Lock()
+		delete(gw.conns, conn)
+		gw.mu.Unlock()
+		conn.Close()
+	}()
+
+	header := make([]byte, 7)
+	for {
+		conn.SetReadDeadline(time.Now().Add(gw.idleTimeout))
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		// MBAP 頭：事務號、協議號 (0)、長度（單元號 + PDU）、單元號
+		length := binary.BigEndian.Uint16(header[4:])
+		if binary.BigEndian.Uint16(header[2:]) != 0 || length < 2 || length > 254 {
+			gw.logger.Printf("⚠️  Modbus TCP 網關收到無效的請求頭 % X，斷開 %s", header, conn.RemoteAddr())
+			return
+		}
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+
+		response := gw.handle(header[6], pdu)
+		frame := make([]byte, 7, 7+len(response))
+		copy(frame, header[:4])
+		binary.BigEndian.PutUint16(frame[4:], uint16(len(response)+1))
+		frame[6] = header[6]
+		if _, err := conn.Write(append(frame, response...)); err != nil {
+			return
+		}
+	}
+}
+
+// handle 處理一個請求 PDU，返回響應 PDU
+func (gw *ModbusGateway) handle(unitID byte, pdu []byte) []byte {
+	function := pdu[0]
+	if function != ModbusFunctionReadHoldingRegisters && function != ModbusFunctionReadInputRegisters {
+		return []byte{function | 0x80, exceptionIllegalFunction}
+	}
+	if len(pdu) != 5 {
+		return []byte{function | 0x80, exceptionIllegalValue}
+	}
+	address := int(binary.BigEndian.Uint16(pdu[1:]))
+	count := int(binary.BigEndian.Uint16(pdu[3:]))
+	if count < 1 || count > 125 {
+		return []byte{function | 0x80, exceptionIllegalValue}
+	}
+	if address+count > gatewayRegisterCount {
+		return []byte{function | 0x80, exceptionIllegalAddress}
+	}
+
+	gw.mu.Lock()
+	gw.requests++
+	registers, updated := gw.unit(unitID)
+	gw.mu.Unlock()
+	if registers == nil {
+		return []byte{function | 0x80, exceptionGatewayTargetError}
+	}
+
+	// 讀數年齡在讀取時計算
+	age := time.Since(updated).Seconds()
+	if age > math.MaxUint16 {
+		age = math.MaxUint16
+	}
+	registers[GatewayRegAge] = uint16(age)
+
+	response := make([]byte, 2, 2+count*2)
+	response[0] = function
+	response[1] = byte(count * 2)
+	for _, value := range registers[address : address+count] {
+		response = binary.BigEndian.AppendUint16(response, value)
+	}
+	return response
+}
+
+// unit 返回單元號對應的寄存器映射副本和讀數時間（調用方需持有鎖）
+func (gw *ModbusGateway) unit(unitID byte) ([]uint16, time.Time) {
+	registers, ok := gw.registers[unitID]
+	if !ok && (unitID == 0 || unitID == 0xFF) && len(gw.registers) == 1 {
+		for id, only := range gw.registers {
+			registers, unitID, ok = only, id, true
+		}
+	}
+	if !ok {
+		return nil, time.Time{}
+	}
+	return append([]uint16(nil), registers...), gw.latest[unitID]
+}
+
+// gatewayRegisters 將讀數寫入寄存器映射
+func gatewayRegisters(reading MonitorReading) []uint16 {
+	registers := make([]uint16, gatewayRegisterCount)
+	putUint32 := func(address int, value uint32) {
+		registers[address] = uint16(value >> 16)
+		registers[address+1] = uint16(value)
+	}
+
+	if reading.Valid {
+		putUint32(GatewayRegPressureFloat, math.Float32bits(float32(reading.Pressure)))
+		scaled := uint32(int32(math.Round(reading.Pressure * 10)))
+		putUint32(GatewayRegPressureInt, scaled)
+		putUint32(GatewayRegPushida, scaled)
+		registers[GatewayRegValid] = 1
+	} else {
+		registers[GatewayRegErrorCode] = uint16(reading.ErrorCode)
+		if reading.ErrorCode == ErrNone {
+			registers[GatewayRegErrorCode] = uint16(ErrUnknown)
+		}
+	}
+	putUint32(GatewayRegCount, uint32(reading.Count))
+	putUint32(GatewayRegTimestamp, uint32(reading.Timestamp.Unix()))
+
+	registers[GatewayRegTemperature] = GatewayNoTemperature
+	if reading.Temperature != nil {
+		registers[GatewayRegTemperature] = uint16(int16(math.Round(*reading.Temperature * 10)))
+	}
+	return registers
+}
@@ -0,0 +1,410 @@
+// pressure/grafana.go - Grafana 數據源接口：simple-json 和 Infinity 插件直接讀取趨勢緩衝繪圖，不需要中間數據庫
+package pressure
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Grafana 查詢可用的指標
+const (
+	GrafanaTargetPressure = "pressure"     // 每個時間段的平均壓力
+	GrafanaTargetMin      = "pressure_min" // 每個時間段的最低壓力
+	GrafanaTargetMax      = "pressure_max" // 每個時間段的最高壓力
+)
+
+// DefaultGrafanaMaxPoints 查詢未指定點數時每條曲線最多返回的點數
+const DefaultGrafanaMaxPoints = 1000
+
+// maxAlarmHistory 為告警標註保留的告警事件上限
+const maxAlarmHistory = 1000
+
+// GrafanaTargets 返回 Grafana 查詢可用的指標名稱
+func GrafanaTargets() []string {
+	return []string{GrafanaTargetPressure, GrafanaTargetMin, GrafanaTargetMax}
+}
+
+// HistoryPoint 歷史查詢的一個數據點，讀數多於請求的點數時為一個時間段內讀數的合併
+type HistoryPoint struct {
+	Time     time.Time `json:"time"`         // 時間段內第一個讀數的時間
+	Pressure float64   `json:"pressure"`     // 平均壓力 (Pa)
+	Min      float64   `json:"pressure_min"` // 最低壓力 (Pa)
+	Max      float64   `json:"pressure_max"` // 最高壓力 (Pa)
+	Count    int       `json:"count"`        // 合併的讀數數量
+}
+
+// value 返回指標對應的值
+func (hp HistoryPoint) value(target string) float64 {
+	switch target {
+	case GrafanaTargetMin:
+		return hp.Min
+	case GrafanaTargetMax:
+		return hp.Max
+	default:
+		return hp.Pressure
+	}
+}
+
+// History 返回 [start, end] 內的讀數，多於 maxPoints 個時將時間範圍等分為 maxPoints 段合併，maxPoints 為 0 時不合併
+func (as *APIServer) History(start, end time.Time, maxPoints int) []HistoryPoint {
+	var points []TrendPoint
+	for _, p := range as.trend.Since(start) {
+		if p.Time.After(end) {
+			break
+		}
+		points = append(points, p)
+	}
+	return downsampleTrend(points, start, end, maxPoints)
+}
+
+// downsampleTrend 將讀數按時間等分為最多 maxPoints 段，每段合併為一個數據點，保留最低和最高壓力
+func downsampleTrend(points []TrendPoint, start, end time.Time, maxPoints int) []HistoryPoint {
+	if maxPoints <= 0 || len(points) <= maxPoints || !end.After(start) {
+		history := make([]HistoryPoint, len(points))
+		for i, p := range points {
+			history[i] = HistoryPoint{Time: p.Time, Pressure: p.Pressure, Min: p.Pressure, Max: p.Pressure, Count: 1}
+		}
+		return history
+	}
+
+	step := end.Sub(start) / time.Duration(maxPoints)
+	if step <= 0 {
+		step = 1
+	}
+	var history []HistoryPoint
+	bucket, sum := -1, 0.0
+	for _, p := range points {
+		index := int(p.Time.Sub(start) / step)
+		if index >= maxPoints {
+			index = maxPoints - 1
+		}
+		if index != bucket {
+			if len(history) > 0 {
+				history[len(history)-1].Pressure = sum / float64(history[len(history)-1].Count)
+			}
+			history = append(history, HistoryPoint{Time: p.Time, Min: p.Pressure, Max: p.Pressure})
+			bucket, sum = index, 0
+		}
+		last := &history[len(history)-1]
+		last.Count++
+		sum += p.Pressure
+		last.Min = math.Min(last.Min, p.Pressure)
+		last.Max = math.Max(last.Max, p.Pressure)
+	}
+	if len(history) > 0 {
+		history[len(history)-1].Pressure = sum / float64(history[len(history)-1].Count)
+	}
+	return history
+}
+
+// WriteAlarm 實現 AlarmSink 接口，保留趨勢緩衝保留時長內的告警事件，作為 Grafana 的標註
+func (as *APIServer) WriteAlarm(event AlarmEvent) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	as.alarms = append(as.alarms, event)
+
+	cutoff := event.Timestamp.Add(-as.trend.Retention())
+	drop := len(as.alarms) - maxAlarmHistory
+	if drop < 0 {
+		drop = 0
+	}
+	for drop < len(as.alarms) && as.alarms[drop].Timestamp.Before(cutoff) {
+		drop++
+	}
+	if drop > 0 {
+		as.alarms = append([]AlarmEvent(nil), as.alarms[drop:]...)
+	}
+	return nil
+}
+
+// alarmsBetween 返回 [start, end] 內的告警事件
+func (as *APIServer) alarmsBetween(start, end time.Time) []AlarmEvent {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+	var events []AlarmEvent
+	for _, event := range as.alarms {
+		if !event.Timestamp.Before(start) && !event.Timestamp.After(end) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// grafanaRange Grafana 請求中的時間範圍
+type grafanaRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// grafanaQueryRequest simple-json 數據源的 /query 請求
+type grafanaQueryRequest struct {
+	Range         grafanaRange `json:"range"`
+	MaxDataPoints int          `json:"maxDataPoints"`
+	Targets       []struct {
+		Target string `json:"target"`
+		Type   string `json:"type"` // timeserie (默認) 或 table
+		Hide   bool   `json:"hide"`
+	} `json:"targets"`
+}
+
+// grafanaAnnotationRequest simple-json 數據源的 /annotations 請求
+type grafanaAnnotationRequest struct {
+	Range      grafanaRange    `json:"range"`
+	Annotation json.RawMessage `json:"annotation"`
+}
+
+// handleGrafana 響應 simple-json 數據源的連接測試，其他路徑返回 404
+func (as *APIServer) handleGrafana(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/grafana/" && r.URL.Path != "/grafana" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "OK")
+}
+
+// handleGrafanaSearch 返回可用的指標名稱，請求中帶 target 時只返回包含該文字的指標
+func (as *APIServer) handleGrafanaSearch(w http.ResponseWriter, r *http.Request) {
+	if !grafanaPost(w, r) {
+		return
+	}
+	var request struct {
+		Target string `json:"target"`
+	}
+	// 舊版插件發送空請求體，忽略解析錯誤
+	json.NewDecoder(r.Body).Decode(&request)
+
+	targets := []string{}
+	for _, target := range GrafanaTargets() {
+		if strings.Contains(target, request.Target) {
+			targets = append(targets, target)
+		}
+	}
+	writeJSON(w, http.StatusOK, targets)
+}
+
+// handleGrafanaQuery 返回時間範圍內的壓力曲線
+//
+// 每個 target 按類型返回時間序列 ({target, datapoints: [[值, 毫秒時間戳]]}) 或表格 (時間、平均、最低和最高壓力)，
+// 讀數多於 maxDataPoints 時按時間段合併。
+func (as *APIServer) handleGrafanaQuery(w http.ResponseWriter, r *http.Request) {
+	if !grafanaPost(w, r) {
+		return
+	}
+	var request grafanaQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+	start, end := request.Range.From, request.Range.To
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if start.IsZero() {
+		start = end.Add(-time.Hour)
+	}
+	maxPoints := request.MaxDataPoints
+	if maxPoints <= 0 {
+		maxPoints = DefaultGrafanaMaxPoints
+	}
+
+	history := as.History(start, end, maxPoints)
+	response := []interface{}{}
+	for _, target := range request.Targets {
+		if target.Hide || target.Target == "" {
+			continue
+		}
+		if !isGrafanaTarget(target.Target) {
+			http.Error(w, fmt.Sprintf("unknown target: %s (%s)", target.Target, strings.Join(GrafanaTargets(), "/")),
+				http.StatusBadRequest)
+			return
+		}
+
+		if target.Type == "table" {
+			rows := make([][]interface{}, len(history))
+			for i, p := range history {
+				rows[i] = []interface{}{p.Time.UnixMilli(), p.Pressure, p.Min, p.Max}
+			}
+			response = append(response, map[string]interface{}{
+				"type": "table",
+				"columns": []map[string]string{
+					{"text": "Time", "type": "time"},
+					{"text": GrafanaTargetPressure, "type": "number"},
+					{"text": GrafanaTargetMin, "type": "number"},
+					{"text": GrafanaTargetMax, "type": "number"},
+				},
+				"rows": rows,
+			})
+			continue
+		}
+
+		datapoints := make([][2]float64, len(history))
+		for i, p := range history {
+			datapoints[i] = [2]float64{p.value(target.Target), float64(p.Time.UnixMilli())}
+		}
+		response = append(response, map[string]interface{}{
+			"target":     target.Target,
+			"datapoints": datapoints,
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGrafanaAnnotations 以告警的觸發和解除作為圖表標註
+func (as *APIServer) handleGrafanaAnnotations(w http.ResponseWriter, r *http.Request) {
+	if !grafanaPost(w, r) {
+		return
+	}
+	var request grafanaAnnotationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, fmt.Sprintf("invalid annotation query: %v", err), http.StatusBadRequest)
+		return
+	}
+	end := request.Range.To
+	if end.IsZero() {
+		end = time.Now()
+	}
+
+	annotations := []interface{}{}
+	for _, event := range as.alarmsBetween(request.Range.From, end) {
+		state := "active"
+		if !event.Active {
+			state = "cleared"
+		}
+		annotation := map[string]interface{}{
+			"time":  event.Timestamp.UnixMilli(),
+			"title": event.Rule,
+			"text":  event.Message,
+			"tags":  []string{event.Rule, state},
+		}
+		if len(request.Annotation) > 0 {
+			annotation["annotation"] = request.Annotation
+		}
+		annotations = append(annotations, annotation)
+	}
+	writeJSON(w, http.StatusOK, annotations)
+}
+
+// handleHistory 返回時間範圍內的讀數，供 Infinity 等通用 JSON/CSV 數據源使用
+//
+// ?from=&to= 接受毫秒時間戳 (Grafana 的 ${__from}/${__to})、RFC 3339 時間或相對現在的時長 (如 6h)，
+// 默認為最近 1 小時；?max_points= 指定最多返回的點數 (默認 1000，0 為不合併)；
+// 默認輸出 JSON (數據在 points 字段)，?format=csv 輸出 CSV，?format=cbor 輸出 CBOR。
+func (as *APIServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	now := time.Now()
+	start, end := now.Add(-time.Hour), now
+	for name, target := range map[string]*time.Time{"from": &start, "to": &end} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := parseHistoryTime(value, now)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid %s: %s", name, value), http.StatusBadRequest)
+			return
+		}
+		*target = t
+	}
+	if !start.Before(end) {
+		http.Error(w, "invalid range: from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	maxPoints := DefaultGrafanaMaxPoints
+	if value := query.Get("max_points"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			http.Error(w, fmt.Sprintf("invalid max_points: %s", value), http.StatusBadRequest)
+			return
+		}
+		maxPoints = n
+	}
+
+	format := responseFormat(r)
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" && format != "cbor" {
+		http.Error(w, fmt.Sprintf("invalid format: %s (json/csv/cbor)", format), http.StatusBadRequest)
+		return
+	}
+
+	history := as.History(start, end, maxPoints)
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(as.maxAge.Seconds())))
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"time", GrafanaTargetPressure, GrafanaTargetMin, GrafanaTargetMax, "count"})
+		for _, p := range history {
+			cw.Write([]string{
+				p.Time.Format(time.RFC3339Nano),
+				strconv.FormatFloat(p.Pressure, 'f', 3, 64),
+				strconv.FormatFloat(p.Min, 'f', 3, 64),
+				strconv.FormatFloat(p.Max, 'f', 3, 64),
+				strconv.Itoa(p.Count),
+			})
+		}
+		cw.Flush()
+		return
+	}
+
+	if history == nil {
+		history = []HistoryPoint{}
+	}
+	writeBody(w, format, http.StatusOK, map[string]interface{}{
+		"schema_version": SchemaVersion,
+		"from":           start,
+		"to":             end,
+		"unit":           Pascal.Symbol(),
+		"points":         history,
+	})
+}
+
+// parseHistoryTime 解析毫秒時間戳、RFC 3339 時間或相對 now 的時長
+func parseHistoryTime(value string, now time.Time) (time.Time, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// grafanaPost 確認請求為 POST，simple-json 數據源的查詢都以 POST 發送 JSON
+func grafanaPost(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
+
+// isGrafanaTarget 是否為可用的指標名稱
+func isGrafanaTarget(target string) bool {
+	for _, name := range GrafanaTargets() {
+		if target == name {
+			return true
+		}
+	}
+	return false
+}
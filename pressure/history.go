@@ -0,0 +1,169 @@
+// pressure/history.go - 監測期間的讀數歷史，結束後匯出 Excel 或趨勢報告；讀數有上限，可同時寫入暫存檔案以防進程異常退出
+package pressure
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// DefaultHistoryLimit 讀數歷史默認保留的讀數上限，為 xlsx 工作表的最大行數 (1048576) 減去表頭
+const DefaultHistoryLimit = 1048575
+
+// ReadingHistory 保留最近讀數的輸出目標，用於結束後匯出
+//
+// 超出上限時丟棄最舊的讀數。設置暫存檔案後每個讀數同時追加到檔案 (JSON Lines)，
+// 進程異常退出時讀數不會遺失，下次打開同一暫存檔案時恢復。
+type ReadingHistory struct {
+	mu       sync.Mutex
+	limit    int
+	readings []PressureReading // 環形緩衝，滿後 start 為最舊的讀數
+	start    int
+	dropped  int // 超出上限而丟棄的讀數
+	spool    *os.File
+	spooled  string
+}
+
+// NewReadingHistory 創建保留最多 DefaultHistoryLimit 個讀數的歷史
+func NewReadingHistory() *ReadingHistory {
+	return &ReadingHistory{limit: DefaultHistoryLimit}
+}
+
+// SetLimit 設置保留的讀數上限，0 或負數為 DefaultHistoryLimit；應在寫入讀數之前設置
+func (rh *ReadingHistory) SetLimit(limit int) *ReadingHistory {
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	rh.limit = limit
+	return rh
+}
+
+// Spool 將讀數同時追加到暫存檔案，返回從既有暫存檔案恢復的讀數數量
+//
+// 暫存檔案已存在時（上次運行未正常匯出）先載入其中的讀數；異常退出時寫了一半的最後一行被截掉。
+func (rh *ReadingHistory) Spool(path string) (int, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("打開讀數暫存檔案失敗: %v", err)
+	}
+
+	var recovered []PressureReading
+	var offset int64 // 最後一個完整讀數之後的位置
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		var reading PressureReading
+		if raw := bytes.TrimSpace(line); len(raw) > 0 {
+			if json.Unmarshal(raw, &reading) != nil {
+				break
+			}
+			recovered = append(recovered, reading)
+		}
+		offset += int64(len(line))
+	}
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return 0, fmt.Errorf("截斷讀數暫存檔案失敗: %v", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return 0, fmt.Errorf("定位讀數暫存檔案失敗: %v", err)
+	}
+
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	for _, reading := range recovered {
+		rh.add(reading)
+	}
+	rh.spool = file
+	rh.spooled = path
+	return len(recovered), nil
+}
+
+// RemoveSpool 匯出完成後刪除暫存檔案
+func (rh *ReadingHistory) RemoveSpool() error {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	if rh.spooled == "" {
+		return nil
+	}
+	if rh.spool != nil {
+		rh.spool.Close()
+		rh.spool = nil
+	}
+	path := rh.spooled
+	rh.spooled = ""
+	return os.Remove(path)
+}
+
+// WriteReading 實現 Sink 接口
+func (rh *ReadingHistory) WriteReading(reading MonitorReading) error {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	rh.add(reading.PressureReading)
+
+	if rh.spool == nil {
+		return nil
+	}
+	line, err := json.Marshal(reading.PressureReading)
+	if err != nil {
+		return fmt.Errorf("序列化讀數失敗: %v", err)
+	}
+	if _, err := rh.spool.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("寫入讀數暫存檔案失敗: %v", err)
+	}
+	return nil
+}
+
+// add 加入一個讀數，超出上限時覆蓋最舊的讀數（調用方需持有鎖）
+func (rh *ReadingHistory) add(reading PressureReading) {
+	if len(rh.readings) < rh.limit {
+		rh.readings = append(rh.readings, reading)
+		return
+	}
+	rh.readings[rh.start] = reading
+	rh.start = (rh.start + 1) % len(rh.readings)
+	rh.dropped++
+}
+
+// Close 實現 Sink 接口，關閉暫存檔案（保留檔案，匯出後由 RemoveSpool 刪除）
+func (rh *ReadingHistory) Close() error {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	if rh.spool == nil {
+		return nil
+	}
+	err := rh.spool.Close()
+	rh.spool = nil
+	return err
+}
+
+// Readings 返回保留的讀數副本，按時間順序
+func (rh *ReadingHistory) Readings() []PressureReading {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	readings := make([]PressureReading, 0, len(rh.readings))
+	readings = append(readings, rh.readings[rh.start:]...)
+	return append(readings, rh.readings[:rh.start]...)
+}
+
+// Dropped 返回超出上限而丟棄的最舊讀數數量
+func (rh *ReadingHistory) Dropped() int {
+	rh.mu.Lock()
+	defer rh.mu.Unlock()
+	return rh.dropped
+}
+
+// SaveXLSX 將保留的讀數匯出為 Excel 檔案
+func (rh *ReadingHistory) SaveXLSX(filename string) error {
+	return SaveReadingsXLSX(rh.Readings(), filename)
+}
@@ -0,0 +1,80 @@
+package pressure
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// historyPressures 返回讀數歷史中各讀數的壓力值
+func historyPressures(rh *ReadingHistory) []float64 {
+	var pressures []float64
+	for _, reading := range rh.Readings() {
+		pressures = append(pressures, reading.Pressure)
+	}
+	return pressures
+}
+
+func writeHistory(t *testing.T, rh *ReadingHistory, pressures ...float64) {
+	t.Helper()
+	for _, pressure := range pressures {
+		if err := rh.WriteReading(MonitorReading{PressureReading: PressureReading{Pressure: pressure, Valid: true}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadingHistoryLimit(t *testing.T) {
+	rh := NewReadingHistory().SetLimit(3)
+	writeHistory(t, rh, 1, 2, 3, 4, 5)
+
+	if got := historyPressures(rh); !equalPressures(got, []float64{3, 4, 5}) {
+		t.Errorf("readings = %v, want the newest [3 4 5]", got)
+	}
+	if rh.Dropped() != 2 {
+		t.Errorf("dropped = %d, want 2", rh.Dropped())
+	}
+}
+
+func TestReadingHistorySpoolRecovers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.xlsx.readings.jsonl")
+
+	rh := NewReadingHistory()
+	if recovered, err := rh.Spool(path); err != nil || recovered != 0 {
+		t.Fatalf("Spool on a new file = %d, %v; want 0, nil", recovered, err)
+	}
+	writeHistory(t, rh, 1, 2)
+	rh.Close()
+
+	// 模擬異常退出時寫了一半的讀數
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"pressure":3,"va`)
+	file.Close()
+
+	restarted := NewReadingHistory()
+	recovered, err := restarted.Spool(path)
+	if err != nil || recovered != 2 {
+		t.Fatalf("Spool after crash = %d, %v; want 2 recovered", recovered, err)
+	}
+	writeHistory(t, restarted, 4)
+	if got := historyPressures(restarted); !equalPressures(got, []float64{1, 2, 4}) {
+		t.Errorf("readings = %v, want [1 2 4]", got)
+	}
+	restarted.Close()
+
+	// 暫存檔案中的半行已截掉，新讀數接在完整讀數之後
+	reopened := NewReadingHistory()
+	if again, _ := reopened.Spool(path); again != 3 {
+		t.Errorf("spool holds %d readings, want 3", again)
+	}
+	reopened.Close()
+	if err := restarted.RemoveSpool(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("spool still exists after RemoveSpool: %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+// pressure/hooks.go - 事件觸發的外部腳本
+package pressure
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// 可觸發外部腳本的事件名稱
+const (
+	HookAlarmRaised        = "alarm_raised"        // 告警觸發
+	HookAlarmCleared       = "alarm_cleared"       // 告警解除
+	HookDeviceConnected    = "device_connected"    // 設備恢復響應
+	HookDeviceDisconnected = "device_disconnected" // 設備停止響應
+	HookThresholdCrossed   = "threshold_crossed"   // 壓力穿越閾值
+	HookBandChanged        = "band_changed"        // 壓力進入另一個區間
+)
+
+// HookEventNames 返回所有可用的事件名稱
+func HookEventNames() []string {
+	return []string{
+		HookAlarmRaised, HookAlarmCleared,
+		HookDeviceConnected, HookDeviceDisconnected,
+		HookThresholdCrossed, HookBandChanged,
+	}
+}
+
+// Hook 外部腳本配置
+//
+// Args 中的每個參數都是 text/template 模板，可使用 HookEvent 的字段，
+// 如 {{.Event}}、{{.Pressure}}、{{.Rule}}、{{.Time.Format "15:04:05"}}。
+type Hook struct {
+	Name      string        `json:"name" yaml:"name"`                               // 名稱，用於日誌
+	Events    []string      `json:"events" yaml:"events"`                           // 觸發事件，為空則全部事件
+	Command   string        `json:"command" yaml:"command"`                         // 可執行程序
+	Args      []string      `json:"args,omitempty" yaml:"args,omitempty"`           // 參數模板
+	Threshold *float64      `json:"threshold,omitempty" yaml:"threshold,omitempty"` // threshold_crossed 的閾值 (Pa)
+	Timeout   time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`     // 執行超時，0 為 DefaultHookTimeout
+}
+
+// HookEvent 傳給腳本模板和環境變數的事件數據
+type HookEvent struct {
+	Event     string    // 事件名稱
+	Time      time.Time // 事件時間
+	SlaveID   byte      // 站點號
+	Pressure  float64   // 相關讀數的壓力值 (Pa)
+	Valid     bool      // 相關讀數是否有效
+	Rule      string    // 告警規則名稱（告警事件）
+	Threshold float64   // 穿越的閾值（threshold_crossed）
+	Direction string    // 穿越方向 up/down（threshold_crossed）
+	Band      string    // 當前區間（band_changed）
+	FromBand  string    // 原區間，首次分類時為空（band_changed）
+	Message   string    // 描述
+	Room      string    // 房間（設置了位置對照表時）
+	Floor     string    // 樓層
+	AssetTag  string    // 資產編號
+}
+
+// env 將事件轉換為環境變數
+func (he HookEvent) env() map[string]string {
+	return map[string]string{
+		"EVENT":     he.Event,
+		"TIME":      he.Time.Format(time.RFC3339),
+		"SLAVE_ID":  fmt.Sprintf("%d", he.SlaveID),
+		"PRESSURE":  fmt.Sprintf("%.3f", he.Pressure),
+		"VALID":     fmt.Sprintf("%t", he.Valid),
+		"RULE":      he.Rule,
+		"THRESHOLD": fmt.Sprintf("%.3f", he.Threshold),
+		"DIRECTION": he.Direction,
+		"BAND":      he.Band,
+		"FROM_BAND": he.FromBand,
+		"MESSAGE":   he.Message,
+		"ROOM":      he.Room,
+		"FLOOR":     he.Floor,
+		"ASSET_TAG": he.AssetTag,
+	}
+}
+
+// setLocation 填入設備的安裝位置
+func (he *HookEvent) setLocation(location *Location) {
+	if location == nil {
+		return
+	}
+	he.Room = location.Room
+	he.Floor = location.Floor
+	he.AssetTag = location.AssetTag
+}
+
+// compiledHook 已解析模板的腳本
+type compiledHook struct {
+	Hook
+	events map[string]bool
+	args   []*template.Template
+}
+
+// HookRunner 根據讀數和告警觸發外部腳本，可作為 Monitor 的輸出目標
+type HookRunner struct {
+	hooks  []*compiledHook
+	logger *log.Logger
+
+	mu          sync.Mutex
+	started     bool
+	lastValid   bool
+	lastReading PressureReading
+	wg          sync.WaitGroup
+}
+
+// NewHookRunner 解析腳本配置並創建執行器
+func NewHookRunner(hooks []Hook, logger *log.Logger) (*HookRunner, error) {
+	if logger == nil {
+		logger = log.Default()
+	}
+
+	valid := make(map[string]bool)
+	for _, name := range HookEventNames() {
+		valid[name] = true
+	}
+
+	hr := &HookRunner{logger: logger}
+	for i, hook := range hooks {
+		if hook.Name == "" {
+			hook.Name = fmt.Sprintf("hook-%d", i+1)
+		}
+		if strings.TrimSpace(hook.Command) == "" {
+			return nil, fmt.Errorf("腳本 %s 未指定命令", hook.Name)
+		}
+
+		compiled := &compiledHook{Hook: hook, events: make(map[string]bool)}
+		for _, event := range hook.Events {
+			if !valid[event] {
+				return nil, fmt.Errorf("腳本 %s 的事件無效: %s (可用: %s)",
+					hook.Name, event, strings.Join(HookEventNames(), ", "))
+			}
+			compiled.events[event] = true
+		}
+		if compiled.events[HookThresholdCrossed] && compiled.Threshold == nil {
+			return nil, fmt.Errorf("腳本 %s 監聽 %s 但未設置 threshold", hook.Name, HookThresholdCrossed)
+		}
+
+		for j, arg := range hook.Args {
+			tmpl, err := template.New(fmt.Sprintf("%s-%d", hook.Name, j)).Parse(arg)
+			if err != nil {
+				return nil, fmt.Errorf("腳本 %s 的參數模板無效: %v", hook.Name, err)
+			}
+			compiled.args = append(compiled.args, tmpl)
+		}
+		hr.hooks = append(hr.hooks, compiled)
+	}
+	return hr, nil
+}
+
+// WriteReading 實現 Sink 接口，檢測設備連接狀態變化和閾值穿越
+func (hr *HookRunner) WriteReading(reading MonitorReading) error {
+	hr.mu.Lock()
+	started, lastValid, last := hr.started, hr.lastValid, hr.lastReading
+	hr.started = true
+	hr.lastValid = reading.Valid
+	if reading.Valid {
+		hr.lastReading = reading.PressureReading
+	}
+	hr.mu.Unlock()
+
+	event := HookEvent{
+		Time:     reading.Timestamp,
+		SlaveID:  reading.SlaveID,
+		Pressure: reading.Pressure,
+		Valid:    reading.Valid,
+	}
+	event.setLocation(reading.Location)
+
+	switch {
+	case reading.Valid && (!started || !lastValid):
+		event.Event = HookDeviceConnected
+		event.Message = "設備響應正常"
+		hr.fire(event, nil)
+	case !reading.Valid && (!started || lastValid):
+		event.Event = HookDeviceDisconnected
+		event.Message = reading.Error
+		hr.fire(event, nil)
+	}
+
+	// 閾值穿越只比較相鄰的兩個有效讀數
+	if !reading.Valid || last.Timestamp.IsZero() {
+		return nil
+	}
+	for _, hook := range hr.hooks {
+		if hook.Threshold == nil {
+			continue
+		}
+		threshold := *hook.Threshold
+		direction := ""
+		switch {
+		case last.Pressure < threshold && reading.Pressure >= threshold:
+			direction = "up"
+		case last.Pressure >= threshold && reading.Pressure < threshold:
+			direction = "down"
+		default:
+			continue
+		}
+
+		crossed := event
+		crossed.Event = HookThresholdCrossed
+		crossed.Threshold = threshold
+		crossed.Direction = direction
+		crossed.Message = fmt.Sprintf("%.2f Pa → %.2f Pa 穿越 %.2f Pa", last.Pressure, reading.Pressure, threshold)
+		hr.fire(crossed, hook)
+	}
+	return nil
+}
+
+// WriteAlarm 實現 AlarmSink 接口
+func (hr *HookRunner) WriteAlarm(alarm AlarmEvent) error {
+	event := HookEvent{
+		Event:    HookAlarmCleared,
+		Time:     alarm.Timestamp,
+		SlaveID:  alarm.Reading.SlaveID,
+		Pressure: alarm.Reading.Pressure,
+		Valid:    alarm.Reading.Valid,
+		Rule:     alarm.Rule,
+		Message:  alarm.Message,
+	}
+	event.setLocation(alarm.Location)
+	if alarm.Active {
+		event.Event = HookAlarmRaised
+	}
+	hr.fire(event, nil)
+	return nil
+}
+
+// WriteBand 實現 BandSink 接口
+func (hr *HookRunner) WriteBand(band BandEvent) error {
+	event := HookEvent{
+		Event:    HookBandChanged,
+		Time:     band.Timestamp,
+		SlaveID:  band.Reading.SlaveID,
+		Pressure: band.Reading.Pressure,
+		Valid:    band.Reading.Valid,
+		Band:     band.To,
+		FromBand: band.From,
+		Message:  band.Message,
+	}
+	event.setLocation(band.Location)
+	hr.fire(event, nil)
+	return nil
+}
+
+// Check 實現 SinkChecker 接口，確認外部腳本的命令可執行
+func (hr *HookRunner) Check() error {
+	for _, hook := range hr.hooks {
+		if _, err := exec.LookPath(hook.Command); err != nil {
+			return fmt.Errorf("腳本 %s 的命令不可執行: %v", hook.Name, err)
+		}
+	}
+	return nil
+}
+
+// Close 等待執行中的腳本結束
+func (hr *HookRunner) Close() error {
+	hr.wg.Wait()
+	return nil
+}
+
+// fire 在背景執行所有監聽該事件的腳本，only 不為空時只執行指定腳本
+func (hr *HookRunner) fire(event HookEvent, only *compiledHook) {
+	for _, hook := range hr.hooks {
+		if only != nil && hook != only {
+			continue
+		}
+		if len(hook.events) > 0 && !hook.events[event.Event] {
+			continue
+		}
+		// 閾值事件只屬於設置了該閾值的腳本
+		if event.Event == HookThresholdCrossed && hook != only {
+			continue
+		}
+
+		args, err := hook.render(event)
+		if err != nil {
+			hr.logger.Printf("❌ 腳本 %s 參數渲染失敗: %v", hook.Name, err)
+			continue
+		}
+
+		timeout := hook.Timeout
+		if timeout <= 0 {
+			timeout = DefaultHookTimeout
+		}
+
+		hr.wg.Add(1)
+		go func(hook *compiledHook) {
+			defer hr.wg.Done()
+			if err := runCommand(hook.Command, args, event.env(), timeout); err != nil {
+				hr.logger.Printf("❌ 腳本 %s (%s) 出錯: %v", hook.Name, event.Event, err)
+			}
+		}(hook)
+	}
+}
+
+// render 用事件數據渲染參數模板
+func (ch *compiledHook) render(event HookEvent) ([]string, error) {
+	args := make([]string, 0, len(ch.args))
+	for _, tmpl := range ch.args {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, event); err != nil {
+			return nil, err
+		}
+		args = append(args, buf.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,286 @@
+// pressure/identity.go - 讀取設備標識（廠商型號、韌體版本寄存器或功能碼 0x2B/0x0E）
+package pressure
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// 讀取設備標識的功能碼（Modbus Encapsulated Interface）
+const (
+	FunctionEncapsulatedInterface = 0x2B // 封裝接口傳輸
+	MEIReadDeviceIdentification   = 0x0E // 讀取設備標識
+)
+
+// 設備標識對象（基本類別）
+const (
+	DeviceIDVendorName  byte = 0x00 // 製造商名稱
+	DeviceIDProductCode byte = 0x01 // 產品代碼
+	DeviceIDRevision    byte = 0x02 // 主次版本號
+)
+
+// deviceIDBasic 讀取基本設備標識的流式訪問代碼
+const deviceIDBasic = 0x01
+
+// maxDeviceIDRequests 設備標識分多個響應返回時最多請求的次數
+const maxDeviceIDRequests = 4
+
+// ReadDeviceInfo 讀取設備的製造商、型號和韌體版本，結果附在 GetStatus 中
+//
+// 設備配置檔設置了型號或韌體版本寄存器時讀取這些寄存器，否則使用功能碼 0x2B/0x0E 讀取基本設備標識。
+// RTU 傳輸下功能碼 0x2B 的響應長度不固定，需暫時直接打開串口，應在 Start 之前調用。
+func (pm *PressureMeter) ReadDeviceInfo() (*DeviceModel, error) {
+	pm.busMu.Lock()
+	var model *DeviceModel
+	var err error
+	if pm.profile.ModelRegister != 0 || pm.profile.FirmwareRegister != 0 {
+		model, err = pm.readIdentityRegisters()
+	} else {
+		model, err = pm.readDeviceIdentification()
+	}
+	pm.busMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if model.Manufacturer == "" {
+		model.Manufacturer = pm.profile.Manufacturer
+	}
+
+	pm.statusMu.Lock()
+	pm.model = model
+	pm.statusMu.Unlock()
+	return model, nil
+}
+
+// DeviceModel 返回上次 ReadDeviceInfo 讀取到的設備標識，尚未讀取時返回 nil
+func (pm *PressureMeter) DeviceModel() *DeviceModel {
+	pm.statusMu.Lock()
+	defer pm.statusMu.Unlock()
+	return pm.model
+}
+
+// readIdentityRegisters 按設備配置檔讀取型號和韌體版本寄存器
+func (pm *PressureMeter) readIdentityRegisters() (*DeviceModel, error) {
+	model := &DeviceModel{Description: pm.profile.Description}
+	if address := pm.profile.ModelRegister; address != 0 {
+		count := pm.profile.ModelLength
+		if count == 0 {
+			count = 1
+		}
+		data, err := pm.client.ReadHoldingRegisters(address, count)
+		if err != nil {
+			return nil, fmt.Errorf("讀取型號寄存器 0x%04X 失敗: %v", address, err)
+		}
+		if len(data) != int(count)*2 {
+			return nil, fmt.Errorf("讀取型號寄存器 0x%04X 返回長度錯誤: %d 字節", address, len(data))
+		}
+		if pm.profile.ModelLength == 0 {
+			model.Model = fmt.Sprintf("%d", binary.BigEndian.Uint16(data))
+		} else {
+			model.Model = strings.TrimSpace(strings.TrimRight(string(data), "\x00"))
+		}
+	}
+	if address := pm.profile.FirmwareRegister; address != 0 {
+		value, err := pm.readRegister(address)
+		if err != nil {
+			return nil, err
+		}
+		model.Version = fmt.Sprintf("%d.%02d", value>>8, value&0xFF)
+	}
+	return model, nil
+}
+
+// readDeviceIdentification 使用功能碼 0x2B/0x0E 讀取基本設備標識
+func (pm *PressureMeter) readDeviceIdentification() (*DeviceModel, error) {
+	if _, serial := pm.handler.(*modbus.RTUClientHandler); !serial {
+		return queryDeviceIdentification(pm.transactMEI)
+	}
+
+	pm.connMu.Lock()
+	defer pm.connMu.Unlock()
+
+	// 串口同一時間只能由一方打開，讀取期間暫時關閉 Modbus 連接
+	pm.handler.Close()
+	defer func() {
+		if err := pm.connect(); err != nil {
+			pm.connected = false
+			pm.backoff = OpenRetryInitialBackoff
+			pm.nextConnect = time.Now().Add(pm.backoff)
+			pm.logger.Printf("⚠️  讀取設備標識後重新打開 %s 失敗: %v", pm.endpoint, err)
+			return
+		}
+		pm.connected = true
+	}()
+
+	line, err := openDiagLine(pm.endpoint, pm.baudRate, pm.parity, pm.stopBits, pm.slaveID, pm.respTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer line.port.Close()
+	return queryDeviceIdentification(line.transactMEI)
+}
+
+// transactMEI 通過 Modbus 連接發送功能碼 0x2B 請求，返回響應 PDU 的數據部分
+//
+// Modbus TCP 的響應帶長度，串口服務器按 rtuFrameLen 判斷幀長度，都可以直接使用；本地串口需改用 diagLine。
+func (pm *PressureMeter) transactMEI(data []byte) ([]byte, error) {
+	return handlerTransactMEI(pm.handler, pm.tracer, data)
+}
+
+// handlerTransactMEI 用 Modbus 處理器編碼、發送和校驗功能碼 0x2B 請求，返回響應 PDU 的數據部分
+func handlerTransactMEI(packager modbus.Packager, transporter modbus.Transporter, data []byte) ([]byte, error) {
+	request, err := packager.Encode(&modbus.ProtocolDataUnit{FunctionCode: FunctionEncapsulatedInterface, Data: data})
+	if err != nil {
+		return nil, err
+	}
+	response, err := transporter.Send(request)
+	if err != nil {
+		return nil, err
+	}
+	if err := packager.Verify(request, response); err != nil {
+		return nil, fmt.Errorf("響應無效: %v", err)
+	}
+	pdu, err := packager.Decode(response)
+	if err != nil {
+		return nil, err
+	}
+	if pdu.FunctionCode != FunctionEncapsulatedInterface {
+		exception := &modbus.ModbusError{FunctionCode: pdu.FunctionCode}
+		if len(pdu.Data) > 0 {
+			exception.ExceptionCode = pdu.Data[0]
+		}
+		return nil, exception
+	}
+	return pdu.Data, nil
+}
+
+// transactMEI 在串口上發送功能碼 0x2B 請求，按響應中的對象數量和長度判斷幀是否收完
+func (dl *diagLine) transactMEI(data []byte) ([]byte, error) {
+	return dl.exchange(FunctionEncapsulatedInterface, data, deviceIDFrameLen)
+}
+
+// deviceIDFrameLen 返回讀取設備標識響應幀的總長度，收到的字節不足以確定時返回 0
+//
+// 幀格式：站點號、功能碼、MEI 類型、訪問代碼、一致性等級、後續標記、下一對象號、對象數量，
+// 每個對象為對象號、長度和值，最後是 CRC。
+func deviceIDFrameLen(frame []byte) int {
+	const header = 8
+	if len(frame) < header {
+		return 0
+	}
+	offset := header
+	for i := 0; i < int(frame[header-1]); i++ {
+		if len(frame) < offset+2 {
+			return 0
+		}
+		offset += 2 + int(frame[offset+1])
+	}
+	return offset + 2
+}
+
+// queryDeviceIdentification 讀取基本設備標識，設備分多個響應返回時繼續請求後續對象
+func queryDeviceIdentification(transact func(data []byte) ([]byte, error)) (*DeviceModel, error) {
+	objects := make(map[byte]string)
+	next := DeviceIDVendorName
+	for i := 0; i < maxDeviceIDRequests; i++ {
+		response, err := transact([]byte{MEIReadDeviceIdentification, deviceIDBasic, next})
+		if err != nil {
+			var modbusErr *modbus.ModbusError
+			if errors.As(err, &modbusErr) {
+				return nil, fmt.Errorf("設備不支援讀取設備標識 (功能碼 0x2B/0x0E): %v", err)
+			}
+			return nil, fmt.Errorf("讀取設備標識失敗: %v", err)
+		}
+		more, nextObject, err := parseDeviceID(response, objects)
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			break
+		}
+		next = nextObject
+	}
+
+	return &DeviceModel{
+		Manufacturer: objects[DeviceIDVendorName],
+		Model:        objects[DeviceIDProductCode],
+		Version:      objects[DeviceIDRevision],
+	}, nil
+}
+
+// parseDeviceID 解析讀取設備標識響應的 PDU 數據，將對象寫入 objects，返回是否還有後續對象
+func parseDeviceID(data []byte, objects map[byte]string) (more bool, next byte, err error) {
+	// MEI 類型、訪問代碼、一致性等級、後續標記、下一對象號、對象數量
+	const header = 6
+	if len(data) < header || data[0] != MEIReadDeviceIdentification {
+		return false, 0, fmt.Errorf("設備標識響應格式錯誤: % X", data)
+	}
+	offset := header
+	for i := 0; i < int(data[header-1]); i++ {
+		if len(data) < offset+2 || len(data) < offset+2+int(data[offset+1]) {
+			return false, 0, fmt.Errorf("設備標識響應不完整: % X", data)
+		}
+		id, length := data[offset], int(data[offset+1])
+		objects[id] = strings.TrimSpace(string(data[offset+2 : offset+2+length]))
+		offset += 2 + length
+	}
+	return data[3] == 0xFF, data[4], nil
+}
+
+// readIdentity 掃描時讀取已響應設備的基本設備標識，讀取期間暫時關閉掃描使用的串口連接
+func (s *Scanner) readIdentity(handler *modbus.RTUClientHandler, port string, setting lineSetting, slaveID byte, timeout time.Duration) (*DeviceModel, error) {
+	handler.Close()
+	defer connectHandler(handler, timeout)
+
+	line, err := openDiagLine(port, setting.BaudRate, setting.Parity, setting.StopBits, slaveID, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer line.port.Close()
+	return queryDeviceIdentification(line.transactMEI)
+}
+
+// recordIdentity 將掃描時讀取到的設備標識寫入設備信息，讀取失敗時只記錄日誌
+func (s *Scanner) recordIdentity(device *DeviceInfo, model *DeviceModel, err error) {
+	if err != nil {
+		s.logf("    ℹ️  站點 %d 未返回設備標識: %v", device.SlaveID, err)
+		return
+	}
+	device.Model = model
+	device.Properties["model"] = model.String()
+	if model.Manufacturer != "" {
+		device.Properties["manufacturer"] = model.Manufacturer
+	}
+	if DeviceIsForeign(*device) {
+		s.logf("    ⚠️  站點 %d 是 %s，不是內建配置檔支援的儀表", device.SlaveID, model)
+	}
+}
+
+// DeviceIsForeign 設備標識顯示其製造商不是內建設備配置檔的製造商（如碰巧在壓力寄存器地址響應的其他 Modbus 設備）
+//
+// 未讀取到設備標識或標識中沒有製造商時無法判斷，返回 false。
+func DeviceIsForeign(device DeviceInfo) bool {
+	if device.Model == nil || device.Model.Manufacturer == "" {
+		return false
+	}
+	return !isProfileManufacturer(device.Model.Manufacturer)
+}
+
+// isProfileManufacturer 製造商名稱是否與內建設備配置檔的製造商一致（普時達也接受英文名稱 Pushida）
+func isProfileManufacturer(name string) bool {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if strings.Contains(name, "pushida") {
+		return true
+	}
+	for _, profile := range deviceProfiles {
+		if profile.Manufacturer != "" && strings.Contains(name, strings.ToLower(profile.Manufacturer)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+// pressure/latency.go - 讀取延遲預算：超時讀數標記為降級，連續超出時觸發告警
+package pressure
+
+import (
+	"fmt"
+	"time"
+)
+
+// LatencyAlarmRule 延遲預算告警的規則名稱
+const LatencyAlarmRule = "latency_budget"
+
+// DefaultLatencyViolations 觸發延遲預算告警的默認連續超出次數
+const DefaultLatencyViolations = 5
+
+// evaluateLatency 統計超出延遲預算的讀數，連續超出達到上限時觸發告警，恢復後解除（調用方需持有鎖）
+//
+// 無效讀數不改變連續計數，避免與連續失敗處理重複。
+func (m *Monitor) evaluateLatency(reading PressureReading, location *Location) []AlarmEvent {
+	if m.config.LatencyBudget <= 0 || !reading.Valid {
+		return nil
+	}
+
+	if !reading.Degraded {
+		m.latencyStreak = 0
+		if !m.latencyAlarm {
+			return nil
+		}
+		m.latencyAlarm = false
+		return []AlarmEvent{{
+			Rule:      LatencyAlarmRule,
+			Active:    false,
+			Message:   fmt.Sprintf("讀取耗時 %v 已回到預算 %v 內", reading.Duration.Round(time.Microsecond), m.config.LatencyBudget),
+			Reading:   reading,
+			Location:  location,
+			Timestamp: time.Now(),
+		}}
+	}
+
+	m.stats.LatencyViolations++
+	m.latencyStreak++
+	threshold := m.config.LatencyViolations
+	if threshold <= 0 {
+		threshold = DefaultLatencyViolations
+	}
+	if m.latencyAlarm || m.latencyStreak < threshold {
+		return nil
+	}
+
+	m.latencyAlarm = true
+	return []AlarmEvent{{
+		Rule:      LatencyAlarmRule,
+		Active:    true,
+		Message:   m.latencySuggestion(reading),
+		Reading:   reading,
+		Location:  location,
+		Timestamp: time.Now(),
+	}}
+}
+
+// latencySuggestion 描述延遲超出情況並給出調整建議
+func (m *Monitor) latencySuggestion(reading PressureReading) string {
+	message := fmt.Sprintf("連續 %d 次讀取耗時超出預算 %v (最近 %v)，建議提高波特率、加大讀取間隔 (當前 %v) 或檢查線路和轉換器",
+		m.latencyStreak, m.config.LatencyBudget, reading.Duration.Round(time.Microsecond), m.interval)
+
+	if !m.config.IsTCP() {
+		plan := NewBusPlan(m.config, 1, 0, 0)
+		if perDevice := plan.CycleTime; perDevice > m.config.LatencyBudget {
+			message += fmt.Sprintf("；預算低於 %d bps 下的估算事務耗時 %v，請放寬預算",
+				plan.BaudRate, perDevice.Round(time.Millisecond))
+		}
+	}
+	return message
+}
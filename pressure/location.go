@@ -0,0 +1,188 @@
+// pressure/location.go - 站點號/串口到安裝位置的對照表（CSV）
+package pressure
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LocationAnyPort 對照表中匹配任意串口的寫法
+const LocationAnyPort = "*"
+
+// Location 設備的安裝位置
+type Location struct {
+	Port     string `json:"port,omitempty"`      // 串口，空或 * 表示任意串口
+	SlaveID  byte   `json:"slave_id"`            // 站點號
+	Room     string `json:"room,omitempty"`      // 房間名稱
+	Floor    string `json:"floor,omitempty"`     // 樓層
+	AssetTag string `json:"asset_tag,omitempty"` // 資產編號
+}
+
+// String 返回位置的簡短描述，如 "3F 潔淨室A [AT-0012]"
+func (l Location) String() string {
+	var parts []string
+	if l.Floor != "" {
+		parts = append(parts, l.Floor)
+	}
+	if l.Room != "" {
+		parts = append(parts, l.Room)
+	}
+	if l.AssetTag != "" {
+		parts = append(parts, "["+l.AssetTag+"]")
+	}
+	return strings.Join(parts, " ")
+}
+
+// locationKey 對照表索引
+type locationKey struct {
+	port    string
+	slaveID byte
+}
+
+// LocationMap 站點號/串口到安裝位置的對照表
+//
+// 由設施人員以 CSV 維護，不需要修改主配置。nil 對照表可以安全使用，查詢總是返回 nil。
+type LocationMap struct {
+	entries map[locationKey]Location
+}
+
+// NewLocationMap 創建空的對照表
+func NewLocationMap() *LocationMap {
+	return &LocationMap{entries: make(map[locationKey]Location)}
+}
+
+// Add 添加或覆蓋一個位置
+func (lm *LocationMap) Add(location Location) *LocationMap {
+	if location.Port == LocationAnyPort {
+		location.Port = ""
+	}
+	lm.entries[locationKey{port: location.Port, slaveID: location.SlaveID}] = location
+	return lm
+}
+
+// Len 返回對照表中的位置數量
+func (lm *LocationMap) Len() int {
+	if lm == nil {
+		return 0
+	}
+	return len(lm.entries)
+}
+
+// Lookup 查找設備的安裝位置，先匹配串口和站點號，再匹配任意串口的條目，找不到時返回 nil
+func (lm *LocationMap) Lookup(port string, slaveID byte) *Location {
+	if lm == nil {
+		return nil
+	}
+	if location, ok := lm.entries[locationKey{port: port, slaveID: slaveID}]; ok {
+		return &location
+	}
+	if location, ok := lm.entries[locationKey{slaveID: slaveID}]; ok {
+		return &location
+	}
+	return nil
+}
+
+// Annotate 為掃描結果中的設備填入安裝位置
+func (lm *LocationMap) Annotate(result *ScanResult) {
+	if lm == nil || result == nil {
+		return
+	}
+	for i := range result.Devices {
+		result.Devices[i].Location = lm.Lookup(result.Devices[i].Device, result.Devices[i].SlaveID)
+	}
+}
+
+// LoadLocationMap 從 CSV 檔案載入對照表
+func LoadLocationMap(filename string) (*LocationMap, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("打開位置對照表失敗: %v", err)
+	}
+	defer file.Close()
+
+	lm, err := ReadLocationMap(file)
+	if err != nil {
+		return nil, fmt.Errorf("位置對照表 %s: %v", filename, err)
+	}
+	return lm, nil
+}
+
+// ReadLocationMap 從 CSV 讀取對照表
+//
+// 第一行為表頭，列順序不限：slave_id（必需）、port、room、floor、asset_tag。
+// port 為空或 * 時匹配任意串口；以 # 開頭的行為註釋。
+func ReadLocationMap(r io.Reader) (*LocationMap, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("檔案為空")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("讀取表頭失敗: %v", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	if _, ok := columns["slave_id"]; !ok {
+		return nil, fmt.Errorf("表頭缺少 slave_id 列")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	lm := NewLocationMap()
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		idStr := field(record, "slave_id")
+		if idStr == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(idStr, 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行站點號無效: %s", line, idStr)
+		}
+		if err := CheckPollingSlaveID(byte(id)); err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", line, err)
+		}
+
+		location := Location{
+			Port:     field(record, "port"),
+			SlaveID:  byte(id),
+			Room:     field(record, "room"),
+			Floor:    field(record, "floor"),
+			AssetTag: field(record, "asset_tag"),
+		}
+		if location.Port == LocationAnyPort {
+			location.Port = ""
+		}
+		if _, exists := lm.entries[locationKey{port: location.Port, slaveID: location.SlaveID}]; exists {
+			return nil, fmt.Errorf("第 %d 行與前面的條目重複: 串口 %q 站點 %d", line, location.Port, location.SlaveID)
+		}
+		lm.Add(location)
+	}
+	return lm, nil
+}
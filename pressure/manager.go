@@ -0,0 +1,420 @@
+// pressure/manager.go - 多串口設備管理器：管理多個串口上的儀表和總線，合併讀數
+package pressure
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultManagerRestartAfter 連續多少個讀數因連接錯誤或超時失敗後重新打開串口
+const DefaultManagerRestartAfter = 10
+
+// 設備管理器中設備的狀態
+const (
+	ManagedStateStopped  = "stopped"  // 已停止
+	ManagedStateStarting = "starting" // 正在打開連接（失敗時按退避時間重試）
+	ManagedStateRunning  = "running"  // 正在讀取
+)
+
+// ManagedDeviceConfig 設備管理器中一個設備（單台儀表或一條總線）的配置
+type ManagedDeviceConfig struct {
+	Name         string           // 唯一名稱，為空時使用連接端點（串口路徑或 TCP 地址）
+	Config       Config           // 連接和讀取配置
+	SlaveIDs     []byte           // 同一總線上輪詢的站點號，為空時只讀取 Config.SlaveID
+	Interval     time.Duration    // 讀取間隔，0 為 Config.ReadInterval
+	Synchronized bool             // 同一總線上多台儀表時使用同步採樣（見 BusPoller.SetSynchronized）
+	Discovery    *DiscoveryConfig // 後台發現配置，設置後新接入總線的儀表自動加入輪詢（見 BusPoller.StartDiscovery）
+}
+
+// ManagedReading 合併讀數流中的讀數，Device 為設備名稱或虛擬壓差通道名稱
+type ManagedReading struct {
+	Device string `json:"device"`
+	PressureReading
+}
+
+// ManagedDeviceStatus 設備管理器中一個設備的狀態
+type ManagedDeviceStatus struct {
+	Name              string     `json:"name"`                   // 設備名稱
+	Endpoint          string     `json:"endpoint"`               // 串口路徑或 TCP 地址
+	SlaveIDs          []int      `json:"slave_ids"`              // 讀取的站點號
+	State             string     `json:"state"`                  // stopped/starting/running
+	Since             time.Time  `json:"since"`                  // 進入當前狀態的時間
+	Restarts          int        `json:"restarts"`               // 因連續失敗重新打開串口的次數
+	Readings          int64      `json:"readings"`               // 讀數總數
+	Errors            int64      `json:"errors"`                 // 無效讀數
+	ConsecutiveErrors int        `json:"consecutive_errors"`     // 當前連續的連接錯誤或超時
+	LastReading       *time.Time `json:"last_reading,omitempty"` // 最近一個讀數的時間
+	LastError         string     `json:"last_error,omitempty"`   // 最近一次錯誤
+}
+
+// managedDevice 設備管理器中的一個設備
+type managedDevice struct {
+	spec   ManagedDeviceConfig
+	status ManagedDeviceStatus
+	stop   chan struct{} // 關閉時停止監督協程，為空表示未啟動
+	done   chan struct{} // 監督協程退出時關閉
+}
+
+// Manager 管理多個串口上的儀表和總線
+//
+// 每個設備在自己的協程中讀取，讀數合併到一個通道；打開失敗時按退避時間重試，
+// 連續讀取失敗時關閉並重新打開串口（如 USB 轉換器重新枚舉）。
+type Manager struct {
+	logger       *log.Logger
+	restartAfter int
+
+	mu            sync.Mutex
+	devices       map[string]*managedDevice
+	differentials []*differential // 虛擬壓差通道
+	readings      chan ManagedReading
+	events        *EventBus // 事件總線，為空則不發布
+	dropped       int64
+	closed        bool
+}
+
+// NewManager 創建設備管理器
+func NewManager(logger *log.Logger) *Manager {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Manager{
+		logger:       logger,
+		restartAfter: DefaultManagerRestartAfter,
+		devices:      make(map[string]*managedDevice),
+		readings:     make(chan ManagedReading, DefaultReadingBufferSize),
+	}
+}
+
+// SetRestartAfter 設置連續多少個讀數因連接錯誤或超時失敗後重新打開串口，0 為不自動重啟
+func (m *Manager) SetRestartAfter(failures int) *Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.restartAfter = failures
+	return m
+}
+
+// SetEventBus 設置事件總線，發布各設備的連接和讀數事件及後台發現的事件，需在啟動設備前設置
+func (m *Manager) SetEventBus(bus *EventBus) *Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = bus
+	return m
+}
+
+// Add 添加設備（不啟動），名稱不能重複
+//
+// 站點號和設備配置檔在添加時檢查；打開連接的錯誤在啟動後由背景重試處理。
+func (m *Manager) Add(device ManagedDeviceConfig) error {
+	if device.Name == "" {
+		device.Name = device.Config.Endpoint()
+	}
+	if len(device.SlaveIDs) == 0 {
+		device.SlaveIDs = []byte{device.Config.SlaveID}
+	}
+	if device.Interval <= 0 {
+		device.Interval = device.Config.ReadInterval
+	}
+	if device.Interval <= 0 {
+		device.Interval = DefaultReadInterval
+	}
+	if err := checkSlaveIDList(device.SlaveIDs); err != nil {
+		return fmt.Errorf("設備 %s: %v", device.Name, err)
+	}
+	if _, err := ResolveDeviceProfile(device.Config); err != nil {
+		return fmt.Errorf("設備 %s: %v", device.Name, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return fmt.Errorf("設備管理器已關閉")
+	}
+	if _, ok := m.devices[device.Name]; ok {
+		return fmt.Errorf("設備 %s 已存在", device.Name)
+	}
+
+	status := ManagedDeviceStatus{
+		Name:     device.Name,
+		Endpoint: device.Config.Endpoint(),
+		State:    ManagedStateStopped,
+		Since:    time.Now(),
+	}
+	for _, id := range device.SlaveIDs {
+		status.SlaveIDs = append(status.SlaveIDs, int(id))
+	}
+	m.devices[device.Name] = &managedDevice{spec: device, status: status}
+	return nil
+}
+
+// Remove 停止並移除設備
+func (m *Manager) Remove(name string) error {
+	if err := m.Stop(name); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.devices, name)
+	return nil
+}
+
+// Start 啟動設備，打開連接失敗時在背景按退避時間重試
+func (m *Manager) Start(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return fmt.Errorf("設備管理器已關閉")
+	}
+	md, ok := m.devices[name]
+	if !ok {
+		return fmt.Errorf("未知的設備: %s", name)
+	}
+	if md.stop != nil {
+		return fmt.Errorf("設備 %s 已在運行", name)
+	}
+
+	md.stop = make(chan struct{})
+	md.done = make(chan struct{})
+	m.setStateLocked(md, ManagedStateStarting)
+	go m.supervise(md, md.stop, md.done)
+	return nil
+}
+
+// Stop 停止設備並關閉串口，等待讀取協程退出
+func (m *Manager) Stop(name string) error {
+	m.mu.Lock()
+	md, ok := m.devices[name]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("未知的設備: %s", name)
+	}
+	stop, done := md.stop, md.done
+	md.stop, md.done = nil, nil
+	m.mu.Unlock()
+
+	if stop == nil {
+		return nil
+	}
+	close(stop)
+	<-done
+	return nil
+}
+
+// Restart 停止設備並重新打開串口
+func (m *Manager) Restart(name string) error {
+	if err := m.Stop(name); err != nil {
+		return err
+	}
+	return m.Start(name)
+}
+
+// StartAll 啟動所有未運行的設備
+func (m *Manager) StartAll() error {
+	for _, name := range m.Names() {
+		if m.State(name) != ManagedStateStopped {
+			continue
+		}
+		if err := m.Start(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StopAll 停止所有設備
+func (m *Manager) StopAll() {
+	for _, name := range m.Names() {
+		m.Stop(name)
+	}
+}
+
+// Close 停止所有設備並關閉合併讀數通道
+func (m *Manager) Close() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	m.mu.Unlock()
+
+	m.StopAll()
+	close(m.readings)
+}
+
+// Readings 返回所有設備的合併讀數通道，通道已滿時丟棄最舊的讀數；Close 後通道關閉
+func (m *Manager) Readings() <-chan ManagedReading {
+	return m.readings
+}
+
+// Dropped 返回合併讀數通道已滿時丟棄的讀數總數
+func (m *Manager) Dropped() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dropped
+}
+
+// Names 返回所有設備名稱（按名稱排序）
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.devices))
+	for name := range m.devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// State 返回設備的狀態，未知設備返回空字符串
+func (m *Manager) State(name string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if md, ok := m.devices[name]; ok {
+		return md.status.State
+	}
+	return ""
+}
+
+// Status 返回所有設備的狀態（按名稱排序）
+func (m *Manager) Status() []ManagedDeviceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := make([]ManagedDeviceStatus, 0, len(m.devices))
+	for _, md := range m.devices {
+		status = append(status, md.status)
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+	return status
+}
+
+// supervise 打開連接並讀取，直到 stop 關閉；連續失敗過多時重新打開
+func (m *Manager) supervise(md *managedDevice, stop, done chan struct{}) {
+	defer close(done)
+	spec := md.spec
+	backoff := time.Duration(0)
+
+	for {
+		config := spec.Config
+		if config.Logger == nil {
+			config.Logger = m.logger
+		}
+		bus, err := NewBusPoller(config, spec.SlaveIDs)
+		if err != nil {
+			backoff = nextBackoff(backoff)
+			m.mu.Lock()
+			md.status.LastError = err.Error()
+			m.mu.Unlock()
+			m.logger.Printf("⚠️  設備 %s 打開失敗: %v，%v 後重試", spec.Name, err, backoff)
+			select {
+			case <-stop:
+				m.setState(md, ManagedStateStopped)
+				return
+			case <-time.After(backoff):
+				continue
+			}
+		}
+		backoff = 0
+
+		m.mu.Lock()
+		bus.SetEventBus(m.events)
+		m.mu.Unlock()
+		bus.SetSynchronized(spec.Synchronized)
+		bus.Start(spec.Interval)
+		if spec.Discovery != nil {
+			if _, err := bus.StartDiscovery(*spec.Discovery); err != nil {
+				m.logger.Printf("⚠️  設備 %s: %v", spec.Name, err)
+			}
+		}
+		m.setState(md, ManagedStateRunning)
+		restart := m.forward(md, bus, stop)
+		bus.Close()
+		if !restart {
+			m.setState(md, ManagedStateStopped)
+			return
+		}
+
+		m.mu.Lock()
+		md.status.Restarts++
+		md.status.ConsecutiveErrors = 0
+		m.setStateLocked(md, ManagedStateStarting)
+		m.mu.Unlock()
+		m.logger.Printf("🔄 設備 %s 連續讀取失敗，重新打開 %s", spec.Name, md.status.Endpoint)
+	}
+}
+
+// forward 將總線的讀數轉發到合併通道，返回是否需要重新打開連接
+func (m *Manager) forward(md *managedDevice, bus *BusPoller, stop chan struct{}) bool {
+	for {
+		select {
+		case <-stop:
+			return false
+		case reading := <-bus.GetReadings():
+			if m.record(md, reading) {
+				return true
+			}
+			m.publish(ManagedReading{Device: md.spec.Name, PressureReading: reading})
+			for _, derived := range m.derive(md.spec.Name, reading) {
+				m.publish(derived)
+			}
+		}
+	}
+}
+
+// record 更新設備狀態，返回是否達到自動重啟的條件
+func (m *Manager) record(md *managedDevice, reading PressureReading) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	status := &md.status
+	status.Readings++
+	timestamp := reading.Timestamp
+	status.LastReading = &timestamp
+	if reading.Valid {
+		status.ConsecutiveErrors = 0
+		return false
+	}
+
+	status.Errors++
+	status.LastError = reading.Error
+	if reading.ErrorCode == ErrConnection || reading.ErrorCode == ErrTimeout {
+		status.ConsecutiveErrors++
+	}
+	return m.restartAfter > 0 && status.ConsecutiveErrors >= m.restartAfter
+}
+
+// publish 將讀數放入合併通道，通道已滿時丟棄最舊的讀數
+func (m *Manager) publish(reading ManagedReading) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	select {
+	case m.readings <- reading:
+	default:
+		select {
+		case <-m.readings:
+			m.dropped++
+		default:
+		}
+		m.readings <- reading
+	}
+}
+
+// setState 更新設備狀態
+func (m *Manager) setState(md *managedDevice, state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setStateLocked(md, state)
+}
+
+// setStateLocked 更新設備狀態（調用方需持有鎖）
+func (m *Manager) setStateLocked(md *managedDevice, state string) {
+	if md.status.State != state {
+		md.status.State = state
+		md.status.Since = time.Now()
+	}
+}
@@ -0,0 +1,226 @@
+// pressure/migrate.go - 已記錄讀數檔案的格式遷移：升級到當前 SchemaVersion
+package pressure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// schemaVersionNone 加入 schema_version 之前的記錄
+const schemaVersionNone = "0"
+
+// recordMigration 將記錄從一個格式版本升級到下一個版本
+type recordMigration struct {
+	from, to string
+	apply    func(record map[string]interface{})
+}
+
+// recordMigrations 按順序排列的遷移步驟，主版本號遞增時在此追加字段改名等轉換
+var recordMigrations = []recordMigration{
+	{from: schemaVersionNone, to: "1.0", apply: func(record map[string]interface{}) {
+		// 早期版本的有效讀數沒有單位字段
+		if valid, _ := record["valid"].(bool); valid {
+			if _, ok := record["unit"]; !ok {
+				record["unit"] = "Pa"
+			}
+		}
+	}},
+	{from: "1.0", to: "1.1", apply: func(record map[string]interface{}) {
+		// 1.1 只新增可選字段
+	}},
+}
+
+// MigrationResult 讀數檔案遷移的結果
+type MigrationResult struct {
+	File     string         `json:"file"`             // 檔案路徑
+	Records  int            `json:"records"`          // JSON 讀數記錄數
+	Migrated int            `json:"migrated"`         // 升級的記錄數
+	Versions map[string]int `json:"versions"`         // 遷移前各格式版本的記錄數，"0" 為沒有版本號的早期記錄
+	Skipped  int            `json:"skipped"`          // 保留原樣的非 JSON 行（如橫幅、日誌）
+	Backup   string         `json:"backup,omitempty"` // 修改前的備份，沒有修改時為空
+}
+
+// MigrateRecord 將一條讀數記錄升級到當前格式版本，返回是否有修改
+//
+// 主版本號比當前版本新的記錄無法遷移；次版本號較新的記錄兼容當前版本，保持不變。
+func MigrateRecord(record map[string]interface{}) (bool, error) {
+	version := schemaVersionNone
+	if v, ok := record["schema_version"].(string); ok {
+		version = v
+	}
+	if compareSchemaVersions(version, SchemaVersion) >= 0 {
+		if schemaMajor(version) > schemaMajor(SchemaVersion) {
+			return false, fmt.Errorf("記錄的格式版本 %s 比本程式支援的 %s 新，請升級程式", version, SchemaVersion)
+		}
+		return false, nil
+	}
+
+	for _, step := range recordMigrations {
+		if step.from == version {
+			step.apply(record)
+			version = step.to
+		}
+	}
+	if version != SchemaVersion {
+		return false, fmt.Errorf("不支援從格式版本 %s 遷移", record["schema_version"])
+	}
+	record["schema_version"] = SchemaVersion
+	return true, nil
+}
+
+// MigrateRecordingFile 將 --output=json 記錄的讀數檔案原地升級到當前格式版本
+//
+// 只改寫需要升級的行，其他行（已是當前版本的記錄、橫幅和日誌）保持原樣；
+// 有修改時先保存帶時間戳的備份（命名同 BackupConfigFile），再原子地寫回。
+// 合規日誌受雜湊鏈保護，不能改寫。
+func MigrateRecordingFile(filename string) (*MigrationResult, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("讀取檔案失敗: %v", err)
+	}
+
+	result := &MigrationResult{File: filename, Versions: make(map[string]int)}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	for i, line := range lines {
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 || trimmed[0] != '{' {
+			if len(trimmed) > 0 {
+				result.Skipped++
+			}
+			continue
+		}
+
+		var record map[string]interface{}
+		if err := json.Unmarshal(trimmed, &record); err != nil {
+			result.Skipped++
+			continue
+		}
+		if _, ok := record["prev_hash"]; ok {
+			return nil, fmt.Errorf("%s 是合規日誌，改寫會破壞雜湊鏈，不能遷移", filename)
+		}
+		if _, ok := record["timestamp"]; !ok {
+			result.Skipped++
+			continue
+		}
+
+		result.Records++
+		version, _ := record["schema_version"].(string)
+		if version == "" {
+			version = schemaVersionNone
+		}
+		result.Versions[version]++
+
+		changed, err := MigrateRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", i+1, err)
+		}
+		if !changed {
+			continue
+		}
+		migrated, err := marshalRecord(record, objectKeys(trimmed))
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: %v", i+1, err)
+		}
+		if bytes.HasSuffix(line, []byte("\n")) {
+			migrated = append(migrated, '\n')
+		}
+		lines[i] = migrated
+		result.Migrated++
+	}
+
+	if result.Migrated == 0 {
+		return result, nil
+	}
+	if result.Backup, err = BackupConfigFile(filename); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(filename, bytes.Join(lines, nil)); err != nil {
+		return nil, fmt.Errorf("寫入檔案失敗: %v", err)
+	}
+	return result, nil
+}
+
+// marshalRecord 序列化記錄：schema_version 在前，其餘字段按原有順序，新增的字段在後
+func marshalRecord(record map[string]interface{}, order []string) ([]byte, error) {
+	var added []string
+	for key := range record {
+		added = append(added, key)
+	}
+	sort.Strings(added)
+	keys := append(append([]string{"schema_version"}, order...), added...)
+
+	var buf bytes.Buffer
+	written := make(map[string]bool, len(record))
+	buf.WriteByte('{')
+	for _, key := range keys {
+		value, ok := record[key]
+		if !ok || written[key] {
+			continue
+		}
+		if len(written) > 0 {
+			buf.WriteByte(',')
+		}
+		written[key] = true
+		name, _ := json.Marshal(key)
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// objectKeys 返回 JSON 物件頂層字段的原有順序
+func objectKeys(data []byte) []string {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+	var keys []string
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return keys
+		}
+		key, _ := token.(string)
+		keys = append(keys, key)
+		var skip json.RawMessage
+		if err := decoder.Decode(&skip); err != nil {
+			return keys
+		}
+	}
+	return keys
+}
+
+// schemaMajor 返回格式版本的主版本號
+func schemaMajor(version string) int {
+	major, _, _ := strings.Cut(version, ".")
+	n, _ := strconv.Atoi(major)
+	return n
+}
+
+// compareSchemaVersions 比較兩個 "主.次" 格式版本，返回 -1、0 或 1
+func compareSchemaVersions(a, b string) int {
+	aMajor, aMinor, _ := strings.Cut(a, ".")
+	bMajor, bMinor, _ := strings.Cut(b, ".")
+	for _, pair := range [][2]string{{aMajor, bMajor}, {aMinor, bMinor}} {
+		x, _ := strconv.Atoi(pair[0])
+		y, _ := strconv.Atoi(pair[1])
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
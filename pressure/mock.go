@@ -0,0 +1,219 @@
+// pressure/mock.go - 模擬傳輸：不接硬件時以腳本化的寄存器值響應 Modbus 請求，用於測試解析、統計和告警
+package pressure
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/goburrow/modbus"
+)
+
+// MockTransport 模擬一台 Modbus RTU 儀表的傳輸層
+//
+// 設置到 Config.ModbusTransport 後，PressureMeter 和 Monitor 的請求由寄存器表響應，不打開串口或網絡連接。
+// 保持寄存器和輸入寄存器共用同一張表，讀取未設置的寄存器返回非法地址異常 (0x02)，寫入 (0x06/0x10) 更新寄存器表；
+// 只響應創建時的站點號，其他站點號按無響應處理，其他功能碼返回非法功能異常 (0x01)。
+type MockTransport struct {
+	packager *modbus.RTUClientHandler // 只用於 RTU 幀的編碼、校驗和解碼
+	slaveID  byte                     // 模擬儀表的站點號
+
+	mu         sync.Mutex
+	registers  map[uint16]uint16
+	scripts    map[uint16][][]uint16 // 從該地址開始讀取時依次寫入寄存器表的值
+	failures   int                   // 之後失敗的請求數
+	failErr    error
+	connectErr error
+	connected  bool
+	requests   int
+}
+
+// NewMockTransport 創建站點號為 slaveID、寄存器表為空的模擬傳輸
+func NewMockTransport(slaveID byte) *MockTransport {
+	packager := modbus.NewRTUClientHandler("")
+	packager.SlaveId = slaveID
+	return &MockTransport{
+		packager:  packager,
+		slaveID:   slaveID,
+		registers: make(map[uint16]uint16),
+		scripts:   make(map[uint16][][]uint16),
+	}
+}
+
+// SetRegisters 從 address 開始設置連續寄存器的值
+func (mt *MockTransport) SetRegisters(address uint16, values ...uint16) *MockTransport {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	mt.setRegisters(address, values)
+	return mt
+}
+
+// SetScript 設置從 address 開始讀取時依次返回的寄存器值
+//
+// 每次讀取前將下一組值寫入 address 開始的寄存器，用完後保持最後一組，可模擬壓力變化、越限和恢復。
+func (mt *MockTransport) SetScript(address uint16, steps ...[]uint16) *MockTransport {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	mt.scripts[address] = append([][]uint16(nil), steps...)
+	return mt
+}
+
+// SetFailures 之後的 n 個請求返回 err，err 為空時按無響應（超時）處理
+func (mt *MockTransport) SetFailures(n int, err error) *MockTransport {
+	if err == nil {
+		err = os.ErrDeadlineExceeded
+	}
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	mt.failures, mt.failErr = n, err
+	return mt
+}
+
+// SetConnectError 設置打開連接時返回的錯誤，nil 為可以連接
+func (mt *MockTransport) SetConnectError(err error) *MockTransport {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	mt.connectErr = err
+	return mt
+}
+
+// Register 返回寄存器表中的值，可用於確認寫入
+func (mt *MockTransport) Register(address uint16) (uint16, bool) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	value, ok := mt.registers[address]
+	return value, ok
+}
+
+// Requests 返回收到的請求數量
+func (mt *MockTransport) Requests() int {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	return mt.requests
+}
+
+// Encode 實現 modbus.Packager 接口
+func (mt *MockTransport) Encode(pdu *modbus.ProtocolDataUnit) ([]byte, error) {
+	return mt.packager.Encode(pdu)
+}
+
+// Decode 實現 modbus.Packager 接口
+func (mt *MockTransport) Decode(adu []byte) (*modbus.ProtocolDataUnit, error) {
+	return mt.packager.Decode(adu)
+}
+
+// Verify 實現 modbus.Packager 接口
+func (mt *MockTransport) Verify(aduRequest, aduResponse []byte) error {
+	return mt.packager.Verify(aduRequest, aduResponse)
+}
+
+// Connect 實現 ModbusTransport 接口
+func (mt *MockTransport) Connect() error {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	if mt.connectErr != nil {
+		return mt.connectErr
+	}
+	mt.connected = true
+	return nil
+}
+
+// Close 實現 ModbusTransport 接口
+func (mt *MockTransport) Close() error {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+	mt.connected = false
+	return nil
+}
+
+// Send 實現 modbus.Transporter 接口，按寄存器表生成響應幀
+func (mt *MockTransport) Send(request []byte) ([]byte, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	mt.requests++
+	if !mt.connected {
+		return nil, fmt.Errorf("模擬傳輸未連接")
+	}
+	if mt.failures > 0 {
+		mt.failures--
+		return nil, mt.failErr
+	}
+	if len(request) < 4 {
+		return nil, fmt.Errorf("請求幀過短: % X", request)
+	}
+	pdu, err := mt.packager.Decode(request)
+	if err != nil {
+		return nil, err
+	}
+	if request[0] != mt.slaveID {
+		return nil, os.ErrDeadlineExceeded
+	}
+
+	data, exception := mt.respond(pdu)
+	response := &modbus.ProtocolDataUnit{FunctionCode: pdu.FunctionCode, Data: data}
+	if exception != 0 {
+		response = &modbus.ProtocolDataUnit{FunctionCode: pdu.FunctionCode | 0x80, Data: []byte{exception}}
+	}
+	return mt.packager.Encode(response)
+}
+
+// respond 執行請求 PDU，返回響應數據或異常碼（調用方需持有鎖）
+func (mt *MockTransport) respond(pdu *modbus.ProtocolDataUnit) ([]byte, byte) {
+	data := pdu.Data
+	switch pdu.FunctionCode {
+	case ModbusFunctionReadHoldingRegisters, ModbusFunctionReadInputRegisters:
+		if len(data) != 4 {
+			return nil, exceptionIllegalValue
+		}
+		address, count := binary.BigEndian.Uint16(data), int(binary.BigEndian.Uint16(data[2:]))
+		if count < 1 || count > MaxReadRegisters {
+			return nil, exceptionIllegalValue
+		}
+		if steps := mt.scripts[address]; len(steps) > 0 {
+			mt.setRegisters(address, steps[0])
+			if len(steps) > 1 {
+				mt.scripts[address] = steps[1:]
+			}
+		}
+		response := []byte{byte(count * 2)}
+		for i := 0; i < count; i++ {
+			value, ok := mt.registers[address+uint16(i)]
+			if !ok || int(address)+i > 0xFFFF {
+				return nil, exceptionIllegalAddress
+			}
+			response = binary.BigEndian.AppendUint16(response, value)
+		}
+		return response, 0
+	case ModbusFunctionWriteSingleRegister:
+		if len(data) != 4 {
+			return nil, exceptionIllegalValue
+		}
+		mt.registers[binary.BigEndian.Uint16(data)] = binary.BigEndian.Uint16(data[2:])
+		return data, 0
+	case ModbusFunctionWriteMultipleRegisters:
+		if len(data) < 5 {
+			return nil, exceptionIllegalValue
+		}
+		address, count := binary.BigEndian.Uint16(data), int(binary.BigEndian.Uint16(data[2:]))
+		if count < 1 || int(data[4]) != count*2 || len(data) != 5+count*2 {
+			return nil, exceptionIllegalValue
+		}
+		values := make([]uint16, count)
+		for i := range values {
+			values[i] = binary.BigEndian.Uint16(data[5+2*i:])
+		}
+		mt.setRegisters(address, values)
+		return data[:4], 0
+	default:
+		return nil, exceptionIllegalFunction
+	}
+}
+
+// setRegisters 寫入寄存器表（調用方需持有鎖）
+func (mt *MockTransport) setRegisters(address uint16, values []uint16) {
+	for i, value := range values {
+		mt.registers[address+uint16(i)] = value
+	}
+}
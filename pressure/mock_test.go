@@ -0,0 +1,140 @@
+package pressure
+
+import (
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/goburrow/modbus"
+)
+
+// testConfig 返回經 transport 通信的儀表配置：站點號 1，日誌丟棄；opts 依次修改配置
+func testConfig(transport ModbusTransport, opts ...func(*Config)) Config {
+	config := Config{
+		Device:          "mock",
+		SlaveID:         1,
+		ModbusTransport: transport,
+		Logger:          log.New(io.Discard, "", 0),
+	}
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
+}
+
+// withControl 允許寫入儀表
+func withControl(config *Config) {
+	config.EnableControl = true
+}
+
+// newTestMeter 創建經 transport 通信的儀表，測試結束時關閉
+func newTestMeter(t *testing.T, transport ModbusTransport, opts ...func(*Config)) *PressureMeter {
+	t.Helper()
+	pm, err := NewPressureMeter(testConfig(transport, opts...))
+	if err != nil {
+		t.Fatalf("NewPressureMeter: %v", err)
+	}
+	t.Cleanup(func() { pm.Close() })
+	return pm
+}
+
+// newTestMonitor 創建經 transport 通信的監測流程，不啟動讀取協程，測試結束時關閉儀表
+func newTestMonitor(t *testing.T, transport ModbusTransport, opts ...func(*Config)) *Monitor {
+	t.Helper()
+	m, err := NewMonitor(testConfig(transport, opts...))
+	if err != nil {
+		t.Fatalf("NewMonitor: %v", err)
+	}
+	t.Cleanup(func() { m.Meter().Close() })
+	return m
+}
+
+func TestMockTransportRegisters(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0100, 7)
+	pm := newTestMeter(t, mock, withControl)
+
+	if value, err := pm.ReadRegister(0x0100); err != nil || value != 7 {
+		t.Fatalf("ReadRegister = %d, %v; want 7", value, err)
+	}
+	if err := pm.WriteRegister(0x0100, 42); err != nil {
+		t.Fatalf("WriteRegister: %v", err)
+	}
+	if value, ok := mock.Register(0x0100); !ok || value != 42 {
+		t.Errorf("register 0x0100 = %d, %v; want 42", value, ok)
+	}
+
+}
+
+func TestMockTransportExceptions(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0100, 7)
+	if err := mock.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		pdu       modbus.ProtocolDataUnit
+		exception byte
+	}{
+		{"unset register", modbus.ProtocolDataUnit{FunctionCode: ModbusFunctionReadHoldingRegisters, Data: []byte{0x02, 0x00, 0x00, 0x01}}, exceptionIllegalAddress},
+		{"zero count", modbus.ProtocolDataUnit{FunctionCode: ModbusFunctionReadHoldingRegisters, Data: []byte{0x01, 0x00, 0x00, 0x00}}, exceptionIllegalValue},
+		{"unsupported function", modbus.ProtocolDataUnit{FunctionCode: 0x2B, Data: []byte{0x0E}}, exceptionIllegalFunction},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, err := mock.Encode(&tt.pdu)
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := mock.Send(request)
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			pdu, err := mock.Decode(response)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pdu.FunctionCode != tt.pdu.FunctionCode|0x80 || len(pdu.Data) != 1 || pdu.Data[0] != tt.exception {
+				t.Errorf("response = %02X % X, want exception 0x%02X", pdu.FunctionCode, pdu.Data, tt.exception)
+			}
+		})
+	}
+
+	// 其他站點號按無響應處理，未連接時不響應
+	read := &modbus.ProtocolDataUnit{FunctionCode: ModbusFunctionReadHoldingRegisters, Data: []byte{0x01, 0x00, 0x00, 0x01}}
+	other := modbus.NewRTUClientHandler("")
+	other.SlaveId = 2
+	request, _ := other.Encode(read)
+	if _, err := mock.Send(request); ClassifyError(err) != ErrTimeout {
+		t.Errorf("Send to slave 2: %v, want timeout", err)
+	}
+	mock.Close()
+	request, _ = mock.Encode(read)
+	if _, err := mock.Send(request); err == nil {
+		t.Error("Send after Close: want error")
+	}
+}
+
+func TestMockTransportScriptAndFailures(t *testing.T) {
+	mock := NewMockTransport(1).SetScript(0x0034, []uint16{0, 10}, []uint16{0, 20})
+	pm := newTestMeter(t, mock)
+
+	for _, want := range []float64{1, 2, 2} {
+		if reading := pm.ReadPressure(); !reading.Valid || reading.Pressure != want {
+			t.Fatalf("scripted reading = %v (%s), want %v", reading.Pressure, reading.Error, want)
+		}
+	}
+
+	mock.SetFailures(1, errors.New("line noise"))
+	if reading := pm.ReadPressure(); reading.Valid {
+		t.Error("reading during scripted failure: want invalid")
+	}
+	requests := mock.Requests()
+	if reading := pm.ReadPressure(); !reading.Valid {
+		t.Errorf("reading after failures ran out: %s", reading.Error)
+	}
+	if mock.Requests() != requests+1 {
+		t.Errorf("requests = %d, want %d", mock.Requests(), requests+1)
+	}
+}
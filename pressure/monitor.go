@@ -0,0 +1,625 @@
+// pressure/monitor.go - 監測流程編排（讀取、統計、告警、輸出）
+package pressure
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// MonitorReading 交給輸出目標的讀數，附帶序號和當前統計
+type MonitorReading struct {
+	PressureReading
+	Count     int             `json:"count"`               // 本次運行的讀數序號
+	Stats     Statistics      `json:"stats"`               // 處理本讀數後的統計
+	Location  *Location       `json:"location,omitempty"`  // 安裝位置（設置了位置對照表時）
+	Deviation *float64        `json:"deviation,omitempty"` // 與設定值的偏差 (Pa)，僅配置了設定值且讀數有效時存在
+	Ambient   *AmbientReading `json:"ambient,omitempty"`   // 大氣壓參考，僅配置了大氣壓來源且最近刷新成功時存在
+}
+
+// Sink 讀數輸出目標
+type Sink interface {
+	WriteReading(reading MonitorReading) error
+	Close() error
+}
+
+// AlarmSink 可選接口，輸出目標實現後會收到告警觸發和解除事件
+type AlarmSink interface {
+	WriteAlarm(event AlarmEvent) error
+}
+
+// SinkFunc 將函數適配為 Sink
+type SinkFunc func(reading MonitorReading) error
+
+// WriteReading 實現 Sink 接口
+func (f SinkFunc) WriteReading(reading MonitorReading) error {
+	return f(reading)
+}
+
+// Close 實現 Sink 接口
+func (f SinkFunc) Close() error {
+	return nil
+}
+
+// AlarmRule 告警規則，有效讀數低於 Low 或高於 High 時觸發
+type AlarmRule struct {
+	Name string   `json:"name" yaml:"name"`                     // 規則名稱
+	Low  *float64 `json:"low,omitempty" yaml:"low,omitempty"`   // 下限 (Pa)
+	High *float64 `json:"high,omitempty" yaml:"high,omitempty"` // 上限 (Pa)
+}
+
+// NewRangeAlarm 創建上下限告警規則
+func NewRangeAlarm(name string, low, high float64) AlarmRule {
+	return AlarmRule{Name: name, Low: &low, High: &high}
+}
+
+// Check 檢查壓力值是否違反規則，返回違反原因
+func (ar AlarmRule) Check(pressure float64) (string, bool) {
+	if ar.Low != nil && pressure < *ar.Low {
+		return fmt.Sprintf("%.2f Pa 低於下限 %.2f Pa", pressure, *ar.Low), true
+	}
+	if ar.High != nil && pressure > *ar.High {
+		return fmt.Sprintf("%.2f Pa 高於上限 %.2f Pa", pressure, *ar.High), true
+	}
+	return "", false
+}
+
+// AlarmEvent 告警觸發或解除事件
+type AlarmEvent struct {
+	Rule      string          `json:"rule"`               // 規則名稱
+	Active    bool            `json:"active"`             // true 為觸發，false 為解除
+	Message   string          `json:"message"`            // 描述
+	Reading   PressureReading `json:"reading"`            // 觸發狀態變化的讀數
+	Location  *Location       `json:"location,omitempty"` // 安裝位置（設置了位置對照表時）
+	Timestamp time.Time       `json:"timestamp"`          // 事件時間
+}
+
+// MonitorStats 監測運行統計
+type MonitorStats struct {
+	StartedAt  time.Time     `json:"started_at"`  // 開始時間
+	Uptime     time.Duration `json:"uptime"`      // 運行時長
+	Readings   int           `json:"readings"`    // 總讀數
+	Errors     int           `json:"errors"`      // 無效讀數
+	SinkErrors int           `json:"sink_errors"` // 輸出失敗次數
+	Device     string        `json:"device"`      // 當前使用的設備
+	Failovers  int           `json:"failovers"`   // 切換備用設備次數
+
+	RecoverySteps    int    `json:"recovery_steps"`               // 已執行的恢復步驟次數
+	LastRecoveryStep string `json:"last_recovery_step,omitempty"` // 最近執行的恢復步驟
+	DeviceFailed     bool   `json:"device_failed"`                // 恢復步驟用完後設備仍無響應，收到有效讀數後清除
+
+	LatencyViolations int    `json:"latency_violations"` // 超出延遲預算的讀數
+	Band              string `json:"band,omitempty"`     // 當前所在的壓力區間
+	BandChanges       int    `json:"band_changes"`       // 區間變化次數（不含首次分類）
+	BaselineAnomalies int    `json:"baseline_anomalies"` // 偏離基線的讀數
+	BaselineLearned   int    `json:"baseline_learned"`   // 已完成學習的基線時段
+
+	ConsecutiveFailures int            `json:"consecutive_failures"` // 當前連續失敗次數
+	Pressure            Statistics     `json:"pressure"`             // 有效讀數統計
+	Today               Statistics     `json:"today"`                // 今天（本地時間）的有效讀數統計，跨日後重新開始
+	Setpoint            *SetpointStats `json:"setpoint,omitempty"`   // 設定值偏差和達標統計，僅配置了設定值時存在
+	ActiveAlarms        []string       `json:"active_alarms"`        // 當前觸發中的告警
+	Sinks               []SinkHealth   `json:"sinks"`                // 各輸出目標的健康狀態
+}
+
+// Monitor 監測流程：連續讀取壓差儀，更新統計，評估告警並分發到輸出目標
+type Monitor struct {
+	meter    *PressureMeter
+	config   Config
+	interval time.Duration
+	logger   *log.Logger
+
+	mu            sync.Mutex
+	sinks         []*managedSink
+	sinkPolicy    SinkBreakerPolicy
+	rules         []AlarmRule
+	activeAlarms  map[string]bool
+	maxReadings   int
+	failurePolicy FailurePolicy
+	locations     *LocationMap
+	latencyStreak int  // 連續超出延遲預算的讀數
+	latencyAlarm  bool // 延遲預算告警是否觸發中
+	bands         []Band
+	bandKnown     bool // 是否已有有效讀數完成區間分類
+	baseline      *baselineState
+	bus           *EventBus
+	preset        UnitPreset
+	setpoint      *SetpointStats   // 設定值跟蹤，未配置時為空
+	ambient       *AmbientSource   // 大氣壓來源，未配置時為空
+	discovery     *DiscoveryConfig // 後台發現配置，未配置時為空
+	discoverer    *Discovery       // 運行中的後台發現
+	recovery      recoveryState    // 恢復階梯的進度
+	today         string           // Today 統計的日期 (2006-01-02)
+	statsFile     string           // 統計保存檔案，為空則不保存
+	statsSaved    time.Time        // 上次保存統計的時間
+	stats         MonitorStats
+	err           error
+
+	cancel  context.CancelFunc
+	done    chan struct{}
+	stopped sync.Once
+}
+
+// NewMonitor 根據配置創建壓差儀並包裝為監測流程
+func NewMonitor(config Config) (*Monitor, error) {
+	preset, err := GetUnitPreset(config.UnitPreset)
+	if err != nil {
+		return nil, err
+	}
+	if config.Setpoint != nil {
+		if err := config.Setpoint.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	pm, err := NewPressureMeter(config)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	interval := config.ReadInterval
+	if interval == 0 {
+		interval = DefaultReadInterval
+	}
+
+	// RS485 總線上讀取間隔過短時讀數會堆積，Modbus TCP 網關後的總線無法估算
+	if !config.IsTCP() {
+		if warning := NewBusPlan(config, 1, 0, 0).Warning(); warning != "" {
+			logger.Printf("⚠️  %s", warning)
+		}
+	}
+
+	var setpoint *SetpointStats
+	if config.Setpoint != nil {
+		setpoint = NewSetpointStats(*config.Setpoint, interval)
+	}
+
+	return &Monitor{
+		meter:        pm,
+		config:       config,
+		interval:     interval,
+		logger:       logger,
+		activeAlarms: make(map[string]bool),
+		preset:       preset,
+		setpoint:     setpoint,
+		stats:        MonitorStats{Device: config.Endpoint()},
+		done:         make(chan struct{}),
+	}, nil
+}
+
+// Meter 返回當前使用的壓差儀（切換備用設備後會改變）
+func (m *Monitor) Meter() *PressureMeter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.meter
+}
+
+// AddSink 添加輸出目標，監測停止時會一併關閉
+func (m *Monitor) AddSink(sink Sink) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sinks = append(m.sinks, newManagedSink(sink, len(m.sinks)+1, nil))
+	return m
+}
+
+// AddAlarmRule 添加告警規則
+func (m *Monitor) AddAlarmRule(rule AlarmRule) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules = append(m.rules, rule)
+	return m
+}
+
+// SetMaxReadings 設置最大讀數，達到後自動停止讀取（0 為不限制）
+func (m *Monitor) SetMaxReadings(n int) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxReadings = n
+	return m
+}
+
+// SetLocations 設置位置對照表，讀數和告警會附帶設備的安裝位置
+func (m *Monitor) SetLocations(locations *LocationMap) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.locations = locations
+	return m
+}
+
+// SetEventBus 設置事件總線，發布告警觸發事件（數據為 AlarmEvent）及壓差儀的連接和讀數事件
+func (m *Monitor) SetEventBus(bus *EventBus) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bus = bus
+	m.meter.SetEventBus(bus)
+	return m
+}
+
+// SetDiscovery 設置後台發現：監測期間按間隔快速掃描同一總線，新接入和移除的儀表記錄日誌並發布事件
+//
+// 只監測本儀表，新發現的儀表不會被讀取；需在 Start 之前設置。
+func (m *Monitor) SetDiscovery(config DiscoveryConfig) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.discovery = &config
+	return m
+}
+
+// SetUnitPreset 設置單位預設，告警描述使用其單位、小數位數和用語（默認取自 Config.UnitPreset）
+func (m *Monitor) SetUnitPreset(preset UnitPreset) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.preset = preset
+	return m
+}
+
+// SetFailurePolicy 設置連續讀取失敗的處理策略
+func (m *Monitor) SetFailurePolicy(policy FailurePolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failurePolicy = policy
+	return nil
+}
+
+// Start 測試連接後開始監測，ctx 取消或達到最大讀數時結束
+func (m *Monitor) Start(ctx context.Context) error {
+	if _, err := m.meter.TestConnection(); err != nil {
+		if !m.config.StartDegraded {
+			return fmt.Errorf("設備連接失敗: %v", err)
+		}
+		m.logger.Printf("⚠️  設備暫時無響應，以降級模式開始監測: %v", err)
+	}
+
+	ctx, m.cancel = context.WithCancel(ctx)
+
+	m.mu.Lock()
+	m.stats.StartedAt = time.Now()
+	m.mu.Unlock()
+
+	m.mu.Lock()
+	ambient := m.ambient
+	m.mu.Unlock()
+	if ambient != nil {
+		go ambient.Run(ctx)
+	}
+
+	m.meter.Start(m.interval)
+	m.startDiscovery()
+	go m.run(ctx)
+	return nil
+}
+
+// startDiscovery 配置了後台發現時在當前儀表上啟動，切換備用設備後重新啟動
+func (m *Monitor) startDiscovery() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.discovery != nil {
+		discoverer, err := m.meter.StartDiscovery(*m.discovery)
+		if err != nil {
+			m.logger.Printf("⚠️  %v", err)
+			return
+		}
+		m.discoverer = discoverer
+	}
+}
+
+// stopDiscovery 停止運行中的後台發現
+func (m *Monitor) stopDiscovery() {
+	m.mu.Lock()
+	discoverer := m.discoverer
+	m.discoverer = nil
+	m.mu.Unlock()
+	if discoverer != nil {
+		discoverer.Stop()
+	}
+}
+
+// Done 返回監測結束時關閉的通道
+func (m *Monitor) Done() <-chan struct{} {
+	return m.done
+}
+
+// Err 返回監測異常結束的原因，正常結束時為 nil
+func (m *Monitor) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.err
+}
+
+// Stop 停止監測，關閉所有輸出目標和設備連接
+func (m *Monitor) Stop() error {
+	var firstErr error
+	m.stopped.Do(func() {
+		if m.cancel != nil {
+			m.cancel()
+			<-m.done
+		}
+		m.stopDiscovery()
+		m.saveBaseline(true)
+		m.saveStats(true)
+
+		m.mu.Lock()
+		sinks := m.sinks
+		m.mu.Unlock()
+		for _, ms := range sinks {
+			if err := ms.sink.Close(); err != nil {
+				m.logger.Printf("關閉輸出目標失敗: %v", err)
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+
+		if err := m.meter.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	})
+	return firstErr
+}
+
+// Stats 返回當前監測統計快照
+func (m *Monitor) Stats() MonitorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := m.stats
+	if !stats.StartedAt.IsZero() {
+		stats.Uptime = time.Since(stats.StartedAt)
+	}
+	stats.ActiveAlarms = nil
+	for _, rule := range m.rules {
+		if m.activeAlarms[rule.Name] {
+			stats.ActiveAlarms = append(stats.ActiveAlarms, rule.Name)
+		}
+	}
+	if m.latencyAlarm {
+		stats.ActiveAlarms = append(stats.ActiveAlarms, LatencyAlarmRule)
+	}
+	if m.baseline != nil && m.baseline.active {
+		stats.ActiveAlarms = append(stats.ActiveAlarms, BaselineAlarmRule)
+	}
+	if m.today != time.Now().Format("2006-01-02") {
+		stats.Today = Statistics{}
+	}
+	stats.Sinks = m.sinkHealthLocked()
+	stats.Setpoint = m.setpoint.Snapshot()
+	return stats
+}
+
+// run 處理讀數的主循環
+func (m *Monitor) run(ctx context.Context) {
+	defer close(m.done)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case reading := <-m.meter.GetReadings():
+			limitReached := m.handleReading(reading)
+			if err := m.checkFailures(reading); err != nil {
+				m.mu.Lock()
+				m.err = err
+				m.mu.Unlock()
+				m.logger.Printf("停止監測: %v", err)
+				m.cancel()
+				return
+			}
+			if limitReached {
+				m.logger.Printf("已達到最大讀數限制: %d", m.maxReadings)
+				m.cancel()
+				return
+			}
+		}
+	}
+}
+
+// handleReading 更新統計、評估告警並分發讀數，返回是否已達到最大讀數
+func (m *Monitor) handleReading(reading PressureReading) bool {
+	m.mu.Lock()
+	m.stats.Readings++
+	if reading.Valid {
+		m.stats.Pressure.Update(reading.Pressure)
+		if date := reading.Timestamp.Local().Format("2006-01-02"); date != m.today {
+			m.stats.Today.Reset()
+			m.today = date
+		}
+		m.stats.Today.Update(reading.Pressure)
+	} else {
+		m.stats.Errors++
+	}
+	record := MonitorReading{
+		PressureReading: reading,
+		Count:           m.stats.Readings,
+		Stats:           m.stats.Pressure,
+		Location:        m.locations.Lookup(m.stats.Device, reading.SlaveID),
+	}
+	if reading.Valid && m.setpoint != nil {
+		deviation := m.setpoint.Update(reading.Pressure, reading.Timestamp)
+		record.Deviation = &deviation
+	}
+	if m.ambient != nil {
+		record.Ambient = m.ambient.Latest(reading.Timestamp)
+	}
+	events := m.evaluateAlarms(reading, record.Location)
+	events = append(events, m.evaluateLatency(reading, record.Location)...)
+	events = append(events, m.evaluateBaseline(reading, record.Location)...)
+	bandEvents := m.evaluateBands(reading, record.Location)
+	sinks := m.sinks
+	bus, device := m.bus, m.stats.Device
+	limitReached := m.maxReadings > 0 && m.stats.Readings >= m.maxReadings
+	m.mu.Unlock()
+
+	for _, event := range events {
+		if event.Active {
+			bus.Publish(Event{Type: EventAlarmTriggered, Timestamp: event.Timestamp, Source: device, SlaveID: reading.SlaveID, Message: event.Message, Data: event})
+		}
+	}
+
+	// 每個輸出目標單獨計算錯誤預算，寫入超時計為失敗，故障的目標暫停輸出，不影響其他目標；
+	// 暫停期間和寫入失敗的告警、區間事件保留到下次輸出時補發
+	for _, ms := range sinks {
+		m.mu.Lock()
+		allowed := ms.allow(time.Now())
+		alarms, bands := events, bandEvents
+		if allowed {
+			alarms, bands = ms.takePending(events, bandEvents)
+		} else {
+			ms.hold(events, bandEvents)
+		}
+		timeout := ms.policyFor(m.sinkPolicy).WriteTimeout
+		m.mu.Unlock()
+		if !allowed {
+			continue
+		}
+
+		errs, failedAlarms, failedBands := ms.deliver(record, alarms, bands, timeout)
+		for _, err := range errs {
+			m.logger.Printf("%s: %v", ms.health.Name, err)
+		}
+
+		m.mu.Lock()
+		m.stats.SinkErrors += len(errs)
+		ms.hold(failedAlarms, failedBands)
+		message := ms.record(errs, m.sinkPolicy, time.Now())
+		m.mu.Unlock()
+		if message != "" {
+			m.logger.Print(message)
+		}
+	}
+	m.saveBaseline(false)
+	m.saveStats(false)
+	return limitReached
+}
+
+// checkFailures 統計連續失敗次數，達到上限時按策略處理，策略為退出時返回錯誤
+func (m *Monitor) checkFailures(reading PressureReading) error {
+	m.mu.Lock()
+	if reading.Valid {
+		m.stats.ConsecutiveFailures = 0
+		message := m.resetRecovery()
+		m.mu.Unlock()
+		if message != "" {
+			m.logger.Print(message)
+		}
+		return nil
+	}
+
+	m.stats.ConsecutiveFailures++
+	failures := m.stats.ConsecutiveFailures
+	policy := m.failurePolicy
+	device := m.config.Endpoint()
+	if policy.MaxConsecutive <= 0 || failures < policy.MaxConsecutive {
+		m.mu.Unlock()
+		return nil
+	}
+	// 處理後重新計數，持續失敗時每 MaxConsecutive 次處理一次
+	m.stats.ConsecutiveFailures = 0
+	m.mu.Unlock()
+
+	m.logger.Printf("⚠️  連續讀取失敗 %d 次 (最後錯誤: %s)，處理方式: %s", failures, reading.Error, policy.Action)
+
+	switch policy.Action {
+	case FailureExit:
+		return fmt.Errorf("%w: %d 次 (最後錯誤: %s)", ErrTooManyFailures, failures, reading.Error)
+	case FailureHook:
+		env := map[string]string{
+			"EVENT":      "consecutive_failures",
+			"FAILURES":   fmt.Sprintf("%d", failures),
+			"DEVICE":     device,
+			"SLAVE_ID":   fmt.Sprintf("%d", reading.SlaveID),
+			"LAST_ERROR": reading.Error,
+		}
+		go func() {
+			if err := runHook(policy.Hook, env, DefaultHookTimeout); err != nil {
+				m.logger.Printf("❌ 失敗處理腳本出錯: %v", err)
+			}
+		}()
+	case FailureFailover:
+		if err := m.failover(); err != nil {
+			m.logger.Printf("❌ 切換備用設備失敗: %v", err)
+		}
+	case FailureRecover:
+		m.recover(reading, failures)
+	}
+	return nil
+}
+
+// failover 切換到備用設備，原設備成為新的備用設備
+func (m *Monitor) failover() error {
+	m.mu.Lock()
+	config := m.config
+	backup := m.failurePolicy.BackupDevice
+	old := m.meter
+	m.mu.Unlock()
+
+	primary := config.Endpoint()
+	config.setEndpoint(backup)
+
+	pm, err := NewPressureMeter(config)
+	if err != nil {
+		return fmt.Errorf("打開 %s 失敗: %v", backup, err)
+	}
+	if _, err := pm.TestConnection(); err != nil {
+		pm.Close()
+		return fmt.Errorf("%s 無響應: %v", backup, err)
+	}
+
+	m.mu.Lock()
+	pm.SetEventBus(m.bus)
+	m.meter = pm
+	m.config.setEndpoint(backup)
+	m.failurePolicy.BackupDevice = primary
+	m.stats.Device = backup
+	m.stats.Failovers++
+	m.mu.Unlock()
+
+	m.stopDiscovery()
+	old.Close()
+	pm.Start(m.interval)
+	m.startDiscovery()
+	m.logger.Printf("🔀 已從 %s 切換到備用設備 %s", primary, backup)
+	return nil
+}
+
+// evaluateAlarms 根據讀數更新告警狀態，返回狀態發生變化的事件（調用方需持有鎖）
+func (m *Monitor) evaluateAlarms(reading PressureReading, location *Location) []AlarmEvent {
+	return evaluateAlarmRules(m.rules, m.activeAlarms, m.preset, reading, location, time.Now())
+}
+
+// evaluateAlarmRules 按有效讀數更新各規則的觸發狀態 active，返回狀態變化產生的事件（時間為 now）
+func evaluateAlarmRules(rules []AlarmRule, active map[string]bool, preset UnitPreset, reading PressureReading, location *Location, now time.Time) []AlarmEvent {
+	if !reading.Valid {
+		return nil
+	}
+
+	var events []AlarmEvent
+	for _, rule := range rules {
+		message, violated := preset.CheckAlarm(rule, reading.Pressure)
+		if violated == active[rule.Name] {
+			continue
+		}
+
+		active[rule.Name] = violated
+		if !violated {
+			message = fmt.Sprintf("%s 已恢復正常", preset.Format(reading.Pressure))
+		}
+		events = append(events, AlarmEvent{
+			Rule:      rule.Name,
+			Active:    violated,
+			Message:   message,
+			Reading:   reading,
+			Location:  location,
+			Timestamp: now,
+		})
+	}
+	return events
+}
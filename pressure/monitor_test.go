@@ -0,0 +1,196 @@
+package pressure
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// alarmRecorder 記錄收到的讀數和告警事件的輸出目標，err 不為空時輸出讀數和告警都失敗
+type alarmRecorder struct {
+	readings []MonitorReading
+	alarms   []AlarmEvent
+	err      error
+}
+
+func (ar *alarmRecorder) WriteReading(reading MonitorReading) error {
+	ar.readings = append(ar.readings, reading)
+	return ar.err
+}
+
+func (ar *alarmRecorder) WriteAlarm(event AlarmEvent) error {
+	if ar.err != nil {
+		return ar.err
+	}
+	ar.alarms = append(ar.alarms, event)
+	return nil
+}
+
+func (ar *alarmRecorder) Close() error {
+	return nil
+}
+
+func TestMonitorAlarmRaiseAndClear(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0x0000, 0x007B)
+	m := newTestMonitor(t, mock)
+	sink := &alarmRecorder{}
+	m.AddSink(sink).AddAlarmRule(NewRangeAlarm("room", 5, 20))
+
+	// 12.3 Pa 在範圍內，200.0 Pa 觸發，仍超限不重複觸發，失敗的讀數不改變告警狀態，回到 10.0 Pa 解除
+	steps := []struct {
+		registers []uint16
+		failures  int
+		active    []string
+		events    int
+	}{
+		{registers: []uint16{0x0000, 0x007B}, events: 0},
+		{registers: []uint16{0x0000, 0x07D0}, active: []string{"room"}, events: 1},
+		{registers: []uint16{0x0000, 0x07D1}, active: []string{"room"}, events: 1},
+		{failures: 1, active: []string{"room"}, events: 1},
+		{registers: []uint16{0x0000, 0x0064}, events: 2},
+	}
+	for i, step := range steps {
+		if step.registers != nil {
+			mock.SetRegisters(0x0034, step.registers...)
+		}
+		mock.SetFailures(step.failures, nil)
+		m.handleReading(m.meter.ReadPressure())
+
+		stats := m.Stats()
+		if len(stats.ActiveAlarms) != len(step.active) || (len(step.active) > 0 && stats.ActiveAlarms[0] != step.active[0]) {
+			t.Errorf("step %d: active alarms %v, want %v", i, stats.ActiveAlarms, step.active)
+		}
+		if len(sink.alarms) != step.events {
+			t.Errorf("step %d: %d alarm events, want %d", i, len(sink.alarms), step.events)
+		}
+	}
+
+	if len(sink.alarms) != 2 {
+		t.Fatalf("alarm events = %d, want raise and clear", len(sink.alarms))
+	}
+	raised, cleared := sink.alarms[0], sink.alarms[1]
+	if !raised.Active || raised.Rule != "room" || raised.Reading.Pressure != 200 {
+		t.Errorf("raise event = %+v, want active room at 200 Pa", raised)
+	}
+	if cleared.Active || cleared.Reading.Pressure != 10 {
+		t.Errorf("clear event = %+v, want inactive at 10 Pa", cleared)
+	}
+	if stats := m.Stats(); stats.Readings != 5 || stats.Errors != 1 {
+		t.Errorf("stats readings=%d errors=%d, want 5 and 1", stats.Readings, stats.Errors)
+	}
+}
+
+func TestMonitorIsolatesFailingSink(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0x0000, 0x007B)
+	m := newTestMonitor(t, mock)
+	healthy, broken := &alarmRecorder{}, &alarmRecorder{err: errors.New("broker unreachable")}
+	m.AddSink(healthy).AddSink(broken)
+	if err := m.SetSinkBreaker(SinkBreakerPolicy{FailureBudget: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		m.handleReading(m.meter.ReadPressure())
+	}
+
+	if len(healthy.readings) != 4 {
+		t.Errorf("healthy sink got %d readings, want 4", len(healthy.readings))
+	}
+	if len(broken.readings) != 2 {
+		t.Errorf("broken sink got %d readings, want 2 before the breaker opened", len(broken.readings))
+	}
+	health := m.SinkHealth()
+	if health[0].State != SinkStateClosed || health[1].State != SinkStateOpen || health[1].Skipped != 2 {
+		t.Errorf("sink health = %+v, want healthy closed and broken open with 2 skipped", health)
+	}
+}
+
+func TestMonitorReplaysAlarmsAfterBreakerCloses(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0x0000, 0x007B)
+	m := newTestMonitor(t, mock)
+	sink := &alarmRecorder{err: errors.New("broker unreachable")}
+	if _, err := m.AddSinkWithBreaker(sink, SinkBreakerPolicy{FailureBudget: 1, RetryInterval: time.Hour}); err != nil {
+		t.Fatal(err)
+	}
+	m.AddAlarmRule(NewRangeAlarm("room", 5, 20))
+
+	m.handleReading(m.meter.ReadPressure())
+	if health := m.SinkHealth()[0]; health.State != SinkStateOpen {
+		t.Fatalf("sink state = %s, want open after the first failure", health.State)
+	}
+
+	// 暫停期間觸發的告警保留下來
+	mock.SetRegisters(0x0034, 0x0000, 0x07D0)
+	m.handleReading(m.meter.ReadPressure())
+	if health := m.SinkHealth()[0]; health.Skipped != 1 || health.PendingEvents != 1 {
+		t.Fatalf("sink health = %+v, want 1 skipped reading and 1 pending alarm", health)
+	}
+
+	// 到達重試時間且輸出目標恢復後補發
+	sink.err = nil
+	m.mu.Lock()
+	retry := time.Now().Add(-time.Second)
+	m.sinks[0].health.NextRetry = &retry
+	m.mu.Unlock()
+	m.handleReading(m.meter.ReadPressure())
+
+	if len(sink.alarms) != 1 || !sink.alarms[0].Active || sink.alarms[0].Reading.Pressure != 200 {
+		t.Fatalf("alarms after recovery = %+v, want the raise held while the breaker was open", sink.alarms)
+	}
+	if health := m.SinkHealth()[0]; health.State != SinkStateClosed || health.PendingEvents != 0 {
+		t.Errorf("sink health = %+v, want closed with nothing pending", health)
+	}
+}
+
+// hangingSink 寫入讀數時卡住直到 release 關閉的輸出目標
+type hangingSink struct {
+	release chan struct{}
+	writes  int
+}
+
+func (hs *hangingSink) WriteReading(reading MonitorReading) error {
+	<-hs.release
+	hs.writes++
+	return nil
+}
+
+func (hs *hangingSink) Close() error {
+	return nil
+}
+
+func TestMonitorTimesOutHangingSink(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0x0000, 0x007B)
+	m := newTestMonitor(t, mock)
+	healthy, hanging := &alarmRecorder{}, &hangingSink{release: make(chan struct{})}
+	m.AddSink(healthy)
+	if _, err := m.AddSinkWithBreaker(hanging, SinkBreakerPolicy{FailureBudget: 2, WriteTimeout: 10 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 卡住的輸出目標第一次超時，第二次因上一次寫入未完成直接失敗，達到錯誤預算後暫停
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		m.handleReading(m.meter.ReadPressure())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("three readings took %v with a hanging sink", elapsed)
+	}
+	if len(healthy.readings) != 3 {
+		t.Errorf("healthy sink got %d readings, want 3", len(healthy.readings))
+	}
+	health := m.SinkHealth()[1]
+	if health.State != SinkStateOpen || health.Failures != 2 || health.Skipped != 1 {
+		t.Fatalf("hanging sink health = %+v, want open after 2 failures with 1 skipped", health)
+	}
+
+	// 卡住的寫入完成後，到達重試時間時恢復輸出
+	close(hanging.release)
+	m.mu.Lock()
+	retry := time.Now().Add(-time.Second)
+	m.sinks[1].health.NextRetry = &retry
+	m.mu.Unlock()
+	m.handleReading(m.meter.ReadPressure())
+	if health := m.SinkHealth()[1]; health.State != SinkStateClosed || health.Writes != 1 {
+		t.Errorf("sink health after release = %+v, want closed with 1 write", health)
+	}
+}
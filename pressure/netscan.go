@@ -0,0 +1,276 @@
+// pressure/netscan.go - 網絡掃描：在網段內查找提供壓力寄存器的 Modbus TCP 設備（網關或帶網口的儀表）
+package pressure
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// 網絡掃描限制
+const (
+	DefaultNetworkScanWorkers = 32   // 網絡掃描默認同時探測的地址數，TCP 連接不受串口數量限制
+	MaxNetworkScanHosts       = 4096 // 單次網絡掃描最多的主機數（相當於 /20 網段）
+)
+
+// GetNetworkScanConfig 獲取網絡掃描配置，network 為 CIDR 網段（如 192.168.1.0/24）或單個 IP
+//
+// 網關後面的儀表通常使用默認站點號或常用站點號，因此只探測這些站點號；需要全範圍時設置 SlaveIDs。
+func GetNetworkScanConfig(network string) ScanConfig {
+	return ScanConfig{
+		Network:          network,
+		SlaveIDs:         quickSlaveIDs(),
+		ScanTimeout:      500 * time.Millisecond, // 同時用作 TCP 連接超時
+		MaxDevices:       100,
+		AutoDetectFormat: true,
+		FormatSamples:    DefaultFormatSamples,
+		Parallel:         true,
+		MaxParallel:      DefaultNetworkScanWorkers,
+		SkipUnresponsive: true,
+	}
+}
+
+// NetworkScan 掃描網段內指定端口的 Modbus TCP 設備，ports 為空時使用 502
+func (s *Scanner) NetworkScan(network string, ports []int) (*ScanResult, error) {
+	s.logf("🌐 開始網絡掃描: %s", network)
+	config := GetNetworkScanConfig(network)
+	if len(ports) > 0 {
+		config.TCPPorts = ports
+	}
+	return s.ScanDevices(config)
+}
+
+// ParseTCPPorts 解析以逗號分隔的 TCP 端口列表，如 "502,503"
+func ParseTCPPorts(value string) ([]int, error) {
+	var ports []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(value, ",") {
+		port, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("無效的 TCP 端口: %s", part)
+		}
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+	return ports, nil
+}
+
+// DeviceTransport 返回掃描到設備時使用的傳輸方式，串口掃描的設備為空
+func DeviceTransport(device DeviceInfo) string {
+	if transport, ok := device.Properties["transport"].(string); ok {
+		return transport
+	}
+	return ""
+}
+
+// UseScannedEndpoint 按掃描到設備時的傳輸方式設置連接端點，網絡掃描的設備使用 Modbus TCP 地址
+func (c *Config) UseScannedEndpoint(device DeviceInfo) {
+	if DeviceTransport(device) == TransportTCP {
+		c.Transport = TransportTCP
+		c.Address = device.Device
+		c.Device = ""
+		return
+	}
+	c.Device = device.Device
+}
+
+// networkHosts 返回網段內的主機地址，IPv4 網段跳過網絡地址和廣播地址（/31、/32 除外）
+func networkHosts(network string) ([]string, error) {
+	if !strings.Contains(network, "/") {
+		ip := net.ParseIP(network)
+		if ip == nil {
+			return nil, fmt.Errorf("無效的網段: %s (應為 CIDR 如 192.168.1.0/24 或單個 IP)", network)
+		}
+		return []string{ip.String()}, nil
+	}
+
+	ip, ipNet, err := net.ParseCIDR(network)
+	if err != nil {
+		return nil, fmt.Errorf("無效的網段: %s (%v)", network, err)
+	}
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones > 30 || 1<<(bits-ones) > MaxNetworkScanHosts+2 {
+		return nil, fmt.Errorf("網段 %s 過大，單次最多掃描 %d 個地址，請拆分網段", network, MaxNetworkScanHosts)
+	}
+
+	skipEdges := ip.To4() != nil && bits-ones >= 2
+	var hosts []string
+	for addr := ipNet.IP.Mask(ipNet.Mask); ipNet.Contains(addr); addr = nextIP(addr) {
+		hosts = append(hosts, addr.String())
+	}
+	if skipEdges {
+		hosts = hosts[1 : len(hosts)-1]
+	}
+	return hosts, nil
+}
+
+// nextIP 返回下一個 IP 地址，溢出時返回的地址不在任何網段內
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			return next
+		}
+	}
+	return nil
+}
+
+// scanNetwork 探測網段內每個地址和端口，端口開放時依次探測各站點號
+//
+// 端口未開放的地址不計入測試數量；每個地址和端口獨立探測，已發現的響應設備達到 MaxDevices 後不再開始新的探測。
+func (s *Scanner) scanNetwork(config ScanConfig, result *ScanResult) error {
+	hosts, err := networkHosts(config.Network)
+	if err != nil {
+		return err
+	}
+	ports := []string{DefaultModbusTCPPort}
+	if len(config.TCPPorts) > 0 {
+		ports = ports[:0]
+		for _, port := range config.TCPPorts {
+			ports = append(ports, strconv.Itoa(port))
+		}
+	}
+
+	var endpoints []string
+	for _, host := range hosts {
+		for _, port := range ports {
+			endpoints = append(endpoints, net.JoinHostPort(host, port))
+		}
+	}
+	if len(endpoints) > MaxNetworkScanHosts {
+		return fmt.Errorf("網絡掃描共 %d 個地址和端口組合，超過上限 %d，請縮小網段或減少端口", len(endpoints), MaxNetworkScanHosts)
+	}
+
+	workers := config.MaxParallel
+	if workers <= 0 || !config.Parallel {
+		workers = 1
+	}
+	if workers > len(endpoints) {
+		workers = len(endpoints)
+	}
+	s.logf("🌐 掃描網段 %s: %d 個地址，端口 %v，同時探測 %d 個", config.Network, len(hosts), ports, workers)
+	s.bus.Publish(Event{Type: EventScanStarted, Message: fmt.Sprintf("%s: %s %v", EventScanStarted.Description(), config.Network, ports), Data: config})
+
+	endpointResults := make([][]DeviceInfo, len(endpoints))
+	var found atomic.Int64
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				endpointResults[i] = s.scanEndpoint(endpoints[i], config)
+				found.Add(int64(len(s.getResponsiveDevices(endpointResults[i]))))
+			}
+		}()
+	}
+	for i := range endpoints {
+		if found.Load() >= int64(config.MaxDevices) {
+			s.logf("📊 已達到最大設備數量限制 %d，跳過其餘 %d 個地址", config.MaxDevices, len(endpoints)-i)
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, devices := range endpointResults {
+		for _, device := range devices {
+			if len(result.Devices) >= config.MaxDevices {
+				s.logf("📊 已達到最大設備數量限制: %d", config.MaxDevices)
+				return nil
+			}
+			result.addProbe(device, config)
+		}
+	}
+	return nil
+}
+
+// scanEndpoint 探測一個地址和端口上的各站點號，端口未開放時返回空
+func (s *Scanner) scanEndpoint(endpoint string, config ScanConfig) []DeviceInfo {
+	conn, err := net.DialTimeout("tcp", endpoint, config.ScanTimeout)
+	if err != nil {
+		return nil
+	}
+	conn.Close()
+	s.logf("🔌 端口開放: %s", endpoint)
+
+	handler := modbus.NewTCPClientHandler(endpoint)
+	handler.Timeout = config.ScanTimeout
+	if err := connectHandler(handler, config.ScanTimeout); err != nil {
+		s.logf("  ❌ 連接 %s 失敗: %v", endpoint, err)
+		return nil
+	}
+	defer handler.Close()
+
+	var devices []DeviceInfo
+	for i, slaveID := range config.SlaveIDs {
+		if i > 0 {
+			config.pause()
+		}
+		device, err := s.testTCPDevice(handler, endpoint, slaveID, config)
+		devices = append(devices, device)
+
+		if device.Responsive {
+			s.bus.Publish(Event{Type: EventDeviceFound, Source: endpoint, SlaveID: slaveID, Message: EventDeviceFound.Description(), Data: device})
+			if device.LastReading != nil {
+				s.logf("    🎯 發現設備: %s 站點=%d, 壓力=%.1f Pa", endpoint, slaveID, device.LastReading.Pressure)
+			} else {
+				s.logf("    🎯 發現設備: %s 站點=%d", endpoint, slaveID)
+			}
+		} else if isConnectionClosed(err) {
+			// 非 Modbus 服務收到請求後通常直接斷開，不必再探測其餘站點號
+			s.logf("  ⚠️  %s 連接已斷開，不是 Modbus TCP 服務: %v", endpoint, err)
+			break
+		}
+
+		if len(devices) >= config.MaxDevices {
+			break
+		}
+	}
+	return devices
+}
+
+// testTCPDevice 測試 Modbus TCP 連接上的特定站點是否響應
+func (s *Scanner) testTCPDevice(handler *modbus.TCPClientHandler, endpoint string, slaveID byte, config ScanConfig) (DeviceInfo, error) {
+	handler.SlaveId = slaveID
+	client, tracer := newTracedClient(handler, endpoint, false, s.trace)
+	device, err := s.probeDevice(client, endpoint, slaveID, config)
+	device.Properties["transport"] = TransportTCP
+	if device.Responsive && config.Identify {
+		// Modbus TCP 的響應帶長度，可直接在掃描連接上讀取設備標識
+		config.pause()
+		model, idErr := queryDeviceIdentification(func(data []byte) ([]byte, error) {
+			return handlerTransactMEI(handler, tracer, data)
+		})
+		s.recordIdentity(&device, model, idErr)
+	}
+	return device, err
+}
+
+// isConnectionClosed 錯誤是否表示 TCP 連接已斷開（而不是站點無響應的超時或 Modbus 異常）
+func isConnectionClosed(err error) bool {
+	if err == nil {
+		return false
+	}
+	if _, ok := err.(*modbus.ModbusError); ok {
+		return false
+	}
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "EOF") || strings.Contains(msg, "reset") ||
+		strings.Contains(msg, "broken pipe") || strings.Contains(msg, "closed")
+}
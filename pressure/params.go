@@ -0,0 +1,257 @@
+// pressure/params.go - 儀表設置的導出和恢復：讀取配置檔中的可寫設置寄存器保存為 YAML，更換儀表時寫回新儀表
+package pressure
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxWriteRegisters Modbus 單次寫入寄存器數量的上限（功能碼 0x10）
+const MaxWriteRegisters = 123
+
+// ParameterDampingName 阻尼寄存器 (dampingregister) 在參數檔案中的名稱
+const ParameterDampingName = "damping"
+
+// ParameterValue 一個設置寄存器的值
+type ParameterValue struct {
+	Name        string   `json:"name" yaml:"name"`                                   // 名稱
+	Register    uint16   `json:"register" yaml:"register"`                           // 保持寄存器起始地址
+	Values      []uint16 `json:"values" yaml:"values,flow"`                          // 寄存器值
+	Description string   `json:"description,omitempty" yaml:"description,omitempty"` // 說明
+}
+
+// ParameterDump 從儀表讀取的全部設置
+type ParameterDump struct {
+	Profile    string           `json:"profile" yaml:"profile"`                 // 設備配置檔名稱
+	Device     string           `json:"device" yaml:"device"`                   // 讀取時的設備
+	SlaveID    byte             `json:"slave_id" yaml:"slaveid"`                // 讀取時的站點號（恢復時不寫入）
+	Model      string           `json:"model,omitempty" yaml:"model,omitempty"` // 儀表型號和韌體版本
+	DumpedAt   time.Time        `json:"dumped_at" yaml:"dumpedat"`              // 讀取時間
+	Parameters []ParameterValue `json:"parameters" yaml:"parameters"`           // 設置寄存器的值
+}
+
+// ParameterChange 恢復時一個設置寄存器的變化
+type ParameterChange struct {
+	Name     string   `json:"name"`              // 名稱
+	Register uint16   `json:"register"`          // 保持寄存器起始地址
+	Current  []uint16 `json:"current"`           // 儀表當前的值
+	Target   []uint16 `json:"target"`            // 參數檔案中的值
+	Written  bool     `json:"written"`           // 是否已寫入並讀回確認
+	Error    string   `json:"error,omitempty"`   // 讀取或寫入錯誤
+	Skipped  string   `json:"skipped,omitempty"` // 未恢復的原因
+}
+
+// Changed 當前值與參數檔案中的值是否不同
+func (pc ParameterChange) Changed() bool {
+	if len(pc.Current) != len(pc.Target) {
+		return true
+	}
+	for i := range pc.Current {
+		if pc.Current[i] != pc.Target[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadParameterDump 從 YAML 檔案讀取儀表設置
+func LoadParameterDump(filename string) (*ParameterDump, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var dump ParameterDump
+	if err := yaml.Unmarshal(data, &dump); err != nil {
+		return nil, fmt.Errorf("解析參數檔案失敗: %v", err)
+	}
+	return &dump, nil
+}
+
+// Save 將儀表設置寫入 YAML 檔案
+func (pd *ParameterDump) Save(filename string) error {
+	data, err := yaml.Marshal(pd)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filename, data); err != nil {
+		return fmt.Errorf("寫入參數檔案失敗: %v", err)
+	}
+	return nil
+}
+
+// Print 將儀表設置寫入 w
+func (pd *ParameterDump) Print(w io.Writer) {
+	fmt.Fprintf(w, "儀表設置: %s 站點 %d，配置檔 %s", pd.Device, pd.SlaveID, pd.Profile)
+	if pd.Model != "" {
+		fmt.Fprintf(w, "，%s", pd.Model)
+	}
+	fmt.Fprintln(w)
+	for _, param := range pd.Parameters {
+		fmt.Fprintf(w, "   %-16s 0x%04X = %v", param.Name, param.Register, param.Values)
+		if param.Description != "" {
+			fmt.Fprintf(w, "  (%s)", param.Description)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// Parameters 返回可導出和恢復的設置寄存器：設備配置檔的 parameters，以及配置了 dampingregister 時的阻尼
+func (pm *PressureMeter) Parameters() []ProfileParameter {
+	params := append([]ProfileParameter(nil), pm.profile.Parameters...)
+	if pm.SupportsDamping() {
+		for _, param := range params {
+			if param.Register == pm.damping {
+				return params
+			}
+		}
+		params = append(params, ProfileParameter{Name: ParameterDampingName, Register: pm.damping, Description: "阻尼/濾波時間"})
+	}
+	return params
+}
+
+// DumpParameters 讀取全部設置寄存器
+func (pm *PressureMeter) DumpParameters() (*ParameterDump, error) {
+	params := pm.Parameters()
+	if len(params) == 0 {
+		return nil, fmt.Errorf("設備配置檔 %s 沒有設置寄存器 (parameters)，也未配置阻尼寄存器", pm.profile.Name)
+	}
+
+	pm.busMu.Lock()
+	defer pm.busMu.Unlock()
+	dump := &ParameterDump{
+		Profile:  pm.profile.Name,
+		Device:   pm.endpoint,
+		SlaveID:  pm.slaveID,
+		DumpedAt: time.Now(),
+	}
+	if pm.profile.ModelRegister != 0 || pm.profile.FirmwareRegister != 0 {
+		if model, err := pm.readIdentityRegisters(); err == nil {
+			dump.Model = model.String()
+		}
+	}
+	for _, param := range params {
+		values, err := pm.readParameter(param)
+		if err != nil {
+			return nil, err
+		}
+		dump.Parameters = append(dump.Parameters, ParameterValue{
+			Name:        param.Name,
+			Register:    param.Register,
+			Values:      values,
+			Description: param.Description,
+		})
+	}
+	return dump, nil
+}
+
+// RestoreParameters 將參數檔案中的設置寫回儀表，返回每個設置的變化
+//
+// 只恢復當前設備配置檔中名稱和地址都相同的設置寄存器，避免把其他型號的設置寫到無關的寄存器；
+// 與當前值相同的設置不寫入。write 為 false 時只比較，不寫入。寫入失敗時停止，之前的設置保持已寫入的狀態。
+func (pm *PressureMeter) RestoreParameters(dump *ParameterDump, write bool) ([]ParameterChange, error) {
+	if dump.Profile != pm.profile.Name {
+		return nil, fmt.Errorf("參數檔案來自設備配置檔 %s，當前為 %s", dump.Profile, pm.profile.Name)
+	}
+	known := make(map[string]ProfileParameter)
+	for _, param := range pm.Parameters() {
+		known[param.Name] = param
+	}
+
+	pm.busMu.Lock()
+	defer pm.busMu.Unlock()
+	var changes []ParameterChange
+	for _, saved := range dump.Parameters {
+		change := ParameterChange{Name: saved.Name, Register: saved.Register, Target: saved.Values}
+		param, ok := known[saved.Name]
+		switch {
+		case !ok:
+			change.Skipped = "當前設備配置檔沒有此設置"
+		case param.Register != saved.Register:
+			change.Skipped = fmt.Sprintf("當前設備配置檔的地址為 0x%04X", param.Register)
+		case len(saved.Values) != int(param.count()):
+			change.Skipped = fmt.Sprintf("寄存器數量應為 %d，參數檔案中為 %d", param.count(), len(saved.Values))
+		}
+		if change.Skipped != "" {
+			changes = append(changes, change)
+			continue
+		}
+
+		current, err := pm.readParameter(param)
+		if err != nil {
+			change.Error = err.Error()
+			changes = append(changes, change)
+			return changes, err
+		}
+		change.Current = current
+		if write && change.Changed() {
+			if err := pm.writeParameter(param, saved.Values); err != nil {
+				change.Error = err.Error()
+				changes = append(changes, change)
+				return changes, err
+			}
+			change.Written = true
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// readParameter 讀取一個設置寄存器
+func (pm *PressureMeter) readParameter(param ProfileParameter) ([]uint16, error) {
+	count := param.count()
+	data, err := pm.client.ReadHoldingRegisters(param.Register, count)
+	if err != nil {
+		return nil, fmt.Errorf("讀取設置 %s (0x%04X) 失敗: %v", param.Name, param.Register, err)
+	}
+	if len(data) != int(count)*2 {
+		return nil, fmt.Errorf("讀取設置 %s (0x%04X) 返回長度錯誤: %d 字節", param.Name, param.Register, len(data))
+	}
+	values := make([]uint16, count)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint16(data[2*i:])
+	}
+	return values, nil
+}
+
+// writeParameter 寫入一個設置寄存器並讀回確認，單個寄存器用功能碼 0x06，多個用 0x10
+func (pm *PressureMeter) writeParameter(param ProfileParameter, values []uint16) error {
+	if err := pm.checkControl(); err != nil {
+		return err
+	}
+	if len(values) == 1 {
+		if err := pm.writeRegister(param.Register, values[0]); err != nil {
+			return fmt.Errorf("寫入設置 %s 失敗: %v", param.Name, err)
+		}
+		return nil
+	}
+
+	data := make([]byte, 0, 2*len(values))
+	for _, value := range values {
+		data = binary.BigEndian.AppendUint16(data, value)
+	}
+	if _, err := pm.client.WriteMultipleRegisters(param.Register, uint16(len(values)), data); err != nil {
+		return fmt.Errorf("寫入設置 %s (0x%04X) 失敗: %v", param.Name, param.Register, err)
+	}
+	readback, err := pm.readParameter(param)
+	if err != nil {
+		return fmt.Errorf("寫入後讀回失敗: %v", err)
+	}
+	if !(ParameterChange{Current: readback, Target: values}).Changed() {
+		pm.logger.Printf("設置 %s (0x%04X) 已寫入: %v", param.Name, param.Register, values)
+		return nil
+	}
+	return fmt.Errorf("設置 %s (0x%04X) 讀回值 %v 與寫入值 %v 不一致", param.Name, param.Register, readback, values)
+}
+
+// count 返回設置寄存器的數量
+func (pp ProfileParameter) count() uint16 {
+	if pp.Count == 0 {
+		return 1
+	}
+	return pp.Count
+}
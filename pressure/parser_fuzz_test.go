@@ -0,0 +1,153 @@
+package pressure
+
+import (
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// 模糊測試：任意寄存器數據和畸形配置檔案不能導致崩潰，解析結果必須是有限數值或錯誤
+//
+// 執行: go test ./pressure -run '^$' -fuzz FuzzDeviceProfileDecode -fuzztime 30s
+
+var fuzzByteOrders = []string{ByteOrderABCD, ByteOrderCDAB, ByteOrderBADC, ByteOrderDCBA}
+
+var fuzzEncodings = []string{EncodingInt16, EncodingUint16, EncodingInt32, EncodingUint32, EncodingFloat32}
+
+// addFrameSeeds 加入常見的寄存器數據：零、普時達十進制和浮點讀數、NaN、Inf 和長度不足的幀
+func addFrameSeeds(f *testing.F, args ...interface{}) {
+	for _, frame := range [][]byte{
+		{},
+		{0x00},
+		{0x00, 0x7B},
+		{0x00, 0x00, 0x00, 0x7B},
+		{0xFF, 0xFF, 0xFF, 0x85},
+		{0x00, 0x00, 0x43, 0xFA},
+		{0x00, 0x00, 0x7F, 0xC0},
+		{0x00, 0x00, 0x7F, 0x80},
+		{0xFF, 0xFF, 0xFF, 0xFF},
+	} {
+		f.Add(append([]interface{}{frame}, args...)...)
+	}
+}
+
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
+
+func FuzzParseDecimalFormat(f *testing.F) {
+	addFrameSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, scale := range []float64{DefaultDecimalScale, 0.01, 1} {
+			if value := parseDecimalFormatStatic(data, scale); !isFinite(value) {
+				t.Fatalf("parseDecimalFormatStatic(% X, %g) = %v", data, scale, value)
+			}
+		}
+		if value := parse16BitFormatStatic(data, true, DefaultDecimalScale); !isFinite(value) {
+			t.Fatalf("parse16BitFormatStatic(% X) = %v", data, value)
+		}
+	})
+}
+
+func FuzzParseFloatFormat(f *testing.F) {
+	addFrameSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, order := range append(fuzzByteOrders, "") {
+			if value := parseFloatFormatStatic(data, order); !isFinite(value) {
+				t.Fatalf("parseFloatFormatStatic(% X, %q) = %v", data, order, value)
+			}
+		}
+	})
+}
+
+func FuzzDeviceProfileDecode(f *testing.F) {
+	addFrameSeeds(f, uint8(0), uint8(0), 0.1)
+	f.Fuzz(func(t *testing.T, data []byte, encoding, order uint8, scale float64) {
+		profile := DeviceProfile{
+			Encoding:  fuzzEncodings[int(encoding)%len(fuzzEncodings)],
+			ByteOrder: fuzzByteOrders[int(order)%len(fuzzByteOrders)],
+			Scale:     scale,
+		}
+		value, err := profile.Decode(data)
+		if err == nil && !isFinite(value) {
+			t.Fatalf("%+v Decode(% X) = %v without error", profile, data, value)
+		}
+	})
+}
+
+func FuzzDetectDataFormat(f *testing.F) {
+	addFrameSeeds(f, []byte{0x00, 0x00, 0x00, 0x7C})
+	scanner := NewScanner(log.New(io.Discard, "", 0)).SetVerbose(false)
+	f.Fuzz(func(t *testing.T, first, second []byte) {
+		_, confidence := scanner.detectDataFormat(first)
+		if !isFinite(confidence) || confidence < 0 || confidence > 1.0001 {
+			t.Fatalf("detectDataFormat(% X) confidence = %v", first, confidence)
+		}
+
+		_, confidence, stability := scanner.detectDataFormatSamples([][]byte{first, second, first})
+		if !isFinite(confidence) || confidence < 0 || confidence > 1.0001 {
+			t.Fatalf("detectDataFormatSamples(% X, % X) confidence = %v", first, second, confidence)
+		}
+		if !isFinite(stability) || stability < 0 || stability > 1 {
+			t.Fatalf("detectDataFormatSamples(% X, % X) stability = %v", first, second, stability)
+		}
+	})
+}
+
+// fuzzConfigFile 將內容寫入臨時目錄中指定副檔名的檔案
+func fuzzConfigFile(t *testing.T, data []byte, ext string) string {
+	filename := filepath.Join(t.TempDir(), "config"+ext)
+	if err := os.WriteFile(filename, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func FuzzLoadConfigFile(f *testing.F) {
+	f.Add([]byte("device: /dev/ttyUSB0\nslaveid: 22\nbaudrate: 9600\n"), false)
+	f.Add([]byte("alarms:\n  - name: high\n    above: 50\n"), false)
+	f.Add([]byte("minpressure: .nan\nmaxpressure: .inf\n"), false)
+	f.Add([]byte(`{"device":"COM3","slaveid":1,"readinterval":1000000000}`), true)
+	f.Add([]byte("{{{{"), true)
+	f.Add([]byte("- [\n"), false)
+
+	logger := log.New(io.Discard, "", 0)
+	f.Fuzz(func(t *testing.T, data []byte, isJSON bool) {
+		ext := ".yaml"
+		if isJSON {
+			ext = ".json"
+		}
+		cl := NewConfigLoader().SetUseEnv(false).SetLogger(logger)
+		info := &ConfigInfo{Config: &Config{}, Source: make(map[string]ConfigSource)}
+		cl.setDefaults(info)
+		if err := cl.loadConfigFile(fuzzConfigFile(t, data, ext), info); err != nil {
+			return
+		}
+		// 合併後的配置無論是否有效，驗證都只能返回錯誤
+		_ = cl.validateConfig(info.Config)
+	})
+}
+
+func FuzzLoadScanConfig(f *testing.F) {
+	f.Add([]byte("slave_ids: [1, 22]\nbaud_rates: [9600]\nscan_timeout: 300ms\n"), false)
+	f.Add([]byte("stop_bits: [3]\nparities: [X]\nprobe_delay: -1s\n"), false)
+	f.Add([]byte(`{"slave_ids":[1],"baud_rates":[9600],"scan_timeout":300000000}`), true)
+	f.Add([]byte("slave_ids: 1\n"), false)
+
+	f.Fuzz(func(t *testing.T, data []byte, isJSON bool) {
+		ext := ".yaml"
+		if isJSON {
+			ext = ".json"
+		}
+		config, err := LoadScanConfig(fuzzConfigFile(t, data, ext))
+		if err != nil {
+			return
+		}
+		if err := config.Validate(); err != nil {
+			t.Fatalf("LoadScanConfig accepted an invalid config: %v", err)
+		}
+	})
+}
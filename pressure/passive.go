@@ -0,0 +1,182 @@
+// pressure/passive.go - 掃描前被動監聽：先不發送任何數據，確認總線上沒有其他主站後才主動探測
+package pressure
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// DefaultPassiveListen 被動監聽時每個波特率的默認監聽時長，應長於現有主站的輪詢間隔
+const DefaultPassiveListen = 2 * time.Second
+
+// passiveNoiseBytes 沒有完整幀時，收到少於此數量的字節按線路噪聲處理
+const passiveNoiseBytes = 8
+
+// BusActivity 掃描前被動監聽一個串口的結果
+type BusActivity struct {
+	Port     string        `json:"port"`                // 串口設備路徑
+	BaudRate int           `json:"baud_rate,omitempty"` // 收到數據的波特率，解析出完整幀時為主站使用的波特率
+	Bytes    int           `json:"bytes"`               // 收到的字節數
+	Frames   int           `json:"frames"`              // CRC 正確的 Modbus RTU 幀數量
+	SlaveIDs []byte        `json:"slave_ids,omitempty"` // 幀中出現的站點號，即現有主站正在輪詢的設備
+	Listened time.Duration `json:"listened"`            // 監聽總時長
+	Probed   bool          `json:"probed"`              // 發現主站後是否仍然主動探測（--force）
+	Error    string        `json:"error,omitempty"`     // 無法監聽時的錯誤
+}
+
+// MasterDetected 總線上是否已有主站：解析出完整幀，或收到的字節多於線路噪聲
+//
+// 收到數據但沒有完整幀時，主站可能使用了未監聽的波特率，同樣按已有主站處理。
+func (ba BusActivity) MasterDetected() bool {
+	return ba.Frames > 0 || ba.Bytes >= passiveNoiseBytes
+}
+
+// String 返回監聽結果的描述
+func (ba BusActivity) String() string {
+	switch {
+	case ba.Error != "":
+		return fmt.Sprintf("%s: 無法監聽: %s", ba.Port, ba.Error)
+	case ba.Frames > 0:
+		return fmt.Sprintf("%s: %d 波特率下收到 %d 個 Modbus 幀，正在輪詢站點號 %s",
+			ba.Port, ba.BaudRate, ba.Frames, formatSlaveIDRanges(ba.SlaveIDs))
+	case ba.MasterDetected():
+		return fmt.Sprintf("%s: 收到 %d 字節無法解析的數據，可能有使用其他波特率的主站", ba.Port, ba.Bytes)
+	default:
+		return fmt.Sprintf("%s: 監聽 %v 未發現其他主站", ba.Port, ba.Listened)
+	}
+}
+
+// SetPassiveFirst 設置掃描前在每個波特率下被動監聽 listen 時長，0 為不監聽
+//
+// 發現其他主站的串口默認不探測，避免打斷生產中的 PLC 輪詢；probeAnyway 為 true 時只警告，仍然探測。
+func (s *Scanner) SetPassiveFirst(listen time.Duration, probeAnyway bool) *Scanner {
+	s.passiveListen = listen
+	s.probeAnyway = probeAnyway
+	return s
+}
+
+// checkBus 掃描前被動監聽串口，返回監聽結果和是否可以主動探測
+func (s *Scanner) checkBus(port string, config ScanConfig) (*BusActivity, bool) {
+	s.logf("  👂 被動監聽 %s，每個波特率 %v...", port, s.passiveListen)
+	activity := s.listenForMaster(port, prioritizeBaudRates(config.BaudRates))
+
+	switch {
+	case activity.Error != "":
+		if !s.probeAnyway {
+			s.logf("  ⛔ %s，不探測此串口 (加上 --force 仍然探測)", activity)
+			return activity, false
+		}
+		s.logf("  ⚠️  %s，仍然探測", activity)
+	case activity.MasterDetected():
+		if !s.probeAnyway {
+			s.logf("  ⛔ %s，不探測此串口以免干擾現有主站 (加上 --force 仍然探測)", activity)
+			return activity, false
+		}
+		s.logf("  ⚠️  %s，仍然探測，可能造成總線衝突", activity)
+	default:
+		s.logf("  ✅ %s", activity)
+	}
+	activity.Probed = true
+	return activity, true
+}
+
+// listenForMaster 依次在各波特率下只接收不發送，解析出完整幀時停止
+func (s *Scanner) listenForMaster(port string, baudRates []int) *BusActivity {
+	activity := &BusActivity{Port: port}
+	for _, baudRate := range baudRates {
+		data, err := listenSerial(port, baudRate, s.passiveListen)
+		activity.Listened += s.passiveListen
+		if err != nil {
+			activity.Error = err.Error()
+			return activity
+		}
+		frames, slaveIDs := findRTUFrames(data)
+		if len(data) > activity.Bytes || frames > 0 {
+			activity.BaudRate, activity.Bytes = baudRate, len(data)
+		}
+		if frames > 0 {
+			activity.Frames, activity.SlaveIDs = frames, slaveIDs
+			return activity
+		}
+	}
+	return activity
+}
+
+// listenSerial 以指定波特率打開串口，在 window 時長內只接收數據
+func listenSerial(port string, baudRate int, window time.Duration) ([]byte, error) {
+	mode := &serial.Mode{
+		BaudRate: baudRate,
+		DataBits: 8,
+		Parity:   serial.NoParity,
+		StopBits: serial.OneStopBit,
+	}
+	p, err := serial.Open(port, mode)
+	if err != nil {
+		return nil, fmt.Errorf("打開串口 %s 失敗: %v", port, err)
+	}
+	defer p.Close()
+	if err := p.SetReadTimeout(100 * time.Millisecond); err != nil {
+		return nil, fmt.Errorf("設置串口超時失敗: %v", err)
+	}
+
+	var data []byte
+	chunk := make([]byte, 256)
+	for deadline := time.Now().Add(window); time.Now().Before(deadline); {
+		n, err := p.Read(chunk)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("讀取串口 %s 失敗: %v", port, err)
+		}
+		data = append(data, chunk[:n]...)
+	}
+	return data, nil
+}
+
+// findRTUFrames 在接收的字節流中查找 CRC 正確的 Modbus RTU 幀，返回幀數量和出現的站點號
+//
+// 被動監聽無法得到幀間隔，按功能碼嘗試請求和響應的可能長度，CRC 正確即視為一幀。
+func findRTUFrames(data []byte) (int, []byte) {
+	frames := 0
+	var slaveIDs []byte
+	seen := make(map[byte]bool)
+	for i := 0; i+4 <= len(data); {
+		length := 0
+		for _, candidate := range rtuFrameCandidates(data[i:]) {
+			if candidate >= 4 && i+candidate <= len(data) && rtuCRCValid(data[i:i+candidate]) {
+				length = candidate
+				break
+			}
+		}
+		if length == 0 {
+			i++
+			continue
+		}
+		frames++
+		if slaveID := data[i]; CheckPollingSlaveID(slaveID) == nil && !seen[slaveID] {
+			seen[slaveID] = true
+			slaveIDs = append(slaveIDs, slaveID)
+		}
+		i += length
+	}
+	return frames, slaveIDs
+}
+
+// rtuFrameCandidates 按功能碼返回從 frame 開頭的幀可能的長度
+func rtuFrameCandidates(frame []byte) []int {
+	function := frame[1]
+	candidates := []int{8} // 0x01-0x06 請求、0x05/0x06/0x0F/0x10 響應、0x08 請求和響應
+	switch {
+	case function&0x80 != 0:
+		return []int{5} // 異常響應
+	case function == 0x0F || function == 0x10:
+		if len(frame) > 6 {
+			candidates = append(candidates, 9+int(frame[6])) // 寫多個線圈/寄存器請求
+		}
+	}
+	if len(frame) > 2 {
+		candidates = append(candidates, 5+int(frame[2])) // 讀取類響應：站點號、功能碼、字節數、數據、CRC
+	}
+	return candidates
+}
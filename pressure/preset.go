@@ -0,0 +1,139 @@
+// pressure/preset.go - 行業單位預設：顯示單位、小數位數、告警符號約定和報告用語
+package pressure
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// 內建單位預設名稱
+const (
+	PresetGeneral    = "general"    // 通用，Pa
+	PresetCleanroom  = "cleanroom"  // 潔淨室室壓差，Pa
+	PresetHVAC       = "hvac"       // 暖通空調風管靜壓，inH2O
+	PresetFiltration = "filtration" // 過濾器壓降，mmH2O
+)
+
+// UnitPreset 行業單位預設，一次設置人讀輸出的單位、小數位數、告警符號約定和報告用語
+//
+// 只影響文本輸出、告警描述和 HTML 報告；JSON/CSV 輸出、告警上下限和區間配置始終使用 Pa。
+type UnitPreset struct {
+	Name        string       // 名稱
+	Description string       // 說明
+	Unit        PressureUnit // 顯示單位
+	Decimals    int          // 小數位數
+	Magnitude   bool         // 按絕對值顯示和判斷告警（過濾器壓降不分取壓口方向）
+	Quantity    string       // 測量量的名稱，用於報告，如 "室壓差"
+	LowText     string       // 低於下限的描述
+	HighText    string       // 高於上限的描述
+	ReportTitle string       // 趨勢報告標題
+}
+
+// 內建單位預設
+var unitPresets = map[string]UnitPreset{
+	PresetGeneral: {
+		Name:        PresetGeneral,
+		Description: "通用壓差，Pa，保留取壓口方向的正負號",
+		Unit:        Pascal,
+		Decimals:    2,
+		Quantity:    "壓差",
+		LowText:     "低於下限",
+		HighText:    "高於上限",
+		ReportTitle: "壓差監測趨勢報告",
+	},
+	PresetCleanroom: {
+		Name:        PresetCleanroom,
+		Description: "潔淨室室壓差，Pa，正值為房間相對參考區域的正壓",
+		Unit:        Pascal,
+		Decimals:    1,
+		Quantity:    "室壓差",
+		LowText:     "正壓不足，低於下限",
+		HighText:    "室壓差過高，高於上限",
+		ReportTitle: "潔淨室壓差監測報告",
+	},
+	PresetHVAC: {
+		Name:        PresetHVAC,
+		Description: "暖通空調風管靜壓，inH2O，回風側為負值",
+		Unit:        InchH2O,
+		Decimals:    3,
+		Quantity:    "靜壓",
+		LowText:     "靜壓過低，低於下限",
+		HighText:    "靜壓過高，高於上限",
+		ReportTitle: "風管靜壓監測報告",
+	},
+	PresetFiltration: {
+		Name:        PresetFiltration,
+		Description: "過濾器壓降，mmH2O，按絕對值判斷，上限用於提示更換濾網",
+		Unit:        MmH2O,
+		Decimals:    1,
+		Magnitude:   true,
+		Quantity:    "壓降",
+		LowText:     "壓降過低（濾網可能破損或旁通），低於下限",
+		HighText:    "壓降過高（濾網需要更換），高於上限",
+		ReportTitle: "過濾器壓降監測報告",
+	},
+}
+
+// DefaultUnitPreset 未設置單位預設時使用的通用預設
+var DefaultUnitPreset = unitPresets[PresetGeneral]
+
+// GetUnitPreset 按名稱獲取內建單位預設，空名稱返回通用預設
+func GetUnitPreset(name string) (UnitPreset, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return DefaultUnitPreset, nil
+	}
+	preset, ok := unitPresets[name]
+	if !ok {
+		return UnitPreset{}, fmt.Errorf("未知的單位預設: %s (可用: %s)",
+			name, strings.Join(UnitPresetNames(), ", "))
+	}
+	return preset, nil
+}
+
+// UnitPresetNames 返回所有內建單位預設名稱
+func UnitPresetNames() []string {
+	names := make([]string, 0, len(unitPresets))
+	for name := range unitPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Adjust 按符號約定處理壓力值 (Pa)，Magnitude 預設取絕對值
+func (up UnitPreset) Adjust(pascal float64) float64 {
+	if up.Magnitude {
+		return math.Abs(pascal)
+	}
+	return pascal
+}
+
+// Value 將壓力值 (Pa) 按符號約定處理後換算為顯示單位
+func (up UnitPreset) Value(pascal float64) float64 {
+	return up.Unit.ConvertFromPascal(up.Adjust(pascal))
+}
+
+// Format 將壓力值 (Pa) 格式化為帶單位的顯示文本，如 "0.052 inH2O"
+func (up UnitPreset) Format(pascal float64) string {
+	return fmt.Sprintf("%.*f %s", up.Decimals, up.Value(pascal), up.Unit.Symbol())
+}
+
+// CheckAlarm 按符號約定檢查壓力值 (Pa) 是否違反告警規則，返回使用本預設用語和單位的描述
+func (up UnitPreset) CheckAlarm(rule AlarmRule, pascal float64) (string, bool) {
+	value := up.Adjust(pascal)
+	if rule.Low != nil && value < *rule.Low {
+		return fmt.Sprintf("%s %s %s", up.Format(value), up.LowText, up.Format(*rule.Low)), true
+	}
+	if rule.High != nil && value > *rule.High {
+		return fmt.Sprintf("%s %s %s", up.Format(value), up.HighText, up.Format(*rule.High)), true
+	}
+	return "", false
+}
+
+// String 返回預設的簡短描述
+func (up UnitPreset) String() string {
+	return fmt.Sprintf("%s (%s, %d 位小數)", up.Name, up.Unit.Symbol(), up.Decimals)
+}
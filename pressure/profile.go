@@ -0,0 +1,459 @@
+// pressure/profile.go - 設備配置檔：壓力寄存器地址、數量、編碼和字節序
+package pressure
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 數值編碼
+const (
+	EncodingInt16   = "int16"
+	EncodingUint16  = "uint16"
+	EncodingInt32   = "int32"
+	EncodingUint32  = "uint32"
+	EncodingFloat32 = "float32"
+)
+
+// 字節序，A 為數值的最高字節；16 位數值只看前兩個字母（AB 或 BA）
+const (
+	ByteOrderABCD = "ABCD" // 大端，Modbus 標準
+	ByteOrderCDAB = "CDAB" // 字交換（低字在前），常見於浮點數
+	ByteOrderBADC = "BADC" // 字內字節交換
+	ByteOrderDCBA = "DCBA" // 小端
+)
+
+// CheckByteOrder 檢查字節序名稱（大小寫不限）
+func CheckByteOrder(order string) error {
+	switch strings.ToUpper(strings.TrimSpace(order)) {
+	case ByteOrderABCD, ByteOrderCDAB, ByteOrderBADC, ByteOrderDCBA:
+		return nil
+	}
+	return fmt.Errorf("無效的字節序: %q (可用: ABCD, CDAB, BADC, DCBA)", order)
+}
+
+// DefaultDecimalScale 普時達十進制格式原始數值到 Pa 的係數（擴大 10 倍）
+const DefaultDecimalScale = 0.1
+
+// CheckScale 檢查原始數值到 Pa 的係數，必須為非零的有限數值
+func CheckScale(scale float64) error {
+	if scale == 0 || math.IsNaN(scale) || math.IsInf(scale, 0) {
+		return fmt.Errorf("無效的換算係數: %v (必須為非零數值，如 0.1、0.01 或 1)", scale)
+	}
+	return nil
+}
+
+// ParseScale 解析換算係數，接受係數 (如 0.01) 或 /除數 (如 /100，即儀表數值擴大 100 倍)
+func ParseScale(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	var scale float64
+	if divisor, ok := strings.CutPrefix(value, "/"); ok {
+		d, err := strconv.ParseFloat(strings.TrimSpace(divisor), 64)
+		if err != nil || d == 0 {
+			return 0, fmt.Errorf("無效的換算係數: %q", value)
+		}
+		scale = 1 / d
+	} else {
+		var err error
+		if scale, err = strconv.ParseFloat(value, 64); err != nil {
+			return 0, fmt.Errorf("無效的換算係數: %q", value)
+		}
+	}
+	if err := CheckScale(scale); err != nil {
+		return 0, err
+	}
+	return scale, nil
+}
+
+// 壓力寄存器類型，對應讀取功能碼
+const (
+	RegisterTypeHolding = "holding" // 保持寄存器，功能碼 0x03
+	RegisterTypeInput   = "input"   // 輸入寄存器，功能碼 0x04
+)
+
+// RegisterTypeFunction 返回寄存器類型對應的讀取功能碼
+func RegisterTypeFunction(registerType string) (byte, error) {
+	switch strings.ToLower(strings.TrimSpace(registerType)) {
+	case RegisterTypeHolding:
+		return ModbusFunctionReadHoldingRegisters, nil
+	case RegisterTypeInput:
+		return ModbusFunctionReadInputRegisters, nil
+	default:
+		return 0, fmt.Errorf("無效的寄存器類型: %s (可用: holding, input)", registerType)
+	}
+}
+
+// 內建設備配置檔名稱
+const (
+	ProfilePushidaDecimal = "pushida-decimal" // 普時達，32 位整數 ×10
+	ProfilePushidaFloat   = "pushida-float"   // 普時達，IEEE 754 浮點數，3412 字節序
+	ProfilePushidaInt16   = "pushida-int16"   // 普時達緊湊型號，單寄存器 16 位有符號整數 ×10
+	ProfilePushidaUint16  = "pushida-uint16"  // 普時達緊湊型號，單寄存器 16 位無符號整數 ×10
+)
+
+// DeviceProfile 描述如何從儀表讀取壓力值
+//
+// 不同廠商的壓差儀只是寄存器地址和數值編碼不同，用配置檔描述即可支援，不需要修改驅動。
+type DeviceProfile struct {
+	Name        string  `json:"name" yaml:"name"`                                   // 名稱
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"` // 說明
+	Register    uint16  `json:"register" yaml:"register"`                           // 壓力寄存器起始地址
+	Count       uint16  `json:"count,omitempty" yaml:"count,omitempty"`             // 讀取的寄存器數量，0 為編碼所需的數量
+	Function    byte    `json:"function,omitempty" yaml:"function,omitempty"`       // 功能碼 3（保持寄存器，默認）或 4（輸入寄存器）
+	Encoding    string  `json:"encoding" yaml:"encoding"`                           // 數值編碼 (int16/uint16/int32/uint32/float32)
+	ByteOrder   string  `json:"byteorder,omitempty" yaml:"byteorder,omitempty"`     // 字節序 (ABCD/CDAB/BADC/DCBA)，默認 ABCD
+	Scale       float64 `json:"scale,omitempty" yaml:"scale,omitempty"`             // 原始數值到 Pa 的係數，0 為 1
+
+	// 設備標識寄存器（ReadDeviceInfo），都未設置時改用功能碼 0x2B/0x0E 讀取設備標識
+	Manufacturer     string `json:"manufacturer,omitempty" yaml:"manufacturer,omitempty"`         // 製造商名稱
+	ModelRegister    uint16 `json:"modelregister,omitempty" yaml:"modelregister,omitempty"`       // 型號保持寄存器地址，0 為不支援
+	ModelLength      uint16 `json:"modellength,omitempty" yaml:"modellength,omitempty"`           // 型號為 ASCII 時佔用的寄存器數量，0 為單個寄存器的型號代碼
+	FirmwareRegister uint16 `json:"firmwareregister,omitempty" yaml:"firmwareregister,omitempty"` // 韌體版本保持寄存器地址（高字節主版本，低字節次版本），0 為不支援
+
+	// 合併讀取：溫度寄存器與壓力寄存器相鄰時用一次請求讀取
+	CoalesceGap int `json:"coalescegap,omitempty" yaml:"coalescegap,omitempty"` // 最多跨越的未使用寄存器數量，0 為默認 8，負數為不合併
+
+	// 可寫的設置寄存器（量程、單位、報警點等），params dump/restore 在更換儀表時複製設置
+	Parameters []ProfileParameter `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+}
+
+// ProfileParameter 儀表的一個可寫設置寄存器
+type ProfileParameter struct {
+	Name        string `json:"name" yaml:"name"`                                   // 名稱，在參數檔案中標識設置
+	Register    uint16 `json:"register" yaml:"register"`                           // 保持寄存器起始地址
+	Count       uint16 `json:"count,omitempty" yaml:"count,omitempty"`             // 寄存器數量，0 為 1
+	Description string `json:"description,omitempty" yaml:"description,omitempty"` // 說明
+}
+
+// 內建設備配置檔
+var deviceProfiles = map[string]DeviceProfile{
+	ProfilePushidaDecimal: {
+		Name:         ProfilePushidaDecimal,
+		Description:  "普時達壓差儀，十進制格式（32 位有符號整數，擴大 10 倍）",
+		Manufacturer: "普時達",
+		Register:     PushidaPressureRegisterAddr,
+		Count:        PushidaPressureRegisterCount,
+		Function:     ModbusFunctionReadHoldingRegisters,
+		Encoding:     EncodingInt32,
+		ByteOrder:    ByteOrderABCD,
+		Scale:        DefaultDecimalScale,
+	},
+	ProfilePushidaFloat: {
+		Name:         ProfilePushidaFloat,
+		Description:  "普時達壓差儀，浮點數格式（IEEE 754，Modbus 3412 字節序）",
+		Manufacturer: "普時達",
+		Register:     PushidaPressureRegisterAddr,
+		Count:        PushidaPressureRegisterCount,
+		Function:     ModbusFunctionReadHoldingRegisters,
+		Encoding:     EncodingFloat32,
+		ByteOrder:    ByteOrderCDAB,
+		Scale:        1,
+	},
+	ProfilePushidaInt16: {
+		Name:         ProfilePushidaInt16,
+		Description:  "普時達緊湊型號，單寄存器 16 位有符號整數（擴大 10 倍）",
+		Manufacturer: "普時達",
+		Register:     PushidaPressureRegisterAddr,
+		Count:        1,
+		Function:     ModbusFunctionReadHoldingRegisters,
+		Encoding:     EncodingInt16,
+		ByteOrder:    ByteOrderABCD,
+		Scale:        DefaultDecimalScale,
+	},
+	ProfilePushidaUint16: {
+		Name:         ProfilePushidaUint16,
+		Description:  "普時達緊湊型號，單寄存器 16 位無符號整數（擴大 10 倍）",
+		Manufacturer: "普時達",
+		Register:     PushidaPressureRegisterAddr,
+		Count:        1,
+		Function:     ModbusFunctionReadHoldingRegisters,
+		Encoding:     EncodingUint16,
+		ByteOrder:    ByteOrderABCD,
+		Scale:        DefaultDecimalScale,
+	},
+}
+
+// GetDeviceProfile 按名稱獲取內建設備配置檔
+func GetDeviceProfile(name string) (DeviceProfile, error) {
+	profile, ok := deviceProfiles[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return DeviceProfile{}, fmt.Errorf("未知的設備配置檔: %s (可用: %s)",
+			name, strings.Join(DeviceProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// DeviceProfileNames 返回所有內建設備配置檔名稱
+func DeviceProfileNames() []string {
+	names := make([]string, 0, len(deviceProfiles))
+	for name := range deviceProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProfileForFormat 返回與普時達數據格式對應的內建配置檔
+func ProfileForFormat(format DataFormatType) DeviceProfile {
+	switch format {
+	case FloatFormat:
+		return deviceProfiles[ProfilePushidaFloat]
+	case Int16Format:
+		return deviceProfiles[ProfilePushidaInt16]
+	case Uint16Format:
+		return deviceProfiles[ProfilePushidaUint16]
+	default:
+		return deviceProfiles[ProfilePushidaDecimal]
+	}
+}
+
+// isPushidaProfile 是否為與數據格式一一對應的普時達內建配置檔
+func isPushidaProfile(name string) bool {
+	switch name {
+	case ProfilePushidaDecimal, ProfilePushidaFloat, ProfilePushidaInt16, ProfilePushidaUint16:
+		return true
+	}
+	return false
+}
+
+// ResolveDeviceProfile 返回配置使用的設備配置檔
+//
+// 優先順序：customprofile、deviceprofile 指定的內建配置檔、dataformat 對應的普時達配置檔。
+// 設置了 registertype 時覆蓋配置檔的功能碼（部分固件版本只通過輸入寄存器提供壓力值），
+// 設置了 byteorder 時覆蓋配置檔的字節序（部分轉換器和固件以大端字序提供浮點數），
+// 設置了 scale 時覆蓋配置檔的換算係數（部分儀表以 ×100 或直接以 Pa 為單位提供整數）。
+func ResolveDeviceProfile(config Config) (DeviceProfile, error) {
+	var profile DeviceProfile
+	switch {
+	case config.CustomProfile != nil:
+		profile = *config.CustomProfile
+		if profile.Name == "" {
+			profile.Name = "custom"
+		}
+	case config.DeviceProfile != "":
+		var err error
+		if profile, err = GetDeviceProfile(config.DeviceProfile); err != nil {
+			return DeviceProfile{}, err
+		}
+	default:
+		profile = ProfileForFormat(config.DataFormat)
+	}
+
+	if config.RegisterType != "" {
+		function, err := RegisterTypeFunction(config.RegisterType)
+		if err != nil {
+			return DeviceProfile{}, err
+		}
+		profile.Function = function
+	}
+	if config.ByteOrder != "" {
+		profile.ByteOrder = config.ByteOrder
+	}
+	if config.Scale != 0 {
+		profile.Scale = config.Scale
+	}
+
+	profile.normalize()
+	if err := profile.Validate(); err != nil {
+		return DeviceProfile{}, fmt.Errorf("設備配置檔 %s: %v", profile.Name, err)
+	}
+	return profile, nil
+}
+
+// normalize 填入默認值
+func (dp *DeviceProfile) normalize() {
+	dp.Encoding = strings.ToLower(strings.TrimSpace(dp.Encoding))
+	dp.ByteOrder = strings.ToUpper(strings.TrimSpace(dp.ByteOrder))
+	if dp.ByteOrder == "" {
+		dp.ByteOrder = ByteOrderABCD
+	}
+	if dp.Function == 0 {
+		dp.Function = ModbusFunctionReadHoldingRegisters
+	}
+	if dp.Count == 0 {
+		dp.Count = uint16(dp.valueSize() / 2)
+	}
+	if dp.Scale == 0 {
+		dp.Scale = 1
+	}
+}
+
+// valueSize 返回數值編碼佔用的字節數，未知編碼返回 0
+func (dp DeviceProfile) valueSize() int {
+	switch dp.Encoding {
+	case EncodingInt16, EncodingUint16:
+		return 2
+	case EncodingInt32, EncodingUint32, EncodingFloat32:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// Validate 檢查配置檔是否完整
+func (dp DeviceProfile) Validate() error {
+	size := dp.valueSize()
+	if size == 0 {
+		return fmt.Errorf("無效的數值編碼: %q (可用: int16, uint16, int32, uint32, float32)", dp.Encoding)
+	}
+	if err := CheckByteOrder(dp.ByteOrder); err != nil {
+		return err
+	}
+	if err := CheckScale(dp.Scale); err != nil {
+		return err
+	}
+	if dp.Function != ModbusFunctionReadHoldingRegisters && dp.Function != ModbusFunctionReadInputRegisters {
+		return fmt.Errorf("功能碼只能是 3 (保持寄存器) 或 4 (輸入寄存器)，當前: %d", dp.Function)
+	}
+	if int(dp.Count)*2 < size {
+		return fmt.Errorf("讀取 %d 個寄存器不足以容納 %s 數值", dp.Count, dp.Encoding)
+	}
+	if dp.Count > MaxReadRegisters {
+		return fmt.Errorf("寄存器數量 %d 超過 Modbus 單次讀取上限 %d", dp.Count, MaxReadRegisters)
+	}
+	names := make(map[string]bool, len(dp.Parameters))
+	for _, param := range dp.Parameters {
+		if param.Name == "" {
+			return fmt.Errorf("設置寄存器 0x%04X 缺少名稱", param.Register)
+		}
+		if names[param.Name] {
+			return fmt.Errorf("設置寄存器名稱重複: %s", param.Name)
+		}
+		names[param.Name] = true
+		if param.Count > MaxWriteRegisters {
+			return fmt.Errorf("設置寄存器 %s 的數量 %d 超過 Modbus 單次寫入上限 %d", param.Name, param.Count, MaxWriteRegisters)
+		}
+	}
+	return nil
+}
+
+// Decode 將讀取到的寄存器數據解碼為壓力值 (Pa)
+func (dp DeviceProfile) Decode(data []byte) (float64, error) {
+	size := dp.valueSize()
+	if size == 0 {
+		return 0, fmt.Errorf("無效的數值編碼: %q", dp.Encoding)
+	}
+	if len(data) < size {
+		return 0, fmt.Errorf("數據長度不足: 需要 %d 字節，實際 %d 字節", size, len(data))
+	}
+	b := reorderBytes(data[:size], dp.ByteOrder)
+
+	var value float64
+	switch dp.Encoding {
+	case EncodingInt16:
+		value = float64(int16(binary.BigEndian.Uint16(b)))
+	case EncodingUint16:
+		value = float64(binary.BigEndian.Uint16(b))
+	case EncodingInt32:
+		value = float64(int32(binary.BigEndian.Uint32(b)))
+	case EncodingUint32:
+		value = float64(binary.BigEndian.Uint32(b))
+	case EncodingFloat32:
+		value = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	}
+	value *= dp.Scale
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, errNonFinite{value: value}
+	}
+	return value, nil
+}
+
+// errNonFinite 解碼結果不是有限數值：浮點編碼的 NaN/Inf，或換算後溢出
+type errNonFinite struct {
+	value float64
+}
+
+func (e errNonFinite) Error() string {
+	return fmt.Sprintf("解析結果不是有限數值: %v", e.value)
+}
+
+// Encode 將壓力值 (Pa) 按配置檔編碼為寄存器值（Decode 的逆運算），數值超出編碼範圍時返回錯誤
+func (dp DeviceProfile) Encode(pressure float64) ([]uint16, error) {
+	size := dp.valueSize()
+	if size == 0 {
+		return nil, fmt.Errorf("無效的數值編碼: %q", dp.Encoding)
+	}
+	scale := dp.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	raw := pressure / scale
+
+	var minRaw, maxRaw float64
+	switch dp.Encoding {
+	case EncodingInt16:
+		minRaw, maxRaw = math.MinInt16, math.MaxInt16
+	case EncodingUint16:
+		minRaw, maxRaw = 0, math.MaxUint16
+	case EncodingInt32:
+		minRaw, maxRaw = math.MinInt32, math.MaxInt32
+	case EncodingUint32:
+		minRaw, maxRaw = 0, math.MaxUint32
+	case EncodingFloat32:
+		minRaw, maxRaw = -math.MaxFloat32, math.MaxFloat32
+	}
+	if dp.Encoding != EncodingFloat32 {
+		raw = math.Round(raw)
+	}
+	if raw < minRaw || raw > maxRaw {
+		return nil, fmt.Errorf("壓力 %.2f Pa 超出 %s 編碼的範圍", pressure, dp.Encoding)
+	}
+
+	b := make([]byte, size)
+	switch dp.Encoding {
+	case EncodingInt16:
+		binary.BigEndian.PutUint16(b, uint16(int16(raw)))
+	case EncodingUint16:
+		binary.BigEndian.PutUint16(b, uint16(raw))
+	case EncodingInt32:
+		binary.BigEndian.PutUint32(b, uint32(int32(raw)))
+	case EncodingUint32:
+		binary.BigEndian.PutUint32(b, uint32(raw))
+	case EncodingFloat32:
+		binary.BigEndian.PutUint32(b, math.Float32bits(float32(raw)))
+	}
+	// 字節序的重排是對稱的，重排兩次即回到原來的順序
+	b = reorderBytes(b, dp.ByteOrder)
+
+	count := int(dp.Count)
+	if count < size/2 {
+		count = size / 2
+	}
+	registers := make([]uint16, count)
+	for i := 0; i < size/2; i++ {
+		registers[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return registers, nil
+}
+
+// String 返回配置檔的簡短描述
+func (dp DeviceProfile) String() string {
+	return fmt.Sprintf("%s (寄存器 0x%04X×%d, 功能碼 %d, %s %s, ×%g)",
+		dp.Name, dp.Register, dp.Count, dp.Function, dp.Encoding, dp.ByteOrder, dp.Scale)
+}
+
+// reorderBytes 按字節序將數據重排為大端（ABCD）
+func reorderBytes(data []byte, order string) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+	if len(out) == 2 {
+		if order == ByteOrderBADC || order == ByteOrderDCBA {
+			out[0], out[1] = data[1], data[0]
+		}
+		return out
+	}
+
+	switch order {
+	case ByteOrderCDAB:
+		out[0], out[1], out[2], out[3] = data[2], data[3], data[0], data[1]
+	case ByteOrderBADC:
+		out[0], out[1], out[2], out[3] = data[1], data[0], data[3], data[2]
+	case ByteOrderDCBA:
+		out[0], out[1], out[2], out[3] = data[3], data[2], data[1], data[0]
+	}
+	return out
+}
@@ -0,0 +1,287 @@
+// pressure/provision.go - 從掃描結果批量生成多設備配置檔
+package pressure
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProvisionedDevice 多設備配置檔中的一台儀表
+type ProvisionedDevice struct {
+	Name     string `json:"name" yaml:"name"`                               // 唯一名稱，用於日誌和讀數
+	Room     string `json:"room,omitempty" yaml:"room,omitempty"`           // 房間名稱
+	Floor    string `json:"floor,omitempty" yaml:"floor,omitempty"`         // 樓層
+	AssetTag string `json:"asset_tag,omitempty" yaml:"asset_tag,omitempty"` // 資產編號
+	Config   Config `json:"config" yaml:"config"`                           // 連接和讀取配置
+}
+
+// Location 返回儀表的安裝位置
+func (pd ProvisionedDevice) Location() Location {
+	return Location{Port: pd.Config.Endpoint(), SlaveID: pd.Config.SlaveID, Room: pd.Room, Floor: pd.Floor, AssetTag: pd.AssetTag}
+}
+
+// ProvisionedDifferential 多設備配置檔中的虛擬壓差通道，兩側以儀表名稱指定
+type ProvisionedDifferential struct {
+	Name    string        `json:"name" yaml:"name"`                             // 通道名稱
+	Plus    string        `json:"plus" yaml:"plus"`                             // 被減數儀表名稱（如房間）
+	Minus   string        `json:"minus" yaml:"minus"`                           // 減數儀表名稱（如走廊）
+	SlaveID byte          `json:"slave_id,omitempty" yaml:"slave_id,omitempty"` // 合成站點號 (248-255)，0 為自動分配
+	MaxSkew time.Duration `json:"max_skew,omitempty" yaml:"max_skew,omitempty"` // 兩個讀數允許的最大時間差，0 為讀取間隔
+}
+
+// DevicesFile 多設備配置檔，每台儀表一條記錄
+type DevicesFile struct {
+	Devices       []ProvisionedDevice       `json:"devices" yaml:"devices"`
+	Differentials []ProvisionedDifferential `json:"differentials,omitempty" yaml:"differentials,omitempty"`
+}
+
+// LoadScanResult 讀取保存的掃描結果 (scan_results_*.json)
+func LoadScanResult(filename string) (*ScanResult, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("讀取掃描結果失敗: %v", err)
+	}
+	var result ScanResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("解析掃描結果 %s 失敗: %v", filename, err)
+	}
+	return &result, nil
+}
+
+// ProvisionFromScan 將掃描結果中響應的設備轉換為多設備配置檔，返回需要人工確認的問題
+//
+// 每台儀表的配置帶上掃描到的串口、站點號、數據格式、校驗位、波特率和寄存器類型，名稱默認為
+// 掃描時的安裝位置或「串口名-站點號」。
+func ProvisionFromScan(result *ScanResult) (*DevicesFile, []string) {
+	file := &DevicesFile{}
+	var warnings []string
+	for _, device := range result.Devices {
+		if !device.Responsive {
+			continue
+		}
+		config := Config{
+			Device:          device.Device,
+			SlaveID:         device.SlaveID,
+			ReadInterval:    DefaultReadInterval,
+			DataFormat:      device.DataFormat,
+			Parity:          DeviceParity(device),
+			StopBits:        DeviceStopBits(device),
+			BaudRate:        DeviceBaudRate(device),
+			RegisterType:    DeviceRegisterType(device),
+			ByteOrder:       DeviceByteOrder(device),
+			Scale:           DeviceScale(device),
+			ConnectTimeout:  DefaultConnectTimeout,
+			ResponseTimeout: DefaultResponseTimeout,
+		}
+		config.UseScannedEndpoint(device)
+
+		pd := ProvisionedDevice{Name: provisionName(device), Config: config}
+		if device.Location != nil {
+			pd.Room, pd.Floor, pd.AssetTag = device.Location.Room, device.Location.Floor, device.Location.AssetTag
+		}
+		if config.BaudRate != 0 {
+			warnings = append(warnings, fmt.Sprintf("%s: 掃描到的波特率為 %d，已寫入配置 (baudrate)；同一串口上的儀表需使用相同波特率，可用 --set-baud-rate 統一",
+				pd.Name, config.BaudRate))
+		}
+		file.Devices = append(file.Devices, pd)
+	}
+	return file, warnings
+}
+
+// provisionName 返回設備的默認名稱
+func provisionName(device DeviceInfo) string {
+	if device.Location != nil && device.Location.Room != "" {
+		return device.Location.Room
+	}
+	return fmt.Sprintf("%s-%d", filepath.Base(device.Device), device.SlaveID)
+}
+
+// Validate 檢查名稱和串口站點號是否重複，同一串口上的儀表校驗位、波特率、數據格式和寄存器類型是否一致，
+// 壓差通道引用的儀表是否存在
+func (df *DevicesFile) Validate() error {
+	names := make(map[string]bool)
+	addresses := make(map[string]bool)
+	for i, pd := range df.Devices {
+		if strings.TrimSpace(pd.Name) == "" {
+			return fmt.Errorf("第 %d 台設備缺少名稱", i+1)
+		}
+		if names[pd.Name] {
+			return fmt.Errorf("設備名稱重複: %s", pd.Name)
+		}
+		names[pd.Name] = true
+
+		address := fmt.Sprintf("%s/%d", pd.Config.Endpoint(), pd.Config.SlaveID)
+		if addresses[address] {
+			return fmt.Errorf("設備 %s: 串口 %s 站點 %d 重複", pd.Name, pd.Config.Endpoint(), pd.Config.SlaveID)
+		}
+		addresses[address] = true
+		if err := CheckPollingSlaveID(pd.Config.SlaveID); err != nil {
+			return fmt.Errorf("設備 %s: %v", pd.Name, err)
+		}
+	}
+	for _, diff := range df.Differentials {
+		switch {
+		case strings.TrimSpace(diff.Name) == "":
+			return fmt.Errorf("壓差通道缺少名稱")
+		case names[diff.Name]:
+			return fmt.Errorf("壓差通道名稱重複: %s", diff.Name)
+		case !names[diff.Plus]:
+			return fmt.Errorf("壓差通道 %s: 未知的儀表 %q", diff.Name, diff.Plus)
+		case !names[diff.Minus]:
+			return fmt.Errorf("壓差通道 %s: 未知的儀表 %q", diff.Name, diff.Minus)
+		case diff.Plus == diff.Minus:
+			return fmt.Errorf("壓差通道 %s 的兩側是同一台儀表 %s", diff.Name, diff.Plus)
+		case diff.SlaveID != 0 && !IsReservedSlaveID(diff.SlaveID):
+			return fmt.Errorf("壓差通道 %s 的合成站點號 %d 必須在 %d-255 之間", diff.Name, diff.SlaveID, ModbusReservedMinID)
+		}
+	}
+	for _, group := range df.groups() {
+		first := group[0].Config
+		for _, pd := range group[1:] {
+			switch {
+			case !strings.EqualFold(pd.Config.Parity, first.Parity):
+				return fmt.Errorf("串口 %s 上的儀表校驗位不一致: %s 為 %q，%s 為 %q",
+					first.Endpoint(), group[0].Name, first.Parity, pd.Name, pd.Config.Parity)
+			case pd.Config.SerialStopBits() != first.SerialStopBits():
+				return fmt.Errorf("串口 %s 上的儀表停止位不一致: %s 為 %d，%s 為 %d",
+					first.Endpoint(), group[0].Name, first.SerialStopBits(), pd.Name, pd.Config.SerialStopBits())
+			case pd.Config.SerialBaudRate() != first.SerialBaudRate():
+				return fmt.Errorf("串口 %s 上的儀表波特率不一致: %s 為 %d，%s 為 %d",
+					first.Endpoint(), group[0].Name, first.SerialBaudRate(), pd.Name, pd.Config.SerialBaudRate())
+			case pd.Config.DataFormat != first.DataFormat:
+				return fmt.Errorf("串口 %s 上的儀表數據格式不一致: %s 為 %s，%s 為 %s",
+					first.Endpoint(), group[0].Name, first.DataFormat, pd.Name, pd.Config.DataFormat)
+			case !strings.EqualFold(pd.Config.RegisterType, first.RegisterType):
+				return fmt.Errorf("串口 %s 上的儀表寄存器類型不一致: %s 為 %q，%s 為 %q",
+					first.Endpoint(), group[0].Name, first.RegisterType, pd.Name, pd.Config.RegisterType)
+			}
+		}
+	}
+	return nil
+}
+
+// groups 按連接端點分組，同一串口或網關上的儀表作為一條總線輪詢
+func (df *DevicesFile) groups() [][]ProvisionedDevice {
+	index := make(map[string]int)
+	var groups [][]ProvisionedDevice
+	for _, pd := range df.Devices {
+		endpoint := pd.Config.Endpoint()
+		i, ok := index[endpoint]
+		if !ok {
+			i = len(groups)
+			index[endpoint] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], pd)
+	}
+	return groups
+}
+
+// ManagedDevices 轉換為設備管理器的配置：同一串口上的多台儀表合併為一條總線
+//
+// 總線使用第一台儀表的配置（Validate 已檢查一致），名稱為串口名；只有一台儀表時使用其名稱。
+func (df *DevicesFile) ManagedDevices() []ManagedDeviceConfig {
+	var devices []ManagedDeviceConfig
+	for _, group := range df.groups() {
+		if len(group) == 1 {
+			devices = append(devices, ManagedDeviceConfig{Name: managedName(group), Config: group[0].Config})
+			continue
+		}
+		device := ManagedDeviceConfig{Name: managedName(group), Config: group[0].Config}
+		for _, pd := range group {
+			device.SlaveIDs = append(device.SlaveIDs, pd.Config.SlaveID)
+		}
+		sort.Slice(device.SlaveIDs, func(i, j int) bool { return device.SlaveIDs[i] < device.SlaveIDs[j] })
+		devices = append(devices, device)
+	}
+	return devices
+}
+
+// managedName 返回一組儀表在設備管理器中的名稱：只有一台時為其名稱，否則為串口名
+func managedName(group []ProvisionedDevice) string {
+	if len(group) == 1 {
+		return group[0].Name
+	}
+	return filepath.Base(group[0].Config.Endpoint())
+}
+
+// DifferentialChannels 轉換為設備管理器的虛擬壓差通道，需在添加 ManagedDevices 之後添加
+func (df *DevicesFile) DifferentialChannels() []DifferentialChannel {
+	refs := make(map[string]ChannelRef)
+	for _, group := range df.groups() {
+		for _, pd := range group {
+			refs[pd.Name] = ChannelRef{Device: managedName(group), SlaveID: pd.Config.SlaveID}
+		}
+	}
+	var channels []DifferentialChannel
+	for _, diff := range df.Differentials {
+		channels = append(channels, DifferentialChannel{
+			Name:    diff.Name,
+			Plus:    refs[diff.Plus],
+			Minus:   refs[diff.Minus],
+			SlaveID: diff.SlaveID,
+			MaxSkew: diff.MaxSkew,
+		})
+	}
+	return channels
+}
+
+// Locations 返回各儀表的安裝位置對照表
+func (df *DevicesFile) Locations() *LocationMap {
+	lm := NewLocationMap()
+	for _, pd := range df.Devices {
+		lm.Add(pd.Location())
+	}
+	return lm
+}
+
+// Save 將多設備配置檔寫入檔案（.yaml/.yml 或 .json），覆蓋已有檔案前先保存備份
+func (df *DevicesFile) Save(filename string) error {
+	var data []byte
+	var err error
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		data, err = yaml.Marshal(df)
+	case ".json":
+		data, err = json.MarshalIndent(df, "", "  ")
+	default:
+		return fmt.Errorf("不支援的檔案格式，請使用 .yaml 或 .json")
+	}
+	if err != nil {
+		return fmt.Errorf("序列化多設備配置失敗: %v", err)
+	}
+
+	if _, err := BackupConfigFile(filename); err != nil {
+		return err
+	}
+	return writeFileAtomic(filename, data)
+}
+
+// LoadDevicesFile 讀取多設備配置檔並檢查
+func LoadDevicesFile(filename string) (*DevicesFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("讀取多設備配置失敗: %v", err)
+	}
+	var df DevicesFile
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		err = json.Unmarshal(data, &df)
+	default:
+		err = yaml.Unmarshal(data, &df)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析多設備配置 %s 失敗: %v", filename, err)
+	}
+	if err := df.Validate(); err != nil {
+		return nil, fmt.Errorf("多設備配置 %s: %v", filename, err)
+	}
+	return &df, nil
+}
@@ -0,0 +1,202 @@
+// pressure/queue.go - 讀數通道的使用情況：佔用、丟棄和消費端延遲
+package pressure
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OverflowPolicy 讀數通道已滿時的處理方式
+type OverflowPolicy int
+
+const (
+	OverflowDropOldest OverflowPolicy = 0 // 丟棄最舊的讀數，保留最新數據（默認）
+	OverflowDropNewest OverflowPolicy = 1 // 丟棄新讀數，保留已緩衝的數據
+	OverflowBlock      OverflowPolicy = 2 // 暫停讀取直到消費端取走讀數，不丟棄但讀取間隔會被拉長
+)
+
+// String 實現 Stringer 接口
+func (op OverflowPolicy) String() string {
+	switch op {
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowBlock:
+		return "block"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText 實現 encoding.TextMarshaler 接口，用於 JSON/YAML 序列化
+func (op OverflowPolicy) MarshalText() ([]byte, error) {
+	return []byte(op.String()), nil
+}
+
+// UnmarshalText 實現 encoding.TextUnmarshaler 接口，用於 JSON/YAML 反序列化
+func (op *OverflowPolicy) UnmarshalText(text []byte) error {
+	switch strings.ToLower(strings.TrimSpace(string(text))) {
+	case "drop-oldest", "oldest", "":
+		*op = OverflowDropOldest
+	case "drop-newest", "newest":
+		*op = OverflowDropNewest
+	case "block":
+		*op = OverflowBlock
+	default:
+		return fmt.Errorf("未知的溢出策略: %s (可用: drop-oldest, drop-newest, block)", string(text))
+	}
+	return nil
+}
+
+// ChannelStats 讀數通道（GetReadings）的使用情況
+type ChannelStats struct {
+	Size      int            `json:"size"`                // 通道中等待消費的讀數
+	Capacity  int            `json:"capacity"`            // 通道容量
+	Published int64          `json:"published"`           // 放入通道的讀數總數
+	Dropped   int64          `json:"dropped"`             // 通道已滿時按溢出策略丟棄的讀數總數
+	Lag       time.Duration  `json:"lag"`                 // 通道中最舊讀數已等待的時間，即消費端落後的程度
+	Dropping  bool           `json:"dropping"`            // 是否正在丟棄讀數或等待消費端（通道排空到一半以下後恢復）
+	LastDrop  *time.Time     `json:"last_drop,omitempty"` // 最近一次丟棄的時間
+	Policy    OverflowPolicy `json:"policy"`              // 通道已滿時的處理方式
+	Blocked   int64          `json:"blocked,omitempty"`   // block 策略下因通道已滿而等待的次數
+}
+
+// readingQueue 讀數通道的統計，記錄最近放入的讀數時間以計算消費端延遲
+type readingQueue struct {
+	mu         sync.Mutex
+	published  int64
+	dropped    int64
+	blocked    int64
+	enqueuedAt []time.Time // 按放入順序循環記錄，長度等於通道容量
+	dropping   bool
+	lastDrop   time.Time
+	onDrop     func(ChannelStats)
+}
+
+// SetDropHandler 設置開始丟棄讀數時的回調
+//
+// 消費端處理過慢、通道已滿時按溢出策略丟棄最舊或最新的讀數（block 策略不丟棄，不會調用）；每輪丟棄開始時調用一次 handler，
+// 通道排空到一半以下後視為恢復。handler 在讀取協程中調用，不應阻塞。
+func (pm *PressureMeter) SetDropHandler(handler func(ChannelStats)) {
+	pm.queue.mu.Lock()
+	defer pm.queue.mu.Unlock()
+	pm.queue.onDrop = handler
+}
+
+// ChannelStats 返回讀數通道的使用情況
+func (pm *PressureMeter) ChannelStats() ChannelStats {
+	pm.queue.mu.Lock()
+	defer pm.queue.mu.Unlock()
+	return pm.channelStatsLocked(time.Now())
+}
+
+// channelStatsLocked 返回讀數通道的使用情況（調用方需持有 queue.mu）
+//
+// 通道先進先出，消費和丟棄都從最舊的讀數開始，所以通道中的讀數就是最近放入的 len 個。
+func (pm *PressureMeter) channelStatsLocked(now time.Time) ChannelStats {
+	q := &pm.queue
+	stats := ChannelStats{
+		Size:      len(pm.readings),
+		Capacity:  cap(pm.readings),
+		Published: q.published,
+		Dropped:   q.dropped,
+		Dropping:  q.dropping,
+		Policy:    pm.overflow,
+		Blocked:   q.blocked,
+	}
+	if stats.Size > 0 && len(q.enqueuedAt) > 0 {
+		oldest := (q.published - int64(stats.Size)) % int64(len(q.enqueuedAt))
+		stats.Lag = now.Sub(q.enqueuedAt[oldest])
+	}
+	if !q.lastDrop.IsZero() {
+		lastDrop := q.lastDrop
+		stats.LastDrop = &lastDrop
+	}
+	return stats
+}
+
+// publish 將讀數放入通道，通道已滿時按溢出策略處理；block 策略在 stop 關閉時放棄讀數
+func (pm *PressureMeter) publish(reading PressureReading, stop <-chan struct{}) {
+	q := &pm.queue
+	q.mu.Lock()
+	now := time.Now()
+
+	overflowStarted := false
+	enqueued := true
+	select {
+	case pm.readings <- reading:
+		if q.dropping && len(pm.readings) < cap(pm.readings)/2 {
+			q.dropping = false
+			if pm.overflow == OverflowBlock {
+				pm.logger.Printf("讀數通道已恢復，共等待消費端 %d 次", q.blocked)
+			} else {
+				pm.logger.Printf("讀數通道已恢復，共丟棄 %d 個讀數", q.dropped)
+			}
+		}
+	default:
+		if !q.dropping {
+			q.dropping = true
+			overflowStarted = true
+		}
+		switch pm.overflow {
+		case OverflowDropNewest:
+			// 通道已滿，丟棄新讀數
+			q.dropped++
+			q.lastDrop = now
+			enqueued = false
+		case OverflowBlock:
+			// 通道已滿，等待消費端取走讀數
+			q.blocked++
+			if overflowStarted {
+				stats := pm.channelStatsLocked(now)
+				pm.logger.Printf("⚠️  讀數通道已滿 (%d/%d)，暫停讀取等待消費端：消費端落後 %v",
+					stats.Size, stats.Capacity, stats.Lag.Round(time.Millisecond))
+				overflowStarted = false
+			}
+			q.mu.Unlock()
+			select {
+			case pm.readings <- reading:
+			case <-stop:
+				return
+			}
+			q.mu.Lock()
+			now = time.Now()
+		default:
+			// 通道已滿，丟棄最舊的讀數
+			select {
+			case <-pm.readings:
+				q.dropped++
+				q.lastDrop = now
+			default:
+			}
+			pm.readings <- reading
+		}
+	}
+	if enqueued {
+		if len(q.enqueuedAt) == 0 {
+			q.enqueuedAt = make([]time.Time, cap(pm.readings))
+		}
+		q.enqueuedAt[q.published%int64(len(q.enqueuedAt))] = now
+		q.published++
+	}
+	stats := pm.channelStatsLocked(now)
+	handler := q.onDrop
+	q.mu.Unlock()
+
+	pm.fanOut(reading)
+
+	if overflowStarted {
+		which := "舊"
+		if pm.overflow == OverflowDropNewest {
+			which = "新"
+		}
+		pm.logger.Printf("⚠️  讀數通道已滿 (%d/%d)，開始丟棄%s讀數：消費端落後 %v，處理速度跟不上讀取間隔",
+			stats.Size, stats.Capacity, which, stats.Lag.Round(time.Millisecond))
+		if handler != nil {
+			handler(stats)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package pressure
+
+import (
+	"testing"
+	"time"
+)
+
+// withQueue 設置讀數通道容量和溢出策略
+func withQueue(size int, policy OverflowPolicy) func(*Config) {
+	return func(c *Config) {
+		c.BufferSize = size
+		c.OverflowPolicy = policy
+	}
+}
+
+// drainPressures 取出通道中的全部讀數，返回各讀數的壓力值
+func drainPressures(pm *PressureMeter) []float64 {
+	var pressures []float64
+	for {
+		select {
+		case reading := <-pm.readings:
+			pressures = append(pressures, reading.Pressure)
+		default:
+			return pressures
+		}
+	}
+}
+
+func equalPressures(got, want []float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPublishOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		policy      OverflowPolicy
+		want        []float64
+		wantDropped int64
+	}{
+		{policy: OverflowDropOldest, want: []float64{3, 4, 5, 6}, wantDropped: 2},
+		{policy: OverflowDropNewest, want: []float64{1, 2, 3, 4}, wantDropped: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			pm := newTestMeter(t, NewMockTransport(1), withQueue(4, tt.policy))
+			var drops []ChannelStats
+			pm.SetDropHandler(func(stats ChannelStats) { drops = append(drops, stats) })
+
+			stop := make(chan struct{})
+			for i := 1; i <= 6; i++ {
+				pm.publish(PressureReading{Pressure: float64(i), Valid: true}, stop)
+			}
+
+			stats := pm.ChannelStats()
+			if stats.Dropped != tt.wantDropped || !stats.Dropping || stats.LastDrop == nil {
+				t.Errorf("stats = %+v, want %d dropped while dropping", stats, tt.wantDropped)
+			}
+			if len(drops) != 1 {
+				t.Errorf("drop handler called %d times, want once per overflow episode", len(drops))
+			}
+			if got := drainPressures(pm); !equalPressures(got, tt.want) {
+				t.Errorf("channel holds %v, want %v", got, tt.want)
+			}
+
+			// 通道排空到一半以下後恢復
+			pm.publish(PressureReading{Pressure: 7, Valid: true}, stop)
+			if stats := pm.ChannelStats(); stats.Dropping {
+				t.Errorf("still dropping after the channel drained: %+v", stats)
+			}
+		})
+	}
+}
+
+func TestPublishBlockWaitsForConsumer(t *testing.T) {
+	pm := newTestMeter(t, NewMockTransport(1), withQueue(1, OverflowBlock))
+	stop := make(chan struct{})
+	pm.publish(PressureReading{Pressure: 1, Valid: true}, stop)
+
+	published := make(chan struct{})
+	go func() {
+		pm.publish(PressureReading{Pressure: 2, Valid: true}, stop)
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("publish returned while the channel was full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if reading := <-pm.readings; reading.Pressure != 1 {
+		t.Fatalf("first reading = %v, want 1", reading.Pressure)
+	}
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publish still blocked after the consumer took a reading")
+	}
+	if got := drainPressures(pm); !equalPressures(got, []float64{2}) {
+		t.Errorf("second drain = %v, want [2]", got)
+	}
+	if stats := pm.ChannelStats(); stats.Dropped != 0 || stats.Blocked != 1 {
+		t.Errorf("stats = %+v, want 0 dropped and 1 blocked", stats)
+	}
+
+	// 已滿時關閉 stop，讀數被放棄而不是永久阻塞
+	pm.publish(PressureReading{Pressure: 3, Valid: true}, stop)
+	abandoned := make(chan struct{})
+	go func() {
+		pm.publish(PressureReading{Pressure: 4, Valid: true}, stop)
+		close(abandoned)
+	}()
+	close(stop)
+	select {
+	case <-abandoned:
+	case <-time.After(time.Second):
+		t.Fatal("publish still blocked after stop closed")
+	}
+}
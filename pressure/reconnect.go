@@ -0,0 +1,123 @@
+// pressure/reconnect.go - 啟動時打開連接的重試，以及降級模式下的延遲連接
+package pressure
+
+import (
+	"fmt"
+	"time"
+)
+
+// 打開連接的重試退避時間
+const (
+	OpenRetryInitialBackoff = 500 * time.Millisecond // 第一次重試前的等待時間
+	OpenRetryMaxBackoff     = 10 * time.Second       // 重試等待時間上限
+)
+
+// nextBackoff 返回加倍後的退避時間，不超過 OpenRetryMaxBackoff
+func nextBackoff(backoff time.Duration) time.Duration {
+	if backoff <= 0 {
+		return OpenRetryInitialBackoff
+	}
+	backoff *= 2
+	if backoff > OpenRetryMaxBackoff {
+		backoff = OpenRetryMaxBackoff
+	}
+	return backoff
+}
+
+// openWithRetry 打開連接，失敗時按退避時間重試直到 maxWait 用完
+//
+// 用於轉換器在開機後才枚舉出串口等情況，maxWait 為 0 時只嘗試一次。
+func (pm *PressureMeter) openWithRetry(maxWait time.Duration) error {
+	deadline := time.Now().Add(maxWait)
+	backoff := time.Duration(0)
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		err := pm.connect()
+		if err == nil {
+			pm.openTime = time.Since(start)
+			if attempt > 1 {
+				pm.logger.Printf("🔌 第 %d 次嘗試打開 %s 成功", attempt, pm.endpoint)
+			}
+			return nil
+		}
+
+		backoff = nextBackoff(backoff)
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return err
+		}
+		if backoff > remaining {
+			backoff = remaining
+		}
+		pm.logger.Printf("⚠️  打開 %s 失敗 (第 %d 次): %v，%v 後重試", pm.endpoint, attempt, err, backoff.Round(time.Millisecond))
+		time.Sleep(backoff)
+	}
+}
+
+// IsConnected 連接是否已打開，降級模式下未連上設備時返回 false
+func (pm *PressureMeter) IsConnected() bool {
+	pm.connMu.Lock()
+	defer pm.connMu.Unlock()
+	return pm.connected
+}
+
+// ensureConnected 在降級模式下嘗試建立連接，按退避時間限制重試頻率
+func (pm *PressureMeter) ensureConnected() error {
+	pm.connMu.Lock()
+	defer pm.connMu.Unlock()
+	if pm.connected {
+		return nil
+	}
+	if time.Now().Before(pm.nextConnect) {
+		return fmt.Errorf("設備 %s 未連接，%v 後重試", pm.endpoint, time.Until(pm.nextConnect).Round(time.Millisecond))
+	}
+
+	start := time.Now()
+	if err := pm.connect(); err != nil {
+		pm.backoff = nextBackoff(pm.backoff)
+		pm.nextConnect = time.Now().Add(pm.backoff)
+		return fmt.Errorf("設備 %s 未連接: %v", pm.endpoint, err)
+	}
+
+	pm.connected = true
+	pm.openTime = time.Since(start)
+	pm.backoff = 0
+	pm.logger.Printf("🔌 已連接設備 %s，退出降級模式", pm.endpoint)
+
+	// 啟動時未能套用的阻尼時間在連上後補寫
+	if pm.pendingDamping != nil {
+		// 由讀取調用，已持有 busMu
+		if err := pm.setDamping(*pm.pendingDamping); err != nil {
+			pm.logger.Printf("⚠️  套用阻尼時間失敗: %v", err)
+		} else {
+			pm.pendingDamping = nil
+		}
+	}
+	return nil
+}
+
+// Reopen 關閉並重新打開連接，用於恢復卡住的串口或網關連接
+//
+// 重新打開失敗時進入降級模式，之後的讀取按退避時間重試連接。先取得 busMu 等待進行中的請求完成，
+// 與讀取時 readPressure → ensureConnected 的加鎖順序 (busMu → connMu) 相同。
+func (pm *PressureMeter) Reopen() error {
+	pm.busMu.Lock()
+	defer pm.busMu.Unlock()
+	pm.connMu.Lock()
+	defer pm.connMu.Unlock()
+
+	pm.handler.Close()
+	start := time.Now()
+	if err := pm.connect(); err != nil {
+		pm.connected = false
+		pm.backoff = OpenRetryInitialBackoff
+		pm.nextConnect = time.Now().Add(pm.backoff)
+		pm.setStatus(StatusDisconnected, err.Error())
+		return fmt.Errorf("重新打開 %s 失敗: %v", pm.endpoint, err)
+	}
+	pm.connected = true
+	pm.openTime = time.Since(start)
+	pm.backoff = 0
+	pm.logger.Printf("🔌 已重新打開 %s", pm.endpoint)
+	return nil
+}
@@ -0,0 +1,90 @@
+package pressure
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyConnectTransport 前 failures 次打開連接失敗的傳輸層，模擬開機後才枚舉出的 USB 轉換器
+type flakyConnectTransport struct {
+	*MockTransport
+
+	mu       sync.Mutex
+	failures int
+	attempts int
+}
+
+func (ft *flakyConnectTransport) Connect() error {
+	ft.mu.Lock()
+	ft.attempts++
+	fail := ft.failures > 0
+	if fail {
+		ft.failures--
+	}
+	ft.mu.Unlock()
+	if fail {
+		return errors.New("no such file or directory")
+	}
+	return ft.MockTransport.Connect()
+}
+
+func TestOpenRetriesUntilDeviceAppears(t *testing.T) {
+	transport := &flakyConnectTransport{MockTransport: NewMockTransport(1).SetRegisters(0x0034, 0, 123), failures: 1}
+	pm := newTestMeter(t, transport, func(c *Config) { c.OpenRetryWait = 5 * time.Second })
+
+	if transport.attempts != 2 || !pm.IsConnected() {
+		t.Fatalf("attempts=%d connected=%v, want connected on the second attempt", transport.attempts, pm.IsConnected())
+	}
+	if reading := pm.ReadPressure(); !reading.Valid {
+		t.Errorf("reading after retried open: %s", reading.Error)
+	}
+}
+
+func TestOpenFailsWithoutRetryOrDegradedStart(t *testing.T) {
+	transport := &flakyConnectTransport{MockTransport: NewMockTransport(1), failures: 1}
+	if pm, err := NewPressureMeter(testConfig(transport)); err == nil {
+		pm.Close()
+		t.Fatal("NewPressureMeter with a missing device: want error")
+	}
+	if transport.attempts != 1 {
+		t.Errorf("attempts = %d, want 1 without OpenRetryWait", transport.attempts)
+	}
+}
+
+func TestDegradedStartConnectsLater(t *testing.T) {
+	transport := &flakyConnectTransport{MockTransport: NewMockTransport(1).SetRegisters(0x0034, 0, 123), failures: 2}
+	pm := newTestMeter(t, transport, func(c *Config) { c.StartDegraded = true })
+
+	if pm.IsConnected() {
+		t.Fatal("connected although the first open failed")
+	}
+	if reading := pm.ReadPressure(); reading.Valid || reading.ErrorCode != ErrConnection {
+		t.Fatalf("degraded reading: valid=%v code=%v, want ErrConnection", reading.Valid, reading.ErrorCode)
+	}
+
+	// 退避時間內不重試連接
+	if reading := pm.ReadPressure(); reading.Valid || transport.attempts != 1 {
+		t.Fatalf("read within backoff: valid=%v attempts=%d, want no new attempt", reading.Valid, transport.attempts)
+	}
+
+	// 到達重試時間後再次失敗，等待時間加倍；之後設備出現時連上
+	expire := func() {
+		pm.connMu.Lock()
+		pm.nextConnect = time.Now()
+		pm.connMu.Unlock()
+	}
+	expire()
+	pm.ReadPressure()
+	if pm.backoff != 2*OpenRetryInitialBackoff {
+		t.Errorf("backoff = %v, want %v after a failed retry", pm.backoff, 2*OpenRetryInitialBackoff)
+	}
+	expire()
+	if reading := pm.ReadPressure(); !reading.Valid || !pm.IsConnected() {
+		t.Errorf("reading after the device appeared: valid=%v connected=%v (%s)", reading.Valid, pm.IsConnected(), reading.Error)
+	}
+	if transport.attempts != 3 {
+		t.Errorf("attempts = %d, want 3", transport.attempts)
+	}
+}
@@ -0,0 +1,223 @@
+// pressure/recovery.go - 持續通信故障的逐級恢復：重新打開連接、重置轉換器、切換備用設備、標記故障
+package pressure
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultRecoveryInterval 兩個恢復步驟之間的最短間隔
+const DefaultRecoveryInterval = time.Minute
+
+// RecoveryStep 恢復階梯中的一個步驟
+type RecoveryStep int
+
+const (
+	RecoveryReopen   RecoveryStep = 0 // 關閉並重新打開串口或網關連接
+	RecoveryReset    RecoveryStep = 1 // 執行重置命令（如 usbreset）重啟轉換器，再重新打開連接
+	RecoveryFailover RecoveryStep = 2 // 切換到備用設備
+	RecoveryFail     RecoveryStep = 3 // 標記設備故障並通知，之後繼續讀取但不再執行恢復步驟
+)
+
+// DefaultRecoveryLadder 默認的恢復階梯，未配置重置命令或備用設備時跳過對應步驟
+var DefaultRecoveryLadder = []RecoveryStep{RecoveryReopen, RecoveryReset, RecoveryFailover, RecoveryFail}
+
+// String 實現 Stringer 接口
+func (rs RecoveryStep) String() string {
+	switch rs {
+	case RecoveryReopen:
+		return "reopen"
+	case RecoveryReset:
+		return "reset"
+	case RecoveryFailover:
+		return "failover"
+	case RecoveryFail:
+		return "fail"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText 實現 encoding.TextMarshaler 接口
+func (rs RecoveryStep) MarshalText() ([]byte, error) {
+	return []byte(rs.String()), nil
+}
+
+// UnmarshalText 實現 encoding.TextUnmarshaler 接口
+func (rs *RecoveryStep) UnmarshalText(text []byte) error {
+	switch strings.ToLower(strings.TrimSpace(string(text))) {
+	case "reopen":
+		*rs = RecoveryReopen
+	case "reset", "usbreset":
+		*rs = RecoveryReset
+	case "failover", "backup":
+		*rs = RecoveryFailover
+	case "fail", "failed":
+		*rs = RecoveryFail
+	default:
+		return fmt.Errorf("未知的恢復步驟: %s (可用: reopen, reset, failover, fail)", string(text))
+	}
+	return nil
+}
+
+// ParseRecoveryLadder 解析逗號分隔的恢復步驟，如 "reopen,reset,fail"
+func ParseRecoveryLadder(s string) ([]RecoveryStep, error) {
+	var ladder []RecoveryStep
+	for _, part := range strings.Split(s, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		var step RecoveryStep
+		if err := step.UnmarshalText([]byte(part)); err != nil {
+			return nil, err
+		}
+		ladder = append(ladder, step)
+	}
+	return ladder, nil
+}
+
+// recoveryState 恢復階梯的進度
+type recoveryState struct {
+	next     int       // 下一個要執行的步驟
+	lastStep time.Time // 上一個步驟的執行時間
+	deferred bool      // 是否已記錄本輪的限速日誌
+}
+
+// recoveryLadder 返回要執行的恢復步驟
+//
+// 未配置 Ladder 時使用 DefaultRecoveryLadder，並跳過未配置重置命令或備用設備的步驟。
+func (fp FailurePolicy) recoveryLadder() []RecoveryStep {
+	if len(fp.Ladder) > 0 {
+		return fp.Ladder
+	}
+	var ladder []RecoveryStep
+	for _, step := range DefaultRecoveryLadder {
+		if step == RecoveryReset && fp.resetCommand() == "" {
+			continue
+		}
+		if step == RecoveryFailover && fp.BackupDevice == "" {
+			continue
+		}
+		ladder = append(ladder, step)
+	}
+	return ladder
+}
+
+// resetCommand 返回重置轉換器的命令，未配置時使用失敗處理腳本
+func (fp FailurePolicy) resetCommand() string {
+	if strings.TrimSpace(fp.ResetCommand) != "" {
+		return fp.ResetCommand
+	}
+	return strings.TrimSpace(fp.Hook)
+}
+
+// recoveryInterval 返回兩個恢復步驟之間的最短間隔
+func (fp FailurePolicy) recoveryInterval() time.Duration {
+	if fp.RecoveryInterval > 0 {
+		return fp.RecoveryInterval
+	}
+	return DefaultRecoveryInterval
+}
+
+// recover 執行恢復階梯的下一個步驟，距上一個步驟不足最短間隔時延後執行
+func (m *Monitor) recover(reading PressureReading, failures int) {
+	m.mu.Lock()
+	policy := m.failurePolicy
+	ladder := policy.recoveryLadder()
+	state := &m.recovery
+	if state.next >= len(ladder) {
+		m.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	if !state.lastStep.IsZero() {
+		if wait := state.lastStep.Add(policy.recoveryInterval()).Sub(now); wait > 0 {
+			logDeferred := !state.deferred
+			state.deferred = true
+			step := ladder[state.next]
+			m.mu.Unlock()
+			if logDeferred {
+				m.logger.Printf("⏳ 恢復步驟限速：%v 後執行下一步 (%s)", wait.Round(time.Millisecond), step)
+			}
+			return
+		}
+	}
+	step := ladder[state.next]
+	state.next++
+	state.lastStep = now
+	state.deferred = false
+	m.stats.RecoverySteps++
+	m.stats.LastRecoveryStep = step.String()
+	meter := m.meter
+	device := m.config.Endpoint()
+	index := state.next
+	m.mu.Unlock()
+
+	m.logger.Printf("🛠️  恢復步驟 %d/%d: %s (連續失敗 %d 次，最後錯誤: %s)", index, len(ladder), step, failures, reading.Error)
+
+	var err error
+	switch step {
+	case RecoveryReopen:
+		err = meter.Reopen()
+	case RecoveryReset:
+		env := map[string]string{
+			"EVENT":      "reset_adapter",
+			"DEVICE":     device,
+			"SLAVE_ID":   fmt.Sprintf("%d", reading.SlaveID),
+			"LAST_ERROR": reading.Error,
+		}
+		// 同步執行，轉換器重新枚舉後才重新打開連接
+		if err = runHook(policy.resetCommand(), env, DefaultHookTimeout); err == nil {
+			err = meter.Reopen()
+		}
+	case RecoveryFailover:
+		err = m.failover()
+	case RecoveryFail:
+		m.markFailed(reading, failures)
+	}
+	if err != nil {
+		m.logger.Printf("❌ 恢復步驟 %s 失敗: %v", step, err)
+	}
+}
+
+// markFailed 將設備標記為故障，發出 EventDeviceFailed 事件並執行失敗處理腳本
+func (m *Monitor) markFailed(reading PressureReading, failures int) {
+	m.mu.Lock()
+	m.stats.DeviceFailed = true
+	bus := m.bus
+	device := m.config.Endpoint()
+	hook := strings.TrimSpace(m.failurePolicy.Hook)
+	m.mu.Unlock()
+
+	message := fmt.Sprintf("恢復步驟已用完，設備 %s 仍無響應 (最後錯誤: %s)", device, reading.Error)
+	m.logger.Printf("🚨 %s，已標記為故障", message)
+	bus.Publish(Event{Type: EventDeviceFailed, Source: device, SlaveID: reading.SlaveID, Message: message})
+
+	if hook != "" {
+		env := map[string]string{
+			"EVENT":      "device_failed",
+			"FAILURES":   fmt.Sprintf("%d", failures),
+			"DEVICE":     device,
+			"SLAVE_ID":   fmt.Sprintf("%d", reading.SlaveID),
+			"LAST_ERROR": reading.Error,
+		}
+		go func() {
+			if err := runHook(hook, env, DefaultHookTimeout); err != nil {
+				m.logger.Printf("❌ 故障通知腳本出錯: %v", err)
+			}
+		}()
+	}
+}
+
+// resetRecovery 收到有效讀數後重置恢復階梯，並清除故障標記（調用方需持有鎖）
+func (m *Monitor) resetRecovery() string {
+	if m.recovery.next == 0 && !m.stats.DeviceFailed {
+		return ""
+	}
+	message := fmt.Sprintf("✅ 設備 %s 已恢復響應 (執行了 %d 個恢復步驟，最後為 %s)", m.config.Endpoint(), m.recovery.next, m.stats.LastRecoveryStep)
+	// 保留上一步的時間，設備反覆斷線時仍按最短間隔限速
+	m.recovery = recoveryState{lastStep: m.recovery.lastStep}
+	m.stats.DeviceFailed = false
+	return message
+}
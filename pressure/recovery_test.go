@@ -0,0 +1,93 @@
+package pressure
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowTransport 打開連接和響應請求都需要一段時間的傳輸層，請求期間連接被關閉時請求失敗
+type slowTransport struct {
+	*MockTransport
+}
+
+func (st slowTransport) Connect() error {
+	time.Sleep(time.Millisecond)
+	return st.MockTransport.Connect()
+}
+
+func (st slowTransport) Send(request []byte) ([]byte, error) {
+	time.Sleep(time.Millisecond)
+	return st.MockTransport.Send(request)
+}
+
+func TestReopenWaitsForBusTransaction(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0, 123)
+	pm := newTestMeter(t, slowTransport{mock})
+
+	// 請求進行中重新打開連接會使請求失敗
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := pm.Reopen(); err != nil {
+				t.Errorf("Reopen: %v", err)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		if reading := pm.ReadPressure(); !reading.Valid {
+			t.Fatalf("read %d during Reopen: %s", i, reading.Error)
+		}
+	}
+	wg.Wait()
+}
+
+func TestRecoveryLadderRateLimited(t *testing.T) {
+	mock := NewMockTransport(1).SetRegisters(0x0034, 0, 123)
+	m := newTestMonitor(t, mock)
+	err := m.SetFailurePolicy(FailurePolicy{
+		MaxConsecutive:   1,
+		Action:           FailureRecover,
+		Ladder:           []RecoveryStep{RecoveryReopen, RecoveryFail},
+		RecoveryInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fail := func() {
+		mock.SetFailures(1, nil)
+		m.checkFailures(m.meter.ReadPressure())
+	}
+
+	fail()
+	if stats := m.Stats(); stats.RecoverySteps != 1 || stats.LastRecoveryStep != "reopen" {
+		t.Fatalf("after first failure: steps=%d last=%q, want 1 reopen", stats.RecoverySteps, stats.LastRecoveryStep)
+	}
+
+	// 未到最短間隔不執行下一步
+	fail()
+	if stats := m.Stats(); stats.RecoverySteps != 1 || stats.DeviceFailed {
+		t.Fatalf("within recovery interval: steps=%d failed=%v, want 1 and not failed", stats.RecoverySteps, stats.DeviceFailed)
+	}
+
+	m.mu.Lock()
+	m.recovery.lastStep = time.Now().Add(-2 * time.Hour)
+	m.mu.Unlock()
+	fail()
+	if stats := m.Stats(); stats.RecoverySteps != 2 || !stats.DeviceFailed {
+		t.Fatalf("after interval: steps=%d failed=%v, want 2 and failed", stats.RecoverySteps, stats.DeviceFailed)
+	}
+
+	// 有效讀數清除故障標記並從第一步重新開始
+	m.checkFailures(m.meter.ReadPressure())
+	if stats := m.Stats(); stats.DeviceFailed {
+		t.Error("DeviceFailed still set after a valid reading")
+	}
+	if m.recovery.next != 0 {
+		t.Errorf("recovery restarts at step %d, want 0", m.recovery.next)
+	}
+}
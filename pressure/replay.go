@@ -0,0 +1,345 @@
+// pressure/replay.go - 原始數據回放：將記錄的原始寄存器數據重新經過解析流程，離線分析和重現數據格式判斷問題
+package pressure
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// replayTolerance 回放解析結果與記錄的壓力值相差超過此值 (Pa) 視為不一致
+const replayTolerance = 0.005
+
+// ErrReplayFinished 記錄中的原始數據已全部回放
+var ErrReplayFinished = errors.New("原始數據已全部回放")
+
+// RecordedFrame 記錄中的一組原始寄存器數據
+type RecordedFrame struct {
+	Time     time.Time `json:"time"`               // 記錄時間
+	SlaveID  byte      `json:"slave_id"`           // 記錄時的站點號
+	Data     []byte    `json:"data"`               // 壓力寄存器的原始數據
+	Pressure *float64  `json:"pressure,omitempty"` // 記錄時解析的壓力值 (Pa)，未解析時為空
+	Valid    bool      `json:"valid"`              // 記錄時讀數是否有效
+	Source   string    `json:"source"`             // 在記錄檔案中的位置
+}
+
+// recordedRawReading JSON 輸出和合規日誌中回放需要的字段
+type recordedRawReading struct {
+	Timestamp   time.Time `json:"timestamp"`
+	SlaveID     byte      `json:"slave_id"`
+	Pressure    *float64  `json:"pressure"`
+	RawPressure *float64  `json:"raw_pressure"` // 溫度補償前的壓力值，回放不做溫度補償
+	Valid       bool      `json:"valid"`
+	RawData     string    `json:"raw_data"`
+}
+
+// LoadRecordedFrames 讀取記錄中的原始寄存器數據：--output=json 的輸出、合規日誌或掃描結果 (scan_results_*.json)
+//
+// JSON 行只取帶 raw_data 的記錄（記錄時需啟用 --raw-data 或 --verbose）；掃描結果只取探測壓力寄存器且有原始數據的設備。
+func LoadRecordedFrames(r io.Reader) ([]RecordedFrame, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// 整個檔案是帶 devices 的 JSON 對象時為掃描結果
+	var probe struct {
+		Devices json.RawMessage `json:"devices"`
+	}
+	if trimmed := bytes.TrimSpace(data); json.Unmarshal(trimmed, &probe) == nil && probe.Devices != nil {
+		var scan ScanResult
+		if err := json.Unmarshal(trimmed, &scan); err != nil {
+			return nil, fmt.Errorf("解析掃描結果失敗: %v", err)
+		}
+		return scanResultFrames(&scan)
+	}
+
+	// JSON 行：跳過不是 JSON 對象的行（如重定向輸出時混入的提示信息）
+	var frames []RecordedFrame
+	for n, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("{")) {
+			continue
+		}
+		var entry struct {
+			recordedRawReading
+			Record *recordedRawReading `json:"record"` // 合規日誌
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("第 %d 行不是有效的 JSON: %v", n+1, err)
+		}
+		reading := entry.recordedRawReading
+		if entry.Record != nil {
+			reading = *entry.Record
+		}
+		if reading.RawData == "" {
+			continue
+		}
+		raw, err := parseRawData(reading.RawData)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行的原始數據無效: %v", n+1, err)
+		}
+		pressure := reading.Pressure
+		if reading.RawPressure != nil {
+			pressure = reading.RawPressure
+		}
+		frames = append(frames, RecordedFrame{
+			Time:     reading.Timestamp,
+			SlaveID:  reading.SlaveID,
+			Data:     raw,
+			Pressure: pressure,
+			Valid:    reading.Valid,
+			Source:   fmt.Sprintf("第 %d 行", n+1),
+		})
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("沒有原始寄存器數據 (記錄時需啟用 --raw-data 或 --verbose)")
+	}
+	return frames, nil
+}
+
+// scanResultFrames 返回掃描結果中響應設備的原始數據
+func scanResultFrames(result *ScanResult) ([]RecordedFrame, error) {
+	if !result.Config.isPressureProbe() {
+		function, register, count := result.Config.probeParams()
+		return nil, fmt.Errorf("掃描探測的不是壓力寄存器 (0x%02X@0x%04X x%d)，沒有可回放的壓力數據", function, register, count)
+	}
+	var frames []RecordedFrame
+	for i, device := range result.Devices {
+		rawData, _ := device.Properties["raw_data"].(string)
+		if !device.Responsive || rawData == "" {
+			continue
+		}
+		raw, err := parseRawData(rawData)
+		if err != nil {
+			return nil, fmt.Errorf("設備 %s 站點 %d 的原始數據無效: %v", device.Device, device.SlaveID, err)
+		}
+		frame := RecordedFrame{
+			Time:    device.ScanTime,
+			SlaveID: device.SlaveID,
+			Data:    raw,
+			Source:  fmt.Sprintf("設備 #%d %s (%s)", i+1, device.Device, device.DataFormat),
+		}
+		if pressure, ok := device.Properties["pressure_pa"].(float64); ok {
+			frame.Pressure, frame.Valid = &pressure, true
+		}
+		frames = append(frames, frame)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("掃描結果中沒有帶原始數據的響應設備")
+	}
+	return frames, nil
+}
+
+// LoadRecordedFramesFile 打開並讀取記錄檔案中的原始寄存器數據
+func LoadRecordedFramesFile(filename string) ([]RecordedFrame, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	frames, err := LoadRecordedFrames(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return frames, nil
+}
+
+// parseRawData 解析十六進制的原始數據，如 "00 00 04 D2"
+func parseRawData(s string) ([]byte, error) {
+	data, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || len(data)%2 != 0 {
+		return nil, fmt.Errorf("長度 %d 字節不是完整的寄存器", len(data))
+	}
+	return data, nil
+}
+
+// ReplayTransport 按記錄的原始寄存器數據響應讀取的回放傳輸
+//
+// 設置到 Config.ModbusTransport 後，每次從設備配置檔的壓力寄存器讀取時按順序寫入下一組記錄的數據，
+// 經過與實際儀表相同的解析流程。speed 為 1 時按記錄的時間間隔回放，大於 1 時按倍數加速，0 為不等待；
+// 全部回放後讀取返回 ErrReplayFinished。記錄中各站點的數據都按順序回放，響應的站點號為配置的站點號。
+type ReplayTransport struct {
+	*MockTransport
+	frames   []RecordedFrame
+	register uint16 // 壓力寄存器起始地址
+	count    uint16 // 壓力寄存器數量
+	speed    float64
+
+	mu    sync.Mutex
+	next  int
+	start time.Time // 第一組數據的回放時間
+}
+
+// NewReplayTransport 按儀表配置（站點號、設備配置檔）創建回放傳輸
+func NewReplayTransport(config Config, frames []RecordedFrame, speed float64) (*ReplayTransport, error) {
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("沒有可回放的原始數據")
+	}
+	if speed < 0 || math.IsNaN(speed) || math.IsInf(speed, 0) {
+		return nil, fmt.Errorf("無效的回放速度: %v", speed)
+	}
+	profile, err := ResolveDeviceProfile(config)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayTransport{
+		MockTransport: NewMockTransport(config.SlaveID),
+		frames:        frames,
+		register:      profile.Register,
+		count:         profile.Count,
+		speed:         speed,
+	}, nil
+}
+
+// Frames 返回全部回放數據
+func (rt *ReplayTransport) Frames() []RecordedFrame {
+	return rt.frames
+}
+
+// Replayed 返回已回放的數據組數
+func (rt *ReplayTransport) Replayed() int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.next
+}
+
+// Send 實現 modbus.Transporter 接口，讀取壓力寄存器時先按時間寫入下一組記錄的數據
+func (rt *ReplayTransport) Send(request []byte) ([]byte, error) {
+	if pdu, err := rt.packager.Decode(request); err == nil && len(pdu.Data) >= 2 &&
+		(pdu.FunctionCode == ModbusFunctionReadHoldingRegisters || pdu.FunctionCode == ModbusFunctionReadInputRegisters) &&
+		binary.BigEndian.Uint16(pdu.Data) == rt.register {
+		if err := rt.advance(); err != nil {
+			return nil, err
+		}
+	}
+	return rt.MockTransport.Send(request)
+}
+
+// advance 等到下一組數據的回放時間後將其寫入壓力寄存器
+//
+// 數據不足配置檔的寄存器數量時，缺少的寄存器保持未設置，讀取返回非法地址異常。
+func (rt *ReplayTransport) advance() error {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if rt.next >= len(rt.frames) {
+		return ErrReplayFinished
+	}
+	frame := rt.frames[rt.next]
+	if rt.next == 0 {
+		rt.start = time.Now()
+	} else if rt.speed > 0 && !frame.Time.IsZero() && !rt.frames[0].Time.IsZero() {
+		due := rt.start.Add(time.Duration(float64(frame.Time.Sub(rt.frames[0].Time)) / rt.speed))
+		if wait := time.Until(due); wait > 0 {
+			time.Sleep(wait)
+		}
+	}
+	rt.next++
+
+	values := make([]uint16, len(frame.Data)/2)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint16(frame.Data[2*i:])
+	}
+	rt.MockTransport.mu.Lock()
+	defer rt.MockTransport.mu.Unlock()
+	for i := uint16(0); i < rt.count; i++ {
+		delete(rt.registers, rt.register+i)
+	}
+	rt.setRegisters(rt.register, values)
+	return nil
+}
+
+// ReplayResult 一組記錄數據的回放結果
+type ReplayResult struct {
+	Frame   RecordedFrame   `json:"frame"`   // 記錄的數據
+	Reading PressureReading `json:"reading"` // 按當前配置解析的讀數
+}
+
+// Changed 解析結果是否與記錄時不同（有效性不同或壓力值相差超過 0.005 Pa），記錄中沒有壓力值時為 false
+func (rr ReplayResult) Changed() bool {
+	if rr.Frame.Pressure == nil {
+		return false
+	}
+	if rr.Frame.Valid != rr.Reading.Valid {
+		return true
+	}
+	return rr.Reading.Valid && math.Abs(rr.Reading.Pressure-*rr.Frame.Pressure) > replayTolerance
+}
+
+// ReplaySummary 回放結果統計
+type ReplaySummary struct {
+	Frames  int               `json:"frames"`  // 回放的數據組數
+	Valid   int               `json:"valid"`   // 解析為有效讀數的組數
+	Changed int               `json:"changed"` // 與記錄時結果不同的組數
+	Errors  map[ErrorCode]int `json:"errors"`  // 按錯誤代碼統計的無效讀數
+}
+
+// Add 將一組回放結果計入統計
+func (rs *ReplaySummary) Add(result ReplayResult) {
+	rs.Frames++
+	if result.Reading.Valid {
+		rs.Valid++
+	} else {
+		if rs.Errors == nil {
+			rs.Errors = make(map[ErrorCode]int)
+		}
+		rs.Errors[result.Reading.ErrorCode]++
+	}
+	if result.Changed() {
+		rs.Changed++
+	}
+}
+
+// Print 將統計寫入 w
+func (rs ReplaySummary) Print(w io.Writer) {
+	fmt.Fprintf(w, "回放 %d 組原始數據: 有效 %d，無效 %d，與記錄不一致 %d\n",
+		rs.Frames, rs.Valid, rs.Frames-rs.Valid, rs.Changed)
+	codes := make([]ErrorCode, 0, len(rs.Errors))
+	for code := range rs.Errors {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	for _, code := range codes {
+		fmt.Fprintf(w, "   %s (%s): %d\n", code.Description(), code, rs.Errors[code])
+	}
+}
+
+// ReplayRecording 用 config 的設備配置檔和量程等設置重新解析回放傳輸中的全部數據，每組結果調用 fn
+//
+// config.ModbusTransport 必須為 *ReplayTransport。記錄的原始數據只包含壓力寄存器，
+// 回放時不讀取溫度、不做溫度補償，也不重試。
+func ReplayRecording(config Config, fn func(ReplayResult)) (ReplaySummary, error) {
+	transport, ok := config.ModbusTransport.(*ReplayTransport)
+	if !ok {
+		return ReplaySummary{}, fmt.Errorf("回放需要設置 ReplayTransport")
+	}
+	config.TemperatureRegister = 0
+	config.MaxRetries = 0
+	pm, err := NewPressureMeter(config)
+	if err != nil {
+		return ReplaySummary{}, err
+	}
+	defer pm.Close()
+
+	var summary ReplaySummary
+	for _, frame := range transport.Frames() {
+		result := ReplayResult{Frame: frame, Reading: pm.ReadPressure()}
+		summary.Add(result)
+		if fn != nil {
+			fn(result)
+		}
+	}
+	return summary, nil
+}
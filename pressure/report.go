@@ -0,0 +1,434 @@
+// pressure/report.go - 掃描報告和監測趨勢報告生成
+package pressure
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// PortSummary 單個串口的掃描摘要
+type PortSummary struct {
+	Port       string `json:"port"`       // 串口
+	Tested     int    `json:"tested"`     // 測試的配置數
+	Responsive int    `json:"responsive"` // 響應設備數
+	BaudRates  []int  `json:"baud_rates"` // 發現設備的波特率
+}
+
+// SummarizePorts 按串口匯總掃描結果
+//
+// 測試的配置數取自掃描記錄的各串口探測數，包括 SkipUnresponsive 時未列入設備列表的無響應探測，
+// 所以沒有發現設備的串口也會列出；舊版本保存的結果沒有探測數，按設備列表計數。
+func SummarizePorts(result *ScanResult) []PortSummary {
+	summaries := make(map[string]*PortSummary)
+	var order []string
+	summaryFor := func(port string) *PortSummary {
+		summary, ok := summaries[port]
+		if !ok {
+			summary = &PortSummary{Port: port}
+			summaries[port] = summary
+			order = append(order, port)
+		}
+		return summary
+	}
+
+	for _, device := range result.Devices {
+		summary := summaryFor(device.Device)
+		if result.PortTested == nil {
+			summary.Tested++
+		}
+		if !device.Responsive {
+			continue
+		}
+		summary.Responsive++
+
+		if baudRate, ok := deviceBaudRate(device); ok && !containsInt(summary.BaudRates, baudRate) {
+			summary.BaudRates = append(summary.BaudRates, baudRate)
+			sort.Ints(summary.BaudRates)
+		}
+	}
+
+	ports := make([]string, 0, len(result.PortTested))
+	for port := range result.PortTested {
+		ports = append(ports, port)
+	}
+	sort.Strings(ports)
+	for _, port := range ports {
+		summaryFor(port).Tested = result.PortTested[port]
+	}
+
+	list := make([]PortSummary, 0, len(order))
+	for _, port := range order {
+		list = append(list, *summaries[port])
+	}
+	return list
+}
+
+// reportDevice 報告中的單行設備數據
+type reportDevice struct {
+	Index        int
+	Port         string
+	SlaveID      byte
+	Location     string
+	Model        string
+	Foreign      bool // 設備標識顯示不是內建配置檔支援的儀表
+	Responsive   bool
+	Marginal     bool // 第一次探測超時、重試後才響應
+	BaudRate     string
+	Format       string
+	Confidence   string
+	Pressure     string
+	RawData      string
+	ResponseTime string
+	Error        string
+}
+
+// reportData HTML 模板數據
+type reportData struct {
+	Title       string
+	GeneratedAt string
+	Library     string
+	Result      *ScanResult
+	Ports       []PortSummary
+	Devices     []reportDevice
+}
+
+// WriteScanReportHTML 將掃描結果渲染為獨立的 HTML 報告
+func WriteScanReportHTML(w io.Writer, result *ScanResult) error {
+	data := reportData{
+		Title:       "壓差儀掃描報告",
+		GeneratedAt: time.Now().Format("2006-01-02 15:04:05"),
+		Library:     fmt.Sprintf("%s v%s", LibraryName, LibraryVersion),
+		Result:      result,
+		Ports:       SummarizePorts(result),
+	}
+
+	for i, device := range result.Devices {
+		row := reportDevice{
+			Index:      i + 1,
+			Port:       device.Device,
+			SlaveID:    device.SlaveID,
+			Responsive: device.Responsive,
+			Marginal:   device.Marginal,
+			Format:     formatToString(device.DataFormat),
+			Error:      device.Error,
+		}
+		if device.Location != nil {
+			row.Location = device.Location.String()
+		}
+		if device.Model != nil {
+			row.Model = device.Model.String()
+			row.Foreign = DeviceIsForeign(device)
+		}
+		if baudRate, ok := device.Properties["baud_rate"]; ok {
+			row.BaudRate = fmt.Sprintf("%v", baudRate)
+		}
+		if confidence, ok := device.Properties["format_confidence"].(float64); ok {
+			row.Confidence = fmt.Sprintf("%.2f", confidence)
+		}
+		if device.LastReading != nil {
+			row.Pressure = fmt.Sprintf("%.2f Pa", device.LastReading.Pressure)
+		}
+		if rawData, ok := device.Properties["raw_data"]; ok {
+			row.RawData = fmt.Sprintf("%v", rawData)
+		}
+		if responseTime, ok := device.Properties["response_time"]; ok {
+			row.ResponseTime = fmt.Sprintf("%v", responseTime)
+		}
+		data.Devices = append(data.Devices, row)
+	}
+
+	return scanReportTemplate.Execute(w, data)
+}
+
+// SaveScanReportHTML 將 HTML 掃描報告寫入檔案
+func SaveScanReportHTML(result *ScanResult, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("創建報告檔案失敗: %v", err)
+	}
+	defer file.Close()
+
+	if err := WriteScanReportHTML(file, result); err != nil {
+		return fmt.Errorf("生成報告失敗: %v", err)
+	}
+	return nil
+}
+
+// deviceBaudRate 從設備屬性中取出波特率（兼容 JSON 反序列化後的 float64）
+func deviceBaudRate(device DeviceInfo) (int, bool) {
+	switch v := device.Properties["baud_rate"].(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+// containsInt 檢查切片中是否包含指定整數
+func containsInt(values []int, target int) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+var scanReportTemplate = template.Must(template.New("scan_report").Parse(`<!DOCTYPE html>
+<html lang="zh-Hant">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "Noto Sans TC", sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.6em; margin-bottom: 0.2em; }
+h2 { font-size: 1.2em; margin-top: 1.6em; border-bottom: 1px solid #ccc; padding-bottom: 0.2em; }
+table { border-collapse: collapse; width: 100%; margin-top: 0.6em; font-size: 0.9em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; }
+tr.ok td.status { color: #1a7f37; font-weight: bold; }
+tr.fail td.status { color: #b42318; }
+.meta { color: #666; font-size: 0.9em; }
+code { font-family: Menlo, Consolas, monospace; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">生成時間: {{.GeneratedAt}} · {{.Library}}</p>
+
+<h2>摘要</h2>
+<table>
+<tr><th>掃描耗時</th><td>{{.Result.ScanTime}}</td></tr>
+<tr><th>測試配置數</th><td>{{.Result.TotalTested}}</td></tr>
+<tr><th>響應設備數</th><td>{{.Result.Successful}}</td></tr>
+<tr><th>探測超時</th><td>{{.Result.Config.ScanTimeout}}</td></tr>
+<tr><th>波特率</th><td>{{range $i, $b := .Result.Config.BaudRates}}{{if $i}}, {{end}}{{$b}}{{end}}</td></tr>
+</table>
+
+<h2>串口摘要</h2>
+<table>
+<tr><th>串口</th><th>測試數</th><th>響應數</th><th>波特率</th></tr>
+{{range .Ports}}<tr><td><code>{{.Port}}</code></td><td>{{.Tested}}</td><td>{{.Responsive}}</td><td>{{range $i, $b := .BaudRates}}{{if $i}}, {{end}}{{$b}}{{end}}</td></tr>
+{{else}}<tr><td colspan="4">無數據</td></tr>
+{{end}}</table>
+
+<h2>設備明細</h2>
+<table>
+<tr><th>#</th><th>串口</th><th>站點號</th><th>位置</th><th>型號</th><th>狀態</th><th>波特率</th><th>數據格式</th><th>置信度</th><th>壓力</th><th>原始數據</th><th>響應時間</th><th>錯誤</th></tr>
+{{range .Devices}}<tr class="{{if .Responsive}}ok{{else}}fail{{end}}"><td>{{.Index}}</td><td><code>{{.Port}}</code></td><td>{{.SlaveID}}</td><td>{{.Location}}</td><td>{{.Model}}{{if .Foreign}} ⚠️ 非普時達儀表{{end}}</td><td class="status">{{if .Responsive}}響應{{if .Marginal}} ⚠️ 重試後{{end}}{{else}}無響應{{end}}</td><td>{{.BaudRate}}</td><td>{{if .Responsive}}{{.Format}}{{end}}</td><td>{{.Confidence}}</td><td>{{.Pressure}}</td><td><code>{{.RawData}}</code></td><td>{{.ResponseTime}}</td><td>{{.Error}}</td></tr>
+{{else}}<tr><td colspan="13">未發現任何設備</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// trendReportData 趨勢報告模板數據
+type trendReportData struct {
+	Title       string
+	GeneratedAt string
+	Library     string
+	Start       string
+	End         string
+	Readings    int
+	Errors      int
+	Quantity    string
+	Unit        string
+	Decimals    int
+	Pressure    Statistics
+	Alarms      []reportAlarm
+	Setpoint    *reportSetpoint
+	Chart       template.HTML
+}
+
+// reportSetpoint 報告中的設定值統計，數值已按單位預設格式化
+type reportSetpoint struct {
+	Setpoint         string
+	InSpecPercent    float64
+	OutOfSpec        string
+	MeanDeviation    string
+	MeanAbsDeviation string
+	MaxAbsDeviation  string
+	Days             []SetpointDay
+}
+
+// TrendReportOptions 趨勢報告選項
+type TrendReportOptions struct {
+	Preset   UnitPreset      // 單位預設，零值為通用預設
+	Setpoint *SetpointConfig // 設定值，設置後報告包含偏差、達標時間比例和每日超標時長
+	Interval time.Duration   // 讀取間隔，用於判斷數據缺失，0 為按讀數間隔的中位數估算
+}
+
+// reportAlarm 報告中的告警規則
+type reportAlarm struct {
+	Name string
+	Low  string
+	High string
+}
+
+// WriteTrendReportHTML 將監測讀數渲染為帶趨勢圖的獨立 HTML 報告
+//
+// 趨勢圖以內嵌 SVG 呈現，報告可直接附在郵件中，或在瀏覽器中列印為 PDF。
+func WriteTrendReportHTML(w io.Writer, readings []PressureReading, alarms []AlarmRule) error {
+	return WriteTrendReportHTMLWithPreset(w, readings, alarms, DefaultUnitPreset)
+}
+
+// WriteTrendReportHTMLWithPreset 按單位預設的標題、單位和小數位數渲染 HTML 趨勢報告
+func WriteTrendReportHTMLWithPreset(w io.Writer, readings []PressureReading, alarms []AlarmRule, preset UnitPreset) error {
+	return WriteTrendReportHTMLWithOptions(w, readings, alarms, TrendReportOptions{Preset: preset})
+}
+
+// WriteTrendReportHTMLWithOptions 按報告選項渲染 HTML 趨勢報告
+func WriteTrendReportHTMLWithOptions(w io.Writer, readings []PressureReading, alarms []AlarmRule, opts TrendReportOptions) error {
+	preset := opts.Preset
+	if preset.Name == "" {
+		preset = DefaultUnitPreset
+	}
+	data := trendReportData{
+		Title:       preset.ReportTitle,
+		GeneratedAt: time.Now().Format("2006-01-02 15:04:05"),
+		Library:     fmt.Sprintf("%s v%s", LibraryName, LibraryVersion),
+		Readings:    len(readings),
+		Quantity:    preset.Quantity,
+		Unit:        preset.Unit.Symbol(),
+		Decimals:    preset.Decimals,
+	}
+	var lines []ChartLine
+	for _, rule := range alarms {
+		row := reportAlarm{Name: rule.Name}
+		if rule.Low != nil {
+			row.Low = preset.Format(*rule.Low)
+			lines = append(lines, ChartLine{Label: rule.Name, Value: preset.Value(*rule.Low)})
+		}
+		if rule.High != nil {
+			row.High = preset.Format(*rule.High)
+			lines = append(lines, ChartLine{Label: rule.Name, Value: preset.Value(*rule.High)})
+		}
+		data.Alarms = append(data.Alarms, row)
+	}
+	points := make([]TrendPoint, 0, len(readings))
+	for _, reading := range readings {
+		if reading.Valid {
+			value := preset.Value(reading.Pressure)
+			data.Pressure.Update(value)
+			points = append(points, TrendPoint{Time: reading.Timestamp, Pressure: value})
+		} else {
+			data.Errors++
+		}
+	}
+	if len(readings) > 0 {
+		data.Start = readings[0].Timestamp.Format("2006-01-02 15:04:05")
+		data.End = readings[len(readings)-1].Timestamp.Format("2006-01-02 15:04:05")
+	}
+
+	if opts.Setpoint != nil {
+		stats := SetpointStatsFromReadings(readings, *opts.Setpoint, opts.Interval)
+		deviation := func(pa float64) string {
+			return fmt.Sprintf("%+.*f %s", preset.Decimals, preset.Unit.ConvertFromPascal(pa), preset.Unit.Symbol())
+		}
+		data.Setpoint = &reportSetpoint{
+			Setpoint: fmt.Sprintf("%s ± %.*f %s", preset.Format(opts.Setpoint.Target),
+				preset.Decimals, preset.Unit.ConvertFromPascal(opts.Setpoint.Tolerance), preset.Unit.Symbol()),
+			InSpecPercent:    stats.InSpecPercent,
+			OutOfSpec:        stats.OutOfSpecTime.Round(time.Second).String(),
+			MeanDeviation:    deviation(stats.MeanDeviation),
+			MeanAbsDeviation: deviation(stats.MeanAbsDeviation),
+			MaxAbsDeviation:  deviation(stats.MaxAbsDeviation),
+			Days:             stats.Days,
+		}
+		low, high := opts.Setpoint.Target-opts.Setpoint.Tolerance, opts.Setpoint.Target+opts.Setpoint.Tolerance
+		lines = append(lines,
+			ChartLine{Label: "設定值下限", Value: preset.Value(low)},
+			ChartLine{Label: "設定值上限", Value: preset.Value(high)})
+	}
+
+	chart := NewChart(preset.Quantity+"趨勢", points).AddLines(lines...)
+	chart.Unit = data.Unit
+	data.Chart = template.HTML(chart.SVG())
+	return trendReportTemplate.Execute(w, data)
+}
+
+// SaveTrendReportHTML 將 HTML 趨勢報告寫入檔案
+func SaveTrendReportHTML(readings []PressureReading, alarms []AlarmRule, filename string) error {
+	return SaveTrendReportHTMLWithPreset(readings, alarms, DefaultUnitPreset, filename)
+}
+
+// SaveTrendReportHTMLWithPreset 按單位預設將 HTML 趨勢報告寫入檔案
+func SaveTrendReportHTMLWithPreset(readings []PressureReading, alarms []AlarmRule, preset UnitPreset, filename string) error {
+	return SaveTrendReportHTMLWithOptions(readings, alarms, TrendReportOptions{Preset: preset}, filename)
+}
+
+// SaveTrendReportHTMLWithOptions 按報告選項將 HTML 趨勢報告寫入檔案
+func SaveTrendReportHTMLWithOptions(readings []PressureReading, alarms []AlarmRule, opts TrendReportOptions, filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("創建報告檔案失敗: %v", err)
+	}
+	defer file.Close()
+
+	if err := WriteTrendReportHTMLWithOptions(file, readings, alarms, opts); err != nil {
+		return fmt.Errorf("生成報告失敗: %v", err)
+	}
+	return nil
+}
+
+var trendReportTemplate = template.Must(template.New("trend_report").Parse(`<!DOCTYPE html>
+<html lang="zh-Hant">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", "Noto Sans TC", sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.6em; margin-bottom: 0.2em; }
+h2 { font-size: 1.2em; margin-top: 1.6em; border-bottom: 1px solid #ccc; padding-bottom: 0.2em; }
+table { border-collapse: collapse; width: 100%; margin-top: 0.6em; font-size: 0.9em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #f0f0f0; }
+.meta { color: #666; font-size: 0.9em; }
+svg { max-width: 100%; height: auto; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">生成時間: {{.GeneratedAt}} · {{.Library}}</p>
+
+<h2>摘要</h2>
+<table>
+<tr><th>時間範圍</th><td>{{.Start}} ~ {{.End}}</td></tr>
+<tr><th>讀數</th><td>{{.Readings}}（無效 {{.Errors}}）</td></tr>
+{{if .Pressure.Count}}<tr><th>{{.Quantity}}最小值</th><td>{{printf "%.*f" .Decimals .Pressure.Min}} {{.Unit}}</td></tr>
+<tr><th>{{.Quantity}}最大值</th><td>{{printf "%.*f" .Decimals .Pressure.Max}} {{.Unit}}</td></tr>
+<tr><th>{{.Quantity}}平均值</th><td>{{printf "%.*f" .Decimals .Pressure.Mean}} {{.Unit}}</td></tr>
+<tr><th>標準偏差</th><td>{{printf "%.*f" .Decimals .Pressure.StdDev}} {{.Unit}}</td></tr>
+{{end}}</table>
+
+<h2>趨勢</h2>
+{{.Chart}}
+
+{{with .Setpoint}}<h2>設定值</h2>
+<table>
+<tr><th>設定值</th><td>{{.Setpoint}}</td></tr>
+<tr><th>達標時間比例</th><td>{{printf "%.1f" .InSpecPercent}}%</td></tr>
+<tr><th>累計超標時長</th><td>{{.OutOfSpec}}</td></tr>
+<tr><th>平均偏差</th><td>{{.MeanDeviation}}</td></tr>
+<tr><th>平均絕對偏差</th><td>{{.MeanAbsDeviation}}</td></tr>
+<tr><th>最大絕對偏差</th><td>{{.MaxAbsDeviation}}</td></tr>
+</table>
+{{if .Days}}<table>
+<tr><th>日期</th><th>超標（分鐘）</th><th>達標（分鐘）</th></tr>
+{{range .Days}}<tr><td>{{.Date}}</td><td>{{printf "%.1f" .OutOfSpecMinutes}}</td><td>{{printf "%.1f" .InSpecMinutes}}</td></tr>
+{{end}}</table>
+{{end}}{{end}}
+{{if .Alarms}}<h2>告警規則</h2>
+<table>
+<tr><th>規則</th><th>下限</th><th>上限</th></tr>
+{{range .Alarms}}<tr><td>{{.Name}}</td><td>{{.Low}}</td><td>{{.High}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
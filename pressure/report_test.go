@@ -0,0 +1,36 @@
+package pressure
+
+import "testing"
+
+func TestSummarizePortsCountsSkippedProbes(t *testing.T) {
+	config := ScanConfig{SkipUnresponsive: true}
+	result := &ScanResult{}
+	for slaveID := byte(1); slaveID <= 5; slaveID++ {
+		result.addProbe(DeviceInfo{Device: "/dev/ttyUSB0", SlaveID: slaveID, Responsive: slaveID == 3}, config)
+	}
+	for slaveID := byte(1); slaveID <= 4; slaveID++ {
+		result.addProbe(DeviceInfo{Device: "/dev/ttyUSB1", SlaveID: slaveID}, config)
+	}
+
+	summaries := SummarizePorts(result)
+	if len(summaries) != 2 {
+		t.Fatalf("summaries = %+v, want both ports including the one without devices", summaries)
+	}
+	if s := summaries[0]; s.Port != "/dev/ttyUSB0" || s.Tested != 5 || s.Responsive != 1 {
+		t.Errorf("first port = %+v, want 5 tested and 1 responsive", s)
+	}
+	if s := summaries[1]; s.Port != "/dev/ttyUSB1" || s.Tested != 4 || s.Responsive != 0 {
+		t.Errorf("second port = %+v, want 4 tested and none responsive", s)
+	}
+}
+
+func TestSummarizePortsWithoutProbeCounts(t *testing.T) {
+	// 舊版本保存的結果沒有各串口探測數，按設備列表計數
+	result := &ScanResult{Devices: []DeviceInfo{
+		{Device: "COM3", SlaveID: 1, Responsive: true},
+		{Device: "COM3", SlaveID: 2},
+	}}
+	if summaries := SummarizePorts(result); len(summaries) != 1 || summaries[0].Tested != 2 {
+		t.Errorf("summaries = %+v, want COM3 with 2 tested", summaries)
+	}
+}
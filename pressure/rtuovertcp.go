@@ -0,0 +1,181 @@
+// pressure/rtuovertcp.go - 串口服務器傳輸：在 TCP 連接上收發 Modbus RTU 幀（Moxa NPort 等透明傳輸模式）
+package pressure
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// RTUOverTCPScheme 串口服務器的設備路徑前綴，如 tcp://192.168.1.60:4001
+const RTUOverTCPScheme = "tcp://"
+
+// IsRTUOverTCP 是否通過串口服務器連接：傳輸方式為 rtuovertcp，或 RTU 傳輸的設備路徑為 tcp://host:port
+func (c Config) IsRTUOverTCP() bool {
+	if strings.EqualFold(c.Transport, TransportRTUOverTCP) {
+		return true
+	}
+	return !c.IsTCP() && strings.HasPrefix(strings.ToLower(c.Device), RTUOverTCPScheme)
+}
+
+// SerialServerAddress 返回串口服務器的 host:port（去掉設備路徑的 tcp:// 前綴）
+func (c Config) SerialServerAddress() string {
+	device := strings.TrimSpace(c.Device)
+	if strings.HasPrefix(strings.ToLower(device), RTUOverTCPScheme) {
+		device = device[len(RTUOverTCPScheme):]
+	}
+	return strings.TrimSuffix(device, "/")
+}
+
+// checkSerialServerAddress 檢查串口服務器地址，串口服務器的端口因型號和設置而異，必須明確指定
+func checkSerialServerAddress(address string) error {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host == "" || port == "" {
+		return fmt.Errorf("串口服務器地址必須為 tcp://host:port，當前: %s", address)
+	}
+	return nil
+}
+
+// rtuOverTCPHandler 在 TCP 連接上收發 Modbus RTU 幀的客戶端處理器
+//
+// 串口服務器將 TCP 數據原樣轉發到串口，幀格式與 RTU 相同（站點號 + PDU + CRC），沒有 MBAP 頭，
+// 響應長度按功能碼判斷。請求失敗時斷開連接，避免遲到的響應被當作下一個請求的響應。
+type rtuOverTCPHandler struct {
+	packager *modbus.RTUClientHandler // 只用於 RTU 幀的編碼、校驗和解碼
+	address  string
+	timeout  time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// newRTUOverTCPHandler 創建連接串口服務器 address (host:port) 的處理器
+func newRTUOverTCPHandler(address string, slaveID byte, timeout time.Duration) *rtuOverTCPHandler {
+	packager := modbus.NewRTUClientHandler("")
+	packager.SlaveId = slaveID
+	if timeout <= 0 {
+		timeout = DefaultResponseTimeout
+	}
+	return &rtuOverTCPHandler{packager: packager, address: address, timeout: timeout}
+}
+
+// Encode 實現 modbus.Packager 接口
+func (h *rtuOverTCPHandler) Encode(pdu *modbus.ProtocolDataUnit) ([]byte, error) {
+	return h.packager.Encode(pdu)
+}
+
+// Decode 實現 modbus.Packager 接口
+func (h *rtuOverTCPHandler) Decode(adu []byte) (*modbus.ProtocolDataUnit, error) {
+	return h.packager.Decode(adu)
+}
+
+// Verify 實現 modbus.Packager 接口
+func (h *rtuOverTCPHandler) Verify(aduRequest, aduResponse []byte) error {
+	return h.packager.Verify(aduRequest, aduResponse)
+}
+
+// Connect 連接串口服務器
+func (h *rtuOverTCPHandler) Connect() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.connect()
+}
+
+// connect 連接串口服務器（調用方需持有鎖）
+func (h *rtuOverTCPHandler) connect() error {
+	if h.conn != nil {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", h.address, h.timeout)
+	if err != nil {
+		return fmt.Errorf("連接串口服務器 %s 失敗: %v", h.address, err)
+	}
+	h.conn = conn
+	return nil
+}
+
+// Close 斷開與串口服務器的連接
+func (h *rtuOverTCPHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.close()
+}
+
+// close 斷開連接（調用方需持有鎖）
+func (h *rtuOverTCPHandler) close() error {
+	if h.conn == nil {
+		return nil
+	}
+	err := h.conn.Close()
+	h.conn = nil
+	return err
+}
+
+// Send 實現 modbus.Transporter 接口，發送 RTU 請求幀並讀取完整的響應幀
+func (h *rtuOverTCPHandler) Send(request []byte) ([]byte, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.connect(); err != nil {
+		return nil, err
+	}
+	response, err := h.exchange(request)
+	if err != nil {
+		h.close()
+		return nil, err
+	}
+	return response, nil
+}
+
+// exchange 發送請求並讀取到 rtuFrameLen 判斷的幀長度為止（調用方需持有鎖）
+func (h *rtuOverTCPHandler) exchange(request []byte) ([]byte, error) {
+	if err := h.conn.SetDeadline(time.Now().Add(h.timeout)); err != nil {
+		return nil, err
+	}
+	if _, err := h.conn.Write(request); err != nil {
+		return nil, fmt.Errorf("發送失敗: %v", err)
+	}
+
+	var buf bytes.Buffer
+	chunk := make([]byte, 256)
+	for {
+		n, err := h.conn.Read(chunk)
+		buf.Write(chunk[:n])
+		if expected := rtuFrameLen(buf.Bytes()); expected > 0 && buf.Len() >= expected {
+			return buf.Bytes()[:expected], nil
+		}
+		if err != nil {
+			if buf.Len() == 0 {
+				return nil, fmt.Errorf("串口服務器 %s 無響應: %v", h.address, err)
+			}
+			return nil, fmt.Errorf("響應不完整: 收到 % X: %v", buf.Bytes(), err)
+		}
+	}
+}
+
+// rtuFrameLen 按功能碼返回 RTU 響應幀的總長度，收到的字節不足以確定時返回 0
+func rtuFrameLen(frame []byte) int {
+	if len(frame) < 2 {
+		return 0
+	}
+	function := frame[1]
+	switch {
+	case function&0x80 != 0:
+		return 5 // 站點號、功能碼、異常碼、CRC
+	case function == FunctionEncapsulatedInterface:
+		return deviceIDFrameLen(frame)
+	case function == 0x05 || function == 0x06 || function == 0x0F || function == 0x10 || function == FunctionDiagnostics:
+		return 8 // 站點號、功能碼、地址/子功能碼、數量/數據、CRC
+	default:
+		// 讀取類功能碼 (0x01-0x04 等)：站點號、功能碼、字節數、數據、CRC
+		if len(frame) < 3 {
+			return 0
+		}
+		return 3 + int(frame[2]) + 2
+	}
+}
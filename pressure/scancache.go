@@ -0,0 +1,196 @@
+// pressure/scancache.go - 掃描緩存：保存上次掃描的結果，重新掃描時先驗證已知設備，全部響應則不必完整掃描
+package pressure
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// DefaultScanCacheFile 默認的掃描緩存檔案
+const DefaultScanCacheFile = "scan_cache.json"
+
+// SetCache 設置掃描緩存檔案，每次掃描完成後寫入結果，Rescan 從中讀取已知設備
+func (s *Scanner) SetCache(path string) *Scanner {
+	s.cacheFile = path
+	return s
+}
+
+// SaveScanCache 將掃描結果寫入緩存檔案，讀取使用 LoadScanResult
+func SaveScanCache(path string, result *ScanResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// 先寫臨時檔案再改名，避免中斷時留下不完整的緩存
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Rescan 增量重新掃描：先以緩存中記錄的串口、線路參數和站點號驗證已知設備，全部響應時直接返回，
+// 否則（或沒有緩存時）按緩存的掃描配置完整掃描
+//
+// 排除列表中的串口上的已知設備不驗證，也不計入。未調用 SetCache 時使用 DefaultScanCacheFile。
+// 驗證和完整掃描的結果都會寫回緩存。
+func (s *Scanner) Rescan() (*ScanResult, error) {
+	if s.cacheFile == "" {
+		s.cacheFile = DefaultScanCacheFile
+	}
+
+	cached, err := LoadScanResult(s.cacheFile)
+	if err != nil {
+		s.logf("ℹ️  無可用的掃描緩存，執行完整掃描: %v", err)
+		return s.ScanDevices(s.PresetConfig(GetDefaultScanConfig()))
+	}
+	known := s.getResponsiveDevices(cached.Devices)
+	if len(known) == 0 {
+		s.logf("ℹ️  掃描緩存中沒有已知設備，執行完整掃描")
+		return s.ScanDevices(cached.Config)
+	}
+
+	result, err := s.verifyDevices(known, cached.Config)
+	if err != nil {
+		return nil, err
+	}
+	if result.TotalTested > 0 && result.Successful == result.TotalTested {
+		return result, nil
+	}
+
+	s.logf("⚠️  %d 台已知設備中 %d 台未響應，執行完整掃描", result.TotalTested, result.TotalTested-result.Successful)
+	return s.ScanDevices(cached.Config)
+}
+
+// knownGroup 同一串口和線路參數（或同一 Modbus TCP 地址）上的已知設備，共用一個連接驗證
+type knownGroup struct {
+	endpoint string
+	setting  lineSetting
+	tcp      bool
+	devices  []DeviceInfo
+}
+
+// verifyDevices 以掃描時記錄的連接參數重新探測已知設備
+func (s *Scanner) verifyDevices(known []DeviceInfo, config ScanConfig) (*ScanResult, error) {
+	startTime := time.Now()
+	s.logf("⚡ 驗證掃描緩存中的 %d 台已知設備...", len(known))
+
+	config, err := s.prepareScanConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	result := &ScanResult{
+		SchemaVersion: SchemaVersion,
+		Devices:       []DeviceInfo{},
+		Config:        config,
+		Incremental:   true,
+	}
+	s.bus.Publish(Event{Type: EventScanStarted, Message: fmt.Sprintf("%s: 驗證 %d 台已知設備", EventScanStarted.Description(), len(known)), Data: config})
+
+	for _, group := range groupKnownDevices(known) {
+		if !group.tcp && config.isExcludedPort(group.endpoint) {
+			s.logf("⏭️  跳過排除的串口上的 %d 台已知設備: %s", len(group.devices), group.endpoint)
+			continue
+		}
+		for _, device := range s.verifyGroup(group, config) {
+			result.addProbe(device, config)
+		}
+	}
+	return s.finishScan(result, startTime), nil
+}
+
+// groupKnownDevices 按連接端點和線路參數分組，保持設備在緩存中的順序
+func groupKnownDevices(known []DeviceInfo) []*knownGroup {
+	index := make(map[string]*knownGroup)
+	var groups []*knownGroup
+	for _, device := range known {
+		group := &knownGroup{endpoint: device.Device, tcp: DeviceTransport(device) == TransportTCP}
+		key := device.Device
+		if !group.tcp {
+			group.setting.BaudRate = DefaultBaudRate
+			if baudRate, ok := deviceBaudRate(device); ok {
+				group.setting.BaudRate = baudRate
+			}
+			group.setting.Parity = DefaultParity
+			if parity, ok := device.Properties["parity"].(string); ok && parity != "" {
+				group.setting.Parity = parity
+			}
+			group.setting.StopBits = DefaultStopBits
+			if stopBits := DeviceStopBits(device); stopBits != 0 {
+				group.setting.StopBits = stopBits
+			}
+			key += "@" + group.setting.String()
+		}
+		if existing, ok := index[key]; ok {
+			group = existing
+		} else {
+			index[key] = group
+			groups = append(groups, group)
+		}
+		group.devices = append(group.devices, device)
+	}
+	return groups
+}
+
+// verifyGroup 打開一次連接，依次探測組內的已知設備
+func (s *Scanner) verifyGroup(group *knownGroup, config ScanConfig) []DeviceInfo {
+	var handler ModbusTransport
+	var err error
+	if group.tcp {
+		tcpHandler := modbus.NewTCPClientHandler(group.endpoint)
+		tcpHandler.Timeout = config.ScanTimeout
+		handler, err = tcpHandler, connectHandler(tcpHandler, config.ScanTimeout)
+	} else {
+		handler, err = openScanHandler(group.endpoint, group.setting, config.ScanTimeout)
+	}
+
+	devices := make([]DeviceInfo, 0, len(group.devices))
+	if err != nil {
+		s.logf("  ❌ 打開 %s 失敗: %v", group.endpoint, err)
+		for _, known := range group.devices {
+			devices = append(devices, DeviceInfo{
+				Device:     known.Device,
+				SlaveID:    known.SlaveID,
+				Properties: known.Properties,
+				ScanTime:   time.Now(),
+				Error:      fmt.Sprintf("打開失敗: %v", err),
+				Location:   known.Location,
+				Model:      known.Model,
+			})
+		}
+		return devices
+	}
+	defer handler.Close()
+
+	for i, known := range group.devices {
+		if i > 0 {
+			config.pause()
+		}
+		var device DeviceInfo
+		if group.tcp {
+			device, _ = s.testTCPDevice(handler.(*modbus.TCPClientHandler), group.endpoint, known.SlaveID, config)
+		} else {
+			device, _ = s.testDevice(handler.(*modbus.RTUClientHandler), group.endpoint, group.setting, known.SlaveID, config)
+		}
+		if device.Location == nil {
+			device.Location = known.Location
+		}
+		if device.Model == nil {
+			device.Model = known.Model
+		}
+
+		if device.Responsive {
+			s.bus.Publish(Event{Type: EventDeviceFound, Source: group.endpoint, SlaveID: known.SlaveID, Message: EventDeviceFound.Description(), Data: device})
+			s.logf("    ✅ 已知設備響應: %s 站點=%d", group.endpoint, known.SlaveID)
+		} else {
+			s.logf("    ❌ 已知設備未響應: %s 站點=%d: %s", group.endpoint, known.SlaveID, device.Error)
+		}
+		devices = append(devices, device)
+	}
+	return devices
+}
@@ -0,0 +1,141 @@
+// pressure/scanconfig.go - 自定義掃描配置：從 YAML/JSON 檔案載入掃描配置，並可用命令列覆蓋串口、站點號和波特率
+package pressure
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadScanConfig 從 YAML 或 JSON 檔案載入掃描配置，檔案中未出現的字段使用完整掃描的默認值
+//
+// 字段名與 ScanResult 中保存的 config 相同；scan_timeout 在 YAML 中可寫作 "500ms"，在 JSON 中為納秒數。
+func LoadScanConfig(filename string) (ScanConfig, error) {
+	config := GetDefaultScanConfig()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return config, fmt.Errorf("讀取掃描配置失敗: %v", err)
+	}
+
+	switch {
+	case strings.HasSuffix(strings.ToLower(filename), ".yaml") ||
+		strings.HasSuffix(strings.ToLower(filename), ".yml"):
+		err = yaml.Unmarshal(data, &config)
+	case strings.HasSuffix(strings.ToLower(filename), ".json"):
+		err = json.Unmarshal(data, &config)
+	default:
+		return config, fmt.Errorf("不支援的掃描配置格式: %s (請使用 .yaml 或 .json)", filename)
+	}
+	if err != nil {
+		return config, fmt.Errorf("解析掃描配置 %s 失敗: %v", filename, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return config, fmt.Errorf("掃描配置 %s: %v", filename, err)
+	}
+	return config, nil
+}
+
+// Validate 檢查掃描配置的站點號、波特率、超時和設備數量，校驗位和探測參數在掃描時檢查
+func (sc ScanConfig) Validate() error {
+	if len(sc.SlaveIDs) == 0 {
+		return fmt.Errorf("站點號列表為空")
+	}
+	if sc.Network == "" && len(sc.BaudRates) == 0 {
+		return fmt.Errorf("波特率列表為空")
+	}
+	for _, baudRate := range sc.BaudRates {
+		if !IsValidBaudRate(baudRate) {
+			return fmt.Errorf("不支援的波特率 %d，支援的波特率: %v", baudRate, GetSupportedBaudRates())
+		}
+	}
+	if sc.ScanTimeout <= 0 {
+		return fmt.Errorf("無效的掃描超時: %v", sc.ScanTimeout)
+	}
+	if sc.ProbeDelay < 0 {
+		return fmt.Errorf("無效的探測間隔: %v", sc.ProbeDelay)
+	}
+	if sc.RetryTimeout < 0 || sc.RetryLimit < 0 {
+		return fmt.Errorf("無效的重試設置: 超時 %v，最多 %d 次", sc.RetryTimeout, sc.RetryLimit)
+	}
+	if sc.MaxDevices <= 0 {
+		return fmt.Errorf("無效的最大設備數量: %d", sc.MaxDevices)
+	}
+	return nil
+}
+
+// ParseBaudRateList 解析以逗號分隔的波特率列表，如 "9600,19200"
+func ParseBaudRateList(value string) ([]int, error) {
+	var baudRates []int
+	for _, part := range strings.Split(value, ",") {
+		baudRate, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || !IsValidBaudRate(baudRate) {
+			return nil, fmt.Errorf("無效的波特率: %s (支援 %v)", part, GetSupportedBaudRates())
+		}
+		baudRates = append(baudRates, baudRate)
+	}
+	return baudRates, nil
+}
+
+// ParseSerialPortList 解析以逗號分隔的串口列表，如 "/dev/ttyUSB0,/dev/ttyUSB1"
+func ParseSerialPortList(value string) ([]string, error) {
+	var ports []string
+	for _, part := range strings.Split(value, ",") {
+		if port := strings.TrimSpace(part); port != "" {
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("串口列表為空")
+	}
+	return ports, nil
+}
+
+// SetScanConfig 用指定的掃描配置（如 LoadScanConfig 載入的檔案）取代快速、完整和自動掃描的內置預設
+func (s *Scanner) SetScanConfig(config ScanConfig) *Scanner {
+	s.preset = &config
+	return s
+}
+
+// PresetConfig 返回掃描使用的配置：設置了 SetScanConfig 時為指定的配置，否則為內置預設 preset
+func (s *Scanner) PresetConfig(preset ScanConfig) ScanConfig {
+	if s.preset != nil {
+		return *s.preset
+	}
+	return preset
+}
+
+// SetProbeDelay 設置同一連接上相鄰兩次探測請求之間的等待時間，覆蓋掃描配置
+func (s *Scanner) SetProbeDelay(delay time.Duration) *Scanner {
+	s.probeDelay = delay
+	return s
+}
+
+// SetSerialPorts 覆蓋掃描配置中的串口列表
+func (s *Scanner) SetSerialPorts(ports []string) *Scanner {
+	s.serialPorts = ports
+	return s
+}
+
+// SetSlaveIDs 覆蓋掃描配置中的站點號列表
+func (s *Scanner) SetSlaveIDs(slaveIDs []byte) *Scanner {
+	s.slaveIDs = slaveIDs
+	return s
+}
+
+// SetBaudRates 覆蓋掃描配置中的波特率列表
+func (s *Scanner) SetBaudRates(baudRates []int) *Scanner {
+	s.baudRates = baudRates
+	return s
+}
+
+// SetExcludePorts 追加不掃描的串口，與掃描配置中的 ExcludePorts 合併
+func (s *Scanner) SetExcludePorts(ports []string) *Scanner {
+	s.excludePorts = ports
+	return s
+}
@@ -0,0 +1,171 @@
+// pressure/scanexport.go - 掃描結果匯出：JSON、CSV（可直接用 Excel 打開）和 YAML
+package pressure
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 掃描結果的匯出格式
+const (
+	ScanOutputJSON = "json"
+	ScanOutputCSV  = "csv"
+	ScanOutputYAML = "yaml"
+)
+
+// ParseScanOutputFormats 解析以逗號分隔的掃描結果格式列表，如 "json,csv"
+func ParseScanOutputFormats(value string) ([]string, error) {
+	var formats []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ",") {
+		format := strings.ToLower(strings.TrimSpace(part))
+		if format == "yml" {
+			format = ScanOutputYAML
+		}
+		switch format {
+		case ScanOutputJSON, ScanOutputCSV, ScanOutputYAML:
+		default:
+			return nil, fmt.Errorf("不支援的掃描結果格式: %s (支援 json/csv/yaml)", part)
+		}
+		if !seen[format] {
+			seen[format] = true
+			formats = append(formats, format)
+		}
+	}
+	return formats, nil
+}
+
+// WriteScanResults 按格式寫出掃描結果
+func WriteScanResults(w io.Writer, result *ScanResult, format string) error {
+	switch format {
+	case ScanOutputJSON:
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case ScanOutputCSV:
+		return WriteScanResultsCSV(w, result)
+	case ScanOutputYAML:
+		return WriteScanResultsYAML(w, result)
+	default:
+		return fmt.Errorf("不支援的掃描結果格式: %s", format)
+	}
+}
+
+// SaveScanResults 將掃描結果寫入檔案，格式由副檔名決定 (.json/.csv/.yaml/.yml)
+func SaveScanResults(result *ScanResult, filename string) error {
+	format := strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	if format == "yml" {
+		format = ScanOutputYAML
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := WriteScanResults(file, result, format); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteScanResultsCSV 將掃描到的設備寫為 CSV，每台設備（含無響應的探測）一行
+//
+// 開頭寫入 UTF-8 BOM，Excel 打開時才能正確顯示位置和錯誤信息中的中文。
+func WriteScanResultsCSV(w io.Writer, result *ScanResult) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"device", "slave_id", "transport", "responsive", "marginal", "baud_rate", "parity", "stop_bits", "data_format",
+		"confidence", "pressure", "location", "model", "scan_time", "error"})
+	for _, device := range result.Devices {
+		var baudRate, confidence, pressure, location, model string
+		if rate, ok := deviceBaudRate(device); ok {
+			baudRate = strconv.Itoa(rate)
+		}
+		if c, ok := device.Properties["format_confidence"].(float64); ok {
+			confidence = strconv.FormatFloat(c, 'f', 2, 64)
+		}
+		if device.LastReading != nil {
+			pressure = strconv.FormatFloat(device.LastReading.Pressure, 'f', 1, 64)
+		}
+		if device.Location != nil {
+			location = device.Location.String()
+		}
+		if device.Model != nil {
+			model = device.Model.String()
+		}
+		parity, _ := device.Properties["parity"].(string)
+		var stopBits string
+		if parity != "" {
+			stopBits = strconv.Itoa(DefaultStopBits)
+			if bits := DeviceStopBits(device); bits != 0 {
+				stopBits = strconv.Itoa(bits)
+			}
+		}
+		transport := DeviceTransport(device)
+		if transport == "" {
+			transport = TransportRTU
+		}
+
+		cw.Write([]string{
+			device.Device,
+			strconv.Itoa(int(device.SlaveID)),
+			transport,
+			strconv.FormatBool(device.Responsive),
+			strconv.FormatBool(device.Marginal),
+			baudRate,
+			parity,
+			stopBits,
+			device.DataFormat.String(),
+			confidence,
+			pressure,
+			location,
+			model,
+			device.ScanTime.Format("2006-01-02 15:04:05"),
+			device.Error,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteScanResultsYAML 將掃描結果寫為 YAML，字段名和順序與 JSON 輸出相同
+func WriteScanResultsYAML(w io.Writer, result *ScanResult) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	// JSON 本身是合法的 YAML，解析為節點樹可保留字段順序，再改為塊樣式輸出
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	clearYAMLStyle(&node)
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// clearYAMLStyle 清除節點樹的流樣式和引號，使用默認的塊樣式輸出
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
@@ -3,10 +3,15 @@ package pressure
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/goburrow/modbus"
@@ -15,14 +20,17 @@ import (
 
 // DeviceInfo 設備信息
 type DeviceInfo struct {
-	Device      string                 `json:"device"`       // 串口設備路徑
-	SlaveID     byte                   `json:"slave_id"`     // 站點號
-	Responsive  bool                   `json:"responsive"`   // 是否響應
-	DataFormat  DataFormatType         `json:"data_format"`  // 數據格式
-	LastReading *PressureReading       `json:"last_reading"` // 最後讀數
-	Properties  map[string]interface{} `json:"properties"`   // 其他屬性
-	ScanTime    time.Time              `json:"scan_time"`    // 掃描時間
-	Error       string                 `json:"error"`        // 錯誤信息
+	Device      string                 `json:"device"`             // 串口設備路徑
+	SlaveID     byte                   `json:"slave_id"`           // 站點號
+	Responsive  bool                   `json:"responsive"`         // 是否響應
+	DataFormat  DataFormatType         `json:"data_format"`        // 數據格式
+	LastReading *PressureReading       `json:"last_reading"`       // 最後讀數
+	Properties  map[string]interface{} `json:"properties"`         // 其他屬性
+	ScanTime    time.Time              `json:"scan_time"`          // 掃描時間
+	Error       string                 `json:"error"`              // 錯誤信息
+	Location    *Location              `json:"location,omitempty"` // 安裝位置（設置了位置對照表時）
+	Model       *DeviceModel           `json:"model,omitempty"`    // 設備標識（啟用讀取設備標識且設備支援時）
+	Marginal    bool                   `json:"marginal,omitempty"` // 第一次探測超時、第二輪重試才響應，通信可能不穩定
 }
 
 // Scanner 設備掃描器
@@ -30,36 +38,169 @@ type Scanner struct {
 	logger        *log.Logger
 	scanTimeout   time.Duration
 	deviceTimeout time.Duration
+	probeTimeout  time.Duration // 覆蓋 ScanConfig.ScanTimeout，0 表示使用掃描配置
+	probeDelay    time.Duration // 覆蓋 ScanConfig.ProbeDelay，0 表示使用掃描配置
+	retryTimeout  time.Duration // 覆蓋 ScanConfig.RetryTimeout，0 表示使用掃描配置，負數為不重試
+	formatSamples int           // 覆蓋 ScanConfig.FormatSamples，0 表示使用掃描配置
 	verbose       bool
+	identify      bool // 讀取已響應設備的設備標識，為 true 時覆蓋 ScanConfig.Identify
+
+	checkpointFile string // 掃描進度檢查點檔案，為空則不記錄
+	resume         bool   // 是否從檢查點恢復
+	cacheFile      string // 掃描緩存檔案，為空則不保存
+
+	preset       *ScanConfig // 取代內置預設的掃描配置，為空表示使用內置預設
+	serialPorts  []string    // 覆蓋 ScanConfig.SerialPorts，為空表示使用掃描配置
+	slaveIDs     []byte      // 覆蓋 ScanConfig.SlaveIDs，為空表示使用掃描配置
+	baudRates    []int       // 覆蓋 ScanConfig.BaudRates，為空表示使用掃描配置
+	excludePorts []string    // 追加到 ScanConfig.ExcludePorts
+
+	parities      []string // 覆蓋 ScanConfig.Parities，為空表示使用掃描配置
+	stopBits      []int    // 覆蓋 ScanConfig.StopBits，為空表示使用掃描配置
+	maxParallel   int      // 覆蓋 ScanConfig.MaxParallel，0 表示使用掃描配置
+	probeFunction byte     // 覆蓋 ScanConfig.ProbeFunction，0 表示使用掃描配置
+	probeRegister uint16   // 覆蓋 ScanConfig.ProbeRegister，0 表示使用掃描配置
+	probeCount    uint16   // 覆蓋 ScanConfig.ProbeCount，0 表示使用掃描配置
+	byteOrder     string   // 檢測和解析浮點格式時的字節序，為空表示普時達的 CDAB
+	scale         float64  // 解析十進制格式時的換算係數，0 表示普時達的 0.1
+
+	passiveListen time.Duration // 掃描前每個波特率的被動監聽時長，0 為不監聽
+	probeAnyway   bool          // 被動監聽發現其他主站時是否仍然探測
+
+	bus   *EventBus   // 事件總線，為空則不發布
+	trace *FrameTrace // 幀跟蹤，為空則不記錄
 }
 
+// DefaultScanWorkers 並行掃描時默認同時掃描的串口數，避免大量 USB 轉換器同時打開時拖慢主機
+const DefaultScanWorkers = 4
+
 // ScanConfig 掃描配置
 type ScanConfig struct {
 	// SerialPorts 要掃描的串口列表，為空則自動檢測
-	SerialPorts []string `json:"serial_ports"`
+	SerialPorts []string `json:"serial_ports" yaml:"serial_ports"`
+	// ExcludePorts 不掃描的串口（PLC 通信、GPS 等其他設備使用的串口），支援通配符如 /dev/ttyS*，
+	// 對自動檢測和指定的串口都生效
+	ExcludePorts []string `json:"exclude_ports,omitempty" yaml:"exclude_ports,omitempty"`
 	// SlaveIDs 要掃描的從站ID範圍
-	SlaveIDs []byte `json:"slave_ids"`
+	SlaveIDs []byte `json:"slave_ids" yaml:"slave_ids"`
 	// BaudRates 要嘗試的波特率
-	BaudRates []int `json:"baud_rates"`
+	BaudRates []int `json:"baud_rates" yaml:"baud_rates"`
 	// ScanTimeout 每個設備的掃描超時時間
-	ScanTimeout time.Duration `json:"scan_timeout"`
+	ScanTimeout time.Duration `json:"scan_timeout" yaml:"scan_timeout"`
+	// ProbeDelay 同一串口（或網關連接）上相鄰兩次探測請求之間的等待時間，0 為不等待；
+	// 部分 RS485 轉換器在請求緊接著發出時會丟幀，導致設備被誤判為無響應
+	ProbeDelay time.Duration `json:"probe_delay,omitempty" yaml:"probe_delay,omitempty"`
+	// RetryTimeout 第二輪重試的探測超時：第一輪超時的站點號以此超時再探測一次，0 為不重試（只用於串口掃描）
+	RetryTimeout time.Duration `json:"retry_timeout,omitempty" yaml:"retry_timeout,omitempty"`
+	// RetryLimit 每個串口第二輪最多重試的探測數，0 為 DefaultScanRetryLimit
+	RetryLimit int `json:"retry_limit,omitempty" yaml:"retry_limit,omitempty"`
 	// MaxDevices 最大掃描設備數量
-	MaxDevices int `json:"max_devices"`
+	MaxDevices int `json:"max_devices" yaml:"max_devices"`
 	// AutoDetectFormat 是否自動檢測數據格式
-	AutoDetectFormat bool `json:"auto_detect_format"`
-	// Parallel 是否並行掃描
-	Parallel bool `json:"parallel"`
+	AutoDetectFormat bool `json:"auto_detect_format" yaml:"auto_detect_format"`
+	// FormatSamples 自動檢測格式時對響應設備讀取的次數，多次讀取時比較各種解析方式的穩定性，0 或 1 為只用探測的讀數
+	FormatSamples int `json:"format_samples,omitempty" yaml:"format_samples,omitempty"`
+	// Parallel 是否並行掃描不同串口（同一串口上的探測始終串行）
+	Parallel bool `json:"parallel" yaml:"parallel"`
+	// MaxParallel 並行掃描時同時掃描的串口數上限，0 為 DefaultScanWorkers
+	MaxParallel int `json:"max_parallel,omitempty" yaml:"max_parallel,omitempty"`
 	// SkipUnresponsive 是否跳過無響應的設備
-	SkipUnresponsive bool `json:"skip_unresponsive"`
+	SkipUnresponsive bool `json:"skip_unresponsive" yaml:"skip_unresponsive"`
+	// Parities 每個波特率下要嘗試的校驗位 (N/E/O)，為空則只嘗試 N
+	Parities []string `json:"parities,omitempty" yaml:"parities,omitempty"`
+	// StopBits 每種校驗位下要嘗試的停止位 (1/2)，為空則只嘗試 1
+	StopBits []int `json:"stop_bits,omitempty" yaml:"stop_bits,omitempty"`
+	// Identify 是否對響應的設備讀取設備標識（功能碼 0x2B/0x0E），區分普時達儀表和碰巧響應的其他 Modbus 設備
+	Identify bool `json:"identify,omitempty" yaml:"identify,omitempty"`
+	// ProbeFunction 探測使用的功能碼（0x03 讀保持寄存器 / 0x04 讀輸入寄存器），0 為 0x03
+	ProbeFunction byte `json:"probe_function,omitempty" yaml:"probe_function,omitempty"`
+	// ProbeRegister 探測的寄存器起始地址，0 為壓力寄存器 0x0034
+	ProbeRegister uint16 `json:"probe_register,omitempty" yaml:"probe_register,omitempty"`
+	// ProbeCount 探測讀取的寄存器數量，0 為 2
+	ProbeCount uint16 `json:"probe_count,omitempty" yaml:"probe_count,omitempty"`
+	// Network 網絡掃描的網段（CIDR 或單個 IP），設置後掃描 Modbus TCP 設備而不是串口
+	Network string `json:"network,omitempty" yaml:"network,omitempty"`
+	// TCPPorts 網絡掃描的 TCP 端口，為空則只掃描 502
+	TCPPorts []int `json:"tcp_ports,omitempty" yaml:"tcp_ports,omitempty"`
+}
+
+// workers 返回同時掃描的串口數，不並行掃描時為 1
+func (sc ScanConfig) workers(ports int) int {
+	if !sc.Parallel || ports <= 1 {
+		return 1
+	}
+	workers := sc.MaxParallel
+	if workers <= 0 {
+		workers = DefaultScanWorkers
+	}
+	if workers > ports {
+		workers = ports
+	}
+	return workers
+}
+
+// pause 在同一連接上發出下一個探測請求前等待 ProbeDelay
+func (sc ScanConfig) pause() {
+	if sc.ProbeDelay > 0 {
+		time.Sleep(sc.ProbeDelay)
+	}
+}
+
+// probeParams 返回探測功能碼、寄存器地址和數量，未設置的字段使用壓力寄存器默認值
+func (sc ScanConfig) probeParams() (function byte, register, count uint16) {
+	function, register, count = sc.ProbeFunction, sc.ProbeRegister, sc.ProbeCount
+	if function == 0 {
+		function = FunctionCode
+	}
+	if register == 0 {
+		register = PressureRegisterAddr
+	}
+	if count == 0 {
+		count = RegisterCount
+	}
+	return function, register, count
+}
+
+// isPressureProbe 探測目標是否為普時達壓力寄存器，只有此時才解析壓力值
+//
+// 部分固件版本只通過輸入寄存器提供壓力值，因此功能碼 0x04 讀取同一地址也視為壓力寄存器；
+// 緊湊型號只有一個 16 位壓力寄存器，因此讀取 1 個寄存器也視為壓力寄存器。
+func (sc ScanConfig) isPressureProbe() bool {
+	function, register, count := sc.probeParams()
+	return (function == FunctionCode || function == ModbusFunctionReadInputRegisters) &&
+		register == PressureRegisterAddr && (count == RegisterCount || count == 1)
 }
 
 // ScanResult 掃描結果
 type ScanResult struct {
-	Devices     []DeviceInfo  `json:"devices"`      // 發現的設備
-	ScanTime    time.Duration `json:"scan_time"`    // 掃描總時間
-	TotalTested int           `json:"total_tested"` // 測試的設備總數
-	Successful  int           `json:"successful"`   // 成功響應的設備數
-	Config      ScanConfig    `json:"config"`       // 使用的掃描配置
+	SchemaVersion string         `json:"schema_version"`         // 輸出格式版本
+	Devices       []DeviceInfo   `json:"devices"`                // 發現的設備
+	ScanTime      time.Duration  `json:"scan_time"`              // 掃描總時間
+	TotalTested   int            `json:"total_tested"`           // 測試的設備總數
+	Successful    int            `json:"successful"`             // 成功響應的設備數
+	Config        ScanConfig     `json:"config"`                 // 使用的掃描配置
+	BusActivity   []BusActivity  `json:"bus_activity,omitempty"` // 掃描前被動監聽的結果（--passive-first）
+	Incremental   bool           `json:"incremental,omitempty"`  // 是否只驗證了掃描緩存中的已知設備（Rescan）
+	Marginal      int            `json:"marginal,omitempty"`     // 第二輪重試才響應的設備數
+	PortTested    map[string]int `json:"port_tested,omitempty"`  // 各串口或網關測試的配置數，包括未列入 Devices 的無響應探測
+}
+
+// addProbe 記錄一次探測結果，SkipUnresponsive 時無響應的探測只計數不列入設備列表
+func (sr *ScanResult) addProbe(device DeviceInfo, config ScanConfig) {
+	if !config.SkipUnresponsive || device.Responsive {
+		sr.Devices = append(sr.Devices, device)
+	}
+	if sr.PortTested == nil {
+		sr.PortTested = make(map[string]int)
+	}
+	sr.PortTested[device.Device]++
+	sr.TotalTested++
+	if device.Responsive {
+		sr.Successful++
+	}
+	if device.Marginal {
+		sr.Marginal++
+	}
 }
 
 // NewScanner 創建新的掃描器
@@ -89,16 +230,111 @@ func (s *Scanner) SetTimeout(scanTimeout, deviceTimeout time.Duration) *Scanner
 	return s
 }
 
+// SetFormatSamples 設置自動檢測格式時對響應設備讀取的次數，覆蓋掃描配置
+func (s *Scanner) SetFormatSamples(samples int) *Scanner {
+	s.formatSamples = samples
+	return s
+}
+
+// SetProbeTimeout 設置每次探測的超時時間，覆蓋掃描配置中的 ScanTimeout
+func (s *Scanner) SetProbeTimeout(timeout time.Duration) *Scanner {
+	s.probeTimeout = timeout
+	return s
+}
+
+// SetProbe 設置探測的功能碼、寄存器地址和數量，覆蓋掃描配置，傳 0 的字段保持掃描配置
+func (s *Scanner) SetProbe(function byte, register, count uint16) *Scanner {
+	s.probeFunction = function
+	s.probeRegister = register
+	s.probeCount = count
+	return s
+}
+
+// SetMaxParallel 設置並行掃描時同時掃描的串口數上限，覆蓋掃描配置，1 為依次掃描
+func (s *Scanner) SetMaxParallel(workers int) *Scanner {
+	s.maxParallel = workers
+	return s
+}
+
+// SetParities 設置每個波特率下要嘗試的校驗位，覆蓋掃描配置
+func (s *Scanner) SetParities(parities []string) *Scanner {
+	s.parities = parities
+	return s
+}
+
+// SetStopBits 設置每種校驗位下要嘗試的停止位，覆蓋掃描配置
+func (s *Scanner) SetStopBits(stopBits []int) *Scanner {
+	s.stopBits = stopBits
+	return s
+}
+
+// SetCheckpoint 設置掃描進度檢查點檔案，resume 為 true 時從既有檢查點繼續掃描
+func (s *Scanner) SetCheckpoint(path string, resume bool) *Scanner {
+	s.checkpointFile = path
+	s.resume = resume
+	return s
+}
+
+// SetEventBus 設置事件總線，發布掃描開始、發現設備和掃描完成事件
+func (s *Scanner) SetEventBus(bus *EventBus) *Scanner {
+	s.bus = bus
+	return s
+}
+
+// SetByteOrder 設置檢測和解析浮點格式時的字節序 (ABCD/CDAB/BADC/DCBA)，為空為普時達的 CDAB
+//
+// 部分轉換器和固件以大端字序 (ABCD) 提供浮點數，按默認字節序會被判斷為十進制格式或解析出錯誤的壓力值。
+func (s *Scanner) SetByteOrder(order string) *Scanner {
+	s.byteOrder = strings.ToUpper(strings.TrimSpace(order))
+	return s
+}
+
+// floatByteOrder 返回浮點格式的字節序
+func (s *Scanner) floatByteOrder() string {
+	if s.byteOrder == "" {
+		return ByteOrderCDAB
+	}
+	return s.byteOrder
+}
+
+// SetScale 設置解析十進制格式時原始數值到 Pa 的換算係數，0 為普時達的 0.1（擴大 10 倍）
+func (s *Scanner) SetScale(scale float64) *Scanner {
+	s.scale = scale
+	return s
+}
+
+// decimalScale 返回十進制格式的換算係數
+func (s *Scanner) decimalScale() float64 {
+	if s.scale == 0 {
+		return DefaultDecimalScale
+	}
+	return s.scale
+}
+
+// SetIdentify 設置是否讀取已響應設備的設備標識（功能碼 0x2B/0x0E），不支援的設備會多等待一次超時
+func (s *Scanner) SetIdentify(identify bool) *Scanner {
+	s.identify = identify
+	return s
+}
+
+// SetFrameTrace 設置幀跟蹤，記錄掃描時每個探測請求和響應幀
+func (s *Scanner) SetFrameTrace(trace *FrameTrace) *Scanner {
+	s.trace = trace
+	return s
+}
+
 // GetDefaultScanConfig 獲取默認掃描配置
 func GetDefaultScanConfig() ScanConfig {
 	return ScanConfig{
 		SerialPorts:      []string{},                        // 自動檢測
 		SlaveIDs:         generateSlaveIDRange(1, 247),      // 全範圍掃描
 		BaudRates:        []int{9600, 19200, 38400, 115200}, // 常用波特率
-		ScanTimeout:      2 * time.Second,
+		ScanTimeout:      500 * time.Millisecond,            // RTU 響應通常在 100ms 內
+		RetryTimeout:     1500 * time.Millisecond,           // 超時的站點號以 3 倍超時重試一次
 		MaxDevices:       20,
 		AutoDetectFormat: true,
-		Parallel:         false, // 默認串行掃描，避免串口衝突
+		FormatSamples:    DefaultFormatSamples,
+		Parallel:         true, // 不同串口互不干擾，可並行掃描
 		SkipUnresponsive: true,
 	}
 }
@@ -110,49 +346,87 @@ func GetQuickScanConfig() ScanConfig {
 		SlaveIDs:         []byte{0x16, 0x01, 0x02, 0x03, 0x04, 0x05}, // 常用站點號
 		BaudRates:        []int{9600},                                // 只嘗試標準波特率
 		ScanTimeout:      1 * time.Second,
+		RetryTimeout:     2 * time.Second,
 		MaxDevices:       10,
 		AutoDetectFormat: true,
+		FormatSamples:    DefaultFormatSamples,
 		Parallel:         false,
 		SkipUnresponsive: true,
 	}
 }
 
+// GetAutoScanConfig 獲取自動配置使用的掃描配置（快速掃描，找到一個設備即停止）
+func GetAutoScanConfig() ScanConfig {
+	config := GetQuickScanConfig()
+	config.MaxDevices = 1
+	return config
+}
+
 // ScanDevices 掃描壓差儀設備
 func (s *Scanner) ScanDevices(config ScanConfig) (*ScanResult, error) {
 	startTime := time.Now()
 	s.logf("🔍 開始掃描壓差儀設備...")
 
-	result := &ScanResult{
-		Devices: []DeviceInfo{},
-		Config:  config,
+	config, err := s.prepareScanConfig(config)
+	if err != nil {
+		return nil, err
 	}
 
-	serialPorts := config.SerialPorts
+	result := &ScanResult{
+		SchemaVersion: SchemaVersion,
+		Devices:       []DeviceInfo{},
+		Config:        config,
+	}
 
-	// 如果沒有指定串口，自動檢測
-	if len(serialPorts) == 0 {
-		ports, err := s.detectSerialPorts()
-		if err != nil {
-			return nil, fmt.Errorf("自動檢測串口失敗: %v", err)
+	if config.Network != "" {
+		if err := s.scanNetwork(config, result); err != nil {
+			return nil, err
 		}
-		serialPorts = ports
+		return s.finishScan(result, startTime), nil
+	}
+
+	serialPorts, err := s.resolveSerialPorts(config)
+	if err != nil {
+		return nil, err
 	}
 
 	s.logf("📍 發現 %d 個串口設備: %v", len(serialPorts), serialPorts)
+	s.bus.Publish(Event{Type: EventScanStarted, Message: fmt.Sprintf("%s: %v", EventScanStarted.Description(), serialPorts), Data: config})
+
+	var tracker *checkpointTracker
+	if s.checkpointFile != "" {
+		var resumed bool
+		tracker, resumed = newCheckpointTracker(s.checkpointFile, config, s.resume, s.logger)
+		if resumed {
+			s.logf("⏯️  從檢查點恢復掃描: %s", s.checkpointFile)
+		} else if s.resume {
+			s.logf("⚠️  無可用的檢查點，重新開始掃描")
+		}
+	}
 
-	// 掃描每個串口
-	for _, port := range serialPorts {
-		s.logf("🔌 掃描串口: %s", port)
+	// 掃描每個串口，同一串口上的探測始終串行
+	portResults := make([][]DeviceInfo, len(serialPorts))
+	activities := make([]*BusActivity, len(serialPorts))
+	if workers := config.workers(len(serialPorts)); workers > 1 {
+		s.scanPortsParallel(serialPorts, workers, config, tracker, portResults, activities)
+	} else {
+		for i, port := range serialPorts {
+			s.logf("🔌 掃描串口: %s", port)
+			portResults[i], activities[i] = s.probePort(port, config, tracker)
+			if len(s.getResponsiveDevices(portResults[i])) >= config.MaxDevices {
+				break
+			}
+		}
+	}
+	for _, activity := range activities {
+		if activity != nil {
+			result.BusActivity = append(result.BusActivity, *activity)
+		}
+	}
 
-		portDevices := s.scanPort(port, config)
+	for _, portDevices := range portResults {
 		for _, device := range portDevices {
-			if !config.SkipUnresponsive || device.Responsive {
-				result.Devices = append(result.Devices, device)
-			}
-			result.TotalTested++
-			if device.Responsive {
-				result.Successful++
-			}
+			result.addProbe(device, config)
 		}
 
 		if len(result.Devices) >= config.MaxDevices {
@@ -161,11 +435,207 @@ func (s *Scanner) ScanDevices(config ScanConfig) (*ScanResult, error) {
 		}
 	}
 
+	// 掃描完整結束後不再需要檢查點
+	tracker.remove()
+
+	return s.finishScan(result, startTime), nil
+}
+
+// finishScan 記錄掃描耗時，保存掃描緩存並發布掃描完成事件
+func (s *Scanner) finishScan(result *ScanResult, startTime time.Time) *ScanResult {
 	result.ScanTime = time.Since(startTime)
+	if s.cacheFile != "" {
+		if err := SaveScanCache(s.cacheFile, result); err != nil {
+			s.logf("⚠️  保存掃描緩存失敗: %v", err)
+		}
+	}
 	s.logf("✅ 掃描完成，耗時 %v，發現 %d 個響應設備，測試了 %d 個配置",
 		result.ScanTime, result.Successful, result.TotalTested)
+	s.bus.Publish(Event{Type: EventScanCompleted, Message: fmt.Sprintf("%s: 發現 %d 個響應設備", EventScanCompleted.Description(), result.Successful), Data: result})
+	return result
+}
+
+// scanPortsParallel 以 workers 個協程並行掃描串口，結果按串口順序寫入 portResults 和 activities
+//
+// 每個協程一次掃描一個串口，同一串口上的探測仍然串行；已發現的響應設備達到 MaxDevices 後不再開始掃描新的串口。
+func (s *Scanner) scanPortsParallel(ports []string, workers int, config ScanConfig, tracker *checkpointTracker,
+	portResults [][]DeviceInfo, activities []*BusActivity) {
+	s.logf("⚡ 並行掃描 %d 個串口，同時掃描 %d 個", len(ports), workers)
+
+	var found atomic.Int64
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				s.logf("🔌 掃描串口: %s", ports[i])
+				portResults[i], activities[i] = s.probePort(ports[i], config, tracker)
+				responsive := len(s.getResponsiveDevices(portResults[i]))
+				found.Add(int64(responsive))
+				s.logf("🏁 串口 %s 掃描完成，%d 個響應設備", ports[i], responsive)
+			}
+		}()
+	}
+
+	for i := range ports {
+		if found.Load() >= int64(config.MaxDevices) {
+			s.logf("📊 已達到最大設備數量限制 %d，跳過其餘 %d 個串口", config.MaxDevices, len(ports)-i)
+			break
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// prepareScanConfig 套用掃描器的覆蓋設置並驗證掃描配置
+func (s *Scanner) prepareScanConfig(config ScanConfig) (ScanConfig, error) {
+	if len(s.serialPorts) > 0 {
+		config.SerialPorts = s.serialPorts
+	}
+	if len(s.slaveIDs) > 0 {
+		config.SlaveIDs = s.slaveIDs
+	}
+	if len(s.baudRates) > 0 {
+		config.BaudRates = s.baudRates
+	}
+	if len(s.excludePorts) > 0 {
+		config.ExcludePorts = append(append([]string(nil), config.ExcludePorts...), s.excludePorts...)
+	}
+	if s.probeTimeout > 0 {
+		config.ScanTimeout = s.probeTimeout
+	}
+	if s.probeDelay > 0 {
+		config.ProbeDelay = s.probeDelay
+	}
+	if config.ProbeDelay < 0 {
+		return config, fmt.Errorf("無效的探測間隔: %v", config.ProbeDelay)
+	}
+	if s.retryTimeout > 0 {
+		config.RetryTimeout = s.retryTimeout
+	} else if s.retryTimeout < 0 {
+		config.RetryTimeout = 0
+	}
+	if config.RetryTimeout < 0 || config.RetryLimit < 0 {
+		return config, fmt.Errorf("無效的重試設置: 超時 %v，最多 %d 次", config.RetryTimeout, config.RetryLimit)
+	}
+	if s.formatSamples > 0 {
+		config.FormatSamples = s.formatSamples
+	}
+	if config.FormatSamples < 0 {
+		return config, fmt.Errorf("無效的格式檢測採樣次數: %d", config.FormatSamples)
+	}
+	if len(s.parities) > 0 {
+		config.Parities = s.parities
+	}
+	if s.maxParallel > 0 {
+		config.MaxParallel = s.maxParallel
+	}
+	for _, parity := range config.Parities {
+		if !IsValidParity(parity) {
+			return config, fmt.Errorf("無效的校驗位: %s (僅支援 N/E/O)", parity)
+		}
+	}
+	if len(s.stopBits) > 0 {
+		config.StopBits = s.stopBits
+	}
+	if s.identify {
+		config.Identify = true
+	}
+	for _, stopBits := range config.StopBits {
+		if !IsValidStopBits(stopBits) {
+			return config, fmt.Errorf("無效的停止位: %d (僅支援 1/2)", stopBits)
+		}
+	}
+	if s.probeFunction != 0 {
+		config.ProbeFunction = s.probeFunction
+	}
+	if s.probeRegister != 0 {
+		config.ProbeRegister = s.probeRegister
+	}
+	if s.probeCount != 0 {
+		config.ProbeCount = s.probeCount
+	}
+
+	function, register, count := config.probeParams()
+	if function != 0x03 && function != 0x04 {
+		return config, fmt.Errorf("不支援的探測功能碼: 0x%02X (僅支援 0x03/0x04)", function)
+	}
+	if count > 125 {
+		return config, fmt.Errorf("探測寄存器數量過大: %d (最大 125)", count)
+	}
+
+	// 廣播和保留地址不會有響應，從掃描列表中剔除
+	slaveIDs := make([]byte, 0, len(config.SlaveIDs))
+	for _, slaveID := range config.SlaveIDs {
+		if err := CheckPollingSlaveID(slaveID); err != nil {
+			s.logf("⚠️  跳過站點號 %d: %v", slaveID, err)
+			continue
+		}
+		slaveIDs = append(slaveIDs, slaveID)
+	}
+	config.SlaveIDs = slaveIDs
+
+	if !config.isPressureProbe() || function != FunctionCode {
+		s.logf("🔎 探測目標: 功能碼 0x%02X, 寄存器 0x%04X, 數量 %d", function, register, count)
+	}
+	return config, nil
+}
+
+// resolveSerialPorts 返回要掃描的串口，未指定時自動檢測，並剔除排除的串口
+func (s *Scanner) resolveSerialPorts(config ScanConfig) ([]string, error) {
+	ports := config.SerialPorts
+	if len(ports) == 0 {
+		var err error
+		if ports, err = s.detectSerialPorts(); err != nil {
+			return nil, fmt.Errorf("自動檢測串口失敗: %v", err)
+		}
+	}
+	if len(config.ExcludePorts) == 0 {
+		return ports, nil
+	}
 
-	return result, nil
+	included := make([]string, 0, len(ports))
+	for _, port := range ports {
+		if config.isExcludedPort(port) {
+			s.logf("⏭️  跳過排除的串口: %s", port)
+			continue
+		}
+		included = append(included, port)
+	}
+	if len(included) == 0 && len(ports) > 0 {
+		return nil, fmt.Errorf("所有串口 %v 都在排除列表 %v 中", ports, config.ExcludePorts)
+	}
+	return included, nil
+}
+
+// isExcludedPort 串口是否在排除列表中
+//
+// 符號鏈接（如 /dev/serial/by-id/...）按實際設備比較，USB 轉換器重新枚舉後排除仍然有效。
+func (sc ScanConfig) isExcludedPort(port string) bool {
+	names := []string{port}
+	if resolved, err := filepath.EvalSymlinks(port); err == nil && resolved != port {
+		names = append(names, resolved)
+	}
+	for _, pattern := range sc.ExcludePorts {
+		patterns := []string{pattern}
+		if resolved, err := filepath.EvalSymlinks(pattern); err == nil && resolved != pattern {
+			patterns = append(patterns, resolved)
+		}
+		for _, p := range patterns {
+			for _, name := range names {
+				if isWindows() {
+					p, name = strings.ToUpper(p), strings.ToUpper(name)
+				}
+				if matched, _ := filepath.Match(p, name); matched || p == name {
+					return true
+				}
+			}
+		}
+	}
+	return false
 }
 
 // detectSerialPorts 自動檢測系統中的串口設備
@@ -224,28 +694,121 @@ func (s *Scanner) isLikelyRS485Port(port string) bool {
 	return false
 }
 
+// probePort 設置了被動監聽時先確認串口上沒有其他主站，再掃描設備
+func (s *Scanner) probePort(port string, config ScanConfig, tracker *checkpointTracker) ([]DeviceInfo, *BusActivity) {
+	if s.passiveListen <= 0 {
+		return s.scanPort(port, config, tracker), nil
+	}
+	activity, ok := s.checkBus(port, config)
+	if !ok {
+		return nil, activity
+	}
+	return s.scanPort(port, config, tracker), activity
+}
+
 // scanPort 掃描指定串口上的設備
-func (s *Scanner) scanPort(port string, config ScanConfig) []DeviceInfo {
+//
+// 先在各波特率下探測常用站點號以盡快確定總線波特率，一旦有設備響應，
+// 其餘站點號只在該波特率下掃描，不再嘗試其他波特率。
+func (s *Scanner) scanPort(port string, config ScanConfig, tracker *checkpointTracker) []DeviceInfo {
 	var devices []DeviceInfo
 
-	// 嘗試不同的波特率
-	for _, baudRate := range config.BaudRates {
+	baudRates := prioritizeBaudRates(config.BaudRates)
+	commonIDs, otherIDs := splitCommonSlaveIDs(config.SlaveIDs)
+
+	settings := lineSettings(baudRates, config.Parities, config.StopBits)
+
+	// 第一階段：常用站點號 × 所有波特率/校驗位
+	var busSetting *lineSetting
+	var misses []scanMiss
+	sweep := func(setting lineSetting, slaveIDs []byte, phase string) bool {
+		portDevices, missed := s.scanPortWithBaudRate(port, setting, slaveIDs, config, tracker, phase)
+		for _, i := range missed {
+			misses = append(misses, scanMiss{setting: setting, slaveID: portDevices[i].SlaveID, index: len(devices) + i})
+		}
+		devices = append(devices, portDevices...)
+		return s.hasResponsiveDevice(portDevices)
+	}
+	for i, setting := range settings {
+		if len(commonIDs) == 0 {
+			break
+		}
 		if s.verbose {
-			s.logf("  📡 嘗試波特率: %d", baudRate)
+			s.logf("  📡 嘗試波特率: %s (常用站點號)", setting)
+		}
+
+		if sweep(setting, commonIDs, "common") {
+			busSetting = &settings[i]
+			s.logf("  ✅ 在 %s 找到響應設備，跳過其他波特率", setting)
+			break
 		}
+	}
 
-		portDevices := s.scanPortWithBaudRate(port, baudRate, config)
-		if len(portDevices) > 0 {
-			devices = append(devices, portDevices...)
-			// 找到設備後通常不需要繼續嘗試其他波特率
-			if s.hasResponsiveDevice(portDevices) {
-				s.logf("  ✅ 在波特率 %d 找到響應設備，跳過其他波特率", baudRate)
+	// 第二階段：其餘站點號，已確定總線參數時只掃描該參數
+	if len(otherIDs) > 0 {
+		if busSetting != nil {
+			settings = []lineSetting{*busSetting}
+		}
+		for i, setting := range settings {
+			if s.verbose {
+				s.logf("  📡 嘗試波特率: %s", setting)
+			}
+
+			if sweep(setting, otherIDs, "other") {
+				busSetting = &settings[i]
+				s.logf("  ✅ 在 %s 找到響應設備，跳過其他波特率", setting)
 				break
 			}
 		}
 	}
 
-	return devices
+	// 第二輪：以更長的超時重試第一輪超時的站點號，避免偶爾丟失一幀就漏掉正常工作的儀表
+	return s.retryMisses(port, devices, misses, busSetting, config)
+}
+
+// lineSetting 一組串口線路參數
+type lineSetting struct {
+	BaudRate int
+	Parity   string
+	StopBits int // 0 為 1
+}
+
+// String 以 9600 8N1 形式表示
+func (ls lineSetting) String() string {
+	return fmt.Sprintf("%d %s", ls.BaudRate, charFormat(ls.Parity, ls.StopBits))
+}
+
+// phase 檢查點中的掃描階段名稱，8N1 時保持原有名稱、1 位停止位時不加後綴，以兼容舊檢查點
+func (ls lineSetting) phase(phase string) string {
+	twoStopBits := ls.StopBits == 2
+	if ls.Parity == "N" && !twoStopBits {
+		return phase
+	}
+	phase += "/" + ls.Parity
+	if twoStopBits {
+		phase += "2"
+	}
+	return phase
+}
+
+// lineSettings 展開波特率 × 校驗位 × 停止位矩陣，同一波特率的各組合相鄰
+func lineSettings(baudRates []int, parities []string, stopBits []int) []lineSetting {
+	if len(parities) == 0 {
+		parities = []string{"N"}
+	}
+	if len(stopBits) == 0 {
+		stopBits = []int{DefaultStopBits}
+	}
+
+	settings := make([]lineSetting, 0, len(baudRates)*len(parities)*len(stopBits))
+	for _, baudRate := range baudRates {
+		for _, parity := range parities {
+			for _, bits := range stopBits {
+				settings = append(settings, lineSetting{BaudRate: baudRate, Parity: strings.ToUpper(parity), StopBits: bits})
+			}
+		}
+	}
+	return settings
 }
 
 // hasResponsiveDevice 檢查設備列表中是否有響應的設備
@@ -258,18 +821,55 @@ func (s *Scanner) hasResponsiveDevice(devices []DeviceInfo) bool {
 	return false
 }
 
-// scanPortWithBaudRate 使用指定波特率掃描串口
-func (s *Scanner) scanPortWithBaudRate(port string, baudRate int, config ScanConfig) []DeviceInfo {
-	var devices []DeviceInfo
+// scanPortWithBaudRate 使用指定線路參數掃描串口上的指定站點號，tracker 不為空時記錄進度
+//
+// 同時返回探測超時的設備在返回列表中的位置，供第二輪重試。
+func (s *Scanner) scanPortWithBaudRate(port string, setting lineSetting, slaveIDs []byte, config ScanConfig,
+	tracker *checkpointTracker, phase string) ([]DeviceInfo, []int) {
+	baudRate := setting.BaudRate
+	phase = setting.phase(phase)
+
+	// 恢復檢查點中已完成的部分
+	start, devices := tracker.resumeSweep(port, baudRate, phase)
+	if start >= len(slaveIDs) || len(devices) >= config.MaxDevices {
+		return devices, nil
+	}
+	defer tracker.flush()
+	if start > 0 {
+		s.logf("  ⏯️  %s@%s 從第 %d 個站點號繼續", port, setting, start+1)
+	}
+
+	// 同一線路參數下復用串口連接，避免每個站點號重新打開串口
+	handler, err := openScanHandler(port, setting, config.ScanTimeout)
+	if err != nil {
+		s.logf("  ❌ 打開串口 %s 失敗: %v", port, err)
+		return devices, nil
+	}
+	defer handler.Close()
 
 	// 掃描每個從站ID
-	for _, slaveID := range config.SlaveIDs {
-		device := s.testDevice(port, baudRate, slaveID, config)
+	var missed []int
+	for i, slaveID := range slaveIDs[start:] {
+		if i > 0 {
+			config.pause()
+		}
+		device, err := s.testDevice(handler, port, setting, slaveID, config)
+		if ClassifyError(err) == ErrTimeout {
+			missed = append(missed, len(devices))
+		}
 		devices = append(devices, device)
+		tracker.advance(port, baudRate, phase, device)
+		if device.Responsive {
+			s.bus.Publish(Event{Type: EventDeviceFound, Source: port, SlaveID: slaveID, Message: EventDeviceFound.Description(), Data: device})
+		}
 
 		if device.Responsive && s.verbose {
-			s.logf("    🎯 發現設備: 站點=%d, 壓力=%.1f Pa",
-				slaveID, device.LastReading.Pressure)
+			if device.LastReading != nil {
+				s.logf("    🎯 發現設備: 站點=%d, 壓力=%.1f Pa",
+					slaveID, device.LastReading.Pressure)
+			} else {
+				s.logf("    🎯 發現設備: 站點=%d", slaveID)
+			}
 		}
 
 		if len(devices) >= config.MaxDevices {
@@ -277,55 +877,108 @@ func (s *Scanner) scanPortWithBaudRate(port string, baudRate int, config ScanCon
 		}
 	}
 
-	return devices
+	return devices, missed
+}
+
+// openScanHandler 以指定線路參數打開掃描用的串口連接
+func openScanHandler(port string, setting lineSetting, timeout time.Duration) (*modbus.RTUClientHandler, error) {
+	handler := modbus.NewRTUClientHandler(port)
+	handler.BaudRate = setting.BaudRate
+	handler.DataBits = 8
+	handler.Parity = setting.Parity
+	handler.StopBits = setting.StopBits
+	if handler.StopBits == 0 {
+		handler.StopBits = DefaultStopBits
+	}
+	handler.Timeout = timeout
+	if err := connectHandler(handler, timeout); err != nil {
+		return nil, err
+	}
+	return handler, nil
+}
+
+// testDevice 測試串口上的特定設備是否響應，返回設備信息和探測的讀取錯誤
+func (s *Scanner) testDevice(handler *modbus.RTUClientHandler, port string, setting lineSetting, slaveID byte, config ScanConfig) (DeviceInfo, error) {
+	handler.SlaveId = slaveID
+	client, _ := newTracedClient(handler, port, true, s.trace)
+	device, err := s.probeDevice(client, port, slaveID, config)
+	if !device.Responsive {
+		return device, err
+	}
+
+	device.Properties["baud_rate"] = setting.BaudRate
+	device.Properties["parity"] = setting.Parity
+	device.Properties["stop_bits"] = setting.StopBits
+	if config.Identify {
+		model, err := s.readIdentity(handler, port, setting, slaveID, config.ScanTimeout)
+		s.recordIdentity(&device, model, err)
+	}
+	return device, nil
 }
 
-// testDevice 測試特定設備是否響應
-func (s *Scanner) testDevice(port string, baudRate int, slaveID byte, config ScanConfig) DeviceInfo {
+// probeDevice 讀取探測寄存器，響應時檢測數據格式並解析壓力值，返回設備信息和讀取錯誤
+func (s *Scanner) probeDevice(client modbus.Client, endpoint string, slaveID byte, config ScanConfig) (DeviceInfo, error) {
 	device := DeviceInfo{
-		Device:     port,
+		Device:     endpoint,
 		SlaveID:    slaveID,
 		Responsive: false,
 		Properties: make(map[string]interface{}),
 		ScanTime:   time.Now(),
 	}
 
-	// 創建臨時 Modbus 連接
-	handler := modbus.NewRTUClientHandler(port)
-	handler.BaudRate = baudRate
-	handler.DataBits = 8
-	handler.Parity = "N"
-	handler.StopBits = 1
-	handler.SlaveId = slaveID
-	handler.Timeout = config.ScanTimeout
-
-	err := handler.Connect()
-	if err != nil {
-		device.Error = fmt.Sprintf("連接失敗: %v", err)
-		return device
+	// 嘗試讀取探測寄存器（默認為壓力數據）
+	function, register, count := config.probeParams()
+	read := func(count uint16) ([]byte, error) {
+		if function == ModbusFunctionReadInputRegisters {
+			return client.ReadInputRegisters(register, count)
+		}
+		return client.ReadHoldingRegisters(register, count)
+	}
+	results, err := read(count)
+	if err != nil && config.isPressureProbe() && count > 1 && isIllegalAddress(err) {
+		// 緊湊型號只有一個壓力寄存器，讀取兩個寄存器時返回非法地址異常，改為讀取一個
+		count = 1
+		config.pause()
+		results, err = read(count)
 	}
-	defer handler.Close()
-
-	client := modbus.NewClient(handler)
-
-	// 嘗試讀取壓力數據
-	results, err := client.ReadHoldingRegisters(PressureRegisterAddr, RegisterCount)
 	if err != nil {
 		device.Error = fmt.Sprintf("讀取失敗: %v", err)
-		return device
+		return device, err
 	}
 
-	if len(results) == 4 {
+	if len(results) == int(count)*2 {
 		device.Responsive = true
-		device.Properties["baud_rate"] = baudRate
 		device.Properties["response_time"] = time.Since(device.ScanTime)
 
-		// 如果啟用了自動檢測數據格式
-		if config.AutoDetectFormat {
-			dataFormat, confidence := s.detectDataFormat(results)
+		if !config.isPressureProbe() || function != FunctionCode {
+			device.Properties["probe"] = fmt.Sprintf("0x%02X@0x%04X", function, register)
+		}
+		if config.isPressureProbe() && function == ModbusFunctionReadInputRegisters {
+			device.Properties["register_type"] = RegisterTypeInput
+		}
+
+		// 如果啟用了自動檢測數據格式（僅適用於壓力寄存器）
+		if config.AutoDetectFormat && config.isPressureProbe() {
+			samples := [][]byte{results}
+			for len(samples) < config.FormatSamples {
+				time.Sleep(config.sampleInterval())
+				sample, err := read(count)
+				if err != nil || len(sample) != len(results) {
+					s.logf("      ⚠️  格式檢測第 %d 次讀取失敗，使用已有的 %d 次讀數: %v", len(samples)+1, len(samples), err)
+					break
+				}
+				samples = append(samples, sample)
+			}
+			results = samples[len(samples)-1]
+
+			dataFormat, confidence, stability := s.detectDataFormatSamples(samples)
 			device.DataFormat = dataFormat
 			device.Properties["auto_detected_format"] = true
 			device.Properties["format_confidence"] = confidence
+			if len(samples) > 1 {
+				device.Properties["format_samples"] = len(samples)
+				device.Properties["format_stability"] = stability
+			}
 
 			// 創建臨時讀數
 			reading := PressureReading{
@@ -338,38 +991,80 @@ func (s *Scanner) testDevice(port string, baudRate int, slaveID byte, config Sca
 			// 解析壓力值
 			switch dataFormat {
 			case DecimalFormat:
-				reading.Pressure = parseDecimalFormatStatic(results)
+				reading.Pressure = parseDecimalFormatStatic(results, s.decimalScale())
+				if s.decimalScale() != DefaultDecimalScale {
+					device.Properties["scale"] = s.decimalScale()
+				}
 			case FloatFormat:
-				reading.Pressure = parseFloatFormatStatic(results)
+				reading.Pressure = parseFloatFormatStatic(results, s.floatByteOrder())
+				if s.floatByteOrder() != ByteOrderCDAB {
+					device.Properties["byte_order"] = s.floatByteOrder()
+				}
+			case Int16Format, Uint16Format:
+				reading.Pressure = parse16BitFormatStatic(results, dataFormat == Int16Format, s.decimalScale())
+				if s.decimalScale() != DefaultDecimalScale {
+					device.Properties["scale"] = s.decimalScale()
+				}
 			}
 
+			// 浮點解析可能得到 NaN/Inf，不能寫入掃描結果（JSON 無法編碼）
+			if math.IsNaN(reading.Pressure) || math.IsInf(reading.Pressure, 0) {
+				reading.Valid = false
+				reading.Error = fmt.Sprintf("解析結果不是有限數值: %v", reading.Pressure)
+				reading.Pressure = 0
+			}
 			device.LastReading = &reading
 			device.Properties["pressure_pa"] = reading.Pressure
 		}
 
 		// 添加一些診斷信息
-		device.Properties["raw_data"] = fmt.Sprintf("%02X %02X %02X %02X",
-			results[0], results[1], results[2], results[3])
+		device.Properties["raw_data"] = fmt.Sprintf("% X", results)
 	}
 
-	return device
+	return device, nil
+}
+
+// isIllegalAddress 錯誤是否為 Modbus 非法數據地址異常 (0x02)
+func isIllegalAddress(err error) bool {
+	var modbusErr *modbus.ModbusError
+	return errors.As(err, &modbusErr) && modbusErr.ExceptionCode == modbus.ExceptionCodeIllegalDataAddress
 }
 
 // detectDataFormat 自動檢測數據格式，返回格式和置信度
+//
+// 單個寄存器的響應只可能是 16 位整數；兩個寄存器時也比較第一個寄存器按 16 位整數解析的結果，
+// 以識別按兩個寄存器讀取、第二個寄存器填零的緊湊型號。無法區分有符號和無符號，檢測為 int16。
 func (s *Scanner) detectDataFormat(data []byte) (DataFormatType, float64) {
+	if len(data) == 2 {
+		value := parse16BitFormatStatic(data, true, s.decimalScale())
+		confidence := s.calculate16BitConfidence(value, data)
+		s.logf("      📊 格式檢測: 16 位整數=%.1f(置信度%.2f)", value, confidence)
+		return Int16Format, confidence
+	}
+	if len(data) < 4 {
+		return DecimalFormat, 0
+	}
+
 	// 嘗試解析為十進制格式
-	decimalValue := parseDecimalFormatStatic(data)
+	decimalValue := parseDecimalFormatStatic(data, s.decimalScale())
 
 	// 嘗試解析為浮點格式
-	floatValue := parseFloatFormatStatic(data)
+	floatValue := parseFloatFormatStatic(data, s.floatByteOrder())
 
 	// 計算置信度的啟發式規則
 	decimalConfidence := s.calculateDecimalConfidence(decimalValue, data)
 	floatConfidence := s.calculateFloatConfidence(floatValue, data)
 
-	s.logf("      📊 格式檢測: 十進制=%.1f(置信度%.2f), 浮點=%.1f(置信度%.2f)",
-		decimalValue, decimalConfidence, floatValue, floatConfidence)
+	// 嘗試將第一個寄存器解析為 16 位整數
+	int16Value := parse16BitFormatStatic(data, true, s.decimalScale())
+	int16Confidence := s.calculate16BitConfidence(int16Value, data)
 
+	s.logf("      📊 格式檢測: 十進制=%.1f(置信度%.2f), 浮點=%.1f(置信度%.2f), 16 位整數=%.1f(置信度%.2f)",
+		decimalValue, decimalConfidence, floatValue, floatConfidence, int16Value, int16Confidence)
+
+	if int16Confidence > decimalConfidence && int16Confidence > floatConfidence {
+		return Int16Format, int16Confidence
+	}
 	if decimalConfidence > floatConfidence {
 		return DecimalFormat, decimalConfidence
 	}
@@ -379,14 +1074,17 @@ func (s *Scanner) detectDataFormat(data []byte) (DataFormatType, float64) {
 // calculateDecimalConfidence 計算十進制格式的置信度
 func (s *Scanner) calculateDecimalConfidence(value float64, data []byte) float64 {
 	confidence := 0.0
+	if len(data) < 4 {
+		return confidence
+	}
 
 	// 如果值在合理的壓力範圍內 (-10000 到 10000 Pa)
 	if value >= -10000 && value <= 10000 {
 		confidence += 0.5
 	}
 
-	// 如果值是整數或一位小數（十進制格式特點）
-	if value == float64(int(value*10))/10 {
+	// 如果值是換算係數的整數倍（十進制格式特點，默認為整數或一位小數）
+	if raw := value / s.decimalScale(); math.Abs(raw-math.Round(raw)) < 1e-6 {
 		confidence += 0.3
 	}
 
@@ -398,9 +1096,44 @@ func (s *Scanner) calculateDecimalConfidence(value float64, data []byte) float64
 	return confidence
 }
 
+// calculate16BitConfidence 計算 16 位整數格式的置信度
+//
+// 兩個寄存器時最高只有 0.8，十進制格式的正常讀數（第一個寄存器為 0x0000 或 0xFFFF）仍然優先。
+func (s *Scanner) calculate16BitConfidence(value float64, data []byte) float64 {
+	confidence := 0.0
+	if len(data) < 2 {
+		return confidence
+	}
+
+	// 如果值在合理的壓力範圍內 (-10000 到 10000 Pa)
+	if value >= -10000 && value <= 10000 {
+		confidence += 0.5
+	}
+
+	if len(data) < 4 {
+		// 不足兩個寄存器只可能是 16 位整數
+		return confidence + 0.3
+	}
+
+	// 第一個寄存器不是 32 位整數的符號擴展（0x0000 或 0xFFFF）
+	if high := binary.BigEndian.Uint16(data); high != 0x0000 && high != 0xFFFF {
+		confidence += 0.2
+	}
+
+	// 第二個寄存器填零
+	if binary.BigEndian.Uint16(data[2:]) == 0 {
+		confidence += 0.1
+	}
+
+	return confidence
+}
+
 // calculateFloatConfidence 計算浮點格式的置信度
 func (s *Scanner) calculateFloatConfidence(value float64, data []byte) float64 {
 	confidence := 0.0
+	if len(data) < 4 {
+		return confidence
+	}
 
 	// 如果值在合理範圍內
 	if value >= -10000 && value <= 10000 && !math.IsNaN(value) && !math.IsInf(value, 0) {
@@ -413,13 +1146,7 @@ func (s *Scanner) calculateFloatConfidence(value float64, data []byte) float64 {
 	}
 
 	// 檢查 IEEE 754 格式的合理性
-	ieeeBytes := make([]byte, 4)
-	ieeeBytes[0] = data[2]
-	ieeeBytes[1] = data[3]
-	ieeeBytes[2] = data[0]
-	ieeeBytes[3] = data[1]
-
-	bits := binary.BigEndian.Uint32(ieeeBytes)
+	bits := binary.BigEndian.Uint32(reorderBytes(data[:4], s.floatByteOrder()))
 	exponent := (bits >> 23) & 0xFF
 
 	// 正常的指數範圍
@@ -434,9 +1161,8 @@ func (s *Scanner) calculateFloatConfidence(value float64, data []byte) float64 {
 func (s *Scanner) AutoConfigure() (*Config, error) {
 	s.logf("🚀 開始自動配置...")
 
-	scanConfig := GetQuickScanConfig() // 使用快速掃描
-	scanConfig.MaxDevices = 1          // 只需要找到一個設備
-
+	scanConfig := s.PresetConfig(GetAutoScanConfig())
+	scanConfig.MaxDevices = 1 // 只使用第一個找到的設備
 	result, err := s.ScanDevices(scanConfig)
 	if err != nil {
 		return nil, fmt.Errorf("掃描設備失敗: %v", err)
@@ -454,11 +1180,18 @@ func (s *Scanner) AutoConfigure() (*Config, error) {
 		SlaveID:      device.SlaveID,
 		ReadInterval: time.Second,
 		DataFormat:   device.DataFormat,
+		Parity:       DeviceParity(device),
+		StopBits:     DeviceStopBits(device),
+		BaudRate:     DeviceBaudRate(device),
+		RegisterType: DeviceRegisterType(device),
+		ByteOrder:    DeviceByteOrder(device),
+		Scale:        DeviceScale(device),
 		Logger:       s.logger,
 	}
+	config.UseScannedEndpoint(device)
 
 	s.logf("✅ 自動配置完成: 設備=%s, 站點=%d, 格式=%v",
-		config.Device, config.SlaveID, config.DataFormat)
+		config.Endpoint(), config.SlaveID, config.DataFormat)
 
 	return config, nil
 }
@@ -466,13 +1199,13 @@ func (s *Scanner) AutoConfigure() (*Config, error) {
 // QuickScan 快速掃描（僅掃描常用設備和參數）
 func (s *Scanner) QuickScan() (*ScanResult, error) {
 	s.logf("⚡ 開始快速掃描...")
-	return s.ScanDevices(GetQuickScanConfig())
+	return s.ScanDevices(s.PresetConfig(GetQuickScanConfig()))
 }
 
 // FullScan 完整掃描
 func (s *Scanner) FullScan() (*ScanResult, error) {
 	s.logf("🔍 開始完整掃描...")
-	return s.ScanDevices(GetDefaultScanConfig())
+	return s.ScanDevices(s.PresetConfig(GetDefaultScanConfig()))
 }
 
 // getResponsiveDevices 獲取響應的設備列表
@@ -486,54 +1219,103 @@ func (s *Scanner) getResponsiveDevices(devices []DeviceInfo) []DeviceInfo {
 	return responsive
 }
 
-// PrintScanResults 打印掃描結果
-func (s *Scanner) PrintScanResults(result *ScanResult) {
-	fmt.Println("=" + strings.Repeat("=", 50))
-	fmt.Printf("📊 掃描結果 (耗時: %v)\n", result.ScanTime)
-	fmt.Printf("🎯 測試了 %d 個配置，發現 %d 個響應設備\n", result.TotalTested, result.Successful)
-	fmt.Println("=" + strings.Repeat("=", 50))
+// PrintScanResults 將掃描結果寫入 w
+func (s *Scanner) PrintScanResults(w io.Writer, result *ScanResult) {
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+	fmt.Fprintf(w, "📊 掃描結果 (耗時: %v)\n", result.ScanTime)
+	fmt.Fprintf(w, "🎯 測試了 %d 個配置，發現 %d 個響應設備\n", result.TotalTested, result.Successful)
+	if result.Incremental {
+		fmt.Fprintln(w, "⚡ 增量掃描: 緩存中的已知設備全部響應，未執行完整掃描")
+	}
+	if result.Marginal > 0 {
+		fmt.Fprintf(w, "🔁 其中 %d 個設備第一次探測超時、重試後才響應，請檢查接線、終端電阻和干擾\n", result.Marginal)
+	}
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+
+	for _, activity := range result.BusActivity {
+		switch {
+		case activity.MasterDetected() && !activity.Probed:
+			fmt.Fprintf(w, "⛔ 已有主站，未探測: %s\n", activity)
+		case activity.MasterDetected():
+			fmt.Fprintf(w, "⚠️  已有主站，仍然探測: %s\n", activity)
+		case activity.Error != "" && !activity.Probed:
+			fmt.Fprintf(w, "⛔ 未探測: %s\n", activity)
+		}
+	}
 
 	responsiveDevices := s.getResponsiveDevices(result.Devices)
 
 	if len(responsiveDevices) == 0 {
-		fmt.Println("❌ 未找到任何響應的設備")
-		fmt.Println("\n💡 建議:")
-		fmt.Println("   - 檢查設備是否正確連接")
-		fmt.Println("   - 確認設備電源是否開啟")
-		fmt.Println("   - 檢查 RS485 接線是否正確")
-		fmt.Println("   - 嘗試不同的波特率或站點號")
+		fmt.Fprintln(w, "❌ 未找到任何響應的設備")
+		fmt.Fprintln(w, "\n💡 建議:")
+		fmt.Fprintln(w, "   - 檢查設備是否正確連接")
+		fmt.Fprintln(w, "   - 確認設備電源是否開啟")
+		fmt.Fprintln(w, "   - 檢查 RS485 接線是否正確")
+		fmt.Fprintln(w, "   - 嘗試不同的波特率或站點號")
 		return
 	}
 
 	for i, device := range responsiveDevices {
-		fmt.Printf("\n🔌 設備 %d:\n", i+1)
-		fmt.Printf("   串口: %s\n", device.Device)
-		fmt.Printf("   站點號: %d (0x%02X)\n", device.SlaveID, device.SlaveID)
+		fmt.Fprintf(w, "\n🔌 設備 %d:\n", i+1)
+		if DeviceTransport(device) == TransportTCP {
+			fmt.Fprintf(w, "   地址: %s (Modbus TCP)\n", device.Device)
+		} else {
+			fmt.Fprintf(w, "   串口: %s\n", device.Device)
+		}
+		fmt.Fprintf(w, "   站點號: %d (0x%02X)\n", device.SlaveID, device.SlaveID)
+		if device.Marginal {
+			fmt.Fprintln(w, "   ⚠️  通信不穩定: 第一次探測超時，以更長的超時重試後才響應")
+		}
+		if device.Location != nil {
+			fmt.Fprintf(w, "   位置: %s\n", device.Location)
+		}
+		if device.Model != nil {
+			fmt.Fprintf(w, "   型號: %s\n", device.Model)
+			if DeviceIsForeign(device) {
+				fmt.Fprintln(w, "   ⚠️  不是普時達儀表，只是在壓力寄存器地址響應，請確認後再使用")
+			}
+		}
 
 		if baudRate, ok := device.Properties["baud_rate"]; ok {
-			fmt.Printf("   波特率: %v\n", baudRate)
+			fmt.Fprintf(w, "   波特率: %v\n", baudRate)
+		}
+		if parity, ok := device.Properties["parity"]; ok {
+			fmt.Fprintf(w, "   校驗位: %v\n", parity)
+		}
+		if stopBits := DeviceStopBits(device); stopBits != 0 {
+			fmt.Fprintf(w, "   停止位: %d\n", stopBits)
 		}
 
-		fmt.Printf("   數據格式: %s", formatToString(device.DataFormat))
+		fmt.Fprintf(w, "   數據格式: %s", formatToString(device.DataFormat))
+		if byteOrder := DeviceByteOrder(device); byteOrder != "" {
+			fmt.Fprintf(w, " %s", byteOrder)
+		}
+		if scale := DeviceScale(device); scale != 0 {
+			fmt.Fprintf(w, " ×%g", scale)
+		}
 		if confidence, ok := device.Properties["format_confidence"]; ok {
-			fmt.Printf(" (置信度: %.2f)", confidence)
+			fmt.Fprintf(w, " (置信度: %.2f", confidence)
+			if samples, ok := device.Properties["format_samples"]; ok {
+				fmt.Fprintf(w, "，%v 次採樣", samples)
+			}
+			fmt.Fprint(w, ")")
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 
 		if device.LastReading != nil {
-			fmt.Printf("   當前壓力: %.2f Pa\n", device.LastReading.Pressure)
+			fmt.Fprintf(w, "   當前壓力: %.2f Pa\n", device.LastReading.Pressure)
 		}
 
 		if rawData, ok := device.Properties["raw_data"]; ok {
-			fmt.Printf("   原始數據: %v\n", rawData)
+			fmt.Fprintf(w, "   原始數據: %v\n", rawData)
 		}
 
 		if responseTime, ok := device.Properties["response_time"]; ok {
-			fmt.Printf("   響應時間: %v\n", responseTime)
+			fmt.Fprintf(w, "   響應時間: %v\n", responseTime)
 		}
 	}
 
-	fmt.Println("\n" + strings.Repeat("=", 52))
+	fmt.Fprintln(w, "\n"+strings.Repeat("=", 52))
 }
 
 // logf 帶條件的日誌輸出
@@ -545,6 +1327,111 @@ func (s *Scanner) logf(format string, args ...interface{}) {
 
 // 輔助函數
 
+// prioritizeBaudRates 將默認波特率排在最前，其餘保持原有順序
+func prioritizeBaudRates(baudRates []int) []int {
+	ordered := make([]int, 0, len(baudRates))
+	for _, rate := range baudRates {
+		if rate == DefaultBaudRate {
+			ordered = append(ordered, rate)
+		}
+	}
+	for _, rate := range baudRates {
+		if rate != DefaultBaudRate {
+			ordered = append(ordered, rate)
+		}
+	}
+	return ordered
+}
+
+// splitCommonSlaveIDs 將站點號分為常用站點號和其餘站點號，常用站點號按 GetCommonSlaveIDs 排序
+func splitCommonSlaveIDs(slaveIDs []byte) (common, other []byte) {
+	requested := make(map[byte]bool, len(slaveIDs))
+	for _, id := range slaveIDs {
+		requested[id] = true
+	}
+
+	isCommon := make(map[byte]bool)
+	for _, id := range append([]byte{DefaultSlaveID}, GetCommonSlaveIDs()...) {
+		if requested[id] && !isCommon[id] {
+			isCommon[id] = true
+			common = append(common, id)
+		}
+	}
+
+	for _, id := range slaveIDs {
+		if !isCommon[id] {
+			other = append(other, id)
+		}
+	}
+	return common, other
+}
+
+// quickSlaveIDs 返回默認站點號和常用站點號，用於網絡掃描和後台發現
+func quickSlaveIDs() []byte {
+	slaveIDs := []byte{DefaultSlaveID}
+	for _, id := range GetCommonSlaveIDs() {
+		if id != DefaultSlaveID {
+			slaveIDs = append(slaveIDs, id)
+		}
+	}
+	return slaveIDs
+}
+
+// DeviceParity 從設備屬性中取出掃描時使用的校驗位
+func DeviceParity(device DeviceInfo) string {
+	if parity, ok := device.Properties["parity"].(string); ok && parity != DefaultParity {
+		return parity
+	}
+	return ""
+}
+
+// DeviceStopBits 從設備屬性中取出掃描時使用的停止位，1 位（默認）時為 0
+func DeviceStopBits(device DeviceInfo) int {
+	switch v := device.Properties["stop_bits"].(type) {
+	case int:
+		if v != DefaultStopBits {
+			return v
+		}
+	case float64:
+		if int(v) != DefaultStopBits {
+			return int(v)
+		}
+	}
+	return 0
+}
+
+// DeviceBaudRate 從設備屬性中取出掃描到的波特率，DefaultBaudRate（默認）時為 0
+func DeviceBaudRate(device DeviceInfo) int {
+	if baudRate, ok := deviceBaudRate(device); ok && baudRate != DefaultBaudRate {
+		return baudRate
+	}
+	return 0
+}
+
+// DeviceRegisterType 從設備屬性中取出掃描時壓力寄存器的類型，保持寄存器（默認）時為空
+func DeviceRegisterType(device DeviceInfo) string {
+	if registerType, ok := device.Properties["register_type"].(string); ok {
+		return registerType
+	}
+	return ""
+}
+
+// DeviceByteOrder 從設備屬性中取出掃描時浮點格式的字節序，普時達的 CDAB（默認）或十進制格式時為空
+func DeviceByteOrder(device DeviceInfo) string {
+	if byteOrder, ok := device.Properties["byte_order"].(string); ok && device.DataFormat == FloatFormat {
+		return byteOrder
+	}
+	return ""
+}
+
+// DeviceScale 從設備屬性中取出掃描時整數格式的換算係數，普時達的 0.1（默認）或浮點格式時為 0
+func DeviceScale(device DeviceInfo) float64 {
+	if scale, ok := device.Properties["scale"].(float64); ok && device.DataFormat != FloatFormat {
+		return scale
+	}
+	return 0
+}
+
 // generateSlaveIDRange 生成從站ID範圍
 func generateSlaveIDRange(start, end int) []byte {
 	var ids []byte
@@ -556,24 +1443,33 @@ func generateSlaveIDRange(start, end int) []byte {
 
 // 靜態解析函數（不依賴 PressureMeter 實例）
 
-// parseDecimalFormatStatic 靜態解析十進制格式
-func parseDecimalFormatStatic(data []byte) float64 {
-	value := int32(binary.BigEndian.Uint32(data))
-	if data[0] == 0xFF || (uint32(value)&0x80000000) == 0x80000000 {
-		return float64(value) / 10.0
+// parseDecimalFormatStatic 按換算係數靜態解析十進制格式（32 位有符號整數）
+func parseDecimalFormatStatic(data []byte, scale float64) float64 {
+	if len(data) < 4 {
+		return 0
 	}
-	return float64(value) / 10.0
+	value := int32(binary.BigEndian.Uint32(data))
+	return float64(value) * scale
 }
 
-// parseFloatFormatStatic 靜態解析浮點格式
-func parseFloatFormatStatic(data []byte) float64 {
-	ieeeBytes := make([]byte, 4)
-	ieeeBytes[0] = data[2]
-	ieeeBytes[1] = data[3]
-	ieeeBytes[2] = data[0]
-	ieeeBytes[3] = data[1]
+// parse16BitFormatStatic 按換算係數靜態解析第一個寄存器的 16 位整數格式
+func parse16BitFormatStatic(data []byte, signed bool, scale float64) float64 {
+	if len(data) < 2 {
+		return 0
+	}
+	value := binary.BigEndian.Uint16(data)
+	if signed {
+		return float64(int16(value)) * scale
+	}
+	return float64(value) * scale
+}
 
-	bits := binary.BigEndian.Uint32(ieeeBytes)
+// parseFloatFormatStatic 按字節序 (ABCD/CDAB/BADC/DCBA) 靜態解析浮點格式
+func parseFloatFormatStatic(data []byte, order string) float64 {
+	if len(data) < 4 {
+		return 0
+	}
+	bits := binary.BigEndian.Uint32(reorderBytes(data[:4], order))
 	pressure := math.Float32frombits(bits)
 
 	// 檢查是否為有效的浮點數
@@ -0,0 +1,163 @@
+// pressure/scanplan.go - 掃描計劃預覽，不訪問總線
+package pressure
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// PlannedSweep 計劃中的一次掃描序列（一組線路參數 + 站點號列表）
+type PlannedSweep struct {
+	Phase    string `json:"phase"`     // 階段: common=常用站點號, other=其餘站點號
+	BaudRate int    `json:"baud_rate"` // 波特率
+	Parity   string `json:"parity"`    // 校驗位
+	StopBits int    `json:"stop_bits"` // 停止位
+	SlaveIDs []byte `json:"slave_ids"` // 依次探測的站點號
+}
+
+// ScanPlan 掃描計劃，列出實際掃描時會探測的全部組合
+//
+// 每個串口的掃描序列相同。實際掃描中一旦在某組線路參數下發現設備，
+// 其他線路參數會被跳過，所以計劃給出的是探測次數和耗時的上限。
+type ScanPlan struct {
+	Config            ScanConfig     `json:"config"`                   // 套用覆蓋設置後的掃描配置
+	Ports             []string       `json:"ports"`                    // 要掃描的串口
+	Sweeps            []PlannedSweep `json:"sweeps"`                   // 每個串口上的掃描序列
+	ProbesPerPort     int            `json:"probes_per_port"`          // 每個串口最多探測次數
+	RetriesPerPort    int            `json:"retries_per_port"`         // 每個串口第二輪最多重試次數
+	TotalProbes       int            `json:"total_probes"`             // 全部串口最多探測次數
+	PassiveListen     time.Duration  `json:"passive_listen,omitempty"` // 每個串口探測前被動監聽的最長時間
+	Workers           int            `json:"workers"`                  // 同時掃描的串口數
+	EstimatedDuration time.Duration  `json:"estimated_duration"`       // 所有探測都超時時的最長耗時
+}
+
+// PlanScan 生成掃描計劃但不打開任何串口
+//
+// 未指定串口時只列出系統中的串口，不會發送任何數據。
+func (s *Scanner) PlanScan(config ScanConfig) (*ScanPlan, error) {
+	config, err := s.prepareScanConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Network != "" {
+		return nil, fmt.Errorf("網絡掃描不支援掃描計劃預覽")
+	}
+
+	ports, err := s.resolveSerialPorts(config)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &ScanPlan{Config: config, Ports: ports}
+
+	settings := lineSettings(prioritizeBaudRates(config.BaudRates), config.Parities, config.StopBits)
+	commonIDs, otherIDs := splitCommonSlaveIDs(config.SlaveIDs)
+	for _, phase := range []struct {
+		name     string
+		slaveIDs []byte
+	}{{"common", commonIDs}, {"other", otherIDs}} {
+		if len(phase.slaveIDs) == 0 {
+			continue
+		}
+		for _, setting := range settings {
+			plan.Sweeps = append(plan.Sweeps, PlannedSweep{
+				Phase:    phase.name,
+				BaudRate: setting.BaudRate,
+				Parity:   setting.Parity,
+				StopBits: setting.StopBits,
+				SlaveIDs: phase.slaveIDs,
+			})
+			plan.ProbesPerPort += len(phase.slaveIDs)
+		}
+	}
+
+	plan.TotalProbes = plan.ProbesPerPort * len(ports)
+	if config.RetryTimeout > 0 {
+		plan.RetriesPerPort = config.RetryLimit
+		if plan.RetriesPerPort <= 0 {
+			plan.RetriesPerPort = DefaultScanRetryLimit
+		}
+		if plan.RetriesPerPort > plan.ProbesPerPort {
+			plan.RetriesPerPort = plan.ProbesPerPort
+		}
+	}
+
+	// 被動監聽在每個波特率下進行，沒有其他主站時全部聽完
+	plan.PassiveListen = s.passiveListen * time.Duration(len(config.BaudRates))
+
+	// 同一串口上的探測串行執行，並行掃描時每輪同時掃描 Workers 個串口
+	portDuration := time.Duration(plan.ProbesPerPort)*(config.ScanTimeout+config.ProbeDelay) +
+		time.Duration(plan.RetriesPerPort)*(config.RetryTimeout+config.ProbeDelay) + plan.PassiveListen
+	plan.Workers = config.workers(len(ports))
+	rounds := (len(ports) + plan.Workers - 1) / plan.Workers
+	plan.EstimatedDuration = portDuration * time.Duration(rounds)
+
+	return plan, nil
+}
+
+// Print 將掃描計劃寫入 w
+func (sp *ScanPlan) Print(w io.Writer) {
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+	fmt.Fprintln(w, "📋 掃描計劃（預覽，不會訪問總線）")
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+
+	function, register, count := sp.Config.probeParams()
+	fmt.Fprintf(w, "🔎 探測目標: 功能碼 0x%02X, 寄存器 0x%04X, 數量 %d\n", function, register, count)
+
+	mode := "依次掃描"
+	if sp.Workers > 1 {
+		mode = fmt.Sprintf("並行掃描，同時 %d 個", sp.Workers)
+	}
+	if len(sp.Ports) == 0 {
+		fmt.Fprintln(w, "🔌 串口: 無（未檢測到串口設備）")
+	} else {
+		fmt.Fprintf(w, "🔌 串口 (%d, %s): %s\n", len(sp.Ports), mode, strings.Join(sp.Ports, ", "))
+	}
+
+	if sp.PassiveListen > 0 {
+		fmt.Fprintf(w, "👂 探測前被動監聽: 每個串口最多 %v，發現其他主站時不探測 (除非 --force)\n", sp.PassiveListen)
+	}
+
+	fmt.Fprintln(w, "\n📡 每個串口的掃描序列:")
+	for i, sweep := range sp.Sweeps {
+		phase := "常用站點號"
+		if sweep.Phase == "other" {
+			phase = "其餘站點號"
+		}
+		setting := lineSetting{BaudRate: sweep.BaudRate, Parity: sweep.Parity, StopBits: sweep.StopBits}
+		fmt.Fprintf(w, "   %2d. %-12s %-10s 站點號 %s (%d 個)\n",
+			i+1, setting, phase, formatSlaveIDRanges(sweep.SlaveIDs), len(sweep.SlaveIDs))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "🎯 最多探測 %d 次 (每個串口 %d 次)，單次超時 %v\n",
+		sp.TotalProbes, sp.ProbesPerPort, sp.Config.ScanTimeout)
+	if sp.Config.ProbeDelay > 0 {
+		fmt.Fprintf(w, "🐢 探測間隔: %v\n", sp.Config.ProbeDelay)
+	}
+	if sp.RetriesPerPort > 0 {
+		fmt.Fprintf(w, "🔁 第二輪: 超時的站點號以 %v 超時重試，每個串口最多 %d 次\n", sp.Config.RetryTimeout, sp.RetriesPerPort)
+	}
+	fmt.Fprintf(w, "⏱️  預計最長耗時: %v\n", sp.EstimatedDuration.Round(time.Second))
+	fmt.Fprintln(w, "💡 發現設備後會跳過其他波特率，實際耗時通常更短")
+}
+
+// formatSlaveIDRanges 將站點號列表壓縮為 1-5, 22 形式，保持原有順序
+func formatSlaveIDRanges(slaveIDs []byte) string {
+	var parts []string
+	for i := 0; i < len(slaveIDs); {
+		j := i
+		for j+1 < len(slaveIDs) && slaveIDs[j+1] == slaveIDs[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", slaveIDs[i], slaveIDs[j]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d", slaveIDs[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
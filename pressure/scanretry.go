@@ -0,0 +1,104 @@
+// pressure/scanretry.go - 掃描第二輪重試：以更長的超時重新探測第一輪超時的站點號，找回偶爾丟幀的儀表
+package pressure
+
+import (
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// DefaultScanRetryLimit 每個串口第二輪默認最多重試的探測數，避免空串口上的重試拖長掃描
+const DefaultScanRetryLimit = 16
+
+// scanMiss 第一輪探測超時的站點號
+type scanMiss struct {
+	setting lineSetting
+	slaveID byte
+	index   int // 在串口掃描結果中的位置
+}
+
+// SetRetryTimeout 設置第二輪重試的探測超時，覆蓋掃描配置；負數為不重試
+func (s *Scanner) SetRetryTimeout(timeout time.Duration) *Scanner {
+	s.retryTimeout = timeout
+	return s
+}
+
+// retryMisses 以 RetryTimeout 重新探測第一輪超時的站點號，響應的設備替換原結果並標記為 Marginal
+//
+// 已確定總線參數時只重試該參數下的站點號；按第一輪的順序（常用站點號在前）最多重試 RetryLimit 個。
+// 重試中發現設備後，其餘線路參數下的站點號不再重試。
+func (s *Scanner) retryMisses(port string, devices []DeviceInfo, misses []scanMiss, busSetting *lineSetting, config ScanConfig) []DeviceInfo {
+	if config.RetryTimeout <= 0 || len(misses) == 0 {
+		return devices
+	}
+	if len(s.getResponsiveDevices(devices)) >= config.MaxDevices {
+		return devices
+	}
+
+	var pending []scanMiss
+	for _, miss := range misses {
+		if busSetting == nil || miss.setting == *busSetting {
+			pending = append(pending, miss)
+		}
+	}
+	limit := config.RetryLimit
+	if limit <= 0 {
+		limit = DefaultScanRetryLimit
+	}
+	if len(pending) > limit {
+		s.logf("  🔁 %d 個站點號探測超時，只重試前 %d 個", len(pending), limit)
+		pending = pending[:limit]
+	}
+	if len(pending) == 0 {
+		return devices
+	}
+	s.logf("  🔁 第二輪: 以 %v 超時重試 %d 個超時的站點號", config.RetryTimeout, len(pending))
+
+	retryConfig := config
+	retryConfig.ScanTimeout = config.RetryTimeout
+
+	var handler *modbus.RTUClientHandler
+	var open lineSetting
+	defer func() {
+		if handler != nil {
+			handler.Close()
+		}
+	}()
+
+	for i, miss := range pending {
+		if busSetting != nil && miss.setting != *busSetting {
+			continue
+		}
+		if handler == nil || miss.setting != open {
+			if handler != nil {
+				handler.Close()
+				handler = nil
+			}
+			var err error
+			if handler, err = openScanHandler(port, miss.setting, config.RetryTimeout); err != nil {
+				s.logf("  ❌ 打開串口 %s 失敗: %v", port, err)
+				return devices
+			}
+			open = miss.setting
+		} else if i > 0 {
+			config.pause()
+		}
+
+		device, _ := s.testDevice(handler, port, miss.setting, miss.slaveID, retryConfig)
+		if !device.Responsive {
+			continue
+		}
+
+		device.Marginal = true
+		devices[miss.index] = device
+		setting := miss.setting
+		busSetting = &setting
+		s.bus.Publish(Event{Type: EventDeviceFound, Source: port, SlaveID: miss.slaveID, Message: EventDeviceFound.Description(), Data: device})
+		s.logf("    🎯 重試後發現設備: 站點=%d (%s)，第一次探測超時，通信可能不穩定", miss.slaveID, miss.setting)
+
+		if len(s.getResponsiveDevices(devices)) >= config.MaxDevices {
+			break
+		}
+	}
+	return devices
+}
@@ -0,0 +1,193 @@
+// pressure/schema.go - JSON 輸出格式版本和結構描述
+package pressure
+
+// SchemaVersion JSON 輸出格式版本
+//
+// 兼容性策略：
+//   - 次版本號 (1.x) 遞增：只新增字段，既有字段的名稱、類型和含義不變，
+//     下游應忽略不認識的字段。
+//   - 主版本號 (x.0) 遞增：刪除、改名或改變字段含義，下游需要遷移。
+const SchemaVersion = "1.1"
+
+// 各類 JSON 輸出的結構名稱
+const (
+	SchemaReading     = "reading"
+	SchemaStatus      = "status"
+	SchemaScanResult  = "scan_result"
+	SchemaDiagnostics = "diagnostics"
+	SchemaValue       = "value"
+	SchemaSnapshot    = "status_snapshot"
+	SchemaBandEvent   = "band_event"
+	SchemaHistory     = "history"
+)
+
+// JSONSchemas 返回當前版本所有 JSON 輸出的 JSON Schema 描述
+func JSONSchemas() map[string]interface{} {
+	return map[string]interface{}{
+		"schema_version": SchemaVersion,
+		"schemas": map[string]interface{}{
+			SchemaReading:     readingSchema(),
+			SchemaStatus:      statusSchema(),
+			SchemaScanResult:  scanResultSchema(),
+			SchemaDiagnostics: diagnosticsSchema(),
+			SchemaValue:       valueSchema(),
+			SchemaSnapshot:    snapshotSchema(),
+			SchemaBandEvent:   bandEventSchema(),
+			SchemaHistory:     historySchema(),
+		},
+	}
+}
+
+// schemaObject 構造 JSON Schema 物件描述
+func schemaObject(title string, required []string, properties map[string]interface{}) map[string]interface{} {
+	properties["schema_version"] = schemaField("string", "輸出格式版本，如 "+SchemaVersion)
+	return map[string]interface{}{
+		"$schema":    "https://json-schema.org/draft/2020-12/schema",
+		"title":      title,
+		"type":       "object",
+		"required":   append([]string{"schema_version"}, required...),
+		"properties": properties,
+	}
+}
+
+// schemaField 構造單個字段描述
+func schemaField(fieldType, description string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":        fieldType,
+		"description": description,
+	}
+}
+
+func readingSchema() map[string]interface{} {
+	return schemaObject("壓力讀數", []string{"timestamp", "count", "slave_id", "valid"}, map[string]interface{}{
+		"timestamp":        schemaField("string", "讀取時間 (RFC 3339)，取值時刻由 timestampsource 配置決定"),
+		"duration_ms":      schemaField("number", "Modbus 請求到響應的耗時（毫秒），1.1 新增"),
+		"count":            schemaField("integer", "本次運行的讀數序號"),
+		"slave_id":         schemaField("integer", "Modbus 站點號"),
+		"pressure":         schemaField("number", "壓力值，僅 valid 為 true 時存在；配置了溫度補償時為補償後的值"),
+		"raw_pressure":     schemaField("number", "未補償的壓力值，僅配置了溫度補償時存在，1.1 新增"),
+		"temperature":      schemaField("number", "儀表溫度 (°C)，僅配置了溫度寄存器時存在，1.1 新增"),
+		"unit":             schemaField("string", "壓力單位"),
+		"valid":            schemaField("boolean", "讀數是否有效"),
+		"degraded":         schemaField("boolean", "讀數有效但耗時超出延遲預算，僅為 true 時存在，1.1 新增"),
+		"retries":          schemaField("integer", "本次讀取的重試次數，僅發生重試時存在，1.1 新增"),
+		"scale":            schemaField("number", "原始數值到 Pa 的換算係數 (如 0.1 為擴大 10 倍)，解析出壓力值時存在，1.1 新增"),
+		"raw_data":         schemaField("string", "原始寄存器數據（十六進制，如 \"00 00 04 D2\"），僅啟用 rawdata 或 --verbose 時存在，1.1 新增"),
+		"error":            schemaField("string", "錯誤信息，僅 valid 為 false 時存在"),
+		"error_code":       schemaField("string", "錯誤代碼（如 timeout、protocol、device_busy、connection、out_of_range），僅 valid 為 false 時存在，1.1 新增"),
+		"ambient_pressure": schemaField("number", "大氣壓參考 (hPa)，僅配置了大氣壓來源且最近刷新成功時存在，1.1 新增"),
+		"deviation":        schemaField("number", "與設定值的偏差 (Pa)，僅配置了設定值且讀數有效時存在，1.1 新增"),
+		"location":         schemaField("object", "安裝位置 (port/slave_id/room/floor/asset_tag)，僅使用 --locations 且找到設備時存在，1.1 新增"),
+	})
+}
+
+func statusSchema() map[string]interface{} {
+	return schemaObject("設備狀態", []string{"running", "slave_id"}, map[string]interface{}{
+		"running":              schemaField("boolean", "是否正在連續讀取"),
+		"slave_id":             schemaField("integer", "Modbus 站點號"),
+		"transport":            schemaField("string", "傳輸方式 (rtu/tcp/rtuovertcp)，1.1 新增"),
+		"endpoint":             schemaField("string", "串口或網關地址，1.1 新增"),
+		"connected":            schemaField("boolean", "連接是否已打開，降級模式下未連上設備時為 false，1.1 新增"),
+		"device_status":        schemaField("string", "設備狀態 (connecting/running/error/disconnected)，1.1 新增"),
+		"status_since":         schemaField("string", "進入當前設備狀態的時間 (RFC 3339)，1.1 新增"),
+		"uptime_ms":            schemaField("number", "設備實例創建以來的運行時長（毫秒），1.1 新增"),
+		"model":                schemaField("object", "設備標識 (manufacturer/model/version/description)，僅讀取過設備標識時存在，1.1 新增"),
+		"data_format":          schemaField("string", "數據格式 (decimal/float/int16/uint16)"),
+		"device_profile":       schemaField("string", "設備配置檔名稱（壓力寄存器地址和數值編碼），1.1 新增"),
+		"timestamp_source":     schemaField("string", "讀數時間戳取值時刻 (before/after/midpoint)，1.1 新增"),
+		"min_pressure":         schemaField("number", "有效讀數下限 (Pa)，超出範圍的讀數標記為 out_of_range，1.1 新增"),
+		"max_pressure":         schemaField("number", "有效讀數上限 (Pa)，1.1 新增"),
+		"damping_register":     schemaField("integer", "阻尼寄存器地址，0 表示未配置，1.1 新增"),
+		"latency_budget_ms":    schemaField("number", "延遲預算（毫秒），0 表示不檢查，1.1 新增"),
+		"max_retries":          schemaField("integer", "單次讀取失敗後的重試次數，1.1 新增"),
+		"temperature_register": schemaField("integer", "溫度寄存器地址，0 表示未配置，1.1 新增"),
+		"compensated":          schemaField("boolean", "是否對壓力通道做溫度補償，1.1 新增"),
+		"read_requests":        schemaField("integer", "每個讀取週期的 Modbus 請求數，溫度寄存器與壓力寄存器合併讀取時為 1，1.1 新增"),
+		"raw_data":             schemaField("boolean", "讀數是否保留原始寄存器數據，1.1 新增"),
+		"queue_size":           schemaField("integer", "讀數緩衝區中的讀數數量"),
+		"queue_capacity":       schemaField("integer", "讀數緩衝區容量"),
+		"queue_published":      schemaField("integer", "放入讀數緩衝區的讀數總數，1.1 新增"),
+		"queue_dropped":        schemaField("integer", "緩衝區已滿時按溢出策略丟棄的讀數總數，1.1 新增"),
+		"queue_blocked":        schemaField("integer", "block 溢出策略下因緩衝區已滿而等待消費端的次數，1.1 新增"),
+		"overflow_policy":      schemaField("string", "緩衝區已滿時的處理方式 (drop-oldest/drop-newest/block)，1.1 新增"),
+		"queue_lag_ms":         schemaField("number", "緩衝區中最舊讀數已等待的時間（毫秒），即消費端落後的程度，1.1 新增"),
+		"comm_requests":        schemaField("integer", "Modbus 請求總數（含重試），1.1 新增"),
+		"comm_successes":       schemaField("integer", "成功的 Modbus 請求，1.1 新增"),
+		"comm_timeouts":        schemaField("integer", "設備無響應的請求，1.1 新增"),
+		"comm_crc_errors":      schemaField("integer", "響應校驗 (CRC) 錯誤的請求，1.1 新增"),
+		"comm_framing_errors":  schemaField("integer", "響應長度、事務號等幀格式錯誤的請求，1.1 新增"),
+		"comm_exceptions":      schemaField("integer", "設備返回 Modbus 異常響應的請求，1.1 新增"),
+		"comm_conn_errors":     schemaField("integer", "串口或網絡連接錯誤的請求，1.1 新增"),
+		"comm_success_rate":    schemaField("number", "請求成功率 (%)，1.1 新增"),
+		"comm_avg_latency_ms":  schemaField("number", "成功請求的平均響應延遲（毫秒），1.1 新增"),
+		"comm_p95_latency_ms":  schemaField("number", "最近 200 次成功請求的 P95 響應延遲（毫秒），1.1 新增"),
+		"last_reading":         schemaField("object", "最近一次讀數 (PressureReading)，尚未讀取時不存在；狀態快照中見頂層 last_reading，1.1 新增"),
+	})
+}
+
+func scanResultSchema() map[string]interface{} {
+	return schemaObject("掃描結果", []string{"devices", "total_tested", "successful"}, map[string]interface{}{
+		"devices":      schemaField("array", "掃描到的設備列表 (DeviceInfo)，啟用 --identify 時帶設備標識 model"),
+		"scan_time":    schemaField("integer", "掃描耗時（納秒）"),
+		"total_tested": schemaField("integer", "測試的配置總數"),
+		"successful":   schemaField("integer", "響應的設備數"),
+		"config":       schemaField("object", "使用的掃描配置"),
+		"bus_activity": schemaField("array", "掃描前被動監聽每個串口的結果 (BusActivity)，僅 --passive-first 時存在"),
+		"port_tested":  schemaField("object", "各串口或網關測試的配置數，包括 skip_unresponsive 時未列入 devices 的探測"),
+	})
+}
+
+func diagnosticsSchema() map[string]interface{} {
+	return schemaObject("串行線路診斷", []string{"diagnostics"}, map[string]interface{}{
+		"diagnostics": schemaField("object", "診斷結果 (SerialDiagnostics)：回顯測試、診斷計數器、壓力寄存器讀取和結論，1.1 新增"),
+	})
+}
+
+func valueSchema() map[string]interface{} {
+	return schemaObject("當前值 (/api/v1/value?format=json)，1.1 新增", []string{}, map[string]interface{}{
+		"value":       schemaField("number", "最新有效壓力值，無法提供時不存在"),
+		"unit":        schemaField("string", "壓力單位"),
+		"timestamp":   schemaField("string", "讀取時間 (RFC 3339)"),
+		"age_seconds": schemaField("number", "讀數年齡（秒）"),
+		"slave_id":    schemaField("integer", "Modbus 站點號"),
+		"raw_value":   schemaField("number", "未補償的壓力值，僅配置了溫度補償時存在"),
+		"temperature": schemaField("number", "儀表溫度 (°C)，僅配置了溫度寄存器時存在"),
+		"error":       schemaField("string", "無法提供當前值的原因（HTTP 503 時存在）"),
+	})
+}
+
+func snapshotSchema() map[string]interface{} {
+	return schemaObject("運行狀態快照 (/api/v1/status)，1.1 新增", []string{"generated_at", "device", "monitor"}, map[string]interface{}{
+		"generated_at":    schemaField("string", "快照時間 (RFC 3339)"),
+		"library_version": schemaField("string", "庫版本"),
+		"product":         schemaField("string", "產品名稱，僅配置了品牌設置時存在"),
+		"product_version": schemaField("string", "產品版本，僅配置了品牌設置時存在"),
+		"device":          schemaField("object", "設備狀態，字段同 status 結構"),
+		"monitor":         schemaField("object", "運行統計 (MonitorStats)：讀數、無效讀數、輸出失敗、連續失敗、切換次數、恢復步驟和故障標記 (recovery_steps、last_recovery_step、device_failed，1.1 新增)、超出延遲預算次數、當前壓力區間、壓力統計、今天的壓力統計 (today，1.1 新增)、設定值偏差和達標統計 (setpoint，1.1 新增) 和當前告警"),
+		"last_reading":    schemaField("object", "最新讀數"),
+		"config":          schemaField("object", "生效的配置"),
+		"config_source":   schemaField("object", "各配置項的來源 (default/file/env/flags)"),
+	})
+}
+
+func bandEventSchema() map[string]interface{} {
+	return schemaObject("區間變化事件 (--output=json 且配置了壓力區間時)，1.1 新增", []string{"event", "timestamp", "slave_id", "to"}, map[string]interface{}{
+		"event":     schemaField("string", "事件名稱，固定為 band_changed"),
+		"timestamp": schemaField("string", "事件時間 (RFC 3339)"),
+		"slave_id":  schemaField("integer", "Modbus 站點號"),
+		"pressure":  schemaField("number", "觸發變化的壓力值"),
+		"unit":      schemaField("string", "壓力單位"),
+		"from":      schemaField("string", "原區間，首次分類時為空"),
+		"to":        schemaField("string", "新區間，不在任何配置的區間內時為空"),
+		"location":  schemaField("object", "安裝位置，僅使用 --locations 且找到設備時存在"),
+	})
+}
+
+func historySchema() map[string]interface{} {
+	return schemaObject("歷史讀數 (/api/v1/history)，1.1 新增", []string{"from", "to", "points"}, map[string]interface{}{
+		"from":   schemaField("string", "查詢開始時間 (RFC 3339)"),
+		"to":     schemaField("string", "查詢結束時間 (RFC 3339)"),
+		"unit":   schemaField("string", "壓力單位"),
+		"points": schemaField("array", "數據點 (HistoryPoint)：時間、平均、最低和最高壓力及合併的讀數數量，讀數多於 max_points 時按時間段合併"),
+	})
+}
@@ -0,0 +1,181 @@
+// pressure/selftest.go - 啟動自檢：串口、測試讀取、數據格式、數值範圍、輸出目標
+package pressure
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// DefaultSelfTestReads 自檢默認的測試讀取次數
+const DefaultSelfTestReads = 3
+
+// SinkChecker 可選接口，輸出目標實現後可在自檢時驗證連通性
+type SinkChecker interface {
+	Check() error
+}
+
+// SelfTestCheck 單項自檢結果
+type SelfTestCheck struct {
+	Name   string `json:"name"`   // 檢查項
+	Passed bool   `json:"passed"` // 是否通過
+	Detail string `json:"detail"` // 詳情
+}
+
+// SelfTestResult 啟動自檢結果
+type SelfTestResult struct {
+	Device   string          `json:"device"`   // 串口
+	SlaveID  byte            `json:"slave_id"` // 站點號
+	Checks   []SelfTestCheck `json:"checks"`   // 各項檢查
+	Passed   bool            `json:"passed"`   // 是否全部通過
+	Duration time.Duration   `json:"duration"` // 自檢耗時
+}
+
+// add 記錄一項檢查結果
+func (r *SelfTestResult) add(name string, passed bool, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, SelfTestCheck{Name: name, Passed: passed, Detail: fmt.Sprintf(format, args...)})
+	if !passed {
+		r.Passed = false
+	}
+}
+
+// SelfTest 在開始監測前執行自檢，reads 為測試讀取次數（0 為 DefaultSelfTestReads）
+//
+// 必須在 Start 之前調用。自檢只讀取設備，不會向輸出目標寫入讀數。
+func (m *Monitor) SelfTest(reads int) *SelfTestResult {
+	if reads <= 0 {
+		reads = DefaultSelfTestReads
+	}
+	start := time.Now()
+
+	meter := m.Meter()
+	result := &SelfTestResult{
+		Device:  m.config.Endpoint(),
+		SlaveID: meter.GetSlaveID(),
+		Passed:  true,
+	}
+
+	// 連接在 NewMonitor 時已打開
+	if m.config.IsTCP() {
+		result.add("網關", true, "Modbus TCP %s 已連接", m.config.Endpoint())
+	} else if m.config.IsRTUOverTCP() {
+		result.add("串口服務器", true, "%s 已連接", m.config.SerialServerAddress())
+	} else {
+		result.add("串口", true, "%s 已打開", m.config.Device)
+	}
+
+	// 測試讀取，臨時保留原始數據以驗證數據格式
+	if !meter.RawDataEnabled() {
+		meter.SetRawData(true)
+		defer meter.SetRawData(false)
+	}
+	var valid []PressureReading
+	var lastErr string
+	var outOfRange int
+	var latency time.Duration
+	for i := 0; i < reads; i++ {
+		if i > 0 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		reading := meter.ReadPressure()
+		switch {
+		case reading.Valid:
+			valid = append(valid, reading)
+			latency += reading.Duration
+		case reading.ErrorCode == ErrOutOfRange:
+			outOfRange++
+			lastErr = reading.Error
+		default:
+			lastErr = reading.Error
+		}
+	}
+	if len(valid)+outOfRange == reads {
+		avg := time.Duration(0)
+		if len(valid) > 0 {
+			avg = latency / time.Duration(len(valid))
+		}
+		result.add("測試讀取", true, "%d/%d 次成功，平均耗時 %v", reads, reads, avg.Round(time.Millisecond))
+	} else {
+		result.add("測試讀取", false, "%d/%d 次成功，最後錯誤: %s", len(valid)+outOfRange, reads, lastErr)
+	}
+
+	// 數據格式置信度（只適用於普時達配置檔）
+	if profile := m.meter.Profile(); !isPushidaProfile(profile.Name) {
+		result.add("數據格式", true, "使用設備配置檔 %s", profile)
+	} else if len(valid) > 0 {
+		// 用全部有效讀數檢測，讀數變化時可排除只在單次讀數上看似合理的格式
+		var samples [][]byte
+		for _, reading := range valid {
+			if len(reading.RawData) > 0 {
+				samples = append(samples, reading.RawData)
+			}
+		}
+		raw := valid[len(valid)-1].RawData
+		detected, confidence, _ := NewScanner(m.logger).SetVerbose(false).SetByteOrder(m.config.ByteOrder).SetScale(m.config.Scale).detectDataFormatSamples(samples)
+		if detected == m.config.DataFormat || confidence < 0.5 {
+			result.add("數據格式", true, "配置為 %s，檢測為 %s (置信度 %.2f)", m.config.DataFormat, detected, confidence)
+		} else {
+			result.add("數據格式", false, "配置為 %s，但原始數據 % X 更像 %s (置信度 %.2f)",
+				m.config.DataFormat, raw, detected, confidence)
+		}
+	} else {
+		result.add("數據格式", false, "沒有有效讀數，無法驗證")
+	}
+
+	// 數值範圍
+	low, high := m.config.PressureLimits()
+	if outOfRange > 0 {
+		result.add("數值範圍", false, "%d 次讀數超出 [%.2f, %.2f] Pa: %s", outOfRange, low, high, lastErr)
+	} else if len(valid) > 0 {
+		stats := Statistics{}
+		for _, reading := range valid {
+			stats.Update(reading.Pressure)
+		}
+		result.add("數值範圍", true, "%.2f ~ %.2f Pa，在 [%.2f, %.2f] Pa 內", stats.Min, stats.Max, low, high)
+	} else {
+		result.add("數值範圍", false, "沒有有效讀數，無法驗證")
+	}
+
+	// 輸出目標連通性
+	m.mu.Lock()
+	sinks := make([]Sink, 0, len(m.sinks))
+	for _, ms := range m.sinks {
+		sinks = append(sinks, ms.sink)
+	}
+	m.mu.Unlock()
+	for _, sink := range sinks {
+		checker, ok := sink.(SinkChecker)
+		if !ok {
+			continue
+		}
+		name := fmt.Sprintf("輸出目標 %T", sink)
+		if err := checker.Check(); err != nil {
+			result.add(name, false, "%v", err)
+		} else {
+			result.add(name, true, "正常")
+		}
+	}
+
+	result.Duration = time.Since(start)
+	return result
+}
+
+// Print 將自檢結果寫入 w
+func (r *SelfTestResult) Print(w io.Writer) {
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+	fmt.Fprintf(w, "🧪 啟動自檢: %s 站點 %d\n", r.Device, r.SlaveID)
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+	for _, check := range r.Checks {
+		mark := "✅"
+		if !check.Passed {
+			mark = "❌"
+		}
+		fmt.Fprintf(w, "%s %s: %s\n", mark, check.Name, check.Detail)
+	}
+	if r.Passed {
+		fmt.Fprintf(w, "🎉 自檢通過 (耗時 %v)\n", r.Duration.Round(time.Millisecond))
+	} else {
+		fmt.Fprintf(w, "⚠️  自檢未通過 (耗時 %v)\n", r.Duration.Round(time.Millisecond))
+	}
+}
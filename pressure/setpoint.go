@@ -0,0 +1,245 @@
+// pressure/setpoint.go - 壓力設定值跟蹤：偏差、達標時間比例和每日超標時長
+package pressure
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// 設定值跟蹤默認值
+const (
+	DefaultSetpointTolerance = 2.5 // 默認容差 (Pa)
+	DefaultSetpointDays      = 31  // 每日統計保留的天數
+)
+
+// SetpointConfig 設備的壓力設定值，讀數在 Target ± Tolerance 內為達標
+type SetpointConfig struct {
+	Target    float64 `json:"target" yaml:"target"`       // 設定值 (Pa)
+	Tolerance float64 `json:"tolerance" yaml:"tolerance"` // 容差 (Pa)，大於 0
+}
+
+// Validate 檢查容差大於 0
+func (sc SetpointConfig) Validate() error {
+	if sc.Tolerance <= 0 || math.IsNaN(sc.Tolerance) || math.IsInf(sc.Tolerance, 0) {
+		return fmt.Errorf("設定值容差必須大於 0: %v", sc.Tolerance)
+	}
+	if math.IsNaN(sc.Target) || math.IsInf(sc.Target, 0) {
+		return fmt.Errorf("無效的壓力設定值: %v", sc.Target)
+	}
+	return nil
+}
+
+// Deviation 返回壓力值與設定值的偏差 (Pa)，取整到 0.000001 Pa 以消除浮點誤差
+func (sc SetpointConfig) Deviation(pressure float64) float64 {
+	return math.Round((pressure-sc.Target)*1e6) / 1e6
+}
+
+// InSpec 壓力值是否在設定值 ± 容差內
+func (sc SetpointConfig) InSpec(pressure float64) bool {
+	return math.Abs(sc.Deviation(pressure)) <= sc.Tolerance
+}
+
+// String 返回設定值的描述，如 "-10.00 ± 2.50 Pa"
+func (sc SetpointConfig) String() string {
+	return fmt.Sprintf("%.2f ± %.2f Pa", sc.Target, sc.Tolerance)
+}
+
+// SetpointDay 一天內的達標和超標時長
+type SetpointDay struct {
+	Date             string  `json:"date"`                // 日期 (本地時間 2006-01-02)
+	InSpecMinutes    float64 `json:"in_spec_minutes"`     // 達標分鐘數
+	OutOfSpecMinutes float64 `json:"out_of_spec_minutes"` // 超標分鐘數
+}
+
+// SetpointStats 設定值跟蹤統計
+//
+// 時長按相鄰有效讀數的間隔計算：每個讀數的達標狀態持續到下一個有效讀數，
+// 間隔超過讀取間隔 3 倍時視為數據缺失，不計入任何一方。
+type SetpointStats struct {
+	Target           float64       `json:"target"`             // 設定值 (Pa)
+	Tolerance        float64       `json:"tolerance"`          // 容差 (Pa)
+	Deviation        float64       `json:"deviation"`          // 最新有效讀數的偏差 (Pa)
+	MeanDeviation    float64       `json:"mean_deviation"`     // 平均偏差 (Pa)，正值表示整體偏高
+	MeanAbsDeviation float64       `json:"mean_abs_deviation"` // 平均絕對偏差 (Pa)
+	MaxAbsDeviation  float64       `json:"max_abs_deviation"`  // 最大絕對偏差 (Pa)
+	Readings         int           `json:"readings"`           // 參與統計的有效讀數
+	InSpecReadings   int           `json:"in_spec_readings"`   // 達標的有效讀數
+	InSpecPercent    float64       `json:"in_spec_percent"`    // 達標時間比例 (%)，尚無時長時按讀數計算
+	InSpec           bool          `json:"in_spec"`            // 最新有效讀數是否達標
+	OutOfSpecTime    time.Duration `json:"out_of_spec_time"`   // 累計超標時長
+	OutOfSpecToday   float64       `json:"out_of_spec_today"`  // 今天的超標分鐘數
+	Days             []SetpointDay `json:"days"`               // 每日達標和超標時長，最多保留 DefaultSetpointDays 天
+	inSpecTime       time.Duration // 累計達標時長
+	sumDeviation     float64
+	sumAbsDeviation  float64
+	lastTime         time.Time
+	lastInSpec       bool
+	maxGap           time.Duration
+}
+
+// NewSetpointStats 創建設定值統計，interval 為讀取間隔，用於判斷數據缺失
+func NewSetpointStats(setpoint SetpointConfig, interval time.Duration) *SetpointStats {
+	if interval <= 0 {
+		interval = DefaultReadInterval
+	}
+	return &SetpointStats{
+		Target:    setpoint.Target,
+		Tolerance: setpoint.Tolerance,
+		maxGap:    3 * interval,
+	}
+}
+
+// Config 返回統計使用的設定值
+func (ss *SetpointStats) Config() SetpointConfig {
+	return SetpointConfig{Target: ss.Target, Tolerance: ss.Tolerance}
+}
+
+// Update 加入一個有效讀數，返回其偏差
+func (ss *SetpointStats) Update(pressure float64, timestamp time.Time) float64 {
+	setpoint := ss.Config()
+	deviation := setpoint.Deviation(pressure)
+	inSpec := setpoint.InSpec(pressure)
+
+	// 上一個讀數的狀態持續到本讀數
+	if !ss.lastTime.IsZero() {
+		if gap := timestamp.Sub(ss.lastTime); gap > 0 && gap <= ss.maxGap {
+			ss.addSpan(ss.lastTime, timestamp, ss.lastInSpec)
+		}
+	}
+	ss.lastTime = timestamp
+	ss.lastInSpec = inSpec
+
+	ss.Readings++
+	if inSpec {
+		ss.InSpecReadings++
+	}
+	ss.Deviation = deviation
+	ss.InSpec = inSpec
+	ss.sumDeviation += deviation
+	ss.sumAbsDeviation += math.Abs(deviation)
+	ss.MeanDeviation = ss.sumDeviation / float64(ss.Readings)
+	ss.MeanAbsDeviation = ss.sumAbsDeviation / float64(ss.Readings)
+	ss.MaxAbsDeviation = math.Max(ss.MaxAbsDeviation, math.Abs(deviation))
+
+	if total := ss.inSpecTime + ss.OutOfSpecTime; total > 0 {
+		ss.InSpecPercent = float64(ss.inSpecTime) / float64(total) * 100
+	} else {
+		ss.InSpecPercent = float64(ss.InSpecReadings) / float64(ss.Readings) * 100
+	}
+	ss.OutOfSpecToday = 0
+	if n := len(ss.Days); n > 0 && ss.Days[n-1].Date == timestamp.Format("2006-01-02") {
+		ss.OutOfSpecToday = ss.Days[n-1].OutOfSpecMinutes
+	}
+	return deviation
+}
+
+// addSpan 將 [start, end) 按日期拆分計入達標或超標時長
+func (ss *SetpointStats) addSpan(start, end time.Time, inSpec bool) {
+	if inSpec {
+		ss.inSpecTime += end.Sub(start)
+	} else {
+		ss.OutOfSpecTime += end.Sub(start)
+	}
+	for start.Before(end) {
+		y, m, d := start.Date()
+		midnight := time.Date(y, m, d+1, 0, 0, 0, 0, start.Location())
+		stop := end
+		if midnight.Before(stop) {
+			stop = midnight
+		}
+		day := ss.day(start.Format("2006-01-02"))
+		if inSpec {
+			day.InSpecMinutes += stop.Sub(start).Minutes()
+		} else {
+			day.OutOfSpecMinutes += stop.Sub(start).Minutes()
+		}
+		start = stop
+	}
+}
+
+// day 返回指定日期的記錄，不存在時追加，超出保留天數時丟棄最舊的
+func (ss *SetpointStats) day(date string) *SetpointDay {
+	if n := len(ss.Days); n > 0 && ss.Days[n-1].Date == date {
+		return &ss.Days[n-1]
+	}
+	ss.Days = append(ss.Days, SetpointDay{Date: date})
+	if len(ss.Days) > DefaultSetpointDays {
+		ss.Days = append([]SetpointDay(nil), ss.Days[len(ss.Days)-DefaultSetpointDays:]...)
+	}
+	return &ss.Days[len(ss.Days)-1]
+}
+
+// Snapshot 返回統計的副本
+func (ss *SetpointStats) Snapshot() *SetpointStats {
+	if ss == nil {
+		return nil
+	}
+	snapshot := *ss
+	snapshot.Days = append([]SetpointDay(nil), ss.Days...)
+	return &snapshot
+}
+
+// setpointState SetpointStats 中不輸出到 JSON 的累計值，保存統計時一併保存
+type setpointState struct {
+	InSpecTime      time.Duration `json:"in_spec_time"`
+	SumDeviation    float64       `json:"sum_deviation"`
+	SumAbsDeviation float64       `json:"sum_abs_deviation"`
+	LastTime        time.Time     `json:"last_time"`
+	LastInSpec      bool          `json:"last_in_spec"`
+}
+
+// state 返回統計的累計值
+func (ss *SetpointStats) state() setpointState {
+	return setpointState{
+		InSpecTime:      ss.inSpecTime,
+		SumDeviation:    ss.sumDeviation,
+		SumAbsDeviation: ss.sumAbsDeviation,
+		LastTime:        ss.lastTime,
+		LastInSpec:      ss.lastInSpec,
+	}
+}
+
+// restore 從保存的統計繼續累計，保留當前的數據缺失判斷間隔
+func (ss *SetpointStats) restore(saved *SetpointStats, state setpointState) {
+	maxGap := ss.maxGap
+	*ss = *saved
+	ss.Days = append([]SetpointDay(nil), saved.Days...)
+	ss.maxGap = maxGap
+	ss.inSpecTime = state.InSpecTime
+	ss.sumDeviation = state.SumDeviation
+	ss.sumAbsDeviation = state.SumAbsDeviation
+	ss.lastTime = state.LastTime
+	ss.lastInSpec = state.LastInSpec
+}
+
+// SetpointStatsFromReadings 從已記錄的讀數計算設定值統計，用於報告
+func SetpointStatsFromReadings(readings []PressureReading, setpoint SetpointConfig, interval time.Duration) *SetpointStats {
+	if interval <= 0 {
+		interval = medianInterval(TrendPoints(readings))
+	}
+	stats := NewSetpointStats(setpoint, interval)
+	for _, reading := range readings {
+		if reading.Valid {
+			stats.Update(reading.Pressure, reading.Timestamp)
+		}
+	}
+	return stats
+}
+
+// Print 將設定值統計寫入 w
+func (ss *SetpointStats) Print(w io.Writer) {
+	fmt.Fprintf(w, "設定值: %s\n", ss.Config())
+	if ss.Readings == 0 {
+		fmt.Fprintln(w, "   尚無有效讀數")
+		return
+	}
+	fmt.Fprintf(w, "   達標: %.1f%% (讀數 %d/%d)，累計超標 %v\n",
+		ss.InSpecPercent, ss.InSpecReadings, ss.Readings, ss.OutOfSpecTime.Round(time.Second))
+	fmt.Fprintf(w, "   偏差: 最新 %+.2f Pa，平均 %+.2f Pa，平均絕對 %.2f Pa，最大絕對 %.2f Pa\n",
+		ss.Deviation, ss.MeanDeviation, ss.MeanAbsDeviation, ss.MaxAbsDeviation)
+	for _, day := range ss.Days {
+		fmt.Fprintf(w, "   %s: 超標 %.1f 分鐘，達標 %.1f 分鐘\n", day.Date, day.OutOfSpecMinutes, day.InSpecMinutes)
+	}
+}
@@ -0,0 +1,211 @@
+// pressure/simfaults.go - 模擬儀表的故障注入：按計劃丟棄請求、損壞 CRC 或返回異常響應，用於驗證重連、重試和告警邏輯
+package pressure
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/goburrow/modbus"
+)
+
+// 模擬故障類型
+const (
+	FaultDropout   = "dropout"   // 不響應，讀取端按超時處理
+	FaultCRC       = "crc"       // 響應幀的 CRC 被損壞
+	FaultException = "exception" // 返回 Modbus 異常響應，不執行請求
+)
+
+// DefaultFaultException 異常故障默認返回的異常碼 (0x06 從站設備忙)
+const DefaultFaultException = 0x06
+
+// SimulatorFault 一項按計劃注入的故障
+//
+// Every 大於 0 時第 Every、2×Every… 個請求起連續 Burst 個請求注入故障（計劃固定，測試可重現）；
+// Rate 大於 0 時每個請求另按該機率注入（配合 SimulatorConfig.Seed 可重現）。
+type SimulatorFault struct {
+	Kind      string  `json:"kind"`                // 故障類型 (dropout/crc/exception)
+	Every     int     `json:"every,omitempty"`     // 每多少個請求注入一次，0 為不按計劃注入
+	Burst     int     `json:"burst,omitempty"`     // 每次連續注入的請求數，0 為 1
+	Rate      float64 `json:"rate,omitempty"`      // 每個請求注入的機率 (0-1)
+	Exception byte    `json:"exception,omitempty"` // 異常故障返回的異常碼，0 為 DefaultFaultException
+}
+
+// FaultNames 返回可用的故障類型
+func FaultNames() []string {
+	return []string{FaultDropout, FaultCRC, FaultException}
+}
+
+// Validate 檢查故障計劃
+func (sf SimulatorFault) Validate() error {
+	switch sf.Kind {
+	case FaultDropout, FaultCRC, FaultException:
+	default:
+		return fmt.Errorf("無效的模擬故障: %q (可用: %s)", sf.Kind, strings.Join(FaultNames(), ", "))
+	}
+	if sf.Every < 0 || sf.Burst < 0 {
+		return fmt.Errorf("模擬故障 %s 的間隔和連續次數不能為負數", sf.Kind)
+	}
+	if sf.Burst > sf.Every && sf.Every > 0 {
+		return fmt.Errorf("模擬故障 %s 的連續次數 %d 不能大於間隔 %d", sf.Kind, sf.Burst, sf.Every)
+	}
+	if sf.Rate < 0 || sf.Rate > 1 {
+		return fmt.Errorf("模擬故障 %s 的機率必須在 0 到 1 之間: %v", sf.Kind, sf.Rate)
+	}
+	if sf.Every == 0 && sf.Rate == 0 {
+		return fmt.Errorf("模擬故障 %s 需要指定間隔或機率", sf.Kind)
+	}
+	if sf.Exception != 0 && sf.Kind != FaultException {
+		return fmt.Errorf("只有 exception 故障可以指定異常碼")
+	}
+	return nil
+}
+
+// String 返回故障計劃的描述
+func (sf SimulatorFault) String() string {
+	var parts []string
+	if sf.Every > 0 {
+		if sf.Burst > 1 {
+			parts = append(parts, fmt.Sprintf("每 %d 個請求連續 %d 次", sf.Every, sf.Burst))
+		} else {
+			parts = append(parts, fmt.Sprintf("每 %d 個請求", sf.Every))
+		}
+	}
+	if sf.Rate > 0 {
+		parts = append(parts, fmt.Sprintf("機率 %.1f%%", sf.Rate*100))
+	}
+	kind := sf.Kind
+	if sf.Kind == FaultException {
+		kind = fmt.Sprintf("%s 0x%02X", sf.Kind, sf.exceptionCode())
+	}
+	return fmt.Sprintf("%s (%s)", kind, strings.Join(parts, "，"))
+}
+
+// exceptionCode 返回異常故障的異常碼
+func (sf SimulatorFault) exceptionCode() byte {
+	if sf.Exception == 0 {
+		return DefaultFaultException
+	}
+	return sf.Exception
+}
+
+// scheduled 返回第 n 個（從 1 開始）請求是否在固定計劃內
+func (sf SimulatorFault) scheduled(n int) bool {
+	if sf.Every <= 0 {
+		return false
+	}
+	burst := sf.Burst
+	if burst == 0 {
+		burst = 1
+	}
+	// 從第 Every 個請求開始連續 burst 個
+	return n >= sf.Every && (n-sf.Every)%sf.Every < burst
+}
+
+// ParseSimulatorFaults 解析故障計劃列表，以逗號分隔
+//
+// 每項格式為 KIND[=CODE]:SCHEDULE，SCHEDULE 為 N（每 N 個請求）、NxB（每 N 個請求連續 B 次）或 P%（機率），
+// 如 "dropout:20x3,crc:5%,exception=4:50"。
+func ParseSimulatorFaults(spec string) ([]SimulatorFault, error) {
+	var faults []SimulatorFault
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kind, schedule, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("無效的模擬故障 %q (格式 KIND[=CODE]:N[xB] 或 KIND:P%%)", item)
+		}
+
+		fault := SimulatorFault{Kind: strings.ToLower(strings.TrimSpace(kind))}
+		if name, code, hasCode := strings.Cut(fault.Kind, "="); hasCode {
+			value, err := strconv.ParseUint(strings.TrimPrefix(code, "0x"), 0, 8)
+			if err != nil || value == 0 {
+				return nil, fmt.Errorf("無效的異常碼 %q", code)
+			}
+			fault.Kind, fault.Exception = name, byte(value)
+		}
+
+		schedule = strings.ToLower(strings.TrimSpace(schedule))
+		if percent, isRate := strings.CutSuffix(schedule, "%"); isRate {
+			rate, err := strconv.ParseFloat(percent, 64)
+			if err != nil {
+				return nil, fmt.Errorf("無效的故障機率 %q", schedule)
+			}
+			fault.Rate = rate / 100
+		} else {
+			every, burst, hasBurst := strings.Cut(schedule, "x")
+			var err error
+			if fault.Every, err = strconv.Atoi(every); err != nil {
+				return nil, fmt.Errorf("無效的故障間隔 %q", schedule)
+			}
+			if hasBurst {
+				if fault.Burst, err = strconv.Atoi(burst); err != nil {
+					return nil, fmt.Errorf("無效的故障連續次數 %q", schedule)
+				}
+			}
+		}
+
+		if err := fault.Validate(); err != nil {
+			return nil, err
+		}
+		faults = append(faults, fault)
+	}
+	return faults, nil
+}
+
+// nextFault 計數一個請求，返回本次注入的故障，不注入時返回 nil；多項故障同時命中時取列表中靠前的一項
+func (s *Simulator) nextFault() *SimulatorFault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sent++
+	var hit *SimulatorFault
+	for i := range s.config.Faults {
+		fault := &s.config.Faults[i]
+		// 機率故障每個請求都抽樣一次，前面的故障命中與否不影響後面故障的隨機序列
+		random := fault.Rate > 0 && s.rng.Float64() < fault.Rate
+		if hit == nil && (fault.scheduled(s.sent) || random) {
+			hit = fault
+		}
+	}
+	if hit != nil {
+		s.injected[hit.Kind]++
+	}
+	return hit
+}
+
+// Injected 返回各類故障已注入的次數
+func (s *Simulator) Injected() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	injected := make(map[string]int, len(s.injected))
+	for kind, count := range s.injected {
+		injected[kind] = count
+	}
+	return injected
+}
+
+// inject 按故障類型處理請求
+func (s *Simulator) inject(fault *SimulatorFault, request []byte) ([]byte, error) {
+	switch fault.Kind {
+	case FaultDropout:
+		return nil, os.ErrDeadlineExceeded
+	case FaultException:
+		// 只有發給本站點號的請求才返回異常，其他站點號仍按無響應處理
+		if len(request) < 4 || request[0] != s.slaveID {
+			return s.MockTransport.Send(request)
+		}
+		return s.Encode(&modbus.ProtocolDataUnit{FunctionCode: request[1] | 0x80, Data: []byte{fault.exceptionCode()}})
+	default:
+		response, err := s.MockTransport.Send(request)
+		if err != nil || len(response) == 0 {
+			return response, err
+		}
+		response[len(response)-1] ^= 0xFF
+		return response, nil
+	}
+}
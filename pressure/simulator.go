@@ -0,0 +1,233 @@
+// pressure/simulator.go - 模擬儀表：按波形生成壓力並經正常的讀取流程輸出，不接儀表也能開發儀表板、輸出目標和告警
+package pressure
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 模擬波形
+const (
+	WaveformSine  = "sine"  // 在範圍內按週期正弦變化
+	WaveformNoise = "noise" // 在範圍中點附近隨機波動
+	WaveformStep  = "step"  // 每個週期跳到範圍內的隨機值，模擬開關門和風機切換
+)
+
+// 模擬儀表的默認參數
+const (
+	DefaultSimulatorMin    = -20.0
+	DefaultSimulatorMax    = 20.0
+	DefaultSimulatorPeriod = time.Minute
+	simulatorTemperature   = 22.0 // 模擬的儀表溫度 (°C)
+)
+
+// SimulatorConfig 模擬儀表的壓力波形
+type SimulatorConfig struct {
+	Waveform string        `json:"waveform"` // 波形 (sine/noise/step)
+	Min      float64       `json:"min"`      // 壓力範圍下限 (Pa)
+	Max      float64       `json:"max"`      // 壓力範圍上限 (Pa)
+	Period   time.Duration `json:"period"`   // 正弦週期或階躍間隔，0 為 1 分鐘
+	Noise    float64       `json:"noise"`    // 疊加的隨機噪聲標準差 (Pa)，0 為不疊加
+	Drift    float64       `json:"drift"`    // 緩慢漂移 (Pa/小時)，從啟動時開始累積，模擬感測器零點漂移
+
+	Seed   int64            `json:"seed,omitempty"`   // 隨機數種子，0 為按啟動時間；固定種子時噪聲和機率故障可重現
+	Faults []SimulatorFault `json:"faults,omitempty"` // 按計劃注入的通信故障
+}
+
+// WaveformNames 返回可用的波形名稱
+func WaveformNames() []string {
+	return []string{WaveformSine, WaveformNoise, WaveformStep}
+}
+
+// Validate 檢查模擬參數
+func (sc SimulatorConfig) Validate() error {
+	switch sc.Waveform {
+	case WaveformSine, WaveformNoise, WaveformStep:
+	default:
+		return fmt.Errorf("無效的模擬波形: %q (可用: %s)", sc.Waveform, strings.Join(WaveformNames(), ", "))
+	}
+	if sc.Min >= sc.Max {
+		return fmt.Errorf("模擬壓力範圍無效: 下限 %.2f 必須小於上限 %.2f", sc.Min, sc.Max)
+	}
+	if sc.Period < 0 {
+		return fmt.Errorf("模擬週期不能為負數: %v", sc.Period)
+	}
+	if sc.Noise < 0 {
+		return fmt.Errorf("模擬噪聲不能為負數: %.2f", sc.Noise)
+	}
+	if math.IsNaN(sc.Drift) || math.IsInf(sc.Drift, 0) {
+		return fmt.Errorf("模擬漂移無效: %v", sc.Drift)
+	}
+	for _, fault := range sc.Faults {
+		if err := fault.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String 返回模擬參數的描述
+func (sc SimulatorConfig) String() string {
+	s := fmt.Sprintf("%s %.1f ~ %.1f Pa", sc.Waveform, sc.Min, sc.Max)
+	if sc.Waveform != WaveformNoise {
+		s += fmt.Sprintf("，週期 %v", sc.period())
+	}
+	if sc.Noise > 0 {
+		s += fmt.Sprintf("，噪聲 %.2f Pa", sc.Noise)
+	}
+	if sc.Drift != 0 {
+		s += fmt.Sprintf("，漂移 %+.2f Pa/h", sc.Drift)
+	}
+	for _, fault := range sc.Faults {
+		s += "，故障 " + fault.String()
+	}
+	return s
+}
+
+// period 返回正弦週期或階躍間隔
+func (sc SimulatorConfig) period() time.Duration {
+	if sc.Period <= 0 {
+		return DefaultSimulatorPeriod
+	}
+	return sc.Period
+}
+
+// Simulator 按波形生成壓力的模擬儀表
+//
+// 設置到 Config.ModbusTransport 後，每次讀取前按當前時間生成壓力，以配置檔的寄存器地址和編碼寫入寄存器表，
+// 經過與真實儀表相同的解析、統計、告警和輸出流程。配置了溫度寄存器時返回約 22 °C 的溫度，
+// 設備配置檔的設置寄存器和阻尼、零點寄存器初始為 0，可以寫入和讀回。配置了故障計劃時，
+// 按請求序號丟棄請求、損壞響應的 CRC 或返回異常響應。
+type Simulator struct {
+	*MockTransport
+	config       SimulatorConfig
+	profile      DeviceProfile
+	tempRegister uint16
+	tempScale    float64
+	start        time.Time
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	level    float64        // 階躍波形的當前壓力
+	nextStep time.Time      // 下次階躍的時間
+	sent     int            // 已收到的請求數，用於故障計劃
+	injected map[string]int // 各類故障已注入的次數
+}
+
+// NewSimulator 按儀表配置 (站點號、設備配置檔、溫度寄存器) 創建模擬儀表
+func NewSimulator(config Config, sim SimulatorConfig) (*Simulator, error) {
+	if err := sim.Validate(); err != nil {
+		return nil, err
+	}
+	profile, err := ResolveDeviceProfile(config)
+	if err != nil {
+		return nil, err
+	}
+	for _, limit := range []float64{sim.Min, sim.Max} {
+		if _, err := profile.Encode(limit); err != nil {
+			return nil, fmt.Errorf("模擬壓力範圍無法用設備配置檔 %s 表示: %v", profile.Name, err)
+		}
+	}
+	tempScale := config.TemperatureScale
+	if tempScale == 0 {
+		tempScale = DefaultTemperatureScale
+	}
+
+	now := time.Now()
+	seed := sim.Seed
+	if seed == 0 {
+		seed = now.UnixNano()
+	}
+	s := &Simulator{
+		MockTransport: NewMockTransport(config.SlaveID),
+		config:        sim,
+		profile:       profile,
+		tempRegister:  config.TemperatureRegister,
+		tempScale:     tempScale,
+		start:         now,
+		rng:           rand.New(rand.NewSource(seed)),
+		level:         (sim.Min + sim.Max) / 2,
+		nextStep:      now.Add(sim.period()),
+		injected:      make(map[string]int),
+	}
+	// 設置寄存器初始為 0，站點號和波特率寄存器為當前值，阻尼、參數導出和恢復等命令也可以在模擬儀表上試用
+	for _, param := range profile.Parameters {
+		s.SetRegisters(param.Register, make([]uint16, param.count())...)
+	}
+	for _, register := range []uint16{config.DampingRegister, config.ZeroRegister} {
+		if register != 0 {
+			s.SetRegisters(register, 0)
+		}
+	}
+	if config.SlaveIDRegister != 0 {
+		s.SetRegisters(config.SlaveIDRegister, uint16(config.SlaveID))
+	}
+	if config.BaudRateRegister != 0 {
+		if value, err := encodeBaudRate(config.SerialBaudRate(), config.BaudRateCodes); err == nil {
+			s.SetRegisters(config.BaudRateRegister, value)
+		}
+	}
+	s.update(now)
+	return s, nil
+}
+
+// Config 返回模擬參數
+func (s *Simulator) Config() SimulatorConfig {
+	return s.config
+}
+
+// Send 實現 modbus.Transporter 接口，按當前時間更新壓力和溫度寄存器後響應請求，故障計劃命中時注入故障
+func (s *Simulator) Send(request []byte) ([]byte, error) {
+	s.update(time.Now())
+	if fault := s.nextFault(); fault != nil {
+		return s.inject(fault, request)
+	}
+	return s.MockTransport.Send(request)
+}
+
+// update 將 now 時刻的壓力和溫度寫入寄存器表
+func (s *Simulator) update(now time.Time) {
+	pressure := s.pressure(now)
+	if registers, err := s.profile.Encode(pressure); err == nil {
+		s.SetRegisters(s.profile.Register, registers...)
+	}
+	if s.tempRegister != 0 {
+		s.mu.Lock()
+		temperature := simulatorTemperature + s.rng.NormFloat64()*0.1
+		s.mu.Unlock()
+		s.SetRegisters(s.tempRegister, uint16(int16(math.Round(temperature/s.tempScale))))
+	}
+}
+
+// pressure 返回 now 時刻的模擬壓力，疊加噪聲和漂移後限制在範圍內
+func (s *Simulator) pressure(now time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sc := s.config
+	mid, amplitude := (sc.Min+sc.Max)/2, (sc.Max-sc.Min)/2
+	var value float64
+	switch sc.Waveform {
+	case WaveformSine:
+		phase := 2 * math.Pi * float64(now.Sub(s.start)) / float64(sc.period())
+		value = mid + amplitude*math.Sin(phase)
+	case WaveformNoise:
+		// 標準差為範圍的六分之一，絕大部分讀數落在範圍內
+		value = mid + s.rng.NormFloat64()*amplitude/3
+	case WaveformStep:
+		for !now.Before(s.nextStep) {
+			s.level = sc.Min + s.rng.Float64()*(sc.Max-sc.Min)
+			s.nextStep = s.nextStep.Add(sc.period())
+		}
+		value = s.level
+	}
+	if sc.Noise > 0 {
+		value += s.rng.NormFloat64() * sc.Noise
+	}
+	value += sc.Drift * now.Sub(s.start).Hours()
+	return math.Max(sc.Min, math.Min(sc.Max, value))
+}
@@ -0,0 +1,131 @@
+package pressure
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// newSimulatedMeter 創建讀取模擬儀表的壓差儀
+func newSimulatedMeter(t *testing.T, sim SimulatorConfig) (*PressureMeter, *Simulator) {
+	t.Helper()
+	simulator, err := NewSimulator(testConfig(nil), sim)
+	if err != nil {
+		t.Fatalf("NewSimulator: %v", err)
+	}
+	return newTestMeter(t, simulator), simulator
+}
+
+func TestSimulatorFaultSchedule(t *testing.T) {
+	pm, simulator := newSimulatedMeter(t, SimulatorConfig{
+		Waveform: WaveformSine,
+		Min:      -20,
+		Max:      20,
+		Faults: []SimulatorFault{
+			{Kind: FaultDropout, Every: 4, Burst: 2},
+			{Kind: FaultCRC, Every: 7},
+			{Kind: FaultException, Every: 10, Exception: 0x04},
+		},
+	})
+
+	// 請求 4-5、8-9 無響應，7 CRC 錯誤，10 返回設備故障異常 (0x04)，其餘正常
+	want := map[int]ErrorCode{4: ErrTimeout, 5: ErrTimeout, 7: ErrProtocol, 8: ErrTimeout, 9: ErrTimeout, 10: ErrHardware}
+	for n := 1; n <= 11; n++ {
+		reading := pm.ReadPressure()
+		code, faulted := want[n]
+		if !faulted {
+			if !reading.Valid {
+				t.Errorf("request %d: %s, want a valid reading", n, reading.Error)
+			}
+			continue
+		}
+		if reading.Valid || reading.ErrorCode != code {
+			t.Errorf("request %d: valid=%v code=%v (%s), want %v", n, reading.Valid, reading.ErrorCode, reading.Error, code)
+		}
+	}
+
+	injected := simulator.Injected()
+	if injected[FaultDropout] != 4 || injected[FaultCRC] != 1 || injected[FaultException] != 1 {
+		t.Errorf("injected = %v, want 4 dropouts, 1 crc, 1 exception", injected)
+	}
+}
+
+func TestSimulatorSeedReproducible(t *testing.T) {
+	sim := SimulatorConfig{
+		Waveform: WaveformNoise,
+		Min:      -20,
+		Max:      20,
+		Seed:     42,
+		Faults:   []SimulatorFault{{Kind: FaultDropout, Rate: 0.3}},
+	}
+	outcomes := func() []float64 {
+		pm, _ := newSimulatedMeter(t, sim)
+		var values []float64
+		for i := 0; i < 20; i++ {
+			reading := pm.ReadPressure()
+			if !reading.Valid {
+				values = append(values, math.Inf(1))
+				continue
+			}
+			values = append(values, reading.Pressure)
+		}
+		return values
+	}
+
+	first, second := outcomes(), outcomes()
+	dropouts := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("reading %d differs with the same seed: %v vs %v", i, first[i], second[i])
+		}
+		if math.IsInf(first[i], 1) {
+			dropouts++
+		}
+	}
+	if dropouts == 0 || dropouts == len(first) {
+		t.Errorf("%d of %d readings dropped at 30%%", dropouts, len(first))
+	}
+}
+
+func TestSimulatorDrift(t *testing.T) {
+	_, simulator := newSimulatedMeter(t, SimulatorConfig{
+		Waveform: WaveformStep,
+		Min:      -20,
+		Max:      20,
+		Period:   24 * time.Hour,
+		Drift:    3,
+	})
+
+	if got := simulator.pressure(simulator.start.Add(2 * time.Hour)); math.Abs(got-6) > 1e-9 {
+		t.Errorf("pressure after 2 h = %v, want 6 (midpoint plus 3 Pa/h drift)", got)
+	}
+	if got := simulator.pressure(simulator.start.Add(10 * time.Hour)); got != 20 {
+		t.Errorf("pressure after 10 h = %v, want clamped to 20", got)
+	}
+}
+
+func TestParseSimulatorFaults(t *testing.T) {
+	faults, err := ParseSimulatorFaults("dropout:20x3, crc:5%,exception=0x04:50")
+	if err != nil {
+		t.Fatalf("ParseSimulatorFaults: %v", err)
+	}
+	want := []SimulatorFault{
+		{Kind: FaultDropout, Every: 20, Burst: 3},
+		{Kind: FaultCRC, Rate: 0.05},
+		{Kind: FaultException, Every: 50, Exception: 0x04},
+	}
+	if len(faults) != len(want) {
+		t.Fatalf("faults = %+v, want %+v", faults, want)
+	}
+	for i := range want {
+		if faults[i] != want[i] {
+			t.Errorf("fault %d = %+v, want %+v", i, faults[i], want[i])
+		}
+	}
+
+	for _, spec := range []string{"dropout", "noise:5", "crc=4:5", "dropout:3x5", "crc:150%", "dropout:0"} {
+		if _, err := ParseSimulatorFaults(spec); err == nil {
+			t.Errorf("ParseSimulatorFaults(%q): want error", spec)
+		}
+	}
+}
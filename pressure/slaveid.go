@@ -0,0 +1,97 @@
+// pressure/slaveid.go - 遠程修改儀表站點號（調試時重新編址）
+package pressure
+
+import (
+	"fmt"
+
+	"github.com/goburrow/modbus"
+)
+
+// SupportsSlaveAddress 設備是否配置了站點號寄存器
+func (pm *PressureMeter) SupportsSlaveAddress() bool {
+	return pm.slaveIDRegister != 0
+}
+
+// SetSlaveAddress 將儀表站點號改為 newID，並以新站點號讀回確認
+//
+// 寫入前先確認新站點號上沒有其他設備響應，避免總線上出現地址衝突。
+// 部分儀表在改號後立即以新站點號響應，舊站點號的寫入響應可能超時，
+// 因此寫入出錯時仍以讀回結果為準。成功後本實例改用新站點號通信。
+func (pm *PressureMeter) SetSlaveAddress(newID byte) error {
+	if !pm.SupportsSlaveAddress() {
+		return fmt.Errorf("未配置站點號寄存器 (slaveidregister)")
+	}
+	if err := CheckPollingSlaveID(newID); err != nil {
+		return err
+	}
+	if err := pm.checkControl(); err != nil {
+		return err
+	}
+	oldID := pm.slaveID
+	if newID == oldID {
+		return fmt.Errorf("新站點號與當前站點號相同: %d", newID)
+	}
+
+	pm.busMu.Lock()
+	defer pm.busMu.Unlock()
+
+	// 新站點號上已有設備響應時拒絕修改
+	if err := setHandlerSlaveID(pm.handler, newID); err != nil {
+		return err
+	}
+	_, probeErr := pm.readRegister(pm.slaveIDRegister)
+	setHandlerSlaveID(pm.handler, oldID)
+	if probeErr == nil {
+		return fmt.Errorf("站點 %d 已有設備響應，請先確認總線上的地址分配", newID)
+	}
+
+	current, err := pm.readRegister(pm.slaveIDRegister)
+	if err != nil {
+		return fmt.Errorf("讀取站點號寄存器失敗: %v", err)
+	}
+	if current != uint16(oldID) {
+		return fmt.Errorf("站點號寄存器 0x%04X 的值 %d 與當前站點號 %d 不一致，請確認寄存器地址", pm.slaveIDRegister, current, oldID)
+	}
+
+	_, writeErr := pm.client.WriteSingleRegister(pm.slaveIDRegister, uint16(newID))
+	if writeErr != nil {
+		pm.logger.Printf("寫入站點號的響應異常，以讀回結果為準: %v", writeErr)
+	}
+
+	// 以新站點號讀回確認
+	setHandlerSlaveID(pm.handler, newID)
+	readback, err := pm.readRegister(pm.slaveIDRegister)
+	if err != nil || readback != uint16(newID) {
+		setHandlerSlaveID(pm.handler, oldID)
+		if err == nil {
+			err = fmt.Errorf("讀回值 %d", readback)
+		}
+		if writeErr != nil {
+			return fmt.Errorf("修改站點號失敗: 寫入: %v；以站點 %d 讀回: %v", writeErr, newID, err)
+		}
+		return fmt.Errorf("修改站點號失敗: 以站點 %d 讀回: %v", newID, err)
+	}
+
+	pm.slaveID = newID
+	pm.logger.Printf("站點號已從 %d 改為 %d (寄存器 0x%04X)", oldID, newID, pm.slaveIDRegister)
+	return nil
+}
+
+// setHandlerSlaveID 修改 Modbus 處理器使用的站點號，自定義傳輸層無法切換時返回錯誤
+func setHandlerSlaveID(handler ModbusTransport, slaveID byte) error {
+	switch h := handler.(type) {
+	case *modbus.RTUClientHandler:
+		h.SlaveId = slaveID
+	case *modbus.TCPClientHandler:
+		h.SlaveId = slaveID
+	case *rtuOverTCPHandler:
+		h.packager.SlaveId = slaveID
+	case *MockTransport:
+		h.packager.SlaveId = slaveID
+	case *Simulator:
+		h.packager.SlaveId = slaveID
+	default:
+		return fmt.Errorf("傳輸層 %T 不支援切換站點號", handler)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+// pressure/statsfile.go - 監測統計持久化：定期保存壓力統計和設定值達標統計，重啟後載入繼續累計
+package pressure
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultStatsSaveInterval 監測統計定期保存的間隔
+const DefaultStatsSaveInterval = time.Minute
+
+// SavedStats 保存到檔案的監測統計
+type SavedStats struct {
+	Device        string         `json:"device"`                   // 設備
+	SlaveID       byte           `json:"slave_id"`                 // 站點號
+	SavedAt       time.Time      `json:"saved_at"`                 // 保存時間
+	Pressure      Statistics     `json:"pressure"`                 // 有效讀數統計
+	Date          string         `json:"date"`                     // Today 的日期 (本地時間 2006-01-02)
+	Today         Statistics     `json:"today"`                    // Date 當天的有效讀數統計
+	Setpoint      *SetpointStats `json:"setpoint,omitempty"`       // 設定值偏差和達標統計
+	SetpointState *setpointState `json:"setpoint_state,omitempty"` // 設定值統計的累計值，用於繼續計算
+}
+
+// LoadSavedStats 從檔案讀取保存的監測統計
+func LoadSavedStats(filename string) (*SavedStats, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var saved SavedStats
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("解析統計檔案失敗: %v", err)
+	}
+	return &saved, nil
+}
+
+// Save 將監測統計寫入檔案（先寫臨時檔案再替換，避免中斷時損壞）
+func (ss *SavedStats) Save(filename string) error {
+	data, err := json.MarshalIndent(ss, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(filename, data); err != nil {
+		return fmt.Errorf("寫入統計檔案失敗: %v", err)
+	}
+	return nil
+}
+
+// SetStatsFile 啟用監測統計持久化（需在 Start 之前調用）
+//
+// 檔案存在時載入其中的壓力統計；今天的統計只在同一天內載入，設定值統計只在設定值和容差相同時載入。
+// 之後每 DefaultStatsSaveInterval 和停止監測時保存，服務在一天中途重啟時每日最小/最大值和達標時長不會歸零。
+func (m *Monitor) SetStatsFile(filename string) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statsFile = filename
+
+	saved, err := LoadSavedStats(filename)
+	switch {
+	case err == nil:
+		m.restoreStats(saved)
+	case !os.IsNotExist(err):
+		m.logger.Printf("⚠️  讀取統計檔案失敗，重新統計: %v", err)
+	}
+	return m
+}
+
+// restoreStats 載入保存的統計（調用方需持有鎖）
+func (m *Monitor) restoreStats(saved *SavedStats) {
+	if saved.Device != m.stats.Device || saved.SlaveID != m.config.SlaveID {
+		m.logger.Printf("⚠️  統計檔案來自 %s 站點 %d，繼續用於當前設備", saved.Device, saved.SlaveID)
+	}
+	m.stats.Pressure = saved.Pressure
+	if saved.Date == time.Now().Format("2006-01-02") {
+		m.stats.Today = saved.Today
+		m.today = saved.Date
+	}
+	if m.setpoint != nil && saved.Setpoint != nil && saved.SetpointState != nil {
+		if saved.Setpoint.Config() == m.setpoint.Config() {
+			m.setpoint.restore(saved.Setpoint, *saved.SetpointState)
+		} else {
+			m.logger.Printf("⚠️  統計檔案的設定值 %s 與配置 %s 不同，設定值統計重新開始", saved.Setpoint.Config(), m.setpoint.Config())
+		}
+	}
+	m.logger.Printf("已載入監測統計 (保存於 %s，有效讀數 %d，今天 %d)",
+		saved.SavedAt.Format("2006-01-02 15:04:05"), m.stats.Pressure.Count, m.stats.Today.Count)
+}
+
+// saveStats 距上次保存超過 DefaultStatsSaveInterval 或 force 時將統計保存到檔案
+func (m *Monitor) saveStats(force bool) {
+	m.mu.Lock()
+	if m.statsFile == "" || (!force && time.Since(m.statsSaved) < DefaultStatsSaveInterval) {
+		m.mu.Unlock()
+		return
+	}
+	m.statsSaved = time.Now()
+	saved := &SavedStats{
+		Device:   m.stats.Device,
+		SlaveID:  m.config.SlaveID,
+		SavedAt:  m.statsSaved,
+		Pressure: m.stats.Pressure,
+		Date:     m.today,
+		Today:    m.stats.Today,
+		Setpoint: m.setpoint.Snapshot(),
+	}
+	if m.setpoint != nil {
+		state := m.setpoint.state()
+		saved.SetpointState = &state
+	}
+	filename := m.statsFile
+	m.mu.Unlock()
+
+	if err := saved.Save(filename); err != nil {
+		m.logger.Printf("保存監測統計失敗: %v", err)
+	}
+}
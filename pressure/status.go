@@ -0,0 +1,169 @@
+// pressure/status.go - 運行狀態快照，供支援工單和遠程查詢
+package pressure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StatusSnapshot 運行中監測的完整狀態，一份文檔即可附到支援工單
+type StatusSnapshot struct {
+	SchemaVersion  string                  `json:"schema_version"`            // 輸出格式版本
+	GeneratedAt    time.Time               `json:"generated_at"`              // 快照時間
+	LibraryVersion string                  `json:"library_version"`           // 庫版本
+	Product        string                  `json:"product,omitempty"`         // 產品名稱，僅配置了品牌設置時存在
+	ProductVersion string                  `json:"product_version,omitempty"` // 產品版本，僅配置了品牌設置時存在
+	Device         Status                  `json:"device"`                    // 設備狀態 (PressureMeter.GetStatus)
+	Monitor        MonitorStats            `json:"monitor"`                   // 運行統計、計數器和當前告警
+	LastReading    *MonitorReading         `json:"last_reading,omitempty"`    // 最新讀數
+	Config         *Config                 `json:"config,omitempty"`          // 生效的配置
+	ConfigSource   map[string]ConfigSource `json:"config_source,omitempty"`   // 各配置項的來源
+}
+
+// Snapshot 生成狀態快照，未設置監測流程時返回 false
+func (as *APIServer) Snapshot() (*StatusSnapshot, bool) {
+	as.mu.RLock()
+	monitor, info, latest := as.monitor, as.config, as.latest
+	as.mu.RUnlock()
+
+	if monitor == nil {
+		return nil, false
+	}
+
+	snapshot := &StatusSnapshot{
+		SchemaVersion:  SchemaVersion,
+		GeneratedAt:    time.Now(),
+		LibraryVersion: LibraryVersion,
+		Device:         monitor.Meter().GetStatus(),
+		Monitor:        monitor.Stats(),
+		LastReading:    latest,
+	}
+	// 版本和最新讀數已在快照頂層
+	snapshot.Device.SchemaVersion = ""
+	snapshot.Device.LastReading = nil
+
+	if info != nil {
+		snapshot.Config = info.Config
+		snapshot.ConfigSource = info.Source
+	} else {
+		config := monitor.config
+		snapshot.Config = &config
+	}
+	if branding := snapshot.Config.Branding; branding != nil {
+		snapshot.Product = branding.Name("")
+		snapshot.ProductVersion = branding.Version("")
+	}
+	return snapshot, true
+}
+
+// FetchStatus 從運行中的監測程序（HTTP 接口或 unix: 控制套接字）獲取狀態快照
+func FetchStatus(addr string) (*StatusSnapshot, error) {
+	network, address := splitAPIAddr(addr)
+
+	url := "http://" + address + "/api/v1/status"
+	client := &http.Client{Timeout: 10 * time.Second}
+	if network == "unix" {
+		url = "http://unix/api/v1/status"
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", address)
+			},
+		}
+	} else if strings.HasPrefix(address, ":") {
+		url = "http://localhost" + address + "/api/v1/status"
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("連接監測程序失敗: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return nil, fmt.Errorf("監測程序返回 %s: %s", resp.Status, body.Error)
+	}
+
+	var snapshot StatusSnapshot
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("解析狀態快照失敗: %v", err)
+	}
+	return &snapshot, nil
+}
+
+// Print 將狀態快照寫入 w
+func (ss *StatusSnapshot) Print(w io.Writer) {
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+	fmt.Fprintf(w, "📊 運行狀態 (%s)\n", ss.GeneratedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintln(w, "="+strings.Repeat("=", 50))
+
+	m := ss.Monitor
+	if ss.Product != "" {
+		fmt.Fprintf(w, "產品: %s %s\n", ss.Product, ss.ProductVersion)
+	}
+	fmt.Fprintf(w, "設備: %s\n", m.Device)
+	fmt.Fprintf(w, "運行時長: %v\n", m.Uptime.Round(time.Second))
+	fmt.Fprintf(w, "讀數: %d (無效 %d, 連續失敗 %d)\n", m.Readings, m.Errors, m.ConsecutiveFailures)
+	fmt.Fprintf(w, "輸出失敗: %d, 切換備用設備: %d\n", m.SinkErrors, m.Failovers)
+	if m.RecoverySteps > 0 {
+		fmt.Fprintf(w, "恢復步驟: %d (最近: %s)\n", m.RecoverySteps, m.LastRecoveryStep)
+	}
+	if m.DeviceFailed {
+		fmt.Fprintln(w, "🚨 設備已標記為故障：恢復步驟已用完，仍無響應")
+	}
+	fmt.Fprintf(w, "%s\n", m.Pressure)
+	if m.Today.Count > 0 && m.Today.Count != m.Pressure.Count {
+		fmt.Fprintf(w, "今天 %s\n", m.Today)
+	}
+	if len(m.ActiveAlarms) > 0 {
+		fmt.Fprintf(w, "🚨 當前告警: %s\n", strings.Join(m.ActiveAlarms, ", "))
+	}
+	if m.Setpoint != nil {
+		m.Setpoint.Print(w)
+	}
+	if len(m.Sinks) > 0 {
+		fmt.Fprintln(w, "輸出目標:")
+		for _, sink := range m.Sinks {
+			if sink.State == SinkStateOpen {
+				fmt.Fprintf(w, "   ⚠️  %s: 暫停輸出 (連續失敗 %d 次，跳過 %d 筆，%d 個事件待補發，%s 重試) - %s\n",
+					sink.Name, sink.ConsecutiveFailures, sink.Skipped, sink.PendingEvents, sink.NextRetry.Format("15:04:05"), sink.LastError)
+			} else {
+				fmt.Fprintf(w, "   ✅ %s: 正常 (輸出 %d 筆，失敗 %d 次)\n", sink.Name, sink.Writes, sink.Failures)
+			}
+		}
+	}
+
+	if r := ss.LastReading; r != nil {
+		if r.Valid {
+			fmt.Fprintf(w, "最新讀數: %.2f Pa (%s)\n", r.Pressure, r.Timestamp.Format("15:04:05"))
+		} else {
+			fmt.Fprintf(w, "最新讀數: 無效 - %s (%s)\n", r.Error, r.Timestamp.Format("15:04:05"))
+		}
+	}
+
+	fmt.Fprintln(w, "\n設備狀態:")
+	ss.Device.Print(w)
+
+	if len(ss.ConfigSource) > 0 {
+		keys := make([]string, 0, len(ss.ConfigSource))
+		for key := range ss.ConfigSource {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		fmt.Fprintln(w, "\n配置來源:")
+		for _, key := range keys {
+			fmt.Fprintf(w, "   %s: %s\n", key, sourceToString(ss.ConfigSource[key]))
+		}
+	}
+}
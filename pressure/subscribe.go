@@ -0,0 +1,98 @@
+// pressure/subscribe.go - 讀數訂閱：每個訂閱者各自收到全部讀數
+package pressure
+
+import "sync"
+
+// DefaultSubscriberBufferSize 每個讀數訂閱者的緩衝讀數數量
+const DefaultSubscriberBufferSize = 100
+
+// readingSubscribers 讀數訂閱者
+type readingSubscribers struct {
+	mu      sync.Mutex
+	subs    map[chan PressureReading]struct{}
+	closed  bool
+	dropped int64
+}
+
+// Subscribe 訂閱讀數，返回只屬於本訂閱者的讀數通道和取消訂閱的函數
+//
+// GetReadings 的通道只能由一個消費者讀取；需要多個消費者（如打印、記錄和匯出）時，
+// 每個消費者各自訂閱，都會收到全部讀數，互不影響。訂閱者處理過慢、通道已滿時
+// 丟棄該訂閱者最舊的讀數。cancel 關閉通道，可重複調用；Close 後所有訂閱通道都會關閉。
+func (pm *PressureMeter) Subscribe() (<-chan PressureReading, func()) {
+	ch := make(chan PressureReading, DefaultSubscriberBufferSize)
+	s := &pm.subscribers
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if s.subs == nil {
+		s.subs = make(map[chan PressureReading]struct{})
+	}
+	s.subs[ch] = struct{}{}
+
+	return ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subs[ch]; ok {
+			delete(s.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribers 返回當前的讀數訂閱者數量
+func (pm *PressureMeter) Subscribers() int {
+	pm.subscribers.mu.Lock()
+	defer pm.subscribers.mu.Unlock()
+	return len(pm.subscribers.subs)
+}
+
+// SubscriberDropped 返回因訂閱者處理過慢而丟棄的讀數總數
+func (pm *PressureMeter) SubscriberDropped() int64 {
+	pm.subscribers.mu.Lock()
+	defer pm.subscribers.mu.Unlock()
+	return pm.subscribers.dropped
+}
+
+// fanOut 將讀數發給所有訂閱者，訂閱者的通道已滿時丟棄其最舊的讀數
+func (pm *PressureMeter) fanOut(reading PressureReading) {
+	s := &pm.subscribers
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subs {
+		select {
+		case ch <- reading:
+			continue
+		default:
+		}
+		select {
+		case <-ch:
+			s.dropped++
+		default:
+		}
+		select {
+		case ch <- reading:
+		default:
+			s.dropped++
+		}
+	}
+}
+
+// closeSubscribers 關閉所有訂閱通道，之後的訂閱返回已關閉的通道
+func (pm *PressureMeter) closeSubscribers() {
+	s := &pm.subscribers
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	for ch := range s.subs {
+		delete(s.subs, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,94 @@
+// pressure/timing.go - 通信時序配置檔（短距離、長距離、中繼/無線）
+package pressure
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CommProfile 一組針對特定線路條件調好的通信參數
+type CommProfile struct {
+	Name            string        `json:"name"`              // 名稱
+	Description     string        `json:"description"`       // 說明
+	ConnectTimeout  time.Duration `json:"connect_timeout"`   // 連接超時
+	ResponseTimeout time.Duration `json:"response_timeout"`  // 響應超時
+	ProbeTimeout    time.Duration `json:"probe_timeout"`     // 掃描探測超時
+	MinReadInterval time.Duration `json:"min_read_interval"` // 最小讀取間隔（節流）
+	MaxRetries      int           `json:"max_retries"`       // 單次讀取失敗後的重試次數
+	RetryDelay      time.Duration `json:"retry_delay"`       // 讀取重試前的等待時間
+}
+
+// 內建通信配置檔
+var commProfiles = map[string]CommProfile{
+	"bench": {
+		Name:            "bench",
+		Description:     "短距離台架測試，USB 轉換器直連",
+		ConnectTimeout:  2 * time.Second,
+		ResponseTimeout: 300 * time.Millisecond,
+		ProbeTimeout:    200 * time.Millisecond,
+		MinReadInterval: 100 * time.Millisecond,
+		MaxRetries:      1,
+		RetryDelay:      50 * time.Millisecond,
+	},
+	"long-line": {
+		Name:            "long-line",
+		Description:     "約 1 km 長線路，傳輸延遲和干擾較大",
+		ConnectTimeout:  5 * time.Second,
+		ResponseTimeout: 1500 * time.Millisecond,
+		ProbeTimeout:    1 * time.Second,
+		MinReadInterval: 500 * time.Millisecond,
+		MaxRetries:      2,
+		RetryDelay:      200 * time.Millisecond,
+	},
+	"radio": {
+		Name:            "radio",
+		Description:     "經中繼器或無線數傳電台，延遲高且不穩定",
+		ConnectTimeout:  10 * time.Second,
+		ResponseTimeout: 5 * time.Second,
+		ProbeTimeout:    3 * time.Second,
+		MinReadInterval: 2 * time.Second,
+		MaxRetries:      3,
+		RetryDelay:      1 * time.Second,
+	},
+}
+
+// GetCommProfile 按名稱獲取通信配置檔
+func GetCommProfile(name string) (CommProfile, error) {
+	profile, ok := commProfiles[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return CommProfile{}, fmt.Errorf("未知的通信配置檔: %s (可用: %s)",
+			name, strings.Join(CommProfileNames(), ", "))
+	}
+	return profile, nil
+}
+
+// CommProfileNames 返回所有內建通信配置檔名稱
+func CommProfileNames() []string {
+	names := make([]string, 0, len(commProfiles))
+	for name := range commProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyTo 將配置檔套用到設備配置，只覆蓋值為零的字段
+func (cp CommProfile) ApplyTo(config *Config) {
+	if config.ConnectTimeout == 0 {
+		config.ConnectTimeout = cp.ConnectTimeout
+	}
+	if config.ResponseTimeout == 0 {
+		config.ResponseTimeout = cp.ResponseTimeout
+	}
+	if config.ReadInterval < cp.MinReadInterval {
+		config.ReadInterval = cp.MinReadInterval
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = cp.MaxRetries
+	}
+	if config.RetryDelay == 0 {
+		config.RetryDelay = cp.RetryDelay
+	}
+}
@@ -0,0 +1,33 @@
+package pressure
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCommProfileApplyToRetries(t *testing.T) {
+	profile, err := GetCommProfile("radio")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := Config{}
+	profile.ApplyTo(&config)
+	if config.MaxRetries != profile.MaxRetries || config.RetryDelay != profile.RetryDelay {
+		t.Errorf("retries = %d after %v, want the profile's %d after %v",
+			config.MaxRetries, config.RetryDelay, profile.MaxRetries, profile.RetryDelay)
+	}
+
+	// 已設置的重試不被配置檔覆蓋
+	config = Config{MaxRetries: 5, RetryDelay: 10 * time.Millisecond}
+	profile.ApplyTo(&config)
+	if config.MaxRetries != 5 || config.RetryDelay != 10*time.Millisecond {
+		t.Errorf("explicit retries overwritten: %d after %v", config.MaxRetries, config.RetryDelay)
+	}
+
+	for _, name := range CommProfileNames() {
+		if profile, _ := GetCommProfile(name); profile.MaxRetries <= 0 || profile.RetryDelay <= 0 {
+			t.Errorf("profile %s has no read retries: %+v", name, profile)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// pressure/trace.go - Modbus 原始幀跟蹤：記錄每個請求和響應幀的十六進制內容和時間戳
+package pressure
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goburrow/modbus"
+)
+
+// FrameTrace 將 Modbus 請求和響應幀逐行寫入 w，用於與廠商排查校驗錯誤和地址問題
+//
+// 每行一幀：時間、連接端點、方向 (TX/RX) 和完整幀的十六進制內容；響應行附帶耗時，
+// RTU 響應的 CRC 不符時標記「CRC 錯誤」，沒有響應時記錄錯誤原因。可由多個設備共用。
+type FrameTrace struct {
+	mu     sync.Mutex
+	w      io.Writer
+	frames int64
+}
+
+// NewFrameTrace 創建寫入 w 的幀跟蹤
+func NewFrameTrace(w io.Writer) *FrameTrace {
+	return &FrameTrace{w: w}
+}
+
+// OpenFrameTrace 以追加方式打開跟蹤檔案，filename 為 "-" 時寫入標準錯誤
+func OpenFrameTrace(filename string) (*FrameTrace, error) {
+	if filename == "-" {
+		return NewFrameTrace(os.Stderr), nil
+	}
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("打開幀跟蹤檔案失敗: %v", err)
+	}
+	return NewFrameTrace(file), nil
+}
+
+// Frames 返回已記錄的幀數
+func (ft *FrameTrace) Frames() int64 {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	return ft.frames
+}
+
+// Close 關閉跟蹤檔案（寫入標準錯誤時不關閉）
+func (ft *FrameTrace) Close() error {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+	if closer, ok := ft.w.(io.Closer); ok && ft.w != os.Stderr && ft.w != os.Stdout {
+		return closer.Close()
+	}
+	return nil
+}
+
+// record 寫入一次事務的請求幀和響應幀
+func (ft *FrameTrace) record(endpoint string, rtu bool, start, end time.Time, request, response []byte, err error) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	fmt.Fprintf(ft.w, "%s %s TX % X\n", start.Format("2006-01-02 15:04:05.000000"), endpoint, request)
+	ft.frames++
+
+	elapsed := end.Sub(start).Round(time.Microsecond)
+	var line strings.Builder
+	fmt.Fprintf(&line, "%s %s RX ", end.Format("2006-01-02 15:04:05.000000"), endpoint)
+	if len(response) > 0 {
+		fmt.Fprintf(&line, "% X", response)
+		if rtu && !rtuCRCValid(response) {
+			line.WriteString(" CRC 錯誤")
+		}
+		ft.frames++
+	}
+	if err != nil {
+		if len(response) > 0 {
+			line.WriteString(" ")
+		}
+		fmt.Fprintf(&line, "ERROR %v", err)
+	}
+	fmt.Fprintf(ft.w, "%s (%v)\n", line.String(), elapsed)
+}
+
+// rtuCRCValid 檢查 RTU 幀末尾的 CRC-16/MODBUS（低字節在前）
+func rtuCRCValid(frame []byte) bool {
+	if len(frame) < 4 {
+		return false
+	}
+	crc := uint16(0xFFFF)
+	for _, b := range frame[:len(frame)-2] {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc = crc>>1 ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return frame[len(frame)-2] == byte(crc) && frame[len(frame)-1] == byte(crc>>8)
+}
+
+// tracingTransporter 包裝 Modbus 傳輸層，設置了幀跟蹤時記錄每次事務的請求和響應幀
+type tracingTransporter struct {
+	modbus.Transporter
+	endpoint string
+	rtu      bool
+	trace    atomic.Pointer[FrameTrace]
+}
+
+// newTracedClient 創建經過幀跟蹤包裝的 Modbus 客戶端，trace 為空時不記錄
+func newTracedClient(handler modbus.ClientHandler, endpoint string, rtu bool, trace *FrameTrace) (modbus.Client, *tracingTransporter) {
+	transporter := &tracingTransporter{Transporter: handler, endpoint: endpoint, rtu: rtu}
+	transporter.trace.Store(trace)
+	return modbus.NewClient2(handler, transporter), transporter
+}
+
+// Send 實現 modbus.Transporter 接口
+func (tt *tracingTransporter) Send(request []byte) ([]byte, error) {
+	trace := tt.trace.Load()
+	if trace == nil {
+		return tt.Transporter.Send(request)
+	}
+	start := time.Now()
+	response, err := tt.Transporter.Send(request)
+	trace.record(tt.endpoint, tt.rtu, start, time.Now(), request, response, err)
+	return response, err
+}
+
+// SetFrameTrace 設置幀跟蹤，之後的每次 Modbus 事務都會記錄請求和響應幀，nil 為停止記錄
+func (pm *PressureMeter) SetFrameTrace(trace *FrameTrace) *PressureMeter {
+	pm.tracer.trace.Store(trace)
+	return pm
+}
@@ -0,0 +1,73 @@
+// pressure/trend.go - 最近一段時間的有效讀數，供趨勢圖使用
+package pressure
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultTrendRetention 趨勢緩衝默認保留的時長
+const DefaultTrendRetention = 24 * time.Hour
+
+// TrendBuffer 保留最近一段時間有效讀數的輸出目標
+type TrendBuffer struct {
+	mu        sync.Mutex
+	retention time.Duration
+	points    []TrendPoint
+}
+
+// NewTrendBuffer 創建保留 retention 時長讀數的緩衝，retention 為 0 時使用 DefaultTrendRetention
+func NewTrendBuffer(retention time.Duration) *TrendBuffer {
+	if retention <= 0 {
+		retention = DefaultTrendRetention
+	}
+	return &TrendBuffer{retention: retention}
+}
+
+// Retention 返回保留時長
+func (tb *TrendBuffer) Retention() time.Duration {
+	return tb.retention
+}
+
+// WriteReading 實現 Sink 接口，記錄有效讀數並丟棄過期讀數
+func (tb *TrendBuffer) WriteReading(reading MonitorReading) error {
+	if !reading.Valid {
+		return nil
+	}
+
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.points = append(tb.points, TrendPoint{Time: reading.Timestamp, Pressure: reading.Pressure})
+
+	cutoff := reading.Timestamp.Add(-tb.retention)
+	drop := 0
+	for drop < len(tb.points) && tb.points[drop].Time.Before(cutoff) {
+		drop++
+	}
+	if drop > 0 {
+		// 丟棄的部分較多時複製到新切片，釋放底層數組
+		if drop > len(tb.points)/2 {
+			tb.points = append([]TrendPoint(nil), tb.points[drop:]...)
+		} else {
+			tb.points = tb.points[drop:]
+		}
+	}
+	return nil
+}
+
+// Close 實現 Sink 接口
+func (tb *TrendBuffer) Close() error {
+	return nil
+}
+
+// Since 返回 since 之後的讀數副本
+func (tb *TrendBuffer) Since(since time.Time) []TrendPoint {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	for i, p := range tb.points {
+		if !p.Time.Before(since) {
+			return append([]TrendPoint(nil), tb.points[i:]...)
+		}
+	}
+	return nil
+}
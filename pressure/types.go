@@ -3,6 +3,7 @@ package pressure
 
 import (
 	"fmt"
+	"net"
 	"strings"
 	"time"
 )
@@ -17,6 +18,8 @@ type DataFormatType int
 const (
 	DecimalFormat DataFormatType = 0 // 十進制格式 (擴大10倍)
 	FloatFormat   DataFormatType = 1 // IEEE 754 浮點數格式
+	Int16Format   DataFormatType = 2 // 單寄存器 16 位有符號整數 (擴大10倍)，緊湊型號
+	Uint16Format  DataFormatType = 3 // 單寄存器 16 位無符號整數 (擴大10倍)，緊湊型號
 )
 
 // String 實現 Stringer 接口
@@ -26,6 +29,10 @@ func (dft DataFormatType) String() string {
 		return "decimal"
 	case FloatFormat:
 		return "float"
+	case Int16Format:
+		return "int16"
+	case Uint16Format:
+		return "uint16"
 	default:
 		return "unknown"
 	}
@@ -43,12 +50,71 @@ func (dft *DataFormatType) UnmarshalText(text []byte) error {
 		*dft = DecimalFormat
 	case "float", "floating", "1":
 		*dft = FloatFormat
+	case "int16", "2":
+		*dft = Int16Format
+	case "uint16", "3":
+		*dft = Uint16Format
 	default:
 		return fmt.Errorf("unknown data format: %s", string(text))
 	}
 	return nil
 }
 
+// TimestampSource 讀數時間戳的取值時刻
+type TimestampSource int
+
+const (
+	TimestampBefore   TimestampSource = 0 // 發送請求前（默認）
+	TimestampAfter    TimestampSource = 1 // 收到響應後
+	TimestampMidpoint TimestampSource = 2 // 請求與響應的中點
+)
+
+// String 實現 Stringer 接口
+func (ts TimestampSource) String() string {
+	switch ts {
+	case TimestampBefore:
+		return "before"
+	case TimestampAfter:
+		return "after"
+	case TimestampMidpoint:
+		return "midpoint"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText 實現 encoding.TextMarshaler 接口，用於 JSON/YAML 序列化
+func (ts TimestampSource) MarshalText() ([]byte, error) {
+	return []byte(ts.String()), nil
+}
+
+// UnmarshalText 實現 encoding.TextUnmarshaler 接口，用於 JSON/YAML 反序列化
+func (ts *TimestampSource) UnmarshalText(text []byte) error {
+	switch strings.ToLower(strings.TrimSpace(string(text))) {
+	case "before", "request", "0":
+		*ts = TimestampBefore
+	case "after", "response", "1":
+		*ts = TimestampAfter
+	case "midpoint", "mid", "2":
+		*ts = TimestampMidpoint
+	default:
+		return fmt.Errorf("unknown timestamp source: %s", string(text))
+	}
+	return nil
+}
+
+// Resolve 根據請求開始和結束時間計算讀數時間戳
+func (ts TimestampSource) Resolve(start, end time.Time) time.Time {
+	switch ts {
+	case TimestampAfter:
+		return end
+	case TimestampMidpoint:
+		return start.Add(end.Sub(start) / 2)
+	default:
+		return start
+	}
+}
+
 // ============================================================================
 // 設備狀態相關類型
 // ============================================================================
@@ -82,6 +148,30 @@ func (ds DeviceStatus) String() string {
 	}
 }
 
+// MarshalText 實現 encoding.TextMarshaler 接口，用於 JSON/YAML 序列化
+func (ds DeviceStatus) MarshalText() ([]byte, error) {
+	return []byte(ds.String()), nil
+}
+
+// UnmarshalText 實現 encoding.TextUnmarshaler 接口，用於 JSON/YAML 反序列化
+func (ds *DeviceStatus) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "stopped":
+		*ds = StatusStopped
+	case "running":
+		*ds = StatusRunning
+	case "error":
+		*ds = StatusError
+	case "connecting":
+		*ds = StatusConnecting
+	case "disconnected":
+		*ds = StatusDisconnected
+	default:
+		return fmt.Errorf("unknown device status: %s", string(text))
+	}
+	return nil
+}
+
 // IsActive 檢查設備是否處於活躍狀態
 func (ds DeviceStatus) IsActive() bool {
 	return ds == StatusRunning || ds == StatusConnecting
@@ -134,6 +224,16 @@ func (pu PressureUnit) Symbol() string {
 	return pu.String()
 }
 
+// ParsePressureUnit 按單位符號（不區分大小寫）解析壓力單位
+func ParsePressureUnit(symbol string) (PressureUnit, error) {
+	for unit := Pascal; unit <= AtmTechnical; unit++ {
+		if strings.EqualFold(strings.TrimSpace(symbol), unit.String()) {
+			return unit, nil
+		}
+	}
+	return Pascal, fmt.Errorf("未知的壓力單位: %s (可用: Pa, kPa, mbar, Torr, psi, inH2O, mmH2O, at)", symbol)
+}
+
 // ConvertFromPascal 從帕斯卡轉換到指定單位
 func (pu PressureUnit) ConvertFromPascal(pascalValue float64) float64 {
 	switch pu {
@@ -229,6 +329,8 @@ const (
 	ErrProtocol       ErrorCode = 7  // 協議錯誤
 	ErrHardware       ErrorCode = 8  // 硬件錯誤
 	ErrSoftware       ErrorCode = 9  // 軟件錯誤
+	ErrOutOfRange     ErrorCode = 10 // 超出量程限制
+	ErrDeviceBusy     ErrorCode = 11 // 設備忙（Modbus 異常 5/6），稍後重試即可
 	ErrUnknown        ErrorCode = 99 // 未知錯誤
 )
 
@@ -255,6 +357,10 @@ func (ec ErrorCode) String() string {
 		return "hardware"
 	case ErrSoftware:
 		return "software"
+	case ErrOutOfRange:
+		return "out_of_range"
+	case ErrDeviceBusy:
+		return "device_busy"
 	default:
 		return "unknown"
 	}
@@ -283,6 +389,10 @@ func (ec ErrorCode) Description() string {
 		return "硬件故障"
 	case ErrSoftware:
 		return "軟件錯誤"
+	case ErrOutOfRange:
+		return "超出量程限制"
+	case ErrDeviceBusy:
+		return "設備忙"
 	default:
 		return "未知錯誤"
 	}
@@ -290,11 +400,12 @@ func (ec ErrorCode) Description() string {
 
 // PressureError 壓差儀專用錯誤類型
 type PressureError struct {
-	Code      ErrorCode `json:"code"`      // 錯誤代碼
-	Message   string    `json:"message"`   // 錯誤消息
-	Timestamp time.Time `json:"timestamp"` // 錯誤時間
-	SlaveID   byte      `json:"slave_id"`  // 設備ID
-	Context   string    `json:"context"`   // 錯誤上下文
+	Code          ErrorCode `json:"code"`                     // 錯誤代碼
+	Message       string    `json:"message"`                  // 錯誤消息
+	Timestamp     time.Time `json:"timestamp"`                // 錯誤時間
+	SlaveID       byte      `json:"slave_id"`                 // 設備ID
+	Context       string    `json:"context"`                  // 錯誤上下文
+	ExceptionCode byte      `json:"exception_code,omitempty"` // 設備返回的 Modbus 異常碼（如果有）
 }
 
 // Error 實現 error 接口
@@ -394,7 +505,10 @@ type DeviceModel struct {
 
 // String 實現 Stringer 接口
 func (dm DeviceModel) String() string {
-	return fmt.Sprintf("%s %s v%s", dm.Manufacturer, dm.Model, dm.Version)
+	if dm.Version == "" {
+		return strings.TrimSpace(dm.FullName())
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s v%s", dm.FullName(), dm.Version))
 }
 
 // FullName 返回完整名稱
@@ -447,6 +561,7 @@ const (
 	EventDeviceFound        EventType = 8  // 發現設備
 	EventStatusChanged      EventType = 9  // 狀態更改
 	EventAlarmTriggered     EventType = 10 // 告警觸發
+	EventDeviceFailed       EventType = 11 // 恢復步驟用完後設備標記為故障
 )
 
 // String 實現 Stringer 接口
@@ -472,11 +587,18 @@ func (et EventType) String() string {
 		return "status_changed"
 	case EventAlarmTriggered:
 		return "alarm_triggered"
+	case EventDeviceFailed:
+		return "device_failed"
 	default:
 		return "unknown"
 	}
 }
 
+// MarshalText 實現 encoding.TextMarshaler 接口，用於 JSON/YAML 序列化
+func (et EventType) MarshalText() ([]byte, error) {
+	return []byte(et.String()), nil
+}
+
 // Description 返回事件描述
 func (et EventType) Description() string {
 	switch et {
@@ -500,30 +622,60 @@ func (et EventType) Description() string {
 		return "設備狀態更改"
 	case EventAlarmTriggered:
 		return "告警觸發"
+	case EventDeviceFailed:
+		return "設備故障"
 	default:
 		return "未知事件"
 	}
 }
 
+// Event 設備發出的事件
+type Event struct {
+	Type      EventType   `json:"type"`               // 事件類型
+	Timestamp time.Time   `json:"timestamp"`          // 發生時間
+	Source    string      `json:"source,omitempty"`   // 來源，如串口路徑或 TCP 地址
+	SlaveID   byte        `json:"slave_id,omitempty"` // Modbus 站點號
+	Message   string      `json:"message,omitempty"`  // 說明
+	Data      interface{} `json:"data,omitempty"`     // 事件數據，如 EventStatusChanged 的 StatusChange
+}
+
+// StatusChange EventStatusChanged 事件的數據
+type StatusChange struct {
+	From   DeviceStatus `json:"from"`             // 原狀態
+	To     DeviceStatus `json:"to"`               // 新狀態
+	Reason string       `json:"reason,omitempty"` // 原因，如讀取錯誤
+}
+
 // ============================================================================
 // 常量定義
 // ============================================================================
 
 const (
 	// Modbus 協議常量
-	ModbusFunctionReadHoldingRegisters = 0x03
-	ModbusMaxSlaveID                   = 247
-	ModbusMinSlaveID                   = 1
+	ModbusFunctionReadHoldingRegisters   = 0x03
+	ModbusFunctionReadInputRegisters     = 0x04
+	ModbusFunctionWriteSingleRegister    = 0x06
+	ModbusFunctionWriteMultipleRegisters = 0x10
+	ModbusMaxSlaveID                     = 247
+	ModbusMinSlaveID                     = 1
+	ModbusBroadcastID                    = 0   // 廣播地址，所有設備執行但不響應
+	ModbusReservedMinID                  = 248 // 248-255 為協議保留地址
 
 	// 普時達壓差儀特定常量
 	PushidaPressureRegisterAddr  = 0x0034 // 壓力寄存器地址
 	PushidaPressureRegisterCount = 0x0002 // 壓力寄存器數量
 
 	// 默認配置值
-	DefaultBaudRate     = 9600
-	DefaultTimeout      = 5 * time.Second
-	DefaultReadInterval = 1 * time.Second
-	DefaultSlaveID      = 0x16 // 22
+	DefaultBaudRate        = 9600
+	DefaultParity          = "N"
+	DefaultStopBits        = 1
+	DefaultTransport       = TransportRTU
+	DefaultModbusTCPPort   = "502"
+	DefaultTimeout         = 5 * time.Second
+	DefaultConnectTimeout  = 5 * time.Second
+	DefaultResponseTimeout = DefaultTimeout
+	DefaultReadInterval    = 1 * time.Second
+	DefaultSlaveID         = 0x16 // 22
 
 	// 壓力範圍常量 (Pa)
 	MinReasonablePressure = -50000.0 // 最小合理壓力值
@@ -547,6 +699,74 @@ func GetSupportedBaudRates() []int {
 	return []int{1200, 2400, 4800, 9600, 19200, 38400, 57600, 115200}
 }
 
+// CommonParities 獲取最常見的校驗位（無校驗、偶校驗）
+func CommonParities() []string {
+	return []string{"N", "E"}
+}
+
+// AllParities 獲取全部校驗位（無校驗、偶校驗、奇校驗）
+func AllParities() []string {
+	return []string{"N", "E", "O"}
+}
+
+// AllStopBits 獲取全部停止位（1、2）
+func AllStopBits() []int {
+	return []int{1, 2}
+}
+
+// Modbus 傳輸方式
+const (
+	TransportRTU = "rtu" // RS485 串口上的 Modbus RTU
+	TransportTCP = "tcp" // 網關提供的 Modbus TCP
+
+	TransportRTUOverTCP = "rtuovertcp" // 串口服務器（如 Moxa NPort）TCP 連接上的 Modbus RTU 幀
+)
+
+// IsValidTransport 檢查傳輸方式是否有效 (rtu/tcp/rtuovertcp)
+func IsValidTransport(transport string) bool {
+	switch strings.ToLower(transport) {
+	case TransportRTU, TransportTCP, TransportRTUOverTCP:
+		return true
+	default:
+		return false
+	}
+}
+
+// NormalizeTCPAddress 為未指定端口的 Modbus TCP 地址補上默認端口 502
+func NormalizeTCPAddress(address string) string {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return address
+	}
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(strings.Trim(address, "[]"), DefaultModbusTCPPort)
+}
+
+// IsValidStopBits 檢查停止位是否有效 (1/2)
+func IsValidStopBits(stopBits int) bool {
+	return stopBits == 1 || stopBits == 2
+}
+
+// charFormat 以 8N1 形式表示字符格式（8 個數據位、校驗位、停止位），停止位 0 視為 1
+func charFormat(parity string, stopBits int) string {
+	if stopBits == 0 {
+		stopBits = DefaultStopBits
+	}
+	return fmt.Sprintf("8%s%d", parity, stopBits)
+}
+
+// IsValidParity 檢查校驗位是否有效 (N/E/O)
+func IsValidParity(parity string) bool {
+	switch strings.ToUpper(parity) {
+	case "N", "E", "O":
+		return true
+	default:
+		return false
+	}
+}
+
 // GetCommonSlaveIDs 獲取常用的從站ID列表
 func GetCommonSlaveIDs() []byte {
 	return []byte{0x01, 0x02, 0x03, 0x16, 0x17, 0x18} // 1, 2, 3, 22, 23, 24
@@ -557,6 +777,31 @@ func IsValidSlaveID(slaveID byte) bool {
 	return slaveID >= ModbusMinSlaveID && slaveID <= ModbusMaxSlaveID
 }
 
+// IsBroadcastSlaveID 檢查是否為廣播地址 (0)
+func IsBroadcastSlaveID(slaveID byte) bool {
+	return slaveID == ModbusBroadcastID
+}
+
+// IsReservedSlaveID 檢查是否為協議保留地址 (248-255)
+func IsReservedSlaveID(slaveID byte) bool {
+	return slaveID >= ModbusReservedMinID
+}
+
+// CheckPollingSlaveID 檢查站點號能否用於需要響應的請求（讀取、掃描）
+//
+// 廣播地址的請求設備不會響應，保留地址不應分配給設備，兩者都不能用於輪詢。
+func CheckPollingSlaveID(slaveID byte) error {
+	switch {
+	case IsBroadcastSlaveID(slaveID):
+		return fmt.Errorf("站點號 0 為廣播地址，設備不會響應，不能用於讀取")
+	case IsReservedSlaveID(slaveID):
+		return fmt.Errorf("站點號 %d 為 Modbus 保留地址 (%d-255)，不能用於讀取", slaveID, ModbusReservedMinID)
+	case !IsValidSlaveID(slaveID):
+		return fmt.Errorf("站點號必須在 %d-%d 之間，當前: %d", ModbusMinSlaveID, ModbusMaxSlaveID, slaveID)
+	}
+	return nil
+}
+
 // IsValidBaudRate 檢查波特率是否支援
 func IsValidBaudRate(baudRate int) bool {
 	supported := GetSupportedBaudRates()
@@ -0,0 +1,166 @@
+// pressure/watch.go - 連接運行中的監測程序，在終端持續顯示讀數和狀態
+package pressure
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// DefaultWatchHistory 觀察畫面保留的最近讀數數量
+const DefaultWatchHistory = 10
+
+// 終端控制序列
+const (
+	ansiClearScreen = "\033[H\033[2J"
+	ansiHideCursor  = "\033[?25l"
+	ansiShowCursor  = "\033[?25h"
+)
+
+// Watcher 定時從運行中的監測程序（HTTP 接口或 unix: 控制套接字）獲取狀態快照並渲染到終端
+//
+// 只讀取狀態，不影響監測程序，可隨時接入或離開。刷新間隔長於讀取間隔時，
+// 最近讀數只包含每次刷新時的最新讀數。
+type Watcher struct {
+	addr    string
+	limit   int
+	history []MonitorReading // 最近讀數，新的在後
+	last    *StatusSnapshot
+	lastErr error
+	updated time.Time
+}
+
+// NewWatcher 創建連接 addr 的觀察器
+func NewWatcher(addr string) *Watcher {
+	return &Watcher{addr: addr, limit: DefaultWatchHistory}
+}
+
+// SetHistory 設置畫面保留的最近讀數數量
+func (wt *Watcher) SetHistory(n int) *Watcher {
+	if n > 0 {
+		wt.limit = n
+	}
+	return wt
+}
+
+// Poll 獲取一次狀態快照並記錄新的讀數，失敗時保留上次的快照
+func (wt *Watcher) Poll() (*StatusSnapshot, error) {
+	snapshot, err := FetchStatus(wt.addr)
+	wt.lastErr = err
+	if err != nil {
+		return wt.last, err
+	}
+
+	wt.last = snapshot
+	wt.updated = time.Now()
+	if r := snapshot.LastReading; r != nil {
+		n := len(wt.history)
+		if n == 0 || wt.history[n-1].Count != r.Count || !wt.history[n-1].Timestamp.Equal(r.Timestamp) {
+			wt.history = append(wt.history, *r)
+			if len(wt.history) > wt.limit {
+				wt.history = wt.history[len(wt.history)-wt.limit:]
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// Render 清屏並將最近一次快照和讀數繪製到 w
+func (wt *Watcher) Render(w io.Writer) {
+	var b strings.Builder
+	b.WriteString(ansiClearScreen)
+
+	fmt.Fprintf(&b, "📺 監測程序 %s  (%s，Ctrl+C 離開)\n", wt.addr, time.Now().Format("15:04:05"))
+	b.WriteString(strings.Repeat("=", 60) + "\n")
+	if wt.lastErr != nil {
+		fmt.Fprintf(&b, "⚠️  %v，重試中...\n", wt.lastErr)
+		if !wt.updated.IsZero() {
+			fmt.Fprintf(&b, "   以下為 %s 的數據\n", wt.updated.Format("15:04:05"))
+		}
+	}
+
+	ss := wt.last
+	if ss == nil {
+		io.WriteString(w, b.String())
+		return
+	}
+	preset := DefaultUnitPreset
+	if ss.Config != nil {
+		if p, err := GetUnitPreset(ss.Config.UnitPreset); err == nil {
+			preset = p
+		}
+	}
+
+	m := ss.Monitor
+	if ss.Product != "" {
+		fmt.Fprintf(&b, "產品: %s %s\n", ss.Product, ss.ProductVersion)
+	}
+	fmt.Fprintf(&b, "設備: %s  狀態: %v  運行時長: %v\n", m.Device, ss.Device.DeviceStatus, m.Uptime.Round(time.Second))
+	fmt.Fprintf(&b, "讀數: %d (無效 %d, 連續失敗 %d)  緩衝: %d/%d (丟棄 %d)\n",
+		m.Readings, m.Errors, m.ConsecutiveFailures,
+		ss.Device.QueueSize, ss.Device.QueueCapacity, ss.Device.QueueDropped)
+	if m.DeviceFailed {
+		fmt.Fprintf(&b, "🚨 設備已標記為故障 (已執行 %d 個恢復步驟)\n", m.RecoverySteps)
+	}
+	if m.Pressure.Count > 0 {
+		fmt.Fprintf(&b, "範圍: %s ~ %s  平均: %s\n",
+			preset.Format(m.Pressure.Min), preset.Format(m.Pressure.Max), preset.Format(m.Pressure.Mean))
+	}
+	if m.Band != "" {
+		fmt.Fprintf(&b, "區間: %s\n", m.Band)
+	}
+	if sp := m.Setpoint; sp != nil && sp.Readings > 0 {
+		fmt.Fprintf(&b, "設定值 %s: 偏差 %+.2f Pa，達標 %.1f%%，今天超標 %.1f 分鐘\n",
+			sp.Config(), sp.Deviation, sp.InSpecPercent, sp.OutOfSpecToday)
+	}
+	if len(m.ActiveAlarms) > 0 {
+		fmt.Fprintf(&b, "🚨 當前告警: %s\n", strings.Join(m.ActiveAlarms, ", "))
+	}
+	for _, sink := range m.Sinks {
+		if sink.State == SinkStateOpen {
+			fmt.Fprintf(&b, "⚠️  輸出目標 %s 暫停: %s\n", sink.Name, sink.LastError)
+		}
+	}
+
+	b.WriteString(strings.Repeat("-", 60) + "\n")
+	b.WriteString("最近讀數:\n")
+	if len(wt.history) == 0 {
+		b.WriteString("   (尚無讀數)\n")
+	}
+	for i := len(wt.history) - 1; i >= 0; i-- {
+		r := wt.history[i]
+		location := ""
+		if r.Location != nil {
+			location = fmt.Sprintf(" (%s)", r.Location)
+		}
+		if r.Valid {
+			fmt.Fprintf(&b, "   [%s] #%d 站點%d%s: %s\n", r.Timestamp.Format("15:04:05"), r.Count, r.SlaveID, location, preset.Format(r.Pressure))
+		} else {
+			fmt.Fprintf(&b, "   [%s] #%d 站點%d%s: ❌ %s\n", r.Timestamp.Format("15:04:05"), r.Count, r.SlaveID, location, r.Error)
+		}
+	}
+
+	io.WriteString(w, b.String())
+}
+
+// Run 按 refresh 間隔刷新畫面，直到 stop 關閉；離開時恢復光標
+func (wt *Watcher) Run(w io.Writer, refresh time.Duration, stop <-chan struct{}) {
+	if refresh <= 0 {
+		refresh = time.Second
+	}
+	io.WriteString(w, ansiHideCursor)
+	defer io.WriteString(w, ansiShowCursor)
+
+	ticker := time.NewTicker(refresh)
+	defer ticker.Stop()
+	for {
+		wt.Poll()
+		wt.Render(w)
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,320 @@
+// pressure/xlsx.go - Excel (xlsx) 匯出，僅依賴標準庫
+package pressure
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XLSXSheet 工作表
+type XLSXSheet struct {
+	Name string          // 工作表名稱（最多 31 個字符）
+	Rows [][]interface{} // 行數據，支援字符串、數字、布爾值和 time.Time
+}
+
+// XLSXWorkbook 簡易 Excel 工作簿
+type XLSXWorkbook struct {
+	Sheets []*XLSXSheet
+}
+
+// NewXLSXWorkbook 創建空的工作簿
+func NewXLSXWorkbook() *XLSXWorkbook {
+	return &XLSXWorkbook{}
+}
+
+// AddSheet 添加工作表並返回該工作表
+func (wb *XLSXWorkbook) AddSheet(name string) *XLSXSheet {
+	sheet := &XLSXSheet{Name: sanitizeSheetName(name)}
+	wb.Sheets = append(wb.Sheets, sheet)
+	return sheet
+}
+
+// AddRow 添加一行數據
+func (sh *XLSXSheet) AddRow(values ...interface{}) {
+	sh.Rows = append(sh.Rows, values)
+}
+
+// Write 將工作簿以 xlsx 格式寫入
+func (wb *XLSXWorkbook) Write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", wb.contentTypesXML()},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", wb.workbookXML()},
+		{"xl/_rels/workbook.xml.rels", wb.workbookRelsXML()},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for i, sheet := range wb.Sheets {
+		files = append(files, struct {
+			name    string
+			content string
+		}{fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), sheet.xml()})
+	}
+
+	for _, file := range files {
+		fw, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, file.content); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// Save 將工作簿保存到檔案
+func (wb *XLSXWorkbook) Save(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("創建 xlsx 檔案失敗: %v", err)
+	}
+	defer file.Close()
+
+	if err := wb.Write(file); err != nil {
+		return fmt.Errorf("寫入 xlsx 檔案失敗: %v", err)
+	}
+	return nil
+}
+
+func (wb *XLSXWorkbook) contentTypesXML() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	b.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	b.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	b.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	b.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := range wb.Sheets {
+		fmt.Fprintf(&b, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+	}
+	b.WriteString(`</Types>`)
+	return b.String()
+}
+
+func (wb *XLSXWorkbook) workbookXML() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range wb.Sheets {
+		fmt.Fprintf(&b, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.Name), i+1, i+1)
+	}
+	b.WriteString(`</sheets></workbook>`)
+	return b.String()
+}
+
+func (wb *XLSXWorkbook) workbookRelsXML() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := range wb.Sheets {
+		fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	fmt.Fprintf(&b, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, len(wb.Sheets)+1)
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func (sh *XLSXSheet) xml() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range sh.Rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+1)
+		for c, value := range row {
+			ref := xlsxColumnName(c) + strconv.Itoa(r+1)
+			b.WriteString(xlsxCell(ref, value))
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// xlsxCell 將值轉為單元格 XML
+func xlsxCell(ref string, value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		n := 0
+		if v {
+			n = 1
+		}
+		return fmt.Sprintf(`<c r="%s" t="b"><v>%d</v></c>`, ref, n)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf(`<c r="%s"><v>%d</v></c>`, ref, v)
+	case float32:
+		return xlsxCell(ref, float64(v))
+	case float64:
+		return fmt.Sprintf(`<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+	case time.Time:
+		// 以 Excel 日期序列值保存，使用日期時間樣式
+		serial := v.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, v.Location())).Hours() / 24
+		return fmt.Sprintf(`<c r="%s" s="1"><v>%s</v></c>`, ref, strconv.FormatFloat(serial, 'f', -1, 64))
+	default:
+		return fmt.Sprintf(`<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, xmlEscape(fmt.Sprint(v)))
+	}
+}
+
+// xlsxColumnName 將從 0 開始的列索引轉為 A, B, ..., AA 等列名
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// sanitizeSheetName 移除工作表名稱中 Excel 不允許的字符
+func sanitizeSheetName(name string) string {
+	replacer := strings.NewReplacer(":", "_", "\\", "_", "/", "_", "?", "_", "*", "_", "[", "(", "]", ")")
+	name = replacer.Replace(name)
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" {
+		name = "Sheet"
+	}
+	return name
+}
+
+// xmlEscape 轉義 XML 特殊字符
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+	`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>` +
+	`</styleSheet>`
+
+// ============================================================================
+// 匯出函數
+// ============================================================================
+
+// SaveScanResultsXLSX 將掃描結果匯出為 xlsx（摘要、串口、設備三個工作表）
+func SaveScanResultsXLSX(result *ScanResult, filename string) error {
+	wb := NewXLSXWorkbook()
+
+	summary := wb.AddSheet("摘要")
+	summary.AddRow("項目", "值")
+	summary.AddRow("生成時間", time.Now())
+	summary.AddRow("掃描耗時 (秒)", result.ScanTime.Seconds())
+	summary.AddRow("測試配置數", result.TotalTested)
+	summary.AddRow("響應設備數", result.Successful)
+	summary.AddRow("探測超時", result.Config.ScanTimeout.String())
+
+	ports := wb.AddSheet("串口")
+	ports.AddRow("串口", "測試數", "響應數", "波特率")
+	for _, port := range SummarizePorts(result) {
+		rates := make([]string, len(port.BaudRates))
+		for i, rate := range port.BaudRates {
+			rates[i] = strconv.Itoa(rate)
+		}
+		ports.AddRow(port.Port, port.Tested, port.Responsive, strings.Join(rates, ", "))
+	}
+
+	devices := wb.AddSheet("設備")
+	devices.AddRow("串口", "站點號", "位置", "型號", "響應", "波特率", "數據格式", "置信度", "壓力 (Pa)", "原始數據", "掃描時間", "錯誤")
+	for _, device := range result.Devices {
+		var baudRate, confidence, pressure interface{}
+		if rate, ok := deviceBaudRate(device); ok {
+			baudRate = rate
+		}
+		if c, ok := device.Properties["format_confidence"].(float64); ok {
+			confidence = c
+		}
+		if device.LastReading != nil {
+			pressure = device.LastReading.Pressure
+		}
+		rawData, _ := device.Properties["raw_data"].(string)
+		location := ""
+		if device.Location != nil {
+			location = device.Location.String()
+		}
+		model := ""
+		if device.Model != nil {
+			model = device.Model.String()
+		}
+
+		devices.AddRow(device.Device, int(device.SlaveID), location, model, device.Responsive, baudRate,
+			device.DataFormat.String(), confidence, pressure, rawData, device.ScanTime, device.Error)
+	}
+
+	return wb.Save(filename)
+}
+
+// SaveReadingsXLSX 將歷史讀數匯出為 xlsx（讀數和統計兩個工作表）
+func SaveReadingsXLSX(readings []PressureReading, filename string) error {
+	wb := NewXLSXWorkbook()
+
+	stats := &Statistics{}
+	invalid := 0
+	for _, reading := range readings {
+		if reading.Valid {
+			stats.Update(reading.Pressure)
+		} else {
+			invalid++
+		}
+	}
+
+	summary := wb.AddSheet("統計")
+	summary.AddRow("項目", "值")
+	summary.AddRow("總讀數", len(readings))
+	summary.AddRow("有效讀數", stats.Count)
+	summary.AddRow("無效讀數", invalid)
+	if len(readings) > 0 {
+		summary.AddRow("開始時間", readings[0].Timestamp)
+		summary.AddRow("結束時間", readings[len(readings)-1].Timestamp)
+	}
+	if stats.Count > 0 {
+		summary.AddRow("最小值 (Pa)", stats.Min)
+		summary.AddRow("最大值 (Pa)", stats.Max)
+		summary.AddRow("平均值 (Pa)", stats.Mean)
+		summary.AddRow("標準偏差 (Pa)", stats.StdDev)
+	}
+
+	sheet := wb.AddSheet("讀數")
+	sheet.AddRow("時間", "站點號", "壓力 (Pa)", "未補償 (Pa)", "溫度 (°C)", "耗時 (ms)", "有效", "錯誤")
+	for _, reading := range readings {
+		var pressure, rawPressure, temperature interface{}
+		if reading.Valid {
+			pressure = reading.Pressure
+		}
+		if reading.RawPressure != nil {
+			rawPressure = *reading.RawPressure
+		}
+		if reading.Temperature != nil {
+			temperature = *reading.Temperature
+		}
+		durationMs := float64(reading.Duration) / float64(time.Millisecond)
+		sheet.AddRow(reading.Timestamp, int(reading.SlaveID), pressure, rawPressure, temperature,
+			durationMs, reading.Valid, reading.Error)
+	}
+
+	return wb.Save(filename)
+}
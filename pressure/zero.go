@@ -0,0 +1,41 @@
+// pressure/zero.go - 零點校準命令
+package pressure
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultZeroCommand 寫入零點校準寄存器觸發校準的默認命令值
+const DefaultZeroCommand = 1
+
+// ZeroSettleTime 寫入校準命令後等待儀表完成校準的時間
+const ZeroSettleTime = 2 * time.Second
+
+// ZeroTolerance 校準後讀數與 0 的允許偏差 (Pa)
+const ZeroTolerance = 0.5
+
+// SupportsZeroCalibration 設備是否配置了零點校準寄存器
+func (pm *PressureMeter) SupportsZeroCalibration() bool {
+	return pm.zeroRegister != 0
+}
+
+// ZeroCalibrate 向零點校準寄存器寫入校準命令，儀表以當前壓差作為新的零點
+//
+// 調用前須確認兩個取壓口已連通（壓差為 0）。校準命令寄存器通常會在執行後自動清零，
+// 因此不做讀回確認；寫入單個寄存器的響應會回顯地址和數值，由 Modbus 客戶端核對。
+func (pm *PressureMeter) ZeroCalibrate() error {
+	if !pm.SupportsZeroCalibration() {
+		return fmt.Errorf("未配置零點校準寄存器 (zeroregister)")
+	}
+	if err := pm.checkControl(); err != nil {
+		return err
+	}
+	pm.busMu.Lock()
+	defer pm.busMu.Unlock()
+	if _, err := pm.client.WriteSingleRegister(pm.zeroRegister, pm.zeroCommand); err != nil {
+		return fmt.Errorf("寫入零點校準命令失敗: %v", err)
+	}
+	pm.logger.Printf("已向寄存器 0x%04X 寫入零點校準命令: %d", pm.zeroRegister, pm.zeroCommand)
+	return nil
+}